@@ -171,6 +171,31 @@ func main() {
 				return nil
 			},
 		},
+		{
+			Name:  "sql",
+			Usage: "sql [-format table|json] [-timeout 30s]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format",
+					Usage: "The output format of the query results, table or json",
+					Value: "table",
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "How long a query runs, 0 means until Ctrl+C",
+					Value: 30 * time.Second,
+				},
+			},
+			Action: func(c *cli.Context) error {
+				format := c.String("format")
+				if format != "table" && format != "json" {
+					fmt.Printf("Invalid format %s, expect table or json.\n", format)
+					return nil
+				}
+				newSqlShell(client, os.Stdin, os.Stdout, format, c.Duration("timeout")).run()
+				return nil
+			},
+		},
 		{
 			Name:    "create",
 			Aliases: []string{"create"},
@@ -1133,6 +1158,141 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:    "test",
+			Aliases: []string{"test"},
+			Usage:   "test rule [$spec_json | -f $spec_file]",
+			Subcommands: []cli.Command{
+				{
+					Name:  "rule",
+					Usage: "test rule [$spec_json | -f $spec_file]",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:     "file, f",
+							Usage:    "the location of the rule test spec file",
+							FilePath: "/home/myspec.json",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						var specs string
+						if sfile := c.String("file"); sfile != "" {
+							spec, err := readDef(sfile, "rule test spec")
+							if err != nil {
+								fmt.Printf("%s", err)
+								return nil
+							}
+							specs = string(spec)
+						} else {
+							if len(c.Args()) != 1 {
+								fmt.Printf("Expect rule test spec json.\nBut found %d args:%s.\n", len(c.Args()), c.Args())
+								return nil
+							}
+							specs = c.Args()[0]
+						}
+						var reply string
+						err = client.Call("Server.TestRule", specs, &reply)
+						if err != nil {
+							fmt.Println(err)
+							os.Exit(1)
+						}
+						fmt.Println(reply)
+						// exit with error if any spec fails so that it can be used in CI
+						var results []struct {
+							Passed bool `json:"passed"`
+						}
+						if err := json.Unmarshal([]byte(reply), &results); err != nil {
+							os.Exit(1)
+						}
+						for _, r := range results {
+							if !r.Passed {
+								os.Exit(1)
+							}
+						}
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:  "bench",
+			Usage: "bench [$bench_json | -f $bench_file] [-rule $rule_id | -scenario $scenario] [-rate 1000] [-duration 10s] [-o $report_file]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:     "file, f",
+					Usage:    "the location of the bench definition file",
+					FilePath: "/home/mybench.json",
+				},
+				cli.StringFlag{
+					Name:  "rule",
+					Usage: "the id of the rule to bench",
+				},
+				cli.StringFlag{
+					Name:  "scenario",
+					Usage: "the canned scenario to bench, passthrough, filter or window",
+				},
+				cli.IntFlag{
+					Name:  "rate",
+					Usage: "the messages per second sent to each stream",
+				},
+				cli.DurationFlag{
+					Name:  "duration",
+					Usage: "how long the bench runs",
+				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Usage: "the location to write the report",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				benchDef := make(map[string]any)
+				var content []byte
+				if sfile := c.String("file"); sfile != "" {
+					content, err = readDef(sfile, "bench")
+					if err != nil {
+						fmt.Printf("%s", err)
+						return nil
+					}
+				} else if len(c.Args()) == 1 {
+					content = []byte(c.Args()[0])
+				}
+				if len(content) > 0 {
+					if err := json.Unmarshal(content, &benchDef); err != nil {
+						fmt.Printf("Invalid bench definition: %s.\n", err)
+						return nil
+					}
+				}
+				// The flags override the definition
+				if v := c.String("rule"); v != "" {
+					benchDef["ruleId"] = v
+				}
+				if v := c.String("scenario"); v != "" {
+					benchDef["scenario"] = v
+				}
+				if v := c.Int("rate"); v > 0 {
+					benchDef["rate"] = v
+				}
+				if v := c.Duration("duration"); v > 0 {
+					benchDef["duration"] = v.String()
+				}
+				args, _ := json.Marshal(benchDef)
+				var reply string
+				err = client.Call("Server.BenchRule", string(args), &reply)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				if output := c.String("output"); output != "" {
+					if err := os.WriteFile(output, []byte(reply), 0o644); err != nil {
+						fmt.Printf("Fail to write the report to %s: %s.\n", output, err)
+						os.Exit(1)
+					}
+					fmt.Printf("Bench report is written to %s.\n", output)
+					return nil
+				}
+				fmt.Println(reply)
+				return nil
+			},
+		},
 		{
 			Name:    "register",
 			Aliases: []string{"register"},
@@ -1243,6 +1403,10 @@ func main() {
 							Name:  "partial, p",
 							Usage: "import partial configuration",
 						},
+						cli.StringFlag{
+							Name:  "conflict, c",
+							Usage: "the policy to handle the existing resources in partial import: overwrite, skip or rename",
+						},
 					},
 					Action: func(c *cli.Context) error {
 						sfile := c.String("file")
@@ -1264,6 +1428,7 @@ func main() {
 							FileName: sfile,
 							Stop:     r == "true",
 							Partial:  p == "true",
+							Conflict: c.String("conflict"),
 						}
 
 						var reply string
@@ -0,0 +1,305 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/rpc"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/model"
+)
+
+const sqlShellHelp = `Statements end with ';' and can span multiple lines.
+  SELECT ...                         run an ad-hoc query against the live streams
+  CREATE/DROP/SHOW/DESCRIBE/EXPLAIN  manage the streams and tables
+Commands:
+  \format table|json                 set the output format of the query results
+  \timeout <duration>                set how long a query runs such as 30s, 0 means until Ctrl+C
+  \help                              show this help
+  \quit, exit, quit                  leave the shell`
+
+// sqlShell is the interactive shell to run the ad-hoc queries and the stream/table statements
+type sqlShell struct {
+	client  *rpc.Client
+	in      *bufio.Reader
+	out     io.Writer
+	format  string
+	timeout time.Duration
+	// the columns of the current table output, the header is printed again when they change
+	columns []string
+	widths  []int
+}
+
+func newSqlShell(client *rpc.Client, in io.Reader, out io.Writer, format string, timeout time.Duration) *sqlShell {
+	return &sqlShell{
+		client:  client,
+		in:      bufio.NewReader(in),
+		out:     out,
+		format:  format,
+		timeout: timeout,
+	}
+}
+
+func (s *sqlShell) run() {
+	fmt.Fprintln(s.out, `Type "\help" for help.`)
+	var buf strings.Builder
+	for {
+		if buf.Len() == 0 {
+			fmt.Fprint(s.out, "kuiper sql> ")
+		} else {
+			fmt.Fprint(s.out, "         -> ")
+		}
+		line, err := s.in.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if buf.Len() == 0 {
+			if quit := s.command(line); quit {
+				return
+			}
+			if strings.HasPrefix(line, `\`) {
+				line = ""
+			}
+		}
+		if line != "" {
+			if buf.Len() > 0 {
+				buf.WriteString(" ")
+			}
+			buf.WriteString(line)
+		}
+		for {
+			stmt, rest, ok := strings.Cut(buf.String(), ";")
+			if !ok {
+				break
+			}
+			if stmt = strings.TrimSpace(stmt); stmt != "" {
+				s.execute(stmt)
+			}
+			buf.Reset()
+			buf.WriteString(strings.TrimSpace(rest))
+		}
+		if err != nil {
+			// EOF, run the last statement without ';'
+			if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+				s.execute(stmt)
+			}
+			fmt.Fprintln(s.out)
+			return
+		}
+	}
+}
+
+// command handles the shell commands and returns true to quit
+func (s *sqlShell) command(line string) bool {
+	if strings.EqualFold(line, "quit") || strings.EqualFold(line, "exit") {
+		return true
+	}
+	if !strings.HasPrefix(line, `\`) {
+		return false
+	}
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case `\q`, `\quit`:
+		return true
+	case `\help`, `\h`, `\?`:
+		fmt.Fprintln(s.out, sqlShellHelp)
+	case `\format`:
+		if len(fields) != 2 || (fields[1] != "table" && fields[1] != "json") {
+			fmt.Fprintln(s.out, `Usage: \format table|json`)
+		} else {
+			s.format = fields[1]
+			fmt.Fprintf(s.out, "Output format is %s.\n", s.format)
+		}
+	case `\timeout`:
+		var (
+			d   time.Duration
+			err error
+		)
+		if len(fields) == 2 {
+			d, err = time.ParseDuration(fields[1])
+		}
+		if len(fields) != 2 || err != nil || d < 0 {
+			fmt.Fprintln(s.out, `Usage: \timeout <duration> such as \timeout 30s`)
+		} else {
+			s.timeout = d
+			fmt.Fprintf(s.out, "Query timeout is %v.\n", s.timeout)
+		}
+	default:
+		fmt.Fprintf(s.out, "Unknown command %s, type \\help for help.\n", fields[0])
+	}
+	return false
+}
+
+func (s *sqlShell) execute(stmt string) {
+	keyword := strings.ToUpper(strings.Fields(stmt)[0])
+	switch keyword {
+	case "SELECT":
+		s.query(stmt)
+	case "CREATE", "DROP", "SHOW", "DESCRIBE", "DESC", "EXPLAIN":
+		var reply string
+		if err := s.client.Call("Server.Stream", stmt, &reply); err != nil {
+			fmt.Fprintln(s.out, err)
+		} else {
+			fmt.Fprintln(s.out, strings.TrimRight(reply, "\n"))
+		}
+	default:
+		fmt.Fprintf(s.out, "Unsupported statement %s, type \\help for help.\n", keyword)
+	}
+}
+
+// query runs the ad-hoc query until timeout or interrupted by Ctrl+C, and prints the results as they arrive
+func (s *sqlShell) query(sql string) {
+	var reply string
+	if err := s.client.Call("Server.CreateTimedQuery", &model.QueryDesc{Sql: sql, Timeout: s.timeout}, &reply); err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	fmt.Fprintln(s.out, reply, "Press Ctrl+C to stop.")
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+	var deadline <-chan time.Time
+	if s.timeout > 0 {
+		// wait a little longer than the server side timeout to fetch the last results
+		deadline = time.After(s.timeout + time.Second)
+	}
+	ticker := time.NewTicker(300 * time.Millisecond)
+	defer ticker.Stop()
+	s.columns = nil
+	for {
+		select {
+		case <-interrupted:
+			s.stopQuery()
+			return
+		case <-deadline:
+			s.stopQuery()
+			return
+		case <-ticker.C:
+			var result string
+			if err := s.client.Call("Server.GetQueryResult", "", &result); err != nil {
+				// the query is stopped by timeout or error
+				fmt.Fprintln(s.out, err)
+				return
+			}
+			if result != "" {
+				for _, r := range strings.Split(result, "\n") {
+					s.print(r)
+				}
+			}
+		}
+	}
+}
+
+func (s *sqlShell) stopQuery() {
+	var reply string
+	if err := s.client.Call("Server.StopQuery", "", &reply); err != nil {
+		fmt.Fprintln(s.out, err)
+	} else {
+		fmt.Fprintln(s.out, reply)
+	}
+}
+
+// print prints a result of the query, which is a json array or object
+func (s *sqlShell) print(result string) {
+	rows, err := decodeRows(result)
+	if err != nil {
+		fmt.Fprintln(s.out, result)
+		return
+	}
+	if s.format == "json" {
+		for _, row := range rows {
+			b, _ := json.Marshal(row)
+			fmt.Fprintln(s.out, string(b))
+		}
+		return
+	}
+	for _, row := range rows {
+		cols := rowColumns(row)
+		if !slices.Equal(cols, s.columns) {
+			s.columns = cols
+			s.widths = make([]int, len(cols))
+			for i, c := range cols {
+				s.widths[i] = max(len(c), len(formatCell(row[c])), 8)
+			}
+			s.printRow(cols)
+			sep := make([]string, len(cols))
+			for i, w := range s.widths {
+				sep[i] = strings.Repeat("-", w)
+			}
+			s.printRow(sep)
+		}
+		cells := make([]string, len(cols))
+		for i, c := range cols {
+			cells[i] = formatCell(row[c])
+		}
+		s.printRow(cells)
+	}
+}
+
+func (s *sqlShell) printRow(cells []string) {
+	var b strings.Builder
+	for i, c := range cells {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		b.WriteString(c)
+		if pad := s.widths[i] - len(c); pad > 0 && i < len(cells)-1 {
+			b.WriteString(strings.Repeat(" ", pad))
+		}
+	}
+	fmt.Fprintln(s.out, b.String())
+}
+
+func decodeRows(result string) ([]map[string]any, error) {
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(result), &rows); err == nil {
+		return rows, nil
+	}
+	row := map[string]any{}
+	if err := json.Unmarshal([]byte(result), &row); err != nil {
+		return nil, err
+	}
+	return []map[string]any{row}, nil
+}
+
+func rowColumns(row map[string]any) []string {
+	cols := make([]string, 0, len(row))
+	for k := range row {
+		cols = append(cols, k)
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func formatCell(v any) string {
+	switch vt := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return vt
+	case map[string]any, []any:
+		b, _ := json.Marshal(vt)
+		return string(b)
+	default:
+		return fmt.Sprint(vt)
+	}
+}
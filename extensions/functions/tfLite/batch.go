@@ -0,0 +1,138 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build tflite
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/mattn/go-tflite"
+)
+
+// tfBatchFunc infers multiple samples in one invoke. It is usually used with collect() in a window to infer all the
+// tuples of the window together. Each tensor argument is an array whose items are the tensor of each sample.
+// It returns an array of the results of each sample and the result is the same as the tfLite function.
+type tfBatchFunc struct{}
+
+func (f *tfBatchFunc) Validate(args []interface{}) error {
+	if len(args) < 2 {
+		return fmt.Errorf("tfLiteBatch function must have at least 2 parameters but got %d", len(args))
+	}
+	return nil
+}
+
+func (f *tfBatchFunc) IsAggregate() bool {
+	return false
+}
+
+func (f *tfBatchFunc) Exec(args []interface{}, ctx api.FunctionContext) (interface{}, bool) {
+	model, ok := args[0].(string)
+	if !ok {
+		return fmt.Errorf("tfLiteBatch function first parameter must be a string, but got %[1]T(%[1]v)", args[0]), false
+	}
+	interpreter, err := ipManager.GetOrCreate(model)
+	if err != nil {
+		return err, false
+	}
+	inputCount := interpreter.GetInputTensorCount()
+	if len(args)-1 != inputCount {
+		return fmt.Errorf("tfLiteBatch function requires %d tensors but got %d", inputCount, len(args)-1), false
+	}
+	batch := -1
+	inputs := make([]interface{}, inputCount)
+	for i := 1; i < len(args); i++ {
+		samples, ok := args[i].([]interface{})
+		if !ok {
+			return fmt.Errorf("tfLiteBatch function parameter %d must be an array of samples, but got %[2]T(%[2]v)", i, args[i]), false
+		}
+		if batch < 0 {
+			batch = len(samples)
+		} else if batch != len(samples) {
+			return fmt.Errorf("tfLiteBatch function parameter %d has %d samples but the previous parameters have %d", i, len(samples), batch), false
+		}
+		inputs[i-1], err = flattenSamples(samples, i)
+		if err != nil {
+			return err, false
+		}
+	}
+	if batch == 0 {
+		return []interface{}{}, true
+	}
+	ctx.GetLogger().Debugf("tfLiteBatch function %s with %d tensors of batch %d", model, inputCount, batch)
+
+	interpreter.Lock()
+	defer interpreter.Unlock()
+	if err := interpreter.resize(batch); err != nil {
+		return err, false
+	}
+	for i, input := range inputs {
+		if err := setInput(interpreter.GetInputTensor(i), i+1, input); err != nil {
+			return err, false
+		}
+	}
+	status := interpreter.Invoke()
+	if status != tflite.OK {
+		return fmt.Errorf("invoke failed"), false
+	}
+	results := make([]interface{}, batch)
+	for j := range results {
+		results[j] = make([]interface{}, interpreter.GetOutputTensorCount())
+	}
+	for i := 0; i < interpreter.GetOutputTensorCount(); i++ {
+		output, err := getOutput(interpreter.GetOutputTensor(i), i)
+		if err != nil {
+			return err, false
+		}
+		split, err := splitOutput(output, batch)
+		if err != nil {
+			return err, false
+		}
+		for j, s := range split {
+			results[j].([]interface{})[i] = s
+		}
+	}
+	return results, true
+}
+
+// flattenSamples concatenates the tensors of all samples. The samples must be all bytea or all arrays.
+func flattenSamples(samples []interface{}, i int) (interface{}, error) {
+	if len(samples) == 0 {
+		return samples, nil
+	}
+	switch samples[0].(type) {
+	case []byte:
+		var result []byte
+		for _, s := range samples {
+			b, ok := s.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("tfLiteBatch function parameter %d has mixed sample types, expect bytea but got %[2]T(%[2]v)", i, s)
+			}
+			result = append(result, b...)
+		}
+		return result, nil
+	default:
+		var result []interface{}
+		for _, s := range samples {
+			a, ok := s.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("tfLiteBatch function parameter %d has mixed sample types, expect array but got %[2]T(%[2]v)", i, s)
+			}
+			result = append(result, a...)
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//go:build tflite && tflite_edgetpu
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattn/go-tflite/delegates"
+	"github.com/mattn/go-tflite/delegates/edgetpu"
+)
+
+func init() {
+	delegateFactories["edgetpu"] = func(_ *modelOptions) (delegates.Delegater, error) {
+		devices, err := edgetpu.DeviceList()
+		if err != nil {
+			return nil, err
+		}
+		if len(devices) == 0 {
+			return nil, fmt.Errorf("no edgetpu device found")
+		}
+		return edgetpu.New(devices[0]), nil
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//go:build tflite && tflite_gpu
+
+package main
+
+/*
+#cgo LDFLAGS: -ltensorflowlite_gpu_delegate
+#include <tensorflow/lite/delegates/gpu/delegate.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/mattn/go-tflite/delegates"
+)
+
+func init() {
+	delegateFactories["gpu"] = func(_ *modelOptions) (delegates.Delegater, error) {
+		opts := C.TfLiteGpuDelegateOptionsV2Default()
+		d := C.TfLiteGpuDelegateV2Create(&opts)
+		if d == nil {
+			return nil, fmt.Errorf("create gpu delegate failed")
+		}
+		return &gpuDelegate{d: d}, nil
+	}
+}
+
+// gpuDelegate wraps the GPU delegate V2 of the TensorFlow Lite C API
+type gpuDelegate struct {
+	d *C.TfLiteDelegate
+}
+
+func (g *gpuDelegate) Delete() {
+	C.TfLiteGpuDelegateV2Delete(g.d)
+}
+
+func (g *gpuDelegate) Ptr() unsafe.Pointer {
+	return unsafe.Pointer(g.d)
+}
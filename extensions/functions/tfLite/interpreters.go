@@ -17,11 +17,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/mattn/go-tflite"
+	"github.com/mattn/go-tflite/delegates"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 )
@@ -34,23 +37,46 @@ func init() {
 		panic(err)
 	}
 	ipManager = &interpreterManager{
-		registry: make(map[string]*tflite.Interpreter),
+		registry: make(map[string]*modelInterpreter),
 		path:     filepath.Join(path, "uploads"),
 	}
 }
 
+// modelOptions is read from the optional {model}.json file next to the model file
+type modelOptions struct {
+	// The hardware delegate such as xnnpack, gpu and edgetpu. Default to run on CPU without delegate.
+	Delegate   string `json:"delegate"`
+	NumThreads int    `json:"numThreads"`
+}
+
+// delegateFactories are registered by build tags because each delegate requires its own native library
+var delegateFactories = map[string]func(opts *modelOptions) (delegates.Delegater, error){}
+
+// modelInterpreter wraps the interpreter which is not thread safe. It must be locked during setting input and invoking.
+type modelInterpreter struct {
+	sync.Mutex
+	*tflite.Interpreter
+	delegate delegates.Delegater
+	// the current batch size of the inputs, 0 means the original shape
+	batch int
+}
+
 type interpreterManager struct {
 	sync.Mutex
-	registry map[string]*tflite.Interpreter
+	registry map[string]*modelInterpreter
 	path     string
 }
 
-func (m *interpreterManager) GetOrCreate(name string) (*tflite.Interpreter, error) {
+func (m *interpreterManager) GetOrCreate(name string) (*modelInterpreter, error) {
 	log := conf.Log
 	m.Lock()
 	defer m.Unlock()
 	ip, ok := m.registry[name]
 	if !ok {
+		opts, err := m.readOptions(name)
+		if err != nil {
+			return nil, err
+		}
 		mf := filepath.Join(m.path, name+".tflite")
 		model := tflite.NewModelFromFile(mf)
 		if model == nil {
@@ -60,20 +86,61 @@ func (m *interpreterManager) GetOrCreate(name string) (*tflite.Interpreter, erro
 		log.Infof("success load model: %s", mf)
 		defer model.Delete()
 		options := tflite.NewInterpreterOptions()
-		options.SetNumThread(4)
+		options.SetNumThread(opts.NumThreads)
 		options.SetErrorReporter(func(msg string, user_data interface{}) {
 			fmt.Println(msg)
 		}, nil)
 		defer options.Delete()
-		ip = tflite.NewInterpreter(model, options)
-		status := ip.AllocateTensors()
+		var delegate delegates.Delegater
+		if opts.Delegate != "" {
+			factory, ok := delegateFactories[opts.Delegate]
+			if !ok {
+				return nil, fmt.Errorf("delegate %s is not supported, the plugin must be built with the tflite_%s tag", opts.Delegate, opts.Delegate)
+			}
+			delegate, err = factory(opts)
+			if err != nil {
+				return nil, fmt.Errorf("create delegate %s error: %v", opts.Delegate, err)
+			}
+			options.AddDelegate(delegate)
+			log.Infof("use delegate %s for model %s", opts.Delegate, name)
+		}
+		interpreter := tflite.NewInterpreter(model, options)
+		if interpreter == nil {
+			if delegate != nil {
+				delegate.Delete()
+			}
+			return nil, fmt.Errorf("cannot create interpreter for: %s", mf)
+		}
+		status := interpreter.AllocateTensors()
 		if status != tflite.OK {
 			log.Errorf("allocate tensors failed for: %s", mf)
-			ip.Delete()
+			interpreter.Delete()
+			if delegate != nil {
+				delegate.Delete()
+			}
 			return nil, fmt.Errorf("allocate failed: %v", status)
 		}
 		log.Infof("success allocate tensors for: %s", mf)
+		ip = &modelInterpreter{Interpreter: interpreter, delegate: delegate}
 		m.registry[name] = ip
 	}
 	return ip, nil
 }
+
+func (m *interpreterManager) readOptions(name string) (*modelOptions, error) {
+	opts := &modelOptions{NumThreads: 4}
+	content, err := os.ReadFile(filepath.Join(m.path, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return opts, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(content, opts); err != nil {
+		return nil, fmt.Errorf("invalid options file of model %s: %v", name, err)
+	}
+	if opts.NumThreads <= 0 {
+		opts.NumThreads = 4
+	}
+	return opts, nil
+}
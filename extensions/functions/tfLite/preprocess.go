@@ -0,0 +1,189 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build tflite
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"sort"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/nfnt/resize"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// imageTensor decodes the image and converts it to the input tensor of the vision models.
+// The image is resized to width*height and the tensor is in HWC layout with RGB channels.
+// Without mean and std, the result is the bytea of the raw pixels for the quantized models.
+// Otherwise, the result is an array of float (pixel - mean) / std for the float models.
+type imageTensor struct{}
+
+func (f *imageTensor) Validate(args []interface{}) error {
+	if len(args) != 3 && len(args) != 5 {
+		return fmt.Errorf("imageTensor function must have 3 or 5 parameters but got %d", len(args))
+	}
+	return nil
+}
+
+func (f *imageTensor) IsAggregate() bool {
+	return false
+}
+
+func (f *imageTensor) Exec(args []interface{}, _ api.FunctionContext) (interface{}, bool) {
+	data, ok := args[0].([]byte)
+	if !ok {
+		return fmt.Errorf("imageTensor function first parameter must be a bytea, but got %[1]T(%[1]v)", args[0]), false
+	}
+	width, err := cast.ToInt(args[1], cast.CONVERT_SAMEKIND)
+	if err != nil || width <= 0 {
+		return fmt.Errorf("imageTensor function width must be a positive integer, but got %v", args[1]), false
+	}
+	height, err := cast.ToInt(args[2], cast.CONVERT_SAMEKIND)
+	if err != nil || height <= 0 {
+		return fmt.Errorf("imageTensor function height must be a positive integer, but got %v", args[2]), false
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("image decode error: %v", err), false
+	}
+	pixels := imagePixels(resize.Resize(uint(width), uint(height), img, resize.Bilinear))
+	if len(args) == 3 {
+		return pixels, true
+	}
+	mean, err := cast.ToFloat64(args[3], cast.CONVERT_SAMEKIND)
+	if err != nil {
+		return fmt.Errorf("imageTensor function mean must be a number, but got %v", args[3]), false
+	}
+	std, err := cast.ToFloat64(args[4], cast.CONVERT_SAMEKIND)
+	if err != nil || std == 0 {
+		return fmt.Errorf("imageTensor function std must be a non-zero number, but got %v", args[4]), false
+	}
+	return normalizePixels(pixels, mean, std), true
+}
+
+// imagePixels returns the RGB values of each pixel in row major order
+func imagePixels(img image.Image) []byte {
+	bounds := img.Bounds()
+	dx, dy := bounds.Dx(), bounds.Dy()
+	result := make([]byte, dx*dy*3)
+	for y := 0; y < dy; y++ {
+		for x := 0; x < dx; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// RGBA returns 16 bits color
+			result[(y*dx+x)*3+0] = byte(r >> 8)
+			result[(y*dx+x)*3+1] = byte(g >> 8)
+			result[(y*dx+x)*3+2] = byte(b >> 8)
+		}
+	}
+	return result
+}
+
+func normalizePixels(pixels []byte, mean, std float64) []interface{} {
+	result := make([]interface{}, len(pixels))
+	for i, p := range pixels {
+		result[i] = float32((float64(p) - mean) / std)
+	}
+	return result
+}
+
+// topK returns the k items with the highest scores in descending order. It is used to post process the
+// classification output. Each item has the index and the score. If the labels are provided, the label of the index
+// is also returned.
+type topK struct{}
+
+func (f *topK) Validate(args []interface{}) error {
+	if len(args) != 2 && len(args) != 3 {
+		return fmt.Errorf("topK function must have 2 or 3 parameters but got %d", len(args))
+	}
+	return nil
+}
+
+func (f *topK) IsAggregate() bool {
+	return false
+}
+
+func (f *topK) Exec(args []interface{}, _ api.FunctionContext) (interface{}, bool) {
+	scores, err := toScores(args[0])
+	if err != nil {
+		return err, false
+	}
+	k, err := cast.ToInt(args[1], cast.CONVERT_SAMEKIND)
+	if err != nil || k <= 0 {
+		return fmt.Errorf("topK function k must be a positive integer, but got %v", args[1]), false
+	}
+	var labels []string
+	if len(args) == 3 {
+		labels, err = cast.ToStringSlice(args[2], cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("topK function labels must be an array of string, but got %[1]T(%[1]v)", args[2]), false
+		}
+	}
+	return topKScores(scores, k, labels), true
+}
+
+// toScores converts the typed output of the tfLite function or a generic array to float
+func toScores(arg interface{}) ([]float64, error) {
+	switch s := arg.(type) {
+	case []float32:
+		result := make([]float64, len(s))
+		for i, v := range s {
+			result[i] = float64(v)
+		}
+		return result, nil
+	case []uint8:
+		// quantized output
+		result := make([]float64, len(s))
+		for i, v := range s {
+			result[i] = float64(v) / 255
+		}
+		return result, nil
+	case []interface{}:
+		return cast.ToFloat64Slice(s, cast.CONVERT_SAMEKIND, cast.FORCE_CONVERT)
+	default:
+		return nil, fmt.Errorf("topK function scores must be an array of number, but got %[1]T(%[1]v)", arg)
+	}
+}
+
+func topKScores(scores []float64, k int, labels []string) []interface{} {
+	indexes := make([]int, len(scores))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+	if k > len(indexes) {
+		k = len(indexes)
+	}
+	result := make([]interface{}, k)
+	for i := 0; i < k; i++ {
+		idx := indexes[i]
+		item := map[string]interface{}{
+			"index": idx,
+			"score": scores[idx],
+		}
+		if idx < len(labels) {
+			item["label"] = labels[idx]
+		}
+		result[i] = item
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build tflite
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageTensor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.RGBA{R: 255, G: 127, B: 0, A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, img))
+	f := &imageTensor{}
+	r, ok := f.Exec([]interface{}{buf.Bytes(), 2, 1}, nil)
+	require.True(t, ok)
+	assert.Equal(t, []byte{255, 127, 0, 255, 127, 0}, r)
+	r, ok = f.Exec([]interface{}{buf.Bytes(), 1, 1, 127.5, 127.5}, nil)
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{float32(1), float32(-0.5 / 127.5), float32(-1)}, r)
+	r, ok = f.Exec([]interface{}{buf.Bytes(), 0, 1}, nil)
+	require.False(t, ok)
+	assert.EqualError(t, r.(error), "imageTensor function width must be a positive integer, but got 0")
+}
+
+func TestTopK(t *testing.T) {
+	f := &topK{}
+	r, ok := f.Exec([]interface{}{[]float32{0.1, 0.7, 0.2}, 2, []interface{}{"a", "b", "c"}}, nil)
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"index": 1, "score": float64(float32(0.7)), "label": "b"},
+		map[string]interface{}{"index": 2, "score": float64(float32(0.2)), "label": "c"},
+	}, r)
+	r, ok = f.Exec([]interface{}{[]interface{}{1, 3}, 5}, nil)
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"index": 1, "score": 3.0},
+		map[string]interface{}{"index": 0, "score": 1.0},
+	}, r)
+	r, ok = f.Exec([]interface{}{"a", 1}, nil)
+	require.False(t, ok)
+	assert.EqualError(t, r.(error), "topK function scores must be an array of number, but got string(a)")
+}
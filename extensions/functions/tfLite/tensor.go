@@ -0,0 +1,185 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build tflite
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattn/go-tflite"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// resize changes the batch dimension, the first dimension, of all the inputs and reallocates the tensors.
+// The model must be exported with a batch dimension of 1. Batch 1 restores the original shape.
+func (ip *modelInterpreter) resize(batch int) error {
+	if ip.batch == 0 {
+		ip.batch = 1
+	}
+	if ip.batch == batch {
+		return nil
+	}
+	for i := 0; i < ip.GetInputTensorCount(); i++ {
+		input := ip.GetInputTensor(i)
+		if input.NumDims() == 0 || (ip.batch == 1 && input.Dim(0) != 1) {
+			return fmt.Errorf("tensorflow model input tensor %d does not have a batch dimension", i)
+		}
+		dims := make([]int32, input.NumDims())
+		dims[0] = int32(batch)
+		for j := 1; j < input.NumDims(); j++ {
+			dims[j] = int32(input.Dim(j))
+		}
+		if status := ip.ResizeInputTensor(i, dims); status != tflite.OK {
+			return fmt.Errorf("resize input tensor %d to batch %d failed: %v", i, batch, status)
+		}
+	}
+	if status := ip.AllocateTensors(); status != tflite.OK {
+		return fmt.Errorf("allocate tensors for batch %d failed: %v", batch, status)
+	}
+	ip.batch = batch
+	return nil
+}
+
+// setInput copies the argument into the input tensor. The argument is a bytea or a one dimensional array in row major order.
+func setInput(input *tflite.Tensor, i int, a interface{}) error {
+	var arg []interface{}
+	switch v := a.(type) {
+	case []byte:
+		if int(input.ByteSize()) != len(v) {
+			return fmt.Errorf("tensorflow function input tensor %d has %d bytes but got %d", i-1, input.ByteSize(), len(v))
+		}
+		input.CopyFromBuffer(v)
+		return nil
+	case []interface{}: // only supports one dimensional arg. Even dim 0 must be an array of 1 element
+		arg = v
+	default:
+		return fmt.Errorf("tensorflow function parameter %d must be a bytea or array of bytea, but got %[2]T(%[2]v)", i, a)
+	}
+	paraLen := 1
+	for j := 0; j < input.NumDims(); j++ {
+		paraLen = paraLen * input.Dim(j)
+	}
+	if paraLen != len(arg) {
+		return fmt.Errorf("tensorflow function input tensor %d must have %d elements but got %d", i-1, paraLen, len(arg))
+	}
+	var status tflite.Status
+	switch input.Type() {
+	case tflite.Float32:
+		v, err := cast.ToFloat32Slice(arg, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("invalid %d parameter, expect float32 but got %[2]T(%[2]v) with err %v", i, a, err)
+		}
+		status = input.SetFloat32s(v)
+	case tflite.Int64:
+		v, err := cast.ToInt64Slice(arg, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("invalid %d parameter, expect int64 but got %[2]T(%[2]v) with err %v", i, a, err)
+		}
+		status = input.SetInt64s(v)
+	case tflite.Int32:
+		v, err := cast.ToTypedSlice(arg, func(input interface{}, sn cast.Strictness) (interface{}, error) {
+			return cast.ToInt32(input, sn)
+		}, "int32", cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("invalid %d parameter, expect int32 but got %[2]T(%[2]v) with err %v", i, a, err)
+		}
+		status = input.SetInt32s(v.([]int32))
+	case tflite.Int16:
+		v, err := cast.ToTypedSlice(arg, func(input interface{}, sn cast.Strictness) (interface{}, error) {
+			return cast.ToInt16(input, sn)
+		}, "int16", cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("invalid %d parameter, expect int16 but got %[2]T(%[2]v) with err %v", i, a, err)
+		}
+		status = input.SetInt16s(v.([]int16))
+	case tflite.Int8:
+		v, err := cast.ToTypedSlice(arg, func(input interface{}, sn cast.Strictness) (interface{}, error) {
+			return cast.ToInt8(input, sn)
+		}, "int8", cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("invalid %d parameter, expect int8 but got %[2]T(%[2]v) with err %v", i, a, err)
+		}
+		status = input.SetInt8s(v.([]int8))
+	case tflite.UInt8:
+		v, err := cast.ToTypedSlice(arg, func(input interface{}, sn cast.Strictness) (interface{}, error) {
+			return cast.ToUint8(input, sn)
+		}, "uint8", cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("invalid %d parameter, expect uint8 but got %[2]T(%[2]v) with err %v", i, a, err)
+		}
+		status = input.SetUint8s(v.([]uint8))
+	default:
+		return fmt.Errorf("invalid %d parameter, unsupported type %v in the model", i, input.Type())
+	}
+	if status != tflite.OK {
+		return fmt.Errorf("set input tensor %d failed: %v", i-1, status)
+	}
+	return nil
+}
+
+func getOutput(output *tflite.Tensor, i int) (interface{}, error) {
+	t := output.Type()
+	switch t {
+	case tflite.Float32:
+		return output.Float32s(), nil
+	case tflite.Int64:
+		return output.Int64s(), nil
+	case tflite.Int32:
+		return output.Int32s(), nil
+	case tflite.Int16:
+		return output.Int16s(), nil
+	case tflite.Int8:
+		return output.Int8s(), nil
+	case tflite.UInt8:
+		return output.UInt8s(), nil
+	default:
+		return nil, fmt.Errorf("invalid %d parameter, unsupported type %v in the model", i, t)
+	}
+}
+
+// splitOutput splits the output of a batch into the outputs of each sample
+func splitOutput(output interface{}, batch int) ([]interface{}, error) {
+	switch o := output.(type) {
+	case []float32:
+		return splitSlice(o, batch)
+	case []int64:
+		return splitSlice(o, batch)
+	case []int32:
+		return splitSlice(o, batch)
+	case []int16:
+		return splitSlice(o, batch)
+	case []int8:
+		return splitSlice(o, batch)
+	case []uint8:
+		return splitSlice(o, batch)
+	default:
+		return nil, fmt.Errorf("unsupported output type %T", output)
+	}
+}
+
+func splitSlice[T any](s []T, batch int) ([]interface{}, error) {
+	if batch <= 0 || len(s)%batch != 0 {
+		return nil, fmt.Errorf("output with %d elements cannot be split into batch %d", len(s), batch)
+	}
+	size := len(s) / batch
+	result := make([]interface{}, batch)
+	for i := 0; i < batch; i++ {
+		// copy because the output tensor buffer is reused by the next invoke
+		result[i] = append([]T(nil), s[i*size:(i+1)*size]...)
+	}
+	return result, nil
+}
@@ -21,8 +21,6 @@ import (
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 	"github.com/mattn/go-tflite"
-
-	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 )
 
 type Tffunc struct{}
@@ -56,93 +54,16 @@ func (f *Tffunc) Exec(args []interface{}, ctx api.FunctionContext) (interface{},
 	}
 
 	ctx.GetLogger().Debugf("tensorflow function %s with %d tensors", model, inputCount)
+	interpreter.Lock()
+	defer interpreter.Unlock()
+	// The batch function may have resized the input
+	if err := interpreter.resize(1); err != nil {
+		return err, false
+	}
 	// Set input tensors
 	for i := 1; i < len(args); i++ {
-		input := interpreter.GetInputTensor(i - 1)
-		var arg []interface{}
-		switch v := args[i].(type) {
-		case []byte:
-			if int(input.ByteSize()) != len(v) {
-				return fmt.Errorf("tensorflow function input tensor %d has %d bytes but got %d", i-1, input.ByteSize(), len(v)), false
-			}
-			input.CopyFromBuffer(v)
-			continue
-		case []interface{}: // only supports one dimensional arg. Even dim 0 must be an array of 1 element
-			arg = v
-		default:
-			return fmt.Errorf("tensorflow function parameter %d must be a bytea or array of bytea, but got %[1]T(%[1]v)", i), false
-		}
-		paraLen := 1
-		for j := 0; j < input.NumDims(); j++ {
-			paraLen = paraLen * input.Dim(j)
-		}
-		ctx.GetLogger().Debugf("receive tensor %v, require %d length", arg, paraLen)
-		if paraLen != len(arg) {
-			return fmt.Errorf("tensorflow function input tensor %d must have %d elements but got %d", i-1, paraLen, len(arg)), false
-		}
-		switch input.Type() {
-		case tflite.Float32:
-			v, err := cast.ToFloat32Slice(arg, cast.CONVERT_SAMEKIND)
-			if err != nil {
-				return fmt.Errorf("invalid %d parameter, expect float32 but got %[2]T(%[2]v) with err %v", i, args[i], err), false
-			}
-			err = input.SetFloat32s(v)
-			if err != nil {
-				return nil, false
-			}
-		case tflite.Int64:
-			v, err := cast.ToInt64Slice(arg, cast.CONVERT_SAMEKIND)
-			if err != nil {
-				return fmt.Errorf("invalid %d parameter, expect int64 but got %[2]T(%[2]v) with err %v", i, args[i], err), false
-			}
-			err = input.SetInt64s(v)
-			if err != nil {
-				return nil, false
-			}
-		case tflite.Int32:
-			v, err := cast.ToTypedSlice(args, func(input interface{}, sn cast.Strictness) (interface{}, error) {
-				return cast.ToInt32(input, sn)
-			}, "int32", cast.CONVERT_SAMEKIND)
-			if err != nil {
-				return fmt.Errorf("invalid %d parameter, expect int32 but got %[2]T(%[2]v) with err %v", i, args[i], err), false
-			}
-			err = input.SetInt32s(v.([]int32))
-			if err != nil {
-				return nil, false
-			}
-		case tflite.Int16:
-			v, err := cast.ToTypedSlice(args, func(input interface{}, sn cast.Strictness) (interface{}, error) {
-				return cast.ToInt16(input, sn)
-			}, "int16", cast.CONVERT_SAMEKIND)
-			if err != nil {
-				return fmt.Errorf("invalid %d parameter, expect int16 but got %[2]T(%[2]v) with err %v", i, args[i], err), false
-			}
-			err = input.SetInt16s(v.([]int16))
-			if err != nil {
-				return nil, false
-			}
-		case tflite.Int8:
-			v, err := cast.ToTypedSlice(args, func(input interface{}, sn cast.Strictness) (interface{}, error) {
-				return cast.ToInt8(input, sn)
-			}, "int8", cast.CONVERT_SAMEKIND)
-			if err != nil {
-				return fmt.Errorf("invalid %d parameter, expect int8 but got %[2]T(%[2]v) with err %v", i, args[i], err), false
-			}
-			err = input.SetInt8s(v.([]int8))
-			if err != nil {
-				return nil, false
-			}
-		case tflite.UInt8:
-			v, err := cast.ToBytes(args, cast.CONVERT_SAMEKIND)
-			if err != nil {
-				return fmt.Errorf("invalid %d parameter, expect uint8 but got %[2]T(%[2]v) with err %v", i, args[i], err), false
-			}
-			err = input.SetUint8s(v)
-			if err != nil {
-				return nil, false
-			}
-		default:
-			return fmt.Errorf("invalid %d parameter, unsupported type %v in the model", i, input.Type()), false
+		if err := setInput(interpreter.GetInputTensor(i-1), i, args[i]); err != nil {
+			return err, false
 		}
 	}
 	status := interpreter.Invoke()
@@ -152,33 +73,18 @@ func (f *Tffunc) Exec(args []interface{}, ctx api.FunctionContext) (interface{},
 	outputCount := interpreter.GetOutputTensorCount()
 	results := make([]interface{}, outputCount)
 	for i := 0; i < outputCount; i++ {
-		output := interpreter.GetOutputTensor(i)
-		//outputSize := output.Dim(output.NumDims() - 1)
-		//b := make([]byte, outputSize)
-		//status = output.CopyToBuffer(&b[0])
-		//if status != tflite.OK {
-		//	return fmt.Errorf("output failed"), false
-		//}
-		//results[i] = b
-		t := output.Type()
-		switch t {
-		case tflite.Float32:
-			results[i] = output.Float32s()
-		case tflite.Int64:
-			results[i] = output.Int64s()
-		case tflite.Int32:
-			results[i] = output.Int32s()
-		case tflite.Int16:
-			results[i] = output.Int16s()
-		case tflite.Int8:
-			results[i] = output.Int8s()
-		case tflite.UInt8:
-			results[i] = output.UInt8s()
-		default:
-			return fmt.Errorf("invalid %d parameter, unsupported type %v in the model", i, t), false
+		r, err := getOutput(interpreter.GetOutputTensor(i), i)
+		if err != nil {
+			return err, false
 		}
+		results[i] = r
 	}
 	return results, true
 }
 
-var TfLite Tffunc
+var (
+	TfLite      Tffunc
+	TfLiteBatch tfBatchFunc
+	ImageTensor imageTensor
+	TopK        topK
+)
@@ -94,3 +94,29 @@ func TestTffunc_Exec(t *testing.T) {
 		})
 	}
 }
+
+func TestTfLiteBatch(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testBatch")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+
+	f := &tfBatchFunc{}
+	got, ok := f.Exec([]interface{}{"xor_model", []interface{}{[]interface{}{1, 0}, []interface{}{1, 0}}}, fctx)
+	if !ok {
+		t.Fatalf("Exec() error %v", got)
+	}
+	want := []interface{}{[]interface{}{[]float32{0.9586827}}, []interface{}{[]float32{0.9586827}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Exec() got = %v, want %v", got, want)
+	}
+	// The single inference must restore the batch size
+	single, ok := (&Tffunc{}).Exec([]interface{}{"xor_model", []interface{}{1, 0}}, fctx)
+	if !ok || !reflect.DeepEqual(single, []interface{}{[]float32{0.9586827}}) {
+		t.Errorf("Exec() after batch got = %v", single)
+	}
+	got, ok = f.Exec([]interface{}{"xor_model", []interface{}{}}, fctx)
+	if !ok || !reflect.DeepEqual(got, []interface{}{}) {
+		t.Errorf("Exec() empty batch got = %v", got)
+	}
+}
@@ -133,6 +133,25 @@ func (m *interpreterManager) GetOrCreate(name string) (*InterPreter, error) {
 	return ip, nil
 }
 
+func (m *interpreterManager) isLoaded(name string) bool {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.registry[name]
+	return ok
+}
+
+// Unload removes the cached session of the model. The rules using it will load the model again in the next inference.
+func (m *interpreterManager) Unload(name string) {
+	m.Lock()
+	ip, ok := m.registry[name]
+	delete(m.registry, name)
+	m.Unlock()
+	if ok {
+		ip.destroy()
+		conf.Log.Infof("unload model: %s", name)
+	}
+}
+
 func getDefaultSharedLibPath() string {
 	// For now, we only include libraries for ARM64 darwin and x86_64 or ARM64 Linux. In the future, libraries may be added or removed.
 	if runtime.GOOS == "darwin" {
@@ -153,6 +172,8 @@ func getDefaultSharedLibPath() string {
 }
 
 type InterPreter struct {
+	// protect the session from being destroyed during running
+	sync.RWMutex
 	session    *ort.DynamicAdvancedSession
 	inputInfo  []ort.InputOutputInfo
 	outputInfo []ort.InputOutputInfo
@@ -169,6 +190,25 @@ func NewInterPreter(session *ort.DynamicAdvancedSession,
 	}
 }
 
+// Run the session. It returns error if the session has been unloaded.
+func (ip *InterPreter) Run(inputs, outputs []ort.ArbitraryTensor) error {
+	ip.RLock()
+	defer ip.RUnlock()
+	if ip.session == nil {
+		return errors.New("the model has been unloaded")
+	}
+	return ip.session.Run(inputs, outputs)
+}
+
+func (ip *InterPreter) destroy() {
+	ip.Lock()
+	defer ip.Unlock()
+	if ip.session != nil {
+		_ = ip.session.Destroy()
+		ip.session = nil
+	}
+}
+
 func (ip *InterPreter) GetInputTensorCount() int {
 	return len(ip.inputInfo)
 }
@@ -0,0 +1,144 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onnx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const modelExt = ".onnx"
+
+var modelNameReg = regexp.MustCompile(`^[A-Za-z0-9_\-.]+$`)
+
+// ModelInfo is the description of an uploaded model
+type ModelInfo struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+	// Loaded shows whether the model is cached in memory
+	Loaded bool `json:"loaded"`
+}
+
+func validateModelName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || !modelNameReg.MatchString(name) {
+		return fmt.Errorf("invalid model name %s, only letters, digits, '_', '-' and '.' are allowed", name)
+	}
+	return nil
+}
+
+// ListModels returns all the models in the model directory sorted by name
+func ListModels() ([]*ModelInfo, error) {
+	entries, err := os.ReadDir(ipManager.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*ModelInfo{}, nil
+		}
+		return nil, err
+	}
+	result := make([]*ModelInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != modelExt {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(e.Name(), modelExt)
+		result = append(result, &ModelInfo{
+			Name:       name,
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime(),
+			Loaded:     ipManager.isLoaded(name),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// GetModel returns the description of the model
+func GetModel(name string) (*ModelInfo, error) {
+	if err := validateModelName(name); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filepath.Join(ipManager.path, name+modelExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("model %s not found", name)
+		}
+		return nil, err
+	}
+	return &ModelInfo{
+		Name:       name,
+		Size:       info.Size(),
+		ModifiedAt: info.ModTime(),
+		Loaded:     ipManager.isLoaded(name),
+	}, nil
+}
+
+// SaveModel writes the model file. If the model exists, it is overwritten and the cached session is unloaded,
+// so the rules will load the new model in the next inference.
+func SaveModel(name string, r io.Reader) error {
+	if err := validateModelName(name); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ipManager.path, os.ModePerm); err != nil {
+		return err
+	}
+	// Write to a temp file and rename to avoid loading a partial model
+	tmp, err := os.CreateTemp(ipManager.path, name+"*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("write model %s error: %v", name, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(ipManager.path, name+modelExt)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	ipManager.Unload(name)
+	return nil
+}
+
+// DeleteModel removes the model file and unloads its session
+func DeleteModel(name string) error {
+	if err := validateModelName(name); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(ipManager.path, name+modelExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("model %s not found", name)
+		}
+		return err
+	}
+	ipManager.Unload(name)
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onnx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelManagement(t *testing.T) {
+	origin := ipManager.path
+	ipManager.path = t.TempDir()
+	defer func() {
+		ipManager.path = origin
+	}()
+
+	models, err := ListModels()
+	require.NoError(t, err)
+	assert.Len(t, models, 0)
+
+	require.NoError(t, SaveModel("b", strings.NewReader("model b")))
+	require.NoError(t, SaveModel("a", strings.NewReader("model a v1")))
+	// overwrite
+	require.NoError(t, SaveModel("a", strings.NewReader("model a")))
+	models, err = ListModels()
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, "a", models[0].Name)
+	assert.Equal(t, int64(7), models[0].Size)
+	assert.False(t, models[0].Loaded)
+	assert.Equal(t, "b", models[1].Name)
+
+	m, err := GetModel("b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), m.Size)
+
+	require.NoError(t, DeleteModel("b"))
+	_, err = GetModel("b")
+	assert.EqualError(t, err, "model b not found")
+	assert.EqualError(t, DeleteModel("b"), "model b not found")
+
+	assert.EqualError(t, SaveModel("../a", strings.NewReader("")), "invalid model name ../a, only letters, digits, '_', '-' and '.' are allowed")
+	assert.EqualError(t, DeleteModel(""), "invalid model name , only letters, digits, '_', '-' and '.' are allowed")
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/x448/float16"
 	ort "github.com/yalue/onnxruntime_go"
 
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 )
 
@@ -36,10 +37,21 @@ type OnnxFunc struct{}
 // Validate the arguments.
 // args[0]: string, model name which maps to a path
 // args[1 to n]: tensors
+// If the model name is a literal, the model is loaded during validation. Thus, the model is warmed up when the rule is
+// created and an invalid model is reported early.
 func (f *OnnxFunc) Validate(args []interface{}) error {
 	if len(args) < 2 {
 		return fmt.Errorf("onnx function must have at least 2 parameters but got %d", len(args))
 	}
+	if sl, ok := args[0].(*ast.StringLiteral); ok {
+		ip, err := ipManager.GetOrCreate(sl.Val)
+		if err != nil {
+			return err
+		}
+		if len(args)-1 != ip.GetInputTensorCount() {
+			return fmt.Errorf("onnx function requires %d tensors but got %d", ip.GetInputTensorCount(), len(args)-1)
+		}
+	}
 	return nil
 }
 
@@ -265,7 +277,7 @@ func (f *OnnxFunc) Exec(ctx api.FunctionContext, args []any) (any, bool) {
 		return err, false
 	}
 
-	err = interpreter.session.Run(inputTensors, outputArbitraryTensors)
+	err = interpreter.Run(inputTensors, outputArbitraryTensors)
 	if err != nil {
 		return fmt.Errorf("run failed,err:%w", err), false
 	}
@@ -174,7 +174,11 @@ func (g defaultSQLGen) buildQuery(fields []string, keys []string, values []inter
 			query += f
 		}
 	}
-	query += fmt.Sprintf(" FROM %s WHERE ", g.table)
+	query += fmt.Sprintf(" FROM %s", g.table)
+	// No keys to query all the rows such as preloading the cache
+	if len(keys) > 0 {
+		query += " WHERE "
+	}
 	for i, k := range keys {
 		if i > 0 {
 			query += " AND "
@@ -203,7 +207,11 @@ func (g noQuoteSQLGen) buildQuery(fields []string, keys []string, values []inter
 			query += f
 		}
 	}
-	query += fmt.Sprintf(" FROM %s WHERE ", g.table)
+	query += fmt.Sprintf(" FROM %s", g.table)
+	// No keys to query all the rows such as preloading the cache
+	if len(keys) > 0 {
+		query += " WHERE "
+	}
 	for i, k := range keys {
 		if i > 0 {
 			query += " AND "
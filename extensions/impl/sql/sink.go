@@ -39,6 +39,10 @@ type SQLSinkConnector struct {
 	conn          *client.SQLConnection
 	props         map[string]any
 	needReconnect bool
+	// transactional is set for the exactly once rule. Then the statements are held in txn until the checkpoint completes.
+	transactional bool
+	txn           []string
+	txnTableReady bool
 }
 
 type sqlSinkConfig struct {
@@ -47,6 +51,9 @@ type sqlSinkConfig struct {
 	Fields       []string `json:"fields"`
 	RowKindField string   `json:"rowKindField"`
 	KeyField     string   `json:"keyField"`
+	TxnTable     string   `json:"txnTable"`
+	// TxnMaxStatements is the max number of statements held in the transaction of a checkpoint
+	TxnMaxStatements int `json:"txnMaxStatements"`
 }
 
 func (c *sqlSinkConfig) buildInsertSql(ctx api.StreamContext, mapData map[string]interface{}) ([]string, string, error) {
@@ -108,7 +115,7 @@ func (s *SQLSinkConnector) Provision(ctx api.StreamContext, configs map[string]a
 	if err != nil {
 		return err
 	}
-	c := &sqlSinkConfig{SQLConf: sc}
+	c := &sqlSinkConfig{SQLConf: sc, TxnTable: defaultTxnTable, TxnMaxStatements: defaultTxnMaxStatements}
 	err = cast.MapToStruct(configs, c)
 	if err != nil {
 		return err
@@ -123,6 +130,9 @@ func (s *SQLSinkConnector) Provision(ctx api.StreamContext, configs map[string]a
 	if c.RowKindField != "" && c.KeyField == "" {
 		return fmt.Errorf("keyField is required when rowKindField is set")
 	}
+	if c.TxnMaxStatements <= 0 {
+		return fmt.Errorf("txnMaxStatements must be positive")
+	}
 	s.config = c
 	s.props = configs
 	return nil
@@ -281,6 +291,13 @@ func (s *SQLSinkConnector) save(ctx api.StreamContext, table string, data map[st
 }
 
 func (s *SQLSinkConnector) writeToDB(ctx api.StreamContext, sqlStr string) error {
+	if s.transactional {
+		if len(s.txn) >= s.config.TxnMaxStatements {
+			return fmt.Errorf("the transaction of the checkpoint reaches the max %d statements, decrease the checkpointInterval or increase the txnMaxStatements", s.config.TxnMaxStatements)
+		}
+		s.txn = append(s.txn, sqlStr)
+		return nil
+	}
 	ctx.GetLogger().Debugf(sqlStr)
 	if s.needReconnect {
 		SQLCounter.WithLabelValues(LblReconn, metrics.LblSinkIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
@@ -331,3 +331,61 @@ func TestSQLSinkReconnect(t *testing.T) {
 	}))
 	require.False(t, sqlSink.needReconnect)
 }
+
+func TestSQLSinkTransaction(t *testing.T) {
+	connection.InitConnectionManager4Test()
+	ctx := mockContext.NewMockContext("1", "2")
+	s, err := testx.SetupEmbeddedMysqlServer(address, port)
+	require.NoError(t, err)
+	defer func() {
+		s.Close()
+	}()
+	dburl := fmt.Sprintf("mysql://root:@%v:%v/test", address, port)
+	sqlSink := &SQLSinkConnector{}
+	require.NoError(t, sqlSink.Provision(ctx, map[string]interface{}{
+		"dburl":  dburl,
+		"table":  "t",
+		"fields": []string{"a", "b"},
+	}))
+	sqlSink.SetTransactional()
+	require.NoError(t, sqlSink.Connect(ctx, func(status string, message string) {
+		// do nothing
+	}))
+	count := func() int {
+		var c int
+		require.NoError(t, sqlSink.conn.GetDB().QueryRow("select count(*) from t where a = 21").Scan(&c))
+		return c
+	}
+	require.NoError(t, sqlSink.collect(ctx, map[string]any{"a": 21, "b": 1}))
+	require.NoError(t, sqlSink.collectList(ctx, []map[string]any{{"a": 21, "b": 2}, {"a": 21, "b": 3}}))
+	// Nothing is written before the commit
+	require.Equal(t, 0, count())
+	txn, err := sqlSink.PreCommit(ctx, 5)
+	require.NoError(t, err)
+	require.Len(t, txn, 2)
+	require.NoError(t, sqlSink.Commit(ctx, 5, txn))
+	require.Equal(t, 3, count())
+	// Commit again after recovery is skipped
+	require.NoError(t, sqlSink.Commit(ctx, 5, txn))
+	require.Equal(t, 3, count())
+	var last int64
+	require.NoError(t, sqlSink.conn.GetDB().QueryRow("select checkpoint_id from ekuiper_txn where sink_id = '1/2'").Scan(&last))
+	require.Equal(t, int64(5), last)
+
+	txn, err = sqlSink.PreCommit(ctx, 6)
+	require.NoError(t, err)
+	require.Nil(t, txn)
+	require.NoError(t, sqlSink.collect(ctx, map[string]any{"a": 21, "b": 4}))
+	txn, err = sqlSink.PreCommit(ctx, 7)
+	require.NoError(t, err)
+	require.NoError(t, sqlSink.Commit(ctx, 7, txn))
+	require.Equal(t, 4, count())
+	// The statements beyond the max are rejected
+	sqlSink.config.TxnMaxStatements = 1
+	require.NoError(t, sqlSink.collect(ctx, map[string]any{"a": 21, "b": 5}))
+	require.EqualError(t, sqlSink.collect(ctx, map[string]any{"a": 21, "b": 6}), "the transaction of the checkpoint reaches the max 1 statements, decrease the checkpointInterval or increase the txnMaxStatements")
+	txn, err = sqlSink.PreCommit(ctx, 8)
+	require.NoError(t, err)
+	require.Len(t, txn, 1)
+	require.NoError(t, sqlSink.Close(ctx))
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/metrics"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// defaultTxnTable is the table to record the last committed checkpoint of each sink
+const defaultTxnTable = "ekuiper_txn"
+
+// defaultTxnMaxStatements is the default max number of statements held in the transaction of a checkpoint
+const defaultTxnMaxStatements = 100000
+
+func (s *SQLSinkConnector) SetTransactional() {
+	s.transactional = true
+}
+
+func (s *SQLSinkConnector) PreCommit(_ api.StreamContext, _ int64) (any, error) {
+	if len(s.txn) == 0 {
+		return nil, nil
+	}
+	txn := s.txn
+	s.txn = nil
+	return txn, nil
+}
+
+// Commit runs the statements of the checkpoint in a database transaction. The last committed checkpoint of the sink is
+// updated in the txnTable in the same transaction, so that the commit of a committed checkpoint is skipped.
+func (s *SQLSinkConnector) Commit(ctx api.StreamContext, checkpointId int64, txn any) (err error) {
+	stmts, ok := txn.([]string)
+	if !ok {
+		return fmt.Errorf("invalid sql transaction %T", txn)
+	}
+	if s.needReconnect {
+		SQLCounter.WithLabelValues(LblReconn, metrics.LblSinkIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
+		if err := s.conn.Reconnect(); err != nil {
+			return errorx.NewIOErr(err.Error())
+		}
+		s.needReconnect = false
+	}
+	db := s.conn.GetDB()
+	table := s.config.TxnTable
+	if !s.txnTableReady {
+		// Create the table manually if the database does not support IF NOT EXISTS
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (sink_id VARCHAR(255) PRIMARY KEY, checkpoint_id BIGINT)", table)); err != nil {
+			ctx.GetLogger().Warnf("create transaction table %s error: %v", table, err)
+		}
+		s.txnTableReady = true
+	}
+	sinkId := strings.ReplaceAll(ctx.GetRuleId()+"/"+ctx.GetOpId(), "'", "''")
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		s.needReconnect = true
+		return errorx.NewIOErr(err.Error())
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var last int64
+	exists := true
+	err = tx.QueryRowContext(ctx, fmt.Sprintf("SELECT checkpoint_id FROM %s WHERE sink_id = '%s'", table, sinkId)).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		exists = false
+	} else if err != nil {
+		return fmt.Errorf("read transaction table %s error: %v", table, err)
+	}
+	if exists && last >= checkpointId {
+		ctx.GetLogger().Infof("skip the committed transaction of checkpoint %d", checkpointId)
+		return tx.Rollback()
+	}
+	for _, stmt := range stmts {
+		ctx.GetLogger().Debugf(stmt)
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if exists {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET checkpoint_id = %d WHERE sink_id = '%s'", table, checkpointId, sinkId))
+	} else {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (sink_id, checkpoint_id) values ('%s', %d)", table, sinkId, checkpointId))
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+var _ model.TwoPhaseCommit = &SQLSinkConnector{}
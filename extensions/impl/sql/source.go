@@ -32,6 +32,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/metrics"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
@@ -43,6 +44,8 @@ type SQLSourceConnector struct {
 	props         map[string]any
 	needReconnect bool
 	conId         string
+	runOnce       bool
+	eof           api.EOFIngest
 }
 
 func (s *SQLSourceConnector) Ping(ctx api.StreamContext, m map[string]any) error {
@@ -129,10 +132,22 @@ func (s *SQLSourceConnector) Close(ctx api.StreamContext) error {
 
 func (s *SQLSourceConnector) Pull(ctx api.StreamContext, recvTime time.Time, ingest api.TupleIngest, ingestError api.ErrorIngest) {
 	SQLCounter.WithLabelValues(LblRequest, metrics.LblSourceIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
-	s.queryData(ctx, recvTime, ingest, ingestError)
+	err := s.queryData(ctx, recvTime, ingest, ingestError)
+	// Only read the query result once in the batch mode. Retry in the next pull if failed.
+	if err == nil && s.runOnce && s.eof != nil {
+		s.eof(ctx)
+	}
+}
+
+func (s *SQLSourceConnector) SetEofIngest(eof api.EOFIngest) {
+	s.eof = eof
+}
+
+func (s *SQLSourceConnector) SetRunOnce() {
+	s.runOnce = true
 }
 
-func (s *SQLSourceConnector) queryData(ctx api.StreamContext, rcvTime time.Time, ingest api.TupleIngest, ingestError api.ErrorIngest) {
+func (s *SQLSourceConnector) queryData(ctx api.StreamContext, rcvTime time.Time, ingest api.TupleIngest, ingestError api.ErrorIngest) error {
 	logger := ctx.GetLogger()
 	if s.needReconnect {
 		SQLCounter.WithLabelValues(LblReconn, metrics.LblSourceIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
@@ -141,7 +156,7 @@ func (s *SQLSourceConnector) queryData(ctx api.StreamContext, rcvTime time.Time,
 			logger.Errorf("reconnect db error %v", err)
 			ingestError(ctx, err)
 			SQLCounter.WithLabelValues(LblException, metrics.LblSourceIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
-			return
+			return err
 		}
 	}
 	query, err := s.Query.SqlQueryStatement()
@@ -152,7 +167,7 @@ func (s *SQLSourceConnector) queryData(ctx api.StreamContext, rcvTime time.Time,
 		logger.Errorf("Get sql query error %v", err)
 		ingestError(ctx, err)
 		SQLCounter.WithLabelValues(LblException, metrics.LblSourceIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
-		return
+		return err
 	}
 	logger.Debugf("Query the database with %s", query)
 	start := time.Now()
@@ -166,7 +181,7 @@ func (s *SQLSourceConnector) queryData(ctx api.StreamContext, rcvTime time.Time,
 		s.needReconnect = true
 		ingestError(ctx, err)
 		SQLCounter.WithLabelValues(LblException, metrics.LblSourceIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
-		return
+		return err
 	} else if s.needReconnect {
 		s.needReconnect = false
 	}
@@ -179,7 +194,7 @@ func (s *SQLSourceConnector) queryData(ctx api.StreamContext, rcvTime time.Time,
 		logger.Errorf("query %v row ColumnTypes error %v", query, err)
 		ingestError(ctx, err)
 		SQLCounter.WithLabelValues(LblException, metrics.LblSourceIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
-		return
+		return err
 	}
 	for rows.Next() {
 		data := make(map[string]interface{})
@@ -193,12 +208,13 @@ func (s *SQLSourceConnector) queryData(ctx api.StreamContext, rcvTime time.Time,
 			logger.Errorf("Run sql scan(%s) error %v", query, err)
 			ingestError(ctx, err)
 			SQLCounter.WithLabelValues(LblException, metrics.LblSourceIO, ctx.GetRuleId(), ctx.GetOpId()).Inc()
-			return
+			return err
 		}
 		scanIntoMap(data, columns, cols)
 		s.Query.UpdateMaxIndexValue(data)
 		ingest(ctx, data, nil, rcvTime)
 	}
+	return nil
 }
 
 func (s *SQLSourceConnector) GetOffset() (interface{}, error) {
@@ -261,6 +277,7 @@ func GetSource() api.Source {
 var (
 	_ api.PullTupleSource = &SQLSourceConnector{}
 	_ util.PingableConn   = &SQLSourceConnector{}
+	_ model.RunOnce       = &SQLSourceConnector{}
 )
 
 func (sc *SQLConf) resolveDBURL(props map[string]any) (map[string]any, error) {
@@ -0,0 +1,203 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// maxCachedCastTypes is the max number of the parsed cast types kept in the cache
+const maxCachedCastTypes = 256
+
+// castTypes caches the parsed complex cast types like `struct(id bigint, tags array(string))`
+var castTypes = newLruCache[ast.FieldType](maxCachedCastTypes)
+
+// isComplexCastType returns whether the cast type is an array or struct
+func isComplexCastType(t string) bool {
+	return strings.ContainsRune(t, '(')
+}
+
+// getCastType parses the complex cast type which uses the same syntax as the stream schema
+func getCastType(t string) (ast.FieldType, error) {
+	if ft, ok := castTypes.get(t); ok {
+		return ft, nil
+	}
+	p := &castTypeParser{input: t}
+	ft, err := p.parseType()
+	if err != nil {
+		return nil, fmt.Errorf("invalid cast type %s: %v", t, err)
+	}
+	if tok := p.next(); tok != "" {
+		return nil, fmt.Errorf("invalid cast type %s: unexpected %q at the end", t, tok)
+	}
+	castTypes.set(t, ft)
+	return ft, nil
+}
+
+type castTypeParser struct {
+	input string
+	pos   int
+}
+
+// next returns the next token which is an identifier or one of the delimiters `(`, `)` and `,`
+func (p *castTypeParser) next() string {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		return ""
+	}
+	start := p.pos
+	switch p.input[p.pos] {
+	case '(', ')', ',':
+		p.pos++
+		return p.input[start:p.pos]
+	}
+	for p.pos < len(p.input) && !unicode.IsSpace(rune(p.input[p.pos])) && !strings.ContainsRune("(),", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *castTypeParser) expect(tok string) error {
+	if n := p.next(); n != tok {
+		return fmt.Errorf("expect %q but found %q", tok, n)
+	}
+	return nil
+}
+
+func (p *castTypeParser) parseType() (ast.FieldType, error) {
+	tok := p.next()
+	t := ast.GetDataType(tok)
+	switch {
+	case t == ast.UNKNOWN:
+		return nil, fmt.Errorf("unknown type %q", tok)
+	case t.IsSimpleType():
+		return &ast.BasicType{Type: t}, nil
+	case t == ast.ARRAY:
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		et, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		switch ett := et.(type) {
+		case *ast.BasicType:
+			return &ast.ArrayType{Type: ett.Type}, nil
+		case *ast.ArrayType:
+			return &ast.ArrayType{Type: ast.ARRAY, FieldType: ett}, nil
+		default:
+			return &ast.ArrayType{Type: ast.STRUCT, FieldType: ett}, nil
+		}
+	default: // struct
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		rt := &ast.RecType{}
+		for {
+			name := p.next()
+			if name == "" || strings.ContainsAny(name, "(),") {
+				return nil, fmt.Errorf("expect struct field name but found %q", name)
+			}
+			ft, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			rt.StreamFields = append(rt.StreamFields, ast.StreamField{Name: name, FieldType: ft})
+			if sep := p.next(); sep == ")" {
+				return rt, nil
+			} else if sep != "," {
+				return nil, fmt.Errorf("expect \",\" or \")\" but found %q", sep)
+			}
+		}
+	}
+}
+
+// castToFieldType converts the value to the complex type recursively. For struct, only the declared fields are kept.
+func castToFieldType(v interface{}, ft ast.FieldType) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch t := ft.(type) {
+	case *ast.BasicType:
+		r, ok := cast.ToType(v, t.Type.String())
+		if !ok {
+			return nil, r.(error)
+		}
+		return r, nil
+	case *ast.ArrayType:
+		if s, ok := v.(string); ok {
+			var arr []interface{}
+			if err := json.Unmarshal([]byte(s), &arr); err != nil {
+				return nil, fmt.Errorf("cannot cast %s to array", s)
+			}
+			v = arr
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot cast %[1]T(%[1]v) to array", v)
+		}
+		et := t.FieldType
+		if et == nil {
+			et = &ast.BasicType{Type: t.Type}
+		}
+		result := make([]interface{}, len(arr))
+		for i, e := range arr {
+			r, err := castToFieldType(e, et)
+			if err != nil {
+				return nil, fmt.Errorf("array element %d: %v", i, err)
+			}
+			result[i] = r
+		}
+		return result, nil
+	case *ast.RecType:
+		if s, ok := v.(string); ok {
+			var m map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &m); err != nil {
+				return nil, fmt.Errorf("cannot cast %s to struct", s)
+			}
+			v = m
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot cast %[1]T(%[1]v) to struct", v)
+		}
+		result := make(map[string]interface{}, len(t.StreamFields))
+		for _, sf := range t.StreamFields {
+			fv, ok := m[sf.Name]
+			if !ok {
+				continue
+			}
+			r, err := castToFieldType(fv, sf.FieldType)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", sf.Name, err)
+			}
+			result[sf.Name] = r
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unsupported cast type %T", ft)
+	}
+}
@@ -16,6 +16,8 @@ package function
 
 import (
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 )
@@ -270,3 +272,159 @@ func dedup(r []interface{}, col []interface{}, all bool) (interface{}, error) {
 		return result, nil
 	}
 }
+
+// topN returns at most n rows ordered by the values in col. Rows with nil value are ignored.
+// Only the top n candidates are kept during the scan, so the order is stable for the equal values.
+func topN(r []interface{}, col []interface{}, n int, ascending bool) ([]interface{}, error) {
+	if len(r) != len(col) {
+		return nil, fmt.Errorf("the rows and the sort values have different length %d and %d", len(r), len(col))
+	}
+	idx := make([]int, 0, n)
+	for i, v := range col {
+		if v == nil {
+			continue
+		}
+		// find the insert position from the tail
+		pos := len(idx)
+		for pos > 0 {
+			c, err := compareValue(v, col[idx[pos-1]])
+			if err != nil {
+				return nil, err
+			}
+			if (ascending && c >= 0) || (!ascending && c <= 0) {
+				break
+			}
+			pos--
+		}
+		if pos >= n {
+			continue
+		}
+		if len(idx) < n {
+			idx = append(idx, 0)
+		}
+		copy(idx[pos+1:], idx[pos:len(idx)-1])
+		idx[pos] = i
+	}
+	result := make([]interface{}, len(idx))
+	for i, j := range idx {
+		result[i] = r[j]
+	}
+	return result, nil
+}
+
+// compareValue returns -1, 0 or 1 if a is less than, equal to or greater than b
+func compareValue(a, b interface{}) (int, error) {
+	switch at := a.(type) {
+	case string:
+		if bt, ok := b.(string); ok {
+			switch {
+			case at < bt:
+				return -1, nil
+			case at > bt:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	case time.Time:
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt), nil
+		}
+	case bool:
+		if bt, ok := b.(bool); ok {
+			switch {
+			case at == bt:
+				return 0, nil
+			case bt:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	default:
+		af, err1 := cast.ToFloat64(a, cast.CONVERT_SAMEKIND)
+		bf, err2 := cast.ToFloat64(b, cast.CONVERT_SAMEKIND)
+		if err1 == nil && err2 == nil {
+			switch {
+			case af < bf:
+				return -1, nil
+			case af > bf:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %[1]T(%[1]v) with %[2]T(%[2]v)", a, b)
+}
+
+// toFloatPairs converts the two columns to float slices. The rows with nil in any column are ignored.
+func toFloatPairs(args []interface{}) ([]float64, []float64, error) {
+	c1, ok1 := args[0].([]interface{})
+	c2, ok2 := args[1].([]interface{})
+	if !ok1 || !ok2 || len(c1) != len(c2) {
+		return nil, nil, fmt.Errorf("Invalid argument type found.")
+	}
+	xs := make([]float64, 0, len(c1))
+	ys := make([]float64, 0, len(c2))
+	for i := range c1 {
+		if c1[i] == nil || c2[i] == nil {
+			continue
+		}
+		x, err := cast.ToFloat64(c1[i], cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, nil, fmt.Errorf("requires number but found %[1]T(%[1]v)", c1[i])
+		}
+		y, err := cast.ToFloat64(c2[i], cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, nil, fmt.Errorf("requires number but found %[1]T(%[1]v)", c2[i])
+		}
+		xs = append(xs, x)
+		ys = append(ys, y)
+	}
+	return xs, ys, nil
+}
+
+func mean(fs []float64) float64 {
+	sum := 0.0
+	for _, f := range fs {
+		sum += f
+	}
+	return sum / float64(len(fs))
+}
+
+// covariance returns the population or sample covariance, or nil if there are not enough values
+func covariance(xs, ys []float64, sample bool) interface{} {
+	n := len(xs)
+	if n == 0 || (sample && n < 2) {
+		return nil
+	}
+	mx, my := mean(xs), mean(ys)
+	sum := 0.0
+	for i := range xs {
+		sum += (xs[i] - mx) * (ys[i] - my)
+	}
+	if sample {
+		return sum / float64(n-1)
+	}
+	return sum / float64(n)
+}
+
+// correlation returns the pearson correlation coefficient, or nil if any column has no variance
+func correlation(xs, ys []float64) interface{} {
+	if len(xs) < 2 {
+		return nil
+	}
+	mx, my := mean(xs), mean(ys)
+	var sxy, sxx, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return nil
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
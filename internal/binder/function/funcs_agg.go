@@ -267,6 +267,42 @@ func registerAggFunc() {
 		val:   ValidateOneNumberArg,
 		check: returnNilIfHasAnyNil,
 	}
+	builtins["covar_pop"] = builtinFunc{
+		fType: ast.FuncTypeAgg,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			xs, ys, err := toFloatPairs(args)
+			if err != nil {
+				return err, false
+			}
+			return covariance(xs, ys, false), true
+		},
+		val:   ValidateTwoNumberArg,
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["covar_samp"] = builtinFunc{
+		fType: ast.FuncTypeAgg,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			xs, ys, err := toFloatPairs(args)
+			if err != nil {
+				return err, false
+			}
+			return covariance(xs, ys, true), true
+		},
+		val:   ValidateTwoNumberArg,
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["corr"] = builtinFunc{
+		fType: ast.FuncTypeAgg,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			xs, ys, err := toFloatPairs(args)
+			if err != nil {
+				return err, false
+			}
+			return correlation(xs, ys), true
+		},
+		val:   ValidateTwoNumberArg,
+		check: returnNilIfHasAnyNil,
+	}
 	builtins["percentile_cont"] = builtinFunc{
 		fType: ast.FuncTypeAgg,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
@@ -378,4 +414,81 @@ func registerAggFunc() {
 		},
 		check: returnNilIfHasAnyNil,
 	}
+	builtins["top_n"] = builtinFunc{
+		fType: ast.FuncTypeAgg,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			rows, ok1 := args[0].([]interface{})
+			col, ok2 := args[1].([]interface{})
+			na, ok3 := args[2].([]interface{})
+			if !ok1 || !ok2 || !ok3 {
+				return fmt.Errorf("Invalid argument type found."), false
+			}
+			if len(na) == 0 {
+				return make([]interface{}, 0), true
+			}
+			n, err := cast.ToInt(getFirstValidArg(na), cast.STRICT)
+			if err != nil {
+				return fmt.Errorf("the second parameter requires int but found %[1]T(%[1]v)", getFirstValidArg(na)), false
+			}
+			ascending := false
+			if len(args) > 3 {
+				if aa, ok := args[3].([]interface{}); ok && len(aa) > 0 {
+					ascending, ok = getFirstValidArg(aa).(bool)
+					if !ok {
+						return fmt.Errorf("the third parameter requires bool but found %[1]T(%[1]v)", getFirstValidArg(aa)), false
+					}
+				}
+			}
+			r, err := topN(rows, col, n, ascending)
+			if err != nil {
+				return err, false
+			}
+			return r, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateAtLeast(2, len(args)); err != nil {
+				return err
+			}
+			if len(args) > 3 {
+				return fmt.Errorf("Expect at most 3 arguments but found %d.", len(args))
+			}
+			if !ast.IsIntegerArg(args[1]) {
+				return ProduceErrInfo(1, "int")
+			}
+			if args[1].(*ast.IntegerLiteral).Val <= 0 {
+				return fmt.Errorf("the second parameter should be a positive integer")
+			}
+			if len(args) == 3 && !ast.IsBooleanArg(args[2]) {
+				return ProduceErrInfo(2, "bool")
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["pivot"] = builtinFunc{
+		fType: ast.FuncTypeAgg,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			keys, ok1 := args[0].([]interface{})
+			values, ok2 := args[1].([]interface{})
+			if !ok1 || !ok2 || len(keys) != len(values) {
+				return fmt.Errorf("Invalid argument type found."), false
+			}
+			result := make(map[string]interface{}, len(keys))
+			// the later value of the same key overrides the former one
+			for i, k := range keys {
+				switch k.(type) {
+				case nil:
+					continue
+				case map[string]interface{}, []interface{}:
+					return fmt.Errorf("the first parameter requires string but found %[1]T(%[1]v)", k), false
+				}
+				result[cast.ToStringAlways(k)] = values[i]
+			}
+			return result, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return ValidateLen(2, len(args))
+		},
+		check: returnNilIfHasAnyNil,
+	}
 }
@@ -16,6 +16,7 @@ package function
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"testing"
 
@@ -667,3 +668,210 @@ func TestLastValueValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestTopN(t *testing.T) {
+	f, ok := builtins["top_n"]
+	require.True(t, ok)
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	rows := []interface{}{
+		map[string]interface{}{"id": 1, "temp": 20.5},
+		map[string]interface{}{"id": 2, "temp": 30},
+		map[string]interface{}{"id": 3, "temp": nil},
+		map[string]interface{}{"id": 4, "temp": 10},
+		map[string]interface{}{"id": 5, "temp": 30},
+	}
+	col := []interface{}{20.5, 30, nil, 10, 30}
+	tests := []struct {
+		name   string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "desc",
+			args:   []interface{}{rows, col, []interface{}{2, 2, 2, 2, 2}},
+			result: []interface{}{rows[1], rows[4]},
+		},
+		{
+			name:   "asc",
+			args:   []interface{}{rows, col, []interface{}{2, 2, 2, 2, 2}, []interface{}{true, true, true, true, true}},
+			result: []interface{}{rows[3], rows[0]},
+		},
+		{
+			name:   "n larger than rows",
+			args:   []interface{}{rows, col, []interface{}{10, 10, 10, 10, 10}},
+			result: []interface{}{rows[1], rows[4], rows[0], rows[3]},
+		},
+		{
+			name:   "empty",
+			args:   []interface{}{[]interface{}{}, []interface{}{}, []interface{}{}},
+			result: []interface{}{},
+		},
+		{
+			name:   "incomparable",
+			args:   []interface{}{rows[:2], []interface{}{"a", 1}, []interface{}{2, 2}},
+			result: fmt.Errorf("cannot compare int(1) with string(a)"),
+		},
+		{
+			name:   "invalid order",
+			args:   []interface{}{rows[:1], col[:1], []interface{}{2}, []interface{}{"asc"}},
+			result: fmt.Errorf("the third parameter requires bool but found string(asc)"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := f.exec(fctx, tt.args)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestTopNValidation(t *testing.T) {
+	f, ok := builtins["top_n"]
+	require.True(t, ok)
+	tests := []struct {
+		args []ast.Expr
+		err  error
+	}{
+		{
+			args: []ast.Expr{&ast.FieldRef{Name: "temp"}},
+			err:  fmt.Errorf("At least has 2 argument but found 1."),
+		},
+		{
+			args: []ast.Expr{&ast.FieldRef{Name: "temp"}, &ast.StringLiteral{Val: "3"}},
+			err:  fmt.Errorf("Expect int type for parameter 2"),
+		},
+		{
+			args: []ast.Expr{&ast.FieldRef{Name: "temp"}, &ast.IntegerLiteral{Val: 0}},
+			err:  fmt.Errorf("the second parameter should be a positive integer"),
+		},
+		{
+			args: []ast.Expr{&ast.FieldRef{Name: "temp"}, &ast.IntegerLiteral{Val: 3}, &ast.StringLiteral{Val: "asc"}},
+			err:  fmt.Errorf("Expect bool type for parameter 3"),
+		},
+		{
+			args: []ast.Expr{&ast.FieldRef{Name: "temp"}, &ast.IntegerLiteral{Val: 3}, &ast.BooleanLiteral{Val: true}},
+		},
+	}
+	for i, tt := range tests {
+		err := f.val(nil, tt.args)
+		assert.Equal(t, tt.err, err, i)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	f, ok := builtins["pivot"]
+	require.True(t, ok)
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	tests := []struct {
+		name   string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name: "normal",
+			args: []interface{}{
+				[]interface{}{"temperature", "humidity", nil, 3},
+				[]interface{}{20.5, 60, 1, true},
+			},
+			result: map[string]interface{}{"temperature": 20.5, "humidity": 60, "3": true},
+		},
+		{
+			name: "last value wins",
+			args: []interface{}{
+				[]interface{}{"temperature", "temperature"},
+				[]interface{}{20.5, 21},
+			},
+			result: map[string]interface{}{"temperature": 21},
+		},
+		{
+			name:   "empty",
+			args:   []interface{}{[]interface{}{}, []interface{}{}},
+			result: map[string]interface{}{},
+		},
+		{
+			name: "invalid key",
+			args: []interface{}{
+				[]interface{}{map[string]interface{}{"a": 1}},
+				[]interface{}{1},
+			},
+			result: fmt.Errorf("the first parameter requires string but found map[string]interface {}(map[a:1])"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := f.exec(fctx, tt.args)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+	assert.EqualError(t, f.val(nil, []ast.Expr{&ast.FieldRef{Name: "tag"}}), "Expect 2 arguments but found 1.")
+}
+
+func TestCovarianceCorrelation(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	xs := []interface{}{1, 2, 3, nil, 4}
+	ys := []interface{}{2.0, 4.0, 6.5, 100.0, 7.5}
+	tests := []struct {
+		name   string
+		fn     string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "covar_pop",
+			fn:     "covar_pop",
+			args:   []interface{}{xs, ys},
+			result: 2.375,
+		},
+		{
+			name:   "covar_samp",
+			fn:     "covar_samp",
+			args:   []interface{}{xs, ys},
+			result: 9.5 / 3,
+		},
+		{
+			name:   "corr",
+			fn:     "corr",
+			args:   []interface{}{xs, ys},
+			result: 9.5 / math.Sqrt(5*18.5),
+		},
+		{
+			name:   "corr no variance",
+			fn:     "corr",
+			args:   []interface{}{[]interface{}{1, 1}, []interface{}{2, 3}},
+			result: nil,
+		},
+		{
+			name:   "covar_samp single",
+			fn:     "covar_samp",
+			args:   []interface{}{[]interface{}{1}, []interface{}{2}},
+			result: nil,
+		},
+		{
+			name:   "invalid",
+			fn:     "covar_pop",
+			args:   []interface{}{[]interface{}{"a"}, []interface{}{2}},
+			result: fmt.Errorf("requires number but found string(a)"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			r, _ := f.exec(fctx, tt.args)
+			if fr, ok := r.(float64); ok {
+				assert.InDelta(t, tt.result, fr, 1e-9)
+			} else {
+				assert.Equal(t, tt.result, r)
+			}
+		})
+	}
+}
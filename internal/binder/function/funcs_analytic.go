@@ -211,6 +211,33 @@ func registerAnalyticFunc() {
 		},
 	}
 
+	builtins["lead"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		// lead needs to wait for the following rows, it is calculated by the analytic operator directly
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			return fmt.Errorf("lead function can only be calculated by the analytic operator"), false
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			l := len(args)
+			if l < 1 || l > 3 {
+				return fmt.Errorf("expect one two or three args but got %d", l)
+			}
+			if l >= 2 {
+				s, ok := args[1].(*ast.IntegerLiteral)
+				if !ok {
+					return ProduceErrInfo(1, "int")
+				}
+				if s.Val < 1 {
+					return fmt.Errorf("the index should be a positive integer")
+				}
+			}
+			if l == 3 && !ast.IsBooleanArg(args[2]) {
+				return ProduceErrInfo(2, "bool")
+			}
+			return nil
+		},
+	}
+
 	builtins["latest"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
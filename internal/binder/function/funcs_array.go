@@ -504,6 +504,10 @@ func registerArrayFunc() {
 	builtins["array_map"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			// array_map(array, x -> expr)
+			if _, ok := args[1].(Lambda); ok {
+				return lambdaArrayMap(args)
+			}
 			funcName, ok := args[0].(string)
 			if !ok {
 				return errorArrayFirstArgumentNotStringError, false
@@ -541,9 +545,21 @@ func registerArrayFunc() {
 			return mapped, true
 		},
 		val: func(ctx api.FunctionContext, args []ast.Expr) error {
+			if len(args) == 2 {
+				if _, ok := args[1].(*ast.LambdaExpr); ok {
+					return validateLambdaFunc("array_map")(ctx, args)
+				}
+			}
 			return ValidateLen(2, len(args))
 		},
-		check: returnNilIfHasAnyNil,
+		check: func(args []interface{}) (interface{}, bool) {
+			if len(args) > 1 {
+				if _, ok := args[1].(Lambda); ok {
+					return returnNilIfFirstNil(args)
+				}
+			}
+			return returnNilIfHasAnyNil(args)
+		},
 	}
 	builtins["array_join"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
@@ -0,0 +1,346 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// registerBytesFunc registers the functions to work on the raw bytes such as the payload of a binary stream,
+// so that the binary frames can be filtered and routed without decoding.
+func registerBytesFunc() {
+	builtins["bytes_len"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			b, err := toBytes(args[0])
+			if err != nil {
+				return err, false
+			}
+			return len(b), true
+		},
+		val:   validateBytesArgs(1, 1),
+		check: return0IfHasAnyNil,
+	}
+	builtins["bytes_slice"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			b, err := toBytes(args[0])
+			if err != nil {
+				return err, false
+			}
+			start, err := cast.ToInt(args[1], cast.STRICT)
+			if err != nil {
+				return err, false
+			}
+			end := len(b)
+			if len(args) > 2 {
+				end, err = cast.ToInt(args[2], cast.STRICT)
+				if err != nil {
+					return err, false
+				}
+			}
+			start, end = bytesIndex(start, len(b)), bytesIndex(end, len(b))
+			if start < 0 || end > len(b) || start > end {
+				return fmt.Errorf("slice [%d:%d] out of range with length %d", start, end, len(b)), false
+			}
+			return b[start:end], true
+		},
+		val:   validateBytesArgs(2, 3),
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["bytes_concat"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			var buf bytes.Buffer
+			for _, arg := range args {
+				if arg == nil {
+					continue
+				}
+				b, err := toBytes(arg)
+				if err != nil {
+					return err, false
+				}
+				buf.Write(b)
+			}
+			return buf.Bytes(), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return ValidateAtLeast(1, len(args))
+		},
+	}
+	builtins["bytes_index"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			b, err := toBytes(args[0])
+			if err != nil {
+				return err, false
+			}
+			sep, err := toBytes(args[1])
+			if err != nil {
+				return err, false
+			}
+			return bytes.Index(b, sep), true
+		},
+		val:   validateBytesArgs(2, 2),
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["bytes_hex"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			b, err := toBytes(args[0])
+			if err != nil {
+				return err, false
+			}
+			return hex.EncodeToString(b), true
+		},
+		val:   validateBytesArgs(1, 1),
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["hex_bytes"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			s, err := cast.ToString(args[0], cast.STRICT)
+			if err != nil {
+				return err, false
+			}
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return fmt.Errorf("invalid hex string %s: %v", s, err), false
+			}
+			return b, true
+		},
+		val:   ValidateOneStrArg,
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["bytes_crc"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			b, err := toBytes(args[0])
+			if err != nil {
+				return err, false
+			}
+			alg := "crc32"
+			if len(args) > 1 {
+				alg, err = cast.ToString(args[1], cast.STRICT)
+				if err != nil {
+					return err, false
+				}
+			}
+			switch strings.ToLower(alg) {
+			case "crc32":
+				return int64(crc32.ChecksumIEEE(b)), true
+			case "crc32c":
+				return int64(crc32.Checksum(b, crc32cTable)), true
+			case "crc16_modbus":
+				return int64(crc16(b, 0xFFFF, 0xA001, true)), true
+			case "crc16_ccitt":
+				return int64(crc16(b, 0xFFFF, 0x1021, false)), true
+			default:
+				return fmt.Errorf("unsupported crc algorithm %s", alg), false
+			}
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := validateBytesArgs(1, 2)(nil, args); err != nil {
+				return err
+			}
+			if len(args) > 1 && !ast.IsStringArg(args[1]) {
+				return ProduceErrInfo(1, "string")
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["bytes_uint"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			v, size, err := readBytesInt(args)
+			if err != nil {
+				return err, false
+			}
+			if size == 8 && v > math.MaxInt64 {
+				return fmt.Errorf("value %d overflows bigint", v), false
+			}
+			return int64(v), true
+		},
+		val:   validateReadBytesArgs,
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["bytes_int"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			v, size, err := readBytesInt(args)
+			if err != nil {
+				return err, false
+			}
+			// sign extend
+			shift := 64 - size*8
+			return int64(v<<shift) >> shift, true
+		},
+		val:   validateReadBytesArgs,
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["bytes_float"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			v, size, err := readBytesInt(args)
+			if err != nil {
+				return err, false
+			}
+			switch size {
+			case 4:
+				return float64(math.Float32frombits(uint32(v))), true
+			case 8:
+				return math.Float64frombits(v), true
+			default:
+				return fmt.Errorf("float size must be 4 or 8 but got %d", size), false
+			}
+		},
+		val:   validateReadBytesArgs,
+		check: returnNilIfHasAnyNil,
+	}
+}
+
+func toBytes(v any) ([]byte, error) {
+	switch vt := v.(type) {
+	case []byte:
+		return vt, nil
+	case string:
+		return []byte(vt), nil
+	default:
+		return nil, fmt.Errorf("expect bytea or string type but got %v", v)
+	}
+}
+
+// bytesIndex converts the negative index which counts from the end
+func bytesIndex(i int, l int) int {
+	if i < 0 {
+		return l + i
+	}
+	return i
+}
+
+// readBytesInt reads the unsigned int of args (bytes, offset, size, [byteOrder]). The size must be 1 to 8.
+func readBytesInt(args []interface{}) (uint64, int, error) {
+	b, err := toBytes(args[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err := cast.ToInt(args[1], cast.STRICT)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, err := cast.ToInt(args[2], cast.STRICT)
+	if err != nil {
+		return 0, 0, err
+	}
+	if size < 1 || size > 8 {
+		return 0, 0, fmt.Errorf("size must be 1 to 8 but got %d", size)
+	}
+	littleEndian := false
+	if len(args) > 3 {
+		order, err := cast.ToString(args[3], cast.STRICT)
+		if err != nil {
+			return 0, 0, err
+		}
+		switch strings.ToLower(order) {
+		case "big":
+		case "little":
+			littleEndian = true
+		default:
+			return 0, 0, fmt.Errorf("byte order must be big or little but got %s", order)
+		}
+	}
+	offset = bytesIndex(offset, len(b))
+	if offset < 0 || offset+size > len(b) {
+		return 0, 0, fmt.Errorf("read %d bytes at %d out of range with length %d", size, offset, len(b))
+	}
+	var buf [8]byte
+	if littleEndian {
+		copy(buf[:], b[offset:offset+size])
+		return binary.LittleEndian.Uint64(buf[:]), size, nil
+	}
+	copy(buf[8-size:], b[offset:offset+size])
+	return binary.BigEndian.Uint64(buf[:]), size, nil
+}
+
+// crc16 calculates the crc16 with the init value and the polynomial. The reflected polynomial is used if reflect is true.
+func crc16(b []byte, init, poly uint16, reflect bool) uint16 {
+	crc := init
+	for _, c := range b {
+		if reflect {
+			crc ^= uint16(c)
+			for i := 0; i < 8; i++ {
+				if crc&1 != 0 {
+					crc = crc>>1 ^ poly
+				} else {
+					crc >>= 1
+				}
+			}
+		} else {
+			crc ^= uint16(c) << 8
+			for i := 0; i < 8; i++ {
+				if crc&0x8000 != 0 {
+					crc = crc<<1 ^ poly
+				} else {
+					crc <<= 1
+				}
+			}
+		}
+	}
+	return crc
+}
+
+func validateBytesArgs(min, max int) func(_ api.FunctionContext, args []ast.Expr) error {
+	return func(_ api.FunctionContext, args []ast.Expr) error {
+		if err := ValidateAtLeast(min, len(args)); err != nil {
+			return err
+		}
+		if len(args) > max {
+			return fmt.Errorf("Expect at most %d arguments but found %d.", max, len(args))
+		}
+		if ast.IsNumericArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+			return ProduceErrInfo(0, "bytea")
+		}
+		for i := 1; i < len(args) && i < 3; i++ {
+			if ast.IsFloatArg(args[i]) || ast.IsTimeArg(args[i]) || ast.IsBooleanArg(args[i]) {
+				return ProduceErrInfo(i, "int")
+			}
+		}
+		return nil
+	}
+}
+
+func validateReadBytesArgs(_ api.FunctionContext, args []ast.Expr) error {
+	if err := validateBytesArgs(3, 4)(nil, args); err != nil {
+		return err
+	}
+	if len(args) > 3 && !ast.IsStringArg(args[3]) {
+		return ProduceErrInfo(3, "string")
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestBytesFunctions(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	frame := []byte{0x12, 0x34, 0xff, 0xfe, 0x3f, 0x80, 0x00, 0x00}
+	tests := []struct {
+		name   string
+		fn     string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "len",
+			fn:     "bytes_len",
+			args:   []interface{}{frame},
+			result: 8,
+		},
+		{
+			name:   "slice",
+			fn:     "bytes_slice",
+			args:   []interface{}{frame, 1, 3},
+			result: []byte{0x34, 0xff},
+		},
+		{
+			name:   "slice to end",
+			fn:     "bytes_slice",
+			args:   []interface{}{frame, 6},
+			result: []byte{0x00, 0x00},
+		},
+		{
+			name:   "slice negative",
+			fn:     "bytes_slice",
+			args:   []interface{}{frame, -2, -1},
+			result: []byte{0x00},
+		},
+		{
+			name:   "slice out of range",
+			fn:     "bytes_slice",
+			args:   []interface{}{frame, 2, 9},
+			result: fmt.Errorf("slice [2:9] out of range with length 8"),
+		},
+		{
+			name:   "concat",
+			fn:     "bytes_concat",
+			args:   []interface{}{[]byte{0x01}, nil, "ab"},
+			result: []byte{0x01, 'a', 'b'},
+		},
+		{
+			name:   "index",
+			fn:     "bytes_index",
+			args:   []interface{}{frame, []byte{0xff, 0xfe}},
+			result: 2,
+		},
+		{
+			name:   "hex",
+			fn:     "bytes_hex",
+			args:   []interface{}{[]byte{0x12, 0xab}},
+			result: "12ab",
+		},
+		{
+			name:   "hex bytes",
+			fn:     "hex_bytes",
+			args:   []interface{}{"12ab"},
+			result: []byte{0x12, 0xab},
+		},
+		{
+			name:   "crc32",
+			fn:     "bytes_crc",
+			args:   []interface{}{"123456789"},
+			result: int64(0xCBF43926),
+		},
+		{
+			name:   "crc32c",
+			fn:     "bytes_crc",
+			args:   []interface{}{"123456789", "crc32c"},
+			result: int64(0xE3069283),
+		},
+		{
+			name:   "crc16 modbus",
+			fn:     "bytes_crc",
+			args:   []interface{}{"123456789", "CRC16_MODBUS"},
+			result: int64(0x4B37),
+		},
+		{
+			name:   "crc16 ccitt",
+			fn:     "bytes_crc",
+			args:   []interface{}{"123456789", "crc16_ccitt"},
+			result: int64(0x29B1),
+		},
+		{
+			name:   "crc unknown",
+			fn:     "bytes_crc",
+			args:   []interface{}{"123456789", "md5"},
+			result: fmt.Errorf("unsupported crc algorithm md5"),
+		},
+		{
+			name:   "uint big endian",
+			fn:     "bytes_uint",
+			args:   []interface{}{frame, 0, 2},
+			result: int64(0x1234),
+		},
+		{
+			name:   "uint little endian",
+			fn:     "bytes_uint",
+			args:   []interface{}{frame, 0, 2, "little"},
+			result: int64(0x3412),
+		},
+		{
+			name:   "int negative",
+			fn:     "bytes_int",
+			args:   []interface{}{frame, 2, 2},
+			result: int64(-2),
+		},
+		{
+			name:   "int positive",
+			fn:     "bytes_int",
+			args:   []interface{}{frame, 0, 1},
+			result: int64(0x12),
+		},
+		{
+			name:   "float32",
+			fn:     "bytes_float",
+			args:   []interface{}{frame, -4, 4},
+			result: 1.0,
+		},
+		{
+			name:   "float wrong size",
+			fn:     "bytes_float",
+			args:   []interface{}{frame, 0, 2},
+			result: fmt.Errorf("float size must be 4 or 8 but got 2"),
+		},
+		{
+			name:   "read out of range",
+			fn:     "bytes_uint",
+			args:   []interface{}{frame, 6, 4},
+			result: fmt.Errorf("read 4 bytes at 6 out of range with length 8"),
+		},
+		{
+			name:   "wrong order",
+			fn:     "bytes_uint",
+			args:   []interface{}{frame, 0, 2, "middle"},
+			result: fmt.Errorf("byte order must be big or little but got middle"),
+		},
+		{
+			name:   "uint overflow",
+			fn:     "bytes_uint",
+			args:   []interface{}{[]byte{0xff, 0, 0, 0, 0, 0, 0, 0}, 0, 8},
+			result: fmt.Errorf("value 18374686479671623680 overflows bigint"),
+		},
+		{
+			name:   "not bytes",
+			fn:     "bytes_len",
+			args:   []interface{}{1},
+			result: fmt.Errorf("expect bytea or string type but got 1"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			r, _ := f.exec(fctx, tt.args)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestBytesValidation(t *testing.T) {
+	tests := []struct {
+		fn   string
+		args []ast.Expr
+		err  error
+	}{
+		{
+			fn:   "bytes_slice",
+			args: []ast.Expr{&ast.FieldRef{Name: "self"}},
+			err:  fmt.Errorf("At least has 2 argument but found 1."),
+		},
+		{
+			fn:   "bytes_slice",
+			args: []ast.Expr{&ast.FieldRef{Name: "self"}, &ast.IntegerLiteral{Val: 1}, &ast.IntegerLiteral{Val: 2}, &ast.IntegerLiteral{Val: 3}},
+			err:  fmt.Errorf("Expect at most 3 arguments but found 4."),
+		},
+		{
+			fn:   "bytes_len",
+			args: []ast.Expr{&ast.IntegerLiteral{Val: 1}},
+			err:  fmt.Errorf("Expect bytea type for parameter 1"),
+		},
+		{
+			fn:   "bytes_uint",
+			args: []ast.Expr{&ast.FieldRef{Name: "self"}, &ast.NumberLiteral{Val: 1.5}, &ast.IntegerLiteral{Val: 2}},
+			err:  fmt.Errorf("Expect int type for parameter 2"),
+		},
+		{
+			fn:   "bytes_int",
+			args: []ast.Expr{&ast.FieldRef{Name: "self"}, &ast.IntegerLiteral{Val: 0}, &ast.IntegerLiteral{Val: 2}, &ast.IntegerLiteral{Val: 2}},
+			err:  fmt.Errorf("Expect string type for parameter 4"),
+		},
+		{
+			fn:   "bytes_crc",
+			args: []ast.Expr{&ast.FieldRef{Name: "self"}, &ast.StringLiteral{Val: "crc16_modbus"}},
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.fn]
+		require.True(t, ok)
+		assert.Equal(t, tt.err, f.val(nil, tt.args), i)
+	}
+}
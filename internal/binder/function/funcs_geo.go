@@ -0,0 +1,348 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+const (
+	earthRadius   = 6371008.8
+	geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+type geoPoint struct {
+	lat float64
+	lon float64
+}
+
+func registerGeoFunc() {
+	builtins["st_distance"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			fs, err := toGeoFloats(args)
+			if err != nil {
+				return err, false
+			}
+			return haversine(geoPoint{lat: fs[0], lon: fs[1]}, geoPoint{lat: fs[2], lon: fs[3]}), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return validateGeoNumbers(4, args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["st_contains"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			polygon, err := toPolygon(args[0])
+			if err != nil {
+				return err, false
+			}
+			fs, err := toGeoFloats(args[1:])
+			if err != nil {
+				return err, false
+			}
+			return polygonContains(polygon, geoPoint{lat: fs[0], lon: fs[1]}), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(3, len(args)); err != nil {
+				return err
+			}
+			if ast.IsNumericArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+				return ProduceErrInfo(0, "array")
+			}
+			for i := 1; i < 3; i++ {
+				if ast.IsStringArg(args[i]) || ast.IsTimeArg(args[i]) || ast.IsBooleanArg(args[i]) {
+					return ProduceErrInfo(i, "number")
+				}
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["st_bbox"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			polygon, err := toPolygon(args[0])
+			if err != nil {
+				return err, false
+			}
+			minP, maxP := polygon[0], polygon[0]
+			for _, p := range polygon[1:] {
+				minP.lat = math.Min(minP.lat, p.lat)
+				minP.lon = math.Min(minP.lon, p.lon)
+				maxP.lat = math.Max(maxP.lat, p.lat)
+				maxP.lon = math.Max(maxP.lon, p.lon)
+			}
+			return map[string]interface{}{
+				"minLat": minP.lat,
+				"minLon": minP.lon,
+				"maxLat": maxP.lat,
+				"maxLon": maxP.lon,
+			}, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(1, len(args)); err != nil {
+				return err
+			}
+			if ast.IsNumericArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+				return ProduceErrInfo(0, "array")
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["st_in_bbox"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			fs, err := toGeoFloats(args)
+			if err != nil {
+				return err, false
+			}
+			lat, lon := fs[0], fs[1]
+			return lat >= fs[2] && lon >= fs[3] && lat <= fs[4] && lon <= fs[5], true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return validateGeoNumbers(6, args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["geohash_encode"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			fs, err := toGeoFloats(args[:2])
+			if err != nil {
+				return err, false
+			}
+			precision := 12
+			if len(args) > 2 {
+				precision, err = cast.ToInt(args[2], cast.STRICT)
+				if err != nil || precision < 1 || precision > 12 {
+					return fmt.Errorf("the precision should be an integer between 1 and 12 but got %v", args[2]), false
+				}
+			}
+			if fs[0] < -90 || fs[0] > 90 || fs[1] < -180 || fs[1] > 180 {
+				return fmt.Errorf("invalid coordinate (%v, %v)", fs[0], fs[1]), false
+			}
+			return geohashEncode(fs[0], fs[1], precision), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 2 && len(args) != 3 {
+				return fmt.Errorf("Expect 2 or 3 arguments but found %d.", len(args))
+			}
+			if err := validateGeoNumbers(2, args[:2]); err != nil {
+				return err
+			}
+			if len(args) == 3 && (ast.IsFloatArg(args[2]) || ast.IsStringArg(args[2]) || ast.IsTimeArg(args[2]) || ast.IsBooleanArg(args[2])) {
+				return ProduceErrInfo(2, "int")
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["geohash_decode"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			hash, ok := args[0].(string)
+			if !ok {
+				return fmt.Errorf("the geohash should be a string but got %[1]T(%[1]v)", args[0]), false
+			}
+			p, err := geohashDecode(hash)
+			if err != nil {
+				return err, false
+			}
+			return map[string]interface{}{
+				"lat": p.lat,
+				"lon": p.lon,
+			}, true
+		},
+		val:   ValidateOneStrArg,
+		check: returnNilIfHasAnyNil,
+	}
+}
+
+func validateGeoNumbers(n int, args []ast.Expr) error {
+	if err := ValidateLen(n, len(args)); err != nil {
+		return err
+	}
+	for i, arg := range args {
+		if ast.IsStringArg(arg) || ast.IsTimeArg(arg) || ast.IsBooleanArg(arg) {
+			return ProduceErrInfo(i, "number")
+		}
+	}
+	return nil
+}
+
+func toGeoFloats(args []interface{}) ([]float64, error) {
+	result := make([]float64, len(args))
+	for i, arg := range args {
+		f, err := cast.ToFloat64(arg, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("the coordinate should be a number but got %[1]T(%[1]v)", arg)
+		}
+		result[i] = f
+	}
+	return result, nil
+}
+
+// haversine returns the great-circle distance of two points in meters
+func haversine(p1, p2 geoPoint) float64 {
+	dLat := (p2.lat - p1.lat) * DegToRad
+	dLon := (p2.lon - p1.lon) * DegToRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(p1.lat*DegToRad)*math.Cos(p2.lat*DegToRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// toPolygon converts the polygon argument to points. The polygon could be
+// an array of [lat, lon] pairs, an array of {"lat", "lon"} objects, a GeoJSON Polygon object whose
+// coordinates are [lon, lat] or the JSON string of them.
+func toPolygon(arg interface{}) ([]geoPoint, error) {
+	if s, ok := arg.(string); ok {
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("invalid polygon %s: %v", s, err)
+		}
+		arg = v
+	}
+	var (
+		points []interface{}
+		lonLat bool
+	)
+	switch at := arg.(type) {
+	case []interface{}:
+		points = at
+	case map[string]interface{}:
+		if t, _ := at["type"].(string); !strings.EqualFold(t, "Polygon") {
+			return nil, fmt.Errorf("only GeoJSON Polygon is supported but got type %v", at["type"])
+		}
+		rings, ok := at["coordinates"].([]interface{})
+		if !ok || len(rings) == 0 {
+			return nil, fmt.Errorf("invalid GeoJSON Polygon coordinates %v", at["coordinates"])
+		}
+		// only the exterior ring is used
+		points, ok = rings[0].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid GeoJSON Polygon coordinates %v", at["coordinates"])
+		}
+		lonLat = true
+	default:
+		return nil, fmt.Errorf("the polygon should be an array of points but got %[1]T(%[1]v)", arg)
+	}
+	if len(points) < 3 {
+		return nil, fmt.Errorf("the polygon should have at least 3 points but got %d", len(points))
+	}
+	result := make([]geoPoint, 0, len(points))
+	for _, p := range points {
+		var c []interface{}
+		switch pt := p.(type) {
+		case []interface{}:
+			if len(pt) < 2 {
+				return nil, fmt.Errorf("invalid polygon point %v", p)
+			}
+			c = pt[:2]
+			if lonLat {
+				c = []interface{}{pt[1], pt[0]}
+			}
+		case map[string]interface{}:
+			c = []interface{}{pt["lat"], pt["lon"]}
+		default:
+			return nil, fmt.Errorf("invalid polygon point %v", p)
+		}
+		fs, err := toGeoFloats(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid polygon point %v", p)
+		}
+		result = append(result, geoPoint{lat: fs[0], lon: fs[1]})
+	}
+	return result, nil
+}
+
+// polygonContains checks if the point is inside the polygon by ray casting
+func polygonContains(polygon []geoPoint, p geoPoint) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		pi, pj := polygon[i], polygon[j]
+		if (pi.lat > p.lat) != (pj.lat > p.lat) && p.lon < (pj.lon-pi.lon)*(p.lat-pi.lat)/(pj.lat-pi.lat)+pi.lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func geohashEncode(lat, lon float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	var sb strings.Builder
+	bit, ch, even := 0, 0, true
+	for sb.Len() < precision {
+		r, v := &latRange, lat
+		if even {
+			r, v = &lonRange, lon
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+		bit++
+		if bit == 5 {
+			sb.WriteByte(geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// geohashDecode returns the center of the geohash cell
+func geohashDecode(hash string) (geoPoint, error) {
+	if len(hash) == 0 {
+		return geoPoint{}, fmt.Errorf("the geohash should not be empty")
+	}
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	even := true
+	for _, c := range strings.ToLower(hash) {
+		idx := strings.IndexRune(geohashBase32, c)
+		if idx < 0 {
+			return geoPoint{}, fmt.Errorf("invalid geohash %s", hash)
+		}
+		for mask := 16; mask > 0; mask >>= 1 {
+			r := &latRange
+			if even {
+				r = &lonRange
+			}
+			mid := (r[0] + r[1]) / 2
+			if idx&mask != 0 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+	return geoPoint{lat: (latRange[0] + latRange[1]) / 2, lon: (lonRange[0] + lonRange[1]) / 2}, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestGeoFunctions(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	square := []interface{}{
+		[]interface{}{0, 0},
+		[]interface{}{0, 10},
+		[]interface{}{10, 10},
+		[]interface{}{10, 0},
+	}
+	tests := []struct {
+		name   string
+		fn     string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "distance",
+			fn:     "st_distance",
+			args:   []interface{}{51.5007, 0.1246, 40.6892, 74.0445},
+			result: 5574848.157146155,
+		},
+		{
+			name:   "distance invalid",
+			fn:     "st_distance",
+			args:   []interface{}{"a", 0.1246, 40.6892, 74.0445},
+			result: fmt.Errorf("the coordinate should be a number but got string(a)"),
+		},
+		{
+			name:   "contains",
+			fn:     "st_contains",
+			args:   []interface{}{square, 5, 5.5},
+			result: true,
+		},
+		{
+			name:   "not contains",
+			fn:     "st_contains",
+			args:   []interface{}{square, 15, 5},
+			result: false,
+		},
+		{
+			name:   "contains json string",
+			fn:     "st_contains",
+			args:   []interface{}{`[{"lat":0,"lon":0},{"lat":0,"lon":10},{"lat":10,"lon":10}]`, 1, 5},
+			result: true,
+		},
+		{
+			name: "contains geojson",
+			fn:   "st_contains",
+			args: []interface{}{map[string]interface{}{
+				"type":        "Polygon",
+				"coordinates": []interface{}{[]interface{}{[]interface{}{0, 0}, []interface{}{20, 0}, []interface{}{20, 10}, []interface{}{0, 10}, []interface{}{0, 0}}},
+			}, 5, 15},
+			result: true,
+		},
+		{
+			name:   "invalid polygon",
+			fn:     "st_contains",
+			args:   []interface{}{square[:2], 5, 5},
+			result: fmt.Errorf("the polygon should have at least 3 points but got 2"),
+		},
+		{
+			name:   "bbox",
+			fn:     "st_bbox",
+			args:   []interface{}{[]interface{}{[]interface{}{1, 2}, []interface{}{-3, 4.5}, []interface{}{2, -1}}},
+			result: map[string]interface{}{"minLat": -3.0, "minLon": -1.0, "maxLat": 2.0, "maxLon": 4.5},
+		},
+		{
+			name:   "in bbox",
+			fn:     "st_in_bbox",
+			args:   []interface{}{1, 2, 0, 0, 10, 10},
+			result: true,
+		},
+		{
+			name:   "not in bbox",
+			fn:     "st_in_bbox",
+			args:   []interface{}{1, 12, 0, 0, 10, 10},
+			result: false,
+		},
+		{
+			name:   "geohash encode",
+			fn:     "geohash_encode",
+			args:   []interface{}{57.64911, 10.40744, 11},
+			result: "u4pruydqqvj",
+		},
+		{
+			name:   "geohash encode default precision",
+			fn:     "geohash_encode",
+			args:   []interface{}{57.64911, 10.40744},
+			result: "u4pruydqqvj8",
+		},
+		{
+			name:   "geohash encode invalid precision",
+			fn:     "geohash_encode",
+			args:   []interface{}{57.64911, 10.40744, 13},
+			result: fmt.Errorf("the precision should be an integer between 1 and 12 but got 13"),
+		},
+		{
+			name:   "geohash encode invalid coordinate",
+			fn:     "geohash_encode",
+			args:   []interface{}{97.64911, 10.40744},
+			result: fmt.Errorf("invalid coordinate (97.64911, 10.40744)"),
+		},
+		{
+			name:   "geohash decode invalid",
+			fn:     "geohash_decode",
+			args:   []interface{}{"u4pa"},
+			result: fmt.Errorf("invalid geohash u4pa"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			r, _ := f.exec(fctx, tt.args)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+	f := builtins["geohash_decode"]
+	r, ok := f.exec(fctx, []interface{}{"u4pruydqqvj"})
+	require.True(t, ok)
+	m := r.(map[string]interface{})
+	assert.InDelta(t, 57.64911, m["lat"], 1e-5)
+	assert.InDelta(t, 10.40744, m["lon"], 1e-5)
+}
+
+func TestGeoValidation(t *testing.T) {
+	tests := []struct {
+		fn   string
+		args []ast.Expr
+		err  error
+	}{
+		{
+			fn:   "st_distance",
+			args: []ast.Expr{&ast.NumberLiteral{Val: 1}, &ast.NumberLiteral{Val: 1}, &ast.NumberLiteral{Val: 1}},
+			err:  fmt.Errorf("Expect 4 arguments but found 3."),
+		},
+		{
+			fn:   "st_distance",
+			args: []ast.Expr{&ast.NumberLiteral{Val: 1}, &ast.StringLiteral{Val: "1"}, &ast.NumberLiteral{Val: 1}, &ast.NumberLiteral{Val: 1}},
+			err:  fmt.Errorf("Expect number type for parameter 2"),
+		},
+		{
+			fn:   "st_contains",
+			args: []ast.Expr{&ast.NumberLiteral{Val: 1}, &ast.FieldRef{Name: "lat"}, &ast.FieldRef{Name: "lon"}},
+			err:  fmt.Errorf("Expect array type for parameter 1"),
+		},
+		{
+			fn:   "st_contains",
+			args: []ast.Expr{&ast.StringLiteral{Val: "[]"}, &ast.FieldRef{Name: "lat"}, &ast.FieldRef{Name: "lon"}},
+		},
+		{
+			fn:   "geohash_encode",
+			args: []ast.Expr{&ast.FieldRef{Name: "lat"}, &ast.FieldRef{Name: "lon"}, &ast.NumberLiteral{Val: 1.5}},
+			err:  fmt.Errorf("Expect int type for parameter 3"),
+		},
+		{
+			fn:   "geohash_decode",
+			args: []ast.Expr{&ast.IntegerLiteral{Val: 1}},
+			err:  fmt.Errorf("Expect string type for parameter 1"),
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.fn]
+		require.True(t, ok)
+		assert.Equal(t, tt.err, f.val(nil, tt.args), i)
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// Lambda is the evaluated lambda expression. It is called with the values of the lambda parameters.
+type Lambda func(args ...interface{}) interface{}
+
+type lambdaArg struct {
+	// the index of the lambda argument in the function arguments
+	index int
+	// the number of the lambda parameters
+	params int
+	// the function also accepts a normal argument at the lambda position
+	optional bool
+}
+
+// lambdaFuncs are the higher order functions which receive a lambda expression argument
+var lambdaFuncs = map[string]lambdaArg{
+	"array_filter":         {index: 1, params: 1},
+	"array_map":            {index: 1, params: 1, optional: true},
+	"array_any_match":      {index: 1, params: 1},
+	"array_all_match":      {index: 1, params: 1},
+	"array_reduce":         {index: 2, params: 2},
+	"map_filter":           {index: 1, params: 2},
+	"map_transform_values": {index: 1, params: 2},
+}
+
+// LambdaArgIndex returns the index of the lambda argument if the function is a higher order function
+func LambdaArgIndex(name string) (int, bool) {
+	la, ok := lambdaFuncs[name]
+	return la.index, ok
+}
+
+// IsLambdaArgOptional returns true if the higher order function also has a form without lambda argument
+func IsLambdaArgOptional(name string) bool {
+	return lambdaFuncs[name].optional
+}
+
+func registerLambdaFunc() {
+	builtins["array_filter"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			array, ok := args[0].([]interface{})
+			if !ok {
+				return errorArrayFirstArgumentNotArrayError, false
+			}
+			f, err := toLambda(args[1])
+			if err != nil {
+				return err, false
+			}
+			result := make([]interface{}, 0, len(array))
+			for _, item := range array {
+				matched, err := lambdaPredicate(f, item)
+				if err != nil {
+					return err, false
+				}
+				if matched {
+					result = append(result, item)
+				}
+			}
+			return result, true
+		},
+		val:   validateLambdaFunc("array_filter"),
+		check: returnNilIfFirstNil,
+	}
+	builtins["array_any_match"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			array, ok := args[0].([]interface{})
+			if !ok {
+				return errorArrayFirstArgumentNotArrayError, false
+			}
+			f, err := toLambda(args[1])
+			if err != nil {
+				return err, false
+			}
+			for _, item := range array {
+				matched, err := lambdaPredicate(f, item)
+				if err != nil {
+					return err, false
+				}
+				if matched {
+					return true, true
+				}
+			}
+			return false, true
+		},
+		val:   validateLambdaFunc("array_any_match"),
+		check: returnNilIfFirstNil,
+	}
+	builtins["array_all_match"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			array, ok := args[0].([]interface{})
+			if !ok {
+				return errorArrayFirstArgumentNotArrayError, false
+			}
+			f, err := toLambda(args[1])
+			if err != nil {
+				return err, false
+			}
+			for _, item := range array {
+				matched, err := lambdaPredicate(f, item)
+				if err != nil {
+					return err, false
+				}
+				if !matched {
+					return false, true
+				}
+			}
+			return true, true
+		},
+		val:   validateLambdaFunc("array_all_match"),
+		check: returnNilIfFirstNil,
+	}
+	builtins["array_reduce"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			array, ok := args[0].([]interface{})
+			if !ok {
+				return errorArrayFirstArgumentNotArrayError, false
+			}
+			f, err := toLambda(args[2])
+			if err != nil {
+				return err, false
+			}
+			acc := args[1]
+			for _, item := range array {
+				acc = f(acc, item)
+				if err, ok := acc.(error); ok {
+					return err, false
+				}
+			}
+			return acc, true
+		},
+		val:   validateLambdaFunc("array_reduce"),
+		check: returnNilIfFirstNil,
+	}
+	builtins["map_filter"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			m, ok := args[0].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("the first argument should be map[string]interface{}"), false
+			}
+			f, err := toLambda(args[1])
+			if err != nil {
+				return err, false
+			}
+			result := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				matched, err := lambdaPredicate(f, k, v)
+				if err != nil {
+					return err, false
+				}
+				if matched {
+					result[k] = v
+				}
+			}
+			return result, true
+		},
+		val:   validateLambdaFunc("map_filter"),
+		check: returnNilIfFirstNil,
+	}
+	builtins["map_transform_values"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			m, ok := args[0].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("the first argument should be map[string]interface{}"), false
+			}
+			f, err := toLambda(args[1])
+			if err != nil {
+				return err, false
+			}
+			result := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				r := f(k, v)
+				if err, ok := r.(error); ok {
+					return err, false
+				}
+				result[k] = r
+			}
+			return result, true
+		},
+		val:   validateLambdaFunc("map_transform_values"),
+		check: returnNilIfFirstNil,
+	}
+}
+
+// lambdaArrayMap maps each item of the array by the lambda. The function name form of array_map is in funcs_array.go
+func lambdaArrayMap(args []interface{}) (interface{}, bool) {
+	array, ok := args[0].([]interface{})
+	if !ok {
+		return errorArrayFirstArgumentNotArrayError, false
+	}
+	f, err := toLambda(args[1])
+	if err != nil {
+		return err, false
+	}
+	result := make([]interface{}, len(array))
+	for i, item := range array {
+		r := f(item)
+		if err, ok := r.(error); ok {
+			return err, false
+		}
+		result[i] = r
+	}
+	return result, true
+}
+
+// validateLambdaFunc validates the argument count and the lambda argument of the higher order function
+func validateLambdaFunc(name string) funcVal {
+	la := lambdaFuncs[name]
+	return func(_ api.FunctionContext, args []ast.Expr) error {
+		if err := ValidateLen(la.index+1, len(args)); err != nil {
+			return err
+		}
+		lambda, ok := args[la.index].(*ast.LambdaExpr)
+		if !ok {
+			return ProduceErrInfo(la.index, "lambda")
+		}
+		if len(lambda.Params) != la.params {
+			return fmt.Errorf("the lambda expression of %s should have %d parameters but got %d", name, la.params, len(lambda.Params))
+		}
+		return nil
+	}
+}
+
+// lambdaPredicate calls the lambda which must return a bool. A nil result is regarded as false.
+func lambdaPredicate(f Lambda, args ...interface{}) (bool, error) {
+	switch r := f(args...).(type) {
+	case bool:
+		return r, nil
+	case nil:
+		return false, nil
+	case error:
+		return false, r
+	default:
+		return false, fmt.Errorf("the lambda expression should return a bool but got %[1]T(%[1]v)", r)
+	}
+}
+
+func toLambda(arg interface{}) (Lambda, error) {
+	f, ok := arg.(Lambda)
+	if !ok {
+		return nil, fmt.Errorf("the argument should be a lambda expression but got %[1]T(%[1]v)", arg)
+	}
+	return f, nil
+}
+
+func returnNilIfFirstNil(args []interface{}) (interface{}, bool) {
+	if args[0] == nil {
+		return nil, true
+	}
+	return nil, false
+}
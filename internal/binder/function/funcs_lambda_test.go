@@ -0,0 +1,244 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestLambdaFunctions(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	gt1 := Lambda(func(args ...interface{}) interface{} {
+		return args[0].(int) > 1
+	})
+	double := Lambda(func(args ...interface{}) interface{} {
+		return args[0].(int) * 2
+	})
+	sum := Lambda(func(args ...interface{}) interface{} {
+		return args[0].(int) + args[1].(int)
+	})
+	tests := []struct {
+		name   string
+		fn     string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "array_filter",
+			fn:     "array_filter",
+			args:   []interface{}{[]interface{}{1, 2, 3}, gt1},
+			result: []interface{}{2, 3},
+		},
+		{
+			name:   "array_filter empty",
+			fn:     "array_filter",
+			args:   []interface{}{[]interface{}{}, gt1},
+			result: []interface{}{},
+		},
+		{
+			name: "array_filter nil predicate",
+			fn:   "array_filter",
+			args: []interface{}{[]interface{}{1, 2}, Lambda(func(args ...interface{}) interface{} {
+				return nil
+			})},
+			result: []interface{}{},
+		},
+		{
+			name:   "array_filter not bool",
+			fn:     "array_filter",
+			args:   []interface{}{[]interface{}{1, 2}, double},
+			result: fmt.Errorf("the lambda expression should return a bool but got int(2)"),
+		},
+		{
+			name:   "array_filter not array",
+			fn:     "array_filter",
+			args:   []interface{}{1, gt1},
+			result: errorArrayFirstArgumentNotArrayError,
+		},
+		{
+			name:   "array_filter not lambda",
+			fn:     "array_filter",
+			args:   []interface{}{[]interface{}{1, 2}, 1},
+			result: fmt.Errorf("the argument should be a lambda expression but got int(1)"),
+		},
+		{
+			name:   "array_map",
+			fn:     "array_map",
+			args:   []interface{}{[]interface{}{1, 2, 3}, double},
+			result: []interface{}{2, 4, 6},
+		},
+		{
+			name: "array_map error",
+			fn:   "array_map",
+			args: []interface{}{[]interface{}{1}, Lambda(func(args ...interface{}) interface{} {
+				return errors.New("eval error")
+			})},
+			result: errors.New("eval error"),
+		},
+		{
+			name:   "array_any_match",
+			fn:     "array_any_match",
+			args:   []interface{}{[]interface{}{0, 1, 2}, gt1},
+			result: true,
+		},
+		{
+			name:   "array_any_match empty",
+			fn:     "array_any_match",
+			args:   []interface{}{[]interface{}{}, gt1},
+			result: false,
+		},
+		{
+			name:   "array_all_match",
+			fn:     "array_all_match",
+			args:   []interface{}{[]interface{}{0, 1, 2}, gt1},
+			result: false,
+		},
+		{
+			name:   "array_all_match empty",
+			fn:     "array_all_match",
+			args:   []interface{}{[]interface{}{}, gt1},
+			result: true,
+		},
+		{
+			name:   "array_reduce",
+			fn:     "array_reduce",
+			args:   []interface{}{[]interface{}{1, 2, 3}, 10, sum},
+			result: 16,
+		},
+		{
+			name:   "array_reduce empty",
+			fn:     "array_reduce",
+			args:   []interface{}{[]interface{}{}, 10, sum},
+			result: 10,
+		},
+		{
+			name: "map_filter",
+			fn:   "map_filter",
+			args: []interface{}{map[string]interface{}{"a": 1, "b": 2}, Lambda(func(args ...interface{}) interface{} {
+				return args[0] == "a" || args[1].(int) > 1
+			})},
+			result: map[string]interface{}{"a": 1, "b": 2},
+		},
+		{
+			name: "map_filter by value",
+			fn:   "map_filter",
+			args: []interface{}{map[string]interface{}{"a": 1, "b": 2}, Lambda(func(args ...interface{}) interface{} {
+				return args[1].(int) > 1
+			})},
+			result: map[string]interface{}{"b": 2},
+		},
+		{
+			name:   "map_filter not map",
+			fn:     "map_filter",
+			args:   []interface{}{[]interface{}{1}, sum},
+			result: fmt.Errorf("the first argument should be map[string]interface{}"),
+		},
+		{
+			name: "map_transform_values",
+			fn:   "map_transform_values",
+			args: []interface{}{map[string]interface{}{"a": 1, "b": 2}, Lambda(func(args ...interface{}) interface{} {
+				return fmt.Sprintf("%s%d", args[0], args[1])
+			})},
+			result: map[string]interface{}{"a": "a1", "b": "b2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			r, _ := f.exec(fctx, tt.args)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestLambdaValidation(t *testing.T) {
+	lambda := &ast.LambdaExpr{Params: []string{"x"}, Body: &ast.LambdaParamRef{Name: "x"}}
+	tests := []struct {
+		fn   string
+		args []ast.Expr
+		err  error
+	}{
+		{
+			fn:   "array_filter",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, lambda},
+		},
+		{
+			fn:   "array_filter",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}},
+			err:  fmt.Errorf("Expect 2 arguments but found 1."),
+		},
+		{
+			fn:   "array_filter",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.FieldRef{Name: "b"}},
+			err:  fmt.Errorf("Expect lambda type for parameter 2"),
+		},
+		{
+			fn:   "array_map",
+			args: []ast.Expr{&ast.StringLiteral{Val: "abs"}, &ast.FieldRef{Name: "b"}},
+		},
+		{
+			fn:   "array_map",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, lambda},
+		},
+		{
+			fn:   "array_reduce",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 0}, lambda},
+			err:  fmt.Errorf("the lambda expression of array_reduce should have 2 parameters but got 1"),
+		},
+		{
+			fn:   "map_filter",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.LambdaExpr{Params: []string{"k", "v"}, Body: &ast.BooleanLiteral{Val: true}}},
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.fn]
+		require.True(t, ok)
+		assert.Equal(t, tt.err, f.val(nil, tt.args), i)
+	}
+}
+
+func TestLambdaFunctionsNil(t *testing.T) {
+	oldBuiltins := builtins
+	defer func() {
+		builtins = oldBuiltins
+	}()
+	builtins = map[string]builtinFunc{}
+	registerLambdaFunc()
+	for name, function := range builtins {
+		r, b := function.check([]interface{}{nil, nil})
+		require.True(t, b, fmt.Sprintf("%v failed", name))
+		require.Nil(t, r, fmt.Sprintf("%v failed", name))
+	}
+	idx, ok := LambdaArgIndex("array_reduce")
+	require.True(t, ok)
+	require.Equal(t, 2, idx)
+	_, ok = LambdaArgIndex("array_concat")
+	require.False(t, ok)
+}
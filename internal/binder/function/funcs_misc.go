@@ -35,6 +35,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/keyedstate"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/ruleparam"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
@@ -72,6 +73,17 @@ func registerMiscFunc() {
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
 			value := args[0]
 			newType := args[1]
+			if t, ok := newType.(string); ok && isComplexCastType(t) {
+				ft, err := getCastType(t)
+				if err != nil {
+					return err, false
+				}
+				r, err := castToFieldType(value, ft)
+				if err != nil {
+					return fmt.Errorf("not supported type conversion, got error %v", err), false
+				}
+				return r, true
+			}
 			return cast.ToType(value, newType)
 		},
 		val: func(_ api.FunctionContext, args []ast.Expr) error {
@@ -83,6 +95,10 @@ func registerMiscFunc() {
 				return ProduceErrInfo(0, "string")
 			}
 			if av, ok := a.(*ast.StringLiteral); ok {
+				if isComplexCastType(av.Val) {
+					_, err := getCastType(av.Val)
+					return err
+				}
 				if !(av.Val == "bigint" || av.Val == "float" || av.Val == "string" || av.Val == "boolean" || av.Val == "datetime" || av.Val == "bytea") {
 					return fmt.Errorf("Expect one of following value for the 2nd parameter: bigint, float, string, boolean, datetime, bytea.")
 				}
@@ -484,6 +500,37 @@ func registerMiscFunc() {
 		},
 		val: ValidateNoArg,
 	}
+	builtins["param"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			name, ok := args[0].(string)
+			if !ok {
+				return fmt.Errorf("parameter name %v is not a string", args[0]), false
+			}
+			if v, ok := ruleparam.Get(ctx.GetRuleId(), name); ok {
+				return v, true
+			}
+			if len(args) > 1 {
+				return args[1], true
+			}
+			return fmt.Errorf("parameter %s is not defined in rule %s", name, ctx.GetRuleId()), false
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) < 1 || len(args) > 2 {
+				return fmt.Errorf("Expect 1 or 2 arguments but found %d.", len(args))
+			}
+			if _, ok := args[0].(*ast.StringLiteral); !ok {
+				return ProduceErrInfo(0, "string literal")
+			}
+			return nil
+		},
+		check: func(args []interface{}) (interface{}, bool) {
+			if args[0] == nil {
+				return nil, true
+			}
+			return nil, false
+		},
+	}
 	builtins["meta"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
@@ -569,6 +616,64 @@ func registerMiscFunc() {
 		},
 		val: ValidateJsonFunc,
 	}
+	builtins["json_query"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			result, found, err := jsonLookup(ctx, args)
+			if err != nil {
+				return err, false
+			}
+			if !found {
+				return []interface{}{}, true
+			}
+			if arr, ok := result.([]interface{}); ok {
+				return arr, true
+			}
+			return []interface{}{result}, true
+		},
+		val:   validateJsonQueryFunc(2, 2),
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["json_value"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			var dft interface{}
+			if len(args) > 2 {
+				dft = args[2]
+			}
+			result, found, err := jsonLookup(ctx, args)
+			if err != nil {
+				return err, false
+			}
+			if !found {
+				return dft, true
+			}
+			// wildcard or filter returns an array, take the first matched item
+			if arr, ok := result.([]interface{}); ok && isJsonPathMulti(args[1].(string)) {
+				if len(arr) == 0 {
+					return dft, true
+				}
+				result = arr[0]
+			}
+			switch result.(type) {
+			case nil:
+				return dft, true
+			case map[string]interface{}, []interface{}:
+				return fmt.Errorf("the result of json path %s is not a scalar value, use json_query instead", args[1]), false
+			}
+			return result, true
+		},
+		val: validateJsonQueryFunc(2, 3),
+		check: func(args []interface{}) (interface{}, bool) {
+			if args[0] == nil {
+				if len(args) > 2 {
+					return args[2], true
+				}
+				return nil, true
+			}
+			return nil, false
+		},
+	}
 	builtins["window_start"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec:  nil, // directly return in the valuer
@@ -584,6 +689,11 @@ func registerMiscFunc() {
 		exec:  nil, // directly return in the valuer
 		val:   ValidateNoArg,
 	}
+	builtins["window_trigger"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec:  nil, // directly return in the valuer
+		val:   ValidateNoArg,
+	}
 
 	builtins["delay"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
@@ -690,6 +800,65 @@ func jsonCall(ctx api.StreamContext, args []interface{}) (interface{}, error) {
 	return ctx.ParseJsonPath(jp, args[0])
 }
 
+// jsonLookup evaluates the json path and returns whether the path is found.
+// Json string or bytes payload is unmarshalled; a path which does not match the payload is not found instead of error.
+func jsonLookup(ctx api.StreamContext, args []interface{}) (interface{}, bool, error) {
+	jp, ok := args[1].(string)
+	if !ok {
+		return nil, false, fmt.Errorf("invalid jsonPath, must be a string but got %v", args[1])
+	}
+	data := args[0]
+	switch dt := data.(type) {
+	case []byte:
+		data = string(dt)
+	}
+	if s, ok := data.(string); ok {
+		var v interface{}
+		if err := json.Unmarshal(cast.StringToBytes(s), &v); err != nil {
+			return nil, false, fmt.Errorf("data '%v' is not a valid json string", s)
+		}
+		if v == nil {
+			return nil, false, nil
+		}
+		data = v
+	}
+	switch data.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return nil, false, fmt.Errorf("invalid data %v for jsonpath", data)
+	}
+	result, err := ctx.ParseJsonPath(jp, data)
+	if err != nil {
+		return nil, false, nil
+	}
+	return result, true, nil
+}
+
+// isJsonPathMulti returns whether the json path may match multiple items
+func isJsonPathMulti(jp string) bool {
+	return strings.ContainsAny(jp, "*?") || strings.Contains(jp, "..") || strings.Contains(jp, ":") || strings.Contains(jp, ",")
+}
+
+func validateJsonQueryFunc(min, max int) func(api.FunctionContext, []ast.Expr) error {
+	return func(_ api.FunctionContext, args []ast.Expr) error {
+		l := len(args)
+		if l < min || l > max {
+			if min == max {
+				return ValidateLen(min, l)
+			}
+			return fmt.Errorf("Expect %d to %d arguments but found %d.", min, max, l)
+		}
+		sl, ok := args[1].(*ast.StringLiteral)
+		if !ok {
+			return ProduceErrInfo(1, "string")
+		}
+		if _, err := conf.GetJsonPathEval(sl.Val); err != nil {
+			return fmt.Errorf("invalid json path %s: %v", sl.Val, err)
+		}
+		return nil
+	}
+}
+
 // page Rotate storage for in memory cache
 // Not thread safe!
 type ringqueue struct {
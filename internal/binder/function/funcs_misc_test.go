@@ -28,6 +28,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/keyedstate"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/ruleparam"
 	"github.com/lf-edge/ekuiper/v2/internal/testx"
 	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
@@ -459,6 +460,52 @@ func TestKeyedStateExec(t *testing.T) {
 	_ = keyedstate.ClearKeyedState()
 }
 
+func TestParamFunc(t *testing.T) {
+	f, ok := builtins["param"]
+	require.True(t, ok)
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("paramRule", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("paramRule", "test", tempStore), 1)
+
+	require.EqualError(t, f.val(fctx, []ast.Expr{}), "Expect 1 or 2 arguments but found 0.")
+	require.EqualError(t, f.val(fctx, []ast.Expr{&ast.FieldRef{Name: "a"}}), "Expect string literal type for parameter 1")
+	require.NoError(t, f.val(fctx, []ast.Expr{&ast.StringLiteral{Val: "threshold"}, &ast.IntegerLiteral{Val: 10}}))
+
+	ruleparam.Set("paramRule", map[string]any{"threshold": 30})
+	defer ruleparam.Delete("paramRule")
+	tests := []struct {
+		args   []any
+		result any
+	}{
+		{
+			args:   []any{"threshold"},
+			result: 30,
+		},
+		{
+			args:   []any{"threshold", 10},
+			result: 30,
+		},
+		{
+			args:   []any{"devices", []any{"d1"}},
+			result: []any{"d1"},
+		},
+		{
+			args:   []any{"devices"},
+			result: errors.New("parameter devices is not defined in rule paramRule"),
+		},
+	}
+	for _, tt := range tests {
+		r, _ := f.exec(fctx, tt.args)
+		assert.Equal(t, tt.result, r)
+	}
+	// The updated params are read in the next call
+	ruleparam.Set("paramRule", map[string]any{"threshold": 40})
+	r, ok := f.exec(fctx, []any{"threshold"})
+	assert.True(t, ok)
+	assert.Equal(t, 40, r)
+}
+
 func TestHexIntFunctions(t *testing.T) {
 	contextLogger := conf.Log.WithField("rule", "testExec")
 	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
@@ -509,7 +556,7 @@ func TestMiscFuncNil(t *testing.T) {
 	registerMiscFunc()
 	for name, function := range builtins {
 		switch name {
-		case "compress", "decompress", "newuuid", "tstamp", "rule_id", "rule_start", "window_start", "window_end", "event_time",
+		case "compress", "decompress", "newuuid", "tstamp", "rule_id", "rule_start", "window_start", "window_end", "event_time", "window_trigger",
 			"json_path_query", "json_path_query_first", "coalesce", "meta", "json_path_exists", "bypass":
 			continue
 		case "isnull":
@@ -651,6 +698,101 @@ func TestCast(t *testing.T) {
 	}
 }
 
+func TestCastNested(t *testing.T) {
+	f, ok := builtins["cast"]
+	require.True(t, ok)
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	tests := []struct {
+		name   string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name: "struct",
+			args: []interface{}{
+				map[string]interface{}{"id": "1", "temp": 20, "extra": true},
+				"struct(id bigint, temp float)",
+			},
+			result: map[string]interface{}{"id": 1, "temp": 20.0},
+		},
+		{
+			name: "nested struct",
+			args: []interface{}{
+				map[string]interface{}{"device": map[string]interface{}{"name": 1, "tags": []interface{}{1, "b"}}},
+				"STRUCT(device STRUCT(name STRING, tags ARRAY(STRING)), missing BIGINT)",
+			},
+			result: map[string]interface{}{"device": map[string]interface{}{"name": "1", "tags": []interface{}{"1", "b"}}},
+		},
+		{
+			name: "array of struct from json string",
+			args: []interface{}{
+				`[{"x": 1, "y": "2"}, null]`,
+				"array(struct(x float, y bigint))",
+			},
+			result: []interface{}{map[string]interface{}{"x": 1.0, "y": 2}, nil},
+		},
+		{
+			name: "array of array",
+			args: []interface{}{
+				[]interface{}{[]interface{}{"1", 2.0}},
+				"array(array(bigint))",
+			},
+			result: []interface{}{[]interface{}{1, 2}},
+		},
+		{
+			name: "not struct",
+			args: []interface{}{
+				1,
+				"struct(id bigint)",
+			},
+			result: fmt.Errorf("not supported type conversion, got error cannot cast int(1) to struct"),
+		},
+		{
+			name: "field mismatch",
+			args: []interface{}{
+				map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "x"}}},
+				"struct(a array(struct(b bigint)))",
+			},
+			result: fmt.Errorf("not supported type conversion, got error field a: array element 0: field b: not supported type conversion, got error cannot convert string(x) to int"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := f.exec(fctx, tt.args)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+
+	vtests := []struct {
+		typ string
+		err string
+	}{
+		{typ: "struct(a bigint, b array(struct(c string)))"},
+		{typ: "array(datetime)"},
+		{typ: "struct(a bigint", err: `invalid cast type struct(a bigint: expect "," or ")" but found ""`},
+		{typ: "struct(a int)", err: `invalid cast type struct(a int): unknown type "int"`},
+		{typ: "array(bigint) x", err: `invalid cast type array(bigint) x: unexpected "x" at the end`},
+		{typ: "struct()", err: `invalid cast type struct(): expect struct field name but found ")"`},
+	}
+	for _, vtt := range vtests {
+		err := f.val(fctx, []ast.Expr{&ast.FieldRef{Name: "foo"}, &ast.StringLiteral{Val: vtt.typ}})
+		if vtt.err == "" {
+			assert.NoError(t, err, vtt.typ)
+		} else {
+			assert.EqualError(t, err, vtt.err, vtt.typ)
+		}
+	}
+	// The dynamic cast types do not grow the cache unbounded
+	for i := 0; i < maxCachedCastTypes+10; i++ {
+		_, err := getCastType(fmt.Sprintf("struct(a%d bigint)", i))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, maxCachedCastTypes, castTypes.len())
+}
+
 func TestProps(t *testing.T) {
 	f, ok := builtins["props"]
 	if !ok {
@@ -670,3 +812,110 @@ func TestProps(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, strconv.FormatInt(tt, 10), et)
 }
+
+func TestJsonQueryValue(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	payload := map[string]interface{}{
+		"device": map[string]interface{}{"id": "d1"},
+		"items": []interface{}{
+			map[string]interface{}{"name": "temp", "v": 23.5},
+			map[string]interface{}{"name": "hum", "v": 60.0},
+		},
+	}
+	tests := []struct {
+		name   string
+		fn     string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "query filter",
+			fn:     "json_query",
+			args:   []interface{}{payload, "$.items[?(@.v > 30)].name"},
+			result: []interface{}{"hum"},
+		},
+		{
+			name:   "query wildcard",
+			fn:     "json_query",
+			args:   []interface{}{payload, "$.items[*].v"},
+			result: []interface{}{23.5, 60.0},
+		},
+		{
+			name:   "query scalar",
+			fn:     "json_query",
+			args:   []interface{}{payload, "$.device.id"},
+			result: []interface{}{"d1"},
+		},
+		{
+			name:   "query not found",
+			fn:     "json_query",
+			args:   []interface{}{payload, "$.device.name"},
+			result: []interface{}{},
+		},
+		{
+			name:   "query json string",
+			fn:     "json_query",
+			args:   []interface{}{`{"a":{"b":[1,2]}}`, "$.a.b[*]"},
+			result: []interface{}{1.0, 2.0},
+		},
+		{
+			name:   "query invalid json",
+			fn:     "json_query",
+			args:   []interface{}{`{"a":`, "$.a"},
+			result: fmt.Errorf("data '{\"a\":' is not a valid json string"),
+		},
+		{
+			name:   "value",
+			fn:     "json_value",
+			args:   []interface{}{payload, "$.device.id"},
+			result: "d1",
+		},
+		{
+			name:   "value filter",
+			fn:     "json_value",
+			args:   []interface{}{payload, `$.items[?(@.name == "temp")].v`},
+			result: 23.5,
+		},
+		{
+			name:   "value default",
+			fn:     "json_value",
+			args:   []interface{}{payload, `$.items[?(@.name == "co2")].v`, 0},
+			result: 0,
+		},
+		{
+			name:   "value not found",
+			fn:     "json_value",
+			args:   []interface{}{payload, "$.device.name"},
+			result: nil,
+		},
+		{
+			name:   "value not scalar",
+			fn:     "json_value",
+			args:   []interface{}{payload, "$.device"},
+			result: fmt.Errorf("the result of json path $.device is not a scalar value, use json_query instead"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			r, _ := f.exec(fctx, tt.args)
+			require.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestJsonQueryValidation(t *testing.T) {
+	f, ok := builtins["json_value"]
+	require.True(t, ok)
+	require.EqualError(t, f.val(nil, []ast.Expr{&ast.FieldRef{Name: "a"}}), "Expect 2 to 3 arguments but found 1.")
+	require.EqualError(t, f.val(nil, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.FieldRef{Name: "b"}}), "Expect string type for parameter 2")
+	require.Error(t, f.val(nil, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "$.a[?(@.b >"}}))
+	require.NoError(t, f.val(nil, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "$.a[?(@.b > 1)]"}}))
+	v, b := f.check([]interface{}{nil, "$.a", 1})
+	require.True(t, b)
+	require.Equal(t, 1, v)
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/rulestate"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// The states of state_get/state_put are shared in the rule while the states of last_value_keep are
+// separated for each function call
+const (
+	ruleStateKeyPrefix     = "state_"
+	lastValueKeepKeyPrefix = "last_value_keep_"
+)
+
+func registerRuleStateFunc() {
+	builtins["state_get"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			if args[0] == nil {
+				return nil, true
+			}
+			v, err := rulestate.Get(ctx.GetRuleId(), ruleStateKeyPrefix+cast.ToStringAlways(args[0]))
+			if err != nil {
+				return err, false
+			}
+			if v == nil && len(args) > 1 {
+				v = args[1]
+			}
+			return v, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) < 1 || len(args) > 2 {
+				return fmt.Errorf("expect one or two arguments but found %d", len(args))
+			}
+			return nil
+		},
+	}
+	builtins["state_put"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			if args[0] == nil {
+				return nil, true
+			}
+			err := rulestate.Put(ctx.GetRuleId(), ruleStateKeyPrefix+cast.ToStringAlways(args[0]), args[1])
+			if err != nil {
+				return err, false
+			}
+			return args[1], true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return ValidateLen(2, len(args))
+		},
+	}
+	builtins["last_value_keep"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			if args[0] == nil {
+				return nil, true
+			}
+			key := fmt.Sprintf("%s%d_%s", lastValueKeepKeyPrefix, ctx.GetFuncId(), cast.ToStringAlways(args[0]))
+			lv, err := rulestate.Get(ctx.GetRuleId(), key)
+			if err != nil {
+				return err, false
+			}
+			if args[1] != nil {
+				err = rulestate.Put(ctx.GetRuleId(), key, args[1])
+				if err != nil {
+					return err, false
+				}
+			}
+			return lv, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return ValidateLen(2, len(args))
+		},
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/rulestate"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestRuleStateFuncs(t *testing.T) {
+	fGet, ok := builtins["state_get"]
+	assert.True(t, ok)
+	fPut, ok := builtins["state_put"]
+	assert.True(t, ok)
+	fKeep, ok := builtins["last_value_keep"]
+	assert.True(t, ok)
+	contextLogger := conf.Log.WithField("rule", "testRuleState")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("testRuleState", def.AtMostOnce)
+	sctx := ctx.WithMeta("testRuleState", "test", tempStore)
+	fctx1 := kctx.NewDefaultFuncContext(sctx, 1)
+	fctx2 := kctx.NewDefaultFuncContext(sctx, 2)
+	defer rulestate.Drop("testRuleState")
+
+	r, ok := fGet.exec(fctx1, []any{"threshold", 30})
+	assert.True(t, ok)
+	assert.Equal(t, 30, r)
+	r, ok = fPut.exec(fctx1, []any{"threshold", int64(40)})
+	assert.True(t, ok)
+	assert.Equal(t, int64(40), r)
+	// The states of state_get/state_put are shared by the function calls
+	r, ok = fGet.exec(fctx2, []any{"threshold", 30})
+	assert.True(t, ok)
+	assert.Equal(t, int64(40), r)
+	r, ok = fGet.exec(fctx2, []any{nil})
+	assert.True(t, ok)
+	assert.Nil(t, r)
+
+	tests := []struct {
+		ctx    *kctx.DefaultFuncContext
+		args   []any
+		result any
+	}{
+		{ctx: fctx1, args: []any{"d1", 20.5}, result: nil},
+		{ctx: fctx1, args: []any{"d2", 30.0}, result: nil},
+		{ctx: fctx1, args: []any{"d1", 21.5}, result: 20.5},
+		// null value is not kept
+		{ctx: fctx1, args: []any{"d1", nil}, result: 21.5},
+		{ctx: fctx1, args: []any{"d1", 22.5}, result: 21.5},
+		// other function call has its own states
+		{ctx: fctx2, args: []any{"d1", 10.0}, result: nil},
+		{ctx: fctx1, args: []any{nil, 10.0}, result: nil},
+	}
+	for i, tt := range tests {
+		r, ok := fKeep.exec(tt.ctx, tt.args)
+		assert.True(t, ok, i)
+		assert.Equal(t, tt.result, r, i)
+	}
+}
+
+func TestRuleStateFuncsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		args []ast.Expr
+		err  error
+	}{
+		{name: "state_get", args: []ast.Expr{&ast.StringLiteral{Val: "a"}}},
+		{name: "state_get", args: []ast.Expr{&ast.StringLiteral{Val: "a"}, &ast.IntegerLiteral{Val: 1}}},
+		{name: "state_get", args: []ast.Expr{}, err: errors.New("expect one or two arguments but found 0")},
+		{name: "state_put", args: []ast.Expr{&ast.StringLiteral{Val: "a"}}, err: errors.New("Expect 2 arguments but found 1.")},
+		{name: "last_value_keep", args: []ast.Expr{&ast.StringLiteral{Val: "a"}, &ast.IntegerLiteral{Val: 1}}},
+	}
+	for _, tt := range tests {
+		err := builtins[tt.name].val(nil, tt.args)
+		assert.Equal(t, tt.err, err, tt.name)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// registerSignalFunc registers the signal processing functions. They all work on an array of samples
+// which are sampled at a fixed rate, such as the result of collect() in a window.
+func registerSignalFunc() {
+	builtins["fft"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			samples, err := toSignal(args[0])
+			if err != nil {
+				return err, false
+			}
+			spectrum := fftMagnitude(samples)
+			result := make([]interface{}, len(spectrum))
+			for i, m := range spectrum {
+				result[i] = m
+			}
+			return result, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return validateSignalArgs(1, args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["fft_freq"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			samples, err := toSignal(args[0])
+			if err != nil {
+				return err, false
+			}
+			params, err := toSignalParams(args[1:])
+			if err != nil {
+				return err, false
+			}
+			n := nextPowerOfTwo(len(samples))
+			result := make([]interface{}, 0, n/2+1)
+			if len(samples) > 0 {
+				for k := 0; k <= n/2; k++ {
+					result = append(result, float64(k)*params[0]/float64(n))
+				}
+			}
+			return result, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return validateSignalArgs(2, args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["lowpass"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			samples, err := toSignal(args[0])
+			if err != nil {
+				return err, false
+			}
+			params, err := toSignalParams(args[1:])
+			if err != nil {
+				return err, false
+			}
+			return signalResult(lowPassFilter(samples, params[0], params[1])), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return validateSignalArgs(3, args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["highpass"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			samples, err := toSignal(args[0])
+			if err != nil {
+				return err, false
+			}
+			params, err := toSignalParams(args[1:])
+			if err != nil {
+				return err, false
+			}
+			return signalResult(highPassFilter(samples, params[0], params[1])), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return validateSignalArgs(3, args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["bandpass"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			samples, err := toSignal(args[0])
+			if err != nil {
+				return err, false
+			}
+			params, err := toSignalParams(args[1:])
+			if err != nil {
+				return err, false
+			}
+			if params[0] >= params[1] {
+				return fmt.Errorf("the low cutoff frequency %v should be less than the high cutoff frequency %v", params[0], params[1]), false
+			}
+			return signalResult(lowPassFilter(highPassFilter(samples, params[0], params[2]), params[1], params[2])), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			return validateSignalArgs(4, args)
+		},
+		check: returnNilIfHasAnyNil,
+	}
+}
+
+// validateSignalArgs validates the first argument is an array and the others are numbers
+func validateSignalArgs(n int, args []ast.Expr) error {
+	if err := ValidateLen(n, len(args)); err != nil {
+		return err
+	}
+	if ast.IsNumericArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) || ast.IsStringArg(args[0]) {
+		return ProduceErrInfo(0, "array")
+	}
+	for i := 1; i < n; i++ {
+		if ast.IsStringArg(args[i]) || ast.IsTimeArg(args[i]) || ast.IsBooleanArg(args[i]) {
+			return ProduceErrInfo(i, "number")
+		}
+	}
+	return nil
+}
+
+func toSignal(arg interface{}) ([]float64, error) {
+	arr, ok := arg.([]interface{})
+	if !ok {
+		return nil, errorArrayFirstArgumentNotArrayError
+	}
+	result := make([]float64, len(arr))
+	for i, v := range arr {
+		f, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("the sample should be a number but got %[1]T(%[1]v)", v)
+		}
+		result[i] = f
+	}
+	return result, nil
+}
+
+// toSignalParams converts the frequency arguments which must be positive
+func toSignalParams(args []interface{}) ([]float64, error) {
+	result := make([]float64, len(args))
+	for i, arg := range args {
+		f, err := cast.ToFloat64(arg, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("the frequency should be a number but got %[1]T(%[1]v)", arg)
+		}
+		if f <= 0 {
+			return nil, fmt.Errorf("the frequency should be positive but got %v", f)
+		}
+		result[i] = f
+	}
+	return result, nil
+}
+
+func signalResult(fs []float64) []interface{} {
+	result := make([]interface{}, len(fs))
+	for i, f := range fs {
+		result[i] = f
+	}
+	return result
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// fftMagnitude returns the magnitudes of the one-sided spectrum. The samples are zero padded to the next power of two,
+// so the result has n/2+1 bins where n is the padded length.
+func fftMagnitude(samples []float64) []float64 {
+	if len(samples) == 0 {
+		return []float64{}
+	}
+	n := nextPowerOfTwo(len(samples))
+	data := make([]complex128, n)
+	for i, s := range samples {
+		data[i] = complex(s, 0)
+	}
+	fft(data)
+	result := make([]float64, n/2+1)
+	for i := range result {
+		result[i] = cmplx.Abs(data[i])
+	}
+	return result
+}
+
+// fft is the in place iterative radix-2 Cooley-Tukey transform. The length of data must be a power of two.
+func fft(data []complex128) {
+	n := len(data)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := data[start+k]
+				v := data[start+k+size/2] * wk
+				data[start+k] = u + v
+				data[start+k+size/2] = u - v
+				wk *= w
+			}
+		}
+	}
+}
+
+// lowPassFilter is a first order RC low pass filter
+func lowPassFilter(samples []float64, cutoff, sampleRate float64) []float64 {
+	result := make([]float64, len(samples))
+	if len(samples) == 0 {
+		return result
+	}
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / sampleRate
+	alpha := dt / (rc + dt)
+	result[0] = samples[0]
+	for i := 1; i < len(samples); i++ {
+		result[i] = result[i-1] + alpha*(samples[i]-result[i-1])
+	}
+	return result
+}
+
+// highPassFilter is a first order RC high pass filter
+func highPassFilter(samples []float64, cutoff, sampleRate float64) []float64 {
+	result := make([]float64, len(samples))
+	if len(samples) == 0 {
+		return result
+	}
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / sampleRate
+	alpha := rc / (rc + dt)
+	result[0] = samples[0]
+	for i := 1; i < len(samples); i++ {
+		result[i] = alpha * (result[i-1] + samples[i] - samples[i-1])
+	}
+	return result
+}
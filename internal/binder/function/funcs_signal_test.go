@@ -0,0 +1,160 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestSignalFunctions(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	step := []interface{}{0, 1, 1, 1}
+	tests := []struct {
+		name   string
+		fn     string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "fft",
+			fn:     "fft",
+			args:   []interface{}{[]interface{}{1, 0, -1, 0}},
+			result: []interface{}{0.0, 2.0, 0.0},
+		},
+		{
+			name:   "fft zero padding",
+			fn:     "fft",
+			args:   []interface{}{[]interface{}{1, 1, 1.0}},
+			result: []interface{}{3.0, 1.0, 1.0},
+		},
+		{
+			name:   "fft empty",
+			fn:     "fft",
+			args:   []interface{}{[]interface{}{}},
+			result: []interface{}{},
+		},
+		{
+			name:   "fft invalid sample",
+			fn:     "fft",
+			args:   []interface{}{[]interface{}{1, "a"}},
+			result: fmt.Errorf("the sample should be a number but got string(a)"),
+		},
+		{
+			name:   "fft not array",
+			fn:     "fft",
+			args:   []interface{}{1},
+			result: errorArrayFirstArgumentNotArrayError,
+		},
+		{
+			name:   "fft freq",
+			fn:     "fft_freq",
+			args:   []interface{}{[]interface{}{1, 1, 1}, 100},
+			result: []interface{}{0.0, 25.0, 50.0},
+		},
+		{
+			name:   "lowpass",
+			fn:     "lowpass",
+			args:   []interface{}{step, 1, 2 * math.Pi},
+			result: []interface{}{0.0, 0.5, 0.75, 0.875},
+		},
+		{
+			name:   "highpass",
+			fn:     "highpass",
+			args:   []interface{}{step, 1, 2 * math.Pi},
+			result: []interface{}{0.0, 0.5, 0.25, 0.125},
+		},
+		{
+			name:   "invalid frequency",
+			fn:     "highpass",
+			args:   []interface{}{step, 0, 2 * math.Pi},
+			result: fmt.Errorf("the frequency should be positive but got 0"),
+		},
+		{
+			name:   "bandpass invalid range",
+			fn:     "bandpass",
+			args:   []interface{}{step, 2, 1, 10},
+			result: fmt.Errorf("the low cutoff frequency 2 should be less than the high cutoff frequency 1"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			r, _ := f.exec(fctx, tt.args)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+	r, ok := builtins["bandpass"].exec(fctx, []interface{}{step, 0.5, 1, 2 * math.Pi})
+	require.True(t, ok)
+	assert.InDeltaSlice(t, []interface{}{0.0, 1.0 / 3, 7.0 / 18, 37.0 / 108}, r, 1e-9)
+	// The spectrum of a sine wave peaks at its frequency
+	samples := make([]interface{}, 64)
+	for i := range samples {
+		samples[i] = math.Sin(2 * math.Pi * 10 * float64(i) / 64)
+	}
+	r, ok = builtins["fft"].exec(fctx, []interface{}{samples})
+	require.True(t, ok)
+	spectrum := r.([]interface{})
+	require.Len(t, spectrum, 33)
+	assert.InDelta(t, 32.0, spectrum[10], 1e-9)
+	assert.InDelta(t, 0.0, spectrum[9], 1e-9)
+}
+
+func TestSignalValidation(t *testing.T) {
+	tests := []struct {
+		fn   string
+		args []ast.Expr
+		err  error
+	}{
+		{
+			fn:   "fft",
+			args: []ast.Expr{},
+			err:  fmt.Errorf("Expect 1 arguments but found 0."),
+		},
+		{
+			fn:   "fft",
+			args: []ast.Expr{&ast.StringLiteral{Val: "a"}},
+			err:  fmt.Errorf("Expect array type for parameter 1"),
+		},
+		{
+			fn:   "lowpass",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "a"}, &ast.IntegerLiteral{Val: 100}},
+			err:  fmt.Errorf("Expect number type for parameter 2"),
+		},
+		{
+			fn:   "bandpass",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 1}, &ast.IntegerLiteral{Val: 10}, &ast.IntegerLiteral{Val: 100}},
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.fn]
+		require.True(t, ok)
+		assert.Equal(t, tt.err, f.val(nil, tt.args), i)
+	}
+}
@@ -15,11 +15,19 @@
 package function
 
 import (
+	"fmt"
+	"math"
+	"sort"
+
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 )
 
+// maxSeriesSize is the max number of items generated by generate_series to avoid running out of memory
+const maxSeriesSize = 100000
+
 func registerSetReturningFunc() {
 	builtins["unnest"] = builtinFunc{
 		fType: ast.FuncTypeSrf,
@@ -34,4 +42,112 @@ func registerSetReturningFunc() {
 		val:   ValidateOneArg,
 		check: returnNilIfHasAnyNil,
 	}
+	builtins["unpivot"] = builtinFunc{
+		fType: ast.FuncTypeSrf,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			obj, ok := args[0].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("the first parameter requires object but found %[1]T(%[1]v)", args[0]), false
+			}
+			keyName, valueName := "key", "value"
+			if len(args) == 3 {
+				keyName, ok = args[1].(string)
+				if !ok {
+					return fmt.Errorf("the second parameter requires string but found %[1]T(%[1]v)", args[1]), false
+				}
+				valueName, ok = args[2].(string)
+				if !ok {
+					return fmt.Errorf("the third parameter requires string but found %[1]T(%[1]v)", args[2]), false
+				}
+			}
+			keys := make([]string, 0, len(obj))
+			for k := range obj {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			result := make([]interface{}, 0, len(keys))
+			for _, k := range keys {
+				result = append(result, map[string]interface{}{
+					keyName:   k,
+					valueName: obj[k],
+				})
+			}
+			return result, true
+		},
+		val: func(ctx api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 1 && len(args) != 3 {
+				return fmt.Errorf("Expect 1 or 3 arguments but found %d.", len(args))
+			}
+			for i := 1; i < len(args); i++ {
+				if ast.IsNumericArg(args[i]) || ast.IsTimeArg(args[i]) || ast.IsBooleanArg(args[i]) {
+					return ProduceErrInfo(i, "string")
+				}
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["generate_series"] = builtinFunc{
+		fType: ast.FuncTypeSrf,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			bounds := make([]int64, 3)
+			bounds[2] = 1
+			for i, arg := range args {
+				v, err := cast.ToInt64(arg, cast.CONVERT_SAMEKIND)
+				if err != nil {
+					return fmt.Errorf("parameter %d requires int but found %[2]T(%[2]v)", i+1, arg), false
+				}
+				bounds[i] = v
+			}
+			start, stop, step := bounds[0], bounds[1], bounds[2]
+			if step == 0 {
+				return fmt.Errorf("the step cannot be 0"), false
+			}
+			if (step > 0 && start > stop) || (step < 0 && start < stop) {
+				return []interface{}{}, true
+			}
+			// Count in uint64 so that the span of the full int64 range does not overflow
+			var span, ustep uint64
+			if step > 0 {
+				span, ustep = uint64(stop)-uint64(start), uint64(step)
+			} else {
+				span, ustep = uint64(start)-uint64(stop), uint64(-step)
+			}
+			steps := span / ustep
+			if steps >= maxSeriesSize {
+				if steps == math.MaxUint64 {
+					return fmt.Errorf("the series exceeds the limit %d", maxSeriesSize), false
+				}
+				return fmt.Errorf("the series has %d items which exceeds the limit %d", steps+1, maxSeriesSize), false
+			}
+			size := int(steps) + 1
+			result := make([]interface{}, size)
+			v := start
+			for i := 0; i < size; i++ {
+				result[i] = v
+				// Do not step beyond the last item which may overflow
+				if i < size-1 {
+					v += step
+				}
+			}
+			return result, true
+		},
+		val: func(ctx api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 2 && len(args) != 3 {
+				return fmt.Errorf("Expect 2 or 3 arguments but found %d.", len(args))
+			}
+			for i, arg := range args {
+				if ast.IsFloatArg(arg) || ast.IsStringArg(arg) || ast.IsTimeArg(arg) || ast.IsBooleanArg(arg) {
+					return ProduceErrInfo(i, "int")
+				}
+			}
+			if len(args) == 3 {
+				if step, ok := args[2].(*ast.IntegerLiteral); ok && step.Val == 0 {
+					return fmt.Errorf("the step cannot be 0")
+				}
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
 }
@@ -16,6 +16,7 @@ package function
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"testing"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 )
 
 func TestUnnestFunctions(t *testing.T) {
@@ -92,3 +94,150 @@ func TestUnnestFunctionsNil(t *testing.T) {
 		require.Nil(t, r, fmt.Sprintf("%v failed", name))
 	}
 }
+
+func TestUnpivot(t *testing.T) {
+	f, ok := builtins["unpivot"]
+	require.True(t, ok)
+	tests := []struct {
+		name   string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name: "default names",
+			args: []interface{}{map[string]interface{}{"temperature": 20.5, "humidity": 60}},
+			result: []interface{}{
+				map[string]interface{}{"key": "humidity", "value": 60},
+				map[string]interface{}{"key": "temperature", "value": 20.5},
+			},
+		},
+		{
+			name: "custom names",
+			args: []interface{}{map[string]interface{}{"temperature": 20.5}, "tag", "v"},
+			result: []interface{}{
+				map[string]interface{}{"tag": "temperature", "v": 20.5},
+			},
+		},
+		{
+			name:   "empty",
+			args:   []interface{}{map[string]interface{}{}},
+			result: []interface{}{},
+		},
+		{
+			name:   "invalid object",
+			args:   []interface{}{[]interface{}{1}},
+			result: fmt.Errorf("the first parameter requires object but found []interface {}([1])"),
+		},
+		{
+			name:   "invalid name",
+			args:   []interface{}{map[string]interface{}{}, 1, "v"},
+			result: fmt.Errorf("the second parameter requires string but found int(1)"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := f.exec(nil, tt.args)
+			require.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestUnpivotValidation(t *testing.T) {
+	f, ok := builtins["unpivot"]
+	require.True(t, ok)
+	tests := []struct {
+		args []ast.Expr
+		err  error
+	}{
+		{
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "k"}},
+			err:  fmt.Errorf("Expect 1 or 3 arguments but found 2."),
+		},
+		{
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 1}, &ast.StringLiteral{Val: "v"}},
+			err:  fmt.Errorf("Expect string type for parameter 2"),
+		},
+		{
+			args: []ast.Expr{&ast.Wildcard{Token: ast.ASTERISK}, &ast.StringLiteral{Val: "k"}, &ast.StringLiteral{Val: "v"}},
+		},
+	}
+	for i, tt := range tests {
+		require.Equal(t, tt.err, f.val(nil, tt.args), i)
+	}
+}
+
+func TestGenerateSeries(t *testing.T) {
+	f, ok := builtins["generate_series"]
+	require.True(t, ok)
+	tests := []struct {
+		name   string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "default step",
+			args:   []interface{}{1, 3},
+			result: []interface{}{int64(1), int64(2), int64(3)},
+		},
+		{
+			name:   "step",
+			args:   []interface{}{int64(1000), 3500, 1000},
+			result: []interface{}{int64(1000), int64(2000), int64(3000)},
+		},
+		{
+			name:   "negative step",
+			args:   []interface{}{3, 1, -2},
+			result: []interface{}{int64(3), int64(1)},
+		},
+		{
+			name:   "empty",
+			args:   []interface{}{3, 1},
+			result: []interface{}{},
+		},
+		{
+			name:   "zero step",
+			args:   []interface{}{1, 3, 0},
+			result: fmt.Errorf("the step cannot be 0"),
+		},
+		{
+			name:   "invalid arg",
+			args:   []interface{}{1, "a"},
+			result: fmt.Errorf("parameter 2 requires int but found string(a)"),
+		},
+		{
+			name:   "too large",
+			args:   []interface{}{0, 1000000},
+			result: fmt.Errorf("the series has 1000001 items which exceeds the limit 100000"),
+		},
+		{
+			name:   "overflow span",
+			args:   []interface{}{int64(math.MinInt64), int64(math.MaxInt64)},
+			result: fmt.Errorf("the series exceeds the limit 100000"),
+		},
+		{
+			name:   "overflow size",
+			args:   []interface{}{int64(math.MinInt64), int64(math.MaxInt64), 2},
+			result: fmt.Errorf("the series has 9223372036854775808 items which exceeds the limit 100000"),
+		},
+		{
+			name:   "near max",
+			args:   []interface{}{int64(math.MaxInt64 - 2), int64(math.MaxInt64), 2},
+			result: []interface{}{int64(math.MaxInt64 - 2), int64(math.MaxInt64)},
+		},
+		{
+			name:   "near min",
+			args:   []interface{}{int64(math.MinInt64 + 1), int64(math.MinInt64), int64(math.MinInt64)},
+			result: []interface{}{int64(math.MinInt64 + 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := f.exec(nil, tt.args)
+			require.Equal(t, tt.result, r)
+		})
+	}
+	require.Equal(t, fmt.Errorf("Expect 2 or 3 arguments but found 1."), f.val(nil, []ast.Expr{&ast.IntegerLiteral{Val: 1}}))
+	require.Equal(t, fmt.Errorf("Expect int type for parameter 2"), f.val(nil, []ast.Expr{&ast.IntegerLiteral{Val: 1}, &ast.StringLiteral{Val: "a"}}))
+	require.Equal(t, fmt.Errorf("the step cannot be 0"), f.val(nil, []ast.Expr{&ast.IntegerLiteral{Val: 1}, &ast.IntegerLiteral{Val: 2}, &ast.IntegerLiteral{Val: 0}}))
+	require.NoError(t, f.val(nil, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 2}}))
+}
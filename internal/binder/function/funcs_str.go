@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -109,9 +110,16 @@ func registerStrFunc() {
 			if err != nil {
 				return err, false
 			}
+			if len(args) == 3 {
+				r, err := padString(arg0, arg1, cast.ToStringAlways(args[2]), true)
+				if err != nil {
+					return err, false
+				}
+				return r, true
+			}
 			return strings.Repeat(" ", arg1) + arg0, true
 		},
-		val:   ValidateOneStrOneInt,
+		val:   validatePad,
 		check: returnNilIfHasAnyNil,
 	}
 	builtins["ltrim"] = builtinFunc{
@@ -149,7 +157,7 @@ func registerStrFunc() {
 		fType: ast.FuncTypeScalar,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
 			arg0, arg1, arg2 := cast.ToStringAlways(args[0]), cast.ToStringAlways(args[1]), cast.ToStringAlways(args[2])
-			if re, err := regexp.Compile(arg1); err != nil {
+			if re, err := compileRegexp(arg1); err != nil {
 				return err, false
 			} else {
 				return re.ReplaceAllString(arg0, arg2), true
@@ -181,6 +189,65 @@ func registerStrFunc() {
 		val:   ValidateTwoStrArg,
 		check: returnNilIfHasAnyNil,
 	}
+	builtins["regexp_extract"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			re, group, err := regexpGroup(args)
+			if err != nil {
+				return err, false
+			}
+			m := re.FindStringSubmatch(cast.ToStringAlways(args[0]))
+			if m == nil {
+				return nil, true
+			}
+			return m[group], true
+		},
+		val:   validateRegexpExtract,
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["regexp_extract_all"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			re, group, err := regexpGroup(args)
+			if err != nil {
+				return err, false
+			}
+			ms := re.FindAllStringSubmatch(cast.ToStringAlways(args[0]), -1)
+			result := make([]interface{}, len(ms))
+			for i, m := range ms {
+				result[i] = m[group]
+			}
+			return result, true
+		},
+		val:   validateRegexpExtract,
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["regexp_extract_groups"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			re, err := compileRegexp(cast.ToStringAlways(args[1]))
+			if err != nil {
+				return err, false
+			}
+			m := re.FindStringSubmatch(cast.ToStringAlways(args[0]))
+			if m == nil {
+				return nil, true
+			}
+			result := make(map[string]interface{}, len(m)-1)
+			for i, name := range re.SubexpNames() {
+				if i == 0 {
+					continue
+				}
+				if name == "" {
+					name = strconv.Itoa(i)
+				}
+				result[name] = m[i]
+			}
+			return result, true
+		},
+		val:   ValidateTwoStrArg,
+		check: returnNilIfHasAnyNil,
+	}
 	builtins["reverse"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
@@ -202,9 +269,16 @@ func registerStrFunc() {
 			if err != nil {
 				return err, false
 			}
+			if len(args) == 3 {
+				r, err := padString(arg0, arg1, cast.ToStringAlways(args[2]), false)
+				if err != nil {
+					return err, false
+				}
+				return r, true
+			}
 			return arg0 + strings.Repeat(" ", arg1), true
 		},
-		val:   ValidateOneStrOneInt,
+		val:   validatePad,
 		check: returnNilIfHasAnyNil,
 	}
 	builtins["rtrim"] = builtinFunc{
@@ -357,6 +431,84 @@ func registerStrFunc() {
 		val:   ValidateOneStrArg,
 		check: returnNilIfHasAnyNil,
 	}
+	builtins["split_part"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			arg0, arg1 := cast.ToStringAlways(args[0]), cast.ToStringAlways(args[1])
+			n, err := cast.ToInt(args[2], cast.STRICT)
+			if err != nil {
+				return err, false
+			}
+			if n == 0 {
+				return errors.New("the part index should not be 0"), false
+			}
+			ss := strings.Split(arg0, arg1)
+			if n < 0 {
+				n = len(ss) + n + 1
+			}
+			if n < 1 || n > len(ss) {
+				return "", true
+			}
+			return ss[n-1], true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(3, len(args)); err != nil {
+				return err
+			}
+			if ast.IsNumericArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+				return ProduceErrInfo(0, "string")
+			}
+			if ast.IsNumericArg(args[1]) || ast.IsTimeArg(args[1]) || ast.IsBooleanArg(args[1]) {
+				return ProduceErrInfo(1, "string")
+			}
+			if ast.IsFloatArg(args[2]) || ast.IsTimeArg(args[2]) || ast.IsBooleanArg(args[2]) || ast.IsStringArg(args[2]) {
+				return ProduceErrInfo(2, "int")
+			}
+			if s, ok := args[2].(*ast.IntegerLiteral); ok && s.Val == 0 {
+				return errors.New("the part index should not be 0")
+			}
+			return nil
+		},
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["format_string"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			return fmt.Sprintf(cast.ToStringAlways(args[0]), args[1:]...), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateAtLeast(1, len(args)); err != nil {
+				return err
+			}
+			if ast.IsNumericArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+				return ProduceErrInfo(0, "string")
+			}
+			return nil
+		},
+		// Only the format is required, the nil arguments are formatted as <nil>
+		check: func(args []interface{}) (interface{}, bool) {
+			if args[0] == nil {
+				return nil, true
+			}
+			return nil, false
+		},
+	}
+	builtins["levenshtein"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			return levenshtein(cast.ToStringAlways(args[0]), cast.ToStringAlways(args[1])), true
+		},
+		val:   ValidateTwoStrArg,
+		check: returnNilIfHasAnyNil,
+	}
+	builtins["soundex"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			return soundex(cast.ToStringAlways(args[0])), true
+		},
+		val:   ValidateOneStrArg,
+		check: returnNilIfHasAnyNil,
+	}
 	builtins["format"] = builtinFunc{
 		fType: ast.FuncTypeScalar,
 		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
@@ -401,3 +553,197 @@ func registerStrFunc() {
 		check: returnNilIfHasAnyNil,
 	}
 }
+
+// maxCachedRegexps is the max number of the compiled regexps kept in the cache
+const maxCachedRegexps = 1024
+
+var regexpCache = newLruCache[*regexp.Regexp](maxCachedRegexps)
+
+// compileRegexp compiles the regex and caches it because the regex is usually a literal for all the events
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache.get(expr); ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.set(expr, re)
+	return re, nil
+}
+
+// regexpGroup returns the compiled regex and the index of the group to extract which is 0 by default
+func regexpGroup(args []interface{}) (*regexp.Regexp, int, error) {
+	re, err := compileRegexp(cast.ToStringAlways(args[1]))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(args) < 3 {
+		return re, 0, nil
+	}
+	switch g := args[2].(type) {
+	case string:
+		index := re.SubexpIndex(g)
+		if index < 0 {
+			return nil, 0, fmt.Errorf("the regex %s has no group named %s", re, g)
+		}
+		return re, index, nil
+	default:
+		index, err := cast.ToInt(g, cast.STRICT)
+		if err != nil {
+			return nil, 0, fmt.Errorf("the group should be an integer or a string but got %[1]T(%[1]v)", g)
+		}
+		if index < 0 || index > re.NumSubexp() {
+			return nil, 0, fmt.Errorf("the regex %s has %d groups but got group index %d", re, re.NumSubexp(), index)
+		}
+		return re, index, nil
+	}
+}
+
+func validateRegexpExtract(_ api.FunctionContext, args []ast.Expr) error {
+	if len(args) != 2 && len(args) != 3 {
+		return fmt.Errorf("Expect 2 or 3 arguments but found %d.", len(args))
+	}
+	for i := 0; i < 2; i++ {
+		if ast.IsNumericArg(args[i]) || ast.IsTimeArg(args[i]) || ast.IsBooleanArg(args[i]) {
+			return ProduceErrInfo(i, "string")
+		}
+	}
+	if sl, ok := args[1].(*ast.StringLiteral); ok {
+		if _, err := regexp.Compile(sl.Val); err != nil {
+			return fmt.Errorf("invalid regex %s: %v", sl.Val, err)
+		}
+	}
+	if len(args) == 3 {
+		if ast.IsFloatArg(args[2]) || ast.IsTimeArg(args[2]) || ast.IsBooleanArg(args[2]) {
+			return ProduceErrInfo(2, "int or string")
+		}
+		if il, ok := args[2].(*ast.IntegerLiteral); ok && il.Val < 0 {
+			return fmt.Errorf("the group index should not be negative")
+		}
+	}
+	return nil
+}
+
+func validatePad(_ api.FunctionContext, args []ast.Expr) error {
+	if len(args) != 2 && len(args) != 3 {
+		return fmt.Errorf("Expect 2 or 3 arguments but found %d.", len(args))
+	}
+	if ast.IsNumericArg(args[0]) || ast.IsTimeArg(args[0]) || ast.IsBooleanArg(args[0]) {
+		return ProduceErrInfo(0, "string")
+	}
+	if ast.IsFloatArg(args[1]) || ast.IsTimeArg(args[1]) || ast.IsBooleanArg(args[1]) || ast.IsStringArg(args[1]) {
+		return ProduceErrInfo(1, "int")
+	}
+	if len(args) == 3 {
+		if ast.IsNumericArg(args[2]) || ast.IsTimeArg(args[2]) || ast.IsBooleanArg(args[2]) {
+			return ProduceErrInfo(2, "string")
+		}
+		if s, ok := args[2].(*ast.StringLiteral); ok && s.Val == "" {
+			return errors.New("the pad string should not be empty")
+		}
+		if l, ok := args[1].(*ast.IntegerLiteral); ok && l.Val > maxPadLength {
+			return fmt.Errorf("the length should not be larger than %d", maxPadLength)
+		}
+	}
+	return nil
+}
+
+// maxPadLength is the max length in characters of the padded string
+const maxPadLength = 1 << 20
+
+// padString pads the string to the length in characters by repeating the pad. The string longer than the length is
+// returned as is.
+func padString(s string, length int, pad string, left bool) (string, error) {
+	if length > maxPadLength {
+		return "", fmt.Errorf("the length %d is larger than the max %d", length, maxPadLength)
+	}
+	n := length - utf8.RuneCountInString(s)
+	if n <= 0 || pad == "" {
+		return s, nil
+	}
+	padRunes := []rune(pad)
+	p := make([]rune, n)
+	for i := range p {
+		p[i] = padRunes[i%len(padRunes)]
+	}
+	if left {
+		return string(p) + s, nil
+	}
+	return s + string(p), nil
+}
+
+// levenshtein returns the edit distance in characters of two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d := prev[j] + 1
+			if curr[j-1]+1 < d {
+				d = curr[j-1] + 1
+			}
+			if prev[j-1]+cost < d {
+				d = prev[j-1] + cost
+			}
+			curr[j] = d
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+var soundexCodes = map[rune]byte{
+	'B': '1', 'F': '1', 'P': '1', 'V': '1',
+	'C': '2', 'G': '2', 'J': '2', 'K': '2', 'Q': '2', 'S': '2', 'X': '2', 'Z': '2',
+	'D': '3', 'T': '3',
+	'L': '4',
+	'M': '5', 'N': '5',
+	'R': '6',
+}
+
+// soundex returns the American Soundex code of the string. The non-letter characters are ignored.
+func soundex(s string) string {
+	var (
+		result = make([]byte, 0, 4)
+		last   byte
+	)
+	for _, r := range strings.ToUpper(s) {
+		if r < 'A' || r > 'Z' {
+			continue
+		}
+		code, ok := soundexCodes[r]
+		if len(result) == 0 {
+			result = append(result, byte(r))
+			last = code
+			continue
+		}
+		switch {
+		case ok && code != last:
+			result = append(result, code)
+			last = code
+		case !ok && r != 'H' && r != 'W':
+			// vowels separate the same codes while H and W do not
+			last = 0
+		}
+		if len(result) == 4 {
+			break
+		}
+	}
+	if len(result) == 0 {
+		return ""
+	}
+	for len(result) < 4 {
+		result = append(result, '0')
+	}
+	return string(result)
+}
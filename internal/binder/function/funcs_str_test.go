@@ -359,3 +359,270 @@ func TestStringFuncVal(t *testing.T) {
 		})
 	}
 }
+
+func TestRegexpExtract(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	line := "2024-05-01 ERROR [db] connection lost, 2024-05-01 WARN [api] slow"
+	tests := []struct {
+		name   string
+		fn     string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "whole match",
+			fn:     "regexp_extract",
+			args:   []interface{}{line, `\[\w+\]`},
+			result: "[db]",
+		},
+		{
+			name:   "numbered group",
+			fn:     "regexp_extract",
+			args:   []interface{}{line, `(\w+) \[(\w+)\]`, 2},
+			result: "db",
+		},
+		{
+			name:   "named group",
+			fn:     "regexp_extract",
+			args:   []interface{}{line, `(?P<level>[A-Z]+) \[(?P<module>\w+)\]`, "level"},
+			result: "ERROR",
+		},
+		{
+			name:   "no match",
+			fn:     "regexp_extract",
+			args:   []interface{}{line, `FATAL`},
+			result: nil,
+		},
+		{
+			name:   "invalid group index",
+			fn:     "regexp_extract",
+			args:   []interface{}{line, `(\w+)`, 2},
+			result: fmt.Errorf("the regex (\\w+) has 1 groups but got group index 2"),
+		},
+		{
+			name:   "invalid group name",
+			fn:     "regexp_extract",
+			args:   []interface{}{line, `(?P<level>[A-Z]+)`, "name"},
+			result: fmt.Errorf("the regex (?P<level>[A-Z]+) has no group named name"),
+		},
+		{
+			name:   "extract all",
+			fn:     "regexp_extract_all",
+			args:   []interface{}{line, `(?P<level>[A-Z]+) \[(?P<module>\w+)\]`, "module"},
+			result: []interface{}{"db", "api"},
+		},
+		{
+			name:   "extract all no match",
+			fn:     "regexp_extract_all",
+			args:   []interface{}{line, `FATAL`},
+			result: []interface{}{},
+		},
+		{
+			name:   "extract groups",
+			fn:     "regexp_extract_groups",
+			args:   []interface{}{line, `(?P<level>[A-Z]+) \[(\w+)\]`},
+			result: map[string]interface{}{"level": "ERROR", "2": "db"},
+		},
+		{
+			name:   "replace does not support back reference",
+			fn:     "regexp_replace",
+			args:   []interface{}{"john@example", `(\w+)@(\w+)`, `\2/\1`},
+			result: `\2/\1`,
+		},
+		{
+			name:   "replace with named reference",
+			fn:     "regexp_replace",
+			args:   []interface{}{"john@example", `(?P<user>\w+)@(?P<host>\w+)`, `${host}/${user}`},
+			result: "example/john",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			r, _ := f.exec(fctx, tt.args)
+			require.Equal(t, tt.result, r)
+		})
+	}
+	f := builtins["regexp_extract"]
+	require.EqualError(t, f.val(fctx, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "("}}), "invalid regex (: error parsing regexp: missing closing ): `(`")
+	require.EqualError(t, f.val(fctx, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "a"}, &ast.BooleanLiteral{Val: true}}), "Expect int or string type for parameter 3")
+	require.NoError(t, f.val(fctx, []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "(a)"}, &ast.IntegerLiteral{Val: 1}}))
+}
+
+func TestRegexpCacheBound(t *testing.T) {
+	for i := 0; i < maxCachedRegexps+10; i++ {
+		_, err := compileRegexp(fmt.Sprintf("a%d", i))
+		require.NoError(t, err)
+	}
+	require.Equal(t, maxCachedRegexps, regexpCache.len())
+	// The least recently used are evicted
+	_, ok := regexpCache.get("a0")
+	require.False(t, ok)
+	_, ok = regexpCache.get(fmt.Sprintf("a%d", maxCachedRegexps+9))
+	require.True(t, ok)
+}
+
+func TestStringNormalizeFunc(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("mockRule0", def.AtMostOnce)
+	fctx := kctx.NewDefaultFuncContext(ctx.WithMeta("mockRule0", "test", tempStore), 2)
+	tests := []struct {
+		name   string
+		fn     string
+		args   []interface{}
+		result interface{}
+	}{
+		{
+			name:   "format string",
+			fn:     "format_string",
+			args:   []interface{}{"%s-%04d-%.2f", "dev", 42, 3.14159},
+			result: "dev-0042-3.14",
+		},
+		{
+			name:   "format string without args",
+			fn:     "format_string",
+			args:   []interface{}{"plain"},
+			result: "plain",
+		},
+		{
+			name:   "split part",
+			fn:     "split_part",
+			args:   []interface{}{"site/line1/dev42", "/", 2},
+			result: "line1",
+		},
+		{
+			name:   "split part from end",
+			fn:     "split_part",
+			args:   []interface{}{"site/line1/dev42", "/", -1},
+			result: "dev42",
+		},
+		{
+			name:   "split part out of range",
+			fn:     "split_part",
+			args:   []interface{}{"site/line1/dev42", "/", 4},
+			result: "",
+		},
+		{
+			name:   "split part zero",
+			fn:     "split_part",
+			args:   []interface{}{"site/line1/dev42", "/", 0},
+			result: errors.New("the part index should not be 0"),
+		},
+		{
+			name:   "lpad spaces",
+			fn:     "lpad",
+			args:   []interface{}{"42", 2},
+			result: "  42",
+		},
+		{
+			name:   "lpad to length",
+			fn:     "lpad",
+			args:   []interface{}{"42", 5, "0"},
+			result: "00042",
+		},
+		{
+			name:   "lpad longer",
+			fn:     "lpad",
+			args:   []interface{}{"123456", 5, "0"},
+			result: "123456",
+		},
+		{
+			name:   "rpad to length",
+			fn:     "rpad",
+			args:   []interface{}{"ab", 7, "xy"},
+			result: "abxyxyx",
+		},
+		{
+			name:   "rpad too long",
+			fn:     "rpad",
+			args:   []interface{}{"ab", 1 << 30, "xy"},
+			result: errors.New("the length 1073741824 is larger than the max 1048576"),
+		},
+		{
+			name:   "levenshtein",
+			fn:     "levenshtein",
+			args:   []interface{}{"kitten", "sitting"},
+			result: 3,
+		},
+		{
+			name:   "levenshtein unicode",
+			fn:     "levenshtein",
+			args:   []interface{}{"héllo", "hello"},
+			result: 1,
+		},
+		{
+			name:   "soundex",
+			fn:     "soundex",
+			args:   []interface{}{"Robert"},
+			result: "R163",
+		},
+		{
+			name:   "soundex same code separated by h",
+			fn:     "soundex",
+			args:   []interface{}{"Ashcraft"},
+			result: "A261",
+		},
+		{
+			name:   "soundex no letter",
+			fn:     "soundex",
+			args:   []interface{}{"123"},
+			result: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			r, _ := f.exec(fctx, tt.args)
+			require.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestStringNormalizeValidation(t *testing.T) {
+	tests := []struct {
+		fn   string
+		args []ast.Expr
+		err  error
+	}{
+		{
+			fn:   "lpad",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 5}, &ast.StringLiteral{Val: ""}},
+			err:  errors.New("the pad string should not be empty"),
+		},
+		{
+			fn:   "lpad",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 1 << 30}, &ast.StringLiteral{Val: "x"}},
+			err:  errors.New("the length should not be larger than 1048576"),
+		},
+		{
+			fn:   "rpad",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}},
+			err:  errors.New("Expect 2 or 3 arguments but found 1."),
+		},
+		{
+			fn:   "split_part",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "/"}, &ast.IntegerLiteral{Val: 0}},
+			err:  errors.New("the part index should not be 0"),
+		},
+		{
+			fn:   "format_string",
+			args: []ast.Expr{&ast.IntegerLiteral{Val: 1}},
+			err:  errors.New("Expect string type for parameter 1"),
+		},
+		{
+			fn:   "levenshtein",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.StringLiteral{Val: "b"}},
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.fn]
+		require.True(t, ok)
+		require.Equal(t, tt.err, f.val(nil, tt.args), i)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// registerTimeSeriesFunc registers the time series analytic functions.
+// Like other analytic functions, the last two args are the when condition result and the partition key.
+// The states only save float64 and []float64 so that they can be checkpointed.
+func registerTimeSeriesFunc() {
+	builtins["delta"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			valid, key, err := analyticMeta(args)
+			if err != nil {
+				return err, false
+			}
+			if !valid || args[0] == nil {
+				return nil, true
+			}
+			v, err := cast.ToFloat64(args[0], cast.CONVERT_SAMEKIND)
+			if err != nil {
+				return fmt.Errorf("the value should be number but got %[1]T(%[1]v)", args[0]), false
+			}
+			isCounter := false
+			if len(args) > 3 {
+				isCounter, _ = args[1].(bool)
+			}
+			prev, err := ctx.GetState(key)
+			if err != nil {
+				return err, false
+			}
+			if err := ctx.PutState(key, v); err != nil {
+				return err, false
+			}
+			if prev == nil {
+				return nil, true
+			}
+			return counterDelta(prev.(float64), v, isCounter), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 1 && len(args) != 2 {
+				return fmt.Errorf("expect one or two args but got %d", len(args))
+			}
+			if err := validateTimeSeriesValue(args[0]); err != nil {
+				return err
+			}
+			if len(args) == 2 && !ast.IsBooleanArg(args[1]) {
+				return ProduceErrInfo(1, "bool")
+			}
+			return nil
+		},
+	}
+	builtins["rate"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			valid, key, err := analyticMeta(args)
+			if err != nil {
+				return err, false
+			}
+			if !valid || args[0] == nil {
+				return nil, true
+			}
+			v, err := cast.ToFloat64(args[0], cast.CONVERT_SAMEKIND)
+			if err != nil {
+				return fmt.Errorf("the value should be number but got %[1]T(%[1]v)", args[0]), false
+			}
+			ts, err := timeSeriesTimestamp(args, 1)
+			if err != nil {
+				return err, false
+			}
+			prev, err := ctx.GetState(key)
+			if err != nil {
+				return err, false
+			}
+			if err := ctx.PutState(key, []float64{v, float64(ts)}); err != nil {
+				return err, false
+			}
+			if prev == nil {
+				return nil, true
+			}
+			pv := prev.([]float64)
+			elapsed := float64(ts) - pv[1]
+			if elapsed <= 0 {
+				return nil, true
+			}
+			return counterDelta(pv[0], v, true) * 1000 / elapsed, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 1 && len(args) != 2 {
+				return fmt.Errorf("expect one or two args but got %d", len(args))
+			}
+			if err := validateTimeSeriesValue(args[0]); err != nil {
+				return err
+			}
+			if len(args) == 2 && (ast.IsBooleanArg(args[1]) || ast.IsFloatArg(args[1])) {
+				return ProduceErrInfo(1, "timestamp")
+			}
+			return nil
+		},
+	}
+	builtins["ewma"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			valid, key, err := analyticMeta(args)
+			if err != nil {
+				return err, false
+			}
+			prev, err := ctx.GetState(key)
+			if err != nil {
+				return err, false
+			}
+			if !valid || args[0] == nil {
+				return prev, true
+			}
+			v, err := cast.ToFloat64(args[0], cast.CONVERT_SAMEKIND)
+			if err != nil {
+				return fmt.Errorf("the value should be number but got %[1]T(%[1]v)", args[0]), false
+			}
+			alpha, err := cast.ToFloat64(args[1], cast.CONVERT_SAMEKIND)
+			if err != nil || alpha <= 0 || alpha > 1 {
+				return fmt.Errorf("the smoothing factor should be a number in (0, 1] but got %v", args[1]), false
+			}
+			if prev != nil {
+				v = alpha*v + (1-alpha)*prev.(float64)
+			}
+			if err := ctx.PutState(key, v); err != nil {
+				return err, false
+			}
+			return v, true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if err := ValidateLen(2, len(args)); err != nil {
+				return err
+			}
+			if err := validateTimeSeriesValue(args[0]); err != nil {
+				return err
+			}
+			if !ast.IsNumericArg(args[1]) {
+				return ProduceErrInfo(1, "number")
+			}
+			var alpha float64
+			switch a := args[1].(type) {
+			case *ast.IntegerLiteral:
+				alpha = float64(a.Val)
+			case *ast.NumberLiteral:
+				alpha = a.Val
+			}
+			if alpha <= 0 || alpha > 1 {
+				return fmt.Errorf("the smoothing factor should be in (0, 1] but got %v", alpha)
+			}
+			return nil
+		},
+	}
+	builtins["slope"] = builtinFunc{
+		fType: ast.FuncTypeScalar,
+		exec: func(ctx api.FunctionContext, args []interface{}) (interface{}, bool) {
+			valid, key, err := analyticMeta(args)
+			if err != nil {
+				return err, false
+			}
+			if !valid || args[0] == nil {
+				return nil, true
+			}
+			v, err := cast.ToFloat64(args[0], cast.CONVERT_SAMEKIND)
+			if err != nil {
+				return fmt.Errorf("the value should be number but got %[1]T(%[1]v)", args[0]), false
+			}
+			lookback, err := cast.ToInt64(args[1], cast.CONVERT_SAMEKIND)
+			if err != nil || lookback <= 0 {
+				return fmt.Errorf("the lookback period should be a positive integer but got %v", args[1]), false
+			}
+			ts, err := timeSeriesTimestamp(args, 2)
+			if err != nil {
+				return err, false
+			}
+			prev, err := ctx.GetState(key)
+			if err != nil {
+				return err, false
+			}
+			// the points are saved as a flat array of timestamp and value pairs
+			var points []float64
+			if prev != nil {
+				points = prev.([]float64)
+			}
+			start := 0
+			for start < len(points) && points[start] < float64(ts-lookback) {
+				start += 2
+			}
+			points = append(points[start:], float64(ts), v)
+			if err := ctx.PutState(key, points); err != nil {
+				return err, false
+			}
+			return linearSlope(points), true
+		},
+		val: func(_ api.FunctionContext, args []ast.Expr) error {
+			if len(args) != 2 && len(args) != 3 {
+				return fmt.Errorf("expect two or three args but got %d", len(args))
+			}
+			if err := validateTimeSeriesValue(args[0]); err != nil {
+				return err
+			}
+			il, ok := args[1].(*ast.IntegerLiteral)
+			if !ok {
+				return ProduceErrInfo(1, "int")
+			}
+			if il.Val <= 0 {
+				return fmt.Errorf("the lookback period should be a positive integer")
+			}
+			if len(args) == 3 && (ast.IsBooleanArg(args[2]) || ast.IsFloatArg(args[2])) {
+				return ProduceErrInfo(2, "timestamp")
+			}
+			return nil
+		},
+	}
+}
+
+// analyticMeta returns the when condition result and the partition key appended to the args of analytic functions
+func analyticMeta(args []interface{}) (bool, string, error) {
+	validData, ok := args[len(args)-2].(bool)
+	if !ok {
+		return false, "", fmt.Errorf("when arg is not a bool but got %v", args[len(args)-2])
+	}
+	return validData, args[len(args)-1].(string), nil
+}
+
+// timeSeriesTimestamp returns the timestamp arg at the index, or the current time if it is not set
+func timeSeriesTimestamp(args []interface{}, index int) (int64, error) {
+	if len(args)-2 <= index {
+		return timex.GetNowInMilli(), nil
+	}
+	ts, err := cast.InterfaceToUnixMilli(args[index], "")
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %v: %v", args[index], err)
+	}
+	return ts, nil
+}
+
+func validateTimeSeriesValue(arg ast.Expr) error {
+	if ast.IsStringArg(arg) || ast.IsTimeArg(arg) || ast.IsBooleanArg(arg) {
+		return ProduceErrInfo(0, "number")
+	}
+	return nil
+}
+
+// counterDelta returns the increase of the value. For counters, a decrease means the counter is reset,
+// so the increase is the current value.
+func counterDelta(prev, v float64, isCounter bool) float64 {
+	if isCounter && v < prev {
+		return v
+	}
+	return v - prev
+}
+
+// linearSlope calculates the least squares slope per second of the flat timestamp and value pairs
+func linearSlope(points []float64) interface{} {
+	n := float64(len(points) / 2)
+	if n < 2 {
+		return nil
+	}
+	// use the first timestamp as origin to avoid losing precision
+	origin := points[0]
+	var sumX, sumY, sumXY, sumXX float64
+	for i := 0; i < len(points); i += 2 {
+		x := (points[i] - origin) / 1000
+		y := points[i+1]
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	d := n*sumXX - sumX*sumX
+	if d == 0 {
+		return nil
+	}
+	return (n*sumXY - sumX*sumY) / d
+}
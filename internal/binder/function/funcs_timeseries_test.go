@@ -0,0 +1,166 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package function
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestTimeSeriesFuncs(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testExec")
+	ctx := kctx.WithValue(kctx.Background(), kctx.LoggerKey, contextLogger)
+	tests := []struct {
+		name    string
+		fn      string
+		args    [][]interface{}
+		results []interface{}
+	}{
+		{
+			name: "delta",
+			fn:   "delta",
+			args: [][]interface{}{
+				{10, true, "self"},
+				{15.5, true, "self"},
+				{nil, true, "self"},
+				{12, true, "self"},
+				{20, false, "self"},
+				{3, true, "other"},
+			},
+			results: []interface{}{nil, 5.5, nil, -3.5, nil, nil},
+		},
+		{
+			name: "counter delta",
+			fn:   "delta",
+			args: [][]interface{}{
+				{10, true, true, "self"},
+				{15, true, true, "self"},
+				{4, true, true, "self"},
+			},
+			results: []interface{}{nil, 5.0, 4.0},
+		},
+		{
+			name: "rate",
+			fn:   "rate",
+			args: [][]interface{}{
+				{100, int64(1000), true, "self"},
+				{150, int64(3000), true, "self"},
+				{20, int64(5000), true, "self"},
+				{30, int64(5000), true, "self"},
+			},
+			results: []interface{}{nil, 25.0, 10.0, nil},
+		},
+		{
+			name: "ewma",
+			fn:   "ewma",
+			args: [][]interface{}{
+				{nil, 0.5, true, "self"},
+				{10, 0.5, true, "self"},
+				{20, 0.5, true, "self"},
+				{100, 0.5, false, "self"},
+				{40, 0.5, true, "self"},
+			},
+			results: []interface{}{nil, 10.0, 15.0, 15.0, 27.5},
+		},
+		{
+			name: "slope",
+			fn:   "slope",
+			args: [][]interface{}{
+				{10, 3000, int64(1000), true, "self"},
+				{12, 3000, int64(2000), true, "self"},
+				{14, 3000, int64(3000), true, "self"},
+				{10, 3000, int64(6000), true, "self"},
+			},
+			// the last one only keeps the points with timestamp 3000 and 6000
+			results: []interface{}{nil, 2.0, 2.0, -4.0 / 3},
+		},
+		{
+			name: "invalid value",
+			fn:   "delta",
+			args: [][]interface{}{
+				{"a", true, "self"},
+			},
+			results: []interface{}{fmt.Errorf("the value should be number but got string(a)")},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := builtins[tt.fn]
+			require.True(t, ok)
+			tempStore, _ := state.CreateStore(fmt.Sprintf("mockRule%d", i), def.AtMostOnce)
+			fctx := kctx.NewDefaultFuncContext(ctx.WithMeta(fmt.Sprintf("mockRule%d", i), "test", tempStore), 2)
+			for j, args := range tt.args {
+				r, _ := f.exec(fctx, args)
+				if fr, ok := r.(float64); ok {
+					assert.InDelta(t, tt.results[j], fr, 1e-9, j)
+				} else {
+					assert.Equal(t, tt.results[j], r, j)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeSeriesValidation(t *testing.T) {
+	tests := []struct {
+		fn   string
+		args []ast.Expr
+		err  string
+	}{
+		{
+			fn:   "delta",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 1}},
+			err:  "Expect bool type for parameter 2",
+		},
+		{
+			fn:   "rate",
+			args: []ast.Expr{&ast.StringLiteral{Val: "a"}},
+			err:  "Expect number type for parameter 1",
+		},
+		{
+			fn:   "ewma",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.NumberLiteral{Val: 1.5}},
+			err:  "the smoothing factor should be in (0, 1] but got 1.5",
+		},
+		{
+			fn:   "slope",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 0}},
+			err:  "the lookback period should be a positive integer",
+		},
+		{
+			fn:   "slope",
+			args: []ast.Expr{&ast.FieldRef{Name: "a"}, &ast.IntegerLiteral{Val: 60000}, &ast.FieldRef{Name: "ts"}},
+		},
+	}
+	for i, tt := range tests {
+		f, ok := builtins[tt.fn]
+		require.True(t, ok)
+		err := f.val(nil, tt.args)
+		if tt.err == "" {
+			assert.NoError(t, err, i)
+		} else {
+			assert.EqualError(t, err, tt.err, i)
+		}
+	}
+}
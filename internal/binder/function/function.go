@@ -54,11 +54,17 @@ func init() {
 	registerStrFunc()
 	registerMiscFunc()
 	registerAnalyticFunc()
+	registerTimeSeriesFunc()
 	registerColsFunc()
 	registerSetReturningFunc()
 	registerArrayFunc()
 	registerObjectFunc()
+	registerLambdaFunc()
+	registerGeoFunc()
+	registerSignalFunc()
+	registerBytesFunc()
 	registerGlobalStateFunc()
+	registerRuleStateFunc()
 	registerDateTimeFunc()
 	registerGlobalAggFunc()
 	registerWindowFunc()
@@ -71,6 +77,7 @@ func init() {
 
 var analyticFuncs = map[string]struct{}{
 	"lag":         {},
+	"lead":        {},
 	"changed_col": {},
 	"had_changed": {},
 	"latest":      {},
@@ -79,6 +86,10 @@ var analyticFuncs = map[string]struct{}{
 	"acc_max":     {},
 	"acc_avg":     {},
 	"acc_count":   {},
+	"delta":       {},
+	"rate":        {},
+	"ewma":        {},
+	"slope":       {},
 }
 
 var windowFuncs = map[string]struct{}{
@@ -53,6 +53,7 @@ func init() {
 	modules.RegisterSink("websocket", func() api.Sink { return websocket.GetSink() })
 
 	modules.RegisterLookupSource("memory", memory.GetLookupSource)
+	modules.RegisterLookupSource("simulator", simulator.GetLookupSource)
 	modules.RegisterLookupSource("httppull", http.GetLookUpSource)
 
 	modules.RegisterConnection("mqtt", mqtt.CreateConnection)
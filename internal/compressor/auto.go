@@ -0,0 +1,79 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build compression || !core
+
+package compressor
+
+import (
+	"bytes"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	lz4Magic  = []byte{0x04, 0x22, 0x4d, 0x18}
+)
+
+// autoDecompressor detects the compression method by the magic bytes of each payload.
+// The payload without known magic bytes, such as the uncompressed data, is passed through as is.
+// Flate is not supported since it has no magic bytes.
+type autoDecompressor struct {
+	decompressors map[string]message.Decompressor
+}
+
+func newAutoDecompressor() (*autoDecompressor, error) {
+	return &autoDecompressor{decompressors: make(map[string]message.Decompressor)}, nil
+}
+
+func (a *autoDecompressor) Decompress(data []byte) ([]byte, error) {
+	method := detect(data)
+	if method == "" {
+		return data, nil
+	}
+	d, ok := a.decompressors[method]
+	if !ok {
+		var err error
+		d, err = GetDecompressor(method)
+		if err != nil {
+			return nil, err
+		}
+		a.decompressors[method] = d
+	}
+	r, err := d.Decompress(data)
+	// The zlib header is only 2 bytes which may be the start of the uncompressed data
+	if err != nil && method == ZLIB {
+		return data, nil
+	}
+	return r, err
+}
+
+// detect returns the compression method by the magic bytes, or empty if unknown
+func detect(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		return GZIP
+	case bytes.HasPrefix(data, zstdMagic):
+		return ZSTD
+	case bytes.HasPrefix(data, lz4Magic):
+		return LZ4
+	case len(data) >= 2 && data[0]&0x0f == 8 && data[0]>>4 <= 7 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0:
+		// zlib header: deflate method with window size up to 32K and the check bits
+		return ZLIB
+	default:
+		return ""
+	}
+}
@@ -19,6 +19,7 @@ package compressor
 import (
 	"github.com/lf-edge/ekuiper/v2/internal/compressor/flate"
 	"github.com/lf-edge/ekuiper/v2/internal/compressor/gzip"
+	"github.com/lf-edge/ekuiper/v2/internal/compressor/lz4"
 	"github.com/lf-edge/ekuiper/v2/internal/compressor/zlib"
 	"github.com/lf-edge/ekuiper/v2/internal/compressor/zstd"
 	"github.com/lf-edge/ekuiper/v2/pkg/message"
@@ -29,6 +30,9 @@ const (
 	GZIP  = "gzip"
 	FLATE = "flate"
 	ZSTD  = "zstd"
+	LZ4   = "lz4"
+	// AUTO detects the compression method by the magic bytes, only for decompression
+	AUTO = "auto"
 )
 
 func init() {
@@ -44,7 +48,11 @@ func init() {
 	compressors[ZSTD] = func(name string) (message.Compressor, error) {
 		return zstd.NewZstdCompressor()
 	}
+	compressors[LZ4] = func(name string) (message.Compressor, error) {
+		return lz4.NewLz4Compressor()
+	}
 
 	compressWriters[GZIP] = gzip.NewWriter
 	compressWriters[ZSTD] = zstd.NewWriter
+	compressWriters[LZ4] = lz4.NewWriter
 }
@@ -19,6 +19,7 @@ package compressor
 import (
 	"github.com/lf-edge/ekuiper/v2/internal/compressor/flate"
 	"github.com/lf-edge/ekuiper/v2/internal/compressor/gzip"
+	"github.com/lf-edge/ekuiper/v2/internal/compressor/lz4"
 	"github.com/lf-edge/ekuiper/v2/internal/compressor/zlib"
 	"github.com/lf-edge/ekuiper/v2/internal/compressor/zstd"
 	"github.com/lf-edge/ekuiper/v2/pkg/message"
@@ -37,7 +38,14 @@ func init() {
 	decompressors[ZSTD] = func(name string) (message.Decompressor, error) {
 		return zstd.NewzstdDecompressor()
 	}
+	decompressors[LZ4] = func(name string) (message.Decompressor, error) {
+		return lz4.NewLz4Decompressor()
+	}
+	decompressors[AUTO] = func(name string) (message.Decompressor, error) {
+		return newAutoDecompressor()
+	}
 
 	decompressReaders[GZIP] = gzip.NewReader
 	decompressReaders[ZSTD] = zstd.NewReader
+	decompressReaders[LZ4] = lz4.NewReader
 }
@@ -23,6 +23,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"time"
@@ -189,14 +190,23 @@ type KuiperConf struct {
 		PrometheusPort          int               `yaml:"prometheusPort"`
 		PluginHosts             string            `yaml:"pluginHosts"`
 		Authentication          bool              `yaml:"authentication"`
+		Rbac                    RbacConf          `yaml:"rbac"`
+		Oidc                    OidcConf          `yaml:"oidc"`
 		IgnoreCase              bool              `yaml:"ignoreCase"`
 		SQLConf                 *SQLConf          `yaml:"sql"`
 		RulePatrolInterval      cast.DurationConf `yaml:"rulePatrolInterval"`
 		EnableOpenZiti          bool              `yaml:"enableOpenZiti"`
 		AesKey                  string            `yaml:"aesKey"`
+		EncryptionKeys          map[string]string `yaml:"encryptionKeys"`
 		GracefulShutdownTimeout cast.DurationConf `yaml:"gracefulShutdownTimeout"`
 		EnableResourceProfiling bool              `yaml:"enableResourceProfiling"`
 		MetricsDumpConfig       MetricsDumpConfig `yaml:"metricsDumpConfig"`
+		RuleVersionLimit        int               `yaml:"ruleVersionLimit"`
+		RuleRestoreParallelism  int               `yaml:"ruleRestoreParallelism"`
+		Audit                   AuditConf         `yaml:"audit"`
+		GitOps                  GitOpsConf        `yaml:"gitops"`
+		Memory                  MemoryConf        `yaml:"memory"`
+		Scheduling              SchedulingConf    `yaml:"scheduling"`
 	}
 	Rule   def.RuleOption
 	Sink   *SinkConf
@@ -204,7 +214,9 @@ type KuiperConf struct {
 	Store  struct {
 		Type         string `yaml:"type"`
 		ExtStateType string `yaml:"extStateType"`
-		Redis        struct {
+		// StateType is the store of the rule checkpoints. It is the same as the Type if not set.
+		StateType string `yaml:"stateType"`
+		Redis     struct {
 			Host               string            `yaml:"host"`
 			Port               int               `yaml:"port"`
 			Password           string            `yaml:"password"`
@@ -217,6 +229,12 @@ type KuiperConf struct {
 		Fdb struct {
 			Path string `yaml:"path"`
 		}
+		Pebble struct {
+			MemTableSize             int64             `yaml:"memTableSize"`
+			BlockCacheSize           int64             `yaml:"blockCacheSize"`
+			MaxConcurrentCompactions int               `yaml:"maxConcurrentCompactions"`
+			CompactionInterval       cast.DurationConf `yaml:"compactionInterval"`
+		}
 	}
 	Portable struct {
 		PythonBin   string            `yaml:"pythonBin"`
@@ -230,6 +248,8 @@ type KuiperConf struct {
 	OpenTelemetry OpenTelemetry `yaml:"openTelemetry"`
 
 	AesKey []byte
+	// EncryptionKeys are the named keys for the payload encryption and decryption
+	EncryptionKeys map[string][]byte
 }
 
 type MetricsDumpConfig struct {
@@ -237,6 +257,105 @@ type MetricsDumpConfig struct {
 	RetainedDuration time.Duration `yaml:"retainedDuration"`
 }
 
+// RbacConf is the role based access control of the REST api. It only works when the authentication is enabled.
+type RbacConf struct {
+	Enable bool `yaml:"enable"`
+	// DefaultRole is the role of the authenticated users not in Users. The users are rejected if it is not set.
+	DefaultRole string `yaml:"defaultRole"`
+	// Users maps the user, which is the subject or the issuer of the token, to the role
+	Users map[string]string `yaml:"users"`
+	// Roles are the custom roles with their permissions like rule:write. They can override the builtin roles.
+	Roles map[string][]string `yaml:"roles"`
+}
+
+// OidcConf is the external OpenID Connect provider to authenticate the REST api. It only works when the
+// authentication is enabled.
+type OidcConf struct {
+	Enable bool `yaml:"enable"`
+	// Issuer is the url of the provider to discover the endpoints and the keys
+	Issuer string `yaml:"issuer"`
+	// JwksUri overrides the jwks_uri discovered from the issuer
+	JwksUri string `yaml:"jwksUri"`
+	// Audience must be in the aud claim of the tokens
+	Audience string `yaml:"audience"`
+	// ClientId and Scopes are for the UI to log in with the provider
+	ClientId string   `yaml:"clientId"`
+	Scopes   []string `yaml:"scopes"`
+	// UserClaim is the claim of the user name. The nested claim is separated by dot.
+	UserClaim string `yaml:"userClaim"`
+	// RoleClaim is the claim of the roles or groups such as realm_access.roles
+	RoleClaim string `yaml:"roleClaim"`
+	// RoleMapping maps the value of the role claim to the role. The values are used as the roles if it is not set.
+	RoleMapping map[string]string `yaml:"roleMapping"`
+	// JwksCacheTTL is the duration to cache the keys. The keys are refreshed earlier for an unknown key id.
+	JwksCacheTTL cast.DurationConf `yaml:"jwksCacheTTL"`
+}
+
+// AuditConf is the setting of the audit log for the management operations
+type AuditConf struct {
+	Enable bool `yaml:"enable"`
+	// MaxAge and MaxCount are the retention of the records
+	MaxAge   cast.DurationConf `yaml:"maxAge"`
+	MaxCount int               `yaml:"maxCount"`
+	// SignKey is the base64 encoded HMAC key to sign the records. The records are not signed if it is not set.
+	SignKey string `yaml:"signKey"`
+	// MaxBodySize is the max bytes of the request body to record
+	MaxBodySize int `yaml:"maxBodySize"`
+	// TrustedProxies are the addresses or CIDRs of the reverse proxies whose forwarded client address headers are
+	// recorded. The headers of the other clients are ignored because they can be forged.
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+// GitOpsConf is the setting to sync the rules, streams and configurations from a git repository
+type GitOpsConf struct {
+	Enable bool `yaml:"enable"`
+	// Repository is the url of the git repository or a local folder
+	Repository string `yaml:"repository"`
+	Branch     string `yaml:"branch"`
+	// Path is the folder in the repository to read the definitions
+	Path     string            `yaml:"path"`
+	Interval cast.DurationConf `yaml:"interval"`
+	// Strategy is apply to reconcile the changes or report to only report the drift
+	Strategy string `yaml:"strategy"`
+	// Prune is whether to delete the resources which are created by the sync but removed from the repository
+	Prune bool `yaml:"prune"`
+}
+
+const (
+	GitOpsStrategyApply  = "apply"
+	GitOpsStrategyReport = "report"
+)
+
+// MemoryConf is the process-wide memory budget. The sources are paused when the memory usage reaches the high watermark
+// and resumed when it drops below the low watermark.
+type MemoryConf struct {
+	// Limit is the budget in bytes. The memory manager is disabled if it is 0.
+	Limit int64 `yaml:"limit"`
+	// HighWatermark and LowWatermark are the ratio of the limit
+	HighWatermark float64           `yaml:"highWatermark"`
+	LowWatermark  float64           `yaml:"lowWatermark"`
+	CheckInterval cast.DurationConf `yaml:"checkInterval"`
+	// MaxPause is the max duration to pause the sources at once. Then the sources resume until the next check so that
+	// the event time windows can fire.
+	MaxPause cast.DurationConf `yaml:"maxPause"`
+}
+
+// SchedulingConf defines the scheduling classes which can be assigned to the rules. The classes share the cpu
+// capacity by priority.
+type SchedulingConf struct {
+	// Cpus is the cpu capacity shared by the classes. If it is 0, use the cgroup cpu quota on linux or the count of the
+	// cpus.
+	Cpus    float64                     `yaml:"cpus"`
+	Classes map[string]*SchedulingClass `yaml:"classes"`
+}
+
+type SchedulingClass struct {
+	// Priority decides the order to use the cpu. The higher one uses first.
+	Priority int `yaml:"priority"`
+	// CpuLimit is the max cpu cores of all the rules of the class. 0 means no limit.
+	CpuLimit float64 `yaml:"cpuLimit"`
+}
+
 type OpenTelemetry struct {
 	ServiceName           string `yaml:"serviceName"`
 	EnableRemoteCollector bool   `yaml:"enableRemoteCollector"`
@@ -386,6 +505,77 @@ func InitConf() {
 		Config.Basic.GracefulShutdownTimeout = cast.DurationConf(3 * time.Second)
 	}
 
+	if Config.Basic.RuleVersionLimit == 0 {
+		Config.Basic.RuleVersionLimit = 10
+	}
+	if Config.Basic.RuleRestoreParallelism <= 0 {
+		Config.Basic.RuleRestoreParallelism = runtime.NumCPU()
+	}
+
+	if Config.Basic.Oidc.Audience == "" {
+		Config.Basic.Oidc.Audience = "eKuiper"
+	}
+	if Config.Basic.Oidc.UserClaim == "" {
+		Config.Basic.Oidc.UserClaim = "sub"
+	}
+	if Config.Basic.Oidc.JwksCacheTTL <= 0 {
+		Config.Basic.Oidc.JwksCacheTTL = cast.DurationConf(time.Hour)
+	}
+
+	if Config.Basic.Audit.MaxAge <= 0 {
+		Config.Basic.Audit.MaxAge = cast.DurationConf(30 * 24 * time.Hour)
+	}
+	if Config.Basic.Audit.MaxCount <= 0 {
+		Config.Basic.Audit.MaxCount = 10000
+	}
+	if Config.Basic.Audit.MaxBodySize <= 0 {
+		Config.Basic.Audit.MaxBodySize = 4096
+	}
+
+	if Config.Basic.GitOps.Interval <= 0 {
+		Config.Basic.GitOps.Interval = cast.DurationConf(time.Minute)
+	}
+	switch Config.Basic.GitOps.Strategy {
+	case "":
+		Config.Basic.GitOps.Strategy = GitOpsStrategyApply
+	case GitOpsStrategyApply, GitOpsStrategyReport:
+	default:
+		Log.Fatalf("invalid gitops strategy %s, must be apply or report", Config.Basic.GitOps.Strategy)
+	}
+
+	if Config.Basic.Memory.Limit < 0 {
+		Log.Fatalf("invalid memory limit %d, must not be negative", Config.Basic.Memory.Limit)
+	}
+	if Config.Basic.Memory.HighWatermark <= 0 {
+		Config.Basic.Memory.HighWatermark = 0.9
+	}
+	if Config.Basic.Memory.LowWatermark <= 0 {
+		Config.Basic.Memory.LowWatermark = 0.75
+	}
+	if Config.Basic.Memory.HighWatermark > 1 || Config.Basic.Memory.LowWatermark >= Config.Basic.Memory.HighWatermark {
+		Log.Fatalf("invalid memory watermarks, must be 0 < lowWatermark < highWatermark <= 1")
+	}
+	if Config.Basic.Memory.CheckInterval <= 0 {
+		Config.Basic.Memory.CheckInterval = cast.DurationConf(500 * time.Millisecond)
+	}
+	if Config.Basic.Memory.MaxPause <= 0 {
+		Config.Basic.Memory.MaxPause = cast.DurationConf(10 * time.Second)
+	}
+	if Config.Basic.Scheduling.Cpus < 0 {
+		Log.Fatalf("invalid scheduling cpus %f, must not be negative", Config.Basic.Scheduling.Cpus)
+	}
+	if Config.Basic.Scheduling.Classes == nil {
+		Config.Basic.Scheduling.Classes = map[string]*SchedulingClass{
+			"control": {Priority: 100},
+			"bulk":    {Priority: 0},
+		}
+	}
+	for name, c := range Config.Basic.Scheduling.Classes {
+		if c == nil || c.CpuLimit < 0 {
+			Log.Fatalf("invalid scheduling class %s, cpuLimit must not be negative", name)
+		}
+	}
+
 	if Config.Basic.TimeZone != "" {
 		if err := cast.SetTimeZone(Config.Basic.TimeZone); err != nil {
 			Log.Fatal(err)
@@ -403,8 +593,18 @@ func InitConf() {
 		}
 		Config.AesKey = key
 	}
+	if len(Config.Basic.EncryptionKeys) > 0 {
+		Config.EncryptionKeys = make(map[string][]byte, len(Config.Basic.EncryptionKeys))
+		for name, v := range Config.Basic.EncryptionKeys {
+			key, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				Log.Fatalf("invalid encryption key %s: %v", name, err)
+			}
+			Config.EncryptionKeys[name] = key
+		}
+	}
 
-	if Config.Store.Type == "redis" && Config.Store.Redis.ConnectionSelector != "" {
+	if (Config.Store.Type == "redis" || Config.Store.StateType == "redis") && Config.Store.Redis.ConnectionSelector != "" {
 		if err := RedisStorageConSelectorApply(Config.Store.Redis.ConnectionSelector, Config); err != nil {
 			Log.Fatal(err)
 		}
@@ -412,6 +612,9 @@ func InitConf() {
 	if Config.Store.ExtStateType == "" {
 		Config.Store.ExtStateType = "sqlite"
 	}
+	if Config.Store.StateType == "" {
+		Config.Store.StateType = Config.Store.Type
+	}
 
 	if Config.Portable.PythonBin == "" {
 		Config.Portable.PythonBin = "python"
@@ -454,6 +657,24 @@ func InitConf() {
 	_ = ValidateRuleOption(&Config.Rule)
 }
 
+// GetEncryptionKey returns the named key in encryptionKeys. If the name is empty, return the aesKey
+func GetEncryptionKey(name string) ([]byte, error) {
+	if name == "" {
+		if Config == nil || Config.AesKey == nil {
+			return nil, fmt.Errorf("AES key is not defined")
+		}
+		return Config.AesKey, nil
+	}
+	if Config == nil {
+		return nil, fmt.Errorf("encryption key %s is not defined", name)
+	}
+	key, ok := Config.EncryptionKeys[name]
+	if !ok {
+		return nil, fmt.Errorf("encryption key %s is not defined", name)
+	}
+	return key, nil
+}
+
 func SetLogFormat(disableTimestamp bool) {
 	Log.Formatter.(*logrus.TextFormatter).DisableTimestamp = disableTimestamp
 }
@@ -475,6 +696,83 @@ func ValidateRuleOption(option *def.RuleOption) error {
 		Log.Warnf("lateTol is negative, set to 1 second")
 		errs = errors.Join(errs, errors.New("invalidLateTol:lateTol must be greater than 0"))
 	}
+	if option.AllowedLateness < 0 {
+		option.AllowedLateness = 0
+		Log.Warnf("allowedLateness is negative, set to 0")
+		errs = errors.Join(errs, errors.New("invalidAllowedLateness:allowedLateness must be greater than 0"))
+	}
+	switch option.Mode {
+	case "", def.RuleModeStream, def.RuleModeBatch:
+	default:
+		errs = errors.Join(errs, fmt.Errorf("invalidMode:mode must be one of stream and batch but got %s", option.Mode))
+	}
+	switch option.LateDataPolicy {
+	case "", def.LateDataDrop, def.LateDataUpdate, def.LateDataSideOutput:
+	default:
+		errs = errors.Join(errs, fmt.Errorf("invalidLateDataPolicy:lateDataPolicy must be one of drop, update and sideOutput but got %s", option.LateDataPolicy))
+	}
+	if option.EmitStrategy != nil {
+		if option.EmitStrategy.Type != def.EmitOnChange {
+			errs = errors.Join(errs, fmt.Errorf("invalidEmitStrategy:emitStrategy type must be onChange but got %s", option.EmitStrategy.Type))
+		}
+		if option.EmitStrategy.Tolerance < 0 {
+			errs = errors.Join(errs, errors.New("invalidEmitTolerance:emitStrategy tolerance must not be negative"))
+		}
+		if option.EmitStrategy.MaxKeys < 0 {
+			errs = errors.Join(errs, errors.New("invalidEmitMaxKeys:emitStrategy maxKeys must not be negative"))
+		}
+	}
+	if option.WindowTrigger != nil {
+		switch option.WindowTrigger.Mode {
+		case "", def.WindowEmitFinal:
+		case def.WindowEmitEarly, def.WindowEmitBoth:
+			if option.WindowTrigger.EarlyInterval <= 0 {
+				errs = errors.Join(errs, fmt.Errorf("invalidWindowTriggerInterval:windowTrigger earlyInterval must be greater than 0 for mode %s", option.WindowTrigger.Mode))
+			}
+		default:
+			errs = errors.Join(errs, fmt.Errorf("invalidWindowTriggerMode:windowTrigger mode must be one of final, early and both but got %s", option.WindowTrigger.Mode))
+		}
+	}
+	if option.WindowBufferLimit < 0 {
+		errs = errors.Join(errs, errors.New("invalidWindowBufferLimit:windowBufferLimit must not be negative"))
+	}
+	if option.SchedulingClass != "" && Config != nil {
+		if _, ok := Config.Basic.Scheduling.Classes[option.SchedulingClass]; !ok {
+			errs = errors.Join(errs, fmt.Errorf("invalidSchedulingClass:schedulingClass %s is not defined", option.SchedulingClass))
+		}
+	}
+	if option.BufferSpill != nil {
+		bs := option.BufferSpill
+		if bs.HighWatermark == 0 {
+			bs.HighWatermark = 0.8
+		}
+		if bs.LowWatermark == 0 {
+			bs.LowWatermark = 0.5
+		}
+		if bs.MaxItems == 0 {
+			bs.MaxItems = 100000
+		}
+		if bs.HighWatermark < 0 || bs.HighWatermark > 1 {
+			errs = errors.Join(errs, errors.New("invalidBufferSpillHighWatermark:bufferSpill highWatermark must be in (0, 1]"))
+		}
+		if bs.LowWatermark < 0 || bs.LowWatermark >= bs.HighWatermark {
+			errs = errors.Join(errs, errors.New("invalidBufferSpillLowWatermark:bufferSpill lowWatermark must be in (0, highWatermark)"))
+		}
+		if bs.MaxItems < 0 {
+			errs = errors.Join(errs, errors.New("invalidBufferSpillMaxItems:bufferSpill maxItems must not be negative"))
+		}
+	}
+	if option.Changelog != nil {
+		if len(option.Changelog.Keys) == 0 {
+			errs = errors.Join(errs, errors.New("invalidChangelogKeys:changelog keys must not be empty"))
+		}
+		if option.Changelog.MaxKeys < 0 {
+			errs = errors.Join(errs, errors.New("invalidChangelogMaxKeys:changelog maxKeys must not be negative"))
+		}
+		if option.Changelog.OpField == "" {
+			option.Changelog.OpField = "op"
+		}
+	}
 	if option.RestartStrategy != nil {
 		if option.RestartStrategy.Multiplier <= 0 {
 			option.RestartStrategy.Multiplier = 2
@@ -501,10 +799,42 @@ func ValidateRuleOption(option *def.RuleOption) error {
 			Log.Warnf("restart jitterFactor must between 0 and 1, set to 0.1")
 			errs = errors.Join(errs, errors.New("invalidRestartJitterFactor:restart jitterFactor must between [0, 1)"))
 		}
+		for i, p := range option.RestartStrategy.Policies {
+			switch p.ErrorClass {
+			case def.ErrorClassSource, def.ErrorClassOperator, def.ErrorClassSink, def.ErrorClassOther, def.ErrorClassAny:
+			default:
+				errs = errors.Join(errs, fmt.Errorf("invalidRestartPolicyErrorClass:restart policy %d errorClass must be one of source, operator, sink, other and any but got %s", i, p.ErrorClass))
+			}
+			switch p.Action {
+			case def.RestartActionRetry, def.RestartActionRestart, def.RestartActionStop:
+			default:
+				errs = errors.Join(errs, fmt.Errorf("invalidRestartPolicyAction:restart policy %d action must be one of retry, restart and stop but got %s", i, p.Action))
+			}
+			if p.Match != "" {
+				if _, err := regexp.Compile(p.Match); err != nil {
+					errs = errors.Join(errs, fmt.Errorf("invalidRestartPolicyMatch:restart policy %d match is not a valid regular expression: %v", i, err))
+				}
+			}
+			if p.Attempts < 0 {
+				errs = errors.Join(errs, fmt.Errorf("invalidRestartPolicyAttempts:restart policy %d attempts must not be negative", i))
+			}
+			if p.Delay < 0 {
+				errs = errors.Join(errs, fmt.Errorf("invalidRestartPolicyDelay:restart policy %d delay must not be negative", i))
+			}
+		}
 	}
 	if err := schedule.ValidateRanges(option.CronDatetimeRange); err != nil {
 		errs = errors.Join(errs, fmt.Errorf("validate cronDatetimeRange failed, err:%v", err))
 	}
+	if _, err := schedule.LoadLocation(option.CronTimezone); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("validate cronTimezone failed, err:%v", err))
+	}
+	if err := schedule.ValidateTimeRanges(option.CronTimeRanges); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("validate cronTimeRanges failed, err:%v", err))
+	}
+	if err := schedule.ValidateExclusions(option.CronExclusions); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("validate cronExclusions failed, err:%v", err))
+	}
 	return errs
 }
 
@@ -226,7 +226,98 @@ func TestRuleOptionValidate(t *testing.T) {
 			},
 			err: "invalidRestartMultiplier:restart multiplier must be greater than 0\ninvalidRestartAttempts:restart attempts must be greater than 0\ninvalidRestartDelay:restart delay must be greater than 0\ninvalidRestartMaxDelay:restart maxDelay must be greater than 0\ninvalidRestartJitterFactor:restart jitterFactor must between [0, 1)",
 		},
+		{
+			s: &def.RuleOption{
+				LateTol:         cast.DurationConf(time.Second),
+				AllowedLateness: cast.DurationConf(-time.Second),
+				LateDataPolicy:  "retract",
+				Concurrency:     1,
+				BufferLength:    1024,
+			},
+			err: "invalidAllowedLateness:allowedLateness must be greater than 0\ninvalidLateDataPolicy:lateDataPolicy must be one of drop, update and sideOutput but got retract",
+		},
+		{
+			s: &def.RuleOption{
+				LateTol:      cast.DurationConf(time.Second),
+				Concurrency:  1,
+				BufferLength: 1024,
+				EmitStrategy: &def.EmitStrategy{Type: "onUpdate", Tolerance: -1, MaxKeys: -1},
+			},
+			err: "invalidEmitStrategy:emitStrategy type must be onChange but got onUpdate\ninvalidEmitTolerance:emitStrategy tolerance must not be negative\ninvalidEmitMaxKeys:emitStrategy maxKeys must not be negative",
+		},
+		{
+			s: &def.RuleOption{
+				LateTol:       cast.DurationConf(time.Second),
+				Concurrency:   1,
+				BufferLength:  1024,
+				WindowTrigger: &def.WindowTrigger{Mode: "both"},
+			},
+			err: "invalidWindowTriggerInterval:windowTrigger earlyInterval must be greater than 0 for mode both",
+		},
+		{
+			s: &def.RuleOption{
+				LateTol:       cast.DurationConf(time.Second),
+				Concurrency:   1,
+				BufferLength:  1024,
+				WindowTrigger: &def.WindowTrigger{Mode: "periodic"},
+			},
+			err: "invalidWindowTriggerMode:windowTrigger mode must be one of final, early and both but got periodic",
+		},
+		{
+			s: &def.RuleOption{
+				LateTol:      cast.DurationConf(time.Second),
+				Concurrency:  1,
+				BufferLength: 1024,
+				Changelog:    &def.Changelog{OpField: "kind", MaxKeys: -1},
+			},
+			err: "invalidChangelogKeys:changelog keys must not be empty\ninvalidChangelogMaxKeys:changelog maxKeys must not be negative",
+		},
+		{
+			s: &def.RuleOption{
+				LateTol:           cast.DurationConf(time.Second),
+				Concurrency:       1,
+				BufferLength:      1024,
+				WindowBufferLimit: -1,
+			},
+			err: "invalidWindowBufferLimit:windowBufferLimit must not be negative",
+		},
+		{
+			s: &def.RuleOption{
+				LateTol:      cast.DurationConf(time.Second),
+				Concurrency:  1,
+				BufferLength: 1024,
+				BufferSpill:  &def.BufferSpill{HighWatermark: 0.4},
+			},
+			err: "invalidBufferSpillLowWatermark:bufferSpill lowWatermark must be in (0, highWatermark)",
+		},
+		{
+			s: &def.RuleOption{
+				LateTol:         cast.DurationConf(time.Second),
+				Concurrency:     1,
+				BufferLength:    1024,
+				SchedulingClass: "unknown",
+			},
+			err: "invalidSchedulingClass:schedulingClass unknown is not defined",
+		},
+		{
+			s: &def.RuleOption{
+				LateTol:      cast.DurationConf(time.Second),
+				Concurrency:  1,
+				BufferLength: 1024,
+				BufferSpill:  &def.BufferSpill{},
+			},
+			e: &def.RuleOption{
+				LateTol:      cast.DurationConf(time.Second),
+				Concurrency:  1,
+				BufferLength: 1024,
+				BufferSpill:  &def.BufferSpill{HighWatermark: 0.8, LowWatermark: 0.5, MaxItems: 100000},
+			},
+		},
 	}
+	old := Config
+	defer func() { Config = old }()
+	Config = &KuiperConf{}
+	Config.Basic.Scheduling.Classes = map[string]*SchedulingClass{"bulk": {}}
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("test_%d", i), func(t *testing.T) {
@@ -0,0 +1,404 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/pool"
+)
+
+// Marshal encodes the value into avro binary by the schema
+func Marshal(s *Schema, v any) ([]byte, error) {
+	buf := pool.GetBuffer()
+	if err := encode(buf, s, v); err != nil {
+		pool.PutBuffer(buf)
+		return nil, err
+	}
+	return pool.CopyAndPutBuffer(buf), nil
+}
+
+func encode(buf *bytes.Buffer, s *Schema, v any) error {
+	switch s.kind {
+	case typeNull:
+		if v != nil {
+			return fmt.Errorf("expect null but got %v", v)
+		}
+	case typeBoolean:
+		b, err := cast.ToBool(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		if b {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case typeInt, typeLong:
+		i, err := cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		if s.kind == typeInt && (i > math.MaxInt32 || i < math.MinInt32) {
+			return fmt.Errorf("value %d overflows avro int", i)
+		}
+		writeLong(buf, i)
+	case typeFloat:
+		f, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		_ = binary.Write(buf, binary.LittleEndian, math.Float32bits(float32(f)))
+	case typeDouble:
+		f, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		_ = binary.Write(buf, binary.LittleEndian, math.Float64bits(f))
+	case typeBytes:
+		b, err := cast.ToByteA(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		writeLong(buf, int64(len(b)))
+		buf.Write(b)
+	case typeString:
+		str, err := cast.ToString(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		writeLong(buf, int64(len(str)))
+		buf.WriteString(str)
+	case typeFixed:
+		b, err := cast.ToByteA(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		if len(b) != s.size {
+			return fmt.Errorf("fixed %s expects %d bytes but got %d", s.name, s.size, len(b))
+		}
+		buf.Write(b)
+	case typeEnum:
+		str, err := cast.ToString(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		for i, sym := range s.symbols {
+			if sym == str {
+				writeLong(buf, int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not a symbol of enum %s", str, s.name)
+	case typeArray:
+		if v == nil {
+			buf.WriteByte(0)
+			return nil
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fmt.Errorf("expect array but got %v", v)
+		}
+		if rv.Len() > 0 {
+			writeLong(buf, int64(rv.Len()))
+			for i := 0; i < rv.Len(); i++ {
+				if err := encode(buf, s.items, rv.Index(i).Interface()); err != nil {
+					return fmt.Errorf("array item %d: %v", i, err)
+				}
+			}
+		}
+		buf.WriteByte(0)
+	case typeMap:
+		if v == nil {
+			buf.WriteByte(0)
+			return nil
+		}
+		m, err := cast.ToStringMap(v)
+		if err != nil {
+			return err
+		}
+		if len(m) > 0 {
+			writeLong(buf, int64(len(m)))
+			for k, mv := range m {
+				writeLong(buf, int64(len(k)))
+				buf.WriteString(k)
+				if err := encode(buf, s.values, mv); err != nil {
+					return fmt.Errorf("map value %s: %v", k, err)
+				}
+			}
+		}
+		buf.WriteByte(0)
+	case typeUnion:
+		return encodeUnion(buf, s, v)
+	case typeRecord:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("record %s expects a map but got %v", s.name, v)
+		}
+		for _, f := range s.fields {
+			fv, ok := m[f.name]
+			if !ok {
+				switch {
+				case f.hasDef:
+					fv = f.def
+				case acceptNull(f.typ):
+					fv = nil
+				default:
+					return fmt.Errorf("field %s of record %s is missing", f.name, s.name)
+				}
+			}
+			if err := encode(buf, f.typ, fv); err != nil {
+				return fmt.Errorf("field %s: %v", f.name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported avro type %s", s.kind)
+	}
+	return nil
+}
+
+// encodeUnion writes the first branch which matches the go type of the value
+func encodeUnion(buf *bytes.Buffer, s *Schema, v any) error {
+	for i, b := range s.branches {
+		if !match(b, v) {
+			continue
+		}
+		sub := pool.GetBuffer()
+		if err := encode(sub, b, v); err != nil {
+			pool.PutBuffer(sub)
+			continue
+		}
+		writeLong(buf, int64(i))
+		buf.Write(sub.Bytes())
+		pool.PutBuffer(sub)
+		return nil
+	}
+	return fmt.Errorf("value %v does not match any branch of the union", v)
+}
+
+func match(s *Schema, v any) bool {
+	if v == nil {
+		return s.kind == typeNull
+	}
+	switch v.(type) {
+	case bool:
+		return s.kind == typeBoolean
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return s.kind == typeInt || s.kind == typeLong || s.kind == typeFloat || s.kind == typeDouble
+	case string:
+		return s.kind == typeString || s.kind == typeEnum
+	case []byte:
+		return s.kind == typeBytes || s.kind == typeFixed
+	case map[string]any:
+		return s.kind == typeRecord || s.kind == typeMap
+	default:
+		k := reflect.ValueOf(v).Kind()
+		return (k == reflect.Slice || k == reflect.Array) && s.kind == typeArray
+	}
+}
+
+func acceptNull(s *Schema) bool {
+	if s.kind == typeNull {
+		return true
+	}
+	if s.kind == typeUnion {
+		for _, b := range s.branches {
+			if b.kind == typeNull {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeLong(buf *bytes.Buffer, i int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], i)
+	buf.Write(b[:n])
+}
+
+var errShort = errors.New("unexpected end of avro data")
+
+type decoder struct {
+	b   []byte
+	pos int
+}
+
+// Unmarshal decodes the avro binary by the schema
+func Unmarshal(s *Schema, b []byte) (any, error) {
+	d := &decoder{b: b}
+	return d.decode(s)
+}
+
+func (d *decoder) decode(s *Schema) (any, error) {
+	switch s.kind {
+	case typeNull:
+		return nil, nil
+	case typeBoolean:
+		if d.pos >= len(d.b) {
+			return nil, errShort
+		}
+		v := d.b[d.pos] != 0
+		d.pos++
+		return v, nil
+	case typeInt, typeLong:
+		return d.readLong()
+	case typeFloat:
+		if d.pos+4 > len(d.b) {
+			return nil, errShort
+		}
+		v := math.Float32frombits(binary.LittleEndian.Uint32(d.b[d.pos:]))
+		d.pos += 4
+		return float64(v), nil
+	case typeDouble:
+		if d.pos+8 > len(d.b) {
+			return nil, errShort
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.b[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case typeBytes:
+		return d.readBytes()
+	case typeString:
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case typeFixed:
+		return d.read(s.size)
+	case typeEnum:
+		i, err := d.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.symbols) {
+			return nil, fmt.Errorf("invalid index %d of enum %s", i, s.name)
+		}
+		return s.symbols[i], nil
+	case typeArray:
+		result := make([]any, 0)
+		err := d.readBlocks(func() error {
+			item, err := d.decode(s.items)
+			if err != nil {
+				return err
+			}
+			result = append(result, item)
+			return nil
+		})
+		return result, err
+	case typeMap:
+		result := make(map[string]any)
+		err := d.readBlocks(func() error {
+			k, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			v, err := d.decode(s.values)
+			if err != nil {
+				return err
+			}
+			result[string(k)] = v
+			return nil
+		})
+		return result, err
+	case typeUnion:
+		i, err := d.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(s.branches) {
+			return nil, fmt.Errorf("invalid union branch %d", i)
+		}
+		return d.decode(s.branches[i])
+	case typeRecord:
+		result := make(map[string]any, len(s.fields))
+		for _, f := range s.fields {
+			v, err := d.decode(f.typ)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", f.name, err)
+			}
+			result[f.name] = v
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unsupported avro type %s", s.kind)
+	}
+}
+
+// readBlocks reads the blocks of array or map until the zero count block
+func (d *decoder) readBlocks(readItem func() error) error {
+	for {
+		count, err := d.readLong()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			// a negative count is followed by the block size in bytes
+			count = -count
+			if _, err := d.readLong(); err != nil {
+				return err
+			}
+		}
+		// Each item takes at least a byte, so a larger count is malformed and must not loop over the data
+		if count < 0 || count > int64(len(d.b)-d.pos) {
+			return fmt.Errorf("invalid block count %d", count)
+		}
+		for i := int64(0); i < count; i++ {
+			if err := readItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *decoder) readLong() (int64, error) {
+	v, n := binary.Varint(d.b[d.pos:])
+	if n <= 0 {
+		return 0, errShort
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *decoder) readBytes() ([]byte, error) {
+	l, err := d.readLong()
+	if err != nil {
+		return nil, err
+	}
+	if l < 0 {
+		return nil, fmt.Errorf("invalid length %d", l)
+	}
+	return d.read(int(l))
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.b)-d.pos {
+		return nil, errShort
+	}
+	b := make([]byte, n)
+	copy(b, d.b[d.pos:d.pos+n])
+	d.pos += n
+	return b, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+  "type": "record",
+  "name": "Reading",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "name", "type": "string"},
+    {"name": "temperature", "type": "double"},
+    {"name": "ratio", "type": "float"},
+    {"name": "valid", "type": "boolean"},
+    {"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["LOW", "HIGH"]}},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "attrs", "type": {"type": "map", "values": "int"}},
+    {"name": "raw", "type": "bytes"},
+    {"name": "mac", "type": {"type": "fixed", "name": "Mac", "size": 2}},
+    {"name": "note", "type": ["null", "string"]},
+    {"name": "count", "type": "int", "default": 7},
+    {"name": "next", "type": ["null", "Reading"]}
+  ]
+}`
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema(testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.Reading", s.FullName())
+	assert.Len(t, s.fields, 13)
+	assert.Equal(t, "com.example.Level", s.fields[5].typ.name)
+	// the recursive reference points to the record itself
+	assert.Same(t, s, s.fields[12].typ.branches[1])
+
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{schema: `{"type": "record", "name": "a", "fields": [{"name": "b", "type": "unknown"}]}`, err: "field a.b: unknown avro type unknown"},
+		{schema: `{"type": "enum", "name": "a"}`, err: "enum a must have symbols"},
+		{schema: `{"type": "fixed", "size": 2}`, err: "named avro type must have a name"},
+		{schema: `[["null"]]`, err: "union cannot contain union directly"},
+		{schema: `{`, err: "invalid avro schema: unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			_, err := ParseSchema(tt.schema)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestMarshalPrimitive(t *testing.T) {
+	tests := []struct {
+		schema string
+		v      any
+		b      []byte
+	}{
+		{schema: `"long"`, v: int64(-1), b: []byte{0x01}},
+		{schema: `"int"`, v: 64, b: []byte{0x80, 0x01}},
+		{schema: `"string"`, v: "foo", b: []byte{0x06, 'f', 'o', 'o'}},
+		{schema: `"boolean"`, v: true, b: []byte{0x01}},
+		{schema: `"double"`, v: 1.5, b: []byte{0, 0, 0, 0, 0, 0, 0xf8, 0x3f}},
+		{schema: `["null", "string"]`, v: nil, b: []byte{0x00}},
+		{schema: `["null", "string"]`, v: "a", b: []byte{0x02, 0x02, 'a'}},
+		{schema: `{"type": "array", "items": "long"}`, v: []any{int64(3), int64(27)}, b: []byte{0x04, 0x06, 0x36, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			s, err := ParseSchema(tt.schema)
+			require.NoError(t, err)
+			b, err := Marshal(s, tt.v)
+			require.NoError(t, err)
+			assert.Equal(t, tt.b, b)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s, err := ParseSchema(testSchema)
+	require.NoError(t, err)
+	input := map[string]any{
+		"id":          123,
+		"name":        "sensor",
+		"temperature": 25.5,
+		"ratio":       0.5,
+		"valid":       true,
+		"level":       "HIGH",
+		"tags":        []string{"a", "b"},
+		"attrs":       map[string]any{"x": 1},
+		"raw":         []byte{1, 2, 3},
+		"mac":         []byte{0xab, 0xcd},
+		"note":        "hello",
+		"next": map[string]any{
+			"id":          124,
+			"name":        "child",
+			"temperature": 1.0,
+			"ratio":       1,
+			"valid":       false,
+			"level":       "LOW",
+			"tags":        []any{},
+			"attrs":       map[string]any{},
+			"raw":         []byte{},
+			"mac":         []byte{0, 0},
+		},
+	}
+	b, err := Marshal(s, input)
+	require.NoError(t, err)
+	v, err := Unmarshal(s, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"id":          int64(123),
+		"name":        "sensor",
+		"temperature": 25.5,
+		"ratio":       0.5,
+		"valid":       true,
+		"level":       "HIGH",
+		"tags":        []any{"a", "b"},
+		"attrs":       map[string]any{"x": int64(1)},
+		"raw":         []byte{1, 2, 3},
+		"mac":         []byte{0xab, 0xcd},
+		"note":        "hello",
+		"count":       int64(7),
+		"next": map[string]any{
+			"id":          int64(124),
+			"name":        "child",
+			"temperature": 1.0,
+			"ratio":       1.0,
+			"valid":       false,
+			"level":       "LOW",
+			"tags":        []any{},
+			"attrs":       map[string]any{},
+			"raw":         []byte{},
+			"mac":         []byte{0, 0},
+			"note":        nil,
+			"count":       int64(7),
+			"next":        nil,
+		},
+	}, v)
+}
+
+func TestMarshalError(t *testing.T) {
+	s, err := ParseSchema(testSchema)
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		v    any
+		err  string
+	}{
+		{name: "missing", v: map[string]any{"name": "a"}, err: "field id of record com.example.Reading is missing"},
+		{name: "enum", v: map[string]any{"id": 1, "name": "a", "temperature": 1, "ratio": 1, "valid": true, "level": "MID"}, err: "field level: MID is not a symbol of enum com.example.Level"},
+		{name: "notMap", v: 1, err: "record com.example.Reading expects a map but got 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Marshal(s, tt.v)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+	_, err = Unmarshal(s, []byte{0x02})
+	assert.EqualError(t, err, "field name: unexpected end of avro data")
+
+	arr, err := ParseSchema(`{"type":"array","items":"null"}`)
+	require.NoError(t, err)
+	// The count is far beyond the data
+	_, err = Unmarshal(arr, []byte{0xfe, 0xff, 0xff, 0xff, 0x0f, 0x00})
+	assert.EqualError(t, err, "invalid block count 2147483647")
+	// The min int64 count overflows when negated
+	_, err = Unmarshal(arr, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x00, 0x00})
+	assert.EqualError(t, err, "invalid block count -9223372036854775808")
+	str, err := ParseSchema(`"string"`)
+	require.NoError(t, err)
+	// The length overflows the position
+	_, err = Unmarshal(str, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x61})
+	assert.EqualError(t, err, "unexpected end of avro data")
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+// The subject name strategies to find the schema of the encoded data in the schema registry
+const (
+	TopicNameStrategy       = "topic"
+	RecordNameStrategy      = "record"
+	TopicRecordNameStrategy = "topicRecord"
+)
+
+// magicByte is the first byte of the Confluent wire format, followed by the 4 bytes big endian schema id
+const magicByte = 0
+
+type Converter struct {
+	// schema is the local schema, it is used directly without the registry
+	schema    *Schema
+	schemaStr string
+	registry  *registryClient
+	subject   string
+
+	mu sync.Mutex
+	// the schema id and the schema to encode, resolved from the registry on demand
+	encodeId     int
+	encodeSchema *Schema
+}
+
+type converterConf struct {
+	registryUrl string
+	user        string
+	password    string
+	strategy    string
+	subject     string
+	topic       string
+}
+
+// NewConverter creates the avro converter. The schema content is the local schema which can be empty if the schema
+// registry is set. With the registry, the data is encoded in the Confluent wire format.
+func NewConverter(schemaContent string, props map[string]any) (message.Converter, error) {
+	conf := &converterConf{strategy: TopicNameStrategy}
+	for k, p := range map[string]*string{
+		"schemaRegistryUrl":      &conf.registryUrl,
+		"schemaRegistryUser":     &conf.user,
+		"schemaRegistryPassword": &conf.password,
+		"subjectNameStrategy":    &conf.strategy,
+		"subject":                &conf.subject,
+		"topic":                  &conf.topic,
+	} {
+		if v, ok := props[k].(string); ok && v != "" {
+			*p = v
+		}
+	}
+	result := &Converter{schemaStr: schemaContent}
+	if schemaContent != "" {
+		s, err := ParseSchema(schemaContent)
+		if err != nil {
+			return nil, err
+		}
+		result.schema = s
+	}
+	if conf.registryUrl == "" {
+		if result.schema == nil {
+			return nil, fmt.Errorf("avro format requires a schemaId or the schemaRegistryUrl property")
+		}
+		return result, nil
+	}
+	result.registry = newRegistryClient(conf.registryUrl, conf.user, conf.password)
+	subject, err := subjectName(conf, result.schema)
+	if err != nil {
+		return nil, err
+	}
+	result.subject = subject
+	return result, nil
+}
+
+// subjectName returns the value subject of the schema registry by the naming strategy
+func subjectName(conf *converterConf, s *Schema) (string, error) {
+	if conf.subject != "" {
+		return conf.subject, nil
+	}
+	switch conf.strategy {
+	case TopicNameStrategy:
+		if conf.topic == "" {
+			return "", nil
+		}
+		return conf.topic + "-value", nil
+	case RecordNameStrategy, TopicRecordNameStrategy:
+		if s == nil {
+			return "", nil
+		}
+		if s.kind != typeRecord {
+			return "", fmt.Errorf("subjectNameStrategy %s requires a record schema", conf.strategy)
+		}
+		if conf.strategy == RecordNameStrategy {
+			return s.name, nil
+		}
+		if conf.topic == "" {
+			return "", fmt.Errorf("subjectNameStrategy %s requires the topic property", conf.strategy)
+		}
+		return conf.topic + "-" + s.name, nil
+	default:
+		return "", fmt.Errorf("invalid subjectNameStrategy %s, must be one of topic, record and topicRecord", conf.strategy)
+	}
+}
+
+func (c *Converter) Encode(ctx api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	m, ok := d.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %v, must be a map", d)
+	}
+	if c.registry == nil {
+		return Marshal(c.schema, m)
+	}
+	id, s, err := c.resolveEncodeSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	body, err := Marshal(s, m)
+	if err != nil {
+		return nil, err
+	}
+	b = make([]byte, 5, 5+len(body))
+	b[0] = magicByte
+	binary.BigEndian.PutUint32(b[1:], uint32(id))
+	return append(b, body...), nil
+}
+
+// resolveEncodeSchema registers the local schema or fetches the latest schema of the subject once
+func (c *Converter) resolveEncodeSchema(ctx api.StreamContext) (int, *Schema, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.encodeSchema != nil {
+		return c.encodeId, c.encodeSchema, nil
+	}
+	if c.subject == "" {
+		return 0, nil, fmt.Errorf("cannot decide the schema registry subject, set the subject or topic property")
+	}
+	if c.schema != nil {
+		id, err := c.registry.register(c.subject, c.schemaStr)
+		if err != nil {
+			return 0, nil, err
+		}
+		c.encodeId, c.encodeSchema = id, c.schema
+	} else {
+		id, s, err := c.registry.latest(c.subject)
+		if err != nil {
+			return 0, nil, err
+		}
+		c.encodeId, c.encodeSchema = id, s
+	}
+	ctx.GetLogger().Infof("avro encode with schema id %d of subject %s", c.encodeId, c.subject)
+	return c.encodeId, c.encodeSchema, nil
+}
+
+func (c *Converter) Decode(ctx api.StreamContext, b []byte) (m any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	if c.registry == nil {
+		return Unmarshal(c.schema, b)
+	}
+	if len(b) < 5 || b[0] != magicByte {
+		return nil, fmt.Errorf("invalid avro wire format, the data must start with the magic byte and schema id")
+	}
+	s, err := c.registry.getById(int(binary.BigEndian.Uint32(b[1:5])))
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(s, b[5:])
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avro
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+const simpleSchema = `{"type":"record","name":"User","namespace":"test","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`
+
+func TestLocalConverter(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	c, err := NewConverter(simpleSchema, nil)
+	require.NoError(t, err)
+	b, err := c.Encode(ctx, map[string]any{"name": "a", "age": 3})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x02, 'a', 0x06}, b)
+	v, err := c.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "a", "age": int64(3)}, v)
+
+	_, err = c.Encode(ctx, []any{1})
+	assert.EqualError(t, err, "unsupported type [1], must be a map")
+	_, err = NewConverter("", nil)
+	assert.EqualError(t, err, "avro format requires a schemaId or the schemaRegistryUrl property")
+}
+
+func mockRegistry(t *testing.T, subjects map[string]bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", registryContentType)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/schemas/ids/42":
+			_ = json.NewEncoder(w).Encode(&registrySchema{Schema: simpleSchema})
+		case r.Method == http.MethodGet && r.URL.Path == "/subjects/users-value/versions/latest":
+			_ = json.NewEncoder(w).Encode(&registrySchema{Id: 42, Schema: simpleSchema, Subject: "users-value", Version: 1})
+		case r.Method == http.MethodPost:
+			rs := &registrySchema{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(rs))
+			assert.Equal(t, simpleSchema, rs.Schema)
+			subjects[r.URL.Path] = true
+			_ = json.NewEncoder(w).Encode(&registrySchema{Id: 43})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code":40401,"message":"Subject not found"}`))
+		}
+	}))
+}
+
+func TestRegistryConverter(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	subjects := make(map[string]bool)
+	server := mockRegistry(t, subjects)
+	defer server.Close()
+
+	// encode with the latest schema of the subject by topic name strategy
+	c, err := NewConverter("", map[string]any{"schemaRegistryUrl": server.URL, "topic": "users"})
+	require.NoError(t, err)
+	b, err := c.Encode(ctx, map[string]any{"name": "a", "age": 3})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, 42, 0x02, 'a', 0x06}, b)
+	v, err := c.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "a", "age": int64(3)}, v)
+
+	_, err = c.Decode(ctx, []byte{0x02, 'a', 0x06})
+	assert.EqualError(t, err, "invalid avro wire format, the data must start with the magic byte and schema id")
+	_, err = c.Decode(ctx, []byte{0, 0, 0, 0, 1, 0x02})
+	assert.ErrorContains(t, err, "schema registry request /schemas/ids/1 failed with status 404")
+
+	// register the local schema by the naming strategies
+	tests := []struct {
+		strategy string
+		subject  string
+	}{
+		{strategy: RecordNameStrategy, subject: "/subjects/test.User/versions"},
+		{strategy: TopicRecordNameStrategy, subject: "/subjects/users-test.User/versions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			c, err := NewConverter(simpleSchema, map[string]any{"schemaRegistryUrl": server.URL, "topic": "users", "subjectNameStrategy": tt.strategy})
+			require.NoError(t, err)
+			b, err := c.Encode(ctx, map[string]any{"name": "a", "age": 3})
+			require.NoError(t, err)
+			assert.Equal(t, []byte{0, 0, 0, 0, 43, 0x02, 'a', 0x06}, b)
+			assert.True(t, subjects[tt.subject])
+		})
+	}
+
+	_, err = NewConverter(simpleSchema, map[string]any{"schemaRegistryUrl": server.URL, "subjectNameStrategy": "none"})
+	assert.EqualError(t, err, "invalid subjectNameStrategy none, must be one of topic, record and topicRecord")
+	c, err = NewConverter("", map[string]any{"schemaRegistryUrl": server.URL})
+	require.NoError(t, err)
+	_, err = c.Encode(ctx, map[string]any{"name": "a", "age": 3})
+	assert.EqualError(t, err, "cannot decide the schema registry subject, set the subject or topic property")
+}
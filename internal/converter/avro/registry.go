@@ -0,0 +1,140 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const registryContentType = "application/vnd.schemaregistry.v1+json"
+
+// registryClient talks to the Confluent compatible schema registry and caches the schemas by id
+type registryClient struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+
+	sync.RWMutex
+	schemas map[int]*Schema
+}
+
+func newRegistryClient(u, user, password string) *registryClient {
+	return &registryClient{
+		url:      strings.TrimSuffix(u, "/"),
+		user:     user,
+		password: password,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		schemas:  make(map[int]*Schema),
+	}
+}
+
+type registrySchema struct {
+	Id      int    `json:"id,omitempty"`
+	Schema  string `json:"schema,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+}
+
+// getById returns the schema of the id, which is immutable in the registry so that it is cached forever
+func (r *registryClient) getById(id int) (*Schema, error) {
+	r.RLock()
+	s, ok := r.schemas[id]
+	r.RUnlock()
+	if ok {
+		return s, nil
+	}
+	rs := &registrySchema{}
+	if err := r.do(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, rs); err != nil {
+		return nil, err
+	}
+	s, err := ParseSchema(rs.Schema)
+	if err != nil {
+		return nil, err
+	}
+	r.Lock()
+	r.schemas[id] = s
+	r.Unlock()
+	return s, nil
+}
+
+// latest returns the id and schema of the latest version of the subject
+func (r *registryClient) latest(subject string) (int, *Schema, error) {
+	rs := &registrySchema{}
+	if err := r.do(http.MethodGet, fmt.Sprintf("/subjects/%s/versions/latest", url.PathEscape(subject)), nil, rs); err != nil {
+		return 0, nil, err
+	}
+	s, err := ParseSchema(rs.Schema)
+	if err != nil {
+		return 0, nil, err
+	}
+	r.Lock()
+	r.schemas[rs.Id] = s
+	r.Unlock()
+	return rs.Id, s, nil
+}
+
+// register registers the schema under the subject and returns its id. The registry returns the existing id
+// if the schema is already registered.
+func (r *registryClient) register(subject string, schema string) (int, error) {
+	rs := &registrySchema{}
+	if err := r.do(http.MethodPost, fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject)), &registrySchema{Schema: schema}, rs); err != nil {
+		return 0, err
+	}
+	return rs.Id, nil
+}
+
+func (r *registryClient) do(method string, path string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, r.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", registryContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", registryContentType)
+	}
+	if r.user != "" {
+		req.SetBasicAuth(r.user, r.password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("schema registry request %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("schema registry request %s failed with status %d: %s", path, resp.StatusCode, string(b))
+	}
+	return json.Unmarshal(b, result)
+}
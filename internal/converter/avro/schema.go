@@ -0,0 +1,219 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The kinds of the avro types
+const (
+	typeNull    = "null"
+	typeBoolean = "boolean"
+	typeInt     = "int"
+	typeLong    = "long"
+	typeFloat   = "float"
+	typeDouble  = "double"
+	typeBytes   = "bytes"
+	typeString  = "string"
+	typeRecord  = "record"
+	typeEnum    = "enum"
+	typeArray   = "array"
+	typeMap     = "map"
+	typeUnion   = "union"
+	typeFixed   = "fixed"
+)
+
+var primitives = map[string]bool{
+	typeNull: true, typeBoolean: true, typeInt: true, typeLong: true,
+	typeFloat: true, typeDouble: true, typeBytes: true, typeString: true,
+}
+
+// Schema is a parsed avro schema
+type Schema struct {
+	kind string
+	// the full name of the named types: record, enum and fixed
+	name     string
+	fields   []*field
+	symbols  []string
+	items    *Schema
+	values   *Schema
+	branches []*Schema
+	size     int
+}
+
+type field struct {
+	name   string
+	typ    *Schema
+	def    any
+	hasDef bool
+}
+
+// FullName returns the full name of the named schema, or the type kind for the others
+func (s *Schema) FullName() string {
+	if s.name != "" {
+		return s.name
+	}
+	return s.kind
+}
+
+// ParseSchema parses the avro schema in JSON
+func ParseSchema(content string) (*Schema, error) {
+	var v any
+	if err := json.Unmarshal([]byte(content), &v); err != nil {
+		return nil, fmt.Errorf("invalid avro schema: %v", err)
+	}
+	return parse(v, "", make(map[string]*Schema))
+}
+
+func parse(v any, namespace string, names map[string]*Schema) (*Schema, error) {
+	switch t := v.(type) {
+	case string:
+		if primitives[t] {
+			return &Schema{kind: t}, nil
+		}
+		if s, ok := names[fullName(t, namespace)]; ok {
+			return s, nil
+		}
+		if s, ok := names[t]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown avro type %s", t)
+	case []any:
+		s := &Schema{kind: typeUnion, branches: make([]*Schema, 0, len(t))}
+		for _, b := range t {
+			bs, err := parse(b, namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			if bs.kind == typeUnion {
+				return nil, fmt.Errorf("union cannot contain union directly")
+			}
+			s.branches = append(s.branches, bs)
+		}
+		return s, nil
+	case map[string]any:
+		return parseComplex(t, namespace, names)
+	default:
+		return nil, fmt.Errorf("invalid avro type %v", v)
+	}
+}
+
+func parseComplex(m map[string]any, namespace string, names map[string]*Schema) (*Schema, error) {
+	kind, ok := m["type"].(string)
+	if !ok {
+		// the type is a nested schema
+		return parse(m["type"], namespace, names)
+	}
+	switch kind {
+	case typeRecord, "error", typeEnum, typeFixed:
+		s, ns, err := newNamed(m, namespace, names)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case typeEnum:
+			s.kind = typeEnum
+			syms, ok := m["symbols"].([]any)
+			if !ok {
+				return nil, fmt.Errorf("enum %s must have symbols", s.name)
+			}
+			for _, sym := range syms {
+				str, ok := sym.(string)
+				if !ok {
+					return nil, fmt.Errorf("enum %s has invalid symbol %v", s.name, sym)
+				}
+				s.symbols = append(s.symbols, str)
+			}
+		case typeFixed:
+			s.kind = typeFixed
+			size, ok := m["size"].(float64)
+			if !ok || size < 0 {
+				return nil, fmt.Errorf("fixed %s must have a valid size", s.name)
+			}
+			s.size = int(size)
+		default:
+			s.kind = typeRecord
+			fs, ok := m["fields"].([]any)
+			if !ok {
+				return nil, fmt.Errorf("record %s must have fields", s.name)
+			}
+			for _, f := range fs {
+				fm, ok := f.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("record %s has invalid field %v", s.name, f)
+				}
+				name, ok := fm["name"].(string)
+				if !ok || name == "" {
+					return nil, fmt.Errorf("record %s has a field without name", s.name)
+				}
+				ft, err := parse(fm["type"], ns, names)
+				if err != nil {
+					return nil, fmt.Errorf("field %s.%s: %v", s.name, name, err)
+				}
+				d, hasDef := fm["default"]
+				s.fields = append(s.fields, &field{name: name, typ: ft, def: d, hasDef: hasDef})
+			}
+		}
+		return s, nil
+	case typeArray:
+		items, err := parse(m["items"], namespace, names)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{kind: typeArray, items: items}, nil
+	case typeMap:
+		values, err := parse(m["values"], namespace, names)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{kind: typeMap, values: values}, nil
+	default:
+		// primitive types with attributes like logicalType or named references
+		return parse(kind, namespace, names)
+	}
+}
+
+// newNamed registers the named type before parsing its content so that it can be referred recursively
+func newNamed(m map[string]any, namespace string, names map[string]*Schema) (*Schema, string, error) {
+	name, ok := m["name"].(string)
+	if !ok || name == "" {
+		return nil, "", fmt.Errorf("named avro type must have a name")
+	}
+	if ns, ok := m["namespace"].(string); ok {
+		namespace = ns
+	}
+	full := fullName(name, namespace)
+	if _, ok := names[full]; ok {
+		return nil, "", fmt.Errorf("duplicate avro type %s", full)
+	}
+	s := &Schema{name: full}
+	names[full] = s
+	if i := strings.LastIndex(full, "."); i >= 0 {
+		namespace = full[:i]
+	} else {
+		namespace = ""
+	}
+	return s, namespace, nil
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
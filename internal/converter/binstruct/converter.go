@@ -0,0 +1,313 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binstruct
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+// FieldLayout defines where and how a field is stored in the frame
+type FieldLayout struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Type   string `json:"type"`
+	// Length is the byte length of string and bytes type
+	Length int `json:"length"`
+	// ByteOrder overrides the byte order of the frame, big or little
+	ByteOrder string `json:"byteOrder"`
+	// BitOffset and BitLength select the bits of the integer as the value. The bit offset counts from the least significant bit
+	BitOffset int `json:"bitOffset"`
+	BitLength int `json:"bitLength"`
+
+	order binary.ByteOrder
+	size  int
+}
+
+type Converter struct {
+	ByteOrder string         `json:"byteOrder"`
+	Layout    []*FieldLayout `json:"layout"`
+
+	// frameSize is the minimum length of the frame to hold all fields
+	frameSize int
+}
+
+func NewConverter(props map[string]any) (message.Converter, error) {
+	c := &Converter{}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return nil, err
+	}
+	if len(c.Layout) == 0 {
+		return nil, fmt.Errorf("layout is required for binstruct format")
+	}
+	defaultOrder, err := parseOrder(c.ByteOrder)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{}, len(c.Layout))
+	for _, f := range c.Layout {
+		if err := f.validate(defaultOrder); err != nil {
+			return nil, err
+		}
+		if _, ok := names[f.Name]; ok {
+			return nil, fmt.Errorf("duplicate field %s", f.Name)
+		}
+		names[f.Name] = struct{}{}
+		if end := f.Offset + f.size; end > c.frameSize {
+			c.frameSize = end
+		}
+	}
+	return c, nil
+}
+
+func parseOrder(s string) (binary.ByteOrder, error) {
+	switch strings.ToLower(s) {
+	case "", "big":
+		return binary.BigEndian, nil
+	case "little":
+		return binary.LittleEndian, nil
+	default:
+		return nil, fmt.Errorf("invalid byteOrder %s, must be big or little", s)
+	}
+}
+
+func (f *FieldLayout) validate(defaultOrder binary.ByteOrder) error {
+	if f.Name == "" {
+		return fmt.Errorf("field name is required in layout")
+	}
+	if f.Offset < 0 {
+		return fmt.Errorf("invalid offset %d of field %s", f.Offset, f.Name)
+	}
+	f.order = defaultOrder
+	if f.ByteOrder != "" {
+		o, err := parseOrder(f.ByteOrder)
+		if err != nil {
+			return fmt.Errorf("field %s: %v", f.Name, err)
+		}
+		f.order = o
+	}
+	switch f.Type {
+	case "int8", "uint8", "bool":
+		f.size = 1
+	case "int16", "uint16":
+		f.size = 2
+	case "int32", "uint32", "float32":
+		f.size = 4
+	case "int64", "uint64", "float64":
+		f.size = 8
+	case "string", "bytes":
+		if f.Length <= 0 {
+			return fmt.Errorf("length is required for field %s of type %s", f.Name, f.Type)
+		}
+		f.size = f.Length
+	default:
+		return fmt.Errorf("unsupported type %s of field %s", f.Type, f.Name)
+	}
+	if f.BitLength != 0 || f.BitOffset != 0 {
+		if f.Type == "string" || f.Type == "bytes" || f.Type == "float32" || f.Type == "float64" {
+			return fmt.Errorf("bit field %s must be integer or bool type", f.Name)
+		}
+		if f.BitLength <= 0 || f.BitOffset < 0 || f.BitOffset+f.BitLength > f.size*8 {
+			return fmt.Errorf("invalid bits %d:%d of field %s", f.BitOffset, f.BitLength, f.Name)
+		}
+	}
+	return nil
+}
+
+func (c *Converter) Decode(_ api.StreamContext, b []byte) (m any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	if len(b) < c.frameSize {
+		return nil, fmt.Errorf("frame length %d is shorter than the layout length %d", len(b), c.frameSize)
+	}
+	result := make(map[string]any, len(c.Layout))
+	for _, f := range c.Layout {
+		result[f.Name] = f.decode(b[f.Offset : f.Offset+f.size])
+	}
+	return result, nil
+}
+
+func (f *FieldLayout) decode(b []byte) any {
+	switch f.Type {
+	case "string":
+		return strings.TrimRight(string(b), "\x00")
+	case "bytes":
+		r := make([]byte, len(b))
+		copy(r, b)
+		return r
+	case "float32":
+		return float64(math.Float32frombits(f.order.Uint32(b)))
+	case "float64":
+		return math.Float64frombits(f.order.Uint64(b))
+	}
+	raw := f.readUint(b)
+	if f.BitLength > 0 {
+		raw = (raw >> f.BitOffset) & (1<<f.BitLength - 1)
+		switch f.Type {
+		case "bool":
+			return raw != 0
+		case "int8", "int16", "int32", "int64":
+			// Sign-extend by the highest bit of the field
+			shift := 64 - f.BitLength
+			return int64(raw<<shift) >> shift
+		case "uint64":
+			return raw
+		default:
+			return int64(raw)
+		}
+	}
+	switch f.Type {
+	case "bool":
+		return raw != 0
+	case "int8":
+		return int64(int8(raw))
+	case "int16":
+		return int64(int16(raw))
+	case "int32":
+		return int64(int32(raw))
+	case "uint64":
+		return raw
+	default:
+		return int64(raw)
+	}
+}
+
+func (f *FieldLayout) readUint(b []byte) uint64 {
+	switch f.size {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(f.order.Uint16(b))
+	case 4:
+		return uint64(f.order.Uint32(b))
+	default:
+		return f.order.Uint64(b)
+	}
+}
+
+func (f *FieldLayout) writeUint(b []byte, v uint64) {
+	switch f.size {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		f.order.PutUint16(b, uint16(v))
+	case 4:
+		f.order.PutUint32(b, uint32(v))
+	default:
+		f.order.PutUint64(b, v)
+	}
+}
+
+// Encode writes the fields of the map into a frame by the layout. The missing fields are filled with zero
+func (c *Converter) Encode(_ api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	m, ok := d.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %v, must be a map", d)
+	}
+	frame := make([]byte, c.frameSize)
+	for _, f := range c.Layout {
+		v, ok := m[f.Name]
+		if !ok || v == nil {
+			continue
+		}
+		if err := f.encode(frame[f.Offset:f.Offset+f.size], v); err != nil {
+			return nil, fmt.Errorf("encode field %s error: %v", f.Name, err)
+		}
+	}
+	return frame, nil
+}
+
+func (f *FieldLayout) encode(b []byte, v any) error {
+	switch f.Type {
+	case "string":
+		s, err := cast.ToString(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		if len(s) > f.size {
+			return fmt.Errorf("string length %d exceeds %d", len(s), f.size)
+		}
+		copy(b, s)
+		return nil
+	case "bytes":
+		bs, err := cast.ToBytes(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		if len(bs) > f.size {
+			return fmt.Errorf("bytes length %d exceeds %d", len(bs), f.size)
+		}
+		copy(b, bs)
+		return nil
+	case "float32":
+		fv, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		f.order.PutUint32(b, math.Float32bits(float32(fv)))
+		return nil
+	case "float64":
+		fv, err := cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		f.order.PutUint64(b, math.Float64bits(fv))
+		return nil
+	}
+	var raw uint64
+	if f.Type == "bool" {
+		bv, err := cast.ToBool(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		if bv {
+			raw = 1
+		}
+	} else if f.Type == "uint64" {
+		uv, err := cast.ToUint64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		raw = uv
+	} else {
+		iv, err := cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return err
+		}
+		raw = uint64(iv)
+	}
+	if f.BitLength > 0 {
+		mask := uint64(1<<f.BitLength-1) << f.BitOffset
+		raw = f.readUint(b)&^mask | (raw<<f.BitOffset)&mask
+	}
+	f.writeUint(b, raw)
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package binstruct
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+var layoutProps = map[string]any{
+	"byteOrder": "big",
+	"layout": []any{
+		map[string]any{"name": "id", "offset": 0, "type": "uint16"},
+		map[string]any{"name": "temp", "offset": 2, "type": "int16", "byteOrder": "little"},
+		map[string]any{"name": "running", "offset": 4, "type": "bool", "bitOffset": 0, "bitLength": 1},
+		map[string]any{"name": "mode", "offset": 4, "type": "uint8", "bitOffset": 1, "bitLength": 3},
+		map[string]any{"name": "pressure", "offset": 5, "type": "float32"},
+		map[string]any{"name": "device", "offset": 9, "type": "string", "length": 4},
+		map[string]any{"name": "raw", "offset": 13, "type": "bytes", "length": 2},
+	},
+}
+
+func TestDecodeEncode(t *testing.T) {
+	frame := []byte{0x00, 0x2a, 0xf6, 0xff, 0x0b, 0x42, 0xca, 0x00, 0x00, 'p', 'l', 'c', 0x00, 0x01, 0x02}
+	exp := map[string]any{
+		"id":       int64(42),
+		"temp":     int64(-10),
+		"running":  true,
+		"mode":     int64(5),
+		"pressure": float64(101),
+		"device":   "plc",
+		"raw":      []byte{0x01, 0x02},
+	}
+	c, err := NewConverter(layoutProps)
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("test", "op1")
+	r, err := c.Decode(ctx, frame)
+	require.NoError(t, err)
+	require.Equal(t, exp, r)
+	b, err := c.Encode(ctx, exp)
+	require.NoError(t, err)
+	require.Equal(t, frame, b)
+}
+
+func TestDecodeEncodeBits(t *testing.T) {
+	c, err := NewConverter(map[string]any{
+		"layout": []any{
+			map[string]any{"name": "low", "offset": 0, "type": "int8", "bitOffset": 0, "bitLength": 4},
+			map[string]any{"name": "high", "offset": 0, "type": "int8", "bitOffset": 4, "bitLength": 4},
+			map[string]any{"name": "flags", "offset": 1, "type": "uint64", "bitOffset": 60, "bitLength": 4},
+		},
+	})
+	require.NoError(t, err)
+	frame := []byte{0x3e, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	// The signed bit fields are sign-extended and the uint64 bit field is uint64
+	exp := map[string]any{
+		"low":   int64(-2),
+		"high":  int64(3),
+		"flags": uint64(15),
+	}
+	ctx := mockContext.NewMockContext("test", "op1")
+	r, err := c.Decode(ctx, frame)
+	require.NoError(t, err)
+	require.Equal(t, exp, r)
+	b, err := c.Encode(ctx, exp)
+	require.NoError(t, err)
+	require.Equal(t, frame, b)
+}
+
+func TestDecodeError(t *testing.T) {
+	c, err := NewConverter(layoutProps)
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("test", "op1")
+	_, err = c.Decode(ctx, []byte{0x00, 0x01})
+	require.EqualError(t, err, "frame length 2 is shorter than the layout length 15")
+	_, err = c.Encode(ctx, map[string]any{"device": "too long"})
+	require.EqualError(t, err, "encode field device error: string length 8 exceeds 4")
+	_, err = c.Encode(ctx, []map[string]any{})
+	require.Error(t, err)
+}
+
+func TestNewConverterError(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]any
+		err   string
+	}{
+		{
+			name:  "no layout",
+			props: map[string]any{},
+			err:   "layout is required for binstruct format",
+		},
+		{
+			name:  "invalid order",
+			props: map[string]any{"byteOrder": "middle", "layout": []any{map[string]any{"name": "a", "type": "int8"}}},
+			err:   "invalid byteOrder middle, must be big or little",
+		},
+		{
+			name:  "invalid type",
+			props: map[string]any{"layout": []any{map[string]any{"name": "a", "type": "int128"}}},
+			err:   "unsupported type int128 of field a",
+		},
+		{
+			name:  "no length",
+			props: map[string]any{"layout": []any{map[string]any{"name": "a", "type": "string"}}},
+			err:   "length is required for field a of type string",
+		},
+		{
+			name:  "invalid bits",
+			props: map[string]any{"layout": []any{map[string]any{"name": "a", "type": "uint8", "bitOffset": 4, "bitLength": 5}}},
+			err:   "invalid bits 4:5 of field a",
+		},
+		{
+			name:  "float bits",
+			props: map[string]any{"layout": []any{map[string]any{"name": "a", "type": "float32", "bitLength": 1}}},
+			err:   "bit field a must be integer or bool type",
+		},
+		{
+			name:  "duplicate",
+			props: map[string]any{"layout": []any{map[string]any{"name": "a", "type": "int8"}, map[string]any{"name": "a", "offset": 1, "type": "int8"}}},
+			err:   "duplicate field a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewConverter(tt.props)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
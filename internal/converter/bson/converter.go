@@ -0,0 +1,168 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	mbson "go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+type Converter struct {
+	// ObjectIdFields are the top level fields encoded as ObjectId if the value is a valid hex string
+	ObjectIdFields []string `json:"objectIdFields"`
+}
+
+func NewConverter(props map[string]any) (message.Converter, error) {
+	c := &Converter{}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return nil, err
+	}
+	if len(c.ObjectIdFields) == 0 {
+		c.ObjectIdFields = []string{"_id"}
+	}
+	return c, nil
+}
+
+// Encode encodes a map to a BSON document and a list of maps to the concatenated documents
+func (c *Converter) Encode(_ api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	switch dt := d.(type) {
+	case map[string]any:
+		return mbson.Marshal(c.toDocument(dt))
+	case []map[string]any:
+		for _, m := range dt {
+			b, err = mbson.MarshalAppend(b, c.toDocument(m))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %v, must be a map or array of maps", d)
+	}
+}
+
+func (c *Converter) toDocument(m map[string]any) map[string]any {
+	var doc map[string]any
+	for _, f := range c.ObjectIdFields {
+		s, ok := m[f].(string)
+		if !ok {
+			continue
+		}
+		oid, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			continue
+		}
+		if doc == nil {
+			doc = make(map[string]any, len(m))
+			for k, v := range m {
+				doc[k] = v
+			}
+		}
+		doc[f] = oid
+	}
+	if doc == nil {
+		return m
+	}
+	return doc
+}
+
+// Decode decodes a BSON document to a map. If the payload has multiple concatenated documents, such as a dump file, they are decoded to a list of maps
+func (c *Converter) Decode(_ api.StreamContext, b []byte) (m any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	var result []map[string]any
+	for len(b) > 0 {
+		doc, rem, ok := bsoncore.ReadDocument(b)
+		if !ok {
+			return nil, fmt.Errorf("invalid bson document")
+		}
+		var d mbson.D
+		if err := mbson.Unmarshal(doc, &d); err != nil {
+			return nil, fmt.Errorf("fail to decode bson: %v", err)
+		}
+		result = append(result, convertDoc(d))
+		b = rem
+	}
+	switch len(result) {
+	case 0:
+		return nil, fmt.Errorf("empty bson payload")
+	case 1:
+		return result[0], nil
+	default:
+		return result, nil
+	}
+}
+
+func convertDoc(d mbson.D) map[string]any {
+	m := make(map[string]any, len(d))
+	for _, e := range d {
+		m[e.Key] = convertValue(e.Value)
+	}
+	return m
+}
+
+// convertValue converts the BSON types to the types of eKuiper. ObjectId is converted to the hex string and the dates are converted to datetime.
+func convertValue(v any) any {
+	switch vt := v.(type) {
+	case mbson.D:
+		return convertDoc(vt)
+	case mbson.A:
+		r := make([]any, len(vt))
+		for i, item := range vt {
+			r[i] = convertValue(item)
+		}
+		return r
+	case int32:
+		return int64(vt)
+	case primitive.ObjectID:
+		return vt.Hex()
+	case primitive.DateTime:
+		return cast.TimeFromUnixMilli(int64(vt))
+	case primitive.Timestamp:
+		return cast.TimeFromUnixMilli(int64(vt.T) * 1000)
+	case primitive.Binary:
+		return vt.Data
+	case primitive.Decimal128:
+		return vt.String()
+	case primitive.Regex:
+		return vt.Pattern
+	case primitive.JavaScript:
+		return string(vt)
+	case primitive.Symbol:
+		return string(vt)
+	case primitive.Null, primitive.Undefined, primitive.MinKey, primitive.MaxKey:
+		return nil
+	case primitive.DBPointer:
+		return vt.Pointer.Hex()
+	default:
+		return v
+	}
+}
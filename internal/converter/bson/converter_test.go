@@ -0,0 +1,110 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	mbson "go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestDecode(t *testing.T) {
+	oid, err := primitive.ObjectIDFromHex("65a1b2c3d4e5f60718293a4b")
+	require.NoError(t, err)
+	ts := time.UnixMilli(1700000000123)
+	doc, err := mbson.Marshal(mbson.D{
+		{Key: "_id", Value: oid},
+		{Key: "operationType", Value: "insert"},
+		{Key: "count", Value: int32(3)},
+		{Key: "total", Value: int64(1 << 40)},
+		{Key: "ratio", Value: 0.5},
+		{Key: "ok", Value: true},
+		{Key: "ts", Value: primitive.NewDateTimeFromTime(ts)},
+		{Key: "data", Value: primitive.Binary{Data: []byte{1, 2}}},
+		{Key: "none", Value: nil},
+		{Key: "fullDocument", Value: mbson.D{{Key: "tags", Value: mbson.A{"a", int32(1)}}}},
+	})
+	require.NoError(t, err)
+	c, err := NewConverter(nil)
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("test", "op1")
+	r, err := c.Decode(ctx, doc)
+	require.NoError(t, err)
+	exp := map[string]any{
+		"_id":           "65a1b2c3d4e5f60718293a4b",
+		"operationType": "insert",
+		"count":         int64(3),
+		"total":         int64(1 << 40),
+		"ratio":         0.5,
+		"ok":            true,
+		"ts":            cast.TimeFromUnixMilli(1700000000123),
+		"data":          []byte{1, 2},
+		"none":          nil,
+		"fullDocument":  map[string]any{"tags": []any{"a", int64(1)}},
+	}
+	require.Equal(t, exp, r)
+
+	// The encoded data is decoded back with the same types
+	b, err := c.Encode(ctx, exp)
+	require.NoError(t, err)
+	var d mbson.M
+	require.NoError(t, mbson.Unmarshal(b, &d))
+	require.Equal(t, oid, d["_id"])
+	require.Equal(t, primitive.NewDateTimeFromTime(ts), d["ts"])
+	r, err = c.Decode(ctx, b)
+	require.NoError(t, err)
+	require.Equal(t, exp, r)
+}
+
+func TestMultipleDocuments(t *testing.T) {
+	c, err := NewConverter(map[string]any{"objectIdFields": []string{"ref"}})
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("test", "op1")
+	data := []map[string]any{
+		{"_id": "65a1b2c3d4e5f60718293a4b", "ref": "65a1b2c3d4e5f60718293a4c"},
+		{"_id": "abc", "ref": "not an id"},
+	}
+	b, err := c.Encode(ctx, data)
+	require.NoError(t, err)
+	r, err := c.Decode(ctx, b)
+	require.NoError(t, err)
+	require.Equal(t, data, r)
+	// only the configured field is encoded as ObjectId
+	first, _, ok := bsoncore.ReadDocument(b)
+	require.True(t, ok)
+	var d mbson.M
+	require.NoError(t, mbson.Unmarshal(first, &d))
+	require.IsType(t, "", d["_id"])
+	require.IsType(t, primitive.ObjectID{}, d["ref"])
+}
+
+func TestError(t *testing.T) {
+	c, err := NewConverter(nil)
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("test", "op1")
+	_, err = c.Decode(ctx, []byte{0x01, 0x02})
+	require.EqualError(t, err, "invalid bson document")
+	_, err = c.Decode(ctx, nil)
+	require.EqualError(t, err, "empty bson payload")
+	_, err = c.Encode(ctx, "abc")
+	require.Error(t, err)
+}
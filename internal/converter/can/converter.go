@@ -0,0 +1,195 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package can
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const (
+	// FrameId is the payload of 4 bytes big endian CAN id followed by the data
+	FrameId = "id"
+	// FrameSocketCan is the payload of the linux socketcan can_frame or canfd_frame struct
+	FrameSocketCan = "socketcan"
+
+	KeyCanId   = "canId"
+	KeyMessage = "message"
+
+	socketCanEff = 0x80000000
+	socketCanRtr = 0x40000000
+	socketCanErr = 0x20000000
+)
+
+// Converter decodes the raw CAN frames to the signal values by the DBC file. The DBC file is reloaded if changed.
+type Converter struct {
+	// FrameType is the layout of the payload, id or socketcan. Default to id
+	FrameType string `json:"frameType"`
+	// IgnoreUnknown drops the frames whose id is not defined in the DBC file instead of reporting an error
+	IgnoreUnknown bool `json:"ignoreUnknown"`
+
+	file          string
+	checkInterval time.Duration
+
+	sync.RWMutex
+	db        *Database
+	modTime   time.Time
+	lastCheck int64
+}
+
+func NewConverter(dbcFile string, props map[string]any) (message.Converter, error) {
+	c := &Converter{file: dbcFile, checkInterval: time.Second}
+	err := cast.MapToStruct(props, c)
+	if err != nil {
+		return nil, err
+	}
+	switch c.FrameType {
+	case "":
+		c.FrameType = FrameId
+	case FrameId, FrameSocketCan:
+	default:
+		return nil, fmt.Errorf("unsupported frameType %s, must be id or socketcan", c.FrameType)
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Converter) load() error {
+	fi, err := os.Stat(c.file)
+	if err != nil {
+		return fmt.Errorf("cannot read dbc file %s: %v", c.file, err)
+	}
+	c.lastCheck = timex.GetNowInMilli()
+	if fi.ModTime().Equal(c.modTime) {
+		return nil
+	}
+	content, err := os.ReadFile(c.file)
+	if err != nil {
+		return fmt.Errorf("cannot read dbc file %s: %v", c.file, err)
+	}
+	db, err := ParseDBC(string(content))
+	if err != nil {
+		return fmt.Errorf("cannot parse dbc file %s: %v", c.file, err)
+	}
+	c.db = db
+	c.modTime = fi.ModTime()
+	return nil
+}
+
+// database returns the current database. The file is checked at most once per check interval and reloaded if changed.
+// If the changed file is invalid, the previous database is kept.
+func (c *Converter) database(ctx api.StreamContext) *Database {
+	c.RLock()
+	db := c.db
+	stale := timex.GetNowInMilli()-c.lastCheck >= c.checkInterval.Milliseconds()
+	c.RUnlock()
+	if !stale {
+		return db
+	}
+	c.Lock()
+	defer c.Unlock()
+	if err := c.load(); err != nil {
+		ctx.GetLogger().Warnf("keep the previous dbc: %v", err)
+	}
+	return c.db
+}
+
+func (c *Converter) Encode(_ api.StreamContext, _ any) ([]byte, error) {
+	return nil, errorx.NewWithCode(errorx.CovnerterErr, "encoding is not supported by the can format")
+}
+
+// Decode decodes a frame to a map of the signal values by name, including the canId and the message name
+func (c *Converter) Decode(ctx api.StreamContext, b []byte) (_ any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	id, data, err := c.parseFrame(b)
+	if err != nil {
+		return nil, err
+	}
+	msg, ok := c.database(ctx).Messages[id]
+	if !ok {
+		if c.IgnoreUnknown {
+			return []map[string]any{}, nil
+		}
+		return nil, fmt.Errorf("CAN id %d is not defined in the dbc file", id)
+	}
+	result := make(map[string]any, len(msg.Signals)+2)
+	result[KeyCanId] = int64(id)
+	result[KeyMessage] = msg.Name
+	mux := int64(-1)
+	for _, s := range msg.Signals {
+		if s.IsMultiplexor {
+			raw, err := s.Raw(data)
+			if err != nil {
+				return nil, err
+			}
+			mux = int64(raw)
+			result[s.Name] = s.Value(raw)
+		}
+	}
+	for _, s := range msg.Signals {
+		if s.IsMultiplexor || (s.MultiplexValue >= 0 && s.MultiplexValue != mux) {
+			continue
+		}
+		raw, err := s.Raw(data)
+		if err != nil {
+			return nil, err
+		}
+		result[s.Name] = s.Value(raw)
+	}
+	return result, nil
+}
+
+func (c *Converter) parseFrame(b []byte) (uint32, []byte, error) {
+	switch c.FrameType {
+	case FrameSocketCan:
+		// can_frame is 16 bytes and canfd_frame is 72 bytes with the id in host byte order
+		if len(b) != 16 && len(b) != 72 {
+			return 0, nil, fmt.Errorf("invalid socketcan frame of %d bytes", len(b))
+		}
+		id := binary.LittleEndian.Uint32(b)
+		if id&(socketCanRtr|socketCanErr) != 0 {
+			return 0, nil, fmt.Errorf("remote or error frame %x is not supported", id)
+		}
+		size := int(b[4])
+		if size > len(b)-8 {
+			return 0, nil, fmt.Errorf("invalid socketcan frame length %d", size)
+		}
+		if id&socketCanEff == 0 {
+			id &= 0x7FF
+		}
+		return id & idMask, b[8 : 8+size], nil
+	default:
+		if len(b) < 4 {
+			return 0, nil, fmt.Errorf("invalid frame of %d bytes, must have the 4 bytes id", len(b))
+		}
+		return binary.BigEndian.Uint32(b) & idMask, b[4:], nil
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package can
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+const testDbc = `VERSION ""
+
+BU_: ECU GW
+
+BO_ 256 EngineData: 8 ECU
+ SG_ EngineSpeed : 0|16@1+ (0.25,0) [0|16383.75] "rpm" GW
+ SG_ CoolantTemp : 16|8@1+ (1,-40) [-40|215] "degC" GW
+ SG_ Torque : 24|12@1- (0.5,0) [-1024|1023.5] "Nm" GW
+ SG_ Gear : 39|4@0+ (1,0) [0|15] "" GW
+
+BO_ 2147484160 Diag: 8 ECU
+ SG_ Page M : 0|8@1+ (1,0) [0|255] "" GW
+ SG_ Voltage m1 : 8|16@1+ (0.01,0) [0|655.35] "V" GW
+ SG_ Current m2 : 8|16@1- (0.1,0) [-3276.8|3276.7] "A" GW
+`
+
+func frame(id uint32, data ...byte) []byte {
+	return append([]byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}, data...)
+}
+
+func TestDecode(t *testing.T) {
+	ctx := context.Background()
+	f := filepath.Join(t.TempDir(), "car.dbc")
+	require.NoError(t, os.WriteFile(f, []byte(testDbc), 0o666))
+	c, err := NewConverter(f, nil)
+	require.NoError(t, err)
+	tests := []struct {
+		name  string
+		input []byte
+		exp   any
+		err   string
+	}{
+		{
+			name: "intel and motorola",
+			// speed 3000 rpm = 12000 raw, coolant 90 = 130 raw, torque -100 = -200 raw (12 bits 0xF38), gear 5 in the high nibble of byte 4
+			input: frame(256, 0xE0, 0x2E, 0x82, 0x38, 0x5F, 0, 0, 0),
+			exp: map[string]any{
+				"canId":       int64(256),
+				"message":     "EngineData",
+				"EngineSpeed": 3000.0,
+				"CoolantTemp": int64(90),
+				"Torque":      -100.0,
+				"Gear":        int64(5),
+			},
+		},
+		{
+			name:  "multiplexed page 1",
+			input: frame(0x80000200, 1, 0xC4, 0x04, 0, 0, 0, 0, 0),
+			exp: map[string]any{
+				"canId":   int64(512),
+				"message": "Diag",
+				"Page":    int64(1),
+				"Voltage": 12.2,
+			},
+		},
+		{
+			name:  "multiplexed page 2",
+			input: frame(512, 2, 0x9C, 0xFF, 0, 0, 0, 0, 0),
+			exp: map[string]any{
+				"canId":   int64(512),
+				"message": "Diag",
+				"Page":    int64(2),
+				"Current": -10.0,
+			},
+		},
+		{
+			name:  "unknown id",
+			input: frame(1, 0),
+			err:   "CAN id 1 is not defined in the dbc file",
+		},
+		{
+			name:  "short data",
+			input: frame(256, 0xE0),
+			err:   "signal EngineSpeed is out of the frame data of 1 bytes",
+		},
+		{
+			name:  "no id",
+			input: []byte{1, 2},
+			err:   "invalid frame of 2 bytes, must have the 4 bytes id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := c.Decode(ctx, tt.input)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			rm := r.(map[string]any)
+			require.Len(t, rm, len(tt.exp.(map[string]any)))
+			for k, v := range tt.exp.(map[string]any) {
+				if f, ok := v.(float64); ok {
+					require.InDelta(t, f, rm[k], 1e-9, k)
+				} else {
+					require.Equal(t, v, rm[k], k)
+				}
+			}
+		})
+	}
+}
+
+func TestSocketCan(t *testing.T) {
+	ctx := context.Background()
+	f := filepath.Join(t.TempDir(), "car.dbc")
+	require.NoError(t, os.WriteFile(f, []byte(testDbc), 0o666))
+	c, err := NewConverter(f, map[string]any{"frameType": "socketcan", "ignoreUnknown": true})
+	require.NoError(t, err)
+	b := make([]byte, 16)
+	b[0], b[1], b[4] = 0x00, 0x01, 8
+	copy(b[8:], []byte{0xE0, 0x2E, 0x82, 0x38, 0x5F})
+	r, err := c.Decode(ctx, b)
+	require.NoError(t, err)
+	require.Equal(t, int64(256), r.(map[string]any)["canId"])
+	b[0] = 0x02
+	r, err = c.Decode(ctx, b)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{}, r)
+	_, err = c.Decode(ctx, b[:8])
+	require.EqualError(t, err, "invalid socketcan frame of 8 bytes")
+	_, err = NewConverter(f, map[string]any{"frameType": "raw"})
+	require.EqualError(t, err, "unsupported frameType raw, must be id or socketcan")
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	f := filepath.Join(t.TempDir(), "car.dbc")
+	require.NoError(t, os.WriteFile(f, []byte(testDbc), 0o666))
+	cc, err := NewConverter(f, nil)
+	require.NoError(t, err)
+	c := cc.(*Converter)
+	c.checkInterval = 0
+	_, err = c.Decode(ctx, frame(1, 0))
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(f, []byte("BO_ 1 New: 1 ECU\n SG_ Flag : 0|1@1+ (1,0) [0|1] \"\" GW\n"), 0o666))
+	require.NoError(t, os.Chtimes(f, time.Now(), time.Now().Add(time.Second)))
+	r, err := c.Decode(ctx, frame(1, 1))
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"canId": int64(1), "message": "New", "Flag": int64(1)}, r)
+
+	// invalid file keeps the previous database
+	require.NoError(t, os.WriteFile(f, []byte("BO_ invalid"), 0o666))
+	require.NoError(t, os.Chtimes(f, time.Now(), time.Now().Add(2*time.Second)))
+	r, err = c.Decode(ctx, frame(1, 0))
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"canId": int64(1), "message": "New", "Flag": int64(0)}, r)
+}
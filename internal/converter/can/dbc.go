@@ -0,0 +1,188 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package can
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Database is the parsed DBC file, which maps the CAN id to the message definition
+type Database struct {
+	Messages map[uint32]*Message
+}
+
+type Message struct {
+	Id      uint32
+	Name    string
+	Size    int
+	Signals []*Signal
+}
+
+type Signal struct {
+	Name      string
+	StartBit  int
+	Length    int
+	BigEndian bool
+	Signed    bool
+	Factor    float64
+	Offset    float64
+	Unit      string
+	// IsMultiplexor is true if the signal is the multiplexor switch of the message
+	IsMultiplexor bool
+	// MultiplexValue is the value of the multiplexor when the signal is present, -1 if the signal is not multiplexed
+	MultiplexValue int64
+}
+
+const idMask = 0x1FFFFFFF
+
+var (
+	msgRegex = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)`)
+	sigRegex = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+)?\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(([^,]+),([^)]+)\)\s*\[[^\]]*\]\s*"([^"]*)"`)
+)
+
+// ParseDBC parses the messages and signals of the DBC content. Other sections are ignored.
+func ParseDBC(content string) (*Database, error) {
+	db := &Database{Messages: make(map[uint32]*Message)}
+	var current *Message
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "BO_ "):
+			m := msgRegex.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid message definition at line %d: %s", lineNo, line)
+			}
+			id, err := strconv.ParseUint(m[1], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid message id at line %d: %v", lineNo, err)
+			}
+			size, _ := strconv.Atoi(m[3])
+			current = &Message{Id: uint32(id) & idMask, Name: m[2], Size: size}
+			db.Messages[current.Id] = current
+		case strings.HasPrefix(line, "SG_ "):
+			if current == nil {
+				return nil, fmt.Errorf("signal without message at line %d", lineNo)
+			}
+			s, err := parseSignal(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid signal definition at line %d: %v", lineNo, err)
+			}
+			current.Signals = append(current.Signals, s)
+		case line == "":
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(db.Messages) == 0 {
+		return nil, fmt.Errorf("no message found in the dbc file")
+	}
+	return db, nil
+}
+
+func parseSignal(line string) (*Signal, error) {
+	m := sigRegex.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("%s", line)
+	}
+	s := &Signal{
+		Name:           m[1],
+		BigEndian:      m[5] == "0",
+		Signed:         m[6] == "-",
+		Unit:           m[9],
+		MultiplexValue: -1,
+	}
+	switch {
+	case m[2] == "M":
+		s.IsMultiplexor = true
+	case m[2] != "":
+		v, err := strconv.ParseInt(m[2][1:], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		s.MultiplexValue = v
+	}
+	s.StartBit, _ = strconv.Atoi(m[3])
+	s.Length, _ = strconv.Atoi(m[4])
+	if s.Length <= 0 || s.Length > 64 {
+		return nil, fmt.Errorf("invalid length %d of signal %s", s.Length, s.Name)
+	}
+	var err error
+	s.Factor, err = strconv.ParseFloat(strings.TrimSpace(m[7]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid factor of signal %s: %v", s.Name, err)
+	}
+	s.Offset, err = strconv.ParseFloat(strings.TrimSpace(m[8]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid offset of signal %s: %v", s.Name, err)
+	}
+	return s, nil
+}
+
+// Raw extracts the raw value of the signal from the frame data
+func (s *Signal) Raw(data []byte) (uint64, error) {
+	var raw uint64
+	pos := s.StartBit
+	for i := 0; i < s.Length; i++ {
+		if s.BigEndian {
+			if pos/8 >= len(data) || pos < 0 {
+				return 0, fmt.Errorf("signal %s is out of the frame data of %d bytes", s.Name, len(data))
+			}
+			raw = raw<<1 | uint64(data[pos/8]>>(pos%8)&1)
+			// The motorola bits go from the msb to the lsb of a byte, then to the msb of the next byte
+			if pos%8 == 0 {
+				pos += 15
+			} else {
+				pos--
+			}
+		} else {
+			p := s.StartBit + i
+			if p/8 >= len(data) {
+				return 0, fmt.Errorf("signal %s is out of the frame data of %d bytes", s.Name, len(data))
+			}
+			raw |= uint64(data[p/8]>>(p%8)&1) << i
+		}
+	}
+	return raw, nil
+}
+
+// Value converts the raw value to the physical value. The value is an int64 if both the factor and the offset are integers.
+func (s *Signal) Value(raw uint64) any {
+	var v int64
+	if s.Signed && s.Length < 64 && raw&(1<<(s.Length-1)) != 0 {
+		v = int64(raw | ^uint64(0)<<s.Length)
+	} else {
+		v = int64(raw)
+	}
+	if s.Factor == math.Trunc(s.Factor) && s.Offset == math.Trunc(s.Offset) {
+		return v*int64(s.Factor) + int64(s.Offset)
+	}
+	var f float64
+	if s.Signed {
+		f = float64(v)
+	} else {
+		f = float64(raw)
+	}
+	return f*s.Factor + s.Offset
+}
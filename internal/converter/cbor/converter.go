@@ -0,0 +1,107 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cbor
+
+import (
+	"fmt"
+	"math"
+
+	fxcbor "github.com/fxamacker/cbor/v2"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+type Converter struct {
+	em fxcbor.EncMode
+}
+
+func NewConverter(_ map[string]any) (message.Converter, error) {
+	// encode with the sorted map keys so that the same data always has the same bytes
+	em, err := fxcbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+	return &Converter{em: em}, nil
+}
+
+func (c *Converter) Encode(_ api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	switch d.(type) {
+	case map[string]any, []map[string]any, []any:
+		return c.em.Marshal(d)
+	default:
+		return nil, fmt.Errorf("unsupported type %v, must be a map or array of maps", d)
+	}
+}
+
+func (c *Converter) Decode(_ api.StreamContext, b []byte) (m any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	var v any
+	if err := fxcbor.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("fail to decode cbor: %v", err)
+	}
+	switch vt := normalize(v).(type) {
+	case map[string]any:
+		return vt, nil
+	case []any:
+		result := make([]map[string]any, 0, len(vt))
+		for _, item := range vt {
+			im, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("only map[string]any and []map[string]any is supported, but got array item %v", item)
+			}
+			result = append(result, im)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("only map[string]any and []map[string]any is supported, but got %v", vt)
+	}
+}
+
+// normalize converts the decoded cbor values to the types of the internal data. The map keys, which can be any type
+// like the integer keys in the constrained device payloads, are converted to strings.
+func normalize(v any) any {
+	switch vt := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(vt))
+		for k, mv := range vt {
+			m[cast.ToStringAlways(k)] = normalize(mv)
+		}
+		return m
+	case []any:
+		for i, item := range vt {
+			vt[i] = normalize(item)
+		}
+		return vt
+	case uint64:
+		if vt <= math.MaxInt64 {
+			return int64(vt)
+		}
+		return vt
+	default:
+		return v
+	}
+}
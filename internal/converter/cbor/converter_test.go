@@ -0,0 +1,90 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cbor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	c, err := NewConverter(nil)
+	require.NoError(t, err)
+	tests := []struct {
+		name   string
+		input  any
+		b      []byte
+		result any
+	}{
+		{
+			name:   "map",
+			input:  map[string]any{"b": "x", "a": 1},
+			b:      []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'b', 0x61, 'x'},
+			result: map[string]any{"a": int64(1), "b": "x"},
+		},
+		{
+			name:   "array",
+			input:  []map[string]any{{"a": -1}, {"a": 1.5}},
+			b:      []byte{0x82, 0xa1, 0x61, 'a', 0x20, 0xa1, 0x61, 'a', 0xf9, 0x3e, 0x00},
+			result: []map[string]any{{"a": int64(-1)}, {"a": 1.5}},
+		},
+		{
+			name:   "nested",
+			input:  map[string]any{"a": []any{true, nil, []byte{1}}, "b": map[string]any{"c": "d"}},
+			result: map[string]any{"a": []any{true, nil, []byte{1}}, "b": map[string]any{"c": "d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := c.Encode(ctx, tt.input)
+			require.NoError(t, err)
+			if tt.b != nil {
+				assert.Equal(t, tt.b, b)
+			}
+			r, err := c.Decode(ctx, b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestDecodeIntKeys(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	c, err := NewConverter(nil)
+	require.NoError(t, err)
+	// {1: "x", -2: {3: 4}}
+	r, err := c.Decode(ctx, []byte{0xa2, 0x01, 0x61, 'x', 0x21, 0xa1, 0x03, 0x04})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"1": "x", "-2": map[string]any{"3": int64(4)}}, r)
+}
+
+func TestError(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	c, err := NewConverter(nil)
+	require.NoError(t, err)
+	_, err = c.Encode(ctx, "a")
+	assert.EqualError(t, err, "unsupported type a, must be a map or array of maps")
+	_, err = c.Decode(ctx, []byte{0x01})
+	assert.EqualError(t, err, "only map[string]any and []map[string]any is supported, but got 1")
+	_, err = c.Decode(ctx, []byte{0x81, 0x01})
+	assert.EqualError(t, err, "only map[string]any and []map[string]any is supported, but got array item 1")
+	_, err = c.Decode(ctx, []byte{0xa1})
+	assert.ErrorContains(t, err, "fail to decode cbor")
+}
@@ -21,8 +21,14 @@ import (
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
 	"github.com/lf-edge/ekuiper/v2/internal/converter/binary"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/binstruct"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/bson"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/cbor"
 	"github.com/lf-edge/ekuiper/v2/internal/converter/delimited"
 	"github.com/lf-edge/ekuiper/v2/internal/converter/json"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/lineprotocol"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/msgpack"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/sparkplug"
 	"github.com/lf-edge/ekuiper/v2/internal/converter/urlencoded"
 	"github.com/lf-edge/ekuiper/v2/internal/converter/xml"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
@@ -36,17 +42,35 @@ func init() {
 		return json.NewFastJsonConverter(schema, props), nil
 	})
 	modules.RegisterConverter(message.FormatXML, func(ctx api.StreamContext, schemaId string, logicalSchema map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
-		return xml.NewXMLConverter(), nil
+		return xml.NewConverter(logicalSchema, props)
 	})
 	modules.RegisterConverter(message.FormatBinary, func(_ api.StreamContext, _ string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
 		return binary.GetConverter()
 	})
+	modules.RegisterConverter(message.FormatBinStruct, func(_ api.StreamContext, _ string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		return binstruct.NewConverter(props)
+	})
 	modules.RegisterConverter(message.FormatDelimited, func(_ api.StreamContext, _ string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
 		return delimited.NewConverter(props)
 	})
 	modules.RegisterConverter(message.FormatUrlEncoded, func(_ api.StreamContext, _ string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
 		return urlencoded.NewConverter(props)
 	})
+	modules.RegisterConverter(message.FormatCbor, func(_ api.StreamContext, _ string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		return cbor.NewConverter(props)
+	})
+	modules.RegisterConverter(message.FormatBson, func(_ api.StreamContext, _ string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		return bson.NewConverter(props)
+	})
+	modules.RegisterConverter(message.FormatMsgpack, func(_ api.StreamContext, _ string, schema map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		return msgpack.NewConverter(schema, props), nil
+	})
+	modules.RegisterConverter(message.FormatLineProtocol, func(_ api.StreamContext, _ string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		return lineprotocol.NewConverter(props)
+	})
+	modules.RegisterConverter(message.FormatSparkplug, func(_ api.StreamContext, _ string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		return sparkplug.NewConverter(props)
+	})
 }
 
 func GetOrCreateConverter(ctx api.StreamContext, format string, schemaId string, schema map[string]*ast.JsonStreamField, props map[string]any) (c message.Converter, err error) {
@@ -27,12 +27,24 @@ import (
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/message"
+	"github.com/lf-edge/ekuiper/v2/pkg/pool"
 )
 
 type Converter struct {
 	Delimiter string   `json:"delimiter"`
 	Cols      []string `json:"fields"`
 	HasHeader bool     `json:"hasHeader"`
+	// Quote is the character to enclose the fields which contain the delimiter, quote or line breaks. Empty means no quoting.
+	Quote string `json:"quote"`
+	// Escape is the character to escape the next character. If not set, the quote is escaped by doubling it.
+	Escape string `json:"escape"`
+	// SkipRows is the number of leading rows to skip when decoding, before the header if any
+	SkipRows int `json:"skipRows"`
+	// ColumnTypes maps the column name to the type it is coerced to when decoding
+	ColumnTypes map[string]string `json:"columnTypes"`
+
+	quote  byte
+	escape byte
 }
 
 func NewConverter(props map[string]any) (message.Converter, error) {
@@ -44,6 +56,34 @@ func NewConverter(props map[string]any) (message.Converter, error) {
 	if c.Delimiter == "" {
 		c.Delimiter = ","
 	}
+	if len(c.Quote) > 1 {
+		return nil, fmt.Errorf("quote must be a single character, got %s", c.Quote)
+	}
+	if len(c.Escape) > 1 {
+		return nil, fmt.Errorf("escape must be a single character, got %s", c.Escape)
+	}
+	if c.Quote != "" {
+		c.quote = c.Quote[0]
+		if strings.Contains(c.Delimiter, c.Quote) {
+			return nil, fmt.Errorf("delimiter %s must not contain the quote %s", c.Delimiter, c.Quote)
+		}
+	}
+	if c.Escape != "" {
+		c.escape = c.Escape[0]
+		if strings.Contains(c.Delimiter, c.Escape) {
+			return nil, fmt.Errorf("delimiter %s must not contain the escape %s", c.Delimiter, c.Escape)
+		}
+	}
+	if c.SkipRows < 0 {
+		return nil, fmt.Errorf("skipRows must not be negative, got %d", c.SkipRows)
+	}
+	for k, t := range c.ColumnTypes {
+		switch t {
+		case "bigint", "float", "boolean", "string", "datetime":
+		default:
+			return nil, fmt.Errorf("unsupported type %s of column %s, must be one of bigint, float, boolean, string and datetime", t, k)
+		}
+	}
 	return c, nil
 }
 
@@ -56,7 +96,7 @@ func (c *Converter) Encode(ctx api.StreamContext, d any) (b []byte, err error) {
 	}()
 	switch m := d.(type) {
 	case map[string]any:
-		sb := &bytes.Buffer{}
+		sb := pool.GetBuffer()
 		if len(c.Cols) == 0 {
 			keys := make([]string, 0, len(m))
 			for k := range m {
@@ -77,11 +117,11 @@ func (c *Converter) Encode(ctx api.StreamContext, d any) (b []byte, err error) {
 				sb.WriteString(c.Delimiter)
 			}
 			p, _ := cast.ToString(m[v], cast.CONVERT_ALL)
-			sb.WriteString(p)
+			c.writeField(sb, p)
 		}
-		return sb.Bytes(), nil
+		return pool.CopyAndPutBuffer(sb), nil
 	case []map[string]any:
-		sb := &bytes.Buffer{}
+		sb := pool.GetBuffer()
 		var cols []string
 		for i, mm := range m {
 			if i > 0 {
@@ -105,30 +145,231 @@ func (c *Converter) Encode(ctx api.StreamContext, d any) (b []byte, err error) {
 					sb.WriteString(c.Delimiter)
 				}
 				p, _ := cast.ToString(mm[v], cast.CONVERT_ALL)
-				sb.WriteString(p)
+				c.writeField(sb, p)
 			}
 		}
-		return sb.Bytes(), nil
+		return pool.CopyAndPutBuffer(sb), nil
 	default:
 		return nil, fmt.Errorf("unsupported type %v, must be a map", d)
 	}
 }
 
+// writeField writes the field and quotes it if it contains any special characters
+func (c *Converter) writeField(sb *bytes.Buffer, f string) {
+	if c.quote == 0 || !c.needQuote(f) {
+		sb.WriteString(f)
+		return
+	}
+	sb.WriteByte(c.quote)
+	for i := 0; i < len(f); i++ {
+		if f[i] == c.quote || (c.escape != 0 && f[i] == c.escape) {
+			if c.escape != 0 {
+				sb.WriteByte(c.escape)
+			} else {
+				sb.WriteByte(c.quote)
+			}
+		}
+		sb.WriteByte(f[i])
+	}
+	sb.WriteByte(c.quote)
+}
+
+func (c *Converter) needQuote(f string) bool {
+	if f == "" {
+		return false
+	}
+	if strings.Contains(f, c.Delimiter) || strings.ContainsAny(f, "\r\n") || strings.IndexByte(f, c.quote) >= 0 {
+		return true
+	}
+	return c.escape != 0 && strings.IndexByte(f, c.escape) >= 0
+}
+
 // Decode If the cols is not set, the default key name is col1, col2, col3...
-// The return value is always a map
+// If the payload has multiple rows, the return value is a list of maps. Otherwise, it is a map
 func (c *Converter) Decode(ctx api.StreamContext, b []byte) (ma any, err error) {
-	tokens := strings.Split(string(b), c.Delimiter)
-	m := make(map[string]interface{})
-	if len(c.Cols) == 0 {
-		for i, v := range tokens {
-			m["col"+strconv.Itoa(i)] = v
-		}
-	} else {
-		for i, v := range tokens {
-			if i < len(c.Cols) {
-				m[c.Cols[i]] = v
+	records, err := c.parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+	if c.SkipRows > 0 {
+		if c.SkipRows >= len(records) {
+			records = nil
+		} else {
+			records = records[c.SkipRows:]
+		}
+	}
+	cols := c.Cols
+	if c.HasHeader {
+		if len(records) == 0 {
+			return nil, fmt.Errorf("header not found")
+		}
+		if len(cols) == 0 {
+			cols = records[0]
+		}
+		records = records[1:]
+	}
+	switch len(records) {
+	case 0:
+		return make(map[string]any), nil
+	case 1:
+		return c.toMap(cols, records[0])
+	default:
+		result := make([]map[string]any, 0, len(records))
+		for _, r := range records {
+			m, err := c.toMap(cols, r)
+			if err != nil {
+				return nil, err
 			}
+			result = append(result, m)
+		}
+		return result, nil
+	}
+}
+
+func (c *Converter) toMap(cols []string, tokens []string) (map[string]any, error) {
+	m := make(map[string]any, len(tokens))
+	for i, v := range tokens {
+		var k string
+		if len(cols) == 0 {
+			k = "col" + strconv.Itoa(i)
+		} else if i < len(cols) {
+			k = cols[i]
+		} else {
+			break
+		}
+		if t, ok := c.ColumnTypes[k]; ok {
+			r, err := coerce(t, v)
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert column %s: %v", k, err)
+			}
+			m[k] = r
+		} else {
+			m[k] = v
 		}
 	}
 	return m, nil
 }
+
+// coerce converts the string value to the given type. Empty value of non string type is converted to nil
+func coerce(t string, v string) (any, error) {
+	if t == "string" {
+		return v, nil
+	}
+	if v == "" {
+		return nil, nil
+	}
+	switch t {
+	case "bigint":
+		return cast.ToInt64(v, cast.CONVERT_ALL)
+	case "float":
+		return cast.ToFloat64(v, cast.CONVERT_ALL)
+	case "boolean":
+		return cast.ToBool(v, cast.CONVERT_ALL)
+	case "datetime":
+		return cast.InterfaceToTime(v, "")
+	default:
+		return v, nil
+	}
+}
+
+// parse splits the payload into rows by line breaks and each row into fields by the delimiter.
+// The quoted fields may contain the delimiter and line breaks. Empty lines are ignored.
+func (c *Converter) parse(s string) ([][]string, error) {
+	var (
+		records [][]string
+		fields  []string
+		sb      strings.Builder
+		row     = 1
+	)
+	endRecord := func() {
+		if len(fields) == 1 && fields[0] == "" {
+			fields = nil
+			return
+		}
+		records = append(records, fields)
+		fields = nil
+	}
+	i := 0
+	for {
+		sb.Reset()
+		if c.quote != 0 && i < len(s) && s[i] == c.quote {
+			// quoted field
+			i++
+			closed := false
+			for i < len(s) {
+				ch := s[i]
+				if c.escape != 0 && ch == c.escape && i+1 < len(s) {
+					sb.WriteByte(s[i+1])
+					i += 2
+					continue
+				}
+				if ch == c.quote {
+					if i+1 < len(s) && s[i+1] == c.quote {
+						sb.WriteByte(c.quote)
+						i += 2
+						continue
+					}
+					i++
+					closed = true
+					break
+				}
+				if ch == '\n' {
+					row++
+				}
+				sb.WriteByte(ch)
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated quoted field in row %d", row)
+			}
+			fields = append(fields, sb.String())
+			switch {
+			case i >= len(s):
+				endRecord()
+				return records, nil
+			case strings.HasPrefix(s[i:], c.Delimiter):
+				i += len(c.Delimiter)
+			case s[i] == '\n':
+				i++
+				row++
+				endRecord()
+			case strings.HasPrefix(s[i:], "\r\n"):
+				i += 2
+				row++
+				endRecord()
+			default:
+				return nil, fmt.Errorf("unexpected character %q after quoted field in row %d", s[i], row)
+			}
+			continue
+		}
+		// unquoted field
+		for i < len(s) {
+			ch := s[i]
+			if c.escape != 0 && ch == c.escape && i+1 < len(s) {
+				sb.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+			if ch == '\n' || strings.HasPrefix(s[i:], c.Delimiter) {
+				break
+			}
+			sb.WriteByte(ch)
+			i++
+		}
+		f := sb.String()
+		if i >= len(s) {
+			fields = append(fields, strings.TrimSuffix(f, "\r"))
+			endRecord()
+			return records, nil
+		}
+		if s[i] == '\n' {
+			fields = append(fields, strings.TrimSuffix(f, "\r"))
+			i++
+			row++
+			endRecord()
+		} else {
+			fields = append(fields, f)
+			i += len(c.Delimiter)
+		}
+	}
+}
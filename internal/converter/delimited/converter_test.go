@@ -212,3 +212,120 @@ func TestError(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, errorx.CovnerterErr, errWithCode.Code())
 }
+
+func TestDecodeAdvanced(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]any
+		r     string
+		m     any
+		e     string
+	}{
+		{
+			name:  "quoted",
+			props: map[string]any{"quote": `"`},
+			r:     `1,"Doe, John","say ""hi"""`,
+			m:     map[string]any{"col0": "1", "col1": "Doe, John", "col2": `say "hi"`},
+		},
+		{
+			name:  "quoted line break",
+			props: map[string]any{"quote": `"`, "fields": []string{"id", "addr"}},
+			r:     "1,\"line1\nline2\"\r\n",
+			m:     map[string]any{"id": "1", "addr": "line1\nline2"},
+		},
+		{
+			name:  "escape",
+			props: map[string]any{"quote": `'`, `escape`: `\`},
+			r:     `a\,b,'it\'s'`,
+			m:     map[string]any{"col0": "a,b", "col1": "it's"},
+		},
+		{
+			name:  "multi-character delimiter",
+			props: map[string]any{"delimiter": "||", "quote": `"`},
+			r:     `a||"b||c"||d|e`,
+			m:     map[string]any{"col0": "a", "col1": "b||c", "col2": "d|e"},
+		},
+		{
+			name:  "header and skip rows",
+			props: map[string]any{"hasHeader": true, "skipRows": 1, "columnTypes": map[string]any{"id": "bigint", "temp": "float", "ok": "boolean"}},
+			r:     "# exported data\nid,temp,ok\n1,20.5,true\n\n2,,false\n",
+			m: []map[string]any{
+				{"id": int64(1), "temp": 20.5, "ok": true},
+				{"id": int64(2), "temp": nil, "ok": false},
+			},
+		},
+		{
+			name:  "coerce error",
+			props: map[string]any{"fields": []string{"id"}, "columnTypes": map[string]any{"id": "bigint"}},
+			r:     "abc",
+			e:     "cannot convert column id: cannot convert string(abc) to int64",
+		},
+		{
+			name:  "unterminated",
+			props: map[string]any{"quote": `"`},
+			r:     "a\n\"b,c",
+			e:     "unterminated quoted field in row 2",
+		},
+		{
+			name:  "bad quote",
+			props: map[string]any{"quote": `"`},
+			r:     `"a"b,c`,
+			e:     "unexpected character 'b' after quoted field in row 1",
+		},
+		{
+			name:  "no header",
+			props: map[string]any{"hasHeader": true, "skipRows": 2},
+			r:     "a\nb",
+			e:     "header not found",
+		},
+	}
+	ctx := mockContext.NewMockContext("test", "op1")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewConverter(tt.props)
+			require.NoError(t, err)
+			r, err := c.Decode(ctx, []byte(tt.r))
+			if tt.e != "" {
+				require.EqualError(t, err, tt.e)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.m, r)
+			}
+		})
+	}
+}
+
+func TestEncodeQuoted(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	c, err := NewConverter(map[string]any{"quote": `"`, "fields": []string{"a", "b", "c"}})
+	require.NoError(t, err)
+	r, err := c.Encode(ctx, map[string]any{"a": "Doe, John", "b": `say "hi"`, "c": 1})
+	require.NoError(t, err)
+	require.Equal(t, `"Doe, John","say ""hi""",1`, string(r))
+	m, err := c.Decode(ctx, r)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": "Doe, John", "b": `say "hi"`, "c": "1"}, m)
+
+	c, err = NewConverter(map[string]any{"quote": `'`, "escape": `\`, "fields": []string{"a"}})
+	require.NoError(t, err)
+	r, err = c.Encode(ctx, map[string]any{"a": `it's a\b`})
+	require.NoError(t, err)
+	require.Equal(t, `'it\'s a\\b'`, string(r))
+}
+
+func TestNewConverterError(t *testing.T) {
+	tests := []struct {
+		props map[string]any
+		e     string
+	}{
+		{props: map[string]any{"quote": `""`}, e: `quote must be a single character, got ""`},
+		{props: map[string]any{"escape": `\\`}, e: `escape must be a single character, got \\`},
+		{props: map[string]any{"delimiter": `"`, "quote": `"`}, e: `delimiter " must not contain the quote "`},
+		{props: map[string]any{"skipRows": -1}, e: "skipRows must not be negative, got -1"},
+		{props: map[string]any{"columnTypes": map[string]any{"a": "int"}}, e: "unsupported type int of column a, must be one of bigint, float, boolean, string and datetime"},
+	}
+	for _, tt := range tests {
+		_, err := NewConverter(tt.props)
+		require.EqualError(t, err, tt.e)
+	}
+}
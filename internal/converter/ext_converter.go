@@ -17,11 +17,19 @@
 package converter
 
 import (
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
+	"github.com/lf-edge/ekuiper/v2/internal/converter/avro"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/can"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/flatbuffers"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/json"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/jsonschema"
 	"github.com/lf-edge/ekuiper/v2/internal/converter/protobuf"
+	"github.com/lf-edge/ekuiper/v2/internal/converter/wasm"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/schema"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
@@ -30,7 +38,28 @@ import (
 )
 
 func init() {
-	modules.RegisterConverter(message.FormatProtobuf, func(_ api.StreamContext, schemaId string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+	// Override the json converter in converter.go to validate the payload by the json schema if specified
+	modules.RegisterConverter(message.FormatJson, func(_ api.StreamContext, schemaId string, fields map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		if schemaId == "" {
+			return json.NewFastJsonConverter(fields, props), nil
+		}
+		ffs, err := schema.GetSchemaFile(def.JSONSCHEMA, schemaId)
+		if err != nil {
+			return nil, err
+		}
+		return jsonschema.NewConverter(ffs.SchemaFile, fields, props)
+	})
+	modules.RegisterConverter(message.FormatProtobuf, func(_ api.StreamContext, schemaId string, logicalSchema map[string]*ast.JsonStreamField, props map[string]any) (c message.Converter, err error) {
+		// Only decode the fields used by the rule
+		defer func() {
+			if pc, ok := c.(*protobuf.Converter); ok && logicalSchema != nil {
+				pc.ResetSchema(logicalSchema)
+			}
+		}()
+		// the schemaId is the full name of the message type in the remote descriptor set
+		if u, ok := props["descriptorSetUrl"].(string); ok && u != "" {
+			return protobuf.NewRemoteConverter(u, schemaId)
+		}
 		schemaFile := ""
 		schemaName := ""
 		if schemaId != "" {
@@ -46,4 +75,49 @@ func init() {
 		}
 		return protobuf.NewConverter(ffs.SchemaFile, ffs.SoFile, schemaName)
 	})
+	modules.RegisterConverter(message.FormatAvro, func(_ api.StreamContext, schemaId string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		content := ""
+		if schemaId != "" {
+			ffs, err := schema.GetSchemaFile(def.AVRO, schemaId)
+			if err != nil {
+				return nil, err
+			}
+			b, err := os.ReadFile(ffs.SchemaFile)
+			if err != nil {
+				return nil, fmt.Errorf("read avro schema file %s failed: %v", ffs.SchemaFile, err)
+			}
+			content = string(b)
+		}
+		return avro.NewConverter(content, props)
+	})
+	modules.RegisterConverter(message.FormatFlatbuffers, func(_ api.StreamContext, schemaId string, fields map[string]*ast.JsonStreamField, _ map[string]any) (message.Converter, error) {
+		// the schemaId is like file.Table, the root_type of the file is used if the table is not specified
+		schemaFile, tableName, _ := strings.Cut(schemaId, ".")
+		ffs, err := schema.GetSchemaFile(def.FLATBUFFERS, schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		c, err := flatbuffers.NewConverter(ffs.SchemaFile, tableName)
+		if err != nil {
+			return nil, err
+		}
+		if fields != nil {
+			c.(message.SchemaResetAbleConverter).ResetSchema(fields)
+		}
+		return c, nil
+	})
+	modules.RegisterConverter(message.FormatCan, func(_ api.StreamContext, schemaId string, _ map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+		ffs, err := schema.GetSchemaFile(def.DBC, schemaId)
+		if err != nil {
+			return nil, err
+		}
+		return can.NewConverter(ffs.SchemaFile, props)
+	})
+	modules.RegisterConverter(message.FormatWasm, func(_ api.StreamContext, schemaId string, _ map[string]*ast.JsonStreamField, _ map[string]any) (message.Converter, error) {
+		ffs, err := schema.GetSchemaFile(def.WASM, schemaId)
+		if err != nil {
+			return nil, err
+		}
+		return wasm.NewConverter(ffs.SchemaFile)
+	})
 }
@@ -0,0 +1,435 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flatbuffers
+
+import (
+	"fmt"
+	"math"
+
+	fb "github.com/google/flatbuffers/go"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// Unmarshal decodes the flatbuffers data of the root table. If fields is not nil, only the fields in it are decoded.
+func (s *Schema) Unmarshal(b []byte, t *Table, fields map[string]struct{}) (m map[string]any, err error) {
+	// the accessors panic for malformed data
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid flatbuffers data: %v", r)
+		}
+	}()
+	if len(b) < fb.SizeUOffsetT {
+		return nil, fmt.Errorf("invalid flatbuffers data: too short")
+	}
+	if s.identifier != "" && (len(b) < fb.SizeUOffsetT+4 || string(b[fb.SizeUOffsetT:fb.SizeUOffsetT+4]) != s.identifier) {
+		return nil, fmt.Errorf("invalid flatbuffers data: file identifier mismatch, expect %s", s.identifier)
+	}
+	return decodeTable(b, fb.GetUOffsetT(b), t, fields), nil
+}
+
+func decodeTable(b []byte, pos fb.UOffsetT, t *Table, fields map[string]struct{}) map[string]any {
+	tab := &fb.Table{Bytes: b, Pos: pos}
+	m := make(map[string]any, len(t.fields))
+	for _, f := range t.fields {
+		if f.deprecated {
+			continue
+		}
+		if fields != nil {
+			if _, ok := fields[f.name]; !ok {
+				continue
+			}
+		}
+		o := fb.UOffsetT(tab.Offset(fb.VOffsetT(4 + 2*f.slot)))
+		if o == 0 {
+			// absent scalars are the default values, absent references are null
+			if f.typ.kind.isScalar() || f.typ.kind == kindEnum {
+				m[f.name] = defaultValue(f)
+			}
+			continue
+		}
+		switch f.typ.kind {
+		case kindString:
+			m[f.name] = tab.String(tab.Pos + o)
+		case kindTable:
+			m[f.name] = decodeTable(b, tab.Indirect(tab.Pos+o), f.typ.table, nil)
+		case kindStruct:
+			m[f.name] = decodeStruct(b, tab.Pos+o, f.typ.table)
+		case kindVector:
+			m[f.name] = decodeVector(tab, o, f.typ.elem)
+		default:
+			m[f.name] = decodeScalar(b, tab.Pos+o, f.typ)
+		}
+	}
+	return m
+}
+
+func decodeStruct(b []byte, pos fb.UOffsetT, t *Table) map[string]any {
+	m := make(map[string]any, len(t.fields))
+	for _, f := range t.fields {
+		p := pos + fb.UOffsetT(f.offset)
+		if f.typ.kind == kindStruct {
+			m[f.name] = decodeStruct(b, p, f.typ.table)
+		} else {
+			m[f.name] = decodeScalar(b, p, f.typ)
+		}
+	}
+	return m
+}
+
+func decodeVector(tab *fb.Table, o fb.UOffsetT, elem *fbType) any {
+	n := tab.VectorLen(o)
+	start := tab.Vector(o)
+	if elem.kind == kindUint8 || elem.kind == kindInt8 {
+		// binary data
+		return tab.ByteVector(tab.Pos + o)
+	}
+	result := make([]any, n)
+	for i := 0; i < n; i++ {
+		switch elem.kind {
+		case kindString:
+			result[i] = tab.String(start + fb.UOffsetT(i*fb.SizeUOffsetT))
+		case kindTable:
+			result[i] = decodeTable(tab.Bytes, tab.Indirect(start+fb.UOffsetT(i*fb.SizeUOffsetT)), elem.table, nil)
+		case kindStruct:
+			result[i] = decodeStruct(tab.Bytes, start+fb.UOffsetT(i*elem.table.size), elem.table)
+		default:
+			result[i] = decodeScalar(tab.Bytes, start+fb.UOffsetT(i*elem.size()), elem)
+		}
+	}
+	return result
+}
+
+// decodeScalar reads the scalar at the position. Integers are int64, floats are float64 and enums are their names.
+func decodeScalar(b []byte, pos fb.UOffsetT, t *fbType) any {
+	var v any
+	buf := b[pos:]
+	switch t.scalar() {
+	case kindBool:
+		return fb.GetBool(buf)
+	case kindInt8:
+		v = int64(fb.GetInt8(buf))
+	case kindUint8:
+		v = int64(fb.GetUint8(buf))
+	case kindInt16:
+		v = int64(fb.GetInt16(buf))
+	case kindUint16:
+		v = int64(fb.GetUint16(buf))
+	case kindInt32:
+		v = int64(fb.GetInt32(buf))
+	case kindUint32:
+		v = int64(fb.GetUint32(buf))
+	case kindInt64:
+		v = fb.GetInt64(buf)
+	case kindUint64:
+		u := fb.GetUint64(buf)
+		if u > math.MaxInt64 {
+			v = u
+		} else {
+			v = int64(u)
+		}
+	case kindFloat32:
+		return float64(fb.GetFloat32(buf))
+	case kindFloat64:
+		return fb.GetFloat64(buf)
+	}
+	if t.kind == kindEnum {
+		return enumName(t.enum, v)
+	}
+	return v
+}
+
+func enumName(e *Enum, v any) any {
+	if n, ok := v.(int64); ok {
+		if name, ok := e.names[n]; ok {
+			return name
+		}
+	}
+	return v
+}
+
+func defaultValue(f *Field) any {
+	k := f.typ.scalar()
+	if f.typ.kind == kindEnum {
+		if _, ok := f.typ.enum.values[f.def]; ok {
+			return f.def
+		}
+	}
+	var v any
+	switch k {
+	case kindBool:
+		return f.def == "true" || f.def == "1"
+	case kindFloat32, kindFloat64:
+		d, _ := cast.ToFloat64(f.def, cast.CONVERT_ALL)
+		return d
+	default:
+		d, _ := cast.ToInt64(f.def, cast.CONVERT_ALL)
+		v = d
+	}
+	if f.typ.kind == kindEnum {
+		return enumName(f.typ.enum, v)
+	}
+	return v
+}
+
+// Marshal encodes the map into flatbuffers data of the root table
+func (s *Schema) Marshal(t *Table, m map[string]any) ([]byte, error) {
+	b := fb.NewBuilder(256)
+	root, err := encodeTable(b, t, m)
+	if err != nil {
+		return nil, err
+	}
+	if s.identifier != "" {
+		b.FinishWithFileIdentifier(root, []byte(s.identifier))
+	} else {
+		b.Finish(root)
+	}
+	return b.FinishedBytes(), nil
+}
+
+func encodeTable(b *fb.Builder, t *Table, m map[string]any) (fb.UOffsetT, error) {
+	// the referred objects must be created before the table
+	offsets := make([]fb.UOffsetT, len(t.fields))
+	for i, f := range t.fields {
+		v, ok := m[f.name]
+		if !ok || v == nil || f.deprecated {
+			continue
+		}
+		var err error
+		switch f.typ.kind {
+		case kindString:
+			var s string
+			s, err = cast.ToString(v, cast.CONVERT_SAMEKIND)
+			offsets[i] = b.CreateString(s)
+		case kindTable:
+			sub, ok := v.(map[string]any)
+			if !ok {
+				err = fmt.Errorf("expect map but got %v", v)
+			} else {
+				offsets[i], err = encodeTable(b, f.typ.table, sub)
+			}
+		case kindVector:
+			offsets[i], err = encodeVector(b, f.typ.elem, v)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid value for field %s: %v", f.name, err)
+		}
+	}
+	b.StartObject(t.slots)
+	for i, f := range t.fields {
+		v, ok := m[f.name]
+		if !ok || v == nil || f.deprecated {
+			continue
+		}
+		var err error
+		switch f.typ.kind {
+		case kindString, kindTable, kindVector:
+			b.PrependUOffsetTSlot(f.slot, offsets[i], 0)
+		case kindStruct:
+			err = encodeStruct(b, f.typ.table, v)
+			if err == nil {
+				b.PrependStructSlot(f.slot, b.Offset(), 0)
+			}
+		default:
+			err = prependSlot(b, f, v)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid value for field %s: %v", f.name, err)
+		}
+	}
+	return b.EndObject(), nil
+}
+
+// encodeStruct writes the struct inline from the last field to the first with the paddings
+func encodeStruct(b *fb.Builder, t *Table, v any) error {
+	if v == nil {
+		// all the struct fields are required, write zeros for the absent struct
+		v = map[string]any{}
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("expect map but got %v", v)
+	}
+	b.Prep(t.align, t.size)
+	end := t.size
+	for i := len(t.fields) - 1; i >= 0; i-- {
+		f := t.fields[i]
+		if pad := end - f.offset - f.typ.size(); pad > 0 {
+			b.Pad(pad)
+		}
+		var err error
+		if f.typ.kind == kindStruct {
+			err = encodeStruct(b, f.typ.table, m[f.name])
+		} else {
+			err = prependScalar(b, f.typ, m[f.name])
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for field %s: %v", f.name, err)
+		}
+		end = f.offset
+	}
+	return nil
+}
+
+func encodeVector(b *fb.Builder, elem *fbType, v any) (fb.UOffsetT, error) {
+	if bs, ok := v.([]byte); ok && (elem.kind == kindUint8 || elem.kind == kindInt8) {
+		return b.CreateByteVector(bs), nil
+	}
+	arr, ok := v.([]any)
+	if !ok {
+		return 0, fmt.Errorf("expect array but got %v", v)
+	}
+	n := len(arr)
+	switch elem.kind {
+	case kindString, kindTable:
+		offsets := make([]fb.UOffsetT, n)
+		for i, item := range arr {
+			if elem.kind == kindString {
+				s, err := cast.ToString(item, cast.CONVERT_SAMEKIND)
+				if err != nil {
+					return 0, err
+				}
+				offsets[i] = b.CreateString(s)
+			} else {
+				sub, ok := item.(map[string]any)
+				if !ok {
+					return 0, fmt.Errorf("expect map but got %v", item)
+				}
+				o, err := encodeTable(b, elem.table, sub)
+				if err != nil {
+					return 0, err
+				}
+				offsets[i] = o
+			}
+		}
+		b.StartVector(fb.SizeUOffsetT, n, fb.SizeUOffsetT)
+		for i := n - 1; i >= 0; i-- {
+			b.PrependUOffsetT(offsets[i])
+		}
+	case kindStruct:
+		b.StartVector(elem.table.size, n, elem.table.align)
+		for i := n - 1; i >= 0; i-- {
+			if err := encodeStruct(b, elem.table, arr[i]); err != nil {
+				return 0, err
+			}
+		}
+	default:
+		b.StartVector(elem.size(), n, elem.size())
+		for i := n - 1; i >= 0; i-- {
+			if err := prependScalar(b, elem, arr[i]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return b.EndVector(n), nil
+}
+
+// scalarValue converts the value to the stored form, which is bool, int64, uint64 or float64
+func scalarValue(t *fbType, v any) (any, error) {
+	if t.kind == kindEnum {
+		if s, ok := v.(string); ok {
+			n, ok := t.enum.values[s]
+			if !ok {
+				return nil, fmt.Errorf("unknown value %s of enum %s", s, t.enum.name)
+			}
+			return n, nil
+		}
+	}
+	switch t.scalar() {
+	case kindBool:
+		return cast.ToBool(v, cast.CONVERT_SAMEKIND)
+	case kindFloat32, kindFloat64:
+		return cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+	case kindUint64:
+		return cast.ToUint64(v, cast.CONVERT_SAMEKIND)
+	default:
+		return cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+	}
+}
+
+func prependScalar(b *fb.Builder, t *fbType, v any) error {
+	if v == nil {
+		v = 0
+		if t.scalar() == kindBool {
+			v = false
+		}
+	}
+	sv, err := scalarValue(t, v)
+	if err != nil {
+		return err
+	}
+	switch t.scalar() {
+	case kindBool:
+		b.PrependBool(sv.(bool))
+	case kindInt8:
+		b.PrependInt8(int8(sv.(int64)))
+	case kindUint8:
+		b.PrependUint8(uint8(sv.(int64)))
+	case kindInt16:
+		b.PrependInt16(int16(sv.(int64)))
+	case kindUint16:
+		b.PrependUint16(uint16(sv.(int64)))
+	case kindInt32:
+		b.PrependInt32(int32(sv.(int64)))
+	case kindUint32:
+		b.PrependUint32(uint32(sv.(int64)))
+	case kindInt64:
+		b.PrependInt64(sv.(int64))
+	case kindUint64:
+		b.PrependUint64(sv.(uint64))
+	case kindFloat32:
+		b.PrependFloat32(float32(sv.(float64)))
+	case kindFloat64:
+		b.PrependFloat64(sv.(float64))
+	}
+	return nil
+}
+
+// prependSlot writes the scalar field of table. The value equal to the default is omitted.
+func prependSlot(b *fb.Builder, f *Field, v any) error {
+	sv, err := scalarValue(f.typ, v)
+	if err != nil {
+		return err
+	}
+	d, err := scalarValue(f.typ, defaultValue(f))
+	if err != nil {
+		return err
+	}
+	switch f.typ.scalar() {
+	case kindBool:
+		b.PrependBoolSlot(f.slot, sv.(bool), d.(bool))
+	case kindInt8:
+		b.PrependInt8Slot(f.slot, int8(sv.(int64)), int8(d.(int64)))
+	case kindUint8:
+		b.PrependUint8Slot(f.slot, uint8(sv.(int64)), uint8(d.(int64)))
+	case kindInt16:
+		b.PrependInt16Slot(f.slot, int16(sv.(int64)), int16(d.(int64)))
+	case kindUint16:
+		b.PrependUint16Slot(f.slot, uint16(sv.(int64)), uint16(d.(int64)))
+	case kindInt32:
+		b.PrependInt32Slot(f.slot, int32(sv.(int64)), int32(d.(int64)))
+	case kindUint32:
+		b.PrependUint32Slot(f.slot, uint32(sv.(int64)), uint32(d.(int64)))
+	case kindInt64:
+		b.PrependInt64Slot(f.slot, sv.(int64), d.(int64))
+	case kindUint64:
+		b.PrependUint64Slot(f.slot, sv.(uint64), d.(uint64))
+	case kindFloat32:
+		b.PrependFloat32Slot(f.slot, float32(sv.(float64)), float32(d.(float64)))
+	case kindFloat64:
+		b.PrependFloat64Slot(f.slot, sv.(float64), d.(float64))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flatbuffers
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+// Converter converts the flatbuffers data of a table. The fields are read from the buffer directly by the vtable,
+// so only the fields in the stream schema are decoded.
+type Converter struct {
+	sync.RWMutex
+	schema *Schema
+	table  *Table
+	fields map[string]struct{}
+}
+
+// NewConverter creates the converter of the table in the fbs file. The root_type is used if the table name is empty.
+func NewConverter(schemaFile string, tableName string) (message.Converter, error) {
+	s, err := ParseFile(schemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema file %s failed: %s", schemaFile, err)
+	}
+	return newConverter(s, tableName)
+}
+
+func newConverter(s *Schema, tableName string) (*Converter, error) {
+	t, err := s.Root(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return &Converter{schema: s, table: t}, nil
+}
+
+func (c *Converter) ResetSchema(schema map[string]*ast.JsonStreamField) {
+	c.Lock()
+	defer c.Unlock()
+	if schema == nil {
+		c.fields = nil
+		return
+	}
+	c.fields = make(map[string]struct{}, len(schema))
+	for k := range schema {
+		c.fields[k] = struct{}{}
+	}
+}
+
+func (c *Converter) Encode(_ api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	m, ok := d.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %v, must be a map", d)
+	}
+	return c.schema.Marshal(c.table, m)
+}
+
+func (c *Converter) Decode(_ api.StreamContext, b []byte) (m any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	c.RLock()
+	defer c.RUnlock()
+	return c.schema.Unmarshal(b, c.table, c.fields)
+}
+
+// DecodeField reads a single field without decoding the others
+func (c *Converter) DecodeField(_ api.StreamContext, b []byte, f string) (v any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	m, err := c.schema.Unmarshal(b, c.table, map[string]struct{}{f: {}})
+	if err != nil {
+		return nil, err
+	}
+	return m[f], nil
+}
@@ -0,0 +1,316 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flatbuffers
+
+import (
+	"math"
+	"testing"
+
+	fb "github.com/google/flatbuffers/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+const readingSchema = `
+// the reading of the sensors
+namespace iot.sensor;
+
+enum Status : byte { Ok = 0, Warn, Fault = 5 }
+
+struct Vec3 { x: float; y: float; z: float; }
+
+/* the struct with paddings */
+struct Sample {
+  flag: bool;
+  pos: Vec3;
+  ts: long;
+}
+
+table Tag {
+  key: string;
+  value: string;
+}
+
+table Reading {
+  device: string;
+  temperature: double = 20.5;
+  count: int;
+  status: Status = Warn;
+  old: short (deprecated);
+  pos: Vec3;
+  samples: [Sample];
+  tags: [Tag];
+  values: [int];
+  names: [string];
+  raw: [ubyte];
+  meta: Tag;
+  big: ulong;
+}
+
+root_type Reading;
+file_identifier "RDNG";
+`
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema(readingSchema)
+	require.NoError(t, err)
+	assert.Equal(t, "iot.sensor.Reading", s.root)
+	assert.Equal(t, "RDNG", s.identifier)
+	sample := s.tables["iot.sensor.Sample"]
+	require.NotNil(t, sample)
+	assert.Equal(t, 24, sample.size)
+	assert.Equal(t, 8, sample.align)
+	assert.Equal(t, []int{0, 4, 16}, []int{sample.fields[0].offset, sample.fields[1].offset, sample.fields[2].offset})
+	r, err := s.Root("Reading")
+	require.NoError(t, err)
+	assert.Equal(t, 13, r.slots)
+	_, err = s.Root("Vec3")
+	assert.EqualError(t, err, "table Vec3 is not found in the schema")
+
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{
+			name:   "union",
+			schema: `union Any { A, B }`,
+			err:    "union is not supported",
+		},
+		{
+			name:   "unknown type",
+			schema: `table A { b: B; }`,
+			err:    "unknown type B",
+		},
+		{
+			name:   "struct with string",
+			schema: `struct A { b: string; }`,
+			err:    "struct A field b must be a scalar, enum or struct",
+		},
+		{
+			name:   "root struct",
+			schema: `struct A { b: int; } root_type A;`,
+			err:    "root_type A must be a table",
+		},
+		{
+			name:   "partial id",
+			schema: `table A { a: int (id: 1); b: int; }`,
+			err:    "field A.b must have id because other fields have",
+		},
+		{
+			name:   "float enum",
+			schema: `enum A : float { B }`,
+			err:    "enum A must have an integer underlying type",
+		},
+		{
+			name:   "include",
+			schema: `include "a.fbs";`,
+			err:    "include a.fbs is not supported in the schema content",
+		},
+		{
+			name:   "unclosed",
+			schema: `table A { a: int; /* b: int; }`,
+			err:    "unclosed comment",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema(tt.schema)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func newTestConverter(t *testing.T, schema string, table string) *Converter {
+	s, err := ParseSchema(schema)
+	require.NoError(t, err)
+	c, err := newConverter(s, table)
+	require.NoError(t, err)
+	return c
+}
+
+func TestEncodeDecode(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op")
+	c := newTestConverter(t, readingSchema, "")
+	tests := []struct {
+		name   string
+		input  map[string]any
+		output map[string]any
+	}{
+		{
+			name: "all fields",
+			input: map[string]any{
+				"device":      "d1",
+				"temperature": 30.5,
+				"count":       float64(3),
+				"status":      "Fault",
+				"old":         int64(1),
+				"pos":         map[string]any{"x": 1.5, "y": 2.25, "z": -3},
+				"samples": []any{
+					map[string]any{"flag": true, "pos": map[string]any{"x": 1, "y": 2, "z": 3}, "ts": int64(100)},
+					map[string]any{"ts": int64(200)},
+				},
+				"tags":   []any{map[string]any{"key": "k1", "value": "v1"}, map[string]any{"key": "k2"}},
+				"values": []any{int64(1), int64(-2)},
+				"names":  []any{"a", "b"},
+				"raw":    []byte{1, 2},
+				"meta":   map[string]any{"key": "location"},
+				"big":    uint64(math.MaxUint64),
+			},
+			output: map[string]any{
+				"device":      "d1",
+				"temperature": 30.5,
+				"count":       int64(3),
+				"status":      "Fault",
+				"pos":         map[string]any{"x": 1.5, "y": 2.25, "z": float64(-3)},
+				"samples": []any{
+					map[string]any{"flag": true, "pos": map[string]any{"x": float64(1), "y": float64(2), "z": float64(3)}, "ts": int64(100)},
+					map[string]any{"flag": false, "pos": map[string]any{"x": float64(0), "y": float64(0), "z": float64(0)}, "ts": int64(200)},
+				},
+				"tags":   []any{map[string]any{"key": "k1", "value": "v1"}, map[string]any{"key": "k2"}},
+				"values": []any{int64(1), int64(-2)},
+				"names":  []any{"a", "b"},
+				"raw":    []byte{1, 2},
+				"meta":   map[string]any{"key": "location"},
+				"big":    uint64(math.MaxUint64),
+			},
+		},
+		{
+			name:  "defaults",
+			input: map[string]any{"device": "d2", "status": int64(1), "tags": nil},
+			output: map[string]any{
+				"device":      "d2",
+				"temperature": 20.5,
+				"count":       int64(0),
+				"status":      "Warn",
+				"big":         int64(0),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := c.Encode(ctx, tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, "RDNG", string(b[4:8]))
+			m, err := c.Decode(ctx, b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.output, m)
+		})
+	}
+}
+
+func TestDecodeBuilder(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op")
+	// build the data like the generated code of the producers
+	b := fb.NewBuilder(0)
+	name := b.CreateString("d3")
+	b.StartObject(13)
+	b.PrependFloat64Slot(1, 25, 20.5)
+	b.PrependUOffsetTSlot(0, name, 0)
+	b.PrependInt32Slot(2, 7, 0)
+	b.PrependInt8Slot(3, 0, 1)
+	b.FinishWithFileIdentifier(b.EndObject(), []byte("RDNG"))
+	data := b.FinishedBytes()
+
+	c := newTestConverter(t, readingSchema, "iot.sensor.Reading")
+	m, err := c.Decode(ctx, data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"device":      "d3",
+		"temperature": float64(25),
+		"count":       int64(7),
+		"status":      "Ok",
+		"big":         int64(0),
+	}, m)
+
+	v, err := c.DecodeField(ctx, data, "count")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), v)
+	v, err = c.DecodeField(ctx, data, "meta")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	c.ResetSchema(map[string]*ast.JsonStreamField{"device": nil, "status": nil})
+	m, err = c.Decode(ctx, data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"device": "d3", "status": "Ok"}, m)
+	c.ResetSchema(nil)
+	m, err = c.Decode(ctx, data)
+	require.NoError(t, err)
+	assert.Len(t, m, 5)
+}
+
+func TestFieldId(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op")
+	c := newTestConverter(t, `table T { b: int (id: 1); a: string (id: 0); } root_type T;`, "")
+	b := fb.NewBuilder(0)
+	a := b.CreateString("hello")
+	b.StartObject(2)
+	b.PrependUOffsetTSlot(0, a, 0)
+	b.PrependInt32Slot(1, 12, 0)
+	b.Finish(b.EndObject())
+	m, err := c.Decode(ctx, b.FinishedBytes())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": "hello", "b": int64(12)}, m)
+}
+
+func TestConvertError(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op")
+	c := newTestConverter(t, readingSchema, "")
+	tests := []struct {
+		name  string
+		input any
+		err   string
+	}{
+		{
+			name:  "not map",
+			input: []map[string]any{{"device": "d1"}},
+			err:   "unsupported type [map[device:d1]], must be a map",
+		},
+		{
+			name:  "wrong type",
+			input: map[string]any{"count": "abc"},
+			err:   "invalid value for field count",
+		},
+		{
+			name:  "wrong enum",
+			input: map[string]any{"status": "Bad"},
+			err:   "unknown value Bad of enum iot.sensor.Status",
+		},
+		{
+			name:  "wrong nested",
+			input: map[string]any{"tags": []any{"a"}},
+			err:   "invalid value for field tags: expect map but got a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.Encode(ctx, tt.input)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err := c.Decode(ctx, []byte{1, 2})
+	assert.EqualError(t, err, "invalid flatbuffers data: too short")
+	_, err = c.Decode(ctx, []byte{8, 0, 0, 0, 'A', 'B', 'C', 'D'})
+	assert.EqualError(t, err, "invalid flatbuffers data: file identifier mismatch, expect RDNG")
+	_, err = c.Decode(ctx, []byte{0xff, 0xff, 0, 0, 'R', 'D', 'N', 'G'})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid flatbuffers data")
+}
@@ -0,0 +1,643 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flatbuffers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type kind int
+
+const (
+	kindBool kind = iota
+	kindInt8
+	kindUint8
+	kindInt16
+	kindUint16
+	kindInt32
+	kindUint32
+	kindInt64
+	kindUint64
+	kindFloat32
+	kindFloat64
+	kindString
+	kindVector
+	kindTable
+	kindStruct
+	kindEnum
+)
+
+var scalarKinds = map[string]kind{
+	"bool": kindBool, "byte": kindInt8, "int8": kindInt8, "ubyte": kindUint8, "uint8": kindUint8,
+	"short": kindInt16, "int16": kindInt16, "ushort": kindUint16, "uint16": kindUint16,
+	"int": kindInt32, "int32": kindInt32, "uint": kindUint32, "uint32": kindUint32,
+	"long": kindInt64, "int64": kindInt64, "ulong": kindUint64, "uint64": kindUint64,
+	"float": kindFloat32, "float32": kindFloat32, "double": kindFloat64, "float64": kindFloat64,
+}
+
+func (k kind) size() int {
+	switch k {
+	case kindBool, kindInt8, kindUint8:
+		return 1
+	case kindInt16, kindUint16:
+		return 2
+	case kindInt32, kindUint32, kindFloat32:
+		return 4
+	case kindInt64, kindUint64, kindFloat64:
+		return 8
+	default:
+		// the offset size
+		return 4
+	}
+}
+
+func (k kind) isScalar() bool {
+	return k <= kindFloat64
+}
+
+// fbType is the type of a field or vector element
+type fbType struct {
+	kind kind
+	// the element type of vector
+	elem *fbType
+	// the referred table, struct or enum
+	table *Table
+	enum  *Enum
+	// the unresolved type name
+	ref string
+}
+
+// scalar returns the scalar kind of the type, which is the underlying type for enum
+func (t *fbType) scalar() kind {
+	if t.kind == kindEnum {
+		return t.enum.underlying
+	}
+	return t.kind
+}
+
+func (t *fbType) size() int {
+	switch t.kind {
+	case kindStruct:
+		return t.table.size
+	case kindEnum:
+		return t.enum.underlying.size()
+	default:
+		return t.kind.size()
+	}
+}
+
+func (t *fbType) align() int {
+	if t.kind == kindStruct {
+		return t.table.align
+	}
+	return t.size()
+}
+
+// Table is a table or struct
+type Table struct {
+	name     string
+	isStruct bool
+	fields   []*Field
+	// the number of vtable slots of table
+	slots int
+	// the layout of struct
+	size  int
+	align int
+}
+
+// Field is the field of table or struct
+type Field struct {
+	name       string
+	typ        *fbType
+	slot       int
+	offset     int
+	def        string
+	deprecated bool
+}
+
+type Enum struct {
+	name       string
+	underlying kind
+	values     map[string]int64
+	names      map[int64]string
+}
+
+// Schema is the parsed flatbuffers schema
+type Schema struct {
+	tables     map[string]*Table
+	enums      map[string]*Enum
+	root       string
+	identifier string
+}
+
+// Root returns the table of the full name or the root type if the name is empty
+func (s *Schema) Root(name string) (*Table, error) {
+	if name == "" {
+		name = s.root
+	}
+	if name == "" {
+		return nil, fmt.Errorf("root_type is not defined in the schema")
+	}
+	if t, ok := s.tables[name]; ok && !t.isStruct {
+		return t, nil
+	}
+	// match the short name
+	for full, t := range s.tables {
+		if !t.isStruct && (full == name || strings.HasSuffix(full, "."+name)) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("table %s is not found in the schema", name)
+}
+
+// ParseFile parses the fbs schema file and its included files
+func ParseFile(file string) (*Schema, error) {
+	p := newParser()
+	if err := p.parseFile(file); err != nil {
+		return nil, err
+	}
+	if err := p.resolve(); err != nil {
+		return nil, err
+	}
+	return p.schema, nil
+}
+
+// ParseSchema parses the fbs schema content without include
+func ParseSchema(content string) (*Schema, error) {
+	p := newParser()
+	if err := p.parse(content, ""); err != nil {
+		return nil, err
+	}
+	if err := p.resolve(); err != nil {
+		return nil, err
+	}
+	return p.schema, nil
+}
+
+type parser struct {
+	schema   *Schema
+	included map[string]bool
+	// the fields to resolve with the namespace where they are defined
+	pending []pendingType
+
+	tokens []string
+	pos    int
+	ns     string
+}
+
+type pendingType struct {
+	t  *fbType
+	ns string
+}
+
+func newParser() *parser {
+	return &parser{
+		schema:   &Schema{tables: make(map[string]*Table), enums: make(map[string]*Enum)},
+		included: make(map[string]bool),
+	}
+}
+
+func (p *parser) parseFile(file string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if p.included[abs] {
+		return nil
+	}
+	p.included[abs] = true
+	b, err := os.ReadFile(abs)
+	if err != nil {
+		return fmt.Errorf("read schema file %s failed: %v", file, err)
+	}
+	return p.parse(string(b), filepath.Dir(abs))
+}
+
+func (p *parser) parse(content string, dir string) error {
+	tokens, err := tokenize(content)
+	if err != nil {
+		return err
+	}
+	// save the state for the nested parsing of included files
+	oldTokens, oldPos, oldNs := p.tokens, p.pos, p.ns
+	defer func() {
+		p.tokens, p.pos, p.ns = oldTokens, oldPos, oldNs
+	}()
+	p.tokens, p.pos, p.ns = tokens, 0, ""
+	for p.pos < len(p.tokens) {
+		tok := p.next()
+		switch tok {
+		case "include":
+			name, err := p.str()
+			if err != nil {
+				return err
+			}
+			if dir == "" {
+				return fmt.Errorf("include %s is not supported in the schema content", name)
+			}
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+			if err := p.parseFile(filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		case "namespace":
+			p.ns = p.next()
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "root_type":
+			p.schema.root = p.fullName(p.next())
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "file_identifier":
+			id, err := p.str()
+			if err != nil {
+				return err
+			}
+			if len(id) != 4 {
+				return fmt.Errorf("file_identifier must be 4 characters but got %s", id)
+			}
+			p.schema.identifier = id
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "file_extension", "attribute":
+			if _, err := p.str(); err != nil {
+				return err
+			}
+			if err := p.expect(";"); err != nil {
+				return err
+			}
+		case "table", "struct":
+			if err := p.parseTable(tok == "struct"); err != nil {
+				return err
+			}
+		case "enum":
+			if err := p.parseEnum(); err != nil {
+				return err
+			}
+		case "union", "rpc_service":
+			return fmt.Errorf("%s is not supported", tok)
+		case ";":
+		default:
+			return fmt.Errorf("unexpected token %s", tok)
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseTable(isStruct bool) error {
+	t := &Table{name: p.fullName(p.next()), isStruct: isStruct}
+	if _, ok := p.schema.tables[t.name]; ok {
+		return fmt.Errorf("duplicate type %s", t.name)
+	}
+	if _, err := p.attributes(); err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	hasId := false
+	for p.peek() != "}" {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("unexpected end of table %s", t.name)
+		}
+		f := &Field{name: p.next()}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		typ, err := p.parseType()
+		if err != nil {
+			return err
+		}
+		f.typ = typ
+		if p.peek() == "=" {
+			p.next()
+			f.def = p.next()
+		}
+		attrs, err := p.attributes()
+		if err != nil {
+			return err
+		}
+		if _, ok := attrs["deprecated"]; ok {
+			f.deprecated = true
+		}
+		f.slot = len(t.fields)
+		if id, ok := attrs["id"]; ok {
+			n, err := strconv.Atoi(id)
+			if err != nil {
+				return fmt.Errorf("invalid id %s of field %s.%s", id, t.name, f.name)
+			}
+			f.slot = n
+			hasId = true
+		} else if hasId {
+			return fmt.Errorf("field %s.%s must have id because other fields have", t.name, f.name)
+		}
+		if err := p.expect(";"); err != nil {
+			return err
+		}
+		t.fields = append(t.fields, f)
+		if f.slot+1 > t.slots {
+			t.slots = f.slot + 1
+		}
+	}
+	p.next()
+	p.schema.tables[t.name] = t
+	return nil
+}
+
+func (p *parser) parseEnum() error {
+	e := &Enum{name: p.fullName(p.next()), values: make(map[string]int64), names: make(map[int64]string)}
+	if err := p.expect(":"); err != nil {
+		return err
+	}
+	k, ok := scalarKinds[p.next()]
+	if !ok || k == kindBool || k == kindFloat32 || k == kindFloat64 {
+		return fmt.Errorf("enum %s must have an integer underlying type", e.name)
+	}
+	e.underlying = k
+	if _, err := p.attributes(); err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	var v int64
+	for p.peek() != "}" {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("unexpected end of enum %s", e.name)
+		}
+		name := p.next()
+		if p.peek() == "=" {
+			p.next()
+			n, err := strconv.ParseInt(p.next(), 0, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value of enum %s.%s", e.name, name)
+			}
+			v = n
+		}
+		e.values[name] = v
+		e.names[v] = name
+		v++
+		if p.peek() == "," {
+			p.next()
+		}
+	}
+	p.next()
+	p.schema.enums[e.name] = e
+	return nil
+}
+
+func (p *parser) parseType() (*fbType, error) {
+	tok := p.next()
+	if tok == "[" {
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if elem.kind == kindVector {
+			return nil, fmt.Errorf("nested vector is not supported")
+		}
+		if p.peek() == ":" {
+			return nil, fmt.Errorf("fixed length array is not supported")
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &fbType{kind: kindVector, elem: elem}, nil
+	}
+	if k, ok := scalarKinds[tok]; ok {
+		return &fbType{kind: k}, nil
+	}
+	if tok == "string" {
+		return &fbType{kind: kindString}, nil
+	}
+	t := &fbType{ref: tok}
+	p.pending = append(p.pending, pendingType{t: t, ns: p.ns})
+	return t, nil
+}
+
+// attributes parses the optional attributes like (id: 1, deprecated)
+func (p *parser) attributes() (map[string]string, error) {
+	attrs := make(map[string]string)
+	if p.peek() != "(" {
+		return attrs, nil
+	}
+	p.next()
+	for p.peek() != ")" {
+		if p.pos >= len(p.tokens) {
+			return nil, fmt.Errorf("unexpected end of attributes")
+		}
+		name := p.next()
+		value := ""
+		if p.peek() == ":" {
+			p.next()
+			value = strings.Trim(p.next(), "\"")
+		}
+		attrs[name] = value
+		if p.peek() == "," {
+			p.next()
+		}
+	}
+	p.next()
+	return attrs, nil
+}
+
+// resolve links the type references and computes the struct layout
+func (p *parser) resolve() error {
+	for _, pt := range p.pending {
+		found := false
+		for _, name := range candidates(pt.t.ref, pt.ns) {
+			if t, ok := p.schema.tables[name]; ok {
+				pt.t.table = t
+				pt.t.kind = kindTable
+				if t.isStruct {
+					pt.t.kind = kindStruct
+				}
+				found = true
+				break
+			}
+			if e, ok := p.schema.enums[name]; ok {
+				pt.t.enum = e
+				pt.t.kind = kindEnum
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown type %s", pt.t.ref)
+		}
+	}
+	for _, t := range p.schema.tables {
+		if t.isStruct {
+			if err := layout(t, nil); err != nil {
+				return err
+			}
+		}
+	}
+	if p.schema.root != "" {
+		if t, ok := p.schema.tables[p.schema.root]; !ok || t.isStruct {
+			return fmt.Errorf("root_type %s must be a table", p.schema.root)
+		}
+	}
+	return nil
+}
+
+// layout computes the offsets of the struct fields, which are aligned by their sizes
+func layout(t *Table, visiting map[*Table]bool) error {
+	if t.size > 0 {
+		return nil
+	}
+	if visiting == nil {
+		visiting = make(map[*Table]bool)
+	}
+	if visiting[t] {
+		return fmt.Errorf("struct %s cannot contain itself", t.name)
+	}
+	visiting[t] = true
+	offset, align := 0, 1
+	for _, f := range t.fields {
+		switch {
+		case f.typ.kind == kindStruct:
+			if err := layout(f.typ.table, visiting); err != nil {
+				return err
+			}
+		case f.typ.kind == kindEnum || f.typ.kind.isScalar():
+		default:
+			return fmt.Errorf("struct %s field %s must be a scalar, enum or struct", t.name, f.name)
+		}
+		a := f.typ.align()
+		offset = alignUp(offset, a)
+		f.offset = offset
+		offset += f.typ.size()
+		if a > align {
+			align = a
+		}
+	}
+	t.align = align
+	t.size = alignUp(offset, align)
+	return nil
+}
+
+func alignUp(n, a int) int {
+	return (n + a - 1) / a * a
+}
+
+// candidates returns the full names to look up the type from the inner namespace to the outer
+func candidates(name string, ns string) []string {
+	var result []string
+	for ns != "" {
+		result = append(result, ns+"."+name)
+		i := strings.LastIndex(ns, ".")
+		if i < 0 {
+			break
+		}
+		ns = ns[:i]
+	}
+	return append(result, name)
+}
+
+func (p *parser) fullName(name string) string {
+	if p.ns == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return p.ns + "." + name
+}
+
+func (p *parser) next() string {
+	if p.pos >= len(p.tokens) {
+		p.pos++
+		return ""
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok
+}
+
+func (p *parser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		return fmt.Errorf("expect %s but got %s", tok, got)
+	}
+	return nil
+}
+
+func (p *parser) str() (string, error) {
+	tok := p.next()
+	if len(tok) < 2 || tok[0] != '"' {
+		return "", fmt.Errorf("expect string but got %s", tok)
+	}
+	return tok[1 : len(tok)-1], nil
+}
+
+// tokenize splits the schema into identifiers, numbers, strings and punctuations without comments
+func tokenize(content string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end < 0 {
+				end = len(content) - i
+			}
+			i += end
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed comment")
+			}
+			i += end + 4
+		case c == '"':
+			end := strings.IndexByte(content[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed string")
+			}
+			tokens = append(tokens, content[i:i+end+2])
+			i += end + 2
+		case strings.IndexByte("{}()[]:;,=", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case isIdentChar(c):
+			j := i
+			for j < len(content) && isIdentChar(content[j]) {
+				j++
+			}
+			tokens = append(tokens, content[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %c", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_.-+", c) >= 0
+}
@@ -15,8 +15,10 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 
@@ -28,6 +30,9 @@ import (
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 )
 
+// parserPool reuses the parsers whose value caches are the major allocations of the decode
+var parserPool fastjson.ParserPool
+
 type FastJsonConverter struct {
 	sync.RWMutex
 	schema map[string]*ast.JsonStreamField
@@ -37,6 +42,8 @@ type FastJsonConverter struct {
 type FastJsonConverterConf struct {
 	UseInt64        bool              `json:"useInt64ForWholeNumber"`
 	ColAliasMapping map[string]string `json:"colAliasMapping"`
+	// SchemaPolicy is the policy of the stream to handle the data which does not match the schema
+	SchemaPolicy string `json:"schemaPolicy"`
 }
 
 func NewFastJsonConverter(schema map[string]*ast.JsonStreamField, props map[string]any) *FastJsonConverter {
@@ -72,11 +79,93 @@ func (f *FastJsonConverter) Decode(ctx api.StreamContext, b []byte) (m any, err
 	}()
 	f.RLock()
 	defer f.RUnlock()
-	return f.decodeWithSchema(b, f.schema)
+	// Only parse the fields used by the rule which are pushed down as the schema
+	if f.schema != nil {
+		if pb, ok := project(b, f.schema); ok {
+			b = pb
+		}
+	}
+	m, err = f.decodeWithSchema(b, f.schema)
+	if err != nil && f.schema != nil && f.isTolerant() {
+		// Decode the defined fields without type check, let the preprocessor handle the mismatched types by the schema policy
+		return f.decodeWithSchema(b, untypedSchema(f.schema))
+	}
+	return m, err
+}
+
+// DecodeStream decodes a json array or NDJSON (newline delimited json) item by item.
+// Only one item is decoded at a time so that a huge payload is not materialized as a whole.
+func (f *FastJsonConverter) DecodeStream(ctx api.StreamContext, b []byte, emit func(map[string]any) error) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	isArray := firstNonSpace(b) == '['
+	if isArray {
+		// consume the opening bracket
+		if _, err := dec.Token(); err != nil {
+			return errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}
+	for i := 0; ; i++ {
+		if isArray && !dec.More() {
+			break
+		}
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errorx.NewWithCode(errorx.CovnerterErr, fmt.Sprintf("item %d: %v", i, err))
+		}
+		m, err := f.Decode(ctx, raw)
+		if err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+		switch r := m.(type) {
+		case map[string]any:
+			err = emit(r)
+		case []map[string]any:
+			for _, mm := range r {
+				if err = emit(mm); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func firstNonSpace(b []byte) byte {
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// isTolerant returns whether the mismatched types are allowed by the schema policy
+func (f *FastJsonConverter) isTolerant() bool {
+	return f.SchemaPolicy == ast.SchemaPolicyNullable || f.SchemaPolicy == ast.SchemaPolicyWiden
+}
+
+// untypedSchema keeps the field names of the schema but drops the types
+func untypedSchema(schema map[string]*ast.JsonStreamField) map[string]*ast.JsonStreamField {
+	result := make(map[string]*ast.JsonStreamField, len(schema))
+	for k := range schema {
+		result[k] = nil
+	}
+	return result
 }
 
 func (f *FastJsonConverter) DecodeField(_ api.StreamContext, b []byte, field string) (any, error) {
-	var p fastjson.Parser
+	p := parserPool.Get()
+	defer parserPool.Put(p)
 	v, err := p.ParseBytes(b)
 	if err != nil {
 		return nil, err
@@ -104,7 +193,9 @@ func (f *FastJsonConverter) DecodeField(_ api.StreamContext, b []byte, field str
 }
 
 func (f *FastJsonConverter) decodeWithSchema(b []byte, schema map[string]*ast.JsonStreamField) (interface{}, error) {
-	var p fastjson.Parser
+	// The values are copied out of the parser, so it can be reused once the decode is done
+	p := parserPool.Get()
+	defer parserPool.Put(p)
 	v, err := p.ParseBytes(b)
 	if err != nil {
 		return nil, err
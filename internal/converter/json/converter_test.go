@@ -340,6 +340,28 @@ func TestFastJsonConverterWithSchemaError(t *testing.T) {
 	}
 }
 
+func TestFastJsonConverterWithSchemaPolicy(t *testing.T) {
+	schema := map[string]*ast.JsonStreamField{
+		"a": {
+			Type: "bigint",
+		},
+		"b": {
+			Type: "string",
+		},
+	}
+	payload := []byte(`{"a":1.5,"b":{"c":1},"d":2}`)
+	ctx := mockContext.NewMockContext("test", "op1")
+	f := NewFastJsonConverter(schema, map[string]any{"schemaPolicy": ast.SchemaPolicyFailFast})
+	_, err := f.Decode(ctx, payload)
+	require.Error(t, err)
+	for _, policy := range []string{ast.SchemaPolicyNullable, ast.SchemaPolicyWiden} {
+		f = NewFastJsonConverter(schema, map[string]any{"schemaPolicy": policy})
+		m, err := f.Decode(ctx, payload)
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"a": 1.5, "b": map[string]any{"c": float64(1)}}, m)
+	}
+}
+
 func TestFastJsonEncode(t *testing.T) {
 	a := make(map[string]int)
 	a["a"] = 1
@@ -350,6 +372,160 @@ func TestFastJsonEncode(t *testing.T) {
 	require.Equal(t, v, []byte(`{"a":1}`))
 }
 
+func TestDecodeStream(t *testing.T) {
+	schema := map[string]*ast.JsonStreamField{
+		"a": {
+			Type: "bigint",
+		},
+	}
+	tests := []struct {
+		name    string
+		payload string
+		result  []map[string]any
+		err     string
+	}{
+		{
+			name:    "ndjson",
+			payload: "{\"a\":1,\"b\":2}\n\n{\"a\":2}\r\n[{\"a\":3},{\"a\":4}]",
+			result:  []map[string]any{{"a": int64(1)}, {"a": int64(2)}, {"a": int64(3)}, {"a": int64(4)}},
+		},
+		{
+			name:    "array",
+			payload: "\n [{\"a\":1},\n{\"a\":2}]",
+			result:  []map[string]any{{"a": int64(1)}, {"a": int64(2)}},
+		},
+		{
+			name:    "empty",
+			payload: "[]",
+		},
+		{
+			name:    "wrong type",
+			payload: "{\"a\":1}\n{\"a\":\"s\"}\n{\"a\":3}",
+			result:  []map[string]any{{"a": int64(1)}},
+			err:     "item 1: a has wrong type:string, expect:bigint",
+		},
+		{
+			name:    "not object",
+			payload: "[{\"a\":1},2]",
+			result:  []map[string]any{{"a": int64(1)}},
+			err:     "item 1: only map[string]interface{} and []map[string]interface{} is supported",
+		},
+		{
+			name:    "broken",
+			payload: "[{\"a\":1},{\"a\"",
+			result:  []map[string]any{{"a": int64(1)}},
+			err:     "item 1: unexpected EOF",
+		},
+	}
+	ctx := mockContext.NewMockContext("test", "op1")
+	f := NewFastJsonConverter(schema, map[string]any{"useInt64ForWholeNumber": true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result []map[string]any
+			err := f.DecodeStream(ctx, []byte(tt.payload), func(m map[string]any) error {
+				result = append(result, m)
+				return nil
+			})
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.result, result)
+		})
+	}
+	// Stop when emit fails
+	count := 0
+	err := f.DecodeStream(ctx, []byte("{\"a\":1}\n{\"a\":2}"), func(m map[string]any) error {
+		count++
+		return fmt.Errorf("stop")
+	})
+	require.EqualError(t, err, "stop")
+	require.Equal(t, 1, count)
+}
+
+func TestProject(t *testing.T) {
+	schema := map[string]*ast.JsonStreamField{
+		"a": nil,
+		"c": {
+			Type: "struct",
+			Properties: map[string]*ast.JsonStreamField{
+				"d": nil,
+			},
+		},
+	}
+	tests := []struct {
+		name    string
+		payload string
+		result  string
+		ok      bool
+	}{
+		{
+			name:    "drop",
+			payload: `{"a":1,"b":{"x":[1,{"y":"}]"}]},"c":{"d":"\"","e":2},"f":"s"}`,
+			result:  `{"a":1,"c":{"d":"\"","e":2}}`,
+			ok:      true,
+		},
+		{
+			name:    "drop first",
+			payload: " {\n \"b\" : [1, 2] ,\n \"a\" : true }\n",
+			result:  `{"a" : true}`,
+			ok:      true,
+		},
+		{
+			name:    "keep null and escaped key",
+			payload: `{"b":null,"\u0061":2,"e":3}`,
+			result:  `{"b":null,"\u0061":2}`,
+			ok:      true,
+		},
+		{
+			name:    "keep all",
+			payload: `{"a":1, "c":{}}`,
+			result:  `{"a":1, "c":{}}`,
+			ok:      true,
+		},
+		{
+			name:    "empty",
+			payload: `{}`,
+			result:  `{}`,
+			ok:      true,
+		},
+		{
+			name:    "array",
+			payload: `[{"a":1}]`,
+		},
+		{
+			name:    "unclosed",
+			payload: `{"a":1,"b":[1,2}`,
+		},
+		{
+			name:    "tail",
+			payload: `{"a":1}}`,
+		},
+		{
+			name:    "no value",
+			payload: `{"a":,"b":1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := project([]byte(tt.payload), schema)
+			require.Equal(t, tt.ok, ok)
+			if ok {
+				require.Equal(t, tt.result, string(r))
+			}
+		})
+	}
+	// The decode result is not changed by the projection
+	ctx := mockContext.NewMockContext("test", "op1")
+	f := NewFastJsonConverter(schema, nil)
+	m, err := f.Decode(ctx, []byte(tests[0].payload))
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": float64(1), "c": map[string]any{"d": "\""}}, m)
+	_, err = f.Decode(ctx, []byte(tests[6].payload))
+	require.Error(t, err)
+}
+
 func TestArrayWithArray(t *testing.T) {
 	payload := []byte(`{
     "a":[
@@ -784,3 +960,18 @@ func TestIssue3441(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, map[string]interface{}{"id": 17952926683484.44}, m)
 }
+
+func TestDecodeWithPooledParser(t *testing.T) {
+	f := NewFastJsonConverter(nil, nil)
+	ctx := mockContext.NewMockContext("test", "op1")
+	first, err := f.Decode(ctx, []byte(`{"name":"first","tags":["a","b"],"nested":{"key":"v1"}}`))
+	require.NoError(t, err)
+	// The parser is reused by the following decode, the previous result must not refer to its memory
+	_, err = f.Decode(ctx, []byte(`{"name":"second","tags":["c","d"],"nested":{"key":"v2"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name":   "first",
+		"tags":   []any{"a", "b"},
+		"nested": map[string]any{"key": "v1"},
+	}, first)
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	"bytes"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// project scans the top level object and only keeps the keys in the schema, so that the values of the unused keys are
+// skipped without parsing. Null values are always kept to be consistent with decodeObject.
+// Return false if the payload is not an object or cannot be scanned, then the caller should parse the whole payload.
+func project(b []byte, schema map[string]*ast.JsonStreamField) ([]byte, bool) {
+	i := skipSpace(b, 0)
+	if i >= len(b) || b[i] != '{' {
+		return nil, false
+	}
+	var (
+		// out is only created when there is a key to drop
+		out     []byte
+		start   = i
+		keptEnd = i + 1
+		first   = true
+	)
+	i = skipSpace(b, i+1)
+	if i < len(b) && b[i] == '}' {
+		i++
+	} else {
+		for {
+			if i >= len(b) || b[i] != '"' {
+				return nil, false
+			}
+			keyStart := i
+			end, escaped, ok := scanString(b, i)
+			if !ok {
+				return nil, false
+			}
+			key := b[keyStart+1 : end-1]
+			i = skipSpace(b, end)
+			if i >= len(b) || b[i] != ':' {
+				return nil, false
+			}
+			i = skipSpace(b, i+1)
+			valStart := i
+			i, ok = skipValue(b, i)
+			if !ok {
+				return nil, false
+			}
+			// The escaped key cannot be compared directly, keep it to let the parser decide
+			_, inSchema := schema[string(key)]
+			if inSchema || escaped || b[valStart] == 'n' {
+				if out == nil {
+					keptEnd = i
+				} else {
+					if !first {
+						out = append(out, ',')
+					}
+					out = append(out, b[keyStart:i]...)
+				}
+				first = false
+			} else if out == nil {
+				out = make([]byte, 0, len(b)-(i-keyStart))
+				out = append(out, b[start:keptEnd]...)
+			}
+			i = skipSpace(b, i)
+			if i >= len(b) {
+				return nil, false
+			}
+			if b[i] == ',' {
+				i = skipSpace(b, i+1)
+				continue
+			}
+			if b[i] == '}' {
+				i++
+				break
+			}
+			return nil, false
+		}
+	}
+	// Let the parser report the error of the tail
+	if skipSpace(b, i) != len(b) {
+		return nil, false
+	}
+	if out == nil {
+		return b, true
+	}
+	return append(out, '}'), true
+}
+
+func skipSpace(b []byte, i int) int {
+	for ; i < len(b); i++ {
+		switch b[i] {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanString returns the index after the closing quote of the string starting at i and whether it has escape chars
+func scanString(b []byte, i int) (int, bool, bool) {
+	escaped := false
+	for j := i + 1; j < len(b); {
+		q := bytes.IndexByte(b[j:], '"')
+		if q < 0 {
+			return 0, false, false
+		}
+		e := bytes.IndexByte(b[j:j+q], '\\')
+		if e < 0 {
+			return j + q + 1, escaped, true
+		}
+		// skip the escaped char
+		escaped = true
+		j += e + 2
+	}
+	return 0, false, false
+}
+
+// skipValue returns the index after the value starting at i. The skipped value is not validated.
+func skipValue(b []byte, i int) (int, bool) {
+	if i >= len(b) {
+		return 0, false
+	}
+	switch b[i] {
+	case '"':
+		end, _, ok := scanString(b, i)
+		return end, ok
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(b); j++ {
+			switch b[j] {
+			case '"':
+				end, _, ok := scanString(b, j)
+				if !ok {
+					return 0, false
+				}
+				j = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return j + 1, true
+				}
+			}
+		}
+		return 0, false
+	default:
+		j := i
+		for ; j < len(b); j++ {
+			switch b[j] {
+			case ',', '}', ']', ' ', '\t', '\r', '\n':
+				if j == i {
+					return 0, false
+				}
+				return j, true
+			}
+		}
+		return j, j > i
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonschema
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/lf-edge/ekuiper/v2/internal/converter/json"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// Converter is the json converter which validates the payload against a JSON schema before decoding.
+// The invalid payload will fail the decoding, so it can be routed to the side output like other decode errors.
+type Converter struct {
+	*json.FastJsonConverter
+	schema *gojsonschema.Schema
+}
+
+func NewConverter(schemaFile string, schema map[string]*ast.JsonStreamField, props map[string]any) (*Converter, error) {
+	content, err := os.ReadFile(schemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("read json schema file %s failed: %v", schemaFile, err)
+	}
+	s, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid json schema %s: %v", schemaFile, err)
+	}
+	return &Converter{
+		FastJsonConverter: json.NewFastJsonConverter(schema, props),
+		schema:            s,
+	}, nil
+}
+
+func (c *Converter) Decode(ctx api.StreamContext, b []byte) (m any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	if err = c.Validate(b); err != nil {
+		return nil, err
+	}
+	return c.FastJsonConverter.Decode(ctx, b)
+}
+
+// Validate checks the json payload against the schema
+func (c *Converter) Validate(b []byte) error {
+	r, err := c.schema.Validate(gojsonschema.NewBytesLoader(b))
+	if err != nil {
+		return fmt.Errorf("invalid json payload: %v", err)
+	}
+	if !r.Valid() {
+		errs := make([]string, 0, len(r.Errors()))
+		for _, e := range r.Errors() {
+			errs = append(errs, e.String())
+		}
+		return fmt.Errorf("payload does not match the json schema: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
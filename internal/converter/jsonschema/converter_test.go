@@ -0,0 +1,90 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+const testSchema = `{
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer"},
+    "name": {"type": "string", "minLength": 1},
+    "temperature": {"type": "number", "maximum": 100}
+  },
+  "required": ["id", "name"]
+}`
+
+func TestDecode(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "test.json")
+	require.NoError(t, os.WriteFile(f, []byte(testSchema), 0o644))
+	c, err := NewConverter(f, nil, nil)
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("test", "op1")
+	tests := []struct {
+		name    string
+		payload string
+		exp     any
+		err     string
+	}{
+		{
+			name:    "valid",
+			payload: `{"id":1,"name":"a","temperature":20.5}`,
+			exp:     map[string]any{"id": float64(1), "name": "a", "temperature": 20.5},
+		},
+		{
+			name:    "missing required",
+			payload: `{"id":1}`,
+			err:     "payload does not match the json schema: (root): name is required",
+		},
+		{
+			name:    "out of range",
+			payload: `{"id":1,"name":"a","temperature":120}`,
+			err:     "payload does not match the json schema: temperature: Must be less than or equal to 100",
+		},
+		{
+			name:    "invalid json",
+			payload: `{"id":1`,
+			err:     "invalid json payload: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := c.Decode(ctx, []byte(tt.payload))
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.exp, r)
+		})
+	}
+}
+
+func TestNewConverterError(t *testing.T) {
+	_, err := NewConverter(filepath.Join(t.TempDir(), "none.json"), nil, nil)
+	require.Error(t, err)
+	f := filepath.Join(t.TempDir(), "bad.json")
+	require.NoError(t, os.WriteFile(f, []byte(`{"type": 3}`), 0o644))
+	_, err = NewConverter(f, nil, nil)
+	require.Error(t, err)
+}
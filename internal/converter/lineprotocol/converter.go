@@ -0,0 +1,406 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lineprotocol
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+	"github.com/lf-edge/ekuiper/v2/pkg/pool"
+)
+
+const (
+	KeyMeasurement = "measurement"
+	KeyTags        = "tags"
+	KeyFields      = "fields"
+	KeyTimestamp   = "timestamp"
+)
+
+// Converter parses and writes the InfluxDB line protocol. Each line is decoded into a map of
+// measurement, tags, fields and timestamp. The timestamp is converted to unix milliseconds.
+type Converter struct {
+	// Precision is the unit of the timestamp in the payload. Supports ns, us, ms and s. Default to ns
+	Precision string `json:"precision"`
+	// Flatten puts the tags and fields in the top level of the decoded map instead of the nested tags and fields map
+	Flatten bool `json:"flatten"`
+	// Measurement is the default measurement name to encode if the data does not have one
+	Measurement string `json:"measurement"`
+	// TagKeys are the keys encoded as tags when encoding a flat map. Other keys are encoded as fields
+	TagKeys []string `json:"tagKeys"`
+
+	// the number of precision units in one millisecond, negative means the number of milliseconds in one unit
+	unitsPerMilli int64
+}
+
+func NewConverter(props map[string]any) (message.Converter, error) {
+	c := &Converter{}
+	err := cast.MapToStruct(props, c)
+	if err != nil {
+		return nil, err
+	}
+	switch c.Precision {
+	case "", "ns":
+		c.unitsPerMilli = 1e6
+	case "us":
+		c.unitsPerMilli = 1e3
+	case "ms":
+		c.unitsPerMilli = 1
+	case "s":
+		c.unitsPerMilli = -1e3
+	default:
+		return nil, fmt.Errorf("unsupported precision %s, must be one of ns, us, ms and s", c.Precision)
+	}
+	return c, nil
+}
+
+// Encode writes a map or a list of maps as line protocol lines. If the map has a fields map, the tags
+// are read from the tags map. Otherwise, the map is flat and the keys in TagKeys are written as tags.
+func (c *Converter) Encode(_ api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	buf := pool.GetBuffer()
+	switch m := d.(type) {
+	case map[string]any:
+		err = c.encodeLine(buf, m)
+	case []map[string]any:
+		for i, mm := range m {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			err = c.encodeLine(buf, mm)
+			if err != nil {
+				break
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported type %v, must be a map or a list of maps", d)
+	}
+	if err != nil {
+		pool.PutBuffer(buf)
+		return nil, err
+	}
+	return pool.CopyAndPutBuffer(buf), nil
+}
+
+func (c *Converter) encodeLine(buf *bytes.Buffer, m map[string]any) error {
+	measurement := c.Measurement
+	if v, ok := m[KeyMeasurement]; ok {
+		measurement, _ = cast.ToString(v, cast.CONVERT_ALL)
+	}
+	if measurement == "" {
+		return fmt.Errorf("measurement is required")
+	}
+	var (
+		tags   map[string]any
+		fields map[string]any
+	)
+	if fm, ok := m[KeyFields].(map[string]any); ok {
+		fields = fm
+		tags, _ = m[KeyTags].(map[string]any)
+	} else {
+		// The flat map is split into the scratch maps which are only used in this line
+		fields = pool.GetMap()
+		defer pool.PutMap(fields)
+		tags = pool.GetMap()
+		defer pool.PutMap(tags)
+		for k, v := range m {
+			if k != KeyMeasurement && k != KeyTimestamp {
+				fields[k] = v
+			}
+		}
+		for _, k := range c.TagKeys {
+			if v, ok := fields[k]; ok {
+				tags[k] = v
+				delete(fields, k)
+			}
+		}
+	}
+	buf.WriteString(escape(measurement, ", "))
+	for _, k := range sortedKeys(tags) {
+		if tags[k] == nil {
+			continue
+		}
+		v, _ := cast.ToString(tags[k], cast.CONVERT_ALL)
+		buf.WriteByte(',')
+		buf.WriteString(escape(k, ",= "))
+		buf.WriteByte('=')
+		buf.WriteString(escape(v, ",= "))
+	}
+	n := 0
+	for _, k := range sortedKeys(fields) {
+		if fields[k] == nil {
+			continue
+		}
+		if n == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(escape(k, ",= "))
+		buf.WriteByte('=')
+		formatField(buf, fields[k])
+		n++
+	}
+	if n == 0 {
+		return fmt.Errorf("measurement %s has no field", measurement)
+	}
+	if v, ok := m[KeyTimestamp]; ok && v != nil {
+		ts, err := cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %v: %v", v, err)
+		}
+		if c.unitsPerMilli > 0 {
+			ts *= c.unitsPerMilli
+		} else {
+			ts /= -c.unitsPerMilli
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(ts, 10))
+	}
+	return nil
+}
+
+func formatField(buf *bytes.Buffer, v any) {
+	switch vt := v.(type) {
+	case int, int8, int16, int32, int64:
+		fmt.Fprintf(buf, "%di", vt)
+	case uint, uint8, uint16, uint32, uint64:
+		fmt.Fprintf(buf, "%du", vt)
+	case float32:
+		buf.WriteString(strconv.FormatFloat(float64(vt), 'f', -1, 32))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(vt, 'f', -1, 64))
+	case bool:
+		buf.WriteString(strconv.FormatBool(vt))
+	default:
+		s, _ := cast.ToString(v, cast.CONVERT_ALL)
+		buf.WriteByte('"')
+		buf.WriteString(escape(s, `"\`))
+		buf.WriteByte('"')
+	}
+}
+
+func escape(s string, chars string) string {
+	if !strings.ContainsAny(s, chars) {
+		return s
+	}
+	sb := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(chars, s[i]) >= 0 {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Decode parses the line protocol payload. Empty lines and comment lines starting with # are ignored.
+// Return a map if there is only one line, otherwise return a list of maps.
+func (c *Converter) Decode(_ api.StreamContext, b []byte) (_ any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	var result []map[string]any
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		m, err := c.decodeLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		result = append(result, m)
+	}
+	switch len(result) {
+	case 0:
+		return nil, fmt.Errorf("no line protocol data found")
+	case 1:
+		return result[0], nil
+	default:
+		return result, nil
+	}
+}
+
+func (c *Converter) decodeLine(line string) (map[string]any, error) {
+	measurement, i := readToken(line, 0, ", ")
+	if measurement == "" {
+		return nil, fmt.Errorf("missing measurement")
+	}
+	tags := make(map[string]any)
+	for i < len(line) && line[i] == ',' {
+		var k, v string
+		k, i = readToken(line, i+1, ",= ")
+		if i >= len(line) || line[i] != '=' || k == "" {
+			return nil, fmt.Errorf("invalid tag set of measurement %s", measurement)
+		}
+		v, i = readToken(line, i+1, ", ")
+		tags[k] = v
+	}
+	i = skipSpaces(line, i)
+	fields := make(map[string]any)
+	for i < len(line) {
+		var (
+			k   string
+			val any
+			err error
+		)
+		k, i = readToken(line, i, ",= ")
+		if i >= len(line) || line[i] != '=' || k == "" {
+			return nil, fmt.Errorf("invalid field set of measurement %s", measurement)
+		}
+		i++
+		if i < len(line) && line[i] == '"' {
+			val, i, err = readString(line, i+1)
+		} else {
+			var raw string
+			raw, i = readToken(line, i, ", ")
+			val, err = parseFieldValue(raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of field %s: %v", k, err)
+		}
+		fields[k] = val
+		if i >= len(line) || line[i] != ',' {
+			break
+		}
+		i++
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("measurement %s has no field", measurement)
+	}
+	var result map[string]any
+	if c.Flatten {
+		result = make(map[string]any, len(tags)+len(fields)+2)
+		for k, v := range tags {
+			result[k] = v
+		}
+		for k, v := range fields {
+			result[k] = v
+		}
+	} else {
+		result = map[string]any{
+			KeyTags:   tags,
+			KeyFields: fields,
+		}
+	}
+	result[KeyMeasurement] = measurement
+	i = skipSpaces(line, i)
+	if i < len(line) {
+		ts, err := strconv.ParseInt(line[i:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %s", line[i:])
+		}
+		if c.unitsPerMilli > 0 {
+			ts /= c.unitsPerMilli
+		} else {
+			ts *= -c.unitsPerMilli
+		}
+		result[KeyTimestamp] = ts
+	}
+	return result, nil
+}
+
+// readToken reads from index i until any of the unescaped stop chars. Return the unescaped token and the index of the stop char.
+func readToken(line string, i int, stops string) (string, int) {
+	sb := strings.Builder{}
+	for ; i < len(line); i++ {
+		ch := line[i]
+		if ch == '\\' && i+1 < len(line) && strings.IndexByte(",= \\", line[i+1]) >= 0 {
+			i++
+			sb.WriteByte(line[i])
+			continue
+		}
+		if strings.IndexByte(stops, ch) >= 0 {
+			break
+		}
+		sb.WriteByte(ch)
+	}
+	return sb.String(), i
+}
+
+// readString reads a quoted string value from index i which is right after the opening quote. Return the index after the closing quote.
+func readString(line string, i int) (string, int, error) {
+	sb := strings.Builder{}
+	for ; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\'):
+			i++
+			sb.WriteByte(line[i])
+		case ch == '"':
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return "", i, fmt.Errorf("unterminated string")
+}
+
+func skipSpaces(line string, i int) int {
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+func parseFieldValue(raw string) (any, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+	switch raw[len(raw)-1] {
+	case 'i':
+		return strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+	case 'u':
+		u, err := strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("unsigned integer %s overflows bigint", raw)
+		}
+		return int64(u), nil
+	}
+	return strconv.ParseFloat(raw, 64)
+}
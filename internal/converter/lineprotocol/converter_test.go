@@ -0,0 +1,179 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lineprotocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]any
+		input string
+		exp   any
+		err   string
+	}{
+		{
+			name:  "full line",
+			input: `weather,location=us-midwest,season=summer temperature=82,humidity=71i,raining=false,desc="hot \"day\"" 1465839830100400200`,
+			exp: map[string]any{
+				"measurement": "weather",
+				"tags":        map[string]any{"location": "us-midwest", "season": "summer"},
+				"fields":      map[string]any{"temperature": 82.0, "humidity": int64(71), "raining": false, "desc": `hot "day"`},
+				"timestamp":   int64(1465839830100),
+			},
+		},
+		{
+			name:  "no tags no timestamp",
+			input: `cpu usage=0.5,count=3u`,
+			exp: map[string]any{
+				"measurement": "cpu",
+				"tags":        map[string]any{},
+				"fields":      map[string]any{"usage": 0.5, "count": int64(3)},
+			},
+		},
+		{
+			name:  "escaped",
+			input: `my\ measure,tag\,key=tag\ value field\=key=T`,
+			exp: map[string]any{
+				"measurement": "my measure",
+				"tags":        map[string]any{"tag,key": "tag value"},
+				"fields":      map[string]any{"field=key": true},
+			},
+		},
+		{
+			name:  "multiple lines with flatten and precision",
+			props: map[string]any{"flatten": true, "precision": "s"},
+			input: "# comment\ncpu,host=a usage=1 1700000000\n\ncpu,host=b usage=2 1700000001\n",
+			exp: []map[string]any{
+				{"measurement": "cpu", "host": "a", "usage": 1.0, "timestamp": int64(1700000000000)},
+				{"measurement": "cpu", "host": "b", "usage": 2.0, "timestamp": int64(1700000001000)},
+			},
+		},
+		{
+			name:  "no field",
+			input: `cpu,host=a`,
+			err:   "line 1: measurement cpu has no field",
+		},
+		{
+			name:  "invalid field",
+			input: "cpu a=1\ncpu a=abc",
+			err:   `line 2: invalid value of field a: strconv.ParseFloat: parsing "abc": invalid syntax`,
+		},
+		{
+			name:  "unterminated string",
+			input: `cpu a="abc`,
+			err:   "line 1: invalid value of field a: unterminated string",
+		},
+		{
+			name:  "overflow",
+			input: `cpu a=18446744073709551615u`,
+			err:   "line 1: invalid value of field a: unsigned integer 18446744073709551615u overflows bigint",
+		},
+		{
+			name:  "invalid timestamp",
+			input: `cpu a=1 abc`,
+			err:   "line 1: invalid timestamp abc",
+		},
+		{
+			name:  "empty",
+			input: "\n# comment only\n",
+			err:   "no line protocol data found",
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewConverter(tt.props)
+			require.NoError(t, err)
+			r, err := c.Decode(ctx, []byte(tt.input))
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.exp, r)
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]any
+		input any
+		exp   string
+		err   string
+	}{
+		{
+			name: "structured",
+			input: map[string]any{
+				"measurement": "weather",
+				"tags":        map[string]any{"location": "us midwest"},
+				"fields":      map[string]any{"temperature": 82.5, "humidity": int64(71), "raining": false, "desc": `hot "day"`},
+				"timestamp":   int64(1465839830100),
+			},
+			exp: `weather,location=us\ midwest desc="hot \"day\"",humidity=71i,raining=false,temperature=82.5 1465839830100000000`,
+		},
+		{
+			name:  "flat",
+			props: map[string]any{"measurement": "cpu", "tagKeys": []any{"host"}, "precision": "ms"},
+			input: []map[string]any{
+				{"host": "a", "usage": 1.0, "timestamp": int64(1700000000000)},
+				{"host": "b", "usage": 2.0},
+			},
+			exp: "cpu,host=a usage=1 1700000000000\ncpu,host=b usage=2",
+		},
+		{
+			name:  "no measurement",
+			input: map[string]any{"a": 1},
+			err:   "measurement is required",
+		},
+		{
+			name:  "no field",
+			input: map[string]any{"measurement": "cpu", "tags": map[string]any{"host": "a"}, "fields": map[string]any{}},
+			err:   "measurement cpu has no field",
+		},
+		{
+			name:  "unsupported",
+			input: "abc",
+			err:   "unsupported type abc, must be a map or a list of maps",
+		},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewConverter(tt.props)
+			require.NoError(t, err)
+			r, err := c.Encode(ctx, tt.input)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.exp, string(r))
+		})
+	}
+}
+
+func TestInvalidPrecision(t *testing.T) {
+	_, err := NewConverter(map[string]any{"precision": "m"})
+	require.EqualError(t, err, "unsupported precision m, must be one of ns, us, ms and s")
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package msgpack
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/ugorji/go/codec"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// Converter decodes msgpack with the same schema handling as the json converter: only the fields defined in the
+// schema are decoded and their types are checked and converted.
+type Converter struct {
+	sync.RWMutex
+	schema map[string]*ast.JsonStreamField
+	ConverterConf
+	h *codec.MsgpackHandle
+}
+
+type ConverterConf struct {
+	ColAliasMapping map[string]string `json:"colAliasMapping"`
+	// SchemaPolicy is the policy of the stream to handle the data which does not match the schema
+	SchemaPolicy string `json:"schemaPolicy"`
+}
+
+func NewConverter(schema map[string]*ast.JsonStreamField, props map[string]any) *Converter {
+	h := &codec.MsgpackHandle{}
+	// encode with the new spec so that string is str and []byte is bin, and they are decoded back as the same types
+	h.WriteExt = true
+	// decode the integers as int64 like the other converters
+	h.SignedInteger = true
+	// encode with the sorted map keys so that the same data always has the same bytes
+	h.Canonical = true
+	c := &Converter{schema: schema, h: h}
+	if props != nil {
+		_ = cast.MapToStruct(props, &c.ConverterConf)
+	}
+	return c
+}
+
+func (c *Converter) ResetSchema(schema map[string]*ast.JsonStreamField) {
+	c.Lock()
+	defer c.Unlock()
+	c.schema = schema
+}
+
+func (c *Converter) Encode(_ api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	err = codec.NewEncoderBytes(&b, c.h).Encode(d)
+	return b, err
+}
+
+func (c *Converter) Decode(_ api.StreamContext, b []byte) (m any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	var v any
+	if err := codec.NewDecoderBytes(b, c.h).Decode(&v); err != nil {
+		return nil, fmt.Errorf("fail to decode msgpack: %v", err)
+	}
+	v = normalize(v)
+	c.RLock()
+	defer c.RUnlock()
+	m, err = c.decodeWithSchema(v, c.schema)
+	if err != nil && c.schema != nil && c.isTolerant() {
+		// Decode the defined fields without type check, let the preprocessor handle the mismatched types by the schema policy
+		return c.decodeWithSchema(v, untypedSchema(c.schema))
+	}
+	return m, err
+}
+
+// isTolerant returns whether the mismatched types are allowed by the schema policy
+func (c *Converter) isTolerant() bool {
+	return c.SchemaPolicy == ast.SchemaPolicyNullable || c.SchemaPolicy == ast.SchemaPolicyWiden
+}
+
+// untypedSchema keeps the field names of the schema but drops the types
+func untypedSchema(schema map[string]*ast.JsonStreamField) map[string]*ast.JsonStreamField {
+	result := make(map[string]*ast.JsonStreamField, len(schema))
+	for k := range schema {
+		result[k] = nil
+	}
+	return result
+}
+
+func (c *Converter) decodeWithSchema(v any, schema map[string]*ast.JsonStreamField) (any, error) {
+	switch vt := v.(type) {
+	case map[string]any:
+		return c.decodeObject(vt, schema, true)
+	case []any:
+		ms := make([]map[string]any, len(vt))
+		for i, item := range vt {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("only map[string]interface{} and []map[string]interface{} is supported")
+			}
+			m, err := c.decodeObject(obj, schema, false)
+			if err != nil {
+				return nil, err
+			}
+			ms[i] = m
+		}
+		return ms, nil
+	}
+	return nil, fmt.Errorf("only map[string]interface{} and []map[string]interface{} is supported")
+}
+
+func (c *Converter) decodeObject(obj map[string]any, schema map[string]*ast.JsonStreamField, isOuter bool) (map[string]any, error) {
+	m, err := decodeMap(obj, schema)
+	if err != nil || !isOuter {
+		return m, err
+	}
+	for key, alias := range c.ColAliasMapping {
+		if v, ok := m[key]; ok {
+			delete(m, key)
+			m[alias] = v
+		}
+	}
+	return m, nil
+}
+
+// decodeValue checks and converts the value by the schema field. Decode the value as is if the field is nil.
+func decodeValue(name string, v any, field *ast.JsonStreamField) (any, error) {
+	switch vt := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		if field == nil {
+			return decodeMap(vt, nil)
+		}
+		if field.Type != "struct" {
+			return nil, wrongType(name, "object", field)
+		}
+		return decodeMap(vt, field.Properties)
+	case []any:
+		var items *ast.JsonStreamField
+		if field != nil {
+			if field.Type != "array" {
+				return nil, wrongType(name, "array", field)
+			}
+			items = field.Items
+		}
+		result := make([]any, len(vt))
+		for i, item := range vt {
+			r, err := decodeValue("array", item, items)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = r
+		}
+		return result, nil
+	case string:
+		if field == nil {
+			return vt, nil
+		}
+		switch field.Type {
+		case "string", "datetime":
+			return vt, nil
+		case "bytea":
+			return cast.ToByteA(vt, cast.CONVERT_ALL)
+		case "boolean":
+			return cast.ToBool(vt, cast.CONVERT_ALL)
+		}
+		return nil, wrongType(name, "string", field)
+	case []byte:
+		if field == nil {
+			return vt, nil
+		}
+		switch field.Type {
+		case "bytea":
+			return vt, nil
+		case "string":
+			return string(vt), nil
+		}
+		return nil, wrongType(name, "bytes", field)
+	case int64, float64:
+		if field == nil {
+			return vt, nil
+		}
+		switch field.Type {
+		case "float", "datetime":
+			return cast.ToFloat64(vt, cast.CONVERT_ALL)
+		case "bigint":
+			return cast.ToInt64(vt, cast.CONVERT_ALL)
+		case "string":
+			return cast.ToStringAlways(vt), nil
+		case "boolean":
+			return cast.ToBool(vt, cast.CONVERT_ALL)
+		}
+		return nil, wrongType(name, "number", field)
+	case bool:
+		if field == nil || field.Type == "boolean" {
+			return vt, nil
+		}
+		return nil, wrongType(name, "boolean", field)
+	default:
+		return v, nil
+	}
+}
+
+func decodeMap(obj map[string]any, schema map[string]*ast.JsonStreamField) (map[string]any, error) {
+	m := make(map[string]any, len(obj))
+	for key, v := range obj {
+		var field *ast.JsonStreamField
+		if schema != nil {
+			f, ok := schema[key]
+			if !ok {
+				continue
+			}
+			field = f
+		}
+		r, err := decodeValue(key, v, field)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = r
+	}
+	return m, nil
+}
+
+func wrongType(name string, typ string, field *ast.JsonStreamField) error {
+	return fmt.Errorf("%v has wrong type:%v, expect:%v", name, typ, field.Type)
+}
+
+// normalize converts the decoded msgpack values to the types of the internal data
+func normalize(v any) any {
+	switch vt := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(vt))
+		for k, mv := range vt {
+			m[cast.ToStringAlways(k)] = normalize(mv)
+		}
+		return m
+	case map[string]any:
+		for k, mv := range vt {
+			vt[k] = normalize(mv)
+		}
+		return vt
+	case []any:
+		for i, item := range vt {
+			vt[i] = normalize(item)
+		}
+		return vt
+	case uint64:
+		if vt <= math.MaxInt64 {
+			return int64(vt)
+		}
+		return vt
+	case float32:
+		return float64(vt)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package msgpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	c := NewConverter(nil, nil)
+	b, err := c.Encode(ctx, map[string]any{"b": "x", "a": 1})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0xa1, 'x'}, b)
+
+	input := map[string]any{
+		"a": int64(-3),
+		"b": 1.5,
+		"c": "hello",
+		"d": true,
+		"e": []byte{1, 2},
+		"f": []any{int64(1), "x", nil},
+		"g": map[string]any{"h": int64(2)},
+		"i": nil,
+	}
+	b, err = c.Encode(ctx, input)
+	require.NoError(t, err)
+	r, err := c.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, input, r)
+
+	b, err = c.Encode(ctx, []map[string]any{{"a": 1}, {"a": 2}})
+	require.NoError(t, err)
+	r, err = c.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"a": int64(1)}, {"a": int64(2)}}, r)
+
+	// {1: "x"} with integer key
+	r, err = c.Decode(ctx, []byte{0x81, 0x01, 0xa1, 'x'})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"1": "x"}, r)
+
+	_, err = c.Decode(ctx, []byte{0x01})
+	assert.EqualError(t, err, "only map[string]interface{} and []map[string]interface{} is supported")
+	_, err = c.Decode(ctx, []byte{0x81})
+	assert.ErrorContains(t, err, "fail to decode msgpack")
+}
+
+func TestDecodeWithSchema(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	schema := map[string]*ast.JsonStreamField{
+		"a": {Type: "bigint"},
+		"b": {Type: "string"},
+		"c": {Type: "struct", Properties: map[string]*ast.JsonStreamField{"d": {Type: "float"}}},
+		"e": {Type: "array", Items: &ast.JsonStreamField{Type: "bytea"}},
+		"f": nil,
+	}
+	enc := NewConverter(nil, nil)
+	b, err := enc.Encode(ctx, map[string]any{
+		"a": 1.0,
+		"b": 2,
+		"c": map[string]any{"d": 3, "x": 4},
+		"e": []any{[]byte{1}},
+		"f": []any{"any"},
+		"g": "ignored",
+	})
+	require.NoError(t, err)
+
+	c := NewConverter(schema, nil)
+	r, err := c.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"a": int64(1),
+		"b": "2",
+		"c": map[string]any{"d": 3.0},
+		"e": []any{[]byte{1}},
+		"f": []any{"any"},
+	}, r)
+
+	b, err = enc.Encode(ctx, map[string]any{"a": "wrong", "b": "x"})
+	require.NoError(t, err)
+	_, err = c.Decode(ctx, b)
+	assert.EqualError(t, err, "a has wrong type:string, expect:bigint")
+
+	// decode the defined fields without type check by the tolerant schema policy
+	c = NewConverter(schema, map[string]any{"schemaPolicy": ast.SchemaPolicyNullable})
+	r, err = c.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": "wrong", "b": "x"}, r)
+
+	c = NewConverter(nil, map[string]any{"colAliasMapping": map[string]string{"b": "bb"}})
+	r, err = c.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": "wrong", "bb": "x"}, r)
+
+	c.ResetSchema(map[string]*ast.JsonStreamField{"a": nil})
+	r, err = c.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": "wrong"}, r)
+}
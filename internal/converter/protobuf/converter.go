@@ -16,20 +16,30 @@ package protobuf
 
 import (
 	"fmt"
+	"io"
+	"sync"
 
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"            //nolint:staticcheck
 	"github.com/jhump/protoreflect/desc/protoparse" //nolint:staticcheck
 	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	kconf "github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/converter/static"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/message"
 )
 
 type Converter struct {
+	sync.RWMutex
 	descriptor *desc.MessageDescriptor
 	fc         *FieldConverter
+	// fields are the numbers of the top level fields used by the rule. Decode all fields if it is nil
+	fields map[int32]struct{}
 }
 
 var protoParser *protoparse.Parser
@@ -48,17 +58,62 @@ func NewConverter(schemaFile string, soFile string, messageName string) (message
 			return nil, fmt.Errorf("parse schema file %s failed: %s", schemaFile, err)
 		} else {
 			messageDescriptor := fds[0].FindMessage(messageName)
+			// the schemaId cannot contain the package, so find the message in the package of the file
+			if messageDescriptor == nil && fds[0].GetPackage() != "" {
+				messageDescriptor = fds[0].FindMessage(fds[0].GetPackage() + "." + messageName)
+			}
 			if messageDescriptor == nil {
 				return nil, fmt.Errorf("message type %s not found in schema file %s", messageName, schemaFile)
 			}
 			return &Converter{
 				descriptor: messageDescriptor,
-				fc:         GetFieldConverter(),
+				fc:         NewFieldConverter(fds...),
 			}, nil
 		}
 	}
 }
 
+// NewRemoteConverter creates the converter by the message type in the binary FileDescriptorSet fetched from the url
+// instead of the local proto file. The url can be http, https or file scheme.
+func NewRemoteConverter(descriptorSetUrl string, messageName string) (message.Converter, error) {
+	r, err := httpx.ReadFile(descriptorSetUrl)
+	if err != nil {
+		return nil, fmt.Errorf("fetch descriptor set %s failed: %v", descriptorSetUrl, err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("fetch descriptor set %s failed: %v", descriptorSetUrl, err)
+	}
+	return newConverterFromDescriptorSet(b, messageName)
+}
+
+func newConverterFromDescriptorSet(b []byte, messageName string) (message.Converter, error) {
+	set := &dpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %v", err)
+	}
+	fileMap, err := desc.CreateFileDescriptorsFromSet(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %v", err)
+	}
+	files := make([]*desc.FileDescriptor, 0, len(fileMap))
+	var messageDescriptor *desc.MessageDescriptor
+	for _, fd := range fileMap {
+		files = append(files, fd)
+		if md := fd.FindMessage(messageName); md != nil {
+			messageDescriptor = md
+		}
+	}
+	if messageDescriptor == nil {
+		return nil, fmt.Errorf("message type %s not found in the descriptor set", messageName)
+	}
+	return &Converter{
+		descriptor: messageDescriptor,
+		fc:         NewFieldConverter(files...),
+	}, nil
+}
+
 func (c *Converter) Encode(ctx api.StreamContext, d any) (b []byte, err error) {
 	defer func() {
 		if err != nil {
@@ -83,10 +138,55 @@ func (c *Converter) Decode(ctx api.StreamContext, b []byte) (m any, err error) {
 			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
 		}
 	}()
+	c.RLock()
+	fields := c.fields
+	c.RUnlock()
+	if fields != nil {
+		b, err = project(b, fields)
+		if err != nil {
+			return nil, err
+		}
+	}
 	result := mf.NewDynamicMessage(c.descriptor)
 	err = result.Unmarshal(b)
 	if err != nil {
 		return nil, err
 	}
-	return c.fc.DecodeMessage(result, c.descriptor), nil
+	return c.fc.decodeMessage(result, c.descriptor, fields), nil
+}
+
+// ResetSchema sets the fields used by the rule so that only these fields are decoded. Decode all fields if the schema is nil.
+func (c *Converter) ResetSchema(schema map[string]*ast.JsonStreamField) {
+	var fields map[int32]struct{}
+	if schema != nil {
+		fields = make(map[int32]struct{}, len(schema))
+		for name := range schema {
+			if fd := c.descriptor.FindFieldByName(name); fd != nil {
+				fields[fd.GetNumber()] = struct{}{}
+			}
+		}
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.fields = fields
+}
+
+// project drops the top level fields which are not used without parsing them
+func project(b []byte, fields map[int32]struct{}) ([]byte, error) {
+	out := make([]byte, 0, len(b))
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return nil, protowire.ParseError(m)
+		}
+		if _, ok := fields[int32(num)]; ok {
+			out = append(out, b[:n+m]...)
+		}
+		b = b[n+m:]
+	}
+	return out, nil
 }
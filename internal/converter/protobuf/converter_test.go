@@ -16,14 +16,20 @@ package protobuf
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
+	"github.com/golang/protobuf/proto"   //nolint:staticcheck
+	"github.com/jhump/protoreflect/desc" //nolint:staticcheck
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/v2/internal/testx"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
 	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
 )
 
@@ -135,6 +141,41 @@ func TestEmbedType(t *testing.T) {
 	}
 }
 
+func TestDecodeProjection(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	c, err := NewConverter("../../schema/test/test3.proto", "", "DrivingData")
+	require.NoError(t, err)
+	payload := []byte{0x08, 0x01, 0x11, 0xa4, 0x70, 0x3d, 0x0a, 0xd7, 0xa3, 0x56, 0x40, 0x1a, 0x02, 0x08, 0x00, 0x20, 0x01, 0x20, 0x02, 0x20, 0x03}
+	c.(*Converter).ResetSchema(map[string]*ast.JsonStreamField{
+		"average_speed": nil,
+		"brk_pedal_sts": nil,
+		"notexist":      nil,
+	})
+	m, err := c.Decode(ctx, payload)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"brk_pedal_sts": map[string]any{
+			"valid": int64(0),
+		},
+		"average_speed": 90.56,
+	}, m)
+	// The unused message field is not parsed
+	broken := []byte{0x08, 0x01, 0x11, 0xa4, 0x70, 0x3d, 0x0a, 0xd7, 0xa3, 0x56, 0x40, 0x1a, 0x02, 0xff, 0xff}
+	c.(*Converter).ResetSchema(map[string]*ast.JsonStreamField{
+		"average_speed": nil,
+	})
+	m, err = c.Decode(ctx, broken)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"average_speed": 90.56}, m)
+	// Reset to decode all
+	c.(*Converter).ResetSchema(nil)
+	_, err = c.Decode(ctx, broken)
+	require.Error(t, err)
+	m, err = c.Decode(ctx, payload)
+	require.NoError(t, err)
+	require.Len(t, m, 4)
+}
+
 func TestDecode(t *testing.T) {
 	ctx := mockContext.NewMockContext("test", "op1")
 	c, err := NewConverter("../../schema/test/test1.proto", "", "Person")
@@ -300,3 +341,75 @@ func TestErr(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, errorx.CovnerterErr, errWithCode.Code())
 }
+
+func TestOneOfAndAny(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "op1")
+	local, err := NewConverter("../../schema/test/test6.proto", "", "Event")
+	require.NoError(t, err)
+	// create the converter by the descriptor set file of the same proto
+	fds, err := protoParser.ParseFiles("../../schema/test/test6.proto")
+	require.NoError(t, err)
+	b, err := proto.Marshal(desc.ToFileDescriptorSet(fds...))
+	require.NoError(t, err)
+	p := filepath.Join(t.TempDir(), "test6.desc")
+	require.NoError(t, os.WriteFile(p, b, 0o644))
+	remote, err := NewRemoteConverter("file://"+p, "test.Event")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		m    map[string]any
+		r    map[string]any
+	}{
+		{
+			name: "any",
+			m: map[string]any{
+				"id":      "e1",
+				"payload": map[string]any{"@type": "type.googleapis.com/test.Reading", "temperature": 20.5, "unit": "C"},
+				"num":     3,
+			},
+			r: map[string]any{
+				"id":      "e1",
+				"payload": map[string]any{"@type": "type.googleapis.com/test.Reading", "temperature": 20.5, "unit": "C"},
+				"num":     int64(3),
+			},
+		},
+		{
+			name: "unknown any",
+			m: map[string]any{
+				"id":      "e2",
+				"payload": map[string]any{"@type": "type.googleapis.com/test.Unknown", "value": []byte{0x08, 0x01}},
+				"num":     nil,
+				"text":    "hello",
+			},
+			r: map[string]any{
+				"id":      "e2",
+				"payload": map[string]any{"@type": "type.googleapis.com/test.Unknown", "value": []byte{0x08, 0x01}},
+				"text":    "hello",
+			},
+		},
+	}
+	for _, c := range []struct {
+		name string
+		c    message.Converter
+	}{{name: "local", c: local}, {name: "remote", c: remote}} {
+		for _, tt := range tests {
+			t.Run(c.name+" "+tt.name, func(t *testing.T) {
+				b, err := c.c.Encode(ctx, tt.m)
+				require.NoError(t, err)
+				m, err := c.c.Decode(ctx, b)
+				require.NoError(t, err)
+				assert.Equal(t, tt.r, m)
+			})
+		}
+	}
+
+	_, err = local.Encode(ctx, map[string]any{"id": "e3", "num": 1, "text": "a"})
+	assert.EqualError(t, err, "oneof value can only set one field but got num and text")
+	_, err = local.Encode(ctx, map[string]any{"payload": map[string]any{"temperature": 1}})
+	assert.EqualError(t, err, "invalid value for any type field 'payload': @type is required")
+	_, err = local.Encode(ctx, map[string]any{"payload": map[string]any{"@type": "test.Unknown"}})
+	assert.EqualError(t, err, "invalid value for any type field 'payload': cannot find message type test.Unknown")
+	_, err = NewRemoteConverter("file://"+p, "test.None")
+	assert.EqualError(t, err, "message type test.None not found in the descriptor set")
+}
@@ -17,6 +17,7 @@ package protobuf
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	// TODO: replace with `google.golang.org/protobuf/proto` pkg.
 	"github.com/golang/protobuf/proto" //nolint:staticcheck
@@ -39,6 +40,9 @@ const (
 	WrapperUInt32 = "google.protobuf.UInt32Value"
 	WrapperUInt64 = "google.protobuf.UInt64Value"
 	WrapperVoid   = "google.protobuf.EMPTY"
+	// AnyType is decoded to a map of the packed message fields with the type url in AnyTypeKey
+	AnyType    = "google.protobuf.Any"
+	AnyTypeKey = "@type"
 )
 
 var WRAPPER_TYPES = map[string]struct{}{
@@ -58,16 +62,66 @@ var (
 	mf                = dynamic.NewMessageFactoryWithDefaults()
 )
 
-type FieldConverter struct{}
+type FieldConverter struct {
+	// types are the message types to pack and unpack google.protobuf.Any by the full name
+	types map[string]*desc.MessageDescriptor
+}
 
 func GetFieldConverter() *FieldConverter {
 	return fieldConverterIns
 }
 
+// NewFieldConverter creates the field converter which resolves the types of Any from the files and their dependencies
+func NewFieldConverter(files ...*desc.FileDescriptor) *FieldConverter {
+	fc := &FieldConverter{types: make(map[string]*desc.MessageDescriptor)}
+	visited := make(map[string]bool)
+	var addFile func(fd *desc.FileDescriptor)
+	var addMessage func(md *desc.MessageDescriptor)
+	addMessage = func(md *desc.MessageDescriptor) {
+		fc.types[md.GetFullyQualifiedName()] = md
+		for _, nested := range md.GetNestedMessageTypes() {
+			addMessage(nested)
+		}
+	}
+	addFile = func(fd *desc.FileDescriptor) {
+		if visited[fd.GetName()] {
+			return
+		}
+		visited[fd.GetName()] = true
+		for _, md := range fd.GetMessageTypes() {
+			addMessage(md)
+		}
+		for _, dep := range fd.GetDependencies() {
+			addFile(dep)
+		}
+	}
+	for _, fd := range files {
+		addFile(fd)
+	}
+	return fc
+}
+
+// findMessageType finds the message type by the full name or the type url of Any. The types linked into the
+// binary like the well-known types are also found.
+func (fc *FieldConverter) findMessageType(name string) *desc.MessageDescriptor {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if md, ok := fc.types[name]; ok {
+		return md
+	}
+	md, err := desc.LoadMessageDescriptor(name)
+	if err != nil {
+		return nil
+	}
+	return md
+}
+
 func (fc *FieldConverter) encodeMap(im *desc.MessageDescriptor, i interface{}) (*dynamic.Message, error) {
 	result := mf.NewDynamicMessage(im)
 	fields := im.GetFields()
 	if m, ok := i.(map[string]interface{}); ok {
+		var oneOfSet map[string]string
 		for _, field := range fields {
 			v, ok := m[field.GetName()]
 			if !ok {
@@ -77,6 +131,19 @@ func (fc *FieldConverter) encodeMap(im *desc.MessageDescriptor, i interface{}) (
 					continue
 				}
 			}
+			if oneOf := field.GetOneOf(); oneOf != nil {
+				// null means the member is not set
+				if v == nil {
+					continue
+				}
+				if oneOfSet == nil {
+					oneOfSet = make(map[string]string)
+				}
+				if other, ok := oneOfSet[oneOf.GetName()]; ok {
+					return nil, fmt.Errorf("oneof %s can only set one field but got %s and %s", oneOf.GetName(), other, field.GetName())
+				}
+				oneOfSet[oneOf.GetName()] = field.GetName()
+			}
 			fv, err := fc.EncodeField(field, v)
 			if err != nil {
 				return nil, err
@@ -143,12 +210,7 @@ func (fc *FieldConverter) EncodeField(field *desc.FieldDescriptor, v interface{}
 			result, err = cast.ToBytesSlice(v, cast.CONVERT_SAMEKIND)
 		case dpb.FieldDescriptorProto_TYPE_MESSAGE:
 			result, err = cast.ToTypedSlice(v, func(input interface{}, sn cast.Strictness) (interface{}, error) {
-				r, err := cast.ToStringMap(input)
-				if err == nil {
-					return fc.encodeMap(field.GetMessageType(), r)
-				} else {
-					return nil, fmt.Errorf("invalid type for map type field '%s': %v", fn, err)
-				}
+				return fc.encodeMessage(field, input)
 			}, "map", cast.CONVERT_SAMEKIND)
 		default:
 			return nil, fmt.Errorf("invalid type for field '%s'", fn)
@@ -235,17 +297,55 @@ func (fc *FieldConverter) encodeSingleField(field *desc.FieldDescriptor, v inter
 			return nil, fmt.Errorf("invalid type for bytes type field '%s': %v", fn, err)
 		}
 	case dpb.FieldDescriptorProto_TYPE_MESSAGE:
-		r, err := cast.ToStringMap(v)
-		if err == nil {
-			return fc.encodeMap(field.GetMessageType(), r)
-		} else {
-			return nil, fmt.Errorf("invalid type for map type field '%s': %v", fn, err)
-		}
+		return fc.encodeMessage(field, v)
 	default:
 		return nil, fmt.Errorf("invalid type for field '%s'", fn)
 	}
 }
 
+func (fc *FieldConverter) encodeMessage(field *desc.FieldDescriptor, v interface{}) (interface{}, error) {
+	fn := field.GetName()
+	r, err := cast.ToStringMap(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type for map type field '%s': %v", fn, err)
+	}
+	if field.GetMessageType().GetFullyQualifiedName() == AnyType {
+		return fc.encodeAny(field, r)
+	}
+	return fc.encodeMap(field.GetMessageType(), r)
+}
+
+// encodeAny packs the map into Any by the message type of the type url in the @type key. If the type is unknown,
+// the map must have the packed bytes in the value key.
+func (fc *FieldConverter) encodeAny(field *desc.FieldDescriptor, m map[string]interface{}) (interface{}, error) {
+	fn := field.GetName()
+	typeUrl, ok := m[AnyTypeKey].(string)
+	if !ok || typeUrl == "" {
+		return nil, fmt.Errorf("invalid value for any type field '%s': %s is required", fn, AnyTypeKey)
+	}
+	var value []byte
+	if md := fc.findMessageType(typeUrl); md != nil {
+		inner, err := fc.encodeMap(md, m)
+		if err != nil {
+			return nil, err
+		}
+		value, err = inner.Marshal()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		b, ok := m["value"].([]byte)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for any type field '%s': cannot find message type %s", fn, typeUrl)
+		}
+		value = b
+	}
+	result := mf.NewDynamicMessage(field.GetMessageType())
+	result.SetFieldByName("type_url", typeUrl)
+	result.SetFieldByName("value", value)
+	return result, nil
+}
+
 func (fc *FieldConverter) DecodeField(src interface{}, field *desc.FieldDescriptor, sn cast.Strictness) (interface{}, error) {
 	var (
 		r interface{}
@@ -346,15 +446,27 @@ func (fc *FieldConverter) decodeMessageField(src interface{}, field *desc.FieldD
 }
 
 func (fc *FieldConverter) DecodeMessage(message *dynamic.Message, outputType *desc.MessageDescriptor) interface{} {
+	return fc.decodeMessage(message, outputType, nil)
+}
+
+// decodeMessage only decodes the fields whose number is in fields. Decode all fields if fields is nil
+func (fc *FieldConverter) decodeMessage(message *dynamic.Message, outputType *desc.MessageDescriptor, fields map[int32]struct{}) interface{} {
 	if _, ok := WRAPPER_TYPES[outputType.GetFullyQualifiedName()]; ok {
 		return message.GetFieldByNumber(1)
 	} else if WrapperVoid == outputType.GetFullyQualifiedName() {
 		return nil
 	} else if message == nil {
 		return nil
+	} else if AnyType == outputType.GetFullyQualifiedName() {
+		return fc.decodeAny(message)
 	}
 	result := make(map[string]interface{})
 	for _, field := range outputType.GetFields() {
+		if fields != nil {
+			if _, ok := fields[field.GetNumber()]; !ok {
+				continue
+			}
+		}
 		if oneOf := field.GetOneOf(); oneOf != nil {
 			fd, v, err := message.TryGetOneOfField(oneOf)
 			if err != nil {
@@ -369,3 +481,22 @@ func (fc *FieldConverter) DecodeMessage(message *dynamic.Message, outputType *de
 	}
 	return result
 }
+
+// decodeAny unpacks the Any message to the map of the packed message with the type url. If the type is unknown,
+// the packed bytes are kept in the value key.
+func (fc *FieldConverter) decodeAny(message *dynamic.Message) interface{} {
+	typeUrl, _ := message.GetFieldByName("type_url").(string)
+	value, _ := message.GetFieldByName("value").([]byte)
+	if md := fc.findMessageType(typeUrl); md != nil {
+		inner := mf.NewDynamicMessage(md)
+		if err := inner.Unmarshal(value); err == nil {
+			if m, ok := fc.DecodeMessage(inner, md).(map[string]interface{}); ok {
+				m[AnyTypeKey] = typeUrl
+				return m
+			}
+		} else {
+			conf.Log.Warnf("cannot unpack any of type %s: %v", typeUrl, err)
+		}
+	}
+	return map[string]interface{}{AnyTypeKey: typeUrl, "value": value}
+}
@@ -0,0 +1,364 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sparkplug
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const (
+	KeyTimestamp   = "timestamp"
+	KeySeq         = "seq"
+	KeyUuid        = "uuid"
+	KeyBody        = "body"
+	KeyMetrics     = "metrics"
+	KeyGroupId     = "groupId"
+	KeyMessageType = "messageType"
+	KeyEdgeNodeId  = "edgeNodeId"
+	KeyDeviceId    = "deviceId"
+
+	KeyName         = "name"
+	KeyAlias        = "alias"
+	KeyDatatype     = "datatype"
+	KeyValue        = "value"
+	KeyIsHistorical = "isHistorical"
+	KeyIsTransient  = "isTransient"
+)
+
+// Converter decodes and encodes the Sparkplug B payload. When decoding, the metric aliases are resolved by the
+// birth certificates received before. The topic of the message is read from the metadata if available.
+type Converter struct {
+	// Flatten puts the metric values in the top level of the decoded map by the metric names
+	Flatten bool `json:"flatten"`
+
+	state *State
+	sync.Mutex
+	// the sequence number of the next encoded payload if not specified
+	seq uint64
+}
+
+func NewConverter(props map[string]any) (message.Converter, error) {
+	c := &Converter{state: NewState()}
+	err := cast.MapToStruct(props, c)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Converter) Decode(ctx api.StreamContext, b []byte) (any, error) {
+	return c.DecodeWithMeta(ctx, b, nil)
+}
+
+// DecodeWithMeta decodes the payload with the topic in the metadata to maintain the state of the edge node
+func (c *Converter) DecodeWithMeta(ctx api.StreamContext, b []byte, meta map[string]any) (_ any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	var t *Topic
+	if tpc, ok := meta["topic"].(string); ok {
+		t, err = ParseTopic(tpc)
+		if err != nil {
+			ctx.GetLogger().Debugf("ignore the topic: %v", err)
+			t, err = nil, nil
+		} else if t.MessageType == STATE {
+			return nil, fmt.Errorf("STATE message of topic %s is not a sparkplug b payload", tpc)
+		}
+	}
+	p, err := UnmarshalPayload(b)
+	if err != nil {
+		return nil, err
+	}
+	gap, err := c.state.Apply(t, p)
+	if err != nil {
+		return nil, err
+	}
+	if gap {
+		ctx.GetLogger().Warnf("sparkplug sequence number %d is not continuous, a rebirth of the edge node is required", p.Seq)
+	}
+	result := make(map[string]any)
+	if p.HasTimestamp {
+		result[KeyTimestamp] = int64(p.Timestamp)
+	}
+	if p.HasSeq {
+		result[KeySeq] = int64(p.Seq)
+	}
+	if p.Uuid != "" {
+		result[KeyUuid] = p.Uuid
+	}
+	if len(p.Body) > 0 {
+		result[KeyBody] = p.Body
+	}
+	if t != nil {
+		result[KeyGroupId] = t.GroupId
+		result[KeyMessageType] = t.MessageType
+		result[KeyEdgeNodeId] = t.EdgeNodeId
+		if t.DeviceId != "" {
+			result[KeyDeviceId] = t.DeviceId
+		}
+	}
+	metrics := make([]any, 0, len(p.Metrics))
+	for _, m := range p.Metrics {
+		v, err := toValue(m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of metric %s: %v", m.Name, err)
+		}
+		if c.Flatten {
+			result[m.Name] = v
+			continue
+		}
+		mm := map[string]any{
+			KeyName:     m.Name,
+			KeyDatatype: m.Datatype.String(),
+			KeyValue:    v,
+		}
+		if m.HasAlias {
+			mm[KeyAlias] = int64(m.Alias)
+		}
+		if m.HasTimestamp {
+			mm[KeyTimestamp] = int64(m.Timestamp)
+		}
+		if m.IsHistorical {
+			mm[KeyIsHistorical] = true
+		}
+		if m.IsTransient {
+			mm[KeyIsTransient] = true
+		}
+		metrics = append(metrics, mm)
+	}
+	if !c.Flatten {
+		result[KeyMetrics] = metrics
+	}
+	return result, nil
+}
+
+// Encode encodes a map to the payload. If the map has the metrics list, each item is a map of the metric.
+// Otherwise, the keys of the map except timestamp, seq and uuid are encoded as the metrics by name.
+// The timestamp defaults to now and the seq defaults to the next sequence number of this converter.
+func (c *Converter) Encode(_ api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	m, ok := d.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %v, must be a map", d)
+	}
+	p := &Payload{HasTimestamp: true, HasSeq: true}
+	if v, ok := m[KeyTimestamp]; ok {
+		ts, err := cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %v", v)
+		}
+		p.Timestamp = uint64(ts)
+	} else {
+		p.Timestamp = uint64(timex.GetNowInMilli())
+	}
+	if v, ok := m[KeySeq]; ok {
+		seq, err := cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		if err != nil || seq < 0 || seq > 255 {
+			return nil, fmt.Errorf("invalid seq %v, must be an integer between 0 and 255", v)
+		}
+		p.Seq = uint64(seq)
+	} else {
+		c.Lock()
+		p.Seq = c.seq
+		c.seq = (c.seq + 1) % 256
+		c.Unlock()
+	}
+	if v, ok := m[KeyUuid]; ok {
+		p.Uuid, _ = cast.ToString(v, cast.CONVERT_ALL)
+	}
+	if v, ok := m[KeyMetrics]; ok {
+		var items []map[string]any
+		switch vt := v.(type) {
+		case []map[string]any:
+			items = vt
+		case []any:
+			for _, item := range vt {
+				mm, ok := item.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("metric %v must be a map", item)
+				}
+				items = append(items, mm)
+			}
+		default:
+			return nil, fmt.Errorf("metrics %v must be a list of maps", v)
+		}
+		for _, item := range items {
+			metric, err := toMetric(item)
+			if err != nil {
+				return nil, err
+			}
+			p.Metrics = append(p.Metrics, metric)
+		}
+	} else {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			if k != KeyTimestamp && k != KeySeq && k != KeyUuid {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			metric := &Metric{Name: k}
+			if err := setValue(metric, m[k]); err != nil {
+				return nil, fmt.Errorf("invalid value of metric %s: %v", k, err)
+			}
+			p.Metrics = append(p.Metrics, metric)
+		}
+	}
+	return p.Marshal(), nil
+}
+
+func toMetric(item map[string]any) (*Metric, error) {
+	metric := &Metric{}
+	if v, ok := item[KeyName]; ok {
+		metric.Name, _ = cast.ToString(v, cast.CONVERT_ALL)
+	}
+	if v, ok := item[KeyAlias]; ok {
+		alias, err := cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		if err != nil || alias < 0 {
+			return nil, fmt.Errorf("invalid alias %v of metric %s", v, metric.Name)
+		}
+		metric.Alias, metric.HasAlias = uint64(alias), true
+	}
+	if metric.Name == "" && !metric.HasAlias {
+		return nil, fmt.Errorf("metric %v must have a name or an alias", item)
+	}
+	if v, ok := item[KeyTimestamp]; ok {
+		ts, err := cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %v of metric %s", v, metric.Name)
+		}
+		metric.Timestamp, metric.HasTimestamp = uint64(ts), true
+	}
+	if v, ok := item[KeyDatatype]; ok {
+		dt, err := ParseDataType(v)
+		if err != nil {
+			return nil, err
+		}
+		metric.Datatype = dt
+	}
+	metric.IsHistorical, _ = item[KeyIsHistorical].(bool)
+	metric.IsTransient, _ = item[KeyIsTransient].(bool)
+	if err := setValue(metric, item[KeyValue]); err != nil {
+		return nil, fmt.Errorf("invalid value of metric %s: %v", metric.Name, err)
+	}
+	return metric, nil
+}
+
+// setValue sets the raw value of the metric by its data type. If the data type is not set, it is inferred by the value.
+func setValue(m *Metric, v any) error {
+	if v == nil {
+		m.IsNull = true
+		return nil
+	}
+	if m.Datatype == Unknown {
+		switch v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			m.Datatype = Int64
+		case float32:
+			m.Datatype = Float
+		case float64:
+			m.Datatype = Double
+		case bool:
+			m.Datatype = Boolean
+		case string:
+			m.Datatype = String
+		case []byte:
+			m.Datatype = Bytes
+		case time.Time:
+			m.Datatype = DateTime
+		default:
+			return fmt.Errorf("unsupported type %T", v)
+		}
+	}
+	var err error
+	switch m.Datatype {
+	case Int8, Int16, Int32, UInt8, UInt16, UInt32:
+		var i int64
+		i, err = cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		m.Value = uint32(i)
+	case Int64, UInt64:
+		var i int64
+		i, err = cast.ToInt64(v, cast.CONVERT_SAMEKIND)
+		m.Value = uint64(i)
+	case DateTime:
+		var i int64
+		i, err = cast.InterfaceToUnixMilli(v, "")
+		m.Value = uint64(i)
+	case Float:
+		var f float64
+		f, err = cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+		m.Value = float32(f)
+	case Double:
+		m.Value, err = cast.ToFloat64(v, cast.CONVERT_SAMEKIND)
+	case Boolean:
+		m.Value, err = cast.ToBool(v, cast.CONVERT_SAMEKIND)
+	case String, Text, UUID:
+		m.Value, err = cast.ToString(v, cast.CONVERT_SAMEKIND)
+	case Bytes, File:
+		m.Value, err = cast.ToByteA(v, cast.CONVERT_SAMEKIND)
+	default:
+		err = fmt.Errorf("unsupported datatype %s", m.Datatype)
+	}
+	return err
+}
+
+// toValue converts the raw value of the metric to the value by its data type
+func toValue(m *Metric) (any, error) {
+	if m.IsNull || m.Value == nil {
+		return nil, nil
+	}
+	var u uint64
+	switch v := m.Value.(type) {
+	case uint32:
+		u = uint64(v)
+	case uint64:
+		u = v
+	case float32:
+		return float64(v), nil
+	default:
+		return v, nil
+	}
+	switch m.Datatype {
+	case Int8:
+		return int64(int8(u)), nil
+	case Int16:
+		return int64(int16(u)), nil
+	case Int32:
+		return int64(int32(u)), nil
+	case UInt64:
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("unsigned integer %d overflows bigint", u)
+		}
+	}
+	return int64(u), nil
+}
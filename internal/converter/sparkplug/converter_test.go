@@ -0,0 +1,164 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sparkplug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+func TestBirthAndData(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewConverter(nil)
+	require.NoError(t, err)
+	md := c.(message.MetaDecoder)
+	birth := (&Payload{
+		Timestamp: 1700000000000, HasTimestamp: true, Seq: 0, HasSeq: true,
+		Metrics: []*Metric{
+			{Name: BdSeqMetric, Datatype: Int64, Value: uint64(3)},
+			{Name: "temperature", Alias: 1, HasAlias: true, Datatype: Float, Value: float32(20.5)},
+			{Name: "offset", Alias: 2, HasAlias: true, Datatype: Int16, Value: uint32(0xFFFE)},
+		},
+	}).Marshal()
+	r, err := md.DecodeWithMeta(ctx, birth, map[string]any{"topic": "spBv1.0/factory/NBIRTH/edge1"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"timestamp":   int64(1700000000000),
+		"seq":         int64(0),
+		"groupId":     "factory",
+		"messageType": "NBIRTH",
+		"edgeNodeId":  "edge1",
+		"metrics": []any{
+			map[string]any{"name": "bdSeq", "datatype": "Int64", "value": int64(3)},
+			map[string]any{"name": "temperature", "alias": int64(1), "datatype": "Float", "value": 20.5},
+			map[string]any{"name": "offset", "alias": int64(2), "datatype": "Int16", "value": int64(-2)},
+		},
+	}, r)
+	require.True(t, c.(*Converter).state.IsOnline("factory", "edge1"))
+
+	// The data message only has the aliases
+	data := (&Payload{
+		Timestamp: 1700000001000, HasTimestamp: true, Seq: 1, HasSeq: true,
+		Metrics: []*Metric{
+			{Alias: 1, HasAlias: true, Value: float32(21.5)},
+			{Alias: 2, HasAlias: true, IsNull: true},
+		},
+	}).Marshal()
+	fc, err := NewConverter(map[string]any{"flatten": true})
+	require.NoError(t, err)
+	fmd := fc.(message.MetaDecoder)
+	_, err = fmd.DecodeWithMeta(ctx, data, map[string]any{"topic": "spBv1.0/factory/NDATA/edge1"})
+	require.EqualError(t, err, "unknown metric alias 1 of edge node edge1, waiting for the birth certificate")
+	_, err = fmd.DecodeWithMeta(ctx, birth, map[string]any{"topic": "spBv1.0/factory/NBIRTH/edge1"})
+	require.NoError(t, err)
+	r, err = fmd.DecodeWithMeta(ctx, data, map[string]any{"topic": "spBv1.0/factory/NDATA/edge1"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"timestamp":   int64(1700000001000),
+		"seq":         int64(1),
+		"groupId":     "factory",
+		"messageType": "NDATA",
+		"edgeNodeId":  "edge1",
+		"temperature": 21.5,
+		"offset":      nil,
+	}, r)
+
+	// A stale death certificate is ignored
+	death := func(bdSeq uint64) []byte {
+		return (&Payload{Metrics: []*Metric{{Name: BdSeqMetric, Datatype: Int64, Value: bdSeq}}}).Marshal()
+	}
+	_, err = fmd.DecodeWithMeta(ctx, death(2), map[string]any{"topic": "spBv1.0/factory/NDEATH/edge1"})
+	require.NoError(t, err)
+	require.True(t, fc.(*Converter).state.IsOnline("factory", "edge1"))
+	_, err = fmd.DecodeWithMeta(ctx, death(3), map[string]any{"topic": "spBv1.0/factory/NDEATH/edge1"})
+	require.NoError(t, err)
+	require.False(t, fc.(*Converter).state.IsOnline("factory", "edge1"))
+
+	_, err = fmd.DecodeWithMeta(ctx, []byte(`{"online":true}`), map[string]any{"topic": "spBv1.0/STATE/host1"})
+	require.EqualError(t, err, "STATE message of topic spBv1.0/STATE/host1 is not a sparkplug b payload")
+}
+
+func TestSeqGap(t *testing.T) {
+	s := NewState()
+	tp := &Topic{GroupId: "g", MessageType: NBIRTH, EdgeNodeId: "e"}
+	gap, err := s.Apply(tp, &Payload{Seq: 0, HasSeq: true})
+	require.NoError(t, err)
+	require.False(t, gap)
+	tp.MessageType = DDATA
+	tp.DeviceId = "d"
+	for i := 1; i < 256; i++ {
+		gap, err = s.Apply(tp, &Payload{Seq: uint64(i), HasSeq: true})
+		require.NoError(t, err)
+		require.False(t, gap)
+	}
+	// wrap
+	gap, err = s.Apply(tp, &Payload{Seq: 0, HasSeq: true})
+	require.NoError(t, err)
+	require.False(t, gap)
+	gap, err = s.Apply(tp, &Payload{Seq: 2, HasSeq: true})
+	require.NoError(t, err)
+	require.True(t, gap)
+}
+
+func TestEncode(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewConverter(nil)
+	require.NoError(t, err)
+	b, err := c.Encode(ctx, map[string]any{
+		"timestamp": int64(1700000000000),
+		"metrics": []any{
+			map[string]any{"name": "temperature", "alias": 1, "datatype": "Float", "value": 20.5},
+			map[string]any{"name": "count", "datatype": "Int32", "value": int64(-5)},
+			map[string]any{"name": "status", "value": "ok", "isTransient": true},
+		},
+	})
+	require.NoError(t, err)
+	r, err := c.Decode(ctx, b)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"timestamp": int64(1700000000000),
+		"seq":       int64(0),
+		"metrics": []any{
+			map[string]any{"name": "temperature", "alias": int64(1), "datatype": "Float", "value": 20.5},
+			map[string]any{"name": "count", "datatype": "Int32", "value": int64(-5)},
+			map[string]any{"name": "status", "datatype": "String", "value": "ok", "isTransient": true},
+		},
+	}, r)
+
+	// flat map with auto sequence number
+	b, err = c.Encode(ctx, map[string]any{"timestamp": int64(1700000000000), "a": 1, "b": true, "c": nil})
+	require.NoError(t, err)
+	p, err := UnmarshalPayload(b)
+	require.NoError(t, err)
+	require.Equal(t, &Payload{
+		Timestamp: 1700000000000, HasTimestamp: true, Seq: 1, HasSeq: true,
+		Metrics: []*Metric{
+			{Name: "a", Datatype: Int64, Value: uint64(1)},
+			{Name: "b", Datatype: Boolean, Value: true},
+			{Name: "c", IsNull: true},
+		},
+	}, p)
+
+	_, err = c.Encode(ctx, map[string]any{"seq": 256, "a": 1})
+	require.EqualError(t, err, "invalid seq 256, must be an integer between 0 and 255")
+	_, err = c.Encode(ctx, map[string]any{"metrics": []any{map[string]any{"value": 1}}})
+	require.EqualError(t, err, "metric map[value:1] must have a name or an alias")
+	_, err = c.Decode(ctx, []byte{0xff})
+	require.Error(t, err)
+}
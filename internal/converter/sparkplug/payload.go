@@ -0,0 +1,330 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sparkplug
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DataType is the Sparkplug B metric data type
+type DataType uint32
+
+const (
+	Unknown DataType = iota
+	Int8
+	Int16
+	Int32
+	Int64
+	UInt8
+	UInt16
+	UInt32
+	UInt64
+	Float
+	Double
+	Boolean
+	String
+	DateTime
+	Text
+	UUID
+	DataSet
+	Bytes
+	File
+	Template
+)
+
+var dataTypeNames = []string{"Unknown", "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32", "UInt64", "Float", "Double", "Boolean", "String", "DateTime", "Text", "UUID", "DataSet", "Bytes", "File", "Template"}
+
+func (t DataType) String() string {
+	if int(t) < len(dataTypeNames) {
+		return dataTypeNames[t]
+	}
+	return fmt.Sprintf("DataType(%d)", uint32(t))
+}
+
+// ParseDataType parses the data type from its name or number
+func ParseDataType(v any) (DataType, error) {
+	switch vt := v.(type) {
+	case string:
+		for i, n := range dataTypeNames {
+			if strings.EqualFold(n, vt) {
+				return DataType(i), nil
+			}
+		}
+	case int:
+		return DataType(vt), nil
+	case int64:
+		return DataType(vt), nil
+	case float64:
+		return DataType(vt), nil
+	}
+	return Unknown, fmt.Errorf("invalid datatype %v", v)
+}
+
+// Metric is a Sparkplug B metric. Value is the raw value of the wire type such as uint32 for int_value.
+// The complex values such as DataSet and Template are not supported and are left nil.
+type Metric struct {
+	Name         string
+	Alias        uint64
+	HasAlias     bool
+	Timestamp    uint64
+	HasTimestamp bool
+	Datatype     DataType
+	IsHistorical bool
+	IsTransient  bool
+	IsNull       bool
+	Value        any
+}
+
+// Payload is the Sparkplug B payload
+type Payload struct {
+	Timestamp    uint64
+	HasTimestamp bool
+	Seq          uint64
+	HasSeq       bool
+	Uuid         string
+	Body         []byte
+	Metrics      []*Metric
+}
+
+// The field numbers defined in sparkplug_b.proto
+const (
+	payloadTimestamp = 1
+	payloadMetrics   = 2
+	payloadSeq       = 3
+	payloadUuid      = 4
+	payloadBody      = 5
+
+	metricName         = 1
+	metricAlias        = 2
+	metricTimestamp    = 3
+	metricDatatype     = 4
+	metricIsHistorical = 5
+	metricIsTransient  = 6
+	metricIsNull       = 7
+	metricIntValue     = 10
+	metricLongValue    = 11
+	metricFloatValue   = 12
+	metricDoubleValue  = 13
+	metricBooleanValue = 14
+	metricStringValue  = 15
+	metricBytesValue   = 16
+)
+
+// UnmarshalPayload parses the protobuf encoded Sparkplug B payload
+func UnmarshalPayload(b []byte) (*Payload, error) {
+	p := &Payload{}
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == payloadTimestamp && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			p.Timestamp, p.HasTimestamp = v, true
+			return n, nil
+		case num == payloadMetrics && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			m, err := unmarshalMetric(v)
+			if err != nil {
+				return 0, err
+			}
+			p.Metrics = append(p.Metrics, m)
+			return n, nil
+		case num == payloadSeq && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			p.Seq, p.HasSeq = v, true
+			return n, nil
+		case num == payloadUuid && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			p.Uuid = string(v)
+			return n, nil
+		case num == payloadBody && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			p.Body = append([]byte(nil), v...)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func unmarshalMetric(b []byte) (*Metric, error) {
+	m := &Metric{}
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			switch num {
+			case metricAlias:
+				m.Alias, m.HasAlias = v, true
+			case metricTimestamp:
+				m.Timestamp, m.HasTimestamp = v, true
+			case metricDatatype:
+				m.Datatype = DataType(v)
+			case metricIsHistorical:
+				m.IsHistorical = v != 0
+			case metricIsTransient:
+				m.IsTransient = v != 0
+			case metricIsNull:
+				m.IsNull = v != 0
+			case metricIntValue:
+				m.Value = uint32(v)
+			case metricLongValue:
+				m.Value = v
+			case metricBooleanValue:
+				m.Value = v != 0
+			}
+			return n, nil
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(b)
+			if num == metricFloatValue {
+				m.Value = math.Float32frombits(v)
+			}
+			return n, nil
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if num == metricDoubleValue {
+				m.Value = math.Float64frombits(v)
+			}
+			return n, nil
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			switch num {
+			case metricName:
+				m.Name = string(v)
+			case metricStringValue:
+				m.Value = string(v)
+			case metricBytesValue:
+				m.Value = append([]byte(nil), v...)
+			}
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// consumeFields iterates the fields of a message. The handler returns the length of the consumed field value.
+func consumeFields(b []byte, handler func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("invalid sparkplug payload: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		n, err := handler(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid sparkplug payload: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// Marshal encodes the payload in protobuf
+func (p *Payload) Marshal() []byte {
+	var b []byte
+	if p.HasTimestamp {
+		b = protowire.AppendTag(b, payloadTimestamp, protowire.VarintType)
+		b = protowire.AppendVarint(b, p.Timestamp)
+	}
+	for _, m := range p.Metrics {
+		b = protowire.AppendTag(b, payloadMetrics, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.marshal())
+	}
+	if p.HasSeq {
+		b = protowire.AppendTag(b, payloadSeq, protowire.VarintType)
+		b = protowire.AppendVarint(b, p.Seq)
+	}
+	if p.Uuid != "" {
+		b = protowire.AppendTag(b, payloadUuid, protowire.BytesType)
+		b = protowire.AppendString(b, p.Uuid)
+	}
+	if len(p.Body) > 0 {
+		b = protowire.AppendTag(b, payloadBody, protowire.BytesType)
+		b = protowire.AppendBytes(b, p.Body)
+	}
+	return b
+}
+
+func (m *Metric) marshal() []byte {
+	var b []byte
+	if m.Name != "" {
+		b = protowire.AppendTag(b, metricName, protowire.BytesType)
+		b = protowire.AppendString(b, m.Name)
+	}
+	if m.HasAlias {
+		b = protowire.AppendTag(b, metricAlias, protowire.VarintType)
+		b = protowire.AppendVarint(b, m.Alias)
+	}
+	if m.HasTimestamp {
+		b = protowire.AppendTag(b, metricTimestamp, protowire.VarintType)
+		b = protowire.AppendVarint(b, m.Timestamp)
+	}
+	if m.Datatype != Unknown {
+		b = protowire.AppendTag(b, metricDatatype, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Datatype))
+	}
+	if m.IsHistorical {
+		b = protowire.AppendTag(b, metricIsHistorical, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	if m.IsTransient {
+		b = protowire.AppendTag(b, metricIsTransient, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	if m.IsNull {
+		b = protowire.AppendTag(b, metricIsNull, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+		return b
+	}
+	switch v := m.Value.(type) {
+	case uint32:
+		b = protowire.AppendTag(b, metricIntValue, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(v))
+	case uint64:
+		b = protowire.AppendTag(b, metricLongValue, protowire.VarintType)
+		b = protowire.AppendVarint(b, v)
+	case float32:
+		b = protowire.AppendTag(b, metricFloatValue, protowire.Fixed32Type)
+		b = protowire.AppendFixed32(b, math.Float32bits(v))
+	case float64:
+		b = protowire.AppendTag(b, metricDoubleValue, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	case bool:
+		b = protowire.AppendTag(b, metricBooleanValue, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case string:
+		b = protowire.AppendTag(b, metricStringValue, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	case []byte:
+		b = protowire.AppendTag(b, metricBytesValue, protowire.BytesType)
+		b = protowire.AppendBytes(b, v)
+	}
+	return b
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sparkplug
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	Namespace = "spBv1.0"
+
+	NBIRTH = "NBIRTH"
+	NDEATH = "NDEATH"
+	DBIRTH = "DBIRTH"
+	DDEATH = "DDEATH"
+	NDATA  = "NDATA"
+	DDATA  = "DDATA"
+	NCMD   = "NCMD"
+	DCMD   = "DCMD"
+	STATE  = "STATE"
+
+	// BdSeqMetric is the metric in NBIRTH and NDEATH to match the death certificate to the birth
+	BdSeqMetric = "bdSeq"
+)
+
+// Topic is the parsed topic spBv1.0/group_id/message_type/edge_node_id[/device_id]
+type Topic struct {
+	GroupId     string
+	MessageType string
+	EdgeNodeId  string
+	DeviceId    string
+}
+
+func ParseTopic(topic string) (*Topic, error) {
+	parts := strings.Split(topic, "/")
+	if len(parts) < 2 || parts[0] != Namespace {
+		return nil, fmt.Errorf("topic %s is not in the sparkplug b namespace", topic)
+	}
+	if parts[1] == STATE {
+		return &Topic{MessageType: STATE}, nil
+	}
+	if len(parts) != 4 && len(parts) != 5 {
+		return nil, fmt.Errorf("invalid sparkplug b topic %s", topic)
+	}
+	t := &Topic{
+		GroupId:     parts[1],
+		MessageType: parts[2],
+		EdgeNodeId:  parts[3],
+	}
+	if len(parts) == 5 {
+		t.DeviceId = parts[4]
+	}
+	return t, nil
+}
+
+type aliasInfo struct {
+	name     string
+	datatype DataType
+}
+
+type nodeState struct {
+	online bool
+	// -1 means unknown
+	bdSeq int64
+	seq   int64
+	// The alias is unique in the edge node including its devices
+	aliases map[uint64]aliasInfo
+}
+
+func newNodeState() *nodeState {
+	return &nodeState{
+		bdSeq:   -1,
+		seq:     -1,
+		aliases: make(map[uint64]aliasInfo),
+	}
+}
+
+// State maintains the birth and death state of the edge nodes, including the metric aliases defined in the
+// birth certificates and the last sequence number. It is safe for concurrent use.
+type State struct {
+	sync.Mutex
+	nodes map[string]*nodeState
+}
+
+func NewState() *State {
+	return &State{nodes: make(map[string]*nodeState)}
+}
+
+// Apply updates the state by the message and resolves the metric aliases of the payload to the names and data
+// types in the birth certificate. If the topic is nil, the message type is unknown and the payload is treated as
+// data. Return true if the sequence number is not continuous which means the host should request a rebirth.
+func (s *State) Apply(t *Topic, p *Payload) (bool, error) {
+	if t == nil {
+		t = &Topic{}
+	}
+	s.Lock()
+	defer s.Unlock()
+	key := t.GroupId + "/" + t.EdgeNodeId
+	ns, ok := s.nodes[key]
+	if !ok || t.MessageType == NBIRTH {
+		ns = newNodeState()
+		s.nodes[key] = ns
+	}
+	switch t.MessageType {
+	case NBIRTH:
+		ns.online = true
+		ns.bdSeq = bdSeq(p)
+		ns.register(p)
+		if p.HasSeq {
+			ns.seq = int64(p.Seq)
+		}
+		return false, nil
+	case NDEATH:
+		// A stale death certificate of the previous session is ignored
+		if d := bdSeq(p); d < 0 || ns.bdSeq < 0 || d == ns.bdSeq {
+			ns.online = false
+			ns.seq = -1
+		}
+		return false, nil
+	case NCMD, DCMD:
+		return false, ns.resolve(t, p)
+	case DBIRTH, "":
+		ns.register(p)
+	}
+	gap := ns.checkSeq(p)
+	return gap, ns.resolve(t, p)
+}
+
+// IsOnline returns whether the edge node has sent the birth certificate and not dead yet
+func (s *State) IsOnline(groupId, edgeNodeId string) bool {
+	s.Lock()
+	defer s.Unlock()
+	ns, ok := s.nodes[groupId+"/"+edgeNodeId]
+	return ok && ns.online
+}
+
+func (ns *nodeState) register(p *Payload) {
+	for _, m := range p.Metrics {
+		if m.HasAlias && m.Name != "" {
+			ns.aliases[m.Alias] = aliasInfo{name: m.Name, datatype: m.Datatype}
+		}
+	}
+}
+
+func (ns *nodeState) resolve(t *Topic, p *Payload) error {
+	for _, m := range p.Metrics {
+		if !m.HasAlias || (m.Name != "" && m.Datatype != Unknown) {
+			continue
+		}
+		info, ok := ns.aliases[m.Alias]
+		if !ok {
+			if m.Name != "" {
+				continue
+			}
+			return fmt.Errorf("unknown metric alias %d of edge node %s, waiting for the birth certificate", m.Alias, t.EdgeNodeId)
+		}
+		if m.Name == "" {
+			m.Name = info.name
+		}
+		if m.Datatype == Unknown {
+			m.Datatype = info.datatype
+		}
+	}
+	return nil
+}
+
+// checkSeq checks whether the sequence number is the next one of the last message. The sequence number wraps at 256.
+func (ns *nodeState) checkSeq(p *Payload) bool {
+	if !p.HasSeq {
+		return false
+	}
+	gap := ns.seq >= 0 && int64(p.Seq) != (ns.seq+1)%256
+	ns.seq = int64(p.Seq)
+	return gap
+}
+
+func bdSeq(p *Payload) int64 {
+	for _, m := range p.Metrics {
+		if m.Name == BdSeqMetric {
+			switch v := m.Value.(type) {
+			case uint64:
+				return int64(v)
+			case uint32:
+				return int64(v)
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/tetratelabs/wazero"
+	wapi "github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+// The codec module must export the memory and the functions below
+//
+//	alloc(size i32) i32: allocate a buffer in the module memory for the host to write the input
+//	decode(ptr i32, len i32) i64: decode the payload to the JSON of a map or a list of maps
+//	encode(ptr i32, len i32) i64: encode the JSON of the data to the payload
+//	dealloc(ptr i32, size i32): optional, free the buffers of the input and output
+//
+// The result of decode and encode packs the output buffer pointer in the high 32 bits and the length in the low 32 bits.
+// The first byte of the output is the status, 0 means success and the rest is the result. Otherwise, the rest is the error message.
+const (
+	fnAlloc   = "alloc"
+	fnDealloc = "dealloc"
+	fnDecode  = "decode"
+	fnEncode  = "encode"
+)
+
+var (
+	rt       wazero.Runtime
+	rtOnce   sync.Once
+	cacheMu  sync.Mutex
+	compiled = make(map[string]*compiledModule)
+)
+
+type compiledModule struct {
+	modTime time.Time
+	mod     wazero.CompiledModule
+}
+
+func getRuntime() wazero.Runtime {
+	rtOnce.Do(func() {
+		ctx := context.Background()
+		rt = wazero.NewRuntime(ctx)
+		// Most toolchains such as TinyGo and Rust wasm32-wasi need wasi to run
+		wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+	})
+	return rt
+}
+
+// compile compiles the wasm file once until it is updated
+func compile(ctx context.Context, file string) (wazero.CompiledModule, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read wasm file %s: %v", file, err)
+	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if c, ok := compiled[file]; ok && c.modTime.Equal(fi.ModTime()) {
+		return c.mod, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read wasm file %s: %v", file, err)
+	}
+	m, err := getRuntime().CompileModule(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile wasm file %s: %v", file, err)
+	}
+	for _, fn := range []string{fnAlloc, fnDecode, fnEncode} {
+		if _, ok := m.ExportedFunctions()[fn]; !ok {
+			return nil, fmt.Errorf("wasm file %s does not export function %s", file, fn)
+		}
+	}
+	if old, ok := compiled[file]; ok {
+		_ = old.mod.Close(ctx)
+	}
+	compiled[file] = &compiledModule{modTime: fi.ModTime(), mod: m}
+	return m, nil
+}
+
+// Converter runs the codec in a sandboxed wasm module instance. The instance is not concurrent safe, so the calls are serialized.
+type Converter struct {
+	sync.Mutex
+	mod     wapi.Module
+	alloc   wapi.Function
+	dealloc wapi.Function
+	decode  wapi.Function
+	encode  wapi.Function
+}
+
+func NewConverter(file string) (message.Converter, error) {
+	ctx := context.Background()
+	cm, err := compile(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	// The reactor modules need to call _initialize before calling the exported functions
+	mod, err := getRuntime().InstantiateModule(ctx, cm, wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot instantiate wasm file %s: %v", file, err)
+	}
+	if mod.Memory() == nil {
+		_ = mod.Close(ctx)
+		return nil, fmt.Errorf("wasm file %s does not export memory", file)
+	}
+	c := &Converter{
+		mod:     mod,
+		alloc:   mod.ExportedFunction(fnAlloc),
+		dealloc: mod.ExportedFunction(fnDealloc),
+		decode:  mod.ExportedFunction(fnDecode),
+		encode:  mod.ExportedFunction(fnEncode),
+	}
+	// The converter has no close method, release the instance when it is not used
+	runtime.SetFinalizer(c, func(c *Converter) {
+		_ = c.mod.Close(context.Background())
+	})
+	return c, nil
+}
+
+func (c *Converter) Encode(ctx api.StreamContext, d any) (b []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	input, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return c.call(ctx, c.encode, input)
+}
+
+func (c *Converter) Decode(ctx api.StreamContext, b []byte) (m any, err error) {
+	defer func() {
+		if err != nil {
+			err = errorx.NewWithCode(errorx.CovnerterErr, err.Error())
+		}
+	}()
+	output, err := c.call(ctx, c.decode, b)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(output, &v); err != nil {
+		return nil, fmt.Errorf("invalid decode result of wasm codec: %v", err)
+	}
+	switch vt := v.(type) {
+	case map[string]any:
+		return vt, nil
+	case []any:
+		ms := make([]map[string]any, len(vt))
+		for i, item := range vt {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("only map[string]interface{} and []map[string]interface{} is supported")
+			}
+			ms[i] = obj
+		}
+		return ms, nil
+	default:
+		return nil, fmt.Errorf("only map[string]interface{} and []map[string]interface{} is supported")
+	}
+}
+
+func (c *Converter) call(sctx api.StreamContext, fn wapi.Function, input []byte) ([]byte, error) {
+	var ctx context.Context = context.Background()
+	if sctx != nil {
+		ctx = sctx
+	}
+	c.Lock()
+	defer c.Unlock()
+	size := uint64(len(input))
+	r, err := c.alloc.Call(ctx, size)
+	if err != nil {
+		return nil, fmt.Errorf("wasm codec alloc error: %v", err)
+	}
+	ptr := r[0]
+	defer c.free(ctx, ptr, size)
+	if !c.mod.Memory().Write(uint32(ptr), input) {
+		return nil, fmt.Errorf("wasm codec alloc out of memory range")
+	}
+	r, err = fn.Call(ctx, ptr, size)
+	if err != nil {
+		return nil, fmt.Errorf("wasm codec %s error: %v", fn.Definition().Name(), err)
+	}
+	outPtr, outLen := uint32(r[0]>>32), uint32(r[0])
+	defer c.free(ctx, uint64(outPtr), uint64(outLen))
+	out, ok := c.mod.Memory().Read(outPtr, outLen)
+	if !ok || outLen == 0 {
+		return nil, fmt.Errorf("invalid result of wasm codec %s", fn.Definition().Name())
+	}
+	if out[0] != 0 {
+		return nil, fmt.Errorf("wasm codec %s error: %s", fn.Definition().Name(), out[1:])
+	}
+	// The memory view will be invalid after next call, so copy it
+	result := make([]byte, outLen-1)
+	copy(result, out[1:])
+	return result, nil
+}
+
+func (c *Converter) free(ctx context.Context, ptr, size uint64) {
+	if c.dealloc != nil && size > 0 {
+		_, _ = c.dealloc.Call(ctx, ptr, size)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+// echoWasm is compiled from the wat below. Both decode and encode return the input as the result
+//
+//	(module
+//	  (memory (export "memory") 1)
+//	  (global $heap (mut i32) (i32.const 1024))
+//	  (func $alloc (export "alloc") (param $size i32) (result i32)
+//	    global.get $heap
+//	    (global.set $heap (i32.add (global.get $heap) (local.get $size))))
+//	  (func $echo (param $ptr i32) (param $len i32) (result i64)
+//	    (local $out i32)
+//	    (i32.store8 (local.tee $out (call $alloc (i32.add (local.get $len) (i32.const 1)))) (i32.const 0))
+//	    (memory.copy (i32.add (local.get $out) (i32.const 1)) (local.get $ptr) (local.get $len))
+//	    (i64.or (i64.shl (i64.extend_i32_u (local.get $out)) (i64.const 32))
+//	            (i64.extend_i32_u (i32.add (local.get $len) (i32.const 1)))))
+//	  (export "decode" (func $echo))
+//	  (export "encode" (func $echo)))
+var echoWasm = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x0c, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f,
+	0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, 0x03, 0x03, 0x02, 0x00, 0x01, 0x05, 0x03, 0x01, 0x00, 0x01,
+	0x06, 0x07, 0x01, 0x7f, 0x01, 0x41, 0x80, 0x08, 0x0b, 0x07, 0x24, 0x04, 0x06, 0x6d, 0x65, 0x6d,
+	0x6f, 0x72, 0x79, 0x02, 0x00, 0x05, 0x61, 0x6c, 0x6c, 0x6f, 0x63, 0x00, 0x00, 0x06, 0x64, 0x65,
+	0x63, 0x6f, 0x64, 0x65, 0x00, 0x01, 0x06, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x00, 0x01, 0x0a,
+	0x3a, 0x02, 0x0b, 0x00, 0x23, 0x00, 0x23, 0x00, 0x20, 0x00, 0x6a, 0x24, 0x00, 0x0b, 0x2c, 0x01,
+	0x01, 0x7f, 0x20, 0x01, 0x41, 0x01, 0x6a, 0x10, 0x00, 0x22, 0x02, 0x41, 0x00, 0x3a, 0x00, 0x00,
+	0x20, 0x02, 0x41, 0x01, 0x6a, 0x20, 0x00, 0x20, 0x01, 0xfc, 0x0a, 0x00, 0x00, 0x20, 0x02, 0xad,
+	0x42, 0x20, 0x86, 0x20, 0x01, 0x41, 0x01, 0x6a, 0xad, 0x84, 0x0b,
+}
+
+func TestEchoCodec(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "echo.wasm")
+	require.NoError(t, os.WriteFile(file, echoWasm, 0o644))
+	c, err := NewConverter(file)
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("test", "op1")
+
+	r, err := c.Decode(ctx, []byte(`{"a":1,"b":"x"}`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": float64(1), "b": "x"}, r)
+	r, err = c.Decode(ctx, []byte(`[{"a":1},{"a":2}]`))
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{{"a": float64(1)}, {"a": float64(2)}}, r)
+	_, err = c.Decode(ctx, []byte(`[1]`))
+	require.EqualError(t, err, "only map[string]interface{} and []map[string]interface{} is supported")
+	_, err = c.Decode(ctx, []byte(`abc`))
+	require.Error(t, err)
+
+	b, err := c.Encode(ctx, map[string]any{"a": 1})
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1}`, string(b))
+
+	// Each converter has its own instance
+	c2, err := NewConverter(file)
+	require.NoError(t, err)
+	b, err = c2.Encode(ctx, map[string]any{"b": 2})
+	require.NoError(t, err)
+	require.Equal(t, `{"b":2}`, string(b))
+}
+
+func TestInvalidModule(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewConverter(filepath.Join(dir, "notExist.wasm"))
+	require.Error(t, err)
+
+	file := filepath.Join(dir, "empty.wasm")
+	require.NoError(t, os.WriteFile(file, echoWasm[:8], 0o644))
+	_, err = NewConverter(file)
+	require.EqualError(t, err, "wasm file "+file+" does not export function alloc")
+
+	file = filepath.Join(dir, "invalid.wasm")
+	require.NoError(t, os.WriteFile(file, []byte("abc"), 0o644))
+	_, err = NewConverter(file)
+	require.Error(t, err)
+}
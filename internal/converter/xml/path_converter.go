@@ -0,0 +1,183 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/beevik/etree"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+type pathConf struct {
+	// Paths maps the field name to the XPath-like expression to select its value
+	Paths map[string]string `json:"xmlPaths"`
+	// Template is the go template to encode the data
+	Template string `json:"xmlTemplate"`
+}
+
+type fieldPath struct {
+	name  string
+	path  etree.Path
+	attr  string
+	array bool
+}
+
+// PathConverter decodes the fields selected by the paths and encodes by the template.
+// If either is not set, it falls back to the generic xml conversion.
+type PathConverter struct {
+	*XMLConverter
+	fields []*fieldPath
+	tmpl   *template.Template
+}
+
+// NewConverter creates the xml converter. If the paths or template is set, the path converter is created.
+func NewConverter(schema map[string]*ast.JsonStreamField, props map[string]any) (message.Converter, error) {
+	c := &pathConf{}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return nil, err
+	}
+	if len(c.Paths) == 0 && c.Template == "" {
+		return NewXMLConverter(), nil
+	}
+	pc := &PathConverter{XMLConverter: NewXMLConverter()}
+	for name, p := range c.Paths {
+		fp, err := compileFieldPath(name, p)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := schema[name]; ok && f != nil && f.Type == "array" {
+			fp.array = true
+		}
+		pc.fields = append(pc.fields, fp)
+	}
+	if c.Template != "" {
+		t, err := template.New("xml").Funcs(conf.FuncMap).Funcs(template.FuncMap{"xmlEscape": xmlEscape}).Parse(c.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xmlTemplate: %v", err)
+		}
+		pc.tmpl = t
+	}
+	return pc, nil
+}
+
+// compileFieldPath compiles the element path. The attribute is selected by the last segment like /a/b/@attr
+func compileFieldPath(name, p string) (*fieldPath, error) {
+	fp := &fieldPath{name: name}
+	ep := p
+	if i := strings.LastIndex(p, "/"); i >= 0 && strings.HasPrefix(p[i+1:], "@") {
+		fp.attr = p[i+2:]
+		ep = p[:i]
+		if ep == "" {
+			ep = "/*"
+		}
+	}
+	if fp.attr == "" && strings.HasPrefix(p, "@") {
+		fp.attr = p[1:]
+		ep = "."
+	}
+	path, err := etree.CompilePath(ep)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xml path %s of field %s: %v", p, name, err)
+	}
+	fp.path = path
+	return fp, nil
+}
+
+func (c *PathConverter) Decode(ctx api.StreamContext, b []byte) (got any, err error) {
+	if len(c.fields) == 0 {
+		return c.XMLConverter.Decode(ctx, b)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			conf.Log.Errorf("xml decode panic, err:%v data:%v", r, string(b))
+			err = fmt.Errorf("xml decode panic: %v", r)
+		}
+	}()
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(b); err != nil {
+		return nil, err
+	}
+	result := make(map[string]any, len(c.fields))
+	for _, f := range c.fields {
+		var values []any
+		for _, ele := range doc.FindElementsPath(f.path) {
+			if f.attr != "" {
+				if attr := ele.SelectAttr(f.attr); attr != nil {
+					values = append(values, parseText(attr.Value))
+				}
+				continue
+			}
+			v, err := selectEleValue(ele)
+			if err != nil {
+				return nil, fmt.Errorf("decode field %s error: %v", f.name, err)
+			}
+			values = append(values, v)
+		}
+		switch {
+		case f.array:
+			if values == nil {
+				values = []any{}
+			}
+			result[f.name] = values
+		case len(values) == 1:
+			result[f.name] = values[0]
+		case len(values) > 1:
+			result[f.name] = values
+		}
+	}
+	return result, nil
+}
+
+// selectEleValue returns the typed text of the leaf element, or the decoded map of the element with children
+func selectEleValue(ele *etree.Element) (any, error) {
+	if len(ele.ChildElements()) > 0 || len(ele.Attr) > 0 {
+		return extractEleValue(ele)
+	}
+	return parseText(strings.TrimSpace(ele.Text())), nil
+}
+
+func parseText(s string) any {
+	v, _ := extractValue(&etree.CharData{Data: s})
+	return v
+}
+
+func (c *PathConverter) Encode(ctx api.StreamContext, d any) ([]byte, error) {
+	if c.tmpl == nil {
+		return c.XMLConverter.Encode(ctx, d)
+	}
+	var buf bytes.Buffer
+	if err := c.tmpl.Execute(&buf, d); err != nil {
+		return nil, fmt.Errorf("fail to encode data %v with xmlTemplate: %v", d, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func xmlEscape(v any) (string, error) {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(cast.ToStringAlways(v))); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+const scadaData = `<?xml version="1.0"?>
+<station id="s1">
+  <meta><name>North</name></meta>
+  <tag name="temp" quality="good">20.5</tag>
+  <tag name="pressure" quality="bad">101</tag>
+  <alarm>
+    <code>E1</code>
+  </alarm>
+</station>`
+
+func TestPathDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		paths  map[string]string
+		schema map[string]*ast.JsonStreamField
+		exp    map[string]any
+	}{
+		{
+			name: "element and attribute",
+			paths: map[string]string{
+				"id":       "/station/@id",
+				"name":     "/station/meta/name",
+				"temp":     "/station/tag[@name='temp']",
+				"quality":  "/station/tag[@name='pressure']/@quality",
+				"notFound": "/station/none",
+			},
+			exp: map[string]any{
+				"id":      "s1",
+				"name":    "North",
+				"temp":    map[string]any{"@value": 20.5, "name": "temp", "quality": "good"},
+				"quality": "bad",
+			},
+		},
+		{
+			name: "repeated elements",
+			paths: map[string]string{
+				"names":  "//tag/@name",
+				"codes":  "//alarm/code",
+				"alarms": "/station/alarm",
+			},
+			schema: map[string]*ast.JsonStreamField{
+				"codes": {Type: "array"},
+			},
+			exp: map[string]any{
+				"names":  []any{"temp", "pressure"},
+				"codes":  []any{"E1"},
+				"alarms": map[string]any{"@value": []any{map[string]any{"code": map[string]any{"@value": "E1"}}}},
+			},
+		},
+	}
+	ctx := mockContext.NewMockContext("1", "2")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewConverter(tt.schema, map[string]any{"xmlPaths": tt.paths})
+			require.NoError(t, err)
+			r, err := c.Decode(ctx, []byte(scadaData))
+			require.NoError(t, err)
+			require.Equal(t, tt.exp, r)
+		})
+	}
+}
+
+func TestTemplateEncode(t *testing.T) {
+	c, err := NewConverter(nil, map[string]any{"xmlTemplate": `<order id="{{.id}}"><item>{{xmlEscape .item}}</item></order>`})
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("1", "2")
+	r, err := c.Encode(ctx, map[string]any{"id": 3, "item": "A&B <1>"})
+	require.NoError(t, err)
+	require.Equal(t, `<order id="3"><item>A&amp;B &lt;1&gt;</item></order>`, string(r))
+	// decode falls back to the generic conversion
+	d, err := c.Decode(ctx, r)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"order": map[string]any{"id": "3", "@value": []any{map[string]any{"item": map[string]any{"@value": "A&B <1>"}}}}}, d)
+}
+
+func TestNewConverterError(t *testing.T) {
+	_, err := NewConverter(nil, map[string]any{"xmlPaths": map[string]string{"a": "/a[@b"}})
+	require.Error(t, err)
+	_, err = NewConverter(nil, map[string]any{"xmlTemplate": "{{.a"})
+	require.Error(t, err)
+}
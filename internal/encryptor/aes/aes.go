@@ -24,6 +24,8 @@ import (
 )
 
 type c struct {
+	// KeyId is the name of the key in encryptionKeys. Use the aesKey if not set
+	KeyId   string `json:"keyId"`
 	Mode    string `json:"mode"`
 	Iv      string `json:"iv"`
 	Aad     string `json:"aad"`
@@ -31,15 +33,15 @@ type c struct {
 }
 
 func GetEncryptor(props map[string]any) (message.Encryptor, error) {
-	if conf.Config == nil || conf.Config.AesKey == nil {
-		return nil, fmt.Errorf("AES key is not defined")
-	}
-	key := conf.Config.AesKey
 	cc := &c{Mode: "cfb"}
 	err := cast.MapToStruct(props, cc)
 	if err != nil {
 		return nil, err
 	}
+	key, err := conf.GetEncryptionKey(cc.KeyId)
+	if err != nil {
+		return nil, err
+	}
 	switch cc.Mode {
 	case "cfb":
 		return NewStreamEncrypter(key, cc)
@@ -50,6 +52,34 @@ func GetEncryptor(props map[string]any) (message.Encryptor, error) {
 	}
 }
 
+// GetDecryptor returns the decryptor of the payload encrypted by the encryptor of the same props
+func GetDecryptor(props map[string]any) (message.Decryptor, error) {
+	cc := &c{Mode: "cfb"}
+	err := cast.MapToStruct(props, cc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := conf.GetEncryptionKey(cc.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	switch cc.Mode {
+	case "cfb":
+		return NewStreamEncrypter(key, cc)
+	case "gcm":
+		d, err := NewGcmEncrypter(key, cc)
+		if err != nil {
+			return nil, err
+		}
+		if d.aad != nil && d.constantNonce == nil {
+			return nil, fmt.Errorf("iv is required to decrypt with aad")
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("unsupported AES decryption mode: %s", cc.Mode)
+	}
+}
+
 func GetEncryptWriter(output io.Writer, props map[string]any) (io.Writer, error) {
 	if conf.Config == nil || conf.Config.AesKey == nil {
 		return nil, fmt.Errorf("AES key is not defined")
@@ -60,6 +60,29 @@ func (a *GcmEncrypter) Encrypt(data []byte) ([]byte, error) {
 	}
 }
 
+// Decrypt decrypts the data in the layout of Encrypt. Without aad, it is nonce, ciphertext and tag.
+// With aad, it is tag, which may be padded to the tagSize, and ciphertext. The nonce is the configured iv.
+func (a *GcmEncrypter) Decrypt(data []byte) ([]byte, error) {
+	if a.aad == nil {
+		nonceSize := a.gcm.NonceSize()
+		if len(data) < nonceSize+a.gcm.Overhead() {
+			return nil, fmt.Errorf("ciphertext too short")
+		}
+		return a.gcm.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	}
+	tagSize := a.gcm.Overhead()
+	if a.tagSize > tagSize {
+		tagSize = a.tagSize
+	}
+	if len(data) < tagSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	sealed := make([]byte, 0, len(data)-tagSize+a.gcm.Overhead())
+	sealed = append(sealed, data[tagSize:]...)
+	sealed = append(sealed, data[:a.gcm.Overhead()]...)
+	return a.gcm.Open(nil, a.constantNonce, sealed, a.aad)
+}
+
 func NewGcmEncrypter(key []byte, cc *c) (*GcmEncrypter, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
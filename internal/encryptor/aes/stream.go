@@ -46,6 +46,17 @@ func (a *StreamEncrypter) Encrypt(data []byte) ([]byte, error) {
 	return result, nil
 }
 
+// Decrypt decrypts the data whose first 16 bytes are the iv
+func (a *StreamEncrypter) Decrypt(data []byte) ([]byte, error) {
+	if len(data) < aes.BlockSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	plaintext := make([]byte, len(data)-aes.BlockSize)
+	stream := cipher.NewCFBDecrypter(a.block, data[:aes.BlockSize])
+	stream.XORKeyStream(plaintext, data[aes.BlockSize:])
+	return plaintext, nil
+}
+
 func NewStreamEncrypter(key []byte, cc *c) (*StreamEncrypter, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -0,0 +1,79 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chacha20
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+type c struct {
+	// KeyId is the name of the key in encryptionKeys. Use the aesKey if not set
+	KeyId string `json:"keyId"`
+	Aad   string `json:"aad"`
+}
+
+// Cipher encrypts and decrypts with ChaCha20-Poly1305. The encrypted data is nonce, ciphertext and tag.
+type Cipher struct {
+	aead cipher.AEAD
+	aad  []byte
+}
+
+func NewCipher(props map[string]any) (*Cipher, error) {
+	cc := &c{}
+	err := cast.MapToStruct(props, cc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := conf.GetEncryptionKey(cc.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	r := &Cipher{aead: aead}
+	if cc.Aad != "" {
+		r.aad, err = base64.StdEncoding.DecodeString(cc.Aad)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Aad setting")
+		}
+	}
+	return r, nil
+}
+
+func (a *Cipher) Encrypt(data []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize(), a.aead.NonceSize()+len(data)+a.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, data, a.aad), nil
+}
+
+func (a *Cipher) Decrypt(data []byte) ([]byte, error) {
+	nonceSize := a.aead.NonceSize()
+	if len(data) < nonceSize+a.aead.Overhead() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return a.aead.Open(nil, data[:nonceSize], data[nonceSize:], a.aad)
+}
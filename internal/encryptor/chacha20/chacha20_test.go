@@ -0,0 +1,54 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chacha20
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestCipher(t *testing.T) {
+	if conf.Config == nil {
+		conf.Config = &conf.KuiperConf{}
+	}
+	conf.Config.AesKey = []byte("0123456789abcdef0123456789abcdef")
+	conf.Config.EncryptionKeys = map[string][]byte{"short": []byte("0123456789abcdef")}
+	aad := base64.StdEncoding.EncodeToString([]byte("helloworld"))
+	pt := []byte(`{"temperature":23.5}`)
+	for _, props := range []map[string]any{nil, {"aad": aad}} {
+		c, err := NewCipher(props)
+		assert.NoError(t, err)
+		secret, err := c.Encrypt(pt)
+		assert.NoError(t, err)
+		assert.Len(t, secret, len(pt)+12+16)
+		revert, err := c.Decrypt(secret)
+		assert.NoError(t, err)
+		assert.Equal(t, pt, revert)
+		// tampered
+		secret[len(secret)-1] ^= 1
+		_, err = c.Decrypt(secret)
+		assert.Error(t, err)
+	}
+	_, err := NewCipher(map[string]any{"keyId": "short"})
+	assert.EqualError(t, err, "chacha20poly1305: bad key length")
+	c, err := NewCipher(nil)
+	assert.NoError(t, err)
+	_, err = c.Decrypt([]byte("short"))
+	assert.EqualError(t, err, "ciphertext too short")
+}
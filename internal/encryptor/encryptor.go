@@ -19,6 +19,7 @@ import (
 	"io"
 
 	"github.com/lf-edge/ekuiper/v2/internal/encryptor/aes"
+	"github.com/lf-edge/ekuiper/v2/internal/encryptor/chacha20"
 	"github.com/lf-edge/ekuiper/v2/pkg/message"
 )
 
@@ -26,11 +27,32 @@ func GetEncryptor(name string, encryptProps map[string]any) (message.Encryptor,
 	switch name {
 	case "aes":
 		return aes.GetEncryptor(encryptProps)
+	case "chacha20":
+		c, err := chacha20.NewCipher(encryptProps)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
 	default:
 		return nil, fmt.Errorf("encryptor '%s' is not supported", name)
 	}
 }
 
+func GetDecryptor(name string, decryptProps map[string]any) (message.Decryptor, error) {
+	switch name {
+	case "aes":
+		return aes.GetDecryptor(decryptProps)
+	case "chacha20":
+		c, err := chacha20.NewCipher(decryptProps)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("decryptor '%s' is not supported", name)
+	}
+}
+
 func GetEncryptWriter(name string, output io.Writer) (io.Writer, error) {
 	// TODO support encryption props later
 	if name == "aes" {
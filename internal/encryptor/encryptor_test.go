@@ -33,3 +33,13 @@ func TestGetEncryptor(t *testing.T) {
 	_, err = GetEncryptor("aes", map[string]any{"mode": "abc"})
 	assert.Error(t, err)
 }
+
+func TestGetDecryptor(t *testing.T) {
+	conf.InitConf()
+	_, err := GetDecryptor("aes", map[string]any{"mode": "gcm"})
+	assert.NoError(t, err)
+	_, err = GetDecryptor("chacha20", map[string]any{"keyId": "unknown"})
+	assert.EqualError(t, err, "encryption key unknown is not defined")
+	_, err = GetDecryptor("unknown", nil)
+	assert.EqualError(t, err, "decryptor 'unknown' is not supported")
+}
@@ -18,12 +18,14 @@ package reader
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 	"github.com/parquet-go/parquet-go"
 
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 )
 
@@ -33,14 +35,44 @@ func init() {
 	})
 }
 
+type parquetConf struct {
+	// Projection is the top level columns to read. Only the column chunks of them are decoded.
+	Projection []string `json:"projection"`
+	// RowGroupFilters skip the row groups whose statistics show that no row can match
+	RowGroupFilters []*rowGroupFilter `json:"rowGroupFilters"`
+}
+
 type ParquetReader struct {
+	config     *parquetConf
 	pf         *parquet.File
 	groups     []parquet.RowGroup
 	curGroup   int
 	rowsReader parquet.Rows
+	// the projected schema and the columns to read, only set when projection is enabled
+	schema  *parquet.Schema
+	columns []projectedColumn
+	cursors []*columnCursor
+	row     parquet.Row
+	preds   []*groupPredicate
+}
+
+type projectedColumn struct {
+	// the column index in the file and in the projected schema
+	source int
+	target int
 }
 
 func (pr *ParquetReader) Provision(ctx api.StreamContext, props map[string]any) error {
+	c := &parquetConf{}
+	if err := cast.MapToStruct(props, c); err != nil {
+		return err
+	}
+	for _, f := range c.RowGroupFilters {
+		if err := f.validate(); err != nil {
+			return err
+		}
+	}
+	pr.config = c
 	return nil
 }
 
@@ -59,23 +91,76 @@ func (pr *ParquetReader) Bind(ctx api.StreamContext, fr io.Reader, _ int) (err e
 		return err
 	}
 	pr.groups = pr.pf.RowGroups()
+	pr.curGroup = 0
+	pr.rowsReader = nil
+	pr.cursors = nil
+	pr.schema = pr.pf.Schema()
+	if pr.config == nil {
+		return nil
+	}
+	if len(pr.config.Projection) > 0 {
+		if err = pr.project(); err != nil {
+			return err
+		}
+	}
+	pr.preds, err = bindPredicates(pr.pf.Schema(), pr.config.RowGroupFilters)
+	return err
+}
+
+// project builds the schema of the projected columns and maps the leaf columns to read
+func (pr *ParquetReader) project() error {
+	fileSchema := pr.pf.Schema()
+	group := make(parquet.Group, len(pr.config.Projection))
+	for _, name := range pr.config.Projection {
+		var found parquet.Field
+		for _, f := range fileSchema.Fields() {
+			if f.Name() == name {
+				found = f
+				break
+			}
+		}
+		if found == nil {
+			return fmt.Errorf("projection column %s is not found in the parquet file", name)
+		}
+		group[name] = found
+	}
+	pr.schema = parquet.NewSchema(fileSchema.Name(), group)
+	paths := pr.schema.Columns()
+	pr.columns = make([]projectedColumn, 0, len(paths))
+	for _, path := range paths {
+		leaf, _ := fileSchema.Lookup(path...)
+		pleaf, _ := pr.schema.Lookup(path...)
+		pr.columns = append(pr.columns, projectedColumn{source: leaf.ColumnIndex, target: pleaf.ColumnIndex})
+	}
 	return nil
 }
 
-func (pr *ParquetReader) Read(_ api.StreamContext) (any, error) {
+func (pr *ParquetReader) Read(ctx api.StreamContext) (any, error) {
 	var row [1]parquet.Row
 	for pr.curGroup < len(pr.groups) {
 		group := pr.groups[pr.curGroup]
-		if pr.rowsReader == nil {
-			pr.rowsReader = group.Rows()
+		if pr.rowsReader == nil && pr.cursors == nil && pr.skipGroup(pr.curGroup) {
+			ctx.GetLogger().Debugf("skip parquet row group %d by the statistics", pr.curGroup)
+			pr.curGroup++
+			continue
+		}
+		var (
+			r   parquet.Row
+			err error
+		)
+		if pr.columns != nil {
+			r, err = pr.readProjected(group)
+		} else {
+			if pr.rowsReader == nil {
+				pr.rowsReader = group.Rows()
+			}
+			_, err = pr.rowsReader.ReadRows(row[:])
+			r = row[0]
 		}
-
-		_, err := pr.rowsReader.ReadRows(row[:])
 		switch {
 		case errors.Is(err, io.EOF):
 			pr.curGroup++
-			err = pr.rowsReader.Close()
-			pr.rowsReader = nil
+			err = pr.closeGroup()
 			if err != nil {
 				return nil, err
 			}
@@ -85,7 +170,7 @@ func (pr *ParquetReader) Read(_ api.StreamContext) (any, error) {
 		}
 
 		m := make(map[string]any)
-		err = pr.pf.Schema().Reconstruct(&m, row[0])
+		err = pr.schema.Reconstruct(&m, r)
 		if err != nil {
 			return nil, err
 		}
@@ -94,12 +179,99 @@ func (pr *ParquetReader) Read(_ api.StreamContext) (any, error) {
 	return nil, io.EOF
 }
 
+// readProjected assembles the next row from the values of the projected column chunks
+func (pr *ParquetReader) readProjected(group parquet.RowGroup) (parquet.Row, error) {
+	if pr.cursors == nil {
+		chunks := group.ColumnChunks()
+		pr.cursors = make([]*columnCursor, len(pr.columns))
+		for i, c := range pr.columns {
+			pr.cursors[i] = newColumnCursor(chunks[c.source].Pages(), c.target)
+		}
+	}
+	var err error
+	row := pr.row[:0]
+	for _, c := range pr.cursors {
+		row, err = c.next(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pr.row = row
+	return row, nil
+}
+
+func (pr *ParquetReader) closeGroup() error {
+	var errs []error
+	if pr.rowsReader != nil {
+		errs = append(errs, pr.rowsReader.Close())
+		pr.rowsReader = nil
+	}
+	for _, c := range pr.cursors {
+		errs = append(errs, c.pages.Close())
+	}
+	pr.cursors = nil
+	return errors.Join(errs...)
+}
+
 func (pr *ParquetReader) IsBytesReader() bool {
 	return false
 }
 
 func (pr *ParquetReader) Close(_ api.StreamContext) error {
-	return nil
+	return pr.closeGroup()
+}
+
+// columnCursor reads the values of a column chunk row by row
+type columnCursor struct {
+	pages  parquet.Pages
+	reader parquet.ValueReader
+	column int
+	buf    []parquet.Value
+	pos    int
+	n      int
+}
+
+func newColumnCursor(pages parquet.Pages, column int) *columnCursor {
+	return &columnCursor{pages: pages, column: column, buf: make([]parquet.Value, 256)}
+}
+
+func (c *columnCursor) peek() (parquet.Value, error) {
+	for c.pos >= c.n {
+		if c.reader == nil {
+			p, err := c.pages.ReadPage()
+			if err != nil {
+				return parquet.Value{}, err
+			}
+			c.reader = p.Values()
+		}
+		n, err := c.reader.ReadValues(c.buf)
+		c.pos, c.n = 0, n
+		if errors.Is(err, io.EOF) {
+			c.reader = nil
+		} else if err != nil {
+			return parquet.Value{}, err
+		}
+	}
+	return c.buf[c.pos], nil
+}
+
+// next appends the values of the next row, which starts with a value of repetition level 0, to the row
+func (c *columnCursor) next(row parquet.Row) (parquet.Row, error) {
+	for first := true; ; first = false {
+		v, err := c.peek()
+		if err != nil {
+			if !first && errors.Is(err, io.EOF) {
+				return row, nil
+			}
+			return row, err
+		}
+		if !first && v.RepetitionLevel() == 0 {
+			return row, nil
+		}
+		// clone the value because the page buffer can be reused by the next page of the row
+		row = append(row, v.Clone().Level(v.RepetitionLevel(), v.DefinitionLevel(), c.column))
+		c.pos++
+	}
 }
 
 var _ modules.FileStreamReader = &ParquetReader{}
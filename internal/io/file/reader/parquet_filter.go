@@ -0,0 +1,206 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build parquet || full
+
+package reader
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// rowGroupFilter is a predicate like `column op value`. A row group is skipped if its statistics show that no row
+// can match the predicate. The rows of the retained groups are not filtered.
+type rowGroupFilter struct {
+	Column string `json:"column"`
+	Op     string `json:"op"`
+	Value  any    `json:"value"`
+}
+
+func (f *rowGroupFilter) validate() error {
+	if f.Column == "" {
+		return fmt.Errorf("rowGroupFilters column is required")
+	}
+	switch f.Op {
+	case "=", "!=", ">", ">=", "<", "<=":
+	default:
+		return fmt.Errorf("rowGroupFilters op %s is invalid, must be one of =, !=, >, >=, < and <=", f.Op)
+	}
+	if f.Value == nil {
+		return fmt.Errorf("rowGroupFilters value of column %s is required", f.Column)
+	}
+	return nil
+}
+
+type groupPredicate struct {
+	column int
+	kind   parquet.Kind
+	op     string
+	// float64 for the numeric and boolean columns or string for the byte array columns
+	value any
+}
+
+// bindPredicates resolves the filter columns in the file schema
+func bindPredicates(schema *parquet.Schema, filters []*rowGroupFilter) ([]*groupPredicate, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	result := make([]*groupPredicate, 0, len(filters))
+	for _, f := range filters {
+		leaf, ok := schema.Lookup(strings.Split(f.Column, ".")...)
+		if !ok {
+			return nil, fmt.Errorf("rowGroupFilters column %s is not found in the parquet file", f.Column)
+		}
+		if leaf.MaxRepetitionLevel > 0 {
+			return nil, fmt.Errorf("rowGroupFilters column %s must not be repeated", f.Column)
+		}
+		p := &groupPredicate{column: leaf.ColumnIndex, kind: leaf.Node.Type().Kind(), op: f.Op}
+		var err error
+		switch p.kind {
+		case parquet.Boolean:
+			var b bool
+			b, err = cast.ToBool(f.Value, cast.CONVERT_SAMEKIND)
+			if b {
+				p.value = float64(1)
+			} else {
+				p.value = float64(0)
+			}
+		case parquet.Int32, parquet.Int64, parquet.Float, parquet.Double:
+			p.value, err = cast.ToFloat64(f.Value, cast.CONVERT_SAMEKIND)
+		case parquet.ByteArray, parquet.FixedLenByteArray:
+			p.value, err = cast.ToString(f.Value, cast.CONVERT_SAMEKIND)
+		default:
+			err = fmt.Errorf("column type %s is not supported", p.kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rowGroupFilters column %s: %v", f.Column, err)
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// skipGroup returns true if any predicate cannot match the row group according to the column statistics
+func (pr *ParquetReader) skipGroup(index int) bool {
+	if len(pr.preds) == 0 {
+		return false
+	}
+	rg := pr.pf.Metadata().RowGroups[index]
+	for _, p := range pr.preds {
+		stats := rg.Columns[p.column].MetaData.Statistics
+		if rg.NumRows > 0 && stats.NullCount == rg.NumRows {
+			// a null value never matches
+			return true
+		}
+		minB, maxB := stats.MinValue, stats.MaxValue
+		if minB == nil || maxB == nil {
+			// the deprecated min and max are only reliable for the numeric types
+			if p.kind == parquet.ByteArray || p.kind == parquet.FixedLenByteArray {
+				continue
+			}
+			minB, maxB = stats.Min, stats.Max
+		}
+		minV, ok1 := decodeStat(p.kind, minB)
+		maxV, ok2 := decodeStat(p.kind, maxB)
+		if !ok1 || !ok2 {
+			continue
+		}
+		if !mayMatch(p.op, compareStat(minV, p.value), compareStat(maxV, p.value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayMatch checks if any value in [min, max] may satisfy the predicate by the comparison results of min and max to
+// the predicate value
+func mayMatch(op string, minCmp, maxCmp int) bool {
+	switch op {
+	case "=":
+		return minCmp <= 0 && maxCmp >= 0
+	case "!=":
+		return minCmp != 0 || maxCmp != 0
+	case ">":
+		return maxCmp > 0
+	case ">=":
+		return maxCmp >= 0
+	case "<":
+		return minCmp < 0
+	case "<=":
+		return minCmp <= 0
+	default:
+		return true
+	}
+}
+
+// decodeStat decodes the plain encoded statistic value
+func decodeStat(kind parquet.Kind, b []byte) (any, bool) {
+	switch kind {
+	case parquet.Boolean:
+		if len(b) != 1 {
+			return nil, false
+		}
+		return float64(b[0] & 1), true
+	case parquet.Int32:
+		if len(b) != 4 {
+			return nil, false
+		}
+		return float64(int32(binary.LittleEndian.Uint32(b))), true
+	case parquet.Int64:
+		if len(b) != 8 {
+			return nil, false
+		}
+		return float64(int64(binary.LittleEndian.Uint64(b))), true
+	case parquet.Float:
+		if len(b) != 4 {
+			return nil, false
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), true
+	case parquet.Double:
+		if len(b) != 8 {
+			return nil, false
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), true
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return string(b), true
+	default:
+		return nil, false
+	}
+}
+
+func compareStat(a, b any) int {
+	switch x := a.(type) {
+	case float64:
+		y := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	case string:
+		return strings.Compare(x, b.(string))
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build parquet || full
+
+package reader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+type parquetRecord struct {
+	Id   int64   `parquet:"id"`
+	Name string  `parquet:"name"`
+	Temp float64 `parquet:"temp"`
+}
+
+// writeParquet writes 12 rows in 3 row groups: id 1-4, 5-8 and 9-12
+func writeParquet(t *testing.T) string {
+	p := filepath.Join(t.TempDir(), "test.parquet")
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	w := parquet.NewGenericWriter[parquetRecord](f, parquet.MaxRowsPerRowGroup(4))
+	for i := 1; i <= 12; i++ {
+		_, err = w.Write([]parquetRecord{{Id: int64(i), Name: fmt.Sprintf("user%d", i), Temp: float64(i) * 1.5}})
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+	return p
+}
+
+func readAll(t *testing.T, path string, props map[string]any) ([]any, error) {
+	ctx := mockContext.NewMockContext("test", "parquet")
+	r := &ParquetReader{}
+	if err := r.Provision(ctx, props); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	if err := r.Bind(ctx, f, 0); err != nil {
+		return nil, err
+	}
+	var result []any
+	for {
+		m, err := r.Read(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, r.Close(ctx)
+}
+
+func TestParquetProjectionAndFilter(t *testing.T) {
+	path := writeParquet(t)
+	tests := []struct {
+		name   string
+		props  map[string]any
+		result []any
+	}{
+		{
+			name: "filter",
+			props: map[string]any{
+				"rowGroupFilters": []any{map[string]any{"column": "id", "op": ">", "value": 8}},
+			},
+			result: []any{
+				map[string]any{"id": int64(9), "name": "user9", "temp": 13.5},
+				map[string]any{"id": int64(10), "name": "user10", "temp": 15.0},
+				map[string]any{"id": int64(11), "name": "user11", "temp": 16.5},
+				map[string]any{"id": int64(12), "name": "user12", "temp": 18.0},
+			},
+		},
+		{
+			name: "projection with filter",
+			props: map[string]any{
+				"projection": []any{"name", "id"},
+				"rowGroupFilters": []any{
+					map[string]any{"column": "id", "op": ">=", "value": 5},
+					map[string]any{"column": "temp", "op": "<", "value": 13.5},
+				},
+			},
+			result: []any{
+				map[string]any{"id": int64(5), "name": "user5"},
+				map[string]any{"id": int64(6), "name": "user6"},
+				map[string]any{"id": int64(7), "name": "user7"},
+				map[string]any{"id": int64(8), "name": "user8"},
+			},
+		},
+		{
+			name: "filter all",
+			props: map[string]any{
+				"projection":      []any{"id"},
+				"rowGroupFilters": []any{map[string]any{"column": "name", "op": "=", "value": "user0"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := readAll(t, path, tt.props)
+			require.NoError(t, err)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+
+	r, err := readAll(t, path, map[string]any{"projection": []any{"temp"}})
+	require.NoError(t, err)
+	require.Len(t, r, 12)
+	assert.Equal(t, map[string]any{"temp": 1.5}, r[0])
+	assert.Equal(t, map[string]any{"temp": 18.0}, r[11])
+}
+
+func TestParquetConfError(t *testing.T) {
+	path := writeParquet(t)
+	tests := []struct {
+		props map[string]any
+		err   string
+	}{
+		{
+			props: map[string]any{"projection": []any{"unknown"}},
+			err:   "projection column unknown is not found in the parquet file",
+		},
+		{
+			props: map[string]any{"rowGroupFilters": []any{map[string]any{"column": "unknown", "op": "=", "value": 1}}},
+			err:   "rowGroupFilters column unknown is not found in the parquet file",
+		},
+		{
+			props: map[string]any{"rowGroupFilters": []any{map[string]any{"column": "id", "op": "like", "value": 1}}},
+			err:   "rowGroupFilters op like is invalid, must be one of =, !=, >, >=, < and <=",
+		},
+		{
+			props: map[string]any{"rowGroupFilters": []any{map[string]any{"column": "id", "op": "="}}},
+			err:   "rowGroupFilters value of column id is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			_, err := readAll(t, path, tt.props)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
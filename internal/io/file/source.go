@@ -66,6 +66,8 @@ type Source struct {
 	// attach to a reader
 	decorator modules.FileStreamDecorator
 	eof       api.EOFIngest
+	// read the files once and then send EOF in the batch mode
+	runOnce bool
 	// rewind support state
 	rewindMeta *FileDirSourceRewindMeta
 }
@@ -179,6 +181,10 @@ func (fs *Source) SetEofIngest(eof api.EOFIngest) {
 	fs.eof = eof
 }
 
+func (fs *Source) SetRunOnce() {
+	fs.runOnce = true
+}
+
 func (fs *Source) Close(ctx api.StreamContext) error {
 	ctx.GetLogger().Infof("Close file source")
 	return nil
@@ -406,8 +412,8 @@ func (fs *Source) Info() (i model.NodeInfo) {
 
 // TransformType must call after provision
 func (fs *Source) TransformType() api.Source {
-	// If interval is not set, use watch source
-	if fs.config.Interval == 0 {
+	// If interval is not set, use watch source. In the batch mode, the watch source reads once without watching
+	if fs.config.Interval == 0 || fs.runOnce {
 		return &WatchWrapper{f: fs}
 	}
 	return fs
@@ -471,5 +477,6 @@ var (
 	// if interval is not set, it uses inotify
 	_ api.Bounded    = &Source{}
 	_ model.InfoNode = &Source{}
+	_ model.RunOnce  = &Source{}
 	_ api.Rewindable = &Source{}
 )
@@ -42,7 +42,7 @@ func (f *WatchWrapper) Connect(ctx api.StreamContext, sch api.StatusChangeHandle
 func (f *WatchWrapper) Subscribe(ctx api.StreamContext, ingest api.TupleIngest, ingestError api.ErrorIngest) error {
 	f.f.Load(ctx, ingest, ingestError)
 	ctx.GetLogger().Infof("file watch loaded initially")
-	if f.f.isDir {
+	if f.f.isDir && !f.f.runOnce {
 		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
 			return err
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/v2/pkg/mock"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
 	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
@@ -107,3 +108,43 @@ func TestWatchDir(t *testing.T) {
 		// do nothing
 	})
 }
+
+func TestRunOnceDir(t *testing.T) {
+	path, err := os.Getwd()
+	require.NoError(t, err)
+	wpath := filepath.Join(path, "runonce")
+	require.NoError(t, os.MkdirAll(wpath, os.ModePerm))
+	defer os.RemoveAll(wpath)
+	content, err := os.ReadFile(filepath.Join(path, "test", "test.lines"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(wpath, "test.lines"), content, 0o644))
+
+	fs := &Source{}
+	fs.SetRunOnce()
+	ctx, cancel := mockContext.NewMockContext("testRunOnce", "op1").WithCancel()
+	defer cancel()
+	// The interval is ignored in the batch mode
+	require.NoError(t, fs.Provision(ctx, map[string]any{
+		"path":     wpath,
+		"fileType": "lines",
+		"interval": "1s",
+	}))
+	r, ok := fs.TransformType().(*WatchWrapper)
+	require.True(t, ok)
+	eof := make(chan struct{}, 1)
+	r.SetEofIngest(func(ctx api.StreamContext) {
+		eof <- struct{}{}
+	})
+	var result [][]byte
+	require.NoError(t, r.Subscribe(ctx, func(ctx api.StreamContext, data any, meta map[string]any, ts time.Time) {
+		result = append(result, data.([]byte))
+	}, func(ctx api.StreamContext, err error) {
+		assert.NoError(t, err)
+	}))
+	select {
+	case <-eof:
+	case <-time.After(time.Second):
+		assert.Fail(t, "should send eof")
+	}
+	assert.Len(t, result, 4)
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
+	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/internal/io/memory/store"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 )
@@ -28,6 +29,8 @@ import (
 type lc struct {
 	Topic string `json:"datasource"`
 	Key   string `json:"key"`
+	// RetainVersions is the number of versions to keep for each key to support temporal join
+	RetainVersions int `json:"retainVersions"`
 }
 
 // lookupsource is a lookup source that reads data from memory
@@ -37,6 +40,7 @@ type lookupsource struct {
 	topicRegex *regexp.Regexp
 	table      *store.Table
 	key        string
+	versions   int
 }
 
 func (s *lookupsource) Connect(ctx api.StreamContext, sch api.StatusChangeHandler) error {
@@ -47,6 +51,9 @@ func (s *lookupsource) Connect(ctx api.StreamContext, sch api.StatusChangeHandle
 		sch(api.ConnectionDisconnected, err.Error())
 		return err
 	}
+	if s.versions > 0 {
+		s.table.KeepVersions(s.versions)
+	}
 	sch(api.ConnectionConnected, "")
 	return nil
 }
@@ -70,14 +77,26 @@ func (s *lookupsource) Provision(ctx api.StreamContext, props map[string]any) er
 	if cfg.Key == "" {
 		return fmt.Errorf("key is required for lookup source")
 	}
+	if cfg.RetainVersions < 0 {
+		return fmt.Errorf("retainVersions must not be negative")
+	}
 	s.topic = cfg.Topic
 	s.key = cfg.Key
+	s.versions = cfg.RetainVersions
 	return nil
 }
 
 func (s *lookupsource) Lookup(ctx api.StreamContext, _ []string, keys []string, values []interface{}) ([]map[string]any, error) {
 	ctx.GetLogger().Debugf("lookup source %s is looking up keys %v with values %v", s.topic, keys, values)
-	tuples, err := s.table.Read(keys, values)
+	var (
+		tuples []pubsub.MemTuple
+		err    error
+	)
+	if s.versions > 0 {
+		tuples, err = s.table.ReadVersions(keys, values)
+	} else {
+		tuples, err = s.table.Read(keys, values)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +112,11 @@ type Table struct {
 	key   string
 	// datamap is the overall data indexed by primary key
 	datamap map[any]pubsub.MemTuple
-	cancel  context.CancelFunc
+	// history is the retained versions of each primary key in the arrival order.
+	// Only kept when versions > 0
+	history  map[any][]pubsub.MemTuple
+	versions int
+	cancel   context.CancelFunc
 }
 
 func createTable(topic string, key string) *Table {
@@ -128,12 +132,78 @@ func (t *Table) add(value pubsub.MemTuple) {
 		conf.Log.Errorf("add to table %s omitted, value not found for key %s", t.topic, t.key)
 	}
 	t.datamap[keyval] = value
+	if t.versions > 0 {
+		h := append(t.history[keyval], value)
+		if len(h) > t.versions {
+			h = h[len(h)-t.versions:]
+		}
+		t.history[keyval] = h
+	}
 }
 
 func (t *Table) delete(key interface{}) {
 	t.Lock()
 	defer t.Unlock()
 	delete(t.datamap, key)
+	delete(t.history, key)
+}
+
+// KeepVersions sets the table to retain at least n versions for each primary key.
+// The versions are only collected after it is set, and the larger one wins if the table is shared.
+func (t *Table) KeepVersions(n int) {
+	t.Lock()
+	defer t.Unlock()
+	if n <= t.versions {
+		return
+	}
+	if t.history == nil {
+		t.history = make(map[any][]pubsub.MemTuple, len(t.datamap))
+		for k, v := range t.datamap {
+			t.history[k] = []pubsub.MemTuple{v}
+		}
+	}
+	t.versions = n
+}
+
+// ReadVersions returns all the retained versions which match the keys. It is the same as Read if the versions are not kept.
+func (t *Table) ReadVersions(keys []string, values []interface{}) ([]pubsub.MemTuple, error) {
+	t.RLock()
+	if t.versions == 0 {
+		t.RUnlock()
+		return t.Read(keys, values)
+	}
+	defer t.RUnlock()
+	var candidates [][]pubsub.MemTuple
+	for i, k := range keys {
+		if k == t.key {
+			candidates = [][]pubsub.MemTuple{t.history[values[i]]}
+			break
+		}
+	}
+	if candidates == nil {
+		candidates = make([][]pubsub.MemTuple, 0, len(t.history))
+		for _, h := range t.history {
+			candidates = append(candidates, h)
+		}
+	}
+	var result []pubsub.MemTuple
+	for _, h := range candidates {
+		for _, v := range h {
+			if matchKeys(v, keys, values) {
+				result = append(result, v)
+			}
+		}
+	}
+	return result, nil
+}
+
+func matchKeys(v pubsub.MemTuple, keys []string, values []interface{}) bool {
+	for i, k := range keys {
+		if val, ok := v.Value(k, ""); !ok || val != values[i] {
+			return false
+		}
+	}
+	return true
 }
 
 func (t *Table) Read(keys []string, values []interface{}) ([]pubsub.MemTuple, error) {
@@ -18,6 +18,8 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 )
@@ -132,3 +134,44 @@ func TestDb(t *testing.T) {
 		return
 	}
 }
+
+func TestTableVersions(t *testing.T) {
+	tb := createTable("topicV", "a")
+	tb.add(&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "0"}})
+	tb.KeepVersions(2)
+	tb.add(&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "1"}})
+	tb.add(&xsql.Tuple{Message: map[string]interface{}{"a": 2, "b": "1"}})
+	v, _ := tb.ReadVersions([]string{"a"}, []interface{}{1})
+	require.Equal(t, []pubsub.MemTuple{
+		&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "0"}},
+		&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "1"}},
+	}, v)
+	// Only the last 2 versions are retained
+	tb.add(&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "2"}})
+	v, _ = tb.ReadVersions([]string{"a"}, []interface{}{1})
+	require.Equal(t, []pubsub.MemTuple{
+		&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "1"}},
+		&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "2"}},
+	}, v)
+	// Read still returns the latest version only
+	v, _ = tb.Read([]string{"a"}, []interface{}{1})
+	require.Equal(t, []pubsub.MemTuple{
+		&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "2"}},
+	}, v)
+	v, _ = tb.ReadVersions([]string{"b"}, []interface{}{"1"})
+	require.Len(t, v, 2)
+	// A smaller versions setting does not shrink the history
+	tb.KeepVersions(1)
+	require.Equal(t, 2, tb.versions)
+	tb.delete(1)
+	v, _ = tb.ReadVersions([]string{"a"}, []interface{}{1})
+	require.Nil(t, v)
+	// Fallback to read the latest if versions are not kept
+	tb2 := createTable("topicV2", "a")
+	tb2.add(&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "0"}})
+	tb2.add(&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "1"}})
+	v, _ = tb2.ReadVersions([]string{"a"}, []interface{}{1})
+	require.Equal(t, []pubsub.MemTuple{
+		&xsql.Tuple{Message: map[string]interface{}{"a": 1, "b": "1"}},
+	}, v)
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// LookupSource is a lookup table of the static data, mainly to mock the lookup tables in the rule test
+type LookupSource struct {
+	data []map[string]any
+}
+
+type lConfig struct {
+	Data []map[string]any `json:"data"`
+}
+
+func (s *LookupSource) Provision(_ api.StreamContext, configs map[string]any) error {
+	cfg := &lConfig{}
+	if err := cast.MapToStruct(configs, cfg); err != nil {
+		return fmt.Errorf("read properties %v fail with error: %v", configs, err)
+	}
+	s.data = cfg.Data
+	return nil
+}
+
+func (s *LookupSource) Connect(_ api.StreamContext, sch api.StatusChangeHandler) error {
+	sch(api.ConnectionConnected, "")
+	return nil
+}
+
+// Lookup returns the rows whose key values are all equal to the values. The values are compared by their string form
+// so that the numbers decoded from json can match.
+func (s *LookupSource) Lookup(_ api.StreamContext, fields []string, keys []string, values []any) ([]map[string]any, error) {
+	var result []map[string]any
+	for _, row := range s.data {
+		matched := true
+		for i, k := range keys {
+			if v, ok := row[k]; !ok || fmt.Sprint(v) != fmt.Sprint(values[i]) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if len(fields) == 0 {
+			result = append(result, row)
+			continue
+		}
+		r := make(map[string]any, len(fields))
+		for _, f := range fields {
+			if v, ok := row[f]; ok {
+				r[f] = v
+			}
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func (s *LookupSource) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func GetLookupSource() api.Source {
+	return &LookupSource{}
+}
+
+var _ api.LookupSource = &LookupSource{}
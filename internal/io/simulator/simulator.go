@@ -15,22 +15,34 @@
 package simulator
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
 )
 
 type SimulatorSource struct {
 	index int
 	cfg   *sConfig
 	eof   api.EOFIngest
+	// the time of the first pull, the timestamps are relative to it
+	start time.Time
+	// do not loop in the batch mode
+	runOnce bool
 }
 
 type sConfig struct {
 	Data []map[string]any `json:"data"`
 	Loop bool             `json:"loop"`
+	// Timestamps are the offsets in milliseconds to send each data relative to the first pull.
+	// If not set, the data is sent in every pull.
+	Timestamps []int64 `json:"timestamps"`
+	// Duration is the min duration since the first pull to send EOF if not looping,
+	// so that the rule has the time to fire the windows after the last data.
+	Duration cast.DurationConf `json:"duration"`
 }
 
 func (s *SimulatorSource) Provision(ctx api.StreamContext, configs map[string]any) error {
@@ -38,6 +50,12 @@ func (s *SimulatorSource) Provision(ctx api.StreamContext, configs map[string]an
 	if err := cast.MapToStruct(configs, cfg); err != nil {
 		return err
 	}
+	if len(cfg.Timestamps) > 0 && len(cfg.Timestamps) != len(cfg.Data) {
+		return fmt.Errorf("the length of timestamps %d must be the same as data %d", len(cfg.Timestamps), len(cfg.Data))
+	}
+	if s.runOnce {
+		cfg.Loop = false
+	}
 	s.cfg = cfg
 	return nil
 }
@@ -45,6 +63,7 @@ func (s *SimulatorSource) Provision(ctx api.StreamContext, configs map[string]an
 func (s *SimulatorSource) Close(ctx api.StreamContext) error {
 	// Allow to reset in close rule trial run
 	s.index = 0
+	s.start = time.Time{}
 	return nil
 }
 
@@ -57,17 +76,33 @@ func (s *SimulatorSource) SetEofIngest(eof api.EOFIngest) {
 	s.eof = eof
 }
 
+func (s *SimulatorSource) SetRunOnce() {
+	s.runOnce = true
+}
+
 func (s *SimulatorSource) Pull(ctx api.StreamContext, trigger time.Time, ingest api.TupleIngest, _ api.ErrorIngest) {
+	if s.start.IsZero() {
+		s.start = trigger
+	}
 	if s.index >= len(s.cfg.Data) {
 		if s.cfg.Loop {
 			s.index = 0
+			s.start = trigger
 		} else {
+			if trigger.Sub(s.start) < time.Duration(s.cfg.Duration) {
+				return
+			}
 			if s.eof != nil {
 				s.eof(ctx)
 			}
 			return
 		}
 	}
+	if len(s.cfg.Timestamps) > 0 {
+		if trigger.Sub(s.start) < time.Duration(s.cfg.Timestamps[s.index])*time.Millisecond {
+			return
+		}
+	}
 	ingest(ctx, s.cfg.Data[s.index], nil, trigger)
 	s.index++
 }
@@ -76,4 +111,7 @@ func GetSource() api.Source {
 	return &SimulatorSource{}
 }
 
-var _ api.PullTupleSource = &SimulatorSource{}
+var (
+	_ api.PullTupleSource = &SimulatorSource{}
+	_ model.RunOnce       = &SimulatorSource{}
+)
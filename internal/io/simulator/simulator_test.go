@@ -71,3 +71,46 @@ func TestSourcePull(t *testing.T) {
 	})
 	require.NoError(t, s2.Close(ctx))
 }
+
+func TestSourcePullWithTimestamps(t *testing.T) {
+	props := map[string]any{
+		"data":       []map[string]any{{"a": 1}, {"a": 2}},
+		"timestamps": []int64{0, 100},
+		"duration":   "200ms",
+	}
+	s := &SimulatorSource{}
+	ctx := mockContext.NewMockContext("1", "2")
+	require.NoError(t, s.Provision(ctx, props))
+	eof := 0
+	s.SetEofIngest(func(ctx api.StreamContext) {
+		eof++
+	})
+	var recv []any
+	ingest := func(ctx api.StreamContext, data any, meta map[string]any, ts time.Time) {
+		recv = append(recv, data)
+	}
+	start := time.UnixMilli(0)
+	for _, offset := range []int64{0, 50, 100, 150, 200} {
+		s.Pull(ctx, start.Add(time.Duration(offset)*time.Millisecond), ingest, func(ctx api.StreamContext, err error) {})
+	}
+	require.Equal(t, []any{map[string]any{"a": 1}, map[string]any{"a": 2}}, recv)
+	require.Equal(t, 1, eof)
+
+	err := s.Provision(ctx, map[string]any{"data": []map[string]any{{"a": 1}}, "timestamps": []int64{0, 100}})
+	require.EqualError(t, err, "the length of timestamps 2 must be the same as data 1")
+}
+
+func TestLookup(t *testing.T) {
+	s := GetLookupSource().(*LookupSource)
+	ctx := mockContext.NewMockContext("1", "2")
+	require.NoError(t, s.Provision(ctx, map[string]any{"data": []map[string]any{
+		{"id": 1, "name": "a", "size": 10},
+		{"id": 2, "name": "b", "size": 20},
+	}}))
+	r, err := s.Lookup(ctx, []string{"name"}, []string{"id"}, []any{float64(2)})
+	require.NoError(t, err)
+	require.Equal(t, []map[string]any{{"name": "b"}}, r)
+	r, err = s.Lookup(ctx, nil, []string{"id"}, []any{3})
+	require.NoError(t, err)
+	require.Empty(t, r)
+}
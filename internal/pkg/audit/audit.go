@@ -0,0 +1,273 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records the management operations into the kv store. The records can be signed by a key so that each
+// record is chained with the previous one and the tampering of the log can be detected.
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// pruneInterval is the count of the appended records to run the retention check
+const pruneInterval = 100
+
+// Record is an audited management operation
+type Record struct {
+	Seq        int64  `json:"seq"`
+	Timestamp  int64  `json:"timestamp"`
+	User       string `json:"user,omitempty"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+	// ForwardedFor is the client address forwarded by a trusted proxy
+	ForwardedFor string `json:"forwardedFor,omitempty"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Query        string `json:"query,omitempty"`
+	Body         string `json:"body,omitempty"`
+	Status       int    `json:"status"`
+	Error        string `json:"error,omitempty"`
+	// PrevSignature is the signature of the previous record to chain the records
+	PrevSignature string `json:"prevSignature,omitempty"`
+	Signature     string `json:"signature,omitempty"`
+}
+
+// Options are the retention and signing settings of the log
+type Options struct {
+	// MaxAge is the max duration to keep the records. 0 means no limit.
+	MaxAge time.Duration
+	// MaxCount is the max count of the records to keep. 0 means no limit.
+	MaxCount int
+	// SignKey is the HMAC key to sign the records. The records are not signed if it is empty.
+	SignKey []byte
+}
+
+// Filter selects the records to query. The zero value fields are ignored.
+type Filter struct {
+	User   string
+	Method string
+	// Path matches the records whose path starts with it
+	Path string
+	// From and To are the inclusive time range in milliseconds
+	From int64
+	To   int64
+}
+
+// VerifyResult is the result to verify the signatures of the records
+type VerifyResult struct {
+	Verified bool   `json:"verified"`
+	Count    int    `json:"count"`
+	Seq      int64  `json:"seq,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+type Log struct {
+	sync.Mutex
+	store    kv.KeyValue
+	opts     Options
+	seq      int64
+	lastSig  string
+	appended int
+}
+
+// New creates the log on the store and continues the sequence of the existing records
+func New(store kv.KeyValue, opts Options) (*Log, error) {
+	l := &Log{store: store, opts: opts}
+	records, err := l.all()
+	if err != nil {
+		return nil, fmt.Errorf("load audit log error: %v", err)
+	}
+	if n := len(records); n > 0 {
+		l.seq = records[n-1].Seq
+		l.lastSig = records[n-1].Signature
+	}
+	if err := l.prune(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Append assigns the sequence and timestamp to the record, signs it and saves it
+func (l *Log) Append(r *Record) error {
+	l.Lock()
+	defer l.Unlock()
+	r.Seq = l.seq + 1
+	if r.Timestamp == 0 {
+		r.Timestamp = timex.GetNowInMilli()
+	}
+	r.PrevSignature, r.Signature = "", ""
+	if len(l.opts.SignKey) > 0 {
+		r.PrevSignature = l.lastSig
+		sig, err := l.sign(r)
+		if err != nil {
+			return err
+		}
+		r.Signature = sig
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := l.store.Set(recordKey(r.Seq), string(b)); err != nil {
+		return fmt.Errorf("save audit record error: %v", err)
+	}
+	l.seq = r.Seq
+	l.lastSig = r.Signature
+	l.appended++
+	if l.appended >= pruneInterval {
+		l.appended = 0
+		return l.prune()
+	}
+	return nil
+}
+
+// Query returns the records matching the filter ordered by the sequence
+func (l *Log) Query(f Filter) ([]*Record, error) {
+	records, err := l.all()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Record, 0, len(records))
+	for _, r := range records {
+		if f.match(r) {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+// Verify checks the signature of each record and the chain between them. The first record kept after the retention
+// is trusted to link to the pruned one.
+func (l *Log) Verify() (*VerifyResult, error) {
+	if len(l.opts.SignKey) == 0 {
+		return nil, fmt.Errorf("audit log is not signed, please set the sign key")
+	}
+	records, err := l.all()
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range records {
+		if i > 0 && r.PrevSignature != records[i-1].Signature {
+			return &VerifyResult{Count: len(records), Seq: r.Seq, Message: fmt.Sprintf("record %d does not link to the previous record %d", r.Seq, records[i-1].Seq)}, nil
+		}
+		sig, err := l.sign(r)
+		if err != nil {
+			return nil, err
+		}
+		if !hmac.Equal([]byte(sig), []byte(r.Signature)) {
+			return &VerifyResult{Count: len(records), Seq: r.Seq, Message: fmt.Sprintf("the signature of record %d is invalid", r.Seq)}, nil
+		}
+	}
+	return &VerifyResult{Verified: true, Count: len(records)}, nil
+}
+
+// sign computes the HMAC of the record content with the signature field excluded
+func (l *Log) sign(r *Record) (string, error) {
+	c := *r
+	c.Signature = ""
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, l.opts.SignKey)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// prune deletes the records beyond the max count or older than the max age
+func (l *Log) prune() error {
+	if l.opts.MaxAge <= 0 && l.opts.MaxCount <= 0 {
+		return nil
+	}
+	keys, err := l.store.Keys()
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+	deadline := timex.GetNowInMilli() - l.opts.MaxAge.Milliseconds()
+	for i, k := range keys {
+		if l.opts.MaxCount > 0 && len(keys)-i > l.opts.MaxCount {
+			_ = l.store.Delete(k)
+			continue
+		}
+		if l.opts.MaxAge <= 0 {
+			break
+		}
+		var v string
+		if ok, err := l.store.Get(k, &v); err != nil || !ok {
+			continue
+		}
+		r := &Record{}
+		if err := json.Unmarshal([]byte(v), r); err == nil && r.Timestamp >= deadline {
+			break
+		}
+		_ = l.store.Delete(k)
+	}
+	return nil
+}
+
+func (l *Log) all() ([]*Record, error) {
+	all, err := l.store.All()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*Record, 0, len(all))
+	for k, v := range all {
+		r := &Record{}
+		if err := json.Unmarshal([]byte(v), r); err != nil {
+			return nil, fmt.Errorf("invalid audit record %s: %v", k, err)
+		}
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Seq < records[j].Seq
+	})
+	return records, nil
+}
+
+func (f Filter) match(r *Record) bool {
+	if f.User != "" && f.User != r.User {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(r.Path, f.Path) {
+		return false
+	}
+	if f.From > 0 && r.Timestamp < f.From {
+		return false
+	}
+	if f.To > 0 && r.Timestamp > f.To {
+		return false
+	}
+	return true
+}
+
+// recordKey pads the sequence so that the keys are sorted in order
+func recordKey(seq int64) string {
+	s := strconv.FormatInt(seq, 10)
+	return strings.Repeat("0", 20-len(s)) + s
+}
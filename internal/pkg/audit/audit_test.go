@@ -0,0 +1,153 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/testx"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+func newTestStore(t *testing.T, name string) kv.KeyValue {
+	testx.InitEnv("audit")
+	s, err := store.GetKV(name)
+	require.NoError(t, err)
+	require.NoError(t, s.Clean())
+	return s
+}
+
+func TestAppendAndQuery(t *testing.T) {
+	s := newTestStore(t, "auditQuery")
+	l, err := New(s, Options{})
+	require.NoError(t, err)
+	records := []*Record{
+		{User: "admin", Method: "POST", Path: "/rules", Status: 201, Timestamp: 1000},
+		{User: "dev", Method: "PUT", Path: "/rules/rule1", Status: 200, Timestamp: 2000},
+		{User: "admin", Method: "DELETE", Path: "/streams/demo", Status: 400, Error: "not found", Timestamp: 3000},
+	}
+	for _, r := range records {
+		require.NoError(t, l.Append(r))
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		seqs   []int64
+	}{
+		{name: "all", seqs: []int64{1, 2, 3}},
+		{name: "user", filter: Filter{User: "admin"}, seqs: []int64{1, 3}},
+		{name: "method", filter: Filter{Method: "put"}, seqs: []int64{2}},
+		{name: "path", filter: Filter{Path: "/rules"}, seqs: []int64{1, 2}},
+		{name: "time", filter: Filter{From: 1500, To: 3000}, seqs: []int64{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := l.Query(tt.filter)
+			require.NoError(t, err)
+			seqs := make([]int64, 0, len(result))
+			for _, r := range result {
+				seqs = append(seqs, r.Seq)
+			}
+			assert.Equal(t, tt.seqs, seqs)
+		})
+	}
+	// The sequence continues after reopen
+	l, err = New(s, Options{})
+	require.NoError(t, err)
+	r := &Record{Method: "POST", Path: "/streams"}
+	require.NoError(t, l.Append(r))
+	assert.Equal(t, int64(4), r.Seq)
+	_, err = l.Verify()
+	assert.EqualError(t, err, "audit log is not signed, please set the sign key")
+}
+
+func TestVerify(t *testing.T) {
+	s := newTestStore(t, "auditVerify")
+	opts := Options{SignKey: []byte("secret")}
+	l, err := New(s, opts)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.Append(&Record{User: "admin", Method: "POST", Path: "/rules", Status: 201}))
+	}
+	result, err := l.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, &VerifyResult{Verified: true, Count: 3}, result)
+	// Tamper the second record
+	var v string
+	ok, err := s.Get(recordKey(2), &v)
+	require.NoError(t, err)
+	require.True(t, ok)
+	r := &Record{}
+	require.NoError(t, json.Unmarshal([]byte(v), r))
+	r.User = "dev"
+	b, _ := json.Marshal(r)
+	require.NoError(t, s.Set(recordKey(2), string(b)))
+	result, err = l.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, &VerifyResult{Count: 3, Seq: 2, Message: "the signature of record 2 is invalid"}, result)
+	// Delete the tampered record
+	require.NoError(t, s.Delete(recordKey(2)))
+	result, err = l.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, &VerifyResult{Count: 2, Seq: 3, Message: "record 3 does not link to the previous record 1"}, result)
+	// Verify with another key
+	l, err = New(s, Options{SignKey: []byte("other")})
+	require.NoError(t, err)
+	result, err = l.Verify()
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+}
+
+func TestRetention(t *testing.T) {
+	s := newTestStore(t, "auditRetention")
+	l, err := New(s, Options{})
+	require.NoError(t, err)
+	now := timex.GetNowInMilli()
+	for i := 0; i < 10; i++ {
+		ts := now
+		if i < 4 {
+			ts = now - time.Hour.Milliseconds()
+		}
+		require.NoError(t, l.Append(&Record{Method: "POST", Path: "/rules", Timestamp: ts}))
+	}
+	// The retention is applied when opening the log
+	l, err = New(s, Options{MaxAge: time.Minute})
+	require.NoError(t, err)
+	result, err := l.Query(Filter{})
+	require.NoError(t, err)
+	assert.Len(t, result, 6)
+	assert.Equal(t, int64(5), result[0].Seq)
+	l, err = New(s, Options{MaxCount: 2})
+	require.NoError(t, err)
+	result, err = l.Query(Filter{})
+	require.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, int64(9), result[0].Seq)
+	// Prune after appending the records
+	for i := 0; i < pruneInterval; i++ {
+		require.NoError(t, l.Append(&Record{Method: "POST", Path: "/rules"}))
+	}
+	result, err = l.Query(Filter{})
+	require.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, int64(pruneInterval+10), result[1].Seq)
+}
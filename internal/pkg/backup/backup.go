@@ -0,0 +1,494 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backup snapshots the persisted data of a node into one archive and restores it. The archive is a tar.gz
+// file which contains the data folder with the consistent copies of the sqlite databases, the plugins folder and the
+// sub folders of the etc folder such as the plugin configurations.
+// The restore is staged in the data folder and applied in the next start before the stores are opened.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	// introduce sqlite
+	_ "modernc.org/sqlite"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const (
+	manifestName = "manifest.json"
+	// stagingDir is the folder in the data folder to keep the restored backup until the next start
+	stagingDir = "restore"
+	// oldDir is the folder in each restored folder to keep the replaced entries until the restore succeeds
+	oldDir     = ".restore_old"
+	dataPrefix = "data"
+	// The plugins and the etc folders are restored by replacing their top level entries
+	pluginsPrefix = "plugins"
+	etcPrefix     = "etc"
+)
+
+// Dirs are the folders of a node
+type Dirs struct {
+	Data    string
+	Plugins string
+	Etc     string
+}
+
+// Manifest describes the backup
+type Manifest struct {
+	Version   string `json:"version"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+// Create writes the backup archive of the node to w
+func Create(w io.Writer, dirs Dirs, version string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	m, err := json.Marshal(&Manifest{Version: version, CreatedAt: timex.GetNowInMilli()})
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0o644, Size: int64(len(m)), ModTime: timex.GetNow()})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(m); err != nil {
+		return err
+	}
+	if err := addDir(tw, dataPrefix, dirs.Data, false); err != nil {
+		return err
+	}
+	if err := addDir(tw, pluginsPrefix, dirs.Plugins, false); err != nil {
+		return err
+	}
+	if err := addDir(tw, etcPrefix, dirs.Etc, true); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// addDir adds the files in the folder to the archive under the prefix. The sqlite databases are added by their
+// snapshots. If onlySubDirs is set, the files in the top level are skipped such as the kuiper.yaml of the node.
+func addDir(tw *tar.Writer, prefix, dir string, onlySubDirs bool) error {
+	if dir == "" {
+		return nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if prefix == dataPrefix && (rel == stagingDir || rel == oldDir) {
+			return filepath.SkipDir
+		}
+		if onlySubDirs && !d.IsDir() && !strings.Contains(rel, string(filepath.Separator)) {
+			return nil
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+		if d.IsDir() {
+			return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0o755})
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		switch {
+		case strings.HasSuffix(p, ".db-wal"), strings.HasSuffix(p, ".db-shm"), strings.HasSuffix(p, ".db-journal"):
+			// included in the snapshot of the database
+			return nil
+		case strings.HasSuffix(p, ".db"):
+			snapshot, err := snapshotDb(p)
+			if err != nil {
+				return fmt.Errorf("snapshot database %s error: %v", rel, err)
+			}
+			defer os.Remove(snapshot)
+			return addFile(tw, name, snapshot)
+		default:
+			return addFile(tw, name, p)
+		}
+	})
+}
+
+func addFile(tw *tar.Writer, name, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// snapshotDb copies the sqlite database consistently even if it is being written, and returns the path of the copy
+func snapshotDb(p string) (string, error) {
+	f, err := os.CreateTemp("", "kuiper_backup_*.db")
+	if err != nil {
+		return "", err
+	}
+	target := f.Name()
+	_ = f.Close()
+	// VACUUM INTO requires the target not to exist
+	_ = os.Remove(target)
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro", p))
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	if _, err := db.Exec("VACUUM INTO ?", target); err != nil {
+		_ = os.Remove(target)
+		return "", err
+	}
+	return target, nil
+}
+
+// Stage validates the backup archive and extracts it to the staging folder. It is applied in the next start.
+func Stage(r io.Reader, dirs Dirs, version string) (*Manifest, error) {
+	staging := filepath.Join(dirs.Data, stagingDir)
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	m, err := extract(r, staging)
+	if err == nil {
+		err = checkVersion(m, version)
+	}
+	if err != nil {
+		_ = os.RemoveAll(staging)
+		return nil, err
+	}
+	return m, nil
+}
+
+// checkVersion checks if the backup can be restored by the running version. The backup of the same major version and
+// the same or an older minor version is supported because the data are upgraded when the node starts.
+func checkVersion(m *Manifest, version string) error {
+	if m.Version == version {
+		return nil
+	}
+	bMajor, bMinor, bOk := parseVersion(m.Version)
+	major, minor, ok := parseVersion(version)
+	if !bOk || !ok || bMajor != major || bMinor > minor {
+		return fmt.Errorf("the backup of version %s cannot be restored by version %s", m.Version, version)
+	}
+	return nil
+}
+
+// parseVersion returns the major and minor version of the version such as 2.0.1 or v2.1.0-alpha.1
+func parseVersion(v string) (int, int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+func extract(r io.Reader, staging string) (*Manifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %v", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	var (
+		m       *Manifest
+		hasData bool
+	)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %v", err)
+		}
+		name := strings.TrimSuffix(hdr.Name, "/")
+		if name == manifestName {
+			m = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(m); err != nil {
+				return nil, fmt.Errorf("invalid backup manifest: %v", err)
+			}
+			continue
+		}
+		top, _, _ := strings.Cut(name, "/")
+		if !filepath.IsLocal(name) || (top != dataPrefix && top != pluginsPrefix && top != etcPrefix) {
+			return nil, fmt.Errorf("invalid file %s in the backup archive", hdr.Name)
+		}
+		if top == dataPrefix && name != dataPrefix {
+			hasData = true
+		}
+		target := filepath.Join(staging, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported file %s in the backup archive", hdr.Name)
+		}
+	}
+	if m == nil {
+		return nil, errors.New("invalid backup archive: missing manifest")
+	}
+	if !hasData {
+		return nil, errors.New("invalid backup archive: missing data")
+	}
+	if err := os.WriteFile(filepath.Join(staging, manifestName), mustMarshal(m), 0o644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func writeFile(target string, r io.Reader, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func copyFile(from, to string) error {
+	f, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return writeFile(to, f, info.Mode().Perm())
+}
+
+func mustMarshal(m *Manifest) []byte {
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// Apply replaces the node data with the staged backup if exists. It must be called before the stores are opened.
+// The whole data folder is replaced while only the top level entries in the backup are replaced for the plugins
+// and the etc folders. The replaced entries are moved aside and moved back if the restore fails. It returns the
+// manifest of the applied backup or nil if nothing is staged.
+func Apply(dirs Dirs, version string) (*Manifest, error) {
+	staging := filepath.Join(dirs.Data, stagingDir)
+	b, err := os.ReadFile(filepath.Join(staging, manifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("invalid staged backup manifest: %v", err)
+	}
+	if err := checkVersion(m, version); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(staging, dataPrefix)); err != nil {
+		return nil, fmt.Errorf("invalid staged backup: %v", err)
+	}
+	rs := &restore{}
+	if err := rs.apply(staging, dirs); err != nil {
+		if rerr := rs.rollback(); rerr != nil {
+			return nil, fmt.Errorf("%v, and roll back error: %v", err, rerr)
+		}
+		return nil, err
+	}
+	rs.clean()
+	return m, os.RemoveAll(staging)
+}
+
+// restore moves the entries of the backup into the node folders. It keeps the replaced entries in the oldDir of their
+// folders to roll back.
+type restore struct {
+	// the original paths and the paths of the replaced entries in the oldDir
+	replaced [][2]string
+	// the paths of the restored entries in the staging folder and in the node folders
+	restored [][2]string
+	olds     []string
+}
+
+func (rs *restore) apply(staging string, dirs Dirs) error {
+	entries, err := os.ReadDir(dirs.Data)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == stagingDir || e.Name() == oldDir {
+			continue
+		}
+		if err := rs.moveAside(dirs.Data, e.Name()); err != nil {
+			return err
+		}
+	}
+	if err := rs.moveEntries(filepath.Join(staging, dataPrefix), dirs.Data); err != nil {
+		return err
+	}
+	if dirs.Plugins != "" {
+		if err := rs.moveEntries(filepath.Join(staging, pluginsPrefix), dirs.Plugins); err != nil {
+			return err
+		}
+	}
+	if dirs.Etc != "" {
+		if err := rs.moveEntries(filepath.Join(staging, etcPrefix), dirs.Etc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveEntries moves the top level entries of src into dst and replaces the existing ones
+func (rs *restore) moveEntries(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := rs.moveAside(dst, e.Name()); err != nil {
+			return err
+		}
+		from := filepath.Join(src, e.Name())
+		to := filepath.Join(dst, e.Name())
+		rs.restored = append(rs.restored, [2]string{from, to})
+		// The rename fails if the folders are in different devices, then copy instead
+		if err := os.Rename(from, to); err != nil {
+			if e.IsDir() {
+				err = os.CopyFS(to, os.DirFS(from))
+			} else {
+				err = copyFile(from, to)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// moveAside moves the entry of the folder into the oldDir of the folder if exists
+func (rs *restore) moveAside(dir, name string) error {
+	p := filepath.Join(dir, name)
+	if _, err := os.Lstat(p); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	old := filepath.Join(dir, oldDir)
+	if !slices.Contains(rs.olds, old) {
+		if err := os.RemoveAll(old); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(old, os.ModePerm); err != nil {
+			return err
+		}
+		rs.olds = append(rs.olds, old)
+	}
+	aside := filepath.Join(old, name)
+	if err := os.Rename(p, aside); err != nil {
+		return err
+	}
+	rs.replaced = append(rs.replaced, [2]string{p, aside})
+	return nil
+}
+
+// rollback moves the restored entries back to the staging folder and moves the replaced ones back, so that the staged
+// backup is kept as is
+func (rs *restore) rollback() error {
+	for i := len(rs.restored) - 1; i >= 0; i-- {
+		from, to := rs.restored[i][0], rs.restored[i][1]
+		var err error
+		if _, serr := os.Lstat(from); serr == nil {
+			// copied
+			err = os.RemoveAll(to)
+		} else {
+			err = os.Rename(to, from)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for i := len(rs.replaced) - 1; i >= 0; i-- {
+		if err := os.Rename(rs.replaced[i][1], rs.replaced[i][0]); err != nil {
+			return err
+		}
+	}
+	rs.clean()
+	return nil
+}
+
+func (rs *restore) clean() {
+	for _, old := range rs.olds {
+		_ = os.RemoveAll(old)
+	}
+}
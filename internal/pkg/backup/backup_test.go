@@ -0,0 +1,217 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDirs(t *testing.T) Dirs {
+	base := t.TempDir()
+	dirs := Dirs{
+		Data:    filepath.Join(base, "data"),
+		Plugins: filepath.Join(base, "plugins"),
+		Etc:     filepath.Join(base, "etc"),
+	}
+	for _, d := range []string{dirs.Data, dirs.Plugins, dirs.Etc} {
+		require.NoError(t, os.MkdirAll(d, os.ModePerm))
+	}
+	return dirs
+}
+
+func writeTestFile(t *testing.T, p, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), os.ModePerm))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+}
+
+func readTestFile(t *testing.T, p string) string {
+	b, err := os.ReadFile(p)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func openDb(t *testing.T, p string) *sql.DB {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_journal=WAL", p))
+	require.NoError(t, err)
+	return db
+}
+
+func TestBackupRestore(t *testing.T) {
+	src := newDirs(t)
+	// Keep the database open to back up the written data
+	db := openDb(t, filepath.Join(src.Data, "sqliteKV.db"))
+	_, err := db.Exec("CREATE TABLE rule (key VARCHAR(255) PRIMARY KEY, val BLOB)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO rule(key, val) VALUES ('rule1', 'select * from demo')")
+	require.NoError(t, err)
+	writeTestFile(t, filepath.Join(src.Data, "uploads", "data.csv"), "a,b")
+	writeTestFile(t, filepath.Join(src.Data, "schemas", "protobuf", "test.proto"), "syntax = \"proto3\";")
+	writeTestFile(t, filepath.Join(src.Plugins, "sources", "Random.so"), "plugin")
+	writeTestFile(t, filepath.Join(src.Etc, "sources", "random.yaml"), "default: {}")
+	writeTestFile(t, filepath.Join(src.Etc, "kuiper.yaml"), "basic: {}")
+
+	var buf bytes.Buffer
+	require.NoError(t, Create(&buf, src, "2.0.0"))
+	require.NoError(t, db.Close())
+
+	dst := newDirs(t)
+	writeTestFile(t, filepath.Join(dst.Data, "uploads", "old.csv"), "old")
+	writeTestFile(t, filepath.Join(dst.Plugins, "sinks", "Old.so"), "old")
+	writeTestFile(t, filepath.Join(dst.Etc, "kuiper.yaml"), "basic: {debug: true}")
+	m, err := Apply(dst, "2.0.0")
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	m, err = Stage(&buf, dst, "2.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", m.Version)
+	// Not applied until the next start
+	assert.FileExists(t, filepath.Join(dst.Data, "uploads", "old.csv"))
+
+	m, err = Apply(dst, "2.0.1")
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, "2.0.0", m.Version)
+	assert.NoDirExists(t, filepath.Join(dst.Data, stagingDir))
+	assert.NoDirExists(t, filepath.Join(dst.Data, oldDir))
+	assert.NoDirExists(t, filepath.Join(dst.Plugins, oldDir))
+	assert.NoFileExists(t, filepath.Join(dst.Data, "uploads", "old.csv"))
+	assert.Equal(t, "a,b", readTestFile(t, filepath.Join(dst.Data, "uploads", "data.csv")))
+	assert.Equal(t, "syntax = \"proto3\";", readTestFile(t, filepath.Join(dst.Data, "schemas", "protobuf", "test.proto")))
+	assert.Equal(t, "plugin", readTestFile(t, filepath.Join(dst.Plugins, "sources", "Random.so")))
+	// The plugin folders not in the backup are kept
+	assert.FileExists(t, filepath.Join(dst.Plugins, "sinks", "Old.so"))
+	assert.Equal(t, "default: {}", readTestFile(t, filepath.Join(dst.Etc, "sources", "random.yaml")))
+	// The configuration of the node is not replaced
+	assert.Equal(t, "basic: {debug: true}", readTestFile(t, filepath.Join(dst.Etc, "kuiper.yaml")))
+
+	db = openDb(t, filepath.Join(dst.Data, "sqliteKV.db"))
+	defer db.Close()
+	var val string
+	require.NoError(t, db.QueryRow("SELECT val FROM rule WHERE key = 'rule1'").Scan(&val))
+	assert.Equal(t, "select * from demo", val)
+}
+
+func TestStageInvalid(t *testing.T) {
+	dirs := newDirs(t)
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{
+			name:  "no manifest",
+			files: map[string]string{"data/a.txt": "a"},
+			err:   "invalid backup archive: missing manifest",
+		},
+		{
+			name:  "outside",
+			files: map[string]string{manifestName: "{}", "data/../../a.txt": "a"},
+			err:   "invalid file data/../../a.txt in the backup archive",
+		},
+		{
+			name:  "no data",
+			files: map[string]string{manifestName: "{}", "plugins/sources/Random.so": "plugin"},
+			err:   "invalid backup archive: missing data",
+		},
+		{
+			name:  "newer version",
+			files: map[string]string{manifestName: `{"version":"2.1.0"}`, "data/a.txt": "a"},
+			err:   "the backup of version 2.1.0 cannot be restored by version 2.0.1",
+		},
+		{
+			name:  "unknown folder",
+			files: map[string]string{manifestName: "{}", "log/a.txt": "a"},
+			err:   "invalid file log/a.txt in the backup archive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			gw := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gw)
+			for name, content := range tt.files {
+				require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}))
+				_, err := tw.Write([]byte(content))
+				require.NoError(t, err)
+			}
+			require.NoError(t, tw.Close())
+			require.NoError(t, gw.Close())
+			_, err := Stage(&buf, dirs, "2.0.1")
+			assert.EqualError(t, err, tt.err)
+			assert.NoDirExists(t, filepath.Join(dirs.Data, stagingDir))
+		})
+	}
+	_, err := Stage(bytes.NewBufferString("not an archive"), dirs, "2.0.1")
+	assert.Error(t, err)
+}
+
+func TestApplyRollback(t *testing.T) {
+	src := newDirs(t)
+	writeTestFile(t, filepath.Join(src.Data, "uploads", "data.csv"), "a,b")
+	writeTestFile(t, filepath.Join(src.Etc, "sources", "random.yaml"), "default: {}")
+	var buf bytes.Buffer
+	require.NoError(t, Create(&buf, src, "2.0.0"))
+
+	dst := newDirs(t)
+	writeTestFile(t, filepath.Join(dst.Data, "uploads", "old.csv"), "old")
+	writeTestFile(t, filepath.Join(dst.Data, "sqliteKV.db"), "old db")
+	_, err := Stage(&buf, dst, "2.0.0")
+	require.NoError(t, err)
+	// The etc folder cannot be restored after the data folder is replaced
+	dst.Etc = filepath.Join(t.TempDir(), "etc")
+	writeTestFile(t, dst.Etc, "not a folder")
+	_, err = Apply(dst, "2.0.0")
+	require.Error(t, err)
+	// The old data is moved back
+	assert.Equal(t, "old", readTestFile(t, filepath.Join(dst.Data, "uploads", "old.csv")))
+	assert.Equal(t, "old db", readTestFile(t, filepath.Join(dst.Data, "sqliteKV.db")))
+	assert.NoFileExists(t, filepath.Join(dst.Data, "uploads", "data.csv"))
+	assert.NoDirExists(t, filepath.Join(dst.Data, oldDir))
+	// The staged backup is kept
+	assert.Equal(t, "a,b", readTestFile(t, filepath.Join(dst.Data, stagingDir, dataPrefix, "uploads", "data.csv")))
+	assert.FileExists(t, filepath.Join(dst.Data, stagingDir, manifestName))
+}
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		backup  string
+		version string
+		ok      bool
+	}{
+		{backup: "2.0.0", version: "2.0.0", ok: true},
+		{backup: "", version: "", ok: true},
+		{backup: "2.0.0", version: "2.1.3", ok: true},
+		{backup: "v2.0.0-alpha.1", version: "2.0.1", ok: true},
+		{backup: "2.1.0", version: "2.0.1", ok: false},
+		{backup: "1.14.0", version: "2.0.0", ok: false},
+		{backup: "unknown", version: "2.0.0", ok: false},
+		{backup: "2.0.0", version: "", ok: false},
+	}
+	for _, tt := range tests {
+		err := checkVersion(&Manifest{Version: tt.backup}, tt.version)
+		assert.Equal(t, tt.ok, err == nil, "%s to %s: %v", tt.backup, tt.version, err)
+	}
+}
@@ -15,6 +15,7 @@
 package def
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -25,24 +26,130 @@ import (
 )
 
 type RuleOption struct {
-	Debug                    bool                     `json:"debug" yaml:"debug"`
-	LogFilename              string                   `json:"logFilename,omitempty" yaml:"logFilename,omitempty"`
-	IsEventTime              bool                     `json:"isEventTime" yaml:"isEventTime"`
-	LateTol                  cast.DurationConf        `json:"lateTolerance,omitempty" yaml:"lateTolerance,omitempty"`
-	Concurrency              int                      `json:"concurrency" yaml:"concurrency"`
-	BufferLength             int                      `json:"bufferLength" yaml:"bufferLength"`
-	SendMetaToSink           bool                     `json:"sendMetaToSink" yaml:"sendMetaToSink"`
-	SendNil                  bool                     `json:"sendNilField" yaml:"sendNilField"`
-	SendError                bool                     `json:"sendError" yaml:"sendError"`
-	Qos                      Qos                      `json:"qos,omitempty" yaml:"qos,omitempty"`
-	CheckpointInterval       cast.DurationConf        `json:"checkpointInterval,omitempty" yaml:"checkpointInterval,omitempty"`
-	RestartStrategy          *RestartStrategy         `json:"restartStrategy,omitempty" yaml:"restartStrategy,omitempty"`
-	Cron                     string                   `json:"cron,omitempty" yaml:"cron,omitempty"`
-	Duration                 string                   `json:"duration,omitempty" yaml:"duration,omitempty"`
-	CronDatetimeRange        []schedule.DatetimeRange `json:"cronDatetimeRange,omitempty" yaml:"cronDatetimeRange,omitempty"`
-	PlanOptimizeStrategy     *PlanOptimizeStrategy    `json:"planOptimizeStrategy,omitempty" yaml:"planOptimizeStrategy,omitempty"`
-	NotifySub                bool                     `json:"notifySub,omitempty" yaml:"notifySub,omitempty"`
-	DisableBufferFullDiscard bool                     `json:"disableBufferFullDiscard,omitempty" yaml:"disableBufferFullDiscard,omitempty"`
+	Debug              bool              `json:"debug" yaml:"debug"`
+	LogFilename        string            `json:"logFilename,omitempty" yaml:"logFilename,omitempty"`
+	IsEventTime        bool              `json:"isEventTime" yaml:"isEventTime"`
+	LateTol            cast.DurationConf `json:"lateTolerance,omitempty" yaml:"lateTolerance,omitempty"`
+	AllowedLateness    cast.DurationConf `json:"allowedLateness,omitempty" yaml:"allowedLateness,omitempty"`
+	LateDataPolicy     string            `json:"lateDataPolicy,omitempty" yaml:"lateDataPolicy,omitempty"`
+	LateDataTopic      string            `json:"lateDataTopic,omitempty" yaml:"lateDataTopic,omitempty"`
+	SideOutput         *SideOutput       `json:"sideOutput,omitempty" yaml:"sideOutput,omitempty"`
+	EmitStrategy       *EmitStrategy     `json:"emitStrategy,omitempty" yaml:"emitStrategy,omitempty"`
+	WindowTrigger      *WindowTrigger    `json:"windowTrigger,omitempty" yaml:"windowTrigger,omitempty"`
+	Changelog          *Changelog        `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+	WindowBufferLimit  int               `json:"windowBufferLimit,omitempty" yaml:"windowBufferLimit,omitempty"`
+	Concurrency        int               `json:"concurrency" yaml:"concurrency"`
+	BufferLength       int               `json:"bufferLength" yaml:"bufferLength"`
+	SendMetaToSink     bool              `json:"sendMetaToSink" yaml:"sendMetaToSink"`
+	SendNil            bool              `json:"sendNilField" yaml:"sendNilField"`
+	SendError          bool              `json:"sendError" yaml:"sendError"`
+	Qos                Qos               `json:"qos,omitempty" yaml:"qos,omitempty"`
+	CheckpointInterval cast.DurationConf `json:"checkpointInterval,omitempty" yaml:"checkpointInterval,omitempty"`
+	// FullSnapshotEvery is the count of checkpoints to save a full snapshot, the others only save the changed states
+	FullSnapshotEvery int `json:"fullSnapshotEvery,omitempty" yaml:"fullSnapshotEvery,omitempty"`
+	// StateTTL evicts the keys of the keyed states such as the dedup keys which are not accessed for the duration
+	StateTTL cast.DurationConf `json:"stateTTL,omitempty" yaml:"stateTTL,omitempty"`
+	// StateMaxKeys evicts the least recently accessed keys of each keyed state beyond the count
+	StateMaxKeys int `json:"stateMaxKeys,omitempty" yaml:"stateMaxKeys,omitempty"`
+	// ExecBatchSize processes the queued rows of the filter and project operators in batches of the size
+	ExecBatchSize     int                      `json:"execBatchSize,omitempty" yaml:"execBatchSize,omitempty"`
+	RestartStrategy   *RestartStrategy         `json:"restartStrategy,omitempty" yaml:"restartStrategy,omitempty"`
+	Cron              string                   `json:"cron,omitempty" yaml:"cron,omitempty"`
+	Duration          string                   `json:"duration,omitempty" yaml:"duration,omitempty"`
+	CronDatetimeRange []schedule.DatetimeRange `json:"cronDatetimeRange,omitempty" yaml:"cronDatetimeRange,omitempty"`
+	// CronTimezone is the IANA time zone to evaluate the cron, the datetime ranges, the time ranges and the exclusions
+	CronTimezone string `json:"cronTimezone,omitempty" yaml:"cronTimezone,omitempty"`
+	// CronTimeRanges are the daily windows such as the shift hours in which the rule can run
+	CronTimeRanges []schedule.TimeRange `json:"cronTimeRanges,omitempty" yaml:"cronTimeRanges,omitempty"`
+	// CronExclusions are the dates such as the holidays on which the rule won't run
+	CronExclusions           []string              `json:"cronExclusions,omitempty" yaml:"cronExclusions,omitempty"`
+	PlanOptimizeStrategy     *PlanOptimizeStrategy `json:"planOptimizeStrategy,omitempty" yaml:"planOptimizeStrategy,omitempty"`
+	NotifySub                bool                  `json:"notifySub,omitempty" yaml:"notifySub,omitempty"`
+	DisableBufferFullDiscard bool                  `json:"disableBufferFullDiscard,omitempty" yaml:"disableBufferFullDiscard,omitempty"`
+	// SchedulingClass is the name of the scheduling class defined in the global configuration to share the cpu by priority
+	SchedulingClass string `json:"schedulingClass,omitempty" yaml:"schedulingClass,omitempty"`
+	// BufferSpill spills the items to the disk-backed queue when the buffers between the operators are full
+	BufferSpill *BufferSpill `json:"bufferSpill,omitempty" yaml:"bufferSpill,omitempty"`
+	// Mode is stream by default. The rule in batch mode reads the bounded inputs to the end and then finishes.
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// TrafficSplit is set in runtime when the rule runs with a canary. It is not persisted.
+	TrafficSplit *TrafficSplit `json:"-" yaml:"-"`
+}
+
+// TrafficSplit selects the events whose content hash modulo 100 is in [From, To), so that a rule and its canary
+// process the complementary parts of the events
+type TrafficSplit struct {
+	From int
+	To   int
+}
+
+// SideOutput is the destination of the data which cannot be processed normally, such as the late events,
+// the decode errors and the schema validation failures
+type SideOutput struct {
+	// Topic is the memory topic to publish to. Default to sideOutput/{ruleId}
+	Topic string `json:"topic,omitempty" yaml:"topic,omitempty"`
+	// Actions are the dedicated sinks to send the side output data
+	Actions []map[string]any `json:"actions,omitempty" yaml:"actions,omitempty"`
+}
+
+// EmitOnChange suppresses the result which is the same as the previously emitted result of the same key
+const EmitOnChange = "onChange"
+
+// EmitStrategy decides which results of the rule are emitted to the actions
+type EmitStrategy struct {
+	// Type is the strategy type. Currently only onChange is supported
+	Type string `json:"type" yaml:"type"`
+	// Keys are the fields to identify the results. The result is compared with the previous result of the same key values
+	Keys []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+	// Fields are the fields to compare. Compare all the fields if not set
+	Fields []string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// Tolerance is the max difference of the numeric fields to be regarded as unchanged
+	Tolerance float64 `json:"tolerance,omitempty" yaml:"tolerance,omitempty"`
+	// MaxKeys is the max number of keys whose last emitted result is kept. The least recently used key is evicted.
+	MaxKeys int `json:"maxKeys,omitempty" yaml:"maxKeys,omitempty"`
+}
+
+// The modes to emit the window results
+const (
+	// WindowEmitFinal emits the result only when the window closes, which is the default mode
+	WindowEmitFinal = "final"
+	// WindowEmitEarly emits the partial results of the current window periodically and not the final result
+	WindowEmitEarly = "early"
+	// WindowEmitBoth emits both the periodical partial results and the final result
+	WindowEmitBoth = "both"
+)
+
+// WindowTrigger decides when the results of the time window are emitted
+type WindowTrigger struct {
+	// Mode is the emit mode, final, early or both
+	Mode string `json:"mode" yaml:"mode"`
+	// EarlyInterval is the interval to emit the partial results of the current window
+	EarlyInterval cast.DurationConf `json:"earlyInterval,omitempty" yaml:"earlyInterval,omitempty"`
+}
+
+// BufferSpill lets the buffers between the operators and before the sinks spill to a disk-backed queue, so that a
+// short outage of the downstream neither blocks the upstream nor drops the data
+type BufferSpill struct {
+	// HighWatermark is the ratio of the buffer length to start spilling. Default to 0.8
+	HighWatermark float64 `json:"highWatermark,omitempty" yaml:"highWatermark,omitempty"`
+	// LowWatermark is the ratio of the buffer length to read back the spilled items. Default to 0.5
+	LowWatermark float64 `json:"lowWatermark,omitempty" yaml:"lowWatermark,omitempty"`
+	// MaxItems is the max count of the spilled items of each buffer. Beyond it, the buffer falls back to discard or
+	// block. Default to 100000
+	MaxItems int `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+}
+
+// Changelog converts the results to the insert, update and delete records so that the downstream can maintain
+// the materialized view of the results
+type Changelog struct {
+	// Keys are the fields to identify the results, usually the group by fields
+	Keys []string `json:"keys" yaml:"keys"`
+	// OpField is the field to keep the change type of the record. Default to op
+	OpField string `json:"opField,omitempty" yaml:"opField,omitempty"`
+	// DeleteAbsent emits the delete records for the keys which are absent in the new result which is a full snapshot
+	DeleteAbsent bool `json:"deleteAbsent,omitempty" yaml:"deleteAbsent,omitempty"`
+	// MaxKeys is the max number of keys whose last emitted result is kept. The least recently used key is evicted.
+	MaxKeys int `json:"maxKeys,omitempty" yaml:"maxKeys,omitempty"`
 }
 
 type PlanOptimizeStrategy struct {
@@ -64,6 +171,58 @@ type RestartStrategy struct {
 	Multiplier   float64           `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
 	MaxDelay     cast.DurationConf `json:"maxDelay,omitempty" yaml:"maxDelay,omitempty"`
 	JitterFactor float64           `json:"jitterFactor,omitempty" yaml:"jitterFactor,omitempty"`
+	// Policies override the strategy for the errors of the specific classes. The first matched policy is used.
+	Policies []*RestartPolicy `json:"policies,omitempty" yaml:"policies,omitempty"`
+}
+
+// The classes of the errors which fail a rule, decided by the node producing the error
+const (
+	ErrorClassSource   = "source"
+	ErrorClassOperator = "operator"
+	ErrorClassSink     = "sink"
+	// ErrorClassOther is for the errors not from a node such as the checkpoint errors
+	ErrorClassOther = "other"
+	// ErrorClassAny matches the errors of all the classes in the restart policies
+	ErrorClassAny = "any"
+)
+
+// The actions of the restart policies
+const (
+	// RestartActionRetry restarts the rule forever
+	RestartActionRetry = "retry"
+	// RestartActionRestart restarts the rule at most the attempts times
+	RestartActionRestart = "restart"
+	// RestartActionStop stops the rule at once
+	RestartActionStop = "stop"
+)
+
+// RestartPolicy decides how to restart the rule for a class of errors
+type RestartPolicy struct {
+	// ErrorClass is the class of the errors, source, operator, sink, other or any
+	ErrorClass string `json:"errorClass" yaml:"errorClass"`
+	// Match is the regular expression to match the error message. Match all the errors of the class if not set
+	Match string `json:"match,omitempty" yaml:"match,omitempty"`
+	// Action is retry, restart or stop
+	Action string `json:"action" yaml:"action"`
+	// Attempts is the max restart times of the restart action. Default to the attempts of the strategy
+	Attempts int `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+	// Delay is the interval to restart. Default to the delay of the strategy
+	Delay cast.DurationConf `json:"delay,omitempty" yaml:"delay,omitempty"`
+	// AlertTopic is the memory topic to publish the alert when the rule is stopped by this policy
+	AlertTopic string `json:"alertTopic,omitempty" yaml:"alertTopic,omitempty"`
+}
+
+// Matches returns whether the policy applies to the error of the class
+func (p *RestartPolicy) Matches(class string, err error) bool {
+	if p.ErrorClass != ErrorClassAny && p.ErrorClass != class {
+		return false
+	}
+	if p.Match == "" {
+		return true
+	}
+	// The errors are rare so that the regex is not cached. It is validated when the rule is created
+	matched, _ := regexp.MatchString(p.Match, err.Error())
+	return matched
 }
 
 type PrintableTopo struct {
@@ -97,17 +256,31 @@ type Rule struct {
 	Triggered bool                     `json:"triggered" yaml:"triggered"`
 	Id        string                   `json:"id,omitempty" yaml:"id,omitempty"`
 	Name      string                   `json:"name,omitempty" yaml:"name,omitempty"` // The display name of a rule
+	Tags      []string                 `json:"tags,omitempty" yaml:"tags,omitempty"`
 	Sql       string                   `json:"sql,omitempty" yaml:"sql,omitempty"`
 	Graph     *RuleGraph               `json:"graph,omitempty" yaml:"graph,omitempty"`
 	Actions   []map[string]interface{} `json:"actions,omitempty" yaml:"actions,omitempty"`
 	Options   *RuleOption              `json:"options,omitempty" yaml:"options,omitempty"`
+	// Labels are the key value pairs such as site, line or team to organize the rules. They can be queried by the label selector.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Statements are the named intermediate statements which run before the rule SQL in the same topology.
+	// The later statements and the rule SQL can read the output of the earlier statements by their names.
+	Statements []*RuleStatement `json:"statements,omitempty" yaml:"statements,omitempty"`
+	// Params are the runtime parameters referred by the param function in the SQL. They can be updated without restarting the rule.
+	Params map[string]any `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// RuleStatement is an intermediate SQL statement of a rule whose output can be read by the name like a stream
+type RuleStatement struct {
+	Name string `json:"name" yaml:"name"`
+	Sql  string `json:"sql" yaml:"sql"`
 }
 
 func (r *Rule) IsScheduleRule() bool {
 	if r.Options == nil {
 		return false
 	}
-	if len(r.Options.CronDatetimeRange) > 0 {
+	if len(r.Options.CronDatetimeRange) > 0 || len(r.Options.CronTimeRanges) > 0 || len(r.Options.CronExclusions) > 0 {
 		return true
 	}
 	if len(r.Options.Cron) > 0 && len(r.Options.Duration) > 0 {
@@ -118,11 +291,16 @@ func (r *Rule) IsScheduleRule() bool {
 
 func (r *Rule) GetNextScheduleStartTime() int64 {
 	if r.IsScheduleRule() && len(r.Options.Cron) > 0 {
-		isIn, err := schedule.IsInScheduleRanges(timex.GetNow(), r.Options.CronDatetimeRange)
+		loc, err := schedule.LoadLocation(r.Options.CronTimezone)
+		if err != nil {
+			return 0
+		}
+		now := timex.GetNow().In(loc)
+		isIn, err := schedule.IsInScheduleRangesInLocation(now, r.Options.CronDatetimeRange, loc)
 		if err == nil && isIn {
 			s, err := cron.ParseStandard(r.Options.Cron)
 			if err == nil {
-				return s.Next(timex.GetNow()).UnixMilli()
+				return s.Next(now).UnixMilli()
 			}
 		}
 	}
@@ -161,3 +339,21 @@ const (
 )
 
 type Qos int
+
+// The modes of the rule
+const (
+	// RuleModeStream processes the unbounded streams continuously, which is the default mode
+	RuleModeStream = "stream"
+	// RuleModeBatch processes the bounded inputs such as a file set or a query result to completion
+	RuleModeBatch = "batch"
+)
+
+// The policies to handle the rows which arrive after the watermark in event time mode
+const (
+	// LateDataDrop drops the late rows, which is the default policy
+	LateDataDrop = "drop"
+	// LateDataUpdate processes the late rows within the allowed lateness and fires the updated window results
+	LateDataUpdate = "update"
+	// LateDataSideOutput sends the late rows to the memory topic of lateDataTopic
+	LateDataSideOutput = "sideOutput"
+)
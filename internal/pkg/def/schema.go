@@ -17,11 +17,21 @@ package def
 type SchemaType string
 
 const (
-	PROTOBUF SchemaType = "protobuf"
-	CUSTOM   SchemaType = "custom"
+	PROTOBUF    SchemaType = "protobuf"
+	CUSTOM      SchemaType = "custom"
+	AVRO        SchemaType = "avro"
+	FLATBUFFERS SchemaType = "flatbuffers"
+	WASM        SchemaType = "wasm"
+	JSONSCHEMA  SchemaType = "jsonschema"
+	DBC         SchemaType = "dbc"
 )
 
 var SchemaTypes = []SchemaType{
 	PROTOBUF,
 	CUSTOM,
+	AVRO,
+	FLATBUFFERS,
+	WASM,
+	JSONSCHEMA,
+	DBC,
 }
@@ -0,0 +1,107 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gitops reads the desired rules, streams, tables and configurations from a git repository and plans the
+// changes to reconcile the node with them.
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repo is the source of the desired state. The URL can be a git repository or a local folder such as a mounted volume.
+type Repo struct {
+	URL    string
+	Branch string
+	// Path is the folder in the repository to read the definitions
+	Path string
+	// Dir is the local folder to clone the git repository into
+	Dir string
+}
+
+// IsGit returns whether the repository is a remote git repository instead of a local folder
+func (r *Repo) IsGit() bool {
+	return strings.Contains(r.URL, "://") || strings.HasPrefix(r.URL, "git@")
+}
+
+// Fetch pulls the latest revision and returns the folder of the definitions and the revision. The git
+// repository is cloned shallowly with the git command for the first time and then fetched and reset.
+func (r *Repo) Fetch(ctx context.Context) (string, string, error) {
+	if !r.IsGit() {
+		dir := filepath.Join(r.URL, r.Path)
+		if _, err := os.Stat(dir); err != nil {
+			return "", "", fmt.Errorf("read repository folder %s error: %v", dir, err)
+		}
+		return dir, "", nil
+	}
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); err != nil {
+		if err := os.RemoveAll(r.Dir); err != nil {
+			return "", "", err
+		}
+		args := []string{"clone", "--depth", "1"}
+		if r.Branch != "" {
+			args = append(args, "--branch", r.Branch)
+		}
+		args = append(args, r.URL, r.Dir)
+		if _, err := r.git(ctx, "", args...); err != nil {
+			return "", "", err
+		}
+	} else {
+		ref := r.Branch
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err := r.git(ctx, r.Dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return "", "", err
+		}
+		if _, err := r.git(ctx, r.Dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", "", err
+		}
+	}
+	rev, err := r.git(ctx, r.Dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(r.Dir, r.Path), rev, nil
+}
+
+func (r *Repo) git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Never prompt for the credentials
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s error: %v %s", args[0], err, RedactURL(strings.TrimSpace(stderr.String()), r.URL))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// RedactURL masks the credentials of the repository url in the text
+func RedactURL(text, repo string) string {
+	u, err := url.Parse(repo)
+	if err != nil || u.User == nil {
+		return text
+	}
+	return strings.ReplaceAll(text, repo, u.Redacted())
+}
@@ -0,0 +1,320 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	KindSourceConfig     = "sourceConfig"
+	KindSinkConfig       = "sinkConfig"
+	KindConnectionConfig = "connectionConfig"
+	KindStream           = "stream"
+	KindTable            = "table"
+	KindRule             = "rule"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// kinds are in the order to create or update, the deletion is in the reverse order
+var kinds = []string{KindSourceConfig, KindSinkConfig, KindConnectionConfig, KindStream, KindTable, KindRule}
+
+// State is the set of resources. The streams and tables are their sql, the rules are their json and the
+// configurations are keyed by type.plugin.name like the data import
+type State struct {
+	SourceConfig     map[string]map[string]any
+	SinkConfig       map[string]map[string]any
+	ConnectionConfig map[string]map[string]any
+	Streams          map[string]string
+	Tables           map[string]string
+	Rules            map[string]string
+}
+
+func NewState() *State {
+	return &State{
+		SourceConfig:     map[string]map[string]any{},
+		SinkConfig:       map[string]map[string]any{},
+		ConnectionConfig: map[string]map[string]any{},
+		Streams:          map[string]string{},
+		Tables:           map[string]string{},
+		Rules:            map[string]string{},
+	}
+}
+
+// document is the format of the definition files which is the same as the data import
+type document struct {
+	SourceConfig     map[string]map[string]any `yaml:"sourceConfig"`
+	SinkConfig       map[string]map[string]any `yaml:"sinkConfig"`
+	ConnectionConfig map[string]map[string]any `yaml:"connectionConfig"`
+	Streams          map[string]struct {
+		SQL string `yaml:"sql"`
+	} `yaml:"streams"`
+	Tables map[string]struct {
+		SQL string `yaml:"sql"`
+	} `yaml:"tables"`
+	Rules map[string]map[string]any `yaml:"rules"`
+}
+
+// Load reads the desired state from all the yaml and json files in the folder and its sub folders.
+// A resource can only be defined once.
+func Load(dir string) (*State, error) {
+	s := NewState()
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		if err := s.loadFile(p); err != nil {
+			return fmt.Errorf("load %s error: %v", filepath.ToSlash(rel), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *State) loadFile(p string) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	doc := &document{}
+	// json is a subset of yaml
+	if err := yaml.Unmarshal(b, doc); err != nil {
+		return err
+	}
+	for _, c := range []struct {
+		kind string
+		from map[string]map[string]any
+		to   map[string]map[string]any
+	}{
+		{KindSourceConfig, doc.SourceConfig, s.SourceConfig},
+		{KindSinkConfig, doc.SinkConfig, s.SinkConfig},
+		{KindConnectionConfig, doc.ConnectionConfig, s.ConnectionConfig},
+	} {
+		for k, v := range c.from {
+			if _, ok := c.to[k]; ok {
+				return fmt.Errorf("duplicate %s %s", c.kind, k)
+			}
+			if v == nil {
+				v = map[string]any{}
+			}
+			c.to[k] = v
+		}
+	}
+	for k, v := range doc.Streams {
+		if _, ok := s.Streams[k]; ok {
+			return fmt.Errorf("duplicate %s %s", KindStream, k)
+		}
+		s.Streams[k] = v.SQL
+	}
+	for k, v := range doc.Tables {
+		if _, ok := s.Tables[k]; ok {
+			return fmt.Errorf("duplicate %s %s", KindTable, k)
+		}
+		s.Tables[k] = v.SQL
+	}
+	for k, v := range doc.Rules {
+		if _, ok := s.Rules[k]; ok {
+			return fmt.Errorf("duplicate %s %s", KindRule, k)
+		}
+		if v == nil {
+			return fmt.Errorf("rule %s is empty", k)
+		}
+		if id, ok := v["id"]; ok && id != k {
+			return fmt.Errorf("rule %s has a different id %v", k, id)
+		}
+		v["id"] = k
+		// The rules in the repository are expected to run unless stated
+		if _, ok := v["triggered"]; !ok {
+			v["triggered"] = true
+		}
+		r, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("invalid rule %s: %v", k, err)
+		}
+		s.Rules[k] = string(r)
+	}
+	return nil
+}
+
+// Resource identifies a resource managed by the repository
+type Resource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Change is the difference of one resource between the desired and the actual state
+type Change struct {
+	Resource
+	Action string `json:"action"`
+	// Desired is the sql, the rule json or the configuration map. It is nil for deletion.
+	Desired any `json:"-"`
+}
+
+// Resources returns all the resources in the state
+func (s *State) Resources() []Resource {
+	var result []Resource
+	for _, kind := range kinds {
+		for _, name := range sortedKeys(s.kind(kind)) {
+			result = append(result, Resource{Kind: kind, Name: name})
+		}
+	}
+	return result
+}
+
+func (s *State) kind(kind string) map[string]any {
+	result := map[string]any{}
+	switch kind {
+	case KindSourceConfig:
+		for k, v := range s.SourceConfig {
+			result[k] = v
+		}
+	case KindSinkConfig:
+		for k, v := range s.SinkConfig {
+			result[k] = v
+		}
+	case KindConnectionConfig:
+		for k, v := range s.ConnectionConfig {
+			result[k] = v
+		}
+	case KindStream:
+		for k, v := range s.Streams {
+			result[k] = v
+		}
+	case KindTable:
+		for k, v := range s.Tables {
+			result[k] = v
+		}
+	case KindRule:
+		for k, v := range s.Rules {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Plan returns the changes to reconcile the actual state with the desired state in the order to apply.
+// The resources not in the desired state are only deleted when prune is set and they were created by the
+// repository before, which are in the managed list.
+func Plan(desired, actual *State, managed []Resource, prune bool) []Change {
+	var changes []Change
+	for _, kind := range kinds {
+		want, have := desired.kind(kind), actual.kind(kind)
+		for _, name := range sortedKeys(want) {
+			v := want[name]
+			cur, ok := have[name]
+			switch {
+			case !ok:
+				changes = append(changes, Change{Resource: Resource{Kind: kind, Name: name}, Action: ActionCreate, Desired: v})
+			case !equal(kind, v, cur):
+				changes = append(changes, Change{Resource: Resource{Kind: kind, Name: name}, Action: ActionUpdate, Desired: v})
+			}
+		}
+	}
+	if !prune {
+		return changes
+	}
+	for i := len(kinds) - 1; i >= 0; i-- {
+		kind := kinds[i]
+		want, have := desired.kind(kind), actual.kind(kind)
+		for _, r := range managed {
+			if r.Kind != kind {
+				continue
+			}
+			_, inWant := want[r.Name]
+			_, inHave := have[r.Name]
+			if !inWant && inHave {
+				changes = append(changes, Change{Resource: r, Action: ActionDelete})
+			}
+		}
+	}
+	return changes
+}
+
+func equal(kind string, desired, actual any) bool {
+	switch kind {
+	case KindStream, KindTable:
+		return strings.TrimSpace(desired.(string)) == strings.TrimSpace(actual.(string))
+	case KindRule:
+		// The triggered is changed by starting or stopping the rule, it is not a drift
+		d, err1 := normalize(desired.(string), "triggered")
+		a, err2 := normalize(actual.(string), "triggered")
+		return err1 == nil && err2 == nil && reflect.DeepEqual(d, a)
+	default:
+		d, err1 := normalize(desired)
+		a, err2 := normalize(actual)
+		return err1 == nil && err2 == nil && reflect.DeepEqual(d, a)
+	}
+}
+
+// normalize converts the value to the generic json types so that the values from yaml and json can be compared
+func normalize(v any, ignores ...string) (any, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if s, ok := v.(string); ok {
+		b = []byte(s)
+	} else if b, err = json.Marshal(v); err != nil {
+		return nil, err
+	}
+	var result any
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	if m, ok := result.(map[string]any); ok {
+		for _, k := range ignores {
+			delete(m, k)
+		}
+	}
+	return result, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
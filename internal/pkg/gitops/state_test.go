@@ -0,0 +1,120 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, p, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), os.ModePerm))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "streams.yaml"), `
+streams:
+  demo:
+    sql: CREATE STREAM demo () WITH (DATASOURCE="demo", TYPE="mqtt")
+tables:
+  lookup:
+    sql: CREATE TABLE lookup () WITH (DATASOURCE="lookup", TYPE="memory", KIND="lookup")
+sourceConfig:
+  sources.mqtt.local:
+    server: tcp://127.0.0.1:1883
+    qos: 1
+`)
+	writeTestFile(t, filepath.Join(dir, "rules", "rule1.json"), `{"rules":{"rule1":{"sql":"SELECT * FROM demo","actions":[{"log":{}}]}}}`)
+	writeTestFile(t, filepath.Join(dir, "rules", "rule2.yml"), `
+rules:
+  rule2:
+    triggered: false
+    sql: SELECT * FROM demo
+    actions:
+      - nop: {}
+`)
+	writeTestFile(t, filepath.Join(dir, "README.md"), "not loaded")
+	s, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"demo": `CREATE STREAM demo () WITH (DATASOURCE="demo", TYPE="mqtt")`}, s.Streams)
+	assert.Equal(t, map[string]string{"lookup": `CREATE TABLE lookup () WITH (DATASOURCE="lookup", TYPE="memory", KIND="lookup")`}, s.Tables)
+	assert.Equal(t, map[string]map[string]any{"sources.mqtt.local": {"server": "tcp://127.0.0.1:1883", "qos": 1}}, s.SourceConfig)
+	assert.JSONEq(t, `{"id":"rule1","triggered":true,"sql":"SELECT * FROM demo","actions":[{"log":{}}]}`, s.Rules["rule1"])
+	assert.JSONEq(t, `{"id":"rule2","triggered":false,"sql":"SELECT * FROM demo","actions":[{"nop":{}}]}`, s.Rules["rule2"])
+	assert.Equal(t, []Resource{
+		{Kind: KindSourceConfig, Name: "sources.mqtt.local"},
+		{Kind: KindStream, Name: "demo"},
+		{Kind: KindTable, Name: "lookup"},
+		{Kind: KindRule, Name: "rule1"},
+		{Kind: KindRule, Name: "rule2"},
+	}, s.Resources())
+
+	writeTestFile(t, filepath.Join(dir, "dup.yaml"), "streams:\n  demo:\n    sql: CREATE STREAM demo () WITH (TYPE=\"memory\")")
+	_, err = Load(dir)
+	assert.EqualError(t, err, "load streams.yaml error: duplicate stream demo")
+
+	dir = t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "rule.yaml"), "rules:\n  rule1:\n    id: rule2\n    sql: SELECT * FROM demo")
+	_, err = Load(dir)
+	assert.EqualError(t, err, "load rule.yaml error: rule rule1 has a different id rule2")
+
+	dir = t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "invalid.yaml"), "streams: [")
+	_, err = Load(dir)
+	assert.Error(t, err)
+}
+
+func TestPlan(t *testing.T) {
+	desired := NewState()
+	desired.SourceConfig["sources.mqtt.local"] = map[string]any{"server": "tcp://127.0.0.1:1883", "qos": 1}
+	desired.Streams["demo"] = "CREATE STREAM demo () WITH (TYPE=\"memory\")"
+	desired.Streams["demo2"] = "CREATE STREAM demo2 () WITH (TYPE=\"memory\")"
+	desired.Rules["rule1"] = `{"id":"rule1","triggered":true,"sql":"SELECT * FROM demo","actions":[{"log":{}}]}`
+	desired.Rules["rule2"] = `{"id":"rule2","triggered":true,"sql":"SELECT a FROM demo","actions":[{"log":{}}]}`
+
+	actual := NewState()
+	// json numbers are float
+	actual.SourceConfig["sources.mqtt.local"] = map[string]any{"server": "tcp://127.0.0.1:1883", "qos": float64(1)}
+	actual.Streams["demo"] = "CREATE STREAM demo () WITH (TYPE=\"memory\")\n"
+	actual.Streams["old"] = "CREATE STREAM old () WITH (TYPE=\"memory\")"
+	actual.Streams["manual"] = "CREATE STREAM manual () WITH (TYPE=\"memory\")"
+	// The triggered is not compared
+	actual.Rules["rule1"] = `{"triggered":false,"actions":[{"log":{}}],"id":"rule1","sql":"SELECT * FROM demo"}`
+	actual.Rules["rule2"] = `{"id":"rule2","triggered":true,"sql":"SELECT * FROM demo","actions":[{"log":{}}]}`
+	actual.Rules["oldRule"] = `{"id":"oldRule","sql":"SELECT * FROM old","actions":[{"log":{}}]}`
+
+	managed := []Resource{{Kind: KindStream, Name: "old"}, {Kind: KindRule, Name: "oldRule"}, {Kind: KindRule, Name: "deleted"}}
+	changes := Plan(desired, actual, managed, false)
+	assert.Equal(t, []Change{
+		{Resource: Resource{Kind: KindStream, Name: "demo2"}, Action: ActionCreate, Desired: desired.Streams["demo2"]},
+		{Resource: Resource{Kind: KindRule, Name: "rule2"}, Action: ActionUpdate, Desired: desired.Rules["rule2"]},
+	}, changes)
+
+	changes = Plan(desired, actual, managed, true)
+	assert.Equal(t, []Change{
+		{Resource: Resource{Kind: KindStream, Name: "demo2"}, Action: ActionCreate, Desired: desired.Streams["demo2"]},
+		{Resource: Resource{Kind: KindRule, Name: "rule2"}, Action: ActionUpdate, Desired: desired.Rules["rule2"]},
+		{Resource: Resource{Kind: KindRule, Name: "oldRule"}, Action: ActionDelete},
+		{Resource: Resource{Kind: KindStream, Name: "old"}, Action: ActionDelete},
+	}, changes)
+
+	assert.Empty(t, Plan(actual, actual, nil, true))
+}
@@ -64,3 +64,13 @@ func ParseToken(th string) (*Token, error) {
 	}
 	return tk, nil
 }
+
+// IssuerOf returns the issuer of the token without validating it
+func IssuerOf(th string) string {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(th, claims); err != nil {
+		return ""
+	}
+	iss, _ := claims.GetIssuer()
+	return iss
+}
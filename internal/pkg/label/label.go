@@ -0,0 +1,118 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package label validates the labels of the rules, streams and tables and matches them with the label selectors.
+package label
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var labelRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_./-]*[A-Za-z0-9])?$`)
+
+// Validate checks the keys and values of the labels. The key must not be empty while the value can be.
+func Validate(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelRegex.MatchString(k) {
+			return fmt.Errorf("invalid label key %q, it must consist of alphanumeric characters, '_', '.', '/' or '-' and start and end with an alphanumeric character", k)
+		}
+		if v != "" && !labelRegex.MatchString(v) {
+			return fmt.Errorf("invalid value %q of label %s, it must consist of alphanumeric characters, '_', '.', '/' or '-' and start and end with an alphanumeric character", v, k)
+		}
+	}
+	return nil
+}
+
+type operator int
+
+const (
+	opEquals operator = iota
+	opNotEquals
+	opExists
+	opNotExists
+)
+
+type requirement struct {
+	key   string
+	op    operator
+	value string
+}
+
+func (r requirement) matches(labels map[string]string) bool {
+	v, ok := labels[r.key]
+	switch r.op {
+	case opEquals:
+		return ok && v == r.value
+	case opNotEquals:
+		return !ok || v != r.value
+	case opExists:
+		return ok
+	case opNotExists:
+		return !ok
+	}
+	return false
+}
+
+// Selector is a list of requirements which must all be met. The empty selector matches everything.
+type Selector []requirement
+
+// Parse parses the comma separated requirements such as `site=sh,line!=1,team,!deprecated`.
+// The supported requirements are key=value, key==value, key!=value, key for existence and !key for non-existence.
+func Parse(s string) (Selector, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	result := make(Selector, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		var r requirement
+		switch {
+		case strings.Contains(p, "!="):
+			kv := strings.SplitN(p, "!=", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: opNotEquals, value: strings.TrimSpace(kv[1])}
+		case strings.Contains(p, "=="):
+			kv := strings.SplitN(p, "==", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: opEquals, value: strings.TrimSpace(kv[1])}
+		case strings.Contains(p, "="):
+			kv := strings.SplitN(p, "=", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: opEquals, value: strings.TrimSpace(kv[1])}
+		case strings.HasPrefix(p, "!"):
+			r = requirement{key: strings.TrimSpace(p[1:]), op: opNotExists}
+		default:
+			r = requirement{key: p, op: opExists}
+		}
+		if !labelRegex.MatchString(r.key) {
+			return nil, fmt.Errorf("invalid label selector %q: invalid key %q", s, r.key)
+		}
+		if r.value != "" && !labelRegex.MatchString(r.value) {
+			return nil, fmt.Errorf("invalid label selector %q: invalid value %q", s, r.value)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// Matches returns true if the labels meet all the requirements of the selector
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package label
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelector(t *testing.T) {
+	labels := map[string]string{"site": "sh", "line": "1", "team": ""}
+	tests := []struct {
+		selector string
+		matched  bool
+	}{
+		{"", true},
+		{"site=sh", true},
+		{"site==sh, line=1", true},
+		{"site=bj", false},
+		{"site!=bj", true},
+		{"zone!=a", true},
+		{"line!=1", false},
+		{"team", true},
+		{"zone", false},
+		{"!zone", true},
+		{"!team", false},
+		{"site=sh,!team", false},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.selector)
+		require.NoError(t, err, tt.selector)
+		require.Equal(t, tt.matched, s.Matches(labels), tt.selector)
+	}
+	_, err := Parse("site=sh,=a")
+	require.EqualError(t, err, `invalid label selector "site=sh,=a": invalid key ""`)
+	_, err = Parse("site=s h")
+	require.EqualError(t, err, `invalid label selector "site=s h": invalid value "s h"`)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(map[string]string{"site": "sh", "example.com/line": "line-1", "empty": ""}))
+	require.Error(t, Validate(map[string]string{"": "a"}))
+	require.Error(t, Validate(map[string]string{"site": "-sh"}))
+}
@@ -14,6 +14,8 @@
 
 package model
 
+import "time"
+
 type RPCArgDesc struct {
 	Name, Json string
 }
@@ -32,9 +34,17 @@ type ImportDataDesc struct {
 	FileName string
 	Stop     bool
 	Partial  bool
+	// Conflict is the policy to handle the existing resources in partial import
+	Conflict string
 }
 
 type ExportDataDesc struct {
 	Rules    []string
 	FileName string
 }
+
+// QueryDesc describes an ad-hoc query which is stopped automatically after the timeout
+type QueryDesc struct {
+	Sql     string
+	Timeout time.Duration
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oidc verifies the tokens issued by an external OpenID Connect provider. The endpoints are discovered from
+// the issuer and the signing keys are cached.
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+const (
+	// The min interval to refresh the keys for an unknown key id, so that the forged tokens cannot flood the provider
+	minRefreshInterval = time.Minute
+	// The tolerance of the clock skew to validate the time claims
+	leeway = 30 * time.Second
+)
+
+// Discovery is the provider metadata from the well known openid configuration
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	EndSessionEndpoint    string `json:"end_session_endpoint,omitempty"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type Provider struct {
+	c      conf.OidcConf
+	client *http.Client
+	parser *jwt.Parser
+
+	mu        sync.Mutex
+	discovery *Discovery
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+func NewProvider(c conf.OidcConf) (*Provider, error) {
+	if c.Issuer == "" {
+		return nil, fmt.Errorf("oidc issuer is required")
+	}
+	return &Provider{
+		c:      c,
+		client: &http.Client{Timeout: 10 * time.Second},
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+			jwt.WithIssuer(c.Issuer),
+			jwt.WithAudience(c.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(leeway),
+		),
+	}, nil
+}
+
+// Issuer returns the issuer of the tokens verified by the provider
+func (p *Provider) Issuer() string {
+	return p.c.Issuer
+}
+
+// Verify validates the token and returns the user and the roles by the claims
+func (p *Provider) Verify(token string) (string, []string, error) {
+	claims := jwt.MapClaims{}
+	_, err := p.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(kid)
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("validate token error: %s", err)
+	}
+	user, _ := claim(claims, p.c.UserClaim).(string)
+	if user == "" {
+		return "", nil, fmt.Errorf("user claim %s not exist in jwt payload", p.c.UserClaim)
+	}
+	return user, p.roles(claims), nil
+}
+
+// roles maps the values of the role claim, which is a string or a list of strings, to the roles
+func (p *Provider) roles(claims jwt.MapClaims) []string {
+	if p.c.RoleClaim == "" {
+		return nil
+	}
+	var values []string
+	switch v := claim(claims, p.c.RoleClaim).(type) {
+	case string:
+		values = strings.Fields(v)
+	case []any:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	if len(p.c.RoleMapping) == 0 {
+		return values
+	}
+	var roles []string
+	for _, v := range values {
+		if r, ok := p.c.RoleMapping[v]; ok {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// claim reads the nested claim by the path separated by dot
+func claim(claims map[string]any, path string) any {
+	var cur any = claims
+	for _, k := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[k]
+	}
+	return cur
+}
+
+// key returns the signing key of the id. The keys are fetched again if they are expired or the id is unknown.
+func (p *Provider) key(kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	k, ok := p.lookup(kid)
+	age := now.Sub(p.fetchedAt)
+	if ok && age < time.Duration(p.c.JwksCacheTTL) {
+		return k, nil
+	}
+	if ok || p.keys == nil || age >= minRefreshInterval {
+		if err := p.refresh(); err != nil {
+			// Keep using the cached key until the next period if the provider is unavailable
+			if ok {
+				conf.Log.Warnf("refresh oidc keys error, use the cached keys: %v", err)
+				p.fetchedAt = now
+				return k, nil
+			}
+			return nil, err
+		}
+		p.fetchedAt = now
+		k, ok = p.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("key %s is not found in the keys of %s", kid, p.c.Issuer)
+	}
+	return k, nil
+}
+
+// lookup finds the key by the id. The only key is used if the token has no key id.
+func (p *Provider) lookup(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func (p *Provider) refresh() error {
+	uri := p.c.JwksUri
+	if uri == "" {
+		d, err := p.discover()
+		if err != nil {
+			return err
+		}
+		uri = d.JwksUri
+	}
+	set := &jwks{}
+	if err := p.getJson(uri, set); err != nil {
+		return fmt.Errorf("fetch oidc keys error: %v", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, jk := range set.Keys {
+		if jk.Use != "" && jk.Use != "sig" {
+			continue
+		}
+		k, err := jk.publicKey()
+		if err != nil {
+			conf.Log.Warnf("ignore oidc key %s: %v", jk.Kid, err)
+			continue
+		}
+		keys[jk.Kid] = k
+	}
+	p.keys = keys
+	return nil
+}
+
+// Discovery returns the provider metadata. It is fetched once succeeded.
+func (p *Provider) Discovery() (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.discover()
+}
+
+func (p *Provider) discover() (*Discovery, error) {
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	d := &Discovery{}
+	if err := p.getJson(strings.TrimSuffix(p.c.Issuer, "/")+"/.well-known/openid-configuration", d); err != nil {
+		return nil, fmt.Errorf("discover oidc provider error: %v", err)
+	}
+	if d.Issuer != p.c.Issuer {
+		return nil, fmt.Errorf("discover oidc provider error: issuer %s does not match %s", d.Issuer, p.c.Issuer)
+	}
+	p.discovery = d
+	return d, nil
+}
+
+func (p *Provider) getJson(uri string, v any) error {
+	resp, err := p.client.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s responds %d: %s", uri, resp.StatusCode, string(b))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
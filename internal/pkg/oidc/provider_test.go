@@ -0,0 +1,232 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+type mockProvider struct {
+	*httptest.Server
+	keys      atomic.Value
+	jwksCount atomic.Int32
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	m := &mockProvider{}
+	m.keys.Store([]jwk{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&Discovery{
+			Issuer:                m.URL,
+			AuthorizationEndpoint: m.URL + "/auth",
+			TokenEndpoint:         m.URL + "/token",
+			JwksUri:               m.URL + "/certs",
+		})
+	})
+	mux.HandleFunc("/certs", func(w http.ResponseWriter, r *http.Request) {
+		m.jwksCount.Add(1)
+		_ = json.NewEncoder(w).Encode(&jwks{Keys: m.keys.Load().([]jwk)})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func rsaJwk(kid string, k *rsa.PublicKey) jwk {
+	return jwk{
+		Kid: kid,
+		Kty: "RSA",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	tk := jwt.NewWithClaims(method, claims)
+	tk.Header["kid"] = kid
+	s, err := tk.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestVerify(t *testing.T) {
+	m := newMockProvider(t)
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	m.keys.Store([]jwk{rsaJwk("rsa1", &rk.PublicKey), {
+		Kid: "ec1",
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(ek.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(ek.Y.FillBytes(make([]byte, 32))),
+	}})
+	p, err := NewProvider(conf.OidcConf{
+		Issuer:       m.URL,
+		Audience:     "eKuiper",
+		UserClaim:    "preferred_username",
+		RoleClaim:    "realm_access.roles",
+		RoleMapping:  map[string]string{"ekuiper-admins": "admin", "ekuiper-viewers": "viewer"},
+		JwksCacheTTL: cast.DurationConf(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, m.URL, p.Issuer())
+
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":                m.URL,
+			"aud":                []string{"eKuiper", "account"},
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"preferred_username": "alice",
+			"realm_access":       map[string]any{"roles": []string{"offline_access", "ekuiper-viewers", "ekuiper-admins"}},
+		}
+	}
+	user, roles, err := p.Verify(sign(t, jwt.SigningMethodRS256, "rsa1", rk, claims()))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user)
+	assert.Equal(t, []string{"viewer", "admin"}, roles)
+	_, _, err = p.Verify(sign(t, jwt.SigningMethodES256, "ec1", ek, claims()))
+	require.NoError(t, err)
+	// The keys are cached
+	assert.Equal(t, int32(1), m.jwksCount.Load())
+
+	invalid := []struct {
+		name  string
+		token func() string
+	}{
+		{
+			name: "wrong audience",
+			token: func() string {
+				c := claims()
+				c["aud"] = "other"
+				return sign(t, jwt.SigningMethodRS256, "rsa1", rk, c)
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				c := claims()
+				c["iss"] = "https://other"
+				return sign(t, jwt.SigningMethodRS256, "rsa1", rk, c)
+			},
+		},
+		{
+			name: "expired",
+			token: func() string {
+				c := claims()
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+				return sign(t, jwt.SigningMethodRS256, "rsa1", rk, c)
+			},
+		},
+		{
+			name: "no user",
+			token: func() string {
+				c := claims()
+				delete(c, "preferred_username")
+				return sign(t, jwt.SigningMethodRS256, "rsa1", rk, c)
+			},
+		},
+		{
+			name: "wrong key",
+			token: func() string {
+				other, _ := rsa.GenerateKey(rand.Reader, 2048)
+				return sign(t, jwt.SigningMethodRS256, "rsa1", other, claims())
+			},
+		},
+		{
+			name: "hmac",
+			token: func() string {
+				return sign(t, jwt.SigningMethodHS256, "rsa1", []byte("secret"), claims())
+			},
+		},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := p.Verify(tt.token())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	m := newMockProvider(t)
+	k1, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	k2, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	m.keys.Store([]jwk{rsaJwk("k1", &k1.PublicKey)})
+	p, err := NewProvider(conf.OidcConf{Issuer: m.URL, Audience: "eKuiper", UserClaim: "sub", RoleClaim: "groups", JwksCacheTTL: cast.DurationConf(time.Hour)})
+	require.NoError(t, err)
+	claims := jwt.MapClaims{"iss": m.URL, "aud": "eKuiper", "sub": "bob", "exp": time.Now().Add(time.Minute).Unix(), "groups": "operator viewer"}
+	_, roles, err := p.Verify(sign(t, jwt.SigningMethodRS256, "k1", k1, claims))
+	require.NoError(t, err)
+	// The values are the roles without mapping
+	assert.Equal(t, []string{"operator", "viewer"}, roles)
+
+	// The unknown key is not refreshed within the min interval
+	m.keys.Store([]jwk{rsaJwk("k1", &k1.PublicKey), rsaJwk("k2", &k2.PublicKey)})
+	_, _, err = p.Verify(sign(t, jwt.SigningMethodRS256, "k2", k2, claims))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), m.jwksCount.Load())
+
+	p.fetchedAt = p.fetchedAt.Add(-minRefreshInterval)
+	_, _, err = p.Verify(sign(t, jwt.SigningMethodRS256, "k2", k2, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), m.jwksCount.Load())
+
+	// The cached keys are used if the provider is unavailable
+	p.fetchedAt = p.fetchedAt.Add(-2 * time.Hour)
+	m.Close()
+	_, _, err = p.Verify(sign(t, jwt.SigningMethodRS256, "k1", k1, claims))
+	require.NoError(t, err)
+}
+
+func TestDiscovery(t *testing.T) {
+	m := newMockProvider(t)
+	p, err := NewProvider(conf.OidcConf{Issuer: m.URL})
+	require.NoError(t, err)
+	d, err := p.Discovery()
+	require.NoError(t, err)
+	assert.Equal(t, m.URL+"/auth", d.AuthorizationEndpoint)
+
+	p, err = NewProvider(conf.OidcConf{Issuer: m.URL + "/"})
+	require.NoError(t, err)
+	_, err = p.Discovery()
+	assert.ErrorContains(t, err, "does not match")
+
+	_, err = NewProvider(conf.OidcConf{})
+	assert.EqualError(t, err, "oidc issuer is required")
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ruleparam keeps the runtime parameters of the rules. The parameters are referred by the param function in
+// the rule SQL and can be updated without restarting the rule.
+package ruleparam
+
+import "sync"
+
+// params is the map of rule id to its parameters. The parameter map is never modified after set.
+var params sync.Map
+
+// Set replaces all the parameters of the rule
+func Set(ruleId string, p map[string]any) {
+	if len(p) == 0 {
+		params.Delete(ruleId)
+		return
+	}
+	m := make(map[string]any, len(p))
+	for k, v := range p {
+		m[k] = v
+	}
+	params.Store(ruleId, m)
+}
+
+// Get returns the value of the parameter of the rule
+func Get(ruleId, name string) (any, bool) {
+	p, ok := params.Load(ruleId)
+	if !ok {
+		return nil, false
+	}
+	v, ok := p.(map[string]any)[name]
+	return v, ok
+}
+
+// Delete removes all the parameters of the rule
+func Delete(ruleId string) {
+	params.Delete(ruleId)
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ruleparam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParams(t *testing.T) {
+	p := map[string]any{"threshold": 30, "devices": []any{"d1", "d2"}}
+	Set("rule1", p)
+	// The params should not be affected by the later change of the input map
+	p["threshold"] = 40
+	v, ok := Get("rule1", "threshold")
+	assert.True(t, ok)
+	assert.Equal(t, 30, v)
+	v, ok = Get("rule1", "devices")
+	assert.True(t, ok)
+	assert.Equal(t, []any{"d1", "d2"}, v)
+	_, ok = Get("rule1", "unknown")
+	assert.False(t, ok)
+	_, ok = Get("rule2", "threshold")
+	assert.False(t, ok)
+
+	Set("rule1", map[string]any{"threshold": 50})
+	v, ok = Get("rule1", "threshold")
+	assert.True(t, ok)
+	assert.Equal(t, 50, v)
+	_, ok = Get("rule1", "devices")
+	assert.False(t, ok)
+
+	Delete("rule1")
+	_, ok = Get("rule1", "threshold")
+	assert.False(t, ok)
+	Set("rule1", map[string]any{"threshold": 50})
+	Set("rule1", nil)
+	_, ok = Get("rule1", "threshold")
+	assert.False(t, ok)
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+const dateLayout = "2006-01-02"
+
+// TimeRange is a daily time of day window such as the shift hours. If End is before Begin, the window crosses midnight.
+// Weekdays limits the days on which the window begins, 0 is Sunday. Empty means every day.
+type TimeRange struct {
+	Begin    string `json:"begin" yaml:"begin"`
+	End      string `json:"end" yaml:"end"`
+	Weekdays []int  `json:"weekdays,omitempty" yaml:"weekdays,omitempty"`
+}
+
+// LoadLocation returns the location of the IANA time zone name. The configured time zone is used if name is empty.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return cast.GetConfiguredTimeZone(), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %s: %v", name, err)
+	}
+	return loc, nil
+}
+
+// IsInTimeRanges checks whether now is in any of the daily time ranges. The ranges are evaluated in the location of now.
+func IsInTimeRanges(now time.Time, ranges []TimeRange) (bool, error) {
+	if len(ranges) < 1 {
+		return true, nil
+	}
+	for _, r := range ranges {
+		isIn, err := r.contains(now)
+		if err != nil {
+			return false, err
+		}
+		if isIn {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r TimeRange) contains(now time.Time) (bool, error) {
+	b, err := parseTimeOfDay(r.Begin)
+	if err != nil {
+		return false, err
+	}
+	e, err := parseTimeOfDay(r.End)
+	if err != nil {
+		return false, err
+	}
+	t := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	if b < e {
+		return t >= b && t < e && r.onWeekday(now.Weekday()), nil
+	}
+	// cross midnight, the early part belongs to the window began in the previous day
+	if t >= b {
+		return r.onWeekday(now.Weekday()), nil
+	}
+	if t < e {
+		return r.onWeekday(now.AddDate(0, 0, -1).Weekday()), nil
+	}
+	return false, nil
+}
+
+func (r TimeRange) onWeekday(d time.Weekday) bool {
+	if len(r.Weekdays) == 0 {
+		return true
+	}
+	for _, w := range r.Weekdays {
+		if time.Weekday(w) == d {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimeOfDay parses the time of day in the format of 15:04 or 15:04:05 to the offset since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	var (
+		t   time.Time
+		err error
+	)
+	if len(s) == len("15:04") {
+		t, err = time.Parse("15:04", s)
+	} else {
+		t, err = time.Parse("15:04:05", s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %s, expect the format of 15:04 or 15:04:05", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+}
+
+// IsExcluded checks whether the date of now is in the exclusion calendar. The dates are in the format of 2006-01-02
+// and are evaluated in the location of now.
+func IsExcluded(now time.Time, dates []string) (bool, error) {
+	if len(dates) < 1 {
+		return false, nil
+	}
+	if err := ValidateExclusions(dates); err != nil {
+		return false, err
+	}
+	today := now.Format(dateLayout)
+	for _, d := range dates {
+		if d == today {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func ValidateTimeRanges(ranges []TimeRange) error {
+	for _, r := range ranges {
+		b, err := parseTimeOfDay(r.Begin)
+		if err != nil {
+			return err
+		}
+		e, err := parseTimeOfDay(r.End)
+		if err != nil {
+			return err
+		}
+		if b == e {
+			return fmt.Errorf("begin time %s shouldn't equal to end time", r.Begin)
+		}
+		for _, w := range r.Weekdays {
+			if w < 0 || w > 6 {
+				return fmt.Errorf("invalid weekday %d, expect 0 (Sunday) to 6 (Saturday)", w)
+			}
+		}
+	}
+	return nil
+}
+
+func ValidateExclusions(dates []string) error {
+	for _, d := range dates {
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return fmt.Errorf("invalid exclusion date %s, expect the format of 2006-01-02", d)
+		}
+	}
+	return nil
+}
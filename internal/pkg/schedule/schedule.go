@@ -33,6 +33,12 @@ type DatetimeRange struct {
 }
 
 func IsInScheduleRanges(now time.Time, timeRanges []DatetimeRange) (bool, error) {
+	return IsInScheduleRangesInLocation(now, timeRanges, nil)
+}
+
+// IsInScheduleRangesInLocation is like IsInScheduleRanges but parses the datetime strings in the given location.
+// The configured time zone is used if loc is nil.
+func IsInScheduleRangesInLocation(now time.Time, timeRanges []DatetimeRange, loc *time.Location) (bool, error) {
 	if len(timeRanges) < 1 {
 		return true, nil
 	}
@@ -46,7 +52,7 @@ func IsInScheduleRanges(now time.Time, timeRanges []DatetimeRange) (bool, error)
 				return true, nil
 			}
 		} else {
-			isIn, err := isInScheduleRange(now, tRange.Begin, tRange.End)
+			isIn, err := isInScheduleRange(now, tRange.Begin, tRange.End, loc)
 			if err != nil {
 				return false, err
 			}
@@ -58,8 +64,8 @@ func IsInScheduleRanges(now time.Time, timeRanges []DatetimeRange) (bool, error)
 	return false, nil
 }
 
-func isInScheduleRange(now time.Time, start string, end string) (bool, error) {
-	return isInTimeRange(now, start, end)
+func isInScheduleRange(now time.Time, start string, end string, loc *time.Location) (bool, error) {
+	return isInTimeRange(now, start, end, loc)
 }
 
 func isInScheduleRangeByTS(now time.Time, startTS int64, endTS int64) (bool, error) {
@@ -79,12 +85,12 @@ func isInScheduleRangeByTS(now time.Time, startTS int64, endTS int64) (bool, err
 	return false, nil
 }
 
-func isInTimeRange(now time.Time, start string, end string) (bool, error) {
-	s, err := cast.InterfaceToTime(start, layout)
+func isInTimeRange(now time.Time, start string, end string, loc *time.Location) (bool, error) {
+	s, err := cast.InterfaceToTimeInLocation(start, layout, loc)
 	if err != nil {
 		return false, err
 	}
-	e, err := cast.InterfaceToTime(end, layout)
+	e, err := cast.InterfaceToTimeInLocation(end, layout, loc)
 	if err != nil {
 		return false, err
 	}
@@ -97,6 +103,11 @@ func isInTimeRange(now time.Time, start string, end string) (bool, error) {
 }
 
 func IsAfterTimeRanges(now time.Time, ranges []DatetimeRange) bool {
+	return IsAfterTimeRangesInLocation(now, ranges, nil)
+}
+
+// IsAfterTimeRangesInLocation is like IsAfterTimeRanges but parses the datetime strings in the given location.
+func IsAfterTimeRangesInLocation(now time.Time, ranges []DatetimeRange, loc *time.Location) bool {
 	if len(ranges) < 1 {
 		return false
 	}
@@ -107,7 +118,7 @@ func IsAfterTimeRanges(now time.Time, ranges []DatetimeRange) bool {
 				return false
 			}
 		} else {
-			isAfter, err := isAfterTimeRange(now, r.End)
+			isAfter, err := isAfterTimeRange(now, r.End, loc)
 			if err != nil || !isAfter {
 				return false
 			}
@@ -128,8 +139,8 @@ func isAfterTimeByTS(now time.Time, end int64) (bool, error) {
 	return isAfterTime(now, e), nil
 }
 
-func isAfterTimeRange(now time.Time, end string) (bool, error) {
-	e, err := cast.InterfaceToTime(end, layout)
+func isAfterTimeRange(now time.Time, end string, loc *time.Location) (bool, error) {
+	e, err := cast.InterfaceToTimeInLocation(end, layout, loc)
 	if err != nil {
 		return false, err
 	}
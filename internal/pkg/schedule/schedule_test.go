@@ -208,3 +208,84 @@ func TestValidateSchedule(t *testing.T) {
 		}
 	}
 }
+
+func TestIsInTimeRanges(t *testing.T) {
+	// Monday
+	now, err := time.Parse(layout, "2006-01-02 01:30:00")
+	require.NoError(t, err)
+	testcases := []struct {
+		ranges []TimeRange
+		isIn   bool
+		err    string
+	}{
+		{
+			isIn: true,
+		},
+		{
+			ranges: []TimeRange{{Begin: "01:00", End: "02:00"}},
+			isIn:   true,
+		},
+		{
+			ranges: []TimeRange{{Begin: "08:00", End: "17:00"}, {Begin: "01:00:00", End: "01:30:00"}},
+			isIn:   false,
+		},
+		{
+			ranges: []TimeRange{{Begin: "22:00", End: "06:00", Weekdays: []int{0}}},
+			isIn:   true,
+		},
+		{
+			ranges: []TimeRange{{Begin: "22:00", End: "06:00", Weekdays: []int{1}}},
+			isIn:   false,
+		},
+		{
+			ranges: []TimeRange{{Begin: "1:00pm", End: "06:00"}},
+			err:    "invalid time of day 1:00pm, expect the format of 15:04 or 15:04:05",
+		},
+	}
+	for i, tc := range testcases {
+		isIn, err := IsInTimeRanges(now, tc.ranges)
+		if tc.err != "" {
+			require.EqualError(t, err, tc.err, i)
+			continue
+		}
+		require.NoError(t, err, i)
+		require.Equal(t, tc.isIn, isIn, i)
+	}
+}
+
+func TestIsExcluded(t *testing.T) {
+	now, err := time.Parse(layout, "2006-01-02 23:30:00")
+	require.NoError(t, err)
+	isEx, err := IsExcluded(now, []string{"2006-01-01", "2006-01-02"})
+	require.NoError(t, err)
+	require.True(t, isEx)
+	loc, err := LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	isEx, err = IsExcluded(now.In(loc), []string{"2006-01-02"})
+	require.NoError(t, err)
+	require.False(t, isEx)
+	_, err = IsExcluded(now, []string{"2006/01/02"})
+	require.EqualError(t, err, "invalid exclusion date 2006/01/02, expect the format of 2006-01-02")
+}
+
+func TestValidateCalendar(t *testing.T) {
+	require.NoError(t, ValidateTimeRanges([]TimeRange{{Begin: "22:00", End: "06:00", Weekdays: []int{0, 6}}}))
+	require.EqualError(t, ValidateTimeRanges([]TimeRange{{Begin: "08:00", End: "08:00"}}), "begin time 08:00 shouldn't equal to end time")
+	require.EqualError(t, ValidateTimeRanges([]TimeRange{{Begin: "08:00", End: "17:00", Weekdays: []int{7}}}), "invalid weekday 7, expect 0 (Sunday) to 6 (Saturday)")
+	require.NoError(t, ValidateExclusions([]string{"2024-12-25"}))
+	require.Error(t, ValidateExclusions([]string{"12-25"}))
+	_, err := LoadLocation("Not/Exist")
+	require.Error(t, err)
+}
+
+func TestIsInScheduleRangesInLocation(t *testing.T) {
+	now, err := time.Parse(layout, "2006-01-02 15:04:05")
+	require.NoError(t, err)
+	loc, err := LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	r := []DatetimeRange{{Begin: "2006-01-02 23:00:00", End: "2006-01-02 23:10:00"}}
+	isIn, err := IsInScheduleRangesInLocation(now, r, loc)
+	require.NoError(t, err)
+	require.True(t, isIn)
+	require.True(t, IsAfterTimeRangesInLocation(now.Add(time.Hour), r, loc))
+}
@@ -24,9 +24,12 @@ type Database interface {
 type Config struct {
 	Type         string
 	ExtStateType string
-	Redis        RedisConfig
-	Sqlite       SqliteConfig
-	Fdb          FdbConfig
+	// StateType is the store type of the rule checkpoints. It is the same as Type if not set.
+	StateType string
+	Redis     RedisConfig
+	Sqlite    SqliteConfig
+	Fdb       FdbConfig
+	Pebble    PebbleConfig
 }
 
 type RedisConfig struct {
@@ -46,3 +49,16 @@ type FdbConfig struct {
 	APIVersion int
 	Timeout    int64
 }
+
+type PebbleConfig struct {
+	Path string
+	// MemTableSize is the size in bytes of a memtable. Default to 4MB.
+	MemTableSize int64
+	// BlockCacheSize is the size in bytes of the block cache. Default to 8MB.
+	BlockCacheSize int64
+	// MaxConcurrentCompactions limits the background compactions to save the IO of the device. Default to 1.
+	MaxConcurrentCompactions int
+	// CompactionInterval is the interval to compact the deleted ranges such as the expired checkpoints to reclaim the
+	// disk space. Disabled if not positive.
+	CompactionInterval time.Duration
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pebbledb || !core
+
+package pebble
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf/logger"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/definition"
+)
+
+const (
+	defaultMemTableSize   = 4 << 20
+	defaultBlockCacheSize = 8 << 20
+)
+
+var (
+	// The databases are shared by the stores of the same name because a pebble folder can only be opened once
+	databases  = make(map[string]*database)
+	databaseMu sync.Mutex
+)
+
+// database is a pebble LSM database. The tables of the kv and ts stores are the key prefixes in it.
+type database struct {
+	db  *pebble.DB
+	dir string
+	mu  sync.Mutex
+	// deleted are the key ranges deleted since the last compaction, indexed by the table prefix
+	deleted map[string]keyRange
+}
+
+type keyRange struct {
+	start []byte
+	end   []byte
+}
+
+func NewPebbleFromConf(c definition.Config, name string) (*database, error) {
+	conf := c.Pebble
+	dir := filepath.Join(conf.Path, strings.TrimSuffix(name, filepath.Ext(name))+".pebble")
+	databaseMu.Lock()
+	defer databaseMu.Unlock()
+	if d, ok := databases[dir]; ok {
+		return d, nil
+	}
+	if conf.MemTableSize <= 0 {
+		conf.MemTableSize = defaultMemTableSize
+	}
+	if conf.BlockCacheSize <= 0 {
+		conf.BlockCacheSize = defaultBlockCacheSize
+	}
+	if conf.MaxConcurrentCompactions <= 0 {
+		conf.MaxConcurrentCompactions = 1
+	}
+	cache := pebble.NewCache(conf.BlockCacheSize)
+	defer cache.Unref()
+	db, err := pebble.Open(dir, &pebble.Options{
+		Cache:                    cache,
+		MemTableSize:             uint64(conf.MemTableSize),
+		MaxConcurrentCompactions: func() int { return conf.MaxConcurrentCompactions },
+		Logger:                   pebbleLogger{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open pebble database %s error: %v", dir, err)
+	}
+	d := &database{
+		db:      db,
+		dir:     dir,
+		deleted: make(map[string]keyRange),
+	}
+	if conf.CompactionInterval > 0 {
+		go d.runCompaction(conf.CompactionInterval)
+	}
+	databases[dir] = d
+	return d, nil
+}
+
+// deleteRange deletes the keys in [start, end) and records the range to compact
+func (d *database) deleteRange(prefix string, start, end []byte) error {
+	if err := d.db.DeleteRange(start, end, pebble.Sync); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if r, ok := d.deleted[prefix]; ok {
+		if string(start) < string(r.start) {
+			r.start = start
+		}
+		if string(end) > string(r.end) {
+			r.end = end
+		}
+		d.deleted[prefix] = r
+	} else {
+		d.deleted[prefix] = keyRange{start: start, end: end}
+	}
+	return nil
+}
+
+// runCompaction compacts the deleted ranges periodically. The deleted keys only free the disk space after the
+// compaction, which may come late when the writes are few such as on the edge.
+func (d *database) runCompaction(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		d.compact()
+	}
+}
+
+func (d *database) compact() {
+	d.mu.Lock()
+	ranges := d.deleted
+	d.deleted = make(map[string]keyRange)
+	d.mu.Unlock()
+	for prefix, r := range ranges {
+		// The compaction rewrites the range before removing the old files, so it needs the free space of the range size
+		size, err := d.db.EstimateDiskUsage(r.start, r.end)
+		if err == nil {
+			usage, err := vfs.Default.GetDiskUsage(d.dir)
+			if err == nil && usage.AvailBytes < size {
+				logger.Log.Warnf("skip compacting %s in pebble %s, the free disk space %d is less than %d", prefix, d.dir, usage.AvailBytes, size)
+				d.mu.Lock()
+				if _, ok := d.deleted[prefix]; !ok {
+					d.deleted[prefix] = r
+				}
+				d.mu.Unlock()
+				continue
+			}
+		}
+		if err := d.db.Compact(r.start, r.end, false); err != nil {
+			logger.Log.Warnf("compact %s in pebble %s error: %v", prefix, d.dir, err)
+		}
+	}
+}
+
+// tablePrefix returns the key prefix of a table, the tables of different kinds are separated by the kind
+func tablePrefix(kind, table string) string {
+	return kind + "\x00" + table + "\x00"
+}
+
+// prefixEnd returns the exclusive upper bound of the keys with the prefix which ends with the separator
+func prefixEnd(prefix string) []byte {
+	b := []byte(prefix)
+	b[len(b)-1] = 1
+	return b
+}
+
+// pebbleLogger writes the verbose pebble logs such as the flushes and compactions in debug level
+type pebbleLogger struct{}
+
+func (pebbleLogger) Infof(format string, args ...interface{}) {
+	logger.Log.Debugf(format, args...)
+}
+
+func (pebbleLogger) Fatalf(format string, args ...interface{}) {
+	logger.Log.Fatalf(format, args...)
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pebbledb || !core
+
+package pebble
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf/logger"
+	kvEncoding "github.com/lf-edge/ekuiper/v2/internal/pkg/store/encoding"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+const kvKind = "kv"
+
+type pebbleKvStore struct {
+	database *database
+	table    string
+	prefix   string
+	// mu guards the read and write of Setnx and Delete
+	mu sync.Mutex
+}
+
+func createPebbleKvStore(d *database, table string) (*pebbleKvStore, error) {
+	return &pebbleKvStore{
+		database: d,
+		table:    table,
+		prefix:   tablePrefix(kvKind, table),
+	}, nil
+}
+
+func (kv *pebbleKvStore) Setnx(key string, value interface{}) error {
+	b, err := kvEncoding.Encode(value)
+	if err != nil {
+		return err
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	found, err := kv.exists(key)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("key %s already exists", key)
+	}
+	return kv.database.db.Set(kv.tableKey(key), b, pebble.Sync)
+}
+
+func (kv *pebbleKvStore) Set(key string, value interface{}) error {
+	b, err := kvEncoding.Encode(value)
+	if err != nil {
+		return err
+	}
+	return kv.database.db.Set(kv.tableKey(key), b, pebble.Sync)
+}
+
+func (kv *pebbleKvStore) Get(key string, value interface{}) (bool, error) {
+	v, closer, err := kv.database.db.Get(kv.tableKey(key))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer closer.Close()
+	if err := gob.NewDecoder(bytes.NewReader(v)).Decode(value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (kv *pebbleKvStore) GetKeyedState(key string) (interface{}, error) {
+	var value interface{}
+	found, err := kv.Get(key, &value)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not found", key)
+	}
+	return value, nil
+}
+
+func (kv *pebbleKvStore) SetKeyedState(key string, value interface{}) error {
+	// Encode as an interface so that it can be decoded without knowing the type
+	return kv.Set(key, &value)
+}
+
+func (kv *pebbleKvStore) Delete(key string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	found, err := kv.exists(key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("%s is not found", key))
+	}
+	return kv.database.db.Delete(kv.tableKey(key), pebble.Sync)
+}
+
+func (kv *pebbleKvStore) Keys() ([]string, error) {
+	result := make([]string, 0)
+	err := kv.iterate(func(key string, _ []byte) error {
+		result = append(result, key)
+		return nil
+	})
+	return result, err
+}
+
+func (kv *pebbleKvStore) All() (map[string]string, error) {
+	result := make(map[string]string)
+	err := kv.iterate(func(key string, v []byte) error {
+		var value string
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&value); err != nil {
+			logger.Log.Errorf("get %s fail during get all in pebble: %v", key, err)
+			return nil
+		}
+		result[key] = value
+		return nil
+	})
+	return result, err
+}
+
+func (kv *pebbleKvStore) Clean() error {
+	return kv.database.deleteRange(kv.prefix, []byte(kv.prefix), prefixEnd(kv.prefix))
+}
+
+func (kv *pebbleKvStore) Drop() error {
+	return kv.Clean()
+}
+
+func (kv *pebbleKvStore) exists(key string) (bool, error) {
+	_, closer, err := kv.database.db.Get(kv.tableKey(key))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, closer.Close()
+}
+
+// iterate visits the keys of the table in order
+func (kv *pebbleKvStore) iterate(f func(key string, v []byte) error) error {
+	iter, err := kv.database.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(kv.prefix),
+		UpperBound: prefixEnd(kv.prefix),
+	})
+	if err != nil {
+		return err
+	}
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := f(string(iter.Key()[len(kv.prefix):]), iter.Value()); err != nil {
+			_ = iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
+}
+
+func (kv *pebbleKvStore) tableKey(key string) []byte {
+	return []byte(kv.prefix + key)
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pebbledb || !core
+
+package pebble
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/definition"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/test/common"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+)
+
+func TestPebbleKvSetnx(t *testing.T) {
+	common.TestKvSetnx(setupPebbleKv(t, "test"), t)
+}
+
+func TestPebbleKvSet(t *testing.T) {
+	common.TestKvSet(setupPebbleKv(t, "test"), t)
+}
+
+func TestPebbleKvSetGet(t *testing.T) {
+	common.TestKvSetGet(setupPebbleKv(t, "test"), t)
+}
+
+func TestPebbleKvGet(t *testing.T) {
+	common.TestKvGet(setupPebbleKv(t, "test"), t)
+}
+
+func TestPebbleKvKeys(t *testing.T) {
+	common.TestKvKeys(10, setupPebbleKv(t, "test"), t)
+}
+
+func TestPebbleKvAll(t *testing.T) {
+	common.TestKvAll(10, setupPebbleKv(t, "test"), t)
+}
+
+func TestPebbleKvGetKeyedState(t *testing.T) {
+	common.TestKvGetKeyedState(setupPebbleKv(t, "test"), t)
+}
+
+// The tables with the common prefix are isolated
+func TestPebbleKvTables(t *testing.T) {
+	dir := t.TempDir()
+	b, _, err := BuildStores(definition.Config{Pebble: definition.PebbleConfig{Path: dir}}, "sqliteKV.db")
+	require.NoError(t, err)
+	ks1, err := b.CreateStore("rule")
+	require.NoError(t, err)
+	ks2, err := b.CreateStore("rule1")
+	require.NoError(t, err)
+	require.NoError(t, ks1.Set("a", "1"))
+	require.NoError(t, ks2.Set("b", "2"))
+	keys, err := ks1.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, keys)
+
+	err = ks1.Delete("b")
+	assert.Equal(t, errorx.NOT_FOUND, err.(errorx.ErrorWithCode).Code())
+	require.NoError(t, ks1.Drop())
+	keys, err = ks1.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	all, err := ks2.All()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"b": "2"}, all)
+
+	// Reopen the same database
+	b, _, err = BuildStores(definition.Config{Pebble: definition.PebbleConfig{Path: dir}}, "sqliteKV.db")
+	require.NoError(t, err)
+	ks2, err = b.CreateStore("rule1")
+	require.NoError(t, err)
+	var v string
+	found, err := ks2.Get("b", &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "2", v)
+}
+
+func setupPebbleKv(t *testing.T, table string) kv.KeyValue {
+	b, _, err := BuildStores(definition.Config{Pebble: definition.PebbleConfig{Path: t.TempDir()}}, "sqliteKV.db")
+	require.NoError(t, err)
+	ks, err := b.CreateStore(table)
+	require.NoError(t, err)
+	return ks
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pebbledb || !core
+
+package pebble
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+
+	"github.com/cockroachdb/pebble"
+
+	kvEncoding "github.com/lf-edge/ekuiper/v2/internal/pkg/store/encoding"
+)
+
+const tsKind = "ts"
+
+// ts keeps the records in the order of the keys which are encoded in big endian
+type ts struct {
+	database *database
+	table    string
+	prefix   string
+	last     int64
+}
+
+func createPebbleTs(d *database, table string) (*ts, error) {
+	t := &ts{
+		database: d,
+		table:    table,
+		prefix:   tablePrefix(tsKind, table),
+	}
+	last, err := t.Last(nil)
+	if err != nil {
+		return nil, err
+	}
+	t.last = last
+	return t, nil
+}
+
+func (t *ts) Set(key int64, value interface{}) (bool, error) {
+	if key <= t.last {
+		return false, nil
+	}
+	b, err := kvEncoding.Encode(value)
+	if err != nil {
+		return false, err
+	}
+	if err := t.database.db.Set(t.tableKey(key), b, pebble.Sync); err != nil {
+		return false, err
+	}
+	t.last = key
+	return true, nil
+}
+
+func (t *ts) Get(key int64, value interface{}) (bool, error) {
+	v, closer, err := t.database.db.Get(t.tableKey(key))
+	if errors.Is(err, pebble.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer closer.Close()
+	if err := gob.NewDecoder(bytes.NewReader(v)).Decode(value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (t *ts) Last(value interface{}) (int64, error) {
+	iter, err := t.database.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(t.prefix),
+		UpperBound: prefixEnd(t.prefix),
+	})
+	if err != nil {
+		return 0, err
+	}
+	var last int64
+	if iter.Last() {
+		last = int64(binary.BigEndian.Uint64(iter.Key()[len(t.prefix):]))
+		if value != nil {
+			if err := gob.NewDecoder(bytes.NewReader(iter.Value())).Decode(value); err != nil {
+				_ = iter.Close()
+				return 0, err
+			}
+		}
+	}
+	return last, iter.Close()
+}
+
+func (t *ts) Delete(key int64) error {
+	return t.database.db.Delete(t.tableKey(key), pebble.Sync)
+}
+
+// DeleteBefore deletes the records whose key is not bigger than the key
+func (t *ts) DeleteBefore(key int64) error {
+	return t.database.deleteRange(t.prefix, []byte(t.prefix), t.tableKey(key+1))
+}
+
+func (t *ts) Close() error {
+	return nil
+}
+
+func (t *ts) Drop() error {
+	t.last = 0
+	return t.database.deleteRange(t.prefix, []byte(t.prefix), prefixEnd(t.prefix))
+}
+
+func (t *ts) tableKey(key int64) []byte {
+	b := make([]byte, len(t.prefix)+8)
+	copy(b, t.prefix)
+	binary.BigEndian.PutUint64(b[len(t.prefix):], uint64(key))
+	return b
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pebbledb || !core
+
+package pebble
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/definition"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/test/common"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+)
+
+func TestPebbleTsSet(t *testing.T) {
+	common.TestTsSet(setupPebbleTs(t), t)
+}
+
+func TestPebbleTsLast(t *testing.T) {
+	common.TestTsLast(setupPebbleTs(t), t)
+}
+
+func TestPebbleTsGet(t *testing.T) {
+	common.TestTsGet(setupPebbleTs(t), t)
+}
+
+func TestPebbleTsDelete(t *testing.T) {
+	common.TestTsDelete(setupPebbleTs(t), t)
+}
+
+func TestPebbleTsDeleteBefore(t *testing.T) {
+	common.TestTsDeleteBefore(setupPebbleTs(t), t)
+}
+
+func TestPebbleTsCompact(t *testing.T) {
+	_, tb, err := BuildStores(definition.Config{Pebble: definition.PebbleConfig{Path: t.TempDir(), MemTableSize: 1 << 16}}, "state.db")
+	require.NoError(t, err)
+	ks, err := tb.CreateTs("rule1")
+	require.NoError(t, err)
+	other, err := tb.CreateTs("rule2")
+	require.NoError(t, err)
+	for i := int64(1); i <= 100; i++ {
+		ok, err := ks.Set(i, fmt.Sprintf("state%d", i))
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	ok, err := other.Set(1, "other")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, ks.DeleteBefore(90))
+	d := tb.(TsBuilder).database
+	assert.Len(t, d.deleted, 1)
+	d.compact()
+	assert.Len(t, d.deleted, 0)
+
+	var v string
+	k, err := ks.Last(&v)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), k)
+	assert.Equal(t, "state100", v)
+	found, err := ks.Get(90, &v)
+	require.NoError(t, err)
+	assert.False(t, found)
+	found, err = ks.Get(91, &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	k, err = other.Last(&v)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), k)
+
+	// The last key is loaded when the ts is created again
+	require.NoError(t, other.Drop())
+	ks, err = tb.CreateTs("rule1")
+	require.NoError(t, err)
+	ok, err = ks.Set(100, "dup")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	k, err = other.Last(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), k)
+}
+
+func setupPebbleTs(t *testing.T) kv.Tskv {
+	_, tb, err := BuildStores(definition.Config{Pebble: definition.PebbleConfig{Path: t.TempDir()}}, "state.db")
+	require.NoError(t, err)
+	ks, err := tb.CreateTs("test")
+	require.NoError(t, err)
+	return ks
+}
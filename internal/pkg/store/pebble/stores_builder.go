@@ -0,0 +1,46 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build pebbledb || !core
+
+package pebble
+
+import (
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/definition"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+)
+
+func BuildStores(c definition.Config, name string) (definition.StoreBuilder, definition.TsBuilder, error) {
+	d, err := NewPebbleFromConf(c, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return StoreBuilder{database: d}, TsBuilder{database: d}, nil
+}
+
+type StoreBuilder struct {
+	database *database
+}
+
+func (b StoreBuilder) CreateStore(table string) (kv.KeyValue, error) {
+	return createPebbleKvStore(b.database, table)
+}
+
+type TsBuilder struct {
+	database *database
+}
+
+func (b TsBuilder) CreateTs(table string) (kv.Tskv, error) {
+	return createPebbleTs(b.database, table)
+}
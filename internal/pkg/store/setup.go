@@ -24,9 +24,11 @@ import (
 type StoreConf struct {
 	Type         string
 	ExtStateType string
+	StateType    string
 	RedisConfig  definition.RedisConfig
 	SqliteConfig definition.SqliteConfig
 	FdbConfig    definition.FdbConfig
+	PebbleConfig definition.PebbleConfig
 }
 
 func SetupDefault(dataDir string) error {
@@ -48,9 +50,11 @@ func SetupWithConfig(sc *StoreConf) error {
 	c := definition.Config{
 		Type:         sc.Type,
 		ExtStateType: sc.ExtStateType,
+		StateType:    sc.StateType,
 		Redis:        sc.RedisConfig,
 		Sqlite:       sc.SqliteConfig,
 		Fdb:          sc.FdbConfig,
+		Pebble:       sc.PebbleConfig,
 	}
 	return Setup(c)
 }
@@ -71,6 +75,15 @@ func Setup(config definition.Config) error {
 		return err
 	}
 	extStateStores = s
+	if config.StateType == "" || config.StateType == config.Type {
+		stateStores = globalStores
+	} else {
+		s, err = newStateStores(config, "state.db")
+		if err != nil {
+			return err
+		}
+		stateStores = s
+	}
 	db, err := sqldb.BuildSqliteStore(config, "trace.db")
 	if err != nil {
 		return err
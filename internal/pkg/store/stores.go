@@ -34,6 +34,9 @@ var (
 	globalStores   *stores = nil
 	cacheStores    *stores = nil
 	extStateStores *stores = nil
+	// stateStores keep the checkpoints of the rules. They can be in another store such as redis so that a standby
+	// node attached to the same store can take over the rules with their states.
+	stateStores *stores = nil
 
 	TraceStores sql.Database
 )
@@ -86,6 +89,26 @@ func newExtStateStores(c definition.Config, name string) (*stores, error) {
 	}
 }
 
+func newStateStores(c definition.Config, name string) (*stores, error) {
+	databaseType := c.StateType
+	if builder, ok := storeBuilders[databaseType]; ok {
+		kvBuilder, tsBuilder, err := builder(c, name)
+		if err != nil {
+			return nil, err
+		} else {
+			return &stores{
+				kv:        make(map[string]kv.KeyValue),
+				ts:        make(map[string]kv.Tskv),
+				mu:        sync.Mutex{},
+				kvBuilder: kvBuilder,
+				tsBuilder: tsBuilder,
+			}, nil
+		}
+	} else {
+		return nil, fmt.Errorf("unknown stateStore type: %s", databaseType)
+	}
+}
+
 func (s *stores) GetKV(table string) (kv.KeyValue, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -106,7 +129,7 @@ func (s *stores) DropKV(table string) {
 
 	if ks, contains := s.kv[table]; contains {
 		_ = ks.Drop()
-		delete(s.ts, table)
+		delete(s.kv, table)
 	}
 }
 
@@ -176,6 +199,29 @@ func DropKV(table string) error {
 	return nil
 }
 
+// GetSpillKV returns the store of the rule for the data spilled to the disk by its operators
+func GetSpillKV(rule string) (kv.KeyValue, error) {
+	return GetKV(spillTable(rule))
+}
+
+// DropSpillKV drops the spilled data of the rule. The store is opened before dropping so that the data spilled
+// before a restart is dropped too.
+func DropSpillKV(rule string) error {
+	if globalStores == nil {
+		return fmt.Errorf("global stores are not initialized")
+	}
+	table := spillTable(rule)
+	if _, err := globalStores.GetKV(table); err != nil {
+		return err
+	}
+	globalStores.DropKV(table)
+	return nil
+}
+
+func spillTable(rule string) string {
+	return "spill_" + rule
+}
+
 func GetCacheKV(table string) (kv.KeyValue, error) {
 	if cacheStores == nil {
 		return nil, fmt.Errorf("cache stores are not initialized")
@@ -205,3 +251,19 @@ func GetExtStateKV(table string) (kv.KeyValue, error) {
 	}
 	return extStateStores.GetKV(table)
 }
+
+// GetStateTS returns the checkpoint store of the rule
+func GetStateTS(table string) (kv.Tskv, error) {
+	if stateStores == nil {
+		return nil, fmt.Errorf("state stores are not initialized")
+	}
+	return stateStores.GetTS(table)
+}
+
+func DropStateTS(table string) error {
+	if stateStores == nil {
+		return fmt.Errorf("state stores are not initialized")
+	}
+	stateStores.DropTS(table)
+	return nil
+}
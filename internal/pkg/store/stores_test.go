@@ -0,0 +1,84 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build redisdb || !core
+
+package store
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/definition"
+)
+
+func TestStateStoreDefault(t *testing.T) {
+	require.NoError(t, SetupDefault(t.TempDir()))
+	assert.Same(t, globalStores, stateStores)
+	s, err := GetStateTS("rule1")
+	require.NoError(t, err)
+	g, err := GetTS("rule1")
+	require.NoError(t, err)
+	assert.Same(t, g, s)
+}
+
+// Two nodes with their own local stores share the rule states in redis
+func TestStateStoreRedis(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	newConfig := func() definition.Config {
+		return definition.Config{
+			Type:         "sqlite",
+			ExtStateType: "sqlite",
+			StateType:    "redis",
+			Redis:        definition.RedisConfig{Host: mr.Host(), Port: int(mr.Server().Addr().Port)},
+			Sqlite:       definition.SqliteConfig{Path: t.TempDir()},
+		}
+	}
+	require.NoError(t, Setup(newConfig()))
+	assert.NotSame(t, globalStores, stateStores)
+	s, err := GetStateTS("rule1")
+	require.NoError(t, err)
+	ok, err := s.Set(100, map[string]any{"op1": "state"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, mr.Exists("KV:TS:rule1"))
+	// Not in the local store
+	g, err := GetTS("rule1")
+	require.NoError(t, err)
+	k, err := g.Last(&map[string]any{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), k)
+
+	// The standby node
+	require.NoError(t, Setup(newConfig()))
+	s, err = GetStateTS("rule1")
+	require.NoError(t, err)
+	var m map[string]any
+	k, err = s.Last(&m)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), k)
+	assert.Equal(t, map[string]any{"op1": "state"}, m)
+
+	require.NoError(t, DropStateTS("rule1"))
+	assert.False(t, mr.Exists("KV:TS:rule1"))
+
+	c := newConfig()
+	c.StateType = "unknown"
+	assert.EqualError(t, Setup(c), "unknown stateStore type: unknown")
+}
@@ -38,8 +38,12 @@ type PortableFunc struct {
 	reg        *PluginMeta // initial plugin meta, only used for initialize the function instance
 	dataCh     DataReqChannel
 	isAgg      int // 0 - not calculate yet, 1 - no, 2 - yes
+	isAcc      int // whether the aggregate function implements the accumulator. 0 - not calculate yet, 1 - no, 2 - yes
 }
 
+// accumulateBatchSize is the max rows sent to the plugin in one Accumulate request
+const accumulateBatchSize = 1000
+
 func NewPortableFunc(symbolName string, reg *PluginMeta) (_ *PortableFunc, e error) {
 	// Setup channel and route the data
 	conf.Log.Infof("Start running portable function meta %+v", reg)
@@ -105,6 +109,13 @@ func (f *PortableFunc) Validate(args []interface{}) error {
 
 func (f *PortableFunc) Exec(ctx api.FunctionContext, args []any) (interface{}, bool) {
 	ctx.GetLogger().Debugf("running portable func with args %+v", args)
+	if f.IsAggregate() && f.isAccumulator() {
+		r, err := f.accumulate(ctx, args)
+		if err != nil {
+			return err, false
+		}
+		return r, true
+	}
 	ctxRaw, err := encodeCtx(ctx)
 	if err != nil {
 		return err, false
@@ -185,6 +196,111 @@ func (f *PortableFunc) IsAggregate() bool {
 	}
 }
 
+func (f *PortableFunc) isAccumulator() bool {
+	if f.isAcc > 0 {
+		return f.isAcc > 1
+	}
+	jsonArg, err := encode("IsAccumulator", nil)
+	if err != nil {
+		conf.Log.Error(err)
+		return false
+	}
+	res, err := f.dataCh.Req(jsonArg)
+	if err != nil {
+		conf.Log.Error(err)
+		return false
+	}
+	fr := &FuncReply{}
+	err = json.Unmarshal(res, fr)
+	if err != nil {
+		conf.Log.Error(err)
+		return false
+	}
+	// The plugin built by old sdk does not support the command
+	r, ok := fr.Result.(bool)
+	if fr.State && ok && r {
+		f.isAcc = 2
+	} else {
+		f.isAcc = 1
+	}
+	return f.isAcc > 1
+}
+
+// accumulate runs the accumulator lifecycle of the plugin for the column slices of a group.
+// The rows are sent in batches. Each batch is accumulated from a new accumulator and then merged.
+func (f *PortableFunc) accumulate(ctx api.FunctionContext, args []any) (any, error) {
+	ctxRaw, err := encodeCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l := 0
+	for _, arg := range args {
+		if col, ok := arg.([]any); ok && len(col) > l {
+			l = len(col)
+		}
+	}
+	var acc any
+	for start := 0; start < l || start == 0; start += accumulateBatchSize {
+		end := start + accumulateBatchSize
+		if end > l {
+			end = l
+		}
+		rows := make([]any, 0, end-start)
+		for i := start; i < end; i++ {
+			row := make([]any, len(args))
+			for j, arg := range args {
+				if col, ok := arg.([]any); ok {
+					if i < len(col) {
+						row[j] = col[i]
+					}
+				} else {
+					row[j] = arg
+				}
+			}
+			rows = append(rows, row)
+		}
+		batchAcc, err := f.callAccumulator("CreateAccumulator", []any{ctxRaw})
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 {
+			batchAcc, err = f.callAccumulator("Accumulate", []any{batchAcc, rows, ctxRaw})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if start == 0 {
+			acc = batchAcc
+		} else {
+			acc, err = f.callAccumulator("Merge", []any{acc, batchAcc, ctxRaw})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f.callAccumulator("Result", []any{acc, ctxRaw})
+}
+
+func (f *PortableFunc) callAccumulator(funcName string, args []any) (any, error) {
+	jsonArg, err := encode(funcName, args)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.dataCh.Req(jsonArg)
+	if err != nil {
+		return nil, handleTimeout(err, f.reg.Name)
+	}
+	fr := &FuncReply{}
+	err = json.Unmarshal(res, fr)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal function %s result %s", funcName, string(res))
+	}
+	if !fr.State {
+		return nil, fmt.Errorf("%s error: %v", funcName, fr.Result)
+	}
+	return fr.Result, nil
+}
+
 func (f *PortableFunc) Close() error {
 	return f.dataCh.Close()
 	// Symbol must be closed by instance manager
@@ -0,0 +1,94 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+)
+
+// mockAccChannel simulates a plugin which implements a sum accumulator
+type mockAccChannel struct {
+	calls []string
+}
+
+func (m *mockAccChannel) Req(req []byte) ([]byte, error) {
+	d := &FuncData{}
+	if err := json.Unmarshal(req, d); err != nil {
+		return nil, err
+	}
+	m.calls = append(m.calls, d.Func)
+	args, _ := d.Arg.([]any)
+	var r any
+	switch d.Func {
+	case "IsAggregate", "IsAccumulator":
+		r = true
+	case "CreateAccumulator":
+		r = 0.0
+	case "Accumulate":
+		acc := args[0].(float64)
+		for _, row := range args[1].([]any) {
+			if v, ok := row.([]any)[0].(float64); ok {
+				acc += v
+			}
+		}
+		r = acc
+	case "Merge":
+		r = args[0].(float64) + args[1].(float64)
+	case "Result":
+		r = fmt.Sprintf("sum:%v", args[0])
+	default:
+		return json.Marshal(FuncReply{State: false, Result: "invalid func"})
+	}
+	return json.Marshal(FuncReply{State: true, Result: r})
+}
+
+func (m *mockAccChannel) Close() error {
+	return nil
+}
+
+func TestPortableFuncAccumulate(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "testAcc")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	tempStore, _ := state.CreateStore("testAcc", def.AtMostOnce)
+	fctx := context.NewDefaultFuncContext(ctx.WithMeta("testAcc", "op1", tempStore), 1)
+	ch := &mockAccChannel{}
+	f := &PortableFunc{symbolName: "sum", reg: &PluginMeta{Name: "test"}, dataCh: ch}
+
+	col := make([]any, accumulateBatchSize+10)
+	for i := range col {
+		col[i] = 1
+	}
+	col[0] = nil
+	r, ok := f.Exec(fctx, []any{col})
+	require.True(t, ok, r)
+	assert.Equal(t, fmt.Sprintf("sum:%d", accumulateBatchSize+9), r)
+	assert.Equal(t, []string{"IsAggregate", "IsAccumulator", "CreateAccumulator", "Accumulate", "CreateAccumulator", "Accumulate", "Merge", "Result"}, ch.calls)
+
+	ch.calls = nil
+	r, ok = f.Exec(fctx, []any{[]any{}})
+	require.True(t, ok, r)
+	assert.Equal(t, "sum:0", r)
+	assert.Equal(t, []string{"CreateAccumulator", "Result"}, ch.calls)
+}
@@ -0,0 +1,356 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// The kinds of the resources owned by the namespaces
+const (
+	ResourceRule   = "rule"
+	ResourceStream = "stream"
+	ResourceTable  = "table"
+	ResourceConfig = "config"
+)
+
+var namespaceRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Namespace isolates the resources of a tenant. The resources created with the token of the namespace are owned by
+// it and are only visible to the requests of the same namespace.
+type Namespace struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Quota       *NamespaceQuota `json:"quota,omitempty"`
+}
+
+// NamespaceQuota limits the count of each kind of the resources in a namespace. 0 means no limit.
+type NamespaceQuota struct {
+	Rules   int `json:"rules,omitempty"`
+	Streams int `json:"streams,omitempty"`
+	Tables  int `json:"tables,omitempty"`
+	Configs int `json:"configs,omitempty"`
+}
+
+// NamespaceToken is the api token of a namespace. Only the hash of the token is saved.
+type NamespaceToken struct {
+	Id        string `json:"id"`
+	Namespace string `json:"namespace"`
+	CreatedAt int64  `json:"createdAt"`
+}
+
+type NamespaceProcessor struct {
+	db         kv.KeyValue
+	tokenDb    kv.KeyValue
+	resourceDb kv.KeyValue
+}
+
+func NewNamespaceProcessor() *NamespaceProcessor {
+	db, err := store.GetKV("namespace")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the namespace processor at path 'namespace': %v", err))
+	}
+	tokenDb, err := store.GetKV("namespaceToken")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the namespace processor at path 'namespaceToken': %v", err))
+	}
+	resourceDb, err := store.GetKV("namespaceResource")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the namespace processor at path 'namespaceResource': %v", err))
+	}
+	return &NamespaceProcessor{
+		db:         db,
+		tokenDb:    tokenDb,
+		resourceDb: resourceDb,
+	}
+}
+
+func (q *NamespaceQuota) limit(kind string) int {
+	if q == nil {
+		return 0
+	}
+	switch kind {
+	case ResourceRule:
+		return q.Rules
+	case ResourceStream:
+		return q.Streams
+	case ResourceTable:
+		return q.Tables
+	case ResourceConfig:
+		return q.Configs
+	default:
+		return 0
+	}
+}
+
+// ParseNamespace parses and validates the namespace json. The name is used if the json does not have one.
+func ParseNamespace(name, content string) (*Namespace, error) {
+	ns := &Namespace{}
+	if err := json.Unmarshal([]byte(content), ns); err != nil {
+		return nil, fmt.Errorf("parse namespace %s error: %v", content, err)
+	}
+	if ns.Name == "" {
+		ns.Name = name
+	}
+	if !namespaceRegex.MatchString(ns.Name) {
+		return nil, fmt.Errorf("invalid namespace name %q, it must consist of alphanumeric characters, '_' or '-' and start with an alphanumeric character", ns.Name)
+	}
+	if name != "" && ns.Name != name {
+		return nil, fmt.Errorf("namespace name %s does not match %s", ns.Name, name)
+	}
+	return ns, nil
+}
+
+func (p *NamespaceProcessor) Create(content string) (*Namespace, error) {
+	ns, err := ParseNamespace("", content)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(ns)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.db.Setnx(ns.Name, string(b)); err != nil {
+		return nil, fmt.Errorf("namespace %s already exists", ns.Name)
+	}
+	return ns, nil
+}
+
+func (p *NamespaceProcessor) Update(name, content string) (*Namespace, error) {
+	ns, err := ParseNamespace(name, content)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Get(name); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(ns)
+	if err != nil {
+		return nil, err
+	}
+	return ns, p.db.Set(name, string(b))
+}
+
+func (p *NamespaceProcessor) Get(name string) (*Namespace, error) {
+	var s string
+	ok, err := p.db.Get(name, &s)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Namespace %s is not found.", name))
+	}
+	ns := &Namespace{}
+	if err := json.Unmarshal([]byte(s), ns); err != nil {
+		return nil, fmt.Errorf("parse namespace %s error: %v", name, err)
+	}
+	return ns, nil
+}
+
+func (p *NamespaceProcessor) List() ([]string, error) {
+	keys, err := p.db.Keys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Delete drops the namespace and its tokens. The namespace must not own any resource.
+func (p *NamespaceProcessor) Delete(name string) error {
+	if _, err := p.Get(name); err != nil {
+		return err
+	}
+	resources, err := p.Resources(name)
+	if err != nil {
+		return err
+	}
+	if len(resources) > 0 {
+		return fmt.Errorf("namespace %s still has resources %v, please delete them first", name, resources)
+	}
+	all, err := p.tokenDb.All()
+	if err != nil {
+		return err
+	}
+	for k, v := range all {
+		t := &NamespaceToken{}
+		if err := json.Unmarshal([]byte(v), t); err == nil && t.Namespace == name {
+			_ = p.tokenDb.Delete(k)
+		}
+	}
+	return p.db.Delete(name)
+}
+
+// CreateToken generates a new api token of the namespace. The token is only returned here.
+func (p *NamespaceProcessor) CreateToken(name string) (string, *NamespaceToken, error) {
+	if _, err := p.Get(name); err != nil {
+		return "", nil, err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := hex.EncodeToString(b)
+	hash := hashToken(token)
+	// The id is from the hash so that it does not reveal the token
+	t := &NamespaceToken{
+		Id:        hash[:8],
+		Namespace: name,
+		CreatedAt: timex.GetNowInMilli(),
+	}
+	v, err := json.Marshal(t)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := p.tokenDb.Set(hash, string(v)); err != nil {
+		return "", nil, err
+	}
+	return token, t, nil
+}
+
+func (p *NamespaceProcessor) ListTokens(name string) ([]*NamespaceToken, error) {
+	if _, err := p.Get(name); err != nil {
+		return nil, err
+	}
+	all, err := p.tokenDb.All()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*NamespaceToken, 0)
+	for _, v := range all {
+		t := &NamespaceToken{}
+		if err := json.Unmarshal([]byte(v), t); err == nil && t.Namespace == name {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt < result[j].CreatedAt
+	})
+	return result, nil
+}
+
+func (p *NamespaceProcessor) DeleteToken(name, id string) error {
+	all, err := p.tokenDb.All()
+	if err != nil {
+		return err
+	}
+	for k, v := range all {
+		t := &NamespaceToken{}
+		if err := json.Unmarshal([]byte(v), t); err == nil && t.Namespace == name && t.Id == id {
+			return p.tokenDb.Delete(k)
+		}
+	}
+	return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Token %s of namespace %s is not found.", id, name))
+}
+
+// Authenticate returns the namespace of the token
+func (p *NamespaceProcessor) Authenticate(token string) (string, error) {
+	var v string
+	ok, err := p.tokenDb.Get(hashToken(token), &v)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("invalid namespace token")
+	}
+	t := &NamespaceToken{}
+	if err := json.Unmarshal([]byte(v), t); err != nil {
+		return "", err
+	}
+	return t.Namespace, nil
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// GetOwner returns the namespace owning the resource. It is empty if the resource is not owned by any namespace.
+func (p *NamespaceProcessor) GetOwner(kind, name string) string {
+	var ns string
+	if ok, err := p.resourceDb.Get(resourceKey(kind, name), &ns); err != nil || !ok {
+		return ""
+	}
+	return ns
+}
+
+func (p *NamespaceProcessor) SetOwner(kind, name, ns string) error {
+	return p.resourceDb.Set(resourceKey(kind, name), ns)
+}
+
+func (p *NamespaceProcessor) RemoveOwner(kind, name string) error {
+	key := resourceKey(kind, name)
+	var ns string
+	if ok, err := p.resourceDb.Get(key, &ns); err != nil || !ok {
+		return err
+	}
+	return p.resourceDb.Delete(key)
+}
+
+// Resources returns the names of the resources owned by the namespace by kind
+func (p *NamespaceProcessor) Resources(ns string) (map[string][]string, error) {
+	all, err := p.resourceDb.All()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]string)
+	for k, v := range all {
+		if v != ns {
+			continue
+		}
+		kind, name, _ := strings.Cut(k, ":")
+		result[kind] = append(result[kind], name)
+	}
+	for _, names := range result {
+		sort.Strings(names)
+	}
+	return result, nil
+}
+
+// CheckQuota returns error if the namespace cannot own more resources of the kind
+func (p *NamespaceProcessor) CheckQuota(ns, kind string) error {
+	n, err := p.Get(ns)
+	if err != nil {
+		return err
+	}
+	limit := n.Quota.limit(kind)
+	if limit <= 0 {
+		return nil
+	}
+	resources, err := p.Resources(ns)
+	if err != nil {
+		return err
+	}
+	if len(resources[kind]) >= limit {
+		return fmt.Errorf("the quota of %s in namespace %s is exceeded, the limit is %d", kind, ns, limit)
+	}
+	return nil
+}
+
+func resourceKey(kind, name string) string {
+	return kind + ":" + name
+}
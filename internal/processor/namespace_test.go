@@ -0,0 +1,71 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	p := NewNamespaceProcessor()
+	_ = p.RemoveOwner(ResourceRule, "nsRule1")
+	_ = p.RemoveOwner(ResourceRule, "nsRule2")
+	_ = p.Delete("team1")
+
+	_, err := p.Create(`{"name":"team 1"}`)
+	require.EqualError(t, err, `invalid namespace name "team 1", it must consist of alphanumeric characters, '_' or '-' and start with an alphanumeric character`)
+	_, err = p.Create(`{"name":"team1","quota":{"rules":1}}`)
+	require.NoError(t, err)
+	_, err = p.Create(`{"name":"team1"}`)
+	require.EqualError(t, err, "namespace team1 already exists")
+	_, err = p.Update("team1", `{"name":"team2"}`)
+	require.EqualError(t, err, "namespace name team2 does not match team1")
+
+	// Tokens
+	token, tk, err := p.CreateToken("team1")
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+	assert.NotContains(t, token, tk.Id)
+	ns, err := p.Authenticate(token)
+	require.NoError(t, err)
+	assert.Equal(t, "team1", ns)
+	tokens, err := p.ListTokens("team1")
+	require.NoError(t, err)
+	assert.Equal(t, []*NamespaceToken{tk}, tokens)
+	require.NoError(t, p.DeleteToken("team1", tk.Id))
+	_, err = p.Authenticate(token)
+	require.EqualError(t, err, "invalid namespace token")
+	require.EqualError(t, p.DeleteToken("team1", tk.Id), "Token "+tk.Id+" of namespace team1 is not found.")
+
+	// Ownership and quota
+	require.NoError(t, p.CheckQuota("team1", ResourceRule))
+	require.NoError(t, p.SetOwner(ResourceRule, "nsRule1", "team1"))
+	assert.Equal(t, "team1", p.GetOwner(ResourceRule, "nsRule1"))
+	assert.Equal(t, "", p.GetOwner(ResourceStream, "nsRule1"))
+	require.EqualError(t, p.CheckQuota("team1", ResourceRule), "the quota of rule in namespace team1 is exceeded, the limit is 1")
+	require.NoError(t, p.CheckQuota("team1", ResourceStream))
+	resources, err := p.Resources("team1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{ResourceRule: {"nsRule1"}}, resources)
+	require.EqualError(t, p.Delete("team1"), "namespace team1 still has resources map[rule:[nsRule1]], please delete them first")
+	require.NoError(t, p.RemoveOwner(ResourceRule, "nsRule1"))
+	require.NoError(t, p.RemoveOwner(ResourceRule, "nsRule1"))
+	require.NoError(t, p.Delete("team1"))
+	_, err = p.Get("team1")
+	require.EqualError(t, err, "Namespace team1 is not found.")
+}
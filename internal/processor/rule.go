@@ -22,7 +22,9 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/label"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/rulestate"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
@@ -33,6 +35,12 @@ import (
 type RuleProcessor struct {
 	db           kv.KeyValue
 	ruleStatusDb kv.KeyValue
+	versionDb    kv.KeyValue
+	snapshotDb   kv.KeyValue
+	canaryDb     kv.KeyValue
+	// savepointDb keeps the savepoint list of each rule and savepointStateDb keeps the state of each savepoint
+	savepointDb      kv.KeyValue
+	savepointStateDb kv.KeyValue
 }
 
 func NewRuleProcessor() *RuleProcessor {
@@ -44,9 +52,34 @@ func NewRuleProcessor() *RuleProcessor {
 	if err != nil {
 		panic(fmt.Sprintf("Can not initialize store for the rule processor at path 'rule': %v", err))
 	}
+	versionDb, err := store.GetKV("ruleVersion")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the rule processor at path 'ruleVersion': %v", err))
+	}
+	snapshotDb, err := store.GetKV("ruleSnapshot")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the rule processor at path 'ruleSnapshot': %v", err))
+	}
+	canaryDb, err := store.GetKV("ruleCanary")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the rule processor at path 'ruleCanary': %v", err))
+	}
+	savepointDb, err := store.GetKV("ruleSavepoint")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the rule processor at path 'ruleSavepoint': %v", err))
+	}
+	savepointStateDb, err := store.GetKV("ruleSavepointState")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the rule processor at path 'ruleSavepointState': %v", err))
+	}
 	processor := &RuleProcessor{
-		db:           db,
-		ruleStatusDb: ruleStatusDb,
+		db:               db,
+		ruleStatusDb:     ruleStatusDb,
+		versionDb:        versionDb,
+		snapshotDb:       snapshotDb,
+		canaryDb:         canaryDb,
+		savepointDb:      savepointDb,
+		savepointStateDb: savepointStateDb,
 	}
 	return processor
 }
@@ -83,6 +116,9 @@ func (p *RuleProcessor) ExecUpdate(name, ruleJson string) (*def.Rule, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := p.recordVersion(rule.Id, ruleJson); err != nil {
+		log.Warnf("Record version of rule %s error: %v", rule.Id, err)
+	}
 	err = p.db.Set(rule.Id, ruleJson)
 	if err != nil {
 		return nil, err
@@ -114,6 +150,28 @@ func (p *RuleProcessor) ExecReplaceRuleState(name string, triggered bool) (*def.
 	return rule, err
 }
 
+// ExecReplaceRuleParams replaces the params of the stored rule
+func (p *RuleProcessor) ExecReplaceRuleParams(name string, params map[string]any) (*def.Rule, error) {
+	rule, err := p.GetRuleById(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Params = params
+	ruleJson, err := json.Marshal(rule)
+	if err != nil {
+		return nil, fmt.Errorf("Marshal rule %s error : %s.", name, err)
+	}
+
+	err = p.db.Set(name, string(ruleJson))
+	if err != nil {
+		return nil, err
+	} else {
+		log.Infof("Rule %s params are replaced.", name)
+	}
+	return rule, err
+}
+
 func (p *RuleProcessor) GetRuleJson(id string) (string, error) {
 	var s1 string
 	f, _ := p.db.Get(id, &s1)
@@ -147,6 +205,7 @@ func (p *RuleProcessor) GetRuleByJsonValidated(id, ruleJson string) (*def.Rule,
 	if rule.Options == nil {
 		rule.Options = &opt
 	}
+	p.applyTrafficSplit(rule)
 	return rule, nil
 }
 
@@ -172,6 +231,14 @@ func (p *RuleProcessor) GetRuleByJson(id, ruleJson string) (*def.Rule, error) {
 		if _, err := xsql.GetStatementFromSql(rule.Sql); err != nil {
 			return nil, err
 		}
+		for _, rs := range rule.Statements {
+			if rs == nil || rs.Name == "" || rs.Sql == "" {
+				return nil, fmt.Errorf("Rule %s has statement without name or sql.", rule.Id)
+			}
+			if _, err := xsql.GetStatementFromSql(rs.Sql); err != nil {
+				return nil, err
+			}
+		}
 		if rule.Actions == nil || len(rule.Actions) == 0 {
 			return nil, fmt.Errorf("Missing rule actions.")
 		}
@@ -179,11 +246,17 @@ func (p *RuleProcessor) GetRuleByJson(id, ruleJson string) (*def.Rule, error) {
 		if rule.Graph == nil {
 			return nil, fmt.Errorf("Rule %s has neither sql nor graph.", rule.Id)
 		}
+		if len(rule.Statements) > 0 {
+			return nil, fmt.Errorf("Rule %s has statements but no sql.", rule.Id)
+		}
 	}
 	err = conf.ValidateRuleOption(rule.Options)
 	if err != nil {
 		return nil, fmt.Errorf("Rule %s has invalid options: %s.", rule.Id, err)
 	}
+	if err := label.Validate(rule.Labels); err != nil {
+		return nil, fmt.Errorf("Rule %s has invalid labels: %s.", rule.Id, err)
+	}
 	return rule, nil
 }
 
@@ -195,18 +268,33 @@ func clone(opt def.RuleOption) *def.RuleOption {
 	return &def.RuleOption{
 		IsEventTime:        opt.IsEventTime,
 		LateTol:            opt.LateTol,
+		AllowedLateness:    opt.AllowedLateness,
+		LateDataPolicy:     opt.LateDataPolicy,
+		LateDataTopic:      opt.LateDataTopic,
+		SideOutput:         opt.SideOutput,
+		EmitStrategy:       opt.EmitStrategy,
+		WindowTrigger:      opt.WindowTrigger,
+		Changelog:          opt.Changelog,
+		WindowBufferLimit:  opt.WindowBufferLimit,
 		Concurrency:        opt.Concurrency,
 		BufferLength:       opt.BufferLength,
 		SendMetaToSink:     opt.SendMetaToSink,
 		SendError:          opt.SendError,
 		Qos:                opt.Qos,
 		CheckpointInterval: opt.CheckpointInterval,
+		FullSnapshotEvery:  opt.FullSnapshotEvery,
+		StateTTL:           opt.StateTTL,
+		StateMaxKeys:       opt.StateMaxKeys,
+		ExecBatchSize:      opt.ExecBatchSize,
+		BufferSpill:        opt.BufferSpill,
+		SchedulingClass:    opt.SchedulingClass,
 		RestartStrategy: &def.RestartStrategy{
 			Attempts:     opt.RestartStrategy.Attempts,
 			Delay:        opt.RestartStrategy.Delay,
 			Multiplier:   opt.RestartStrategy.Multiplier,
 			MaxDelay:     opt.RestartStrategy.MaxDelay,
 			JitterFactor: opt.RestartStrategy.JitterFactor,
+			Policies:     opt.RestartStrategy.Policies,
 		},
 	}
 }
@@ -251,6 +339,18 @@ func (p *RuleProcessor) ExecDrop(name string) error {
 		if err := cleanCheckpoint(name); err != nil {
 			allErr = errors.Join(allErr, fmt.Errorf("Clean checkpoint cache failed: %v.", err))
 		}
+		if err := store.DropSpillKV(name); err != nil {
+			allErr = errors.Join(allErr, fmt.Errorf("Clean spilled data failed: %v.", err))
+		}
+		if err := rulestate.Drop(name); err != nil {
+			allErr = errors.Join(allErr, fmt.Errorf("Clean rule state failed: %v.", err))
+		}
+		if err := p.dropVersions(name); err != nil {
+			allErr = errors.Join(allErr, fmt.Errorf("Clean rule versions failed: %v.", err))
+		}
+		if err := p.dropSavepoints(name); err != nil {
+			allErr = errors.Join(allErr, fmt.Errorf("Clean rule savepoints failed: %v.", err))
+		}
 
 	}
 	err := p.db.Delete(name)
@@ -261,7 +361,7 @@ func (p *RuleProcessor) ExecDrop(name string) error {
 }
 
 func cleanCheckpoint(name string) error {
-	err := store.DropTS(name)
+	err := store.DropStateTS(name)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,92 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+// CanarySuffix is appended to the rule id as the id of its canary rule
+const CanarySuffix = "_canary"
+
+// RuleCanary is a new version of a rule running alongside the current one before it is promoted or aborted
+type RuleCanary struct {
+	RuleId   string `json:"ruleId"`
+	CanaryId string `json:"canaryId"`
+	// Weight is the percentage of the events processed by the canary. The rule processes the rest.
+	Weight int `json:"weight"`
+	// Shadow runs the canary with all the events and its actions disabled. The rule is not affected.
+	Shadow    bool  `json:"shadow"`
+	CreatedAt int64 `json:"createdAt"`
+	// Rule is the new rule json to replace the rule when promoted
+	Rule string `json:"rule"`
+}
+
+func CanaryId(ruleId string) string {
+	return ruleId + CanarySuffix
+}
+
+func (p *RuleProcessor) SaveCanary(c *RuleCanary) error {
+	return p.canaryDb.Set(c.RuleId, c)
+}
+
+// GetCanary returns the canary of the rule
+func (p *RuleProcessor) GetCanary(ruleId string) (*RuleCanary, error) {
+	c := &RuleCanary{}
+	ok, err := p.canaryDb.Get(ruleId, c)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s has no canary.", ruleId))
+	}
+	return c, nil
+}
+
+// GetCanaryOf returns the canary whose canary rule is the given id
+func (p *RuleProcessor) GetCanaryOf(canaryId string) (*RuleCanary, bool) {
+	ruleId, found := strings.CutSuffix(canaryId, CanarySuffix)
+	if !found {
+		return nil, false
+	}
+	c, err := p.GetCanary(ruleId)
+	if err != nil || c.CanaryId != canaryId {
+		return nil, false
+	}
+	return c, true
+}
+
+func (p *RuleProcessor) DeleteCanary(ruleId string) error {
+	return p.canaryDb.Delete(ruleId)
+}
+
+// applyTrafficSplit splits the events between the rule and its canary by the weight. The events whose hash bucket is
+// below the weight go to the canary.
+func (p *RuleProcessor) applyTrafficSplit(rule *def.Rule) {
+	if p.canaryDb == nil || rule.Options == nil {
+		return
+	}
+	if c, err := p.GetCanary(rule.Id); err == nil {
+		if !c.Shadow {
+			rule.Options.TrafficSplit = &def.TrafficSplit{From: c.Weight, To: 100}
+		}
+	} else if c, ok := p.GetCanaryOf(rule.Id); ok && !c.Shadow {
+		rule.Options.TrafficSplit = &def.TrafficSplit{From: 0, To: c.Weight}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// Savepoint is a state snapshot of the rule saved explicitly. Different from the checkpoints, it is kept until deleted.
+type Savepoint struct {
+	// Id is the id of the checkpoint which the savepoint is taken from
+	Id        int64    `json:"id"`
+	CreatedAt int64    `json:"createdAt"`
+	Operators []string `json:"operators"`
+}
+
+// SaveSavepoint saves the latest checkpoint of the rule as a savepoint
+func (p *RuleProcessor) SaveSavepoint(id string) (*Savepoint, error) {
+	if !p.ExecExists(id) {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found.", id))
+	}
+	ts, err := store.GetStateTS(id)
+	if err != nil {
+		return nil, err
+	}
+	k, m, err := state.LoadCheckpoint(ts)
+	if err != nil {
+		return nil, err
+	}
+	if k <= 0 {
+		return nil, fmt.Errorf("rule %s has no checkpoint to save", id)
+	}
+	savepoints, err := p.loadSavepoints(id)
+	if err != nil {
+		return nil, err
+	}
+	sp := &Savepoint{Id: k, CreatedAt: timex.GetNowInMilli(), Operators: make([]string, 0, len(m))}
+	for op := range m {
+		sp.Operators = append(sp.Operators, op)
+	}
+	sort.Strings(sp.Operators)
+	if err := p.savepointStateDb.Set(savepointKey(id, k), m); err != nil {
+		return nil, fmt.Errorf("save state of savepoint %d error: %v", k, err)
+	}
+	// The same checkpoint is saved again
+	result := make([]*Savepoint, 0, len(savepoints)+1)
+	for _, s := range savepoints {
+		if s.Id != k {
+			result = append(result, s)
+		}
+	}
+	result = append(result, sp)
+	if err := p.saveSavepoints(id, result); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// GetSavepoints returns the savepoints of the rule in the order of creation
+func (p *RuleProcessor) GetSavepoints(id string) ([]*Savepoint, error) {
+	if !p.ExecExists(id) {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found.", id))
+	}
+	savepoints, err := p.loadSavepoints(id)
+	if err != nil {
+		return nil, err
+	}
+	if savepoints == nil {
+		savepoints = []*Savepoint{}
+	}
+	return savepoints, nil
+}
+
+func (p *RuleProcessor) GetSavepoint(id string, spId int64) (*Savepoint, error) {
+	savepoints, err := p.loadSavepoints(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range savepoints {
+		if s.Id == spId {
+			return s, nil
+		}
+	}
+	return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Savepoint %d of rule %s is not found.", spId, id))
+}
+
+func (p *RuleProcessor) DeleteSavepoint(id string, spId int64) error {
+	savepoints, err := p.loadSavepoints(id)
+	if err != nil {
+		return err
+	}
+	for i, s := range savepoints {
+		if s.Id == spId {
+			_ = p.savepointStateDb.Delete(savepointKey(id, spId))
+			return p.saveSavepoints(id, append(savepoints[:i], savepoints[i+1:]...))
+		}
+	}
+	return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Savepoint %d of rule %s is not found.", spId, id))
+}
+
+// RestoreSavepoint saves the state of the savepoint as the latest checkpoint of the rule. The rule must be stopped,
+// and it will restore from the savepoint when it starts.
+func (p *RuleProcessor) RestoreSavepoint(id string, spId int64) error {
+	var m map[string]any
+	ok, err := p.savepointStateDb.Get(savepointKey(id, spId), &m)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Savepoint %d of rule %s is not found.", spId, id))
+	}
+	ts, err := store.GetStateTS(id)
+	if err != nil {
+		return err
+	}
+	var last map[string]any
+	k, err := ts.Last(&last)
+	if err != nil {
+		return err
+	}
+	return saveLatestCheckpoint(ts, k, m)
+}
+
+func (p *RuleProcessor) loadSavepoints(id string) ([]*Savepoint, error) {
+	var s string
+	ok, err := p.savepointDb.Get(id, &s)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var savepoints []*Savepoint
+	if err := json.Unmarshal(cast.StringToBytes(s), &savepoints); err != nil {
+		return nil, fmt.Errorf("Parse savepoints of rule %s error : %s.", id, err)
+	}
+	return savepoints, nil
+}
+
+func (p *RuleProcessor) saveSavepoints(id string, savepoints []*Savepoint) error {
+	b, err := json.Marshal(savepoints)
+	if err != nil {
+		return err
+	}
+	return p.savepointDb.Set(id, string(b))
+}
+
+func (p *RuleProcessor) dropSavepoints(id string) error {
+	savepoints, err := p.loadSavepoints(id)
+	if err != nil || len(savepoints) == 0 {
+		return err
+	}
+	for _, s := range savepoints {
+		_ = p.savepointStateDb.Delete(savepointKey(id, s.Id))
+	}
+	return p.savepointDb.Delete(id)
+}
+
+func savepointKey(id string, spId int64) string {
+	return id + "/" + strconv.FormatInt(spId, 10)
+}
@@ -0,0 +1,300 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+)
+
+var placeholderRegex = regexp.MustCompile(`\$\{(\w+)}`)
+
+// RuleTemplate is a rule json with ${param} placeholders to instantiate many rules with different params
+type RuleTemplate struct {
+	Id string `json:"id"`
+	// Params are the default values of the placeholders. The placeholder without default value is required.
+	Params map[string]any `json:"params,omitempty"`
+	// Rule is the rule json without id. The placeholders in its string values are substituted when instantiating.
+	Rule map[string]any `json:"rule"`
+}
+
+// TemplateInstance is a rule instantiated from a template with the params
+type TemplateInstance struct {
+	Id       string         `json:"id"`
+	Template string         `json:"template,omitempty"`
+	Params   map[string]any `json:"params,omitempty"`
+}
+
+type RuleTemplateProcessor struct {
+	db         kv.KeyValue
+	instanceDb kv.KeyValue
+}
+
+func NewRuleTemplateProcessor() *RuleTemplateProcessor {
+	db, err := store.GetKV("ruleTemplate")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the rule template processor at path 'ruleTemplate': %v", err))
+	}
+	instanceDb, err := store.GetKV("ruleTemplateInstance")
+	if err != nil {
+		panic(fmt.Sprintf("Can not initialize store for the rule template processor at path 'ruleTemplateInstance': %v", err))
+	}
+	return &RuleTemplateProcessor{
+		db:         db,
+		instanceDb: instanceDb,
+	}
+}
+
+// ParseTemplate parses and validates the template json. The id is used if the json does not have one.
+func ParseTemplate(id, content string) (*RuleTemplate, error) {
+	t := &RuleTemplate{}
+	if err := json.Unmarshal([]byte(content), t); err != nil {
+		return nil, fmt.Errorf("parse rule template %s error: %v", content, err)
+	}
+	if t.Id == "" {
+		t.Id = id
+	}
+	if t.Id == "" {
+		return nil, fmt.Errorf("missing rule template id")
+	}
+	if id != "" && t.Id != id {
+		return nil, fmt.Errorf("template id %s does not match %s", t.Id, id)
+	}
+	if len(t.Rule) == 0 {
+		return nil, fmt.Errorf("missing rule of template %s", t.Id)
+	}
+	if _, ok := t.Rule["id"]; ok {
+		return nil, fmt.Errorf("rule of template %s must not have id", t.Id)
+	}
+	return t, nil
+}
+
+func (p *RuleTemplateProcessor) Create(content string) (*RuleTemplate, error) {
+	t, err := ParseTemplate("", content)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.db.Setnx(t.Id, string(b)); err != nil {
+		return nil, fmt.Errorf("rule template %s already exists", t.Id)
+	}
+	return t, nil
+}
+
+func (p *RuleTemplateProcessor) Update(id, content string) (*RuleTemplate, error) {
+	t, err := ParseTemplate(id, content)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Get(id); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return t, p.db.Set(id, string(b))
+}
+
+func (p *RuleTemplateProcessor) Get(id string) (*RuleTemplate, error) {
+	var s string
+	ok, err := p.db.Get(id, &s)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule template %s is not found.", id))
+	}
+	t := &RuleTemplate{}
+	if err := json.Unmarshal([]byte(s), t); err != nil {
+		return nil, fmt.Errorf("parse rule template %s error: %v", id, err)
+	}
+	return t, nil
+}
+
+func (p *RuleTemplateProcessor) List() ([]string, error) {
+	keys, err := p.db.Keys()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Delete drops the template and unbinds its instances. The instantiated rules are kept.
+func (p *RuleTemplateProcessor) Delete(id string) error {
+	if _, err := p.Get(id); err != nil {
+		return err
+	}
+	instances, err := p.GetInstances(id)
+	if err != nil {
+		return err
+	}
+	for _, inst := range instances {
+		_ = p.instanceDb.Delete(inst.Id)
+	}
+	return p.db.Delete(id)
+}
+
+// GetInstance returns the instance binding of the rule, nil if the rule is not instantiated from a template
+func (p *RuleTemplateProcessor) GetInstance(ruleId string) (*TemplateInstance, error) {
+	var s string
+	ok, err := p.instanceDb.Get(ruleId, &s)
+	if err != nil || !ok {
+		return nil, err
+	}
+	inst := &TemplateInstance{}
+	if err := json.Unmarshal([]byte(s), inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+func (p *RuleTemplateProcessor) GetInstances(templateId string) ([]*TemplateInstance, error) {
+	all, err := p.instanceDb.All()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*TemplateInstance, 0)
+	for _, v := range all {
+		inst := &TemplateInstance{}
+		if err := json.Unmarshal([]byte(v), inst); err != nil {
+			continue
+		}
+		if inst.Template == templateId {
+			result = append(result, inst)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Id < result[j].Id
+	})
+	return result, nil
+}
+
+func (p *RuleTemplateProcessor) SaveInstance(inst *TemplateInstance) error {
+	b, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+	return p.instanceDb.Set(inst.Id, string(b))
+}
+
+func (p *RuleTemplateProcessor) DeleteInstance(ruleId string) error {
+	return p.instanceDb.Delete(ruleId)
+}
+
+// Render substitutes the placeholders of the template with the params and returns the rule json of the instance.
+// A string value which is exactly a placeholder is replaced by the param value with its type, so that the numeric or
+// bool options can be parameterized too.
+func (t *RuleTemplate) Render(inst *TemplateInstance) (string, error) {
+	params := make(map[string]any, len(t.Params)+len(inst.Params))
+	for k, v := range t.Params {
+		params[k] = v
+	}
+	for k, v := range inst.Params {
+		if _, ok := t.Params[k]; !ok && !t.hasPlaceholder(k) {
+			return "", fmt.Errorf("unknown param %s of rule template %s", k, t.Id)
+		}
+		params[k] = v
+	}
+	rendered, err := renderValue(t.Rule, params)
+	if err != nil {
+		return "", fmt.Errorf("render rule %s with template %s error: %v", inst.Id, t.Id, err)
+	}
+	m := rendered.(map[string]any)
+	m["id"] = inst.Id
+	// keep the operators such as > in sql readable
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(m); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func (t *RuleTemplate) hasPlaceholder(name string) bool {
+	b, _ := json.Marshal(t.Rule)
+	return strings.Contains(string(b), "${"+name+"}")
+}
+
+func renderValue(v any, params map[string]any) (any, error) {
+	switch vt := v.(type) {
+	case string:
+		return renderString(vt, params)
+	case map[string]any:
+		result := make(map[string]any, len(vt))
+		for k, e := range vt {
+			r, err := renderValue(e, params)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = r
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(vt))
+		for i, e := range vt {
+			r, err := renderValue(e, params)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = r
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+func renderString(s string, params map[string]any) (any, error) {
+	if m := placeholderRegex.FindStringSubmatch(s); m != nil && m[0] == s {
+		v, ok := params[m[1]]
+		if !ok || v == nil {
+			return nil, fmt.Errorf("missing param %s", m[1])
+		}
+		return v, nil
+	}
+	var err error
+	result := placeholderRegex.ReplaceAllStringFunc(s, func(p string) string {
+		name := p[2 : len(p)-1]
+		v, ok := params[name]
+		if !ok || v == nil {
+			err = fmt.Errorf("missing param %s", name)
+			return p
+		}
+		switch vt := v.(type) {
+		case string:
+			return vt
+		case map[string]any, []any:
+			b, _ := json.Marshal(vt)
+			return string(b)
+		default:
+			return fmt.Sprint(vt)
+		}
+	})
+	return result, err
+}
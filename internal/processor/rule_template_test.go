@@ -0,0 +1,76 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tpl, err := ParseTemplate("", `{"id":"tpl","params":{"threshold":30,"qos":1},"rule":{"sql":"SELECT * FROM demo WHERE deviceId = \"${deviceId}\" AND temperature > ${threshold}","actions":[{"mqtt":{"topic":"alerts/${deviceId}","fields":"${fields}","qos":"${qos}"}}]}}`)
+	require.NoError(t, err)
+	tests := []struct {
+		name   string
+		params map[string]any
+		result string
+		err    string
+	}{
+		{
+			name:   "default",
+			params: map[string]any{"deviceId": "dev1", "fields": []any{"a", "b"}},
+			result: `{"actions":[{"mqtt":{"fields":["a","b"],"qos":1,"topic":"alerts/dev1"}}],"id":"r1","sql":"SELECT * FROM demo WHERE deviceId = \"dev1\" AND temperature > 30"}`,
+		},
+		{
+			name:   "override",
+			params: map[string]any{"deviceId": "dev2", "threshold": 45.5, "fields": "a", "qos": 2},
+			result: `{"actions":[{"mqtt":{"fields":"a","qos":2,"topic":"alerts/dev2"}}],"id":"r1","sql":"SELECT * FROM demo WHERE deviceId = \"dev2\" AND temperature > 45.5"}`,
+		},
+		{
+			name:   "missing",
+			params: map[string]any{"fields": "a"},
+			err:    "render rule r1 with template tpl error: missing param deviceId",
+		},
+		{
+			name:   "unknown",
+			params: map[string]any{"deviceId": "dev1", "fields": "a", "device": "dev1"},
+			err:    "unknown param device of rule template tpl",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tpl.Render(&TemplateInstance{Id: "r1", Params: tt.params})
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	_, err := ParseTemplate("", `{"rule":{"sql":"SELECT * FROM demo"}}`)
+	require.EqualError(t, err, "missing rule template id")
+	_, err = ParseTemplate("tpl", `{"id":"tpl2","rule":{"sql":"SELECT * FROM demo"}}`)
+	require.EqualError(t, err, "template id tpl2 does not match tpl")
+	_, err = ParseTemplate("tpl", `{"rule":{"id":"r1","sql":"SELECT * FROM demo"}}`)
+	require.EqualError(t, err, "rule of template tpl must not have id")
+	tp, err := ParseTemplate("tpl", `{"rule":{"sql":"SELECT * FROM demo"}}`)
+	require.NoError(t, err)
+	require.Equal(t, "tpl", tp.Id)
+}
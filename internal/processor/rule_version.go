@@ -0,0 +1,302 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// RuleVersion is a revision of the rule json. The versions are recorded when the rule is updated and the last one is
+// the current version.
+type RuleVersion struct {
+	Version   int   `json:"version"`
+	CreatedAt int64 `json:"createdAt"`
+	// HasState is true if the last checkpoint of the version is saved when it is replaced
+	HasState bool   `json:"hasState"`
+	Rule     string `json:"rule,omitempty"`
+}
+
+// RuleDiff is a changed property between two rule versions. The path is the dot separated keys of the rule json.
+type RuleDiff struct {
+	Path string `json:"path"`
+	From any    `json:"from,omitempty"`
+	To   any    `json:"to,omitempty"`
+}
+
+// recordVersion saves the old version with its state snapshot and the new rule json as the latest version
+func (p *RuleProcessor) recordVersion(id, ruleJson string) error {
+	limit := conf.Config.Basic.RuleVersionLimit
+	if limit < 0 {
+		return nil
+	}
+	versions, err := p.loadVersions(id)
+	if err != nil {
+		return err
+	}
+	now := timex.GetNowInMilli()
+	if len(versions) == 0 {
+		var old string
+		if ok, _ := p.db.Get(id, &old); ok {
+			versions = append(versions, &RuleVersion{Version: 1, CreatedAt: now, Rule: old})
+		}
+	}
+	if len(versions) > 0 {
+		last := versions[len(versions)-1]
+		if last.Rule == ruleJson {
+			return nil
+		}
+		last.HasState = p.snapshotState(id, last)
+	}
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1].Version + 1
+	}
+	versions = append(versions, &RuleVersion{Version: next, CreatedAt: now, Rule: ruleJson})
+	if limit > 0 && len(versions) > limit {
+		for _, v := range versions[:len(versions)-limit] {
+			_ = p.snapshotDb.Delete(snapshotKey(id, v.Version))
+		}
+		versions = versions[len(versions)-limit:]
+	}
+	s, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	return p.versionDb.Set(id, string(s))
+}
+
+// snapshotState saves the last checkpoint of the rule for the version. Only the rule with qos >= 1 has checkpoint.
+func (p *RuleProcessor) snapshotState(id string, v *RuleVersion) bool {
+	r, err := p.GetRuleByJsonValidated(id, v.Rule)
+	if err != nil || r.Options.Qos < def.AtLeastOnce {
+		return false
+	}
+	ts, err := store.GetStateTS(id)
+	if err != nil {
+		return false
+	}
+	// The latest checkpoint may only have the changes, so rebuild the full states
+	k, m, err := state.LoadCheckpoint(ts)
+	if err != nil || k <= 0 {
+		return false
+	}
+	if err := p.snapshotDb.Set(snapshotKey(id, v.Version), m); err != nil {
+		log.Warnf("save state snapshot of rule %s version %d error: %v", id, v.Version, err)
+		return false
+	}
+	return true
+}
+
+// RestoreRuleState saves the state snapshot of the version as the latest checkpoint of the rule. The rule must be
+// stopped, and it will restore from the snapshot when it starts.
+func (p *RuleProcessor) RestoreRuleState(id string, version int) error {
+	var m map[string]any
+	ok, err := p.snapshotDb.Get(snapshotKey(id, version), &m)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Version %d of rule %s has no state snapshot.", version, id))
+	}
+	ts, err := store.GetStateTS(id)
+	if err != nil {
+		return err
+	}
+	var last map[string]any
+	k, err := ts.Last(&last)
+	if err != nil {
+		return err
+	}
+	return saveLatestCheckpoint(ts, k, m)
+}
+
+// MigrateRuleState rewrites the latest checkpoint of the rule for the updated operators. The mapping is from the new
+// operator name to the old one whose state is taken over. The states of the other operators are dropped. The rule must
+// be stopped, and it returns the names of the operators which keep the state.
+func (p *RuleProcessor) MigrateRuleState(id string, mapping map[string]string) ([]string, error) {
+	ts, err := store.GetStateTS(id)
+	if err != nil {
+		return nil, err
+	}
+	k, last, err := state.LoadCheckpoint(ts)
+	if err != nil || k <= 0 {
+		return nil, err
+	}
+	m := make(map[string]any, len(mapping))
+	kept := make([]string, 0, len(mapping))
+	for newOp, oldOp := range mapping {
+		if v, ok := last[oldOp]; ok {
+			m[newOp] = v
+			kept = append(kept, newOp)
+		}
+	}
+	sort.Strings(kept)
+	return kept, saveLatestCheckpoint(ts, k, m)
+}
+
+// saveLatestCheckpoint saves the snapshot as a checkpoint after the last one, so that the rule restores from it
+func saveLatestCheckpoint(ts kv.Tskv, last int64, m map[string]any) error {
+	k := timex.GetNowInMilli()
+	if k <= last {
+		k = last + 1
+	}
+	_, err := ts.Set(k, m)
+	return err
+}
+
+// GetRuleVersions returns the versions of the rule without the rule json
+func (p *RuleProcessor) GetRuleVersions(id string) ([]*RuleVersion, error) {
+	if !p.ExecExists(id) {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found.", id))
+	}
+	versions, err := p.loadVersions(id)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*RuleVersion, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, &RuleVersion{Version: v.Version, CreatedAt: v.CreatedAt, HasState: v.HasState})
+	}
+	return result, nil
+}
+
+func (p *RuleProcessor) GetRuleVersion(id string, version int) (*RuleVersion, error) {
+	versions, err := p.loadVersions(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Version %d of rule %s is not found.", version, id))
+}
+
+// DiffRuleVersions compares the rule json of two versions. If the to version is 0, compare with the current rule.
+func (p *RuleProcessor) DiffRuleVersions(id string, from, to int) ([]*RuleDiff, error) {
+	fv, err := p.GetRuleVersion(id, from)
+	if err != nil {
+		return nil, err
+	}
+	var toJson string
+	if to == 0 {
+		toJson, err = p.GetRuleJson(id)
+	} else {
+		var tv *RuleVersion
+		tv, err = p.GetRuleVersion(id, to)
+		if tv != nil {
+			toJson = tv.Rule
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fm, tm map[string]any
+	if err := json.Unmarshal(cast.StringToBytes(fv.Rule), &fm); err != nil {
+		return nil, fmt.Errorf("Parse rule version %d error : %s.", from, err)
+	}
+	if err := json.Unmarshal(cast.StringToBytes(toJson), &tm); err != nil {
+		return nil, fmt.Errorf("Parse rule version %d error : %s.", to, err)
+	}
+	ff, tf := map[string]any{}, map[string]any{}
+	flatten("", fm, ff)
+	flatten("", tm, tf)
+	result := make([]*RuleDiff, 0)
+	for k, v := range ff {
+		if tv, ok := tf[k]; !ok || !reflect.DeepEqual(v, tv) {
+			result = append(result, &RuleDiff{Path: k, From: v, To: tv})
+		}
+	}
+	for k, v := range tf {
+		if _, ok := ff[k]; !ok {
+			result = append(result, &RuleDiff{Path: k, To: v})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+func (p *RuleProcessor) loadVersions(id string) ([]*RuleVersion, error) {
+	var s string
+	ok, err := p.versionDb.Get(id, &s)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var versions []*RuleVersion
+	if err := json.Unmarshal(cast.StringToBytes(s), &versions); err != nil {
+		return nil, fmt.Errorf("Parse versions of rule %s error : %s.", id, err)
+	}
+	return versions, nil
+}
+
+func (p *RuleProcessor) dropVersions(id string) error {
+	versions, err := p.loadVersions(id)
+	if err != nil || len(versions) == 0 {
+		return err
+	}
+	for _, v := range versions {
+		_ = p.snapshotDb.Delete(snapshotKey(id, v.Version))
+	}
+	return p.versionDb.Delete(id)
+}
+
+func snapshotKey(id string, version int) string {
+	return id + "/" + strconv.Itoa(version)
+}
+
+// flatten the json map to the leaf values with the dot separated path
+func flatten(prefix string, v any, result map[string]any) {
+	switch vt := v.(type) {
+	case map[string]any:
+		if len(vt) == 0 && prefix != "" {
+			result[prefix] = vt
+		}
+		for k, c := range vt {
+			flatten(join(prefix, k), c, result)
+		}
+	case []any:
+		if len(vt) == 0 {
+			result[prefix] = vt
+		}
+		for i, c := range vt {
+			flatten(join(prefix, strconv.Itoa(i)), c, result)
+		}
+	default:
+		result[prefix] = v
+	}
+}
+
+func join(prefix, k string) string {
+	if prefix == "" {
+		return k
+	}
+	return prefix + "." + k
+}
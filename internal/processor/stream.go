@@ -24,6 +24,7 @@ import (
 	"golang.org/x/text/language"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/label"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/v2/internal/schema"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/lookup"
@@ -43,9 +44,10 @@ type StreamProcessor struct {
 }
 
 type StreamDetail struct {
-	Name   string `json:"name"`
-	Type   string `json:"type"`
-	Format string `json:"format"`
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Format string            `json:"format"`
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 func NewStreamProcessor() *StreamProcessor {
@@ -70,6 +72,10 @@ func NewStreamProcessor() *StreamProcessor {
 }
 
 func (p *StreamProcessor) ExecStmt(statement string) (result []string, err error) {
+	return p.execStmt(statement, nil)
+}
+
+func (p *StreamProcessor) execStmt(statement string, labels map[string]string) (result []string, err error) {
 	defer func() {
 		if err != nil {
 			if _, ok := err.(errorx.ErrorWithCode); !ok {
@@ -86,7 +92,7 @@ func (p *StreamProcessor) ExecStmt(statement string) (result []string, err error
 	switch s := stmt.(type) {
 	case *ast.StreamStmt: // Table is also StreamStmt
 		var r string
-		err = p.execSave(s, statement, false)
+		err = p.execSave(s, statement, labels, false)
 		stt := ast.StreamTypeMap[s.StreamType]
 		if err != nil {
 			err = fmt.Errorf("Create %s fails: %v.", stt, err)
@@ -164,7 +170,17 @@ func (p *StreamProcessor) RecoverLookupTable() (err error) {
 	return nil
 }
 
-func (p *StreamProcessor) execSave(stmt *ast.StreamStmt, statement string, replace bool) error {
+// execSave saves the stream or table. If the labels are nil when replacing, the existing labels are kept.
+func (p *StreamProcessor) execSave(stmt *ast.StreamStmt, statement string, labels map[string]string, replace bool) error {
+	if err := label.Validate(labels); err != nil {
+		return err
+	}
+	if replace && labels == nil {
+		existed, err := p.GetLabels(string(stmt.Name))
+		if err == nil {
+			labels = existed
+		}
+	}
 	if stmt.StreamType == ast.TypeTable && stmt.Options.KIND == ast.StreamKindLookup {
 		_ = lookup.DropInstance(string(stmt.Name))
 		log.Infof("Creating lookup table %s", stmt.Name)
@@ -177,6 +193,7 @@ func (p *StreamProcessor) execSave(stmt *ast.StreamStmt, statement string, repla
 		StreamType: stmt.StreamType,
 		Statement:  statement,
 		StreamKind: stmt.Options.KIND,
+		Labels:     labels,
 	})
 	if err != nil {
 		return fmt.Errorf("error when saving to db: %v.", err)
@@ -189,7 +206,8 @@ func (p *StreamProcessor) execSave(stmt *ast.StreamStmt, statement string, repla
 	return err
 }
 
-func (p *StreamProcessor) ExecReplaceStream(name string, statement string, st ast.StreamType) (info string, err error) {
+// ExecReplaceStream replaces the stream or table by the statement. The existing labels are kept if the labels are nil.
+func (p *StreamProcessor) ExecReplaceStream(name string, statement string, labels map[string]string, st ast.StreamType) (info string, err error) {
 	defer func() {
 		if err != nil {
 			if _, ok := err.(errorx.ErrorWithCode); !ok {
@@ -212,7 +230,7 @@ func (p *StreamProcessor) ExecReplaceStream(name string, statement string, st as
 		if string(s.Name) != name {
 			return "", fmt.Errorf("Replace %s fails: the sql statement must update the %s source.", name, name)
 		}
-		err = p.execSave(s, statement, true)
+		err = p.execSave(s, statement, labels, true)
 		if err != nil {
 			return "", fmt.Errorf("Replace %s fails: %v.", stt, err)
 		} else {
@@ -226,7 +244,12 @@ func (p *StreamProcessor) ExecReplaceStream(name string, statement string, st as
 }
 
 func (p *StreamProcessor) ExecStreamSql(statement string) (info string, err error) {
-	r, err := p.ExecStmt(statement)
+	return p.ExecStreamSqlWithLabels(statement, nil)
+}
+
+// ExecStreamSqlWithLabels runs the statement and attaches the labels if the statement creates a stream or table
+func (p *StreamProcessor) ExecStreamSqlWithLabels(statement string, labels map[string]string) (info string, err error) {
+	r, err := p.execStmt(statement, labels)
 	if err != nil {
 		return "", err
 	} else {
@@ -234,6 +257,38 @@ func (p *StreamProcessor) ExecStreamSql(statement string) (info string, err erro
 	}
 }
 
+// GetLabels returns the labels of the stream or table
+func (p *StreamProcessor) GetLabels(name string) (map[string]string, error) {
+	var v string
+	ok, err := p.db.Get(name, &v)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("%s is not found", name))
+	}
+	vs := &xsql.StreamInfo{}
+	if err := json.Unmarshal(cast.StringToBytes(v), vs); err != nil {
+		return nil, err
+	}
+	return vs.Labels, nil
+}
+
+// FilterByLabels returns the streams or tables whose labels match the selector
+func (p *StreamProcessor) FilterByLabels(names []string, selector label.Selector) []string {
+	if len(selector) == 0 {
+		return names
+	}
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		labels, err := p.GetLabels(name)
+		if err == nil && selector.Matches(labels) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
 func (p *StreamProcessor) execShow(st ast.StreamType) ([]string, error) {
 	keys, err := p.ShowStream(st)
 	if len(keys) == 0 {
@@ -302,7 +357,8 @@ func (p *StreamProcessor) ShowStreamOrTableDetails(kind string, st ast.StreamTyp
 			if f == "" {
 				f = "json"
 			}
-			streamDetails = append(streamDetails, StreamDetail{Name: name, Type: strings.ToLower(t), Format: strings.ToLower(f)})
+			labels, _ := p.GetLabels(name)
+			streamDetails = append(streamDetails, StreamDetail{Name: name, Type: strings.ToLower(t), Format: strings.ToLower(f), Labels: labels})
 		}
 	}
 
@@ -409,6 +465,9 @@ func printOptions(opts *ast.Options, buff *bytes.Buffer) {
 	if opts.RETAIN_SIZE != 0 {
 		buff.WriteString(fmt.Sprintf("RETAIN_SIZE: %d\n", opts.RETAIN_SIZE))
 	}
+	if opts.SCHEMA_POLICY != "" {
+		buff.WriteString(fmt.Sprintf("SCHEMA_POLICY: %s\n", opts.SCHEMA_POLICY))
+	}
 	if opts.SHARED {
 		buff.WriteString(fmt.Sprintf("SHARED: %v\n", opts.SHARED))
 	}
@@ -421,6 +480,12 @@ func printOptions(opts *ast.Options, buff *bytes.Buffer) {
 	if opts.TIMESTAMP_FORMAT != "" {
 		buff.WriteString(fmt.Sprintf("TIMESTAMP_FORMAT: %s\n", opts.TIMESTAMP_FORMAT))
 	}
+	if opts.TIMESTAMP_TZ != "" {
+		buff.WriteString(fmt.Sprintf("TIMESTAMP_TZ: %s\n", opts.TIMESTAMP_TZ))
+	}
+	if opts.TIMESTAMP_POLICY != "" {
+		buff.WriteString(fmt.Sprintf("TIMESTAMP_POLICY: %s\n", opts.TIMESTAMP_POLICY))
+	}
 	if opts.TYPE != "" {
 		buff.WriteString(fmt.Sprintf("TYPE: %s\n", opts.TYPE))
 	}
@@ -406,7 +406,7 @@ func TestErr(t *testing.T) {
 	p.db.Clean()
 	defer p.db.Clean()
 
-	_, err := p.ExecReplaceStream("1", "2", 1)
+	_, err := p.ExecReplaceStream("1", "2", nil, 1)
 	require.Error(t, err)
 	_, ok := err.(errorx.ErrorWithCode)
 	require.True(t, ok)
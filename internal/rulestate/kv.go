@@ -0,0 +1,115 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rulestate keeps the states of the state functions which must survive the rule restarts.
+// The states are saved in the kv store with a table for each rule and dropped when the rule is deleted.
+package rulestate
+
+import (
+	"encoding/gob"
+	"sync"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	kv2 "github.com/lf-edge/ekuiper/v2/pkg/kv"
+)
+
+const tablePrefix = "rule_state_"
+
+func init() {
+	// The composite states are saved as interfaces in the wrapper map
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+var (
+	mu     sync.Mutex
+	states = make(map[string]*ruleState)
+)
+
+// ruleState caches the states of a rule in memory. The states are written through to the kv store.
+type ruleState struct {
+	sync.Mutex
+	kv    kv2.KeyValue
+	cache map[string]any
+}
+
+func getRuleState(ruleId string) (*ruleState, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if rs, ok := states[ruleId]; ok {
+		return rs, nil
+	}
+	kv, err := store.GetKV(tablePrefix + ruleId)
+	if err != nil {
+		return nil, err
+	}
+	rs := &ruleState{kv: kv, cache: make(map[string]any)}
+	states[ruleId] = rs
+	return rs, nil
+}
+
+// Get returns the state of the key for the rule. Return nil if the state is not found.
+func Get(ruleId string, key string) (any, error) {
+	rs, err := getRuleState(ruleId)
+	if err != nil {
+		return nil, err
+	}
+	rs.Lock()
+	defer rs.Unlock()
+	if v, ok := rs.cache[key]; ok {
+		return v, nil
+	}
+	// The value is wrapped in a map so that gob keeps its concrete type
+	var m map[string]any
+	ok, err := rs.kv.Get(key, &m)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if ok {
+		v = m["v"]
+	}
+	rs.cache[key] = v
+	return v, nil
+}
+
+// Put sets the state of the key for the rule. The state of the key is removed if the value is nil.
+func Put(ruleId string, key string, value any) error {
+	rs, err := getRuleState(ruleId)
+	if err != nil {
+		return err
+	}
+	rs.Lock()
+	defer rs.Unlock()
+	if value == nil {
+		// Delete returns error if the key is not found which can be ignored
+		_ = rs.kv.Delete(key)
+	} else if err := rs.kv.Set(key, map[string]any{"v": value}); err != nil {
+		return err
+	}
+	rs.cache[key] = value
+	return nil
+}
+
+// Drop removes all the states of the rule
+func Drop(ruleId string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(states, ruleId)
+	// Open the table in case the rule has not run since the server started
+	if _, err := store.GetKV(tablePrefix + ruleId); err != nil {
+		return err
+	}
+	return store.DropKV(tablePrefix + ruleId)
+}
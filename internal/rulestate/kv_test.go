@@ -0,0 +1,66 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rulestate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/testx"
+)
+
+func init() {
+	testx.InitEnv("rulestate")
+}
+
+func TestRuleState(t *testing.T) {
+	v, err := Get("ruleState1", "a")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	require.NoError(t, Put("ruleState1", "a", int64(10)))
+	require.NoError(t, Put("ruleState1", "b", map[string]any{"temperature": 25.5}))
+	require.NoError(t, Put("ruleState2", "a", "v2"))
+	// Clean the cache to read from the kv store like a restart
+	mu.Lock()
+	states = make(map[string]*ruleState)
+	mu.Unlock()
+
+	v, err = Get("ruleState1", "a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), v)
+	v, err = Get("ruleState1", "b")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"temperature": 25.5}, v)
+	v, err = Get("ruleState2", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", v)
+
+	require.NoError(t, Put("ruleState1", "a", nil))
+	v, err = Get("ruleState1", "a")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	require.NoError(t, Drop("ruleState1"))
+	v, err = Get("ruleState1", "b")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	v, err = Get("ruleState2", "a")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", v)
+	require.NoError(t, Drop("ruleState2"))
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build schema || !core
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+func init() {
+	inferes[message.FormatJson] = InferJsonSchema
+}
+
+// jsonSchemaNode is the subset of the JSON schema keywords used to infer the stream fields
+type jsonSchemaNode struct {
+	Type        any                        `json:"type"`
+	Ref         string                     `json:"$ref"`
+	Properties  map[string]*jsonSchemaNode `json:"properties"`
+	Items       *jsonSchemaNode            `json:"items"`
+	Defs        map[string]*jsonSchemaNode `json:"$defs"`
+	Definitions map[string]*jsonSchemaNode `json:"definitions"`
+}
+
+// InferJsonSchema infers the stream fields from the properties of a registered JSON schema.
+// The schema id of json format is the schema name, so the message name is always empty.
+func InferJsonSchema(schemaFile string, _ string) (ast.StreamFields, error) {
+	ffs, err := GetSchemaFile(def.JSONSCHEMA, schemaFile)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(ffs.SchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("read json schema file %s failed: %s", ffs.SchemaFile, err)
+	}
+	return convertJsonSchema(content)
+}
+
+func convertJsonSchema(content []byte) (ast.StreamFields, error) {
+	root := &jsonSchemaNode{}
+	if err := json.Unmarshal(content, root); err != nil {
+		return nil, fmt.Errorf("invalid json schema: %s", err)
+	}
+	r := &jsonSchemaResolver{root: root}
+	n, err := r.resolve(root)
+	if err != nil {
+		return nil, err
+	}
+	if t, _ := n.dataType(); t != "object" {
+		return nil, fmt.Errorf("the root of the json schema must be an object")
+	}
+	return r.convertProperties(n, 0)
+}
+
+// maxRefDepth stops the recursive schemas which cannot be represented by the stream fields
+const maxRefDepth = 32
+
+type jsonSchemaResolver struct {
+	root *jsonSchemaNode
+}
+
+// resolve follows the local reference like #/$defs/name or #/definitions/name
+func (r *jsonSchemaResolver) resolve(n *jsonSchemaNode) (*jsonSchemaNode, error) {
+	for i := 0; n.Ref != ""; i++ {
+		if i >= maxRefDepth {
+			return nil, fmt.Errorf("reference %s is too deep", n.Ref)
+		}
+		var defs map[string]*jsonSchemaNode
+		switch {
+		case n.Ref == "#":
+			n = r.root
+			continue
+		case strings.HasPrefix(n.Ref, "#/$defs/"):
+			defs = r.root.Defs
+		case strings.HasPrefix(n.Ref, "#/definitions/"):
+			defs = r.root.Definitions
+		default:
+			return nil, fmt.Errorf("unsupported reference %s, only local definitions are supported", n.Ref)
+		}
+		name := n.Ref[strings.LastIndex(n.Ref, "/")+1:]
+		d, ok := defs[name]
+		if !ok {
+			return nil, fmt.Errorf("reference %s not found", n.Ref)
+		}
+		n = d
+	}
+	return n, nil
+}
+
+func (r *jsonSchemaResolver) convertProperties(n *jsonSchemaNode, depth int) (ast.StreamFields, error) {
+	if depth > maxRefDepth {
+		return nil, fmt.Errorf("the json schema is too deep")
+	}
+	// the properties are sorted by name to have a stable field order
+	names := make([]string, 0, len(n.Properties))
+	for k := range n.Properties {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	result := make(ast.StreamFields, 0, len(names))
+	for _, name := range names {
+		ft, err := r.convertType(n.Properties[name], depth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type for field '%s': %s", name, err)
+		}
+		result = append(result, ast.StreamField{Name: name, FieldType: ft})
+	}
+	return result, nil
+}
+
+func (r *jsonSchemaResolver) convertType(n *jsonSchemaNode, depth int) (ast.FieldType, error) {
+	n, err := r.resolve(n)
+	if err != nil {
+		return nil, err
+	}
+	t, err := n.dataType()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case "integer":
+		return &ast.BasicType{Type: ast.BIGINT}, nil
+	case "number":
+		return &ast.BasicType{Type: ast.FLOAT}, nil
+	case "string":
+		return &ast.BasicType{Type: ast.STRINGS}, nil
+	case "boolean":
+		return &ast.BasicType{Type: ast.BOOLEAN}, nil
+	case "object":
+		sfs, err := r.convertProperties(n, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.RecType{StreamFields: sfs}, nil
+	case "array":
+		if n.Items == nil {
+			return nil, fmt.Errorf("items is required for array")
+		}
+		it, err := r.convertType(n.Items, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		switch ft := it.(type) {
+		case *ast.BasicType:
+			return &ast.ArrayType{Type: ft.Type}, nil
+		case *ast.RecType:
+			return &ast.ArrayType{Type: ast.STRUCT, FieldType: ft}, nil
+		default:
+			return &ast.ArrayType{Type: ast.ARRAY, FieldType: ft}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// dataType returns the type name. For the type list like ["string", "null"], the only non-null type is used.
+func (n *jsonSchemaNode) dataType() (string, error) {
+	switch t := n.Type.(type) {
+	case string:
+		return t, nil
+	case []any:
+		result := ""
+		for _, tt := range t {
+			s, ok := tt.(string)
+			if !ok {
+				return "", fmt.Errorf("invalid type %v", tt)
+			}
+			if s == "null" {
+				continue
+			}
+			if result != "" {
+				return "", fmt.Errorf("multiple types %v are not supported", t)
+			}
+			result = s
+		}
+		if result == "" {
+			return "", fmt.Errorf("invalid type %v", t)
+		}
+		return result, nil
+	case nil:
+		// object schema may omit the type
+		if n.Properties != nil {
+			return "object", nil
+		}
+		return "", fmt.Errorf("type is required")
+	default:
+		return "", fmt.Errorf("invalid type %v", t)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build schema || !core
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestConvertJsonSchema(t *testing.T) {
+	content := `{
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer"},
+    "name": {"type": ["string", "null"]},
+    "ok": {"type": "boolean"},
+    "location": {"$ref": "#/$defs/point"},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "points": {"type": "array", "items": {"$ref": "#/definitions/point"}}
+  },
+  "$defs": {
+    "point": {"type": "object", "properties": {"x": {"type": "number"}, "y": {"type": "number"}}}
+  },
+  "definitions": {
+    "point": {"properties": {"x": {"type": "number"}}}
+  }
+}`
+	r, err := convertJsonSchema([]byte(content))
+	require.NoError(t, err)
+	exp := ast.StreamFields{
+		{Name: "id", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+		{Name: "location", FieldType: &ast.RecType{StreamFields: ast.StreamFields{
+			{Name: "x", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+			{Name: "y", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+		}}},
+		{Name: "name", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+		{Name: "ok", FieldType: &ast.BasicType{Type: ast.BOOLEAN}},
+		{Name: "points", FieldType: &ast.ArrayType{Type: ast.STRUCT, FieldType: &ast.RecType{StreamFields: ast.StreamFields{
+			{Name: "x", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+		}}}},
+		{Name: "tags", FieldType: &ast.ArrayType{Type: ast.STRINGS}},
+	}
+	require.Equal(t, exp, r)
+}
+
+func TestConvertJsonSchemaError(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{
+			content: `{"type": "array"}`,
+			err:     "the root of the json schema must be an object",
+		},
+		{
+			content: `{"type": "object", "properties": {"a": {}}}`,
+			err:     "invalid type for field 'a': type is required",
+		},
+		{
+			content: `{"type": "object", "properties": {"a": {"type": ["string", "integer"]}}}`,
+			err:     "invalid type for field 'a': multiple types [string integer] are not supported",
+		},
+		{
+			content: `{"type": "object", "properties": {"a": {"$ref": "other.json#/a"}}}`,
+			err:     "invalid type for field 'a': unsupported reference other.json#/a, only local definitions are supported",
+		},
+		{
+			content: `{"type": "object", "properties": {"a": {"$ref": "#"}}}`,
+			err:     "invalid type for field 'a': the json schema is too deep",
+		},
+	}
+	for _, tt := range tests {
+		_, err := convertJsonSchema([]byte(tt.content))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), tt.err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
 )
 
 type inferer func(schemaFileName string, SchemaMessageName string) (ast.StreamFields, error)
@@ -30,6 +31,10 @@ var inferes = map[string]inferer{}
 func InferFromSchemaFile(schemaType string, schemaId string) (ast.StreamFields, error) {
 	if c, ok := inferes[schemaType]; ok {
 		r := strings.Split(schemaId, ".")
+		// the json schema is referred by its name only
+		if schemaType == message.FormatJson && len(r) == 1 {
+			r = append(r, "")
+		}
 		if len(r) != 2 {
 			return nil, fmt.Errorf("invalid schemaId: %s", schemaId)
 		}
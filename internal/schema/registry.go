@@ -186,6 +186,14 @@ func GetSchema(schemaType def.SchemaType, name string) (*Info, error) {
 	if err != nil {
 		return nil, err
 	}
+	// The wasm module is binary, only return its file path
+	if schemaType == def.WASM {
+		return &Info{
+			Type:     schemaType,
+			Name:     name,
+			FilePath: schemaFile.SchemaFile,
+		}, nil
+	}
 	if schemaFile.SchemaFile != "" {
 		content, err := os.ReadFile(schemaFile.SchemaFile)
 		if err != nil {
@@ -45,10 +45,21 @@ func (i *Info) Validate() error {
 		return fmt.Errorf("cannot specify both content and file")
 	}
 	switch i.Type {
-	case def.PROTOBUF:
+	case def.PROTOBUF, def.AVRO, def.FLATBUFFERS, def.DBC:
 		if i.Content == "" && i.FilePath == "" {
 			return fmt.Errorf("must specify content or file")
 		}
+	case def.JSONSCHEMA:
+		if i.Content == "" && i.FilePath == "" {
+			return fmt.Errorf("must specify content or file")
+		}
+		if i.Content != "" && !json.Valid([]byte(i.Content)) {
+			return fmt.Errorf("content is not a valid json")
+		}
+	case def.WASM:
+		if i.FilePath == "" {
+			return fmt.Errorf("file is required")
+		}
 	case def.CUSTOM:
 		if i.SoPath == "" {
 			return fmt.Errorf("soFile is required")
@@ -60,5 +71,10 @@ func (i *Info) Validate() error {
 }
 
 var schemaExt = map[def.SchemaType]string{
-	def.PROTOBUF: ".proto",
+	def.PROTOBUF:    ".proto",
+	def.AVRO:        ".avsc",
+	def.FLATBUFFERS: ".fbs",
+	def.WASM:        ".wasm",
+	def.JSONSCHEMA:  ".json",
+	def.DBC:         ".dbc",
 }
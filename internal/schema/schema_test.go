@@ -78,6 +78,59 @@ func TestSchemaInfo(t *testing.T) {
 			},
 			err: nil,
 		},
+		{
+			i: &Info{
+				Type: "avro",
+				Name: "aa",
+			},
+			err: errors.New("must specify content or file"),
+		},
+		{
+			i: &Info{
+				Type:    "avro",
+				Name:    "aa",
+				Content: "bb",
+			},
+			err: nil,
+		},
+		{
+			i: &Info{
+				Type: "flatbuffers",
+				Name: "aa",
+			},
+			err: errors.New("must specify content or file"),
+		},
+		{
+			i: &Info{
+				Type:    "jsonschema",
+				Name:    "aa",
+				Content: `{"type":"object"}`,
+			},
+			err: nil,
+		},
+		{
+			i: &Info{
+				Type:    "jsonschema",
+				Name:    "aa",
+				Content: "{type",
+			},
+			err: errors.New("content is not a valid json"),
+		},
+		{
+			i: &Info{
+				Type: "dbc",
+				Name: "aa",
+			},
+			err: errors.New("must specify content or file"),
+		},
+		{
+			i: &Info{
+				Type:    "wasm",
+				Name:    "aa",
+				Content: "abc",
+			},
+			err: errors.New("file is required"),
+		},
 		{
 			i: &Info{
 				Type:   "custom",
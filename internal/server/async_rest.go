@@ -49,6 +49,9 @@ func registerDataImportTask(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "Invalid file path", logger)
 		return
 	}
+	if c := r.URL.Query().Get("conflict"); c != "" {
+		rsi.Conflict = c
+	}
 	taskID, err := handleDataImportAsyncTask(rsi, partial, stop)
 	if err != nil {
 		handleError(w, err, "", logger)
@@ -0,0 +1,513 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+)
+
+// auditLog records the management operations. It is nil if the audit is disabled.
+var auditLog *audit.Log
+
+// maxAuditErrorSize is the max bytes of the error response to record
+const maxAuditErrorSize = 512
+
+// sensitiveKeys are the parts of the json keys and the query parameters whose values are masked in the audit records
+var sensitiveKeys = []string{"password", "passwd", "token", "secret", "credential"}
+
+// trustedProxies are the networks of the proxies whose forwarded headers are trusted
+var trustedProxies []*net.IPNet
+
+func initAuditLog() error {
+	c := conf.Config.Basic.Audit
+	if !c.Enable {
+		return nil
+	}
+	opts := audit.Options{
+		MaxAge:   time.Duration(c.MaxAge),
+		MaxCount: c.MaxCount,
+	}
+	if c.SignKey != "" {
+		key, err := base64.StdEncoding.DecodeString(c.SignKey)
+		if err != nil {
+			return fmt.Errorf("invalid audit sign key: %v", err)
+		}
+		opts.SignKey = key
+	}
+	proxies, err := parseTrustedProxies(c.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	trustedProxies = proxies
+	s, err := store.GetKV("audit")
+	if err != nil {
+		return err
+	}
+	auditLog, err = audit.New(s, opts)
+	return err
+}
+
+// parseTrustedProxies parses the addresses or CIDRs of the trusted proxies
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid audit trusted proxy %s", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			result = append(result, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit trusted proxy %s: %v", p, err)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+func appendAudit(r *audit.Record) {
+	if err := auditLog.Append(r); err != nil {
+		logger.Warnf("append audit record for %s %s error: %v", r.Method, r.Path, err)
+	}
+}
+
+// auditMiddleware records the mutating requests. It runs before the Auth middleware to record the rejected requests
+// too, and reads the authenticated user after serving the request.
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auditLog == nil || !isMutatingMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &audit.Record{
+			RemoteAddr: remoteAddr(r),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      auditQuery(r.URL.RawQuery),
+		}
+		rec.ForwardedFor = forwardedFor(r, rec.RemoteAddr)
+		if r.Body != nil && !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			body, err := io.ReadAll(r.Body)
+			_ = r.Body.Close()
+			if err != nil {
+				logger.Warnf("read request body for audit error: %v", err)
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			rec.Body = auditBody(body)
+		}
+		r = r.WithContext(middleware.WithUser(r.Context()))
+		aw := &statusResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(aw, r)
+		rec.User = middleware.UserFromContext(r.Context())
+		rec.Status = aw.status
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+		if rec.Status >= http.StatusBadRequest {
+			rec.Error = strings.TrimSpace(aw.errBuf.String())
+		}
+		appendAudit(rec)
+	})
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// remoteAddr returns the address of the peer which sends the request
+func remoteAddr(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// forwardedFor returns the client address in the forwarded headers if the peer is a trusted proxy. The addresses in
+// X-Forwarded-For appended by the trusted proxies are skipped from the right.
+func forwardedFor(r *http.Request, peer string) string {
+	if !isTrustedProxy(peer) {
+		return ""
+	}
+	if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+		addrs := strings.Split(strings.Join(fwd, ","), ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			addr := strings.TrimSpace(addrs[i])
+			if i == 0 || !isTrustedProxy(addr) {
+				return addr
+			}
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-Real-IP"))
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditQuery masks the values of the sensitive query parameters such as the token
+func auditQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, p := range params {
+		k, _, found := strings.Cut(p, "=")
+		if key, err := url.QueryUnescape(k); err == nil {
+			k = key
+		}
+		if found && isSensitiveKey(k) {
+			params[i] = p[:strings.IndexByte(p, '=')] + "=******"
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// auditBody masks the sensitive values of the json body and truncates it to the max body size
+func auditBody(body []byte) string {
+	var v any
+	s := string(body)
+	if err := json.Unmarshal(body, &v); err == nil {
+		if b, err := json.Marshal(maskSensitive(v)); err == nil {
+			s = string(b)
+		}
+	}
+	if max := conf.Config.Basic.Audit.MaxBodySize; max > 0 && len(s) > max {
+		s = s[:max] + "...(truncated)"
+	}
+	return s
+}
+
+// maskSensitive replaces the values of the sensitive keys. The string values of json object such as the rule json
+// of the rpc arguments are masked too.
+func maskSensitive(v any) any {
+	switch vt := v.(type) {
+	case map[string]any:
+		for k, vv := range vt {
+			if isSensitiveKey(k) {
+				vt[k] = "******"
+			} else {
+				vt[k] = maskSensitive(vv)
+			}
+		}
+	case []any:
+		for i, vv := range vt {
+			vt[i] = maskSensitive(vv)
+		}
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(vt), "{") {
+			var m map[string]any
+			if err := json.Unmarshal([]byte(vt), &m); err == nil {
+				if b, err := json.Marshal(maskSensitive(m)); err == nil {
+					return string(b)
+				}
+			}
+		}
+	}
+	return v
+}
+
+// maskSecrets returns a copy of the props whose sensitive values are masked if the user of the request cannot write the
+// config, so that the viewers do not see the secrets. The props are returned as is for the writers.
+func maskSecrets(r *http.Request, props map[string]any) map[string]any {
+	if props == nil || middleware.Allowed(r.Context(), "config", middleware.ActionWrite) {
+		return props
+	}
+	b, err := json.Marshal(props)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	maskSensitive(m)
+	return m
+}
+
+// maskSecretsJson masks the sensitive values of the json response like maskSecrets
+func maskSecretsJson(r *http.Request, b []byte) ([]byte, error) {
+	if middleware.Allowed(r.Context(), "config", middleware.ActionWrite) {
+		return b, nil
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(maskSensitive(v))
+}
+
+func isSensitiveKey(key string) bool {
+	k := strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// statusResponseWriter captures the status and the error message of the response
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+	errBuf bytes.Buffer
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status >= http.StatusBadRequest && w.errBuf.Len() < maxAuditErrorSize {
+		n := maxAuditErrorSize - w.errBuf.Len()
+		if n > len(b) {
+			n = len(b)
+		}
+		w.errBuf.Write(b[:n])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the original writer to http.ResponseController
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// rpcHandler serves the rpc of the cli. If the audit is enabled, the mutating calls are recorded.
+func rpcHandler(srv *rpc.Server) http.Handler {
+	if auditLog == nil {
+		return srv
+	}
+	return &auditRpcHandler{srv: srv}
+}
+
+type auditRpcHandler struct {
+	srv *rpc.Server
+}
+
+// ServeHTTP serves the rpc over http connection like rpc.Server with the audit codec
+func (h *auditRpcHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodConnect {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "405 must CONNECT\n")
+		return
+	}
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		logger.Errorf("rpc hijacking %s error: %v", req.RemoteAddr, err)
+		return
+	}
+	_, _ = io.WriteString(conn, "HTTP/1.0 200 Connected to Go RPC\n\n")
+	h.srv.ServeCodec(newAuditCodec(conn, remoteAddr(req)))
+}
+
+// auditCodec is the gob codec of the rpc server which records the mutating calls after responding
+type auditCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	remote string
+	// method and seq are of the request being read
+	method  string
+	seq     uint64
+	mu      sync.Mutex
+	pending map[uint64]*audit.Record
+}
+
+func newAuditCodec(conn io.ReadWriteCloser, remote string) *auditCodec {
+	buf := bufio.NewWriter(conn)
+	return &auditCodec{
+		rwc:     conn,
+		dec:     gob.NewDecoder(conn),
+		enc:     gob.NewEncoder(buf),
+		encBuf:  buf,
+		remote:  remote,
+		pending: make(map[uint64]*audit.Record),
+	}
+}
+
+func (c *auditCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.dec.Decode(r); err != nil {
+		return err
+	}
+	c.method = r.ServiceMethod
+	c.seq = r.Seq
+	return nil
+}
+
+func (c *auditCodec) ReadRequestBody(body any) error {
+	if err := c.dec.Decode(body); err != nil || body == nil {
+		return err
+	}
+	if !isMutatingRpc(c.method, body) {
+		return nil
+	}
+	rec := &audit.Record{
+		RemoteAddr: c.remote,
+		Method:     "RPC",
+		Path:       c.method,
+	}
+	if s, ok := body.(*string); ok {
+		rec.Body = auditBody([]byte(strconv.Quote(*s)))
+	} else if b, err := json.Marshal(body); err == nil {
+		rec.Body = auditBody(b)
+	}
+	c.mu.Lock()
+	c.pending[c.seq] = rec
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *auditCodec) WriteResponse(r *rpc.Response, body any) (err error) {
+	if err = c.enc.Encode(r); err == nil {
+		if err = c.enc.Encode(body); err == nil {
+			err = c.encBuf.Flush()
+		}
+	}
+	if err != nil {
+		_ = c.Close()
+	}
+	c.mu.Lock()
+	rec := c.pending[r.Seq]
+	delete(c.pending, r.Seq)
+	c.mu.Unlock()
+	if rec != nil {
+		rec.Status = http.StatusOK
+		if r.Error != "" {
+			rec.Status = http.StatusBadRequest
+			rec.Error = r.Error
+		}
+		appendAudit(rec)
+	}
+	return err
+}
+
+func (c *auditCodec) Close() error {
+	return c.rwc.Close()
+}
+
+// readOnlyRpcPrefixes are the prefixes of the rpc methods which do not change anything
+var readOnlyRpcPrefixes = []string{"Server.Get", "Server.Desc", "Server.Show", "Server.Export", "Server.Validate"}
+
+func isMutatingRpc(method string, body any) bool {
+	for _, p := range readOnlyRpcPrefixes {
+		if strings.HasPrefix(method, p) {
+			return false
+		}
+	}
+	// The stream statements include the show and describe statements
+	if s, ok := body.(*string); ok && method == "Server.Stream" {
+		stmt := strings.ToUpper(strings.TrimSpace(*s))
+		if strings.HasPrefix(stmt, "SHOW") || strings.HasPrefix(stmt, "DESC") {
+			return false
+		}
+	}
+	return true
+}
+
+// query the audit records
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	if auditLog == nil {
+		handleError(w, fmt.Errorf("audit log is not enabled"), "", logger)
+		return
+	}
+	q := r.URL.Query()
+	f := audit.Filter{
+		User:   q.Get("user"),
+		Method: q.Get("method"),
+		Path:   q.Get("path"),
+	}
+	var err error
+	if v := q.Get("from"); v != "" {
+		if f.From, err = strconv.ParseInt(v, 10, 64); err != nil {
+			handleError(w, fmt.Errorf("invalid from %s: %v", v, err), "", logger)
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if f.To, err = strconv.ParseInt(v, 10, 64); err != nil {
+			handleError(w, fmt.Errorf("invalid to %s: %v", v, err), "", logger)
+			return
+		}
+	}
+	records, err := auditLog.Query(f)
+	if err != nil {
+		handleError(w, err, "query audit log error", logger)
+		return
+	}
+	listResponse(records, w, r, logger)
+}
+
+// verify the signatures of the audit records
+func auditVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	if auditLog == nil {
+		handleError(w, fmt.Errorf("audit log is not enabled"), "", logger)
+		return
+	}
+	result, err := auditLog.Verify()
+	if err != nil {
+		handleError(w, err, "verify audit log error", logger)
+		return
+	}
+	jsonResponse(result, w, logger)
+}
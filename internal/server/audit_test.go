@@ -0,0 +1,203 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/audit"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/model"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func newTestAuditLog(t *testing.T, name string) {
+	s, err := store.GetKV(name)
+	require.NoError(t, err)
+	require.NoError(t, s.Clean())
+	auditLog, err = audit.New(s, audit.Options{SignKey: []byte("secret")})
+	require.NoError(t, err)
+}
+
+func (suite *RestTestSuite) TestAudit() {
+	newTestAuditLog(suite.T(), "auditRest")
+	defer func() {
+		auditLog = nil
+	}()
+	_, _ = streamProcessor.DropStream("demoAudit", ast.TypeStream)
+	h := auditMiddleware(suite.r)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", bytes.NewBufferString(`{"sql":"CREATE stream demoAudit() WITH (DATASOURCE=\"audit\", TYPE=\"memory\", FORMAT=\"json\")"}`))
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 192.168.0.1")
+	req.RemoteAddr = "192.168.0.2:51234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	// Not recorded
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/streams", http.NoBody)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	// Failed with the password masked
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(`{"id":"auditRule","sql":"select * from nonexist","actions":[{"mqtt":{"server":"tcp://127.0.0.1:1883","password":"pass"}}]}`))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/streams/demoAudit?token=abc", http.NoBody)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/audit?method=post", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	var records []*audit.Record
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &records))
+	require.Len(suite.T(), records, 2)
+	assert.Equal(suite.T(), "/streams", records[0].Path)
+	// The forwarded header is ignored if the peer is not a trusted proxy
+	assert.Equal(suite.T(), "192.168.0.2", records[0].RemoteAddr)
+	assert.Empty(suite.T(), records[0].ForwardedFor)
+	assert.Equal(suite.T(), http.StatusCreated, records[0].Status)
+	assert.Equal(suite.T(), "/rules", records[1].Path)
+	assert.Equal(suite.T(), http.StatusBadRequest, records[1].Status)
+	assert.NotEmpty(suite.T(), records[1].Error)
+	assert.Contains(suite.T(), records[1].Body, `"password":"******"`)
+	assert.NotContains(suite.T(), records[1].Body, "pass\"")
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/audit?path=/streams&sort=-seq&limit=1", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	records = nil
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &records))
+	require.Len(suite.T(), records, 1)
+	assert.Equal(suite.T(), http.MethodDelete, records[0].Method)
+	assert.Equal(suite.T(), "token=******", records[0].Query)
+	assert.Equal(suite.T(), "2", w.Header().Get(TotalCountHeader))
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/audit/verify", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.JSONEq(suite.T(), `{"verified":true,"count":3}`, w.Body.String())
+}
+
+type auditTestService int
+
+func (s *auditTestService) CreateRule(arg *model.RPCArgDesc, reply *string) error {
+	if arg.Name == "bad" {
+		return errors.New("invalid rule")
+	}
+	*reply = "created"
+	return nil
+}
+
+func (s *auditTestService) ShowRules(_ int, reply *string) error {
+	*reply = "[]"
+	return nil
+}
+
+func (s *auditTestService) Stream(stream string, reply *string) error {
+	*reply = "done"
+	return nil
+}
+
+func TestAuditRpc(t *testing.T) {
+	newTestAuditLog(t, "auditRpc")
+	defer func() {
+		auditLog = nil
+	}()
+	srv := rpc.NewServer()
+	require.NoError(t, srv.RegisterName("Server", new(auditTestService)))
+	sc, cc := net.Pipe()
+	go srv.ServeCodec(newAuditCodec(sc, "127.0.0.1"))
+	client := rpc.NewClient(cc)
+	defer client.Close()
+
+	var reply string
+	require.NoError(t, client.Call("Server.CreateRule", &model.RPCArgDesc{Name: "rule1", Json: `{"actions":[{"mqtt":{"password":"pass"}}]}`}, &reply))
+	require.EqualError(t, client.Call("Server.CreateRule", &model.RPCArgDesc{Name: "bad"}, &reply), "invalid rule")
+	require.NoError(t, client.Call("Server.ShowRules", 0, &reply))
+	require.NoError(t, client.Call("Server.Stream", "show streams", &reply))
+	require.NoError(t, client.Call("Server.Stream", "DROP STREAM demo", &reply))
+
+	records, err := auditLog.Query(audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "RPC", records[0].Method)
+	assert.Equal(t, "Server.CreateRule", records[0].Path)
+	assert.Equal(t, "127.0.0.1", records[0].RemoteAddr)
+	assert.Equal(t, http.StatusOK, records[0].Status)
+	assert.True(t, strings.Contains(records[0].Body, `\"password\":\"******\"`), records[0].Body)
+	assert.Equal(t, http.StatusBadRequest, records[1].Status)
+	assert.Equal(t, "invalid rule", records[1].Error)
+	assert.Equal(t, "Server.Stream", records[2].Path)
+	assert.Equal(t, `"DROP STREAM demo"`, records[2].Body)
+}
+
+func TestForwardedFor(t *testing.T) {
+	var err error
+	trustedProxies, err = parseTrustedProxies([]string{"192.168.0.1", "10.1.0.0/16"})
+	require.NoError(t, err)
+	defer func() {
+		trustedProxies = nil
+	}()
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		peer    string
+		client  string
+	}{
+		{name: "direct", remote: "10.0.0.1:1234", peer: "10.0.0.1"},
+		{name: "untrusted", remote: "10.0.0.1:1234", headers: map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Real-IP": "1.1.1.1"}, peer: "10.0.0.1"},
+		{name: "trusted", remote: "192.168.0.1:1234", headers: map[string]string{"X-Forwarded-For": "1.1.1.1"}, peer: "192.168.0.1", client: "1.1.1.1"},
+		// The forged address before the address appended by the trusted proxy is skipped
+		{name: "chain", remote: "10.1.2.3:1234", headers: map[string]string{"X-Forwarded-For": "6.6.6.6, 2.2.2.2, 192.168.0.1"}, peer: "10.1.2.3", client: "2.2.2.2"},
+		{name: "real ip", remote: "192.168.0.1:1234", headers: map[string]string{"X-Real-IP": "3.3.3.3"}, peer: "192.168.0.1", client: "3.3.3.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/rules", http.NoBody)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			peer := remoteAddr(req)
+			assert.Equal(t, tt.peer, peer)
+			assert.Equal(t, tt.client, forwardedFor(req, peer))
+		})
+	}
+	_, err = parseTrustedProxies([]string{"proxy"})
+	assert.EqualError(t, err, "invalid audit trusted proxy proxy")
+}
+
+func TestAuditQuery(t *testing.T) {
+	assert.Equal(t, "", auditQuery(""))
+	assert.Equal(t, "force=true&access_token=******&Password=******&flag", auditQuery("force=true&access_token=abc&Password=p%26w&flag"))
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/backup"
+)
+
+type restoreResponse struct {
+	*backup.Manifest
+	Message string `json:"message"`
+}
+
+func nodeDirs() (backup.Dirs, error) {
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		return backup.Dirs{}, err
+	}
+	// The plugins folder may not exist if no plugin is installed
+	pluginsDir, err := conf.GetPluginsLoc()
+	if err != nil {
+		conf.Log.Warnf("skip the plugins in backup: %v", err)
+		pluginsDir = ""
+	}
+	etcDir, err := conf.GetConfLoc()
+	if err != nil {
+		return backup.Dirs{}, err
+	}
+	return backup.Dirs{Data: dataDir, Plugins: pluginsDir, Etc: etcDir}, nil
+}
+
+// applyRestore replaces the node data with the restored backup. It must run before the stores are set up.
+func applyRestore() error {
+	dirs, err := nodeDirs()
+	if err != nil {
+		return err
+	}
+	m, err := backup.Apply(dirs, version)
+	if err != nil {
+		return fmt.Errorf("apply the restored backup error: %v", err)
+	}
+	if m != nil {
+		conf.Log.Infof("node is restored from the backup of version %s created at %d", m.Version, m.CreatedAt)
+	}
+	return nil
+}
+
+// The kv stores in redis or foundationDB are not in the data folder, they should be backed up by their own tools
+func checkBackupStore() error {
+	if conf.Config == nil {
+		return nil
+	}
+	if conf.Config.Store.Type != "sqlite" || conf.Config.Store.ExtStateType != "sqlite" || (conf.Config.Store.StateType != "" && conf.Config.Store.StateType != "sqlite") {
+		return fmt.Errorf("backup only supports the sqlite store, but got %s, ext state store %s and state store %s", conf.Config.Store.Type, conf.Config.Store.ExtStateType, conf.Config.Store.StateType)
+	}
+	return nil
+}
+
+func nodeBackupHandler(w http.ResponseWriter, r *http.Request) {
+	if err := checkBackupStore(); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	dirs, err := nodeDirs()
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	// Write to a temp file first so that the error can be responded
+	f, err := os.CreateTemp("", "kuiper_backup_*.tar.gz")
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	if err := backup.Create(f, dirs, version); err != nil {
+		handleError(w, err, "Create backup error", logger)
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=ekuiper_backup_%s.tar.gz", time.Now().Format("20060102150405")))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, f); err != nil {
+		logger.Errorf("send backup error: %v", err)
+	}
+}
+
+func nodeRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if err := checkBackupStore(); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	dirs, err := nodeDirs()
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	// Maximum upload of 1 GB files
+	if err := r.ParseMultipartForm(1024 << 20); err != nil {
+		handleError(w, err, "Error parse the multi part form", logger)
+		return
+	}
+	file, _, err := r.FormFile("backupFile")
+	if err != nil {
+		handleError(w, err, "Error Retrieving the File", logger)
+		return
+	}
+	defer file.Close()
+	m, err := backup.Stage(file, dirs, version)
+	if err != nil {
+		handleError(w, err, "Restore backup error", logger)
+		return
+	}
+	stop := r.URL.Query().Get("stop") == "1"
+	if stop {
+		go func() {
+			time.Sleep(1 * time.Second)
+			os.Exit(100)
+		}()
+	}
+	jsonResponse(&restoreResponse{Manifest: m, Message: "the backup is restored and will take effect after restart"}, w, logger)
+}
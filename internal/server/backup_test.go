@@ -0,0 +1,76 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func (suite *RestTestSuite) TestNodeBackupRestore() {
+	dataDir, err := conf.GetDataLoc()
+	require.NoError(suite.T(), err)
+	defer os.RemoveAll(filepath.Join(dataDir, "restore"))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/node/backup", http.NoBody)
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(suite.T(), "application/gzip", w.Header().Get("Content-Type"))
+	archive := w.Body.Bytes()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	fw, err := mw.CreateFormFile("backupFile", "backup.tar.gz")
+	require.NoError(suite.T(), err)
+	_, err = fw.Write(archive)
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), mw.Close())
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/node/restore", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	r := &restoreResponse{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), r))
+	assert.NotEmpty(suite.T(), r.Message)
+	assert.FileExists(suite.T(), filepath.Join(dataDir, "restore", "manifest.json"))
+	assert.FileExists(suite.T(), filepath.Join(dataDir, "restore", "data", "sqliteKV.db"))
+
+	// Invalid archive
+	body = &bytes.Buffer{}
+	mw = multipart.NewWriter(body)
+	fw, err = mw.CreateFormFile("backupFile", "backup.tar.gz")
+	require.NoError(suite.T(), err)
+	_, err = fw.Write([]byte("invalid"))
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), mw.Close())
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/node/restore", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.NoDirExists(suite.T(), filepath.Join(dataDir, "restore"))
+}
@@ -0,0 +1,379 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/meta"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// The policies to handle the imported streams, tables, rules and other resources which already exist in partial import
+const (
+	ConflictOverwrite = "overwrite"
+	ConflictSkip      = "skip"
+	ConflictRename    = "rename"
+)
+
+func validateConflictPolicy(policy string) error {
+	switch policy {
+	case "", ConflictOverwrite, ConflictSkip, ConflictRename:
+		return nil
+	default:
+		return fmt.Errorf("invalid conflict policy %s, must be overwrite, skip or rename", policy)
+	}
+}
+
+// resolveConflicts removes or renames the resources of the bundle which already exist according to the policy.
+// The streams, tables and rules are renamed with a number suffix in rename policy and the rules in the bundle are
+// updated to refer to the new stream names. The other resources like conf keys, schemas and plugins are shared, so
+// they are kept as is for both skip and rename policy. It returns the resolved resources as "type.name" to "skipped"
+// or the new name.
+func resolveConflicts(c *Configuration, policy string) map[string]string {
+	resolved := map[string]string{}
+	if policy == "" || policy == ConflictOverwrite {
+		return resolved
+	}
+	for _, st := range []ast.StreamType{ast.TypeStream, ast.TypeTable} {
+		set, typ := c.Streams, "streams"
+		if st == ast.TypeTable {
+			set, typ = c.Tables, "tables"
+		}
+		for _, name := range sortedKeys(set) {
+			if !streamExists(name) {
+				continue
+			}
+			statement := set[name]
+			delete(set, name)
+			if policy == ConflictSkip {
+				resolved[typ+"."+name] = "skipped"
+				continue
+			}
+			newName := newResourceName(name, func(n string) bool {
+				_, inStreams := c.Streams[n]
+				_, inTables := c.Tables[n]
+				return inStreams || inTables || streamExists(n)
+			})
+			set[newName] = renameStreamStatement(statement, name, newName)
+			renameRuleStreams(c.Rules, name, newName)
+			resolved[typ+"."+name] = newName
+		}
+	}
+	for _, id := range sortedKeys(c.Rules) {
+		if _, err := ruleProcessor.GetRuleJson(id); err != nil {
+			continue
+		}
+		ruleJson := c.Rules[id]
+		delete(c.Rules, id)
+		if policy == ConflictSkip {
+			resolved["rules."+id] = "skipped"
+			continue
+		}
+		newId := newResourceName(id, func(n string) bool {
+			_, inBundle := c.Rules[n]
+			_, err := ruleProcessor.GetRuleJson(n)
+			return inBundle || err == nil
+		})
+		c.Rules[newId] = setRuleField(ruleJson, "id", newId)
+		resolved["rules."+id] = newId
+	}
+	// shared resources
+	skipConfKeys(c.SourceConfig, meta.SourceCfgOperatorKeyPrefix, "sourceConfig", resolved)
+	skipConfKeys(c.SinkConfig, meta.SinkCfgOperatorKeyPrefix, "sinkConfig", resolved)
+	skipConfKeys(c.ConnectionConfig, meta.ConnectionCfgOperatorKeyPrefix, "connectionConfig", resolved)
+	for typ, res := range map[string]struct {
+		m       map[string]string
+		manager string
+	}{
+		"nativePlugins":   {c.NativePlugins, "plugin"},
+		"portablePlugins": {c.PortablePlugins, "portable"},
+		"Service":         {c.Service, "service"},
+		"Schema":          {c.Schema, "schema"},
+		"scripts":         {c.Scripts, "script"},
+	} {
+		if managers[res.manager] == nil {
+			continue
+		}
+		skipExisting(res.m, managers[res.manager].Export(), typ, resolved)
+	}
+	skipExisting(c.Uploads, uploadsExport(), "uploads", resolved)
+	return resolved
+}
+
+// validateDependencies checks the streams, conf keys and schemas required by the rules and streams of the bundle are
+// either in the bundle or already installed. All the missing dependencies are reported in the error.
+func validateDependencies(c *Configuration) error {
+	var missing []string
+	for _, id := range sortedKeys(c.Rules) {
+		rule := &def.Rule{}
+		if err := json.Unmarshal([]byte(c.Rules[id]), rule); err != nil || rule.Sql == "" {
+			continue
+		}
+		stmt, err := xsql.GetStatementFromSql(rule.Sql)
+		if err != nil {
+			continue
+		}
+		for _, s := range xsql.GetStreams(stmt) {
+			_, inStreams := c.Streams[s]
+			_, inTables := c.Tables[s]
+			if !inStreams && !inTables && !streamExists(s) {
+				missing = append(missing, fmt.Sprintf("rule %s requires stream %s", id, s))
+			}
+		}
+	}
+	var schemaGetter InstallScriptGetter
+	if managers["schema"] != nil {
+		schemaGetter, _ = managers["schema"].(InstallScriptGetter)
+	}
+	for _, set := range []map[string]string{c.Streams, c.Tables} {
+		for _, name := range sortedKeys(set) {
+			stmt, err := xsql.NewParser(strings.NewReader(set[name])).ParseCreateStmt()
+			if err != nil {
+				continue
+			}
+			ss, ok := stmt.(*ast.StreamStmt)
+			if !ok || ss.Options == nil {
+				continue
+			}
+			key, typ := ss.Options.CONF_KEY, ss.Options.TYPE
+			if typ == "" {
+				typ = "mqtt"
+				if ss.StreamType == ast.TypeTable {
+					typ = "file"
+				}
+			}
+			// only validate the source types which support conf keys
+			if ops, hasConf := meta.GetConfOperator(meta.SourceCfgOperatorKeyPrefix + typ); key != "" && key != "default" && hasConf {
+				if _, installed := ops.CopyConfContent()[key]; !installed && !hasConfKey(c.SourceConfig[typ], key) {
+					missing = append(missing, fmt.Sprintf("%s %s requires conf key %s of %s", ast.StreamTypeMap[ss.StreamType], name, key, typ))
+				}
+			}
+			if ss.Options.SCHEMAID != "" && schemaGetter != nil {
+				sk := schemaKey(ss.Options.FORMAT, ss.Options.SCHEMAID)
+				if _, inBundle := c.Schema[sk]; !inBundle {
+					if _, script := schemaGetter.InstallScript(sk); script == "" {
+						missing = append(missing, fmt.Sprintf("%s %s requires schema %s", ast.StreamTypeMap[ss.StreamType], name, ss.Options.SCHEMAID))
+					}
+				}
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing dependencies: %s", strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// referencedUploads returns the uploaded files which are referred by the exported resources
+func referencedUploads(c *Configuration) map[string]string {
+	var sb strings.Builder
+	for _, set := range []map[string]string{c.Streams, c.Tables, c.Rules, c.SourceConfig, c.SinkConfig, c.ConnectionConfig} {
+		for _, v := range set {
+			sb.WriteString(v)
+		}
+	}
+	content := sb.String()
+	result := map[string]string{}
+	for name, v := range uploadsExport() {
+		if strings.Contains(content, name) {
+			result[name] = v
+		}
+	}
+	return result
+}
+
+func streamExists(name string) bool {
+	if _, err := streamProcessor.GetStream(name, ast.TypeStream); err == nil {
+		return true
+	}
+	_, err := streamProcessor.GetStream(name, ast.TypeTable)
+	return err == nil
+}
+
+func newResourceName(name string, exists func(string) bool) string {
+	for i := 1; ; i++ {
+		n := fmt.Sprintf("%s_%d", name, i)
+		if !exists(n) {
+			return n
+		}
+	}
+}
+
+var createStmtPrefix = regexp.MustCompile(`(?i)^\s*CREATE\s+(STREAM|TABLE)\s+`)
+
+func renameStreamStatement(statement, name, newName string) string {
+	loc := createStmtPrefix.FindStringIndex(statement)
+	if loc == nil {
+		return statement
+	}
+	rest := statement[loc[1]:]
+	switch {
+	case strings.HasPrefix(rest, "`"+name+"`"):
+		rest = "`" + newName + "`" + rest[len(name)+2:]
+	case strings.HasPrefix(rest, name):
+		rest = newName + rest[len(name):]
+	}
+	return statement[:loc[1]] + rest
+}
+
+// renameRuleStreams updates the sql of the rules which refer to the renamed stream
+func renameRuleStreams(rules map[string]string, name, newName string) {
+	for id, ruleJson := range rules {
+		rule := &def.Rule{}
+		if err := json.Unmarshal([]byte(ruleJson), rule); err != nil || rule.Sql == "" {
+			continue
+		}
+		sql := renameIdent(rule.Sql, name, newName)
+		if sql != rule.Sql {
+			rules[id] = setRuleField(ruleJson, "sql", sql)
+		}
+	}
+}
+
+// renameIdent replaces the identifiers in the sql which equal to the name. The string literals are not touched.
+func renameIdent(sql, name, newName string) string {
+	var sb strings.Builder
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(sql) && sql[j] != c {
+				if sql[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(sql) {
+				j++
+			}
+			sb.WriteString(sql[i:j])
+			i = j
+		case c == '`':
+			j := strings.IndexByte(sql[i+1:], '`')
+			if j < 0 {
+				sb.WriteString(sql[i:])
+				return sb.String()
+			}
+			if sql[i+1:i+1+j] == name {
+				sb.WriteString("`" + newName + "`")
+			} else {
+				sb.WriteString(sql[i : i+j+2])
+			}
+			i += j + 2
+		case isIdentChar(c):
+			j := i
+			for j < len(sql) && isIdentChar(sql[j]) {
+				j++
+			}
+			// the field of a stream like demo.name is kept
+			if sql[i:j] == name && (i == 0 || sql[i-1] != '.') {
+				sb.WriteString(newName)
+			} else {
+				sb.WriteString(sql[i:j])
+			}
+			i = j
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func setRuleField(ruleJson string, field string, value string) string {
+	m := map[string]any{}
+	if err := json.Unmarshal([]byte(ruleJson), &m); err != nil {
+		return ruleJson
+	}
+	m[field] = value
+	r, err := json.Marshal(m)
+	if err != nil {
+		return ruleJson
+	}
+	return string(r)
+}
+
+// skipConfKeys removes the installed conf keys from the bundle which is the json of conf key to props map by plugin
+func skipConfKeys(bundle map[string]string, prefix string, typ string, resolved map[string]string) {
+	for plugin, content := range bundle {
+		ops, ok := meta.GetConfOperator(prefix + plugin)
+		if !ok {
+			continue
+		}
+		installed := ops.CopyConfContent()
+		keys := map[string]any{}
+		if err := json.Unmarshal([]byte(content), &keys); err != nil {
+			continue
+		}
+		changed := false
+		for key := range keys {
+			if _, ok := installed[key]; ok {
+				delete(keys, key)
+				resolved[typ+"."+plugin+"."+key] = "skipped"
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if len(keys) == 0 {
+			delete(bundle, plugin)
+		} else if r, err := json.Marshal(keys); err == nil {
+			bundle[plugin] = string(r)
+		}
+	}
+}
+
+func skipExisting(bundle, existing map[string]string, typ string, resolved map[string]string) {
+	for k := range bundle {
+		if _, ok := existing[k]; ok {
+			delete(bundle, k)
+			resolved[typ+"."+k] = "skipped"
+		}
+	}
+}
+
+func hasConfKey(content string, key string) bool {
+	if content == "" {
+		return false
+	}
+	keys := map[string]any{}
+	if err := json.Unmarshal([]byte(content), &keys); err != nil {
+		return false
+	}
+	_, ok := keys[key]
+	return ok
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
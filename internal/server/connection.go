@@ -58,10 +58,9 @@ func connectionsHandler(w http.ResponseWriter, r *http.Request) {
 		metaList := connection.GetAllConnectionsMeta(forceAll)
 		resp := make([]*ConnectionResponse, 0)
 		for _, meta := range metaList {
-			resp = append(resp, getConnectionRespByMeta(meta))
+			resp = append(resp, getConnectionRespByMeta(r, meta))
 		}
-		w.WriteHeader(http.StatusOK)
-		jsonResponse(resp, w, logger)
+		listResponse(resp, w, r, logger)
 	}
 }
 
@@ -85,7 +84,7 @@ func connectionHandler(w http.ResponseWriter, r *http.Request) {
 			handleError(w, err, "", logger)
 			return
 		}
-		res := getConnectionRespByMeta(meta)
+		res := getConnectionRespByMeta(r, meta)
 		jsonResponse(res, w, logger)
 	case http.MethodDelete:
 		if err := connection.DropNameConnection(context.Background(), id); err != nil {
@@ -115,12 +114,13 @@ func connectionHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func getConnectionRespByMeta(meta *connection.Meta) *ConnectionResponse {
+// getConnectionRespByMeta returns the connection response whose secrets are masked for the viewers
+func getConnectionRespByMeta(req *http.Request, meta *connection.Meta) *ConnectionResponse {
 	status, e := meta.GetStatus()
 	r := &ConnectionResponse{
 		Typ:      meta.Typ,
 		ID:       meta.ID,
-		Props:    meta.Props,
+		Props:    maskSecrets(req, meta.Props),
 		IsNamed:  meta.Named,
 		RefCount: meta.GetRefCount(),
 		Status:   status,
@@ -24,6 +24,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 )
 
@@ -127,3 +130,35 @@ func (suite *RestTestSuite) TestEditInternalConn() {
 	suite.r.ServeHTTP(w, req)
 	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
 }
+
+func (suite *RestTestSuite) TestConnectionSecretsForViewer() {
+	connection.InitConnectionManager4Test()
+	buf := bytes.NewBufferString(`{"id":"connSecret","typ":"mock","props":{"datasource":"/test1","password":"pwd"}}`)
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/connections", buf)
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code)
+	defer connection.DropNameConnection(context.Background(), "connSecret")
+
+	rb, err := middleware.NewRBAC(conf.RbacConf{Enable: true, Users: map[string]string{"alice": middleware.RoleViewer, "carol": middleware.RoleAdmin}})
+	require.NoError(suite.T(), err)
+	handler := rb.Middleware(suite.r)
+	get := func(user string, path string) string {
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080"+path, bytes.NewBufferString("any"))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, middleware.SetUser(req, user))
+		require.Equal(suite.T(), http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+	// The viewer cannot see the password
+	body := get("alice", "/connections/connSecret")
+	require.Contains(suite.T(), body, `"password":"******"`)
+	require.NotContains(suite.T(), body, "pwd")
+	require.NotContains(suite.T(), get("alice", "/connections?forceAll=true"), "pwd")
+	// The admin can
+	require.Contains(suite.T(), get("carol", "/connections/connSecret"), `"password":"pwd"`)
+	// The connection is not modified by the masking
+	meta, err := connection.GetConnectionDetail(context.Background(), "connSecret")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "pwd", meta.Props["password"])
+}
@@ -0,0 +1,283 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/gitops"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const gitOpsManagedKey = "managed"
+
+var gitOpsCtrl *gitOpsController
+
+type gitOpsDrift struct {
+	gitops.Change
+	Error string `json:"error,omitempty"`
+}
+
+type gitOpsStatus struct {
+	Repository   string        `json:"repository"`
+	Branch       string        `json:"branch,omitempty"`
+	Path         string        `json:"path,omitempty"`
+	Strategy     string        `json:"strategy"`
+	Revision     string        `json:"revision,omitempty"`
+	LastSyncTime int64         `json:"lastSyncTime"`
+	LastError    string        `json:"lastError,omitempty"`
+	Drift        []gitOpsDrift `json:"drift"`
+}
+
+// gitOpsController reconciles the node with the definitions in the repository. The resources created by the sync
+// are recorded as managed so that only they are pruned.
+type gitOpsController struct {
+	sync.Mutex
+	c      conf.GitOpsConf
+	repo   *gitops.Repo
+	db     kv.KeyValue
+	status gitOpsStatus
+}
+
+func newGitOpsController(c conf.GitOpsConf) (*gitOpsController, error) {
+	if c.Repository == "" {
+		return nil, errors.New("gitops repository is required")
+	}
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		return nil, err
+	}
+	db, err := store.GetKV("gitops")
+	if err != nil {
+		return nil, err
+	}
+	return &gitOpsController{
+		c: c,
+		repo: &gitops.Repo{
+			URL:    c.Repository,
+			Branch: c.Branch,
+			Path:   c.Path,
+			Dir:    filepath.Join(dataDir, "gitops", "repo"),
+		},
+		db: db,
+		status: gitOpsStatus{
+			Repository: gitops.RedactURL(c.Repository, c.Repository),
+			Branch:     c.Branch,
+			Path:       c.Path,
+			Strategy:   c.Strategy,
+			Drift:      []gitOpsDrift{},
+		},
+	}, nil
+}
+
+func initGitOps(ctx context.Context) error {
+	if !conf.Config.Basic.GitOps.Enable {
+		return nil
+	}
+	var err error
+	gitOpsCtrl, err = newGitOpsController(conf.Config.Basic.GitOps)
+	if err != nil {
+		return err
+	}
+	go gitOpsCtrl.run(ctx)
+	return nil
+}
+
+func (g *gitOpsController) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(g.c.Interval))
+	defer ticker.Stop()
+	for {
+		if err := g.sync(ctx, g.c.Strategy == conf.GitOpsStrategyApply); err != nil {
+			conf.Log.Errorf("gitops sync error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync pulls the repository and compares it with the node. The changes are applied if apply is set, otherwise
+// they are reported as drift.
+func (g *gitOpsController) sync(ctx context.Context, apply bool) error {
+	g.Lock()
+	defer g.Unlock()
+	drift, rev, err := g.reconcile(ctx, apply)
+	g.status.LastSyncTime = timex.GetNowInMilli()
+	if err != nil {
+		g.status.LastError = err.Error()
+		return err
+	}
+	g.status.Revision = rev
+	g.status.LastError = ""
+	g.status.Drift = drift
+	return nil
+}
+
+func (g *gitOpsController) reconcile(ctx context.Context, apply bool) ([]gitOpsDrift, string, error) {
+	dir, rev, err := g.repo.Fetch(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	desired, err := gitops.Load(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	actual, err := actualGitOpsState()
+	if err != nil {
+		return nil, "", err
+	}
+	var managed []gitops.Resource
+	if _, err := g.db.Get(gitOpsManagedKey, &managed); err != nil {
+		return nil, "", err
+	}
+	changes := gitops.Plan(desired, actual, managed, g.c.Prune)
+	drift := make([]gitOpsDrift, 0, len(changes))
+	if !apply {
+		for _, c := range changes {
+			drift = append(drift, gitOpsDrift{Change: c})
+		}
+		return drift, rev, nil
+	}
+	failed := make(map[gitops.Resource]bool)
+	connChanged := false
+	for _, c := range changes {
+		if err := applyGitOpsChange(c); err != nil {
+			conf.Log.Errorf("gitops %s %s %s error: %v", c.Action, c.Kind, c.Name, err)
+			drift = append(drift, gitOpsDrift{Change: c, Error: err.Error()})
+			failed[c.Resource] = true
+			continue
+		}
+		conf.Log.Infof("gitops %s %s %s", c.Action, c.Kind, c.Name)
+		if c.Kind == gitops.KindConnectionConfig {
+			connChanged = true
+		}
+	}
+	if connChanged {
+		if err := connection.ReloadNamedConnection(); err != nil {
+			conf.Log.Errorf("reload connection config error: %v", err)
+		}
+	}
+	// Keep tracking the removed resources which are not pruned yet
+	newManaged := desired.Resources()
+	inDesired := make(map[gitops.Resource]bool, len(newManaged))
+	for _, r := range newManaged {
+		inDesired[r] = true
+	}
+	for _, r := range managed {
+		if !inDesired[r] && (!g.c.Prune || failed[r]) {
+			newManaged = append(newManaged, r)
+		}
+	}
+	if err := g.db.Set(gitOpsManagedKey, newManaged); err != nil {
+		return nil, "", err
+	}
+	return drift, rev, nil
+}
+
+func actualGitOpsState() (*gitops.State, error) {
+	s := gitops.NewState()
+	rs := rulesetProcessor.ExportRuleSet()
+	if rs == nil {
+		return nil, errors.New("fail to get the rules and streams")
+	}
+	s.Streams, s.Tables, s.Rules = rs.Streams, rs.Tables, rs.Rules
+	var err error
+	if s.SourceConfig, err = conf.GetCfgFromKVStorage("sources", "", ""); err != nil {
+		return nil, err
+	}
+	if s.SinkConfig, err = conf.GetCfgFromKVStorage("sinks", "", ""); err != nil {
+		return nil, err
+	}
+	if s.ConnectionConfig, err = conf.GetCfgFromKVStorage("connections", "", ""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func applyGitOpsChange(c gitops.Change) error {
+	switch c.Kind {
+	case gitops.KindSourceConfig, gitops.KindSinkConfig, gitops.KindConnectionConfig:
+		if c.Action == gitops.ActionDelete {
+			typ, plu, name, err := splitConfKey(c.Name)
+			if err != nil {
+				return err
+			}
+			return conf.DropCfgKeyFromStorage(typ, plu, name)
+		}
+		return writeConf(c.Name, c.Desired.(map[string]any))
+	case gitops.KindStream, gitops.KindTable:
+		st := ast.TypeStream
+		if c.Kind == gitops.KindTable {
+			st = ast.TypeTable
+		}
+		var err error
+		if c.Action == gitops.ActionDelete {
+			_, err = streamProcessor.DropStream(c.Name, st)
+		} else {
+			_, err = streamProcessor.ExecReplaceStream(c.Name, c.Desired.(string), nil, st)
+		}
+		return err
+	case gitops.KindRule:
+		switch c.Action {
+		case gitops.ActionCreate:
+			_, err := registry.CreateRule(c.Name, c.Desired.(string))
+			return err
+		case gitops.ActionUpdate:
+			return registry.UpdateRule(c.Name, c.Desired.(string))
+		default:
+			return registry.DeleteRule(c.Name)
+		}
+	default:
+		return fmt.Errorf("unknown kind %s", c.Kind)
+	}
+}
+
+func gitOpsStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if gitOpsCtrl == nil {
+		handleError(w, errors.New("gitops is not enabled"), "", logger)
+		return
+	}
+	gitOpsCtrl.Lock()
+	status := gitOpsCtrl.status
+	gitOpsCtrl.Unlock()
+	jsonResponse(status, w, logger)
+}
+
+// gitOpsSyncHandler syncs immediately. The changes are applied by the strategy unless apply=1 is set.
+func gitOpsSyncHandler(w http.ResponseWriter, r *http.Request) {
+	if gitOpsCtrl == nil {
+		handleError(w, errors.New("gitops is not enabled"), "", logger)
+		return
+	}
+	apply := gitOpsCtrl.c.Strategy == conf.GitOpsStrategyApply || r.URL.Query().Get("apply") == "1"
+	if err := gitOpsCtrl.sync(r.Context(), apply); err != nil {
+		handleError(w, err, "gitops sync error", logger)
+		return
+	}
+	gitOpsStatusHandler(w, r)
+}
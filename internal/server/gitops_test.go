@@ -0,0 +1,126 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/gitops"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func (suite *RestTestSuite) TestGitOpsSync() {
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/gitops/status", http.NoBody)
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	repo := suite.T().TempDir()
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(repo, "streams.yaml"), []byte(`
+streams:
+  gitopsDemo:
+    sql: CREATE STREAM gitopsDemo () WITH (DATASOURCE="gitopsDemo", TYPE="memory", FORMAT="json")
+`), 0o644))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(repo, "rules.yaml"), []byte(`
+rules:
+  gitopsRule:
+    triggered: false
+    sql: SELECT * FROM gitopsDemo
+    actions:
+      - nop: {}
+`), 0o644))
+	ctrl, err := newGitOpsController(conf.GitOpsConf{Repository: repo, Strategy: conf.GitOpsStrategyReport, Prune: true})
+	require.NoError(suite.T(), err)
+	gitOpsCtrl = ctrl
+	defer func() {
+		gitOpsCtrl = nil
+		_ = registry.DeleteRule("gitopsRule")
+		_, _ = streamProcessor.DropStream("gitopsDemo", ast.TypeStream)
+		_ = ctrl.db.Delete(gitOpsManagedKey)
+	}()
+
+	sync := func(query string) *gitOpsStatus {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/gitops/sync"+query, http.NoBody)
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+		s := &gitOpsStatus{}
+		require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), s))
+		return s
+	}
+	// Only report the drift
+	s := sync("")
+	assert.Equal(suite.T(), conf.GitOpsStrategyReport, s.Strategy)
+	assert.Empty(suite.T(), s.LastError)
+	assert.Equal(suite.T(), []gitOpsDrift{
+		{Change: gitops.Change{Resource: gitops.Resource{Kind: gitops.KindStream, Name: "gitopsDemo"}, Action: gitops.ActionCreate}},
+		{Change: gitops.Change{Resource: gitops.Resource{Kind: gitops.KindRule, Name: "gitopsRule"}, Action: gitops.ActionCreate}},
+	}, s.Drift)
+	_, err = ruleProcessor.GetRuleById("gitopsRule")
+	assert.Error(suite.T(), err)
+
+	s = sync("?apply=1")
+	assert.Empty(suite.T(), s.Drift)
+	r, err := ruleProcessor.GetRuleById("gitopsRule")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "SELECT * FROM gitopsDemo", r.Sql)
+	s = sync("")
+	assert.Empty(suite.T(), s.Drift)
+
+	// Update the rule and remove the stream, the stream is pruned
+	require.NoError(suite.T(), os.Remove(filepath.Join(repo, "streams.yaml")))
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(repo, "rules.yaml"), []byte(`
+rules:
+  gitopsRule:
+    triggered: false
+    sql: SELECT a FROM gitopsDemo
+    actions:
+      - nop: {}
+`), 0o644))
+	s = sync("")
+	assert.Equal(suite.T(), []gitOpsDrift{
+		{Change: gitops.Change{Resource: gitops.Resource{Kind: gitops.KindRule, Name: "gitopsRule"}, Action: gitops.ActionUpdate}},
+		{Change: gitops.Change{Resource: gitops.Resource{Kind: gitops.KindStream, Name: "gitopsDemo"}, Action: gitops.ActionDelete}},
+	}, s.Drift)
+	s = sync("?apply=1")
+	assert.Empty(suite.T(), s.Drift)
+	r, err = ruleProcessor.GetRuleById("gitopsRule")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "SELECT a FROM gitopsDemo", r.Sql)
+	_, err = streamProcessor.DescStream("gitopsDemo", ast.TypeStream)
+	assert.Error(suite.T(), err)
+
+	// Invalid definition
+	require.NoError(suite.T(), os.WriteFile(filepath.Join(repo, "invalid.yaml"), []byte("rules: ["), 0o644))
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/gitops/sync", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/gitops/status", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	s = &gitOpsStatus{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), s))
+	assert.Contains(suite.T(), s.LastError, "load invalid.yaml error")
+}
@@ -149,6 +149,8 @@ func configurationReset() {
 type ImportConfigurationStatus struct {
 	ErrorMsg       string
 	ConfigResponse Configuration
+	// Conflicts are the existing resources which are skipped or renamed in partial import
+	Conflicts map[string]string `json:"Conflicts,omitempty"`
 }
 
 func configurationImport(ctx context.Context, data []byte, reboot bool) ImportConfigurationStatus {
@@ -281,7 +283,9 @@ func configurationImport(ctx context.Context, data []byte, reboot bool) ImportCo
 	return importStatus
 }
 
-func configurationPartialImport(ctx context.Context, data []byte) ImportConfigurationStatus {
+// configurationPartialImport imports the configuration without reset. The existing resources are handled by the
+// conflict policy and the dependencies of the imported rules and streams must be satisfied.
+func configurationPartialImport(ctx context.Context, data []byte, conflict string) ImportConfigurationStatus {
 	conf := &Configuration{
 		Streams:          make(map[string]string),
 		Tables:           make(map[string]string),
@@ -334,6 +338,15 @@ func configurationPartialImport(ctx context.Context, data []byte) ImportConfigur
 		importStatus.ErrorMsg = fmt.Errorf("configuration unmarshal with error %v", err).Error()
 		return importStatus
 	}
+	if err := validateConflictPolicy(conflict); err != nil {
+		importStatus.ErrorMsg = err.Error()
+		return importStatus
+	}
+	importStatus.Conflicts = resolveConflicts(conf, conflict)
+	if err := validateDependencies(conf); err != nil {
+		importStatus.ErrorMsg = err.Error()
+		return importStatus
+	}
 
 	yamlCfgSet := meta.YamlConfigurationSet{
 		Sources:     conf.SourceConfig,
@@ -389,6 +402,8 @@ func configurationPartialImport(ctx context.Context, data []byte) ImportConfigur
 type configurationInfo struct {
 	Content  string `json:"content" yaml:"content"`
 	FilePath string `json:"file" yaml:"filePath"`
+	// Conflict is the policy to handle the existing resources in partial import: overwrite(default), skip or rename
+	Conflict string `json:"conflict,omitempty" yaml:"conflict"`
 }
 
 func configurationImportHandler(w http.ResponseWriter, r *http.Request) {
@@ -406,6 +421,9 @@ func configurationImportHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err, "", logger)
 		return
 	}
+	if c := r.URL.Query().Get("conflict"); c != "" {
+		rsi.Conflict = c
+	}
 
 	result, err := handleConfigurationImport(context.Background(), rsi, partial, stop)
 	if err != nil {
@@ -455,7 +473,7 @@ func handleConfigurationImport(ctx context.Context, rsi *configurationInfo, part
 			return &result, nil
 		}
 	} else {
-		result := configurationPartialImport(ctx, content)
+		result := configurationPartialImport(ctx, content, rsi.Conflict)
 		if result.ErrorMsg != "" {
 			return &result, errors.New(result.ErrorMsg)
 		} else {
@@ -579,7 +597,7 @@ func importRuleSetPartial(all processor.Ruleset) processor.Ruleset {
 	}
 	// replace streams
 	for k, v := range all.Streams {
-		_, e := streamProcessor.ExecReplaceStream(k, v, ast.TypeStream)
+		_, e := streamProcessor.ExecReplaceStream(k, v, nil, ast.TypeStream)
 		if e != nil {
 			ruleSetRsp.Streams[k] = e.Error()
 			continue
@@ -587,7 +605,7 @@ func importRuleSetPartial(all processor.Ruleset) processor.Ruleset {
 	}
 	// replace tables
 	for k, v := range all.Tables {
-		_, e := streamProcessor.ExecReplaceStream(k, v, ast.TypeTable)
+		_, e := streamProcessor.ExecReplaceStream(k, v, nil, ast.TypeTable)
 		if e != nil {
 			ruleSetRsp.Tables[k] = e.Error()
 			continue
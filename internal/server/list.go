@@ -0,0 +1,182 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+)
+
+// TotalCountHeader is the response header of the list APIs to tell the total count of the items before paginating
+const TotalCountHeader = "X-Total-Count"
+
+// listOptions are the common query parameters of the list APIs to paginate, sort and select the fields
+type listOptions struct {
+	// limit is the max count of the returned items, 0 means no limit
+	limit  int
+	offset int
+	// sortBy is the field to sort by, the items are sorted in descending order if desc is true
+	sortBy string
+	desc   bool
+	fields []string
+}
+
+func parseListOptions(r *http.Request) (*listOptions, error) {
+	q := r.URL.Query()
+	o := &listOptions{}
+	if v := q.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 0 {
+			return nil, fmt.Errorf("invalid limit %s, it must be a non-negative integer", v)
+		}
+		o.limit = l
+	}
+	if v := q.Get("offset"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 0 {
+			return nil, fmt.Errorf("invalid offset %s, it must be a non-negative integer", v)
+		}
+		o.offset = l
+	}
+	if v := q.Get("sort"); v != "" {
+		if strings.HasPrefix(v, "-") {
+			o.desc = true
+			v = v[1:]
+		}
+		o.sortBy = v
+	}
+	if v := q.Get("fields"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				o.fields = append(o.fields, f)
+			}
+		}
+	}
+	return o, nil
+}
+
+func (o *listOptions) isSet() bool {
+	return o.limit > 0 || o.offset > 0 || o.sortBy != "" || len(o.fields) > 0
+}
+
+// apply sorts, paginates and selects the fields of the items. The items are the decoded json array,
+// which are either the objects or the names.
+func (o *listOptions) apply(items []any) []any {
+	if o.sortBy != "" {
+		sort.SliceStable(items, func(i, j int) bool {
+			c := compareListValue(o.sortValue(items[i]), o.sortValue(items[j]))
+			if o.desc {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+	if o.offset >= len(items) {
+		items = items[:0]
+	} else {
+		items = items[o.offset:]
+	}
+	if o.limit > 0 && o.limit < len(items) {
+		items = items[:o.limit]
+	}
+	if len(o.fields) > 0 {
+		for i, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				selected := make(map[string]any, len(o.fields))
+				for _, f := range o.fields {
+					if v, ok := m[f]; ok {
+						selected[f] = v
+					}
+				}
+				items[i] = selected
+			}
+		}
+	}
+	return items
+}
+
+// sortValue returns the field value of an object or the item itself for the list of names
+func (o *listOptions) sortValue(item any) any {
+	if m, ok := item.(map[string]any); ok {
+		return m[o.sortBy]
+	}
+	return item
+}
+
+// compareListValue compares the json values. The nil is the smallest and the numbers are compared by value.
+func compareListValue(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	if fa, ok := a.(float64); ok {
+		if fb, ok := b.(float64); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// listResponse writes the list with the pagination, sorting and field selection by the query parameters.
+// The total count before paginating is set to the header. The list is returned as is if no option is set.
+func listResponse(i any, w http.ResponseWriter, r *http.Request, logger api.Logger) {
+	o, err := parseListOptions(r)
+	if err != nil {
+		handleError(w, err, "Invalid list parameters", logger)
+		return
+	}
+	if !o.isSet() {
+		if v := reflect.ValueOf(i); v.Kind() == reflect.Slice {
+			w.Header().Set(TotalCountHeader, strconv.Itoa(v.Len()))
+		}
+		jsonResponse(i, w, logger)
+		return
+	}
+	bs, err := json.Marshal(i)
+	if err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	var items []any
+	if err := json.Unmarshal(bs, &items); err != nil {
+		handleError(w, err, "", logger)
+		return
+	}
+	if items == nil {
+		items = []any{}
+	}
+	w.Header().Set(TotalCountHeader, strconv.Itoa(len(items)))
+	jsonResponse(o.apply(items), w, logger)
+}
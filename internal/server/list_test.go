@@ -0,0 +1,67 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOptionsApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		items []any
+		exp   []any
+	}{
+		{
+			name:  "sort names",
+			query: "sort=-name",
+			items: []any{"b", "a", "c"},
+			exp:   []any{"c", "b", "a"},
+		},
+		{
+			name:  "sort numbers",
+			query: "sort=n",
+			items: []any{map[string]any{"n": 10.0}, map[string]any{"n": 9.0}, map[string]any{}},
+			exp:   []any{map[string]any{}, map[string]any{"n": 9.0}, map[string]any{"n": 10.0}},
+		},
+		{
+			name:  "paginate",
+			query: "offset=1&limit=1",
+			items: []any{"a", "b", "c"},
+			exp:   []any{"b"},
+		},
+		{
+			name:  "fields",
+			query: "fields=id,status",
+			items: []any{map[string]any{"id": "r1", "name": "n1", "status": "running"}},
+			exp:   []any{map[string]any{"id": "r1", "status": "running"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "http://localhost/?"+tt.query, http.NoBody)
+			o, err := parseListOptions(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.exp, o.apply(tt.items))
+		})
+	}
+	r, _ := http.NewRequest(http.MethodGet, "http://localhost/?offset=a", http.NoBody)
+	_, err := parseListOptions(r)
+	require.EqualError(t, err, "invalid offset a, it must be a non-negative integer")
+}
@@ -186,6 +186,9 @@ func sourceConfHandler(w http.ResponseWriter, r *http.Request) {
 	language := getLanguage(r)
 	configOperatorKey := fmt.Sprintf(meta.SourceCfgOperatorKeyTemplate, pluginName)
 	ret, err := meta.GetYamlConf(configOperatorKey, language)
+	if err == nil {
+		ret, err = maskSecretsJson(r, ret)
+	}
 	if err != nil {
 		handleError(w, err, "", logger)
 		return
@@ -203,6 +206,9 @@ func connectionConfHandler(w http.ResponseWriter, r *http.Request) {
 	language := getLanguage(r)
 	configOperatorKey := fmt.Sprintf(meta.ConnectionCfgOperatorKeyTemplate, pluginName)
 	ret, err := meta.GetYamlConf(configOperatorKey, language)
+	if err == nil {
+		ret, err = maskSecretsJson(r, ret)
+	}
 	if err != nil {
 		handleError(w, err, "", logger)
 		return
@@ -220,6 +226,9 @@ func sinkConfHandler(w http.ResponseWriter, r *http.Request) {
 	language := getLanguage(r)
 	configOperatorKey := fmt.Sprintf(meta.SinkCfgOperatorKeyTemplate, pluginName)
 	ret, err := meta.GetYamlConf(configOperatorKey, language)
+	if err == nil {
+		ret, err = maskSecretsJson(r, ret)
+	}
 	if err != nil {
 		handleError(w, err, "", logger)
 		return
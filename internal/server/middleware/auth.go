@@ -15,47 +15,129 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/jwt"
 )
 
-var notAuth = []string{"/", "/ping"}
+var notAuth = []string{"/", "/ping", "/oidc/config"}
 
-var Auth = func(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestPath := r.URL.Path
-		for _, value := range notAuth {
-			if value == requestPath {
+// IsPublic returns true if the path can be accessed without the token
+func IsPublic(path string) bool {
+	for _, value := range notAuth {
+		if value == path {
+			return true
+		}
+	}
+	return false
+}
+
+type userKey struct{}
+
+// WithUser returns the context to hold the user of the request. The user is filled by the Auth middleware so that the
+// middlewares running before it can read the user after serving the request.
+func WithUser(ctx context.Context) context.Context {
+	return context.WithValue(ctx, userKey{}, new(string))
+}
+
+// UserFromContext returns the authenticated user of the request
+func UserFromContext(ctx context.Context) string {
+	if u, ok := ctx.Value(userKey{}).(*string); ok {
+		return *u
+	}
+	return ""
+}
+
+// SetUser sets the authenticated user of the request into the holder context if any
+func SetUser(r *http.Request, user string) *http.Request {
+	if u, ok := r.Context().Value(userKey{}).(*string); ok {
+		*u = user
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, &user))
+}
+
+type namespaceKey struct{}
+
+// WithNamespace returns the context of the request authenticated by the namespace token
+func WithNamespace(ctx context.Context, ns string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, ns)
+}
+
+// NamespaceFromContext returns the namespace of the request. It is empty if the request is not in a namespace.
+func NamespaceFromContext(ctx context.Context) string {
+	ns, _ := ctx.Value(namespaceKey{}).(string)
+	return ns
+}
+
+// Verifier verifies the tokens issued by an external identity provider
+type Verifier interface {
+	// Issuer returns the issuer of the tokens verified by the verifier
+	Issuer() string
+	// Verify validates the token and returns the user and the roles
+	Verify(token string) (string, []string, error)
+}
+
+// Auth authenticates the requests by the tokens signed by the keys in the etc/mgmt folder
+var Auth = NewAuth(nil)
+
+// NewAuth authenticates the requests by the tokens. The tokens of the issuer of the verifier are validated by the
+// verifier and the others are validated by the keys in the etc/mgmt folder.
+func NewAuth(v Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The request is authenticated by the namespace token
+			if IsPublic(r.URL.Path) || NamespaceFromContext(r.Context()) != "" {
 				next.ServeHTTP(w, r)
 				return
 			}
-		}
 
-		tokenHeader := r.Header.Get("Authorization")
+			tokenHeader := r.Header.Get("Authorization")
+			if len(tokenHeader) > 7 && strings.EqualFold(tokenHeader[:7], "Bearer ") {
+				tokenHeader = tokenHeader[7:]
+			}
 
-		if tokenHeader == "" {
-			http.Error(w, "missing_token", http.StatusUnauthorized)
-			return
-		}
-		tk, err := jwt.ParseToken(tokenHeader)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-			return
-		}
-		hit := false
-		for _, value := range tk.RegisteredClaims.Audience {
-			if value == "eKuiper" {
-				hit = true
-				break
+			if tokenHeader == "" {
+				http.Error(w, "missing_token", http.StatusUnauthorized)
+				return
 			}
-		}
-		if !hit {
-			http.Error(w, fmt.Sprintf("audience field should contain eKuiper, but got %s", tk.RegisteredClaims.Audience), http.StatusUnauthorized)
-			return
-		}
+			if v != nil && jwt.IssuerOf(tokenHeader) == v.Issuer() {
+				user, roles, err := v.Verify(tokenHeader)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				r = SetUser(r, user)
+				r = r.WithContext(WithRoles(r.Context(), roles))
+				next.ServeHTTP(w, r)
+				return
+			}
+			tk, err := jwt.ParseToken(tokenHeader)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			hit := false
+			for _, value := range tk.RegisteredClaims.Audience {
+				if value == "eKuiper" {
+					hit = true
+					break
+				}
+			}
+			if !hit {
+				http.Error(w, fmt.Sprintf("audience field should contain eKuiper, but got %s", tk.RegisteredClaims.Audience), http.StatusUnauthorized)
+				return
+			}
+			user := tk.RegisteredClaims.Subject
+			if user == "" {
+				user = tk.RegisteredClaims.Issuer
+			}
+			r = SetUser(r, user)
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -15,12 +15,15 @@
 package middleware
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
 
+	jwtv5 "github.com/golang-jwt/jwt/v5"
+
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/jwt"
 )
 
@@ -97,3 +100,73 @@ func Test_AUTH(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthUser(t *testing.T) {
+	var user string
+	handler := Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = UserFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:9081/streams", nil)
+	req.Header.Set("Authorization", genToken("sample_key", "sample_key.pub", []string{"eKuiper"}))
+	// The user is visible to the outer handler by the holder context
+	req = req.WithContext(WithUser(req.Context()))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if user != "sample_key.pub" {
+		t.Errorf("expect user sample_key.pub, actual %s", user)
+	}
+	if u := UserFromContext(req.Context()); u != "sample_key.pub" {
+		t.Errorf("expect user sample_key.pub in the holder, actual %s", u)
+	}
+}
+
+type mockVerifier struct{}
+
+func (m *mockVerifier) Issuer() string {
+	return "https://idp.example.com"
+}
+
+func (m *mockVerifier) Verify(token string) (string, []string, error) {
+	if token != genIssuerToken("https://idp.example.com") {
+		return "", nil, errors.New("invalid token")
+	}
+	return "alice", []string{RoleOperator}, nil
+}
+
+func genIssuerToken(iss string) string {
+	// The signature is checked by the verifier
+	s, _ := jwtv5.NewWithClaims(jwtv5.SigningMethodHS256, jwtv5.MapClaims{"iss": iss}).SignedString([]byte("secret"))
+	return s
+}
+
+func TestAuthVerifier(t *testing.T) {
+	var (
+		user  string
+		roles []string
+	)
+	handler := NewAuth(&mockVerifier{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = UserFromContext(r.Context())
+		roles, _ = RolesFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:9081/rules", nil)
+	req.Header.Set("Authorization", "Bearer "+genIssuerToken("https://idp.example.com"))
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Code != http.StatusOK || user != "alice" || !reflect.DeepEqual(roles, []string{RoleOperator}) {
+		t.Errorf("expect alice with operator role, actual %d %s %v", res.Code, user, roles)
+	}
+	// The tokens of other issuers are validated by the keys
+	req = httptest.NewRequest(http.MethodGet, "http://127.0.0.1:9081/rules", nil)
+	req.Header.Set("Authorization", "Bearer "+genToken("sample_key", "sample_key.pub", []string{"eKuiper"}))
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Code != http.StatusOK || user != "sample_key.pub" || roles != nil {
+		t.Errorf("expect sample_key.pub without roles, actual %d %s %v", res.Code, user, roles)
+	}
+	req = httptest.NewRequest(http.MethodGet, "http://127.0.0.1:9081/rules", nil)
+	req.Header.Set("Authorization", genIssuerToken("sample_key.pub"))
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("expect 401, actual %d", res.Code)
+	}
+}
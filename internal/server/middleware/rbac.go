@@ -0,0 +1,218 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+const (
+	ActionRead  = "read"
+	ActionWrite = "write"
+)
+
+// The resources of the api by the first segment of the path. The other paths are the system resource.
+var pathResources = map[string]string{
+	"streams":       "stream",
+	"streamdetails": "stream",
+	"tables":        "table",
+	"tabledetails":  "table",
+	"rules":         "rule",
+	"ruletemplates": "rule",
+	"ruletest":      "rule",
+	"dependencies":  "rule",
+	"trace":         "rule",
+	"tracer":        "rule",
+	"metadata":      "config",
+	"connections":   "config",
+	"config":        "config",
+	"plugins":       "plugin",
+	"udf":           "plugin",
+	"services":      "plugin",
+	"schemas":       "plugin",
+	"models":        "plugin",
+	"data":          "data",
+	"ruleset":       "data",
+	"async":         "data",
+	"node":          "data",
+	"gitops":        "data",
+	// The api document is readable by all the authenticated users
+	"openapi.json": "",
+}
+
+// The data resource is not readable by the viewer because the exported data contains the secrets
+var builtinRoles = map[string][]string{
+	RoleViewer:   {"stream:read", "table:read", "rule:read", "config:read", "plugin:read"},
+	RoleOperator: {"stream:read", "table:read", "rule:read", "config:read", "plugin:read", "rule:write"},
+	RoleAdmin:    {"*"},
+}
+
+type roleKey struct{}
+
+// WithRoles returns the context of the user authenticated by an external identity provider with the roles from the
+// token. The roles may be empty if the token has none.
+func WithRoles(ctx context.Context, roles []string) context.Context {
+	if roles == nil {
+		roles = []string{}
+	}
+	return context.WithValue(ctx, roleKey{}, roles)
+}
+
+// RolesFromContext returns the roles from the token and whether the user is authenticated by an external identity
+// provider
+func RolesFromContext(ctx context.Context) ([]string, bool) {
+	roles, ok := ctx.Value(roleKey{}).([]string)
+	return roles, ok
+}
+
+type permissionKey struct{}
+
+// Allowed returns whether the user of the request has the permission of the action on the resource. It is always true
+// if the request is not authorized by RBAC.
+func Allowed(ctx context.Context, resource, action string) bool {
+	allow, ok := ctx.Value(permissionKey{}).(func(resource, action string) bool)
+	return !ok || allow(resource, action)
+}
+
+// RBAC authorizes the authenticated requests by the role of the user
+type RBAC struct {
+	defaultRole string
+	users       map[string]string
+	roles       map[string][]string
+}
+
+func NewRBAC(c conf.RbacConf) (*RBAC, error) {
+	rb := &RBAC{
+		defaultRole: c.DefaultRole,
+		users:       c.Users,
+		roles:       make(map[string][]string, len(builtinRoles)+len(c.Roles)),
+	}
+	for name, perms := range builtinRoles {
+		rb.roles[name] = perms
+	}
+	for name, perms := range c.Roles {
+		for _, p := range perms {
+			if p != "*" && len(strings.Split(p, ":")) != 2 {
+				return nil, fmt.Errorf("invalid permission %s of role %s, should be in the format of resource:action", p, name)
+			}
+		}
+		rb.roles[name] = perms
+	}
+	if rb.defaultRole != "" {
+		if _, ok := rb.roles[rb.defaultRole]; !ok {
+			return nil, fmt.Errorf("default role %s is not defined", rb.defaultRole)
+		}
+	}
+	for user, role := range rb.users {
+		if _, ok := rb.roles[role]; !ok {
+			return nil, fmt.Errorf("role %s of user %s is not defined", role, user)
+		}
+	}
+	return rb, nil
+}
+
+// Middleware rejects the requests whose user has no permission. It must run after the Auth middleware.
+// The namespace requests are limited by the namespace instead.
+func (rb *RBAC) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsPublic(r.URL.Path) || NamespaceFromContext(r.Context()) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user := UserFromContext(r.Context())
+		roles := rb.rolesOf(r.Context(), user)
+		if len(roles) == 0 {
+			http.Error(w, fmt.Sprintf("user %s has no role", user), http.StatusForbidden)
+			return
+		}
+		allow := func(resource, action string) bool {
+			for _, role := range roles {
+				if rb.Allow(role, resource, action) {
+					return true
+				}
+			}
+			return false
+		}
+		resource, action := requestPermission(r)
+		if allow(resource, action) {
+			// Let the handlers check the other permissions such as masking the secrets for the readers
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), permissionKey{}, allow)))
+			return
+		}
+		http.Error(w, fmt.Sprintf("user %s with role %s has no permission %s:%s", user, strings.Join(roles, ","), resource, action), http.StatusForbidden)
+	})
+}
+
+// rolesOf returns the roles of the user. The users of an external identity provider are not looked up in the users
+// configuration, which is for the local users, so that an external account cannot take a local user's role by name.
+func (rb *RBAC) rolesOf(ctx context.Context, user string) []string {
+	if roles, external := RolesFromContext(ctx); external {
+		if len(roles) > 0 {
+			return roles
+		}
+	} else if role, ok := rb.users[user]; ok {
+		return []string{role}
+	}
+	if rb.defaultRole != "" {
+		return []string{rb.defaultRole}
+	}
+	return nil
+}
+
+// Allow returns true if the role has the permission of the action on the resource
+func (rb *RBAC) Allow(role, resource, action string) bool {
+	if resource == "" {
+		return true
+	}
+	for _, p := range rb.roles[role] {
+		if p == "*" {
+			return true
+		}
+		res, act, _ := strings.Cut(p, ":")
+		if (res == "*" || res == resource) && (act == "*" || act == action) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestPermission returns the resource and the action of the request. The GET and HEAD requests are read and the
+// others are write.
+func requestPermission(r *http.Request) (string, string) {
+	segs := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if segs[0] == "v2" && len(segs) > 1 {
+		segs = segs[1:]
+	}
+	resource, ok := pathResources[segs[0]]
+	if !ok {
+		resource = "system"
+	}
+	action := ActionWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		action = ActionRead
+	}
+	return resource, action
+}
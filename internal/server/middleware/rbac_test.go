@@ -0,0 +1,119 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+)
+
+func TestRBAC(t *testing.T) {
+	rb, err := NewRBAC(conf.RbacConf{
+		Enable: true,
+		Users: map[string]string{
+			"alice": RoleViewer,
+			"bob":   RoleOperator,
+			"carol": RoleAdmin,
+			"dave":  "streamer",
+		},
+		Roles: map[string][]string{
+			"streamer": {"*:read", "stream:write"},
+		},
+	})
+	require.NoError(t, err)
+	handler := rb.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		user  string
+		roles []string
+		// authenticated by the external identity provider
+		external bool
+		method   string
+		path     string
+		code     int
+	}{
+		{user: "alice", method: http.MethodGet, path: "/rules/r1/status", code: http.StatusOK},
+		{user: "alice", method: http.MethodGet, path: "/v2/rules/r1/status", code: http.StatusOK},
+		{user: "alice", method: http.MethodPost, path: "/rules/r1/start", code: http.StatusForbidden},
+		{user: "alice", method: http.MethodGet, path: "/data/export", code: http.StatusForbidden},
+		{user: "alice", method: http.MethodGet, path: "/openapi.json", code: http.StatusOK},
+		{user: "alice", method: http.MethodGet, path: "/ping", code: http.StatusOK},
+		{user: "bob", method: http.MethodPost, path: "/rules/r1/start", code: http.StatusOK},
+		{user: "bob", method: http.MethodDelete, path: "/rules/r1", code: http.StatusOK},
+		{user: "bob", method: http.MethodPost, path: "/streams", code: http.StatusForbidden},
+		{user: "bob", method: http.MethodPost, path: "/stop", code: http.StatusForbidden},
+		{user: "carol", method: http.MethodPost, path: "/stop", code: http.StatusOK},
+		{user: "carol", method: http.MethodPut, path: "/namespaces/ns1", code: http.StatusOK},
+		{user: "dave", method: http.MethodPut, path: "/streams/s1", code: http.StatusOK},
+		{user: "dave", method: http.MethodGet, path: "/audit", code: http.StatusOK},
+		{user: "dave", method: http.MethodPut, path: "/tables/t1", code: http.StatusForbidden},
+		// The roles decided by the authentication take precedence
+		{user: "alice", roles: []string{RoleViewer, RoleAdmin}, method: http.MethodPost, path: "/stop", code: http.StatusOK},
+		{user: "carol", roles: []string{"unknown"}, method: http.MethodGet, path: "/rules", code: http.StatusForbidden},
+		// The external user is not looked up in the local users even with the same name
+		{user: "carol", external: true, method: http.MethodPost, path: "/stop", code: http.StatusForbidden},
+		// No default role
+		{user: "eve", method: http.MethodGet, path: "/rules", code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://127.0.0.1:9081"+tt.path, nil)
+			req = SetUser(req, tt.user)
+			if tt.external || len(tt.roles) > 0 {
+				req = req.WithContext(WithRoles(req.Context(), tt.roles))
+			}
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+			assert.Equal(t, tt.code, res.Code, res.Body.String())
+		})
+	}
+}
+
+func TestRBACDefaultRole(t *testing.T) {
+	rb, err := NewRBAC(conf.RbacConf{Enable: true, DefaultRole: RoleViewer})
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleViewer}, rb.rolesOf(context.Background(), "anyone"))
+	// The external user without role claims gets the default role
+	assert.Equal(t, []string{RoleViewer}, rb.rolesOf(WithRoles(context.Background(), nil), "anyone"))
+	assert.True(t, rb.Allow(RoleViewer, "rule", ActionRead))
+	assert.False(t, rb.Allow(RoleViewer, "rule", ActionWrite))
+
+	// The permissions of the authorized user are available to the handlers
+	var canWrite, canRead bool
+	handler := rb.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		canRead = Allowed(r.Context(), "config", ActionRead)
+		canWrite = Allowed(r.Context(), "config", ActionWrite)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), SetUser(httptest.NewRequest(http.MethodGet, "http://127.0.0.1:9081/connections", nil), "anyone"))
+	assert.True(t, canRead)
+	assert.False(t, canWrite)
+	// Not authorized by RBAC
+	assert.True(t, Allowed(context.Background(), "config", ActionWrite))
+
+	_, err = NewRBAC(conf.RbacConf{DefaultRole: "unknown"})
+	assert.EqualError(t, err, "default role unknown is not defined")
+	_, err = NewRBAC(conf.RbacConf{Users: map[string]string{"alice": "unknown"}})
+	assert.EqualError(t, err, "role unknown of user alice is not defined")
+	_, err = NewRBAC(conf.RbacConf{Roles: map[string][]string{"bad": {"rule"}}})
+	assert.EqualError(t, err, "invalid permission rule of role bad, should be in the format of resource:action")
+}
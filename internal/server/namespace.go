@@ -0,0 +1,622 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/internal/meta"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// NamespaceTokenHeader is the header of the api token of a namespace
+const NamespaceTokenHeader = "X-Namespace-Token"
+
+// namespaceRoutes are the routes accessible with a namespace token and the kind of the resources they operate. The
+// other routes are only for the admin. The plugins run arbitrary code in the process, so they are only for the admin.
+var namespaceRoutes = map[string]string{
+	"/ping":                                   "",
+	"/streams":                                processor.ResourceStream,
+	"/streams/{name}":                         processor.ResourceStream,
+	"/streams/{name}/schema":                  processor.ResourceStream,
+	"/tables":                                 processor.ResourceTable,
+	"/tables/{name}":                          processor.ResourceTable,
+	"/tables/{name}/schema":                   processor.ResourceTable,
+	"/rules":                                  processor.ResourceRule,
+	"/rules/validate":                         processor.ResourceRule,
+	"/rules/{name}":                           processor.ResourceRule,
+	"/rules/{name}/status":                    processor.ResourceRule,
+	"/v2/rules/{name}/status":                 processor.ResourceRule,
+	"/rules/{name}/start":                     processor.ResourceRule,
+	"/rules/{name}/stop":                      processor.ResourceRule,
+	"/rules/{name}/restart":                   processor.ResourceRule,
+	"/rules/{name}/topo":                      processor.ResourceRule,
+	"/rules/{name}/explain":                   processor.ResourceRule,
+	"/rules/{name}/reset_state":               processor.ResourceRule,
+	"/rules/{name}/state":                     processor.ResourceRule,
+	"/rules/{name}/versions":                  processor.ResourceRule,
+	"/rules/{name}/versions/diff":             processor.ResourceRule,
+	"/rules/{name}/versions/{version:[0-9]+}": processor.ResourceRule,
+	"/rules/{name}/versions/{version:[0-9]+}/rollback": processor.ResourceRule,
+	"/rules/{name}/savepoints":                         processor.ResourceRule,
+	"/rules/{name}/savepoints/{id:[0-9]+}":             processor.ResourceRule,
+	"/rules/{name}/savepoints/{id:[0-9]+}/restore":     processor.ResourceRule,
+	"/metadata/sources/{name}/confKeys/{confKey}":      processor.ResourceConfig,
+	"/metadata/sinks/{name}/confKeys/{confKey}":        processor.ResourceConfig,
+	"/metadata/connections/{name}/confKeys/{confKey}":  processor.ResourceConfig,
+}
+
+// nsAccess is the resource operated by the request
+type nsAccess struct {
+	kind string
+	name string
+	// create is true if the request creates the resource which will be owned by the namespace
+	create bool
+	// drop is true if the request deletes the resource
+	drop bool
+}
+
+// namespaceError is the error to reject the namespace request with the status code
+type namespaceError struct {
+	code int
+	msg  string
+}
+
+func (e *namespaceError) Error() string {
+	return e.msg
+}
+
+func forbidden(format string, args ...any) error {
+	return &namespaceError{code: http.StatusForbidden, msg: fmt.Sprintf(format, args...)}
+}
+
+// namespaceMiddleware authenticates the requests with the namespace token and limits them to the resources of the
+// namespace. The resources created by the namespace requests are owned by the namespace. The requests without the
+// namespace token are not limited, but the ownership is released when they delete the resources.
+func namespaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if namespaceProcessor == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var (
+			ns  string
+			err error
+		)
+		if token := r.Header.Get(NamespaceTokenHeader); token != "" {
+			ns, err = namespaceProcessor.Authenticate(token)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			r = r.WithContext(middleware.WithNamespace(r.Context(), ns))
+			r = middleware.SetUser(r, "namespace/"+ns)
+		}
+		access, err := checkNamespaceAccess(r, ns)
+		if err != nil {
+			code := http.StatusBadRequest
+			if e, ok := err.(*namespaceError); ok {
+				code = e.code
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		if access == nil || (!access.create && !access.drop) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sw := &statusResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status >= http.StatusBadRequest {
+			return
+		}
+		if access.create {
+			err = namespaceProcessor.SetOwner(access.kind, access.name, ns)
+		} else {
+			err = namespaceProcessor.RemoveOwner(access.kind, access.name)
+		}
+		if err != nil {
+			logger.Warnf("update the namespace of %s %s error: %v", access.kind, access.name, err)
+		}
+	})
+}
+
+// checkNamespaceAccess finds the resource of the request and checks if the namespace can access it. Without the
+// namespace, only the deletion is tracked.
+func checkNamespaceAccess(r *http.Request, ns string) (*nsAccess, error) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil, nil
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return nil, nil
+	}
+	kind, ok := namespaceRoutes[tpl]
+	if !ok {
+		if ns != "" {
+			return nil, forbidden("%s is not accessible in namespace %s", r.URL.Path, ns)
+		}
+		return nil, nil
+	}
+	if kind == "" {
+		return nil, nil
+	}
+	vars := mux.Vars(r)
+	access := &nsAccess{kind: kind}
+	switch {
+	case vars["confKey"] != "":
+		// The config key is identified by the type like sources/mqtt/confKey
+		access.name = fmt.Sprintf("%s/%s/%s", strings.Split(tpl, "/")[2], vars["name"], vars["confKey"])
+	default:
+		access.name = vars["name"]
+	}
+	if access.name != "" {
+		access.drop = r.Method == http.MethodDelete
+		if ns == "" {
+			return access, nil
+		}
+		owner := namespaceProcessor.GetOwner(kind, access.name)
+		if owner == ns {
+			if r.Method != http.MethodPut {
+				return access, nil
+			}
+			switch {
+			case kind == processor.ResourceRule && !strings.Contains(tpl, "/versions/"):
+				return access, checkRule(r, ns)
+			case (kind == processor.ResourceStream || kind == processor.ResourceTable) && !strings.HasSuffix(tpl, "/schema"):
+				return access, checkStreamBody(r, ns)
+			case kind == processor.ResourceConfig:
+				return access, checkConfKeyBody(r, ns, tpl, vars["name"])
+			}
+			return access, nil
+		}
+		// The config key is upserted, the namespace can create a new one
+		if kind == processor.ResourceConfig && owner == "" && r.Method == http.MethodPut && !confKeyExists(tpl, vars["name"], vars["confKey"]) {
+			access.create = true
+			if err := checkConfKeyBody(r, ns, tpl, vars["name"]); err != nil {
+				return nil, err
+			}
+			return access, namespaceProcessor.CheckQuota(ns, kind)
+		}
+		return nil, &namespaceError{code: http.StatusNotFound, msg: fmt.Sprintf("%s %s is not found in namespace %s", kind, access.name, ns)}
+	}
+	// The collection routes
+	switch r.Method {
+	case http.MethodGet:
+		return nil, nil
+	case http.MethodPost:
+	default:
+		if ns != "" {
+			return nil, forbidden("%s %s is not accessible in namespace %s", r.Method, r.URL.Path, ns)
+		}
+		return nil, nil
+	}
+	return collectionAccess(r, ns, tpl, access)
+}
+
+// collectionAccess finds the resource created by posting to the collection route
+func collectionAccess(r *http.Request, ns, tpl string, access *nsAccess) (*nsAccess, error) {
+	body, err := peekBody(r)
+	if err != nil {
+		return nil, err
+	}
+	switch access.kind {
+	case processor.ResourceStream, processor.ResourceTable:
+		v := &struct {
+			Sql string `json:"sql"`
+		}{}
+		if err := json.Unmarshal(body, v); err != nil {
+			// Let the handler report the invalid body
+			return nil, nil
+		}
+		stmt, err := xsql.Language.Parse(xsql.NewParser(strings.NewReader(v.Sql)))
+		if err != nil {
+			return nil, nil
+		}
+		switch s := stmt.(type) {
+		case *ast.StreamStmt:
+			if s.StreamType == ast.TypeTable {
+				access.kind = processor.ResourceTable
+			} else {
+				access.kind = processor.ResourceStream
+			}
+			access.name, access.create = string(s.Name), true
+		case *ast.DropStreamStatement:
+			access.name, access.drop = s.Name, true
+		case *ast.DropTableStatement:
+			access.name, access.kind, access.drop = s.Name, processor.ResourceTable, true
+		default:
+			if ns != "" {
+				return nil, forbidden("only create statement is allowed in namespace %s", ns)
+			}
+			return nil, nil
+		}
+		if ns == "" {
+			if access.create {
+				return nil, nil
+			}
+			return access, nil
+		}
+		if access.drop {
+			return nil, forbidden("please delete %s %s by its path in namespace %s", access.kind, access.name, ns)
+		}
+		if err := checkStreamSource(ns, stmt.(*ast.StreamStmt)); err != nil {
+			return nil, err
+		}
+	case processor.ResourceRule:
+		if ns == "" {
+			return nil, nil
+		}
+		if err := checkRule(r, ns); err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(tpl, "/validate") {
+			return nil, nil
+		}
+		v := &struct {
+			Id string `json:"id"`
+		}{}
+		if err := json.Unmarshal(body, v); err != nil || v.Id == "" {
+			return nil, nil
+		}
+		access.name, access.create = v.Id, true
+	}
+	return access, namespaceProcessor.CheckQuota(ns, access.kind)
+}
+
+// checkRule checks if the streams and tables used by the rule are in the namespace. The names of the rule statements
+// can be used by the later statements and the rule SQL. The actions can only refer to the resources of the namespace.
+func checkRule(r *http.Request, ns string) error {
+	body, err := peekBody(r)
+	if err != nil {
+		return err
+	}
+	v := &struct {
+		Sql        string                      `json:"sql"`
+		Graph      json.RawMessage             `json:"graph"`
+		Statements []*def.RuleStatement        `json:"statements"`
+		Actions    []map[string]map[string]any `json:"actions"`
+	}{}
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil
+	}
+	for _, action := range v.Actions {
+		for typ, props := range action {
+			if err := checkProps(ns, "sinks", typ, props); err != nil {
+				return err
+			}
+		}
+	}
+	scope := make(map[string]struct{}, len(v.Statements))
+	for _, st := range v.Statements {
+		if st == nil {
+			continue
+		}
+		if err := checkSqlStreams(st.Sql, ns, scope); err != nil {
+			return err
+		}
+		scope[st.Name] = struct{}{}
+	}
+	if v.Sql == "" {
+		if len(v.Graph) > 0 {
+			return forbidden("graph rule is not supported in namespace %s", ns)
+		}
+		return nil
+	}
+	return checkSqlStreams(v.Sql, ns, scope)
+}
+
+// checkSqlStreams checks if the streams and tables used by the SQL are in the namespace or the scope
+func checkSqlStreams(sql, ns string, scope map[string]struct{}) error {
+	stmt, err := xsql.GetStatementFromSql(sql)
+	if err != nil {
+		// Let the handler report the invalid SQL
+		return nil
+	}
+	for _, s := range xsql.GetStreams(stmt) {
+		if _, ok := scope[s]; ok {
+			continue
+		}
+		if namespaceProcessor.GetOwner(processor.ResourceStream, s) != ns && namespaceProcessor.GetOwner(processor.ResourceTable, s) != ns {
+			return fmt.Errorf("stream or table %s is not found in namespace %s", s, ns)
+		}
+	}
+	return nil
+}
+
+// checkStreamBody checks the stream or table statement in the body of the update request
+func checkStreamBody(r *http.Request, ns string) error {
+	body, err := peekBody(r)
+	if err != nil {
+		return err
+	}
+	v := &struct {
+		Sql string `json:"sql"`
+	}{}
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil
+	}
+	stmt, err := xsql.Language.Parse(xsql.NewParser(strings.NewReader(v.Sql)))
+	if err != nil {
+		return nil
+	}
+	if s, ok := stmt.(*ast.StreamStmt); ok {
+		return checkStreamSource(ns, s)
+	}
+	return nil
+}
+
+// checkStreamSource checks if the config key and the data source of the stream are in the namespace
+func checkStreamSource(ns string, s *ast.StreamStmt) error {
+	typ := strings.ToLower(s.Options.TYPE)
+	if typ == "" {
+		typ = "mqtt"
+	}
+	if err := checkConfKey(ns, "sources", typ, s.Options.CONF_KEY); err != nil {
+		return err
+	}
+	return checkDataPath(ns, typ, s.Options.DATASOURCE)
+}
+
+// checkConfKeyBody checks the props in the body of the config key request
+func checkConfKeyBody(r *http.Request, ns, tpl, typ string) error {
+	body, err := peekBody(r)
+	if err != nil {
+		return err
+	}
+	props := make(map[string]any)
+	if err := json.Unmarshal(body, &props); err != nil {
+		return nil
+	}
+	return checkProps(ns, strings.Split(tpl, "/")[2], typ, props)
+}
+
+// checkProps checks if the props of a source, sink or connection only refer to the config keys, connections, memory
+// topics and file paths of the namespace
+func checkProps(ns, category, typ string, props map[string]any) error {
+	typ = strings.ToLower(typ)
+	// The props of the config key are checked when it is saved
+	keyed := false
+	if category == "sinks" {
+		for _, k := range []string{conf.ResourceID, "confKey"} {
+			if key, ok := props[k].(string); ok {
+				if err := checkConfKey(ns, category, typ, key); err != nil {
+					return err
+				}
+				keyed = keyed || (key != "" && key != "default")
+			}
+		}
+	}
+	if sel, ok := props[conf.ConnectionSelector].(string); ok && sel != "" {
+		if err := checkConfKey(ns, "connections", typ, sel); err != nil {
+			return err
+		}
+	}
+	var path string
+	switch typ {
+	case "memory":
+		path, _ = props["topic"].(string)
+	case "file":
+		path, _ = props["path"].(string)
+	}
+	if path == "" && (category != "sinks" || keyed) {
+		return nil
+	}
+	return checkDataPath(ns, typ, path)
+}
+
+// checkConfKey checks if the config key is owned by the namespace. The default config key is shared by all.
+func checkConfKey(ns, category, typ, key string) error {
+	if key == "" || key == "default" {
+		return nil
+	}
+	name := fmt.Sprintf("%s/%s/%s", category, typ, key)
+	if namespaceProcessor.GetOwner(processor.ResourceConfig, name) != ns {
+		return forbidden("config key %s is not found in namespace %s", name, ns)
+	}
+	return nil
+}
+
+// checkDataPath checks if the memory topic or the file path is under the folder of the namespace, which is the
+// namespace name followed by a slash
+func checkDataPath(ns, typ, path string) error {
+	switch typ {
+	case "memory":
+		if !strings.HasPrefix(path, ns+"/") {
+			return forbidden("memory topic %s is not in namespace %s, it must start with %s/", path, ns, ns)
+		}
+	case "file":
+		p := filepath.ToSlash(filepath.Clean(path))
+		if filepath.IsAbs(path) || !strings.HasPrefix(p, ns+"/") {
+			return forbidden("file path %s is not in namespace %s, it must be a relative path under %s/", path, ns, ns)
+		}
+	}
+	return nil
+}
+
+// peekBody reads the request body and restores it for the handler
+func peekBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+func confKeyExists(tpl, name, confKey string) bool {
+	var key string
+	switch strings.Split(tpl, "/")[2] {
+	case "sources":
+		key = fmt.Sprintf(meta.SourceCfgOperatorKeyTemplate, name)
+	case "sinks":
+		key = fmt.Sprintf(meta.SinkCfgOperatorKeyTemplate, name)
+	default:
+		key = fmt.Sprintf(meta.ConnectionCfgOperatorKeyTemplate, name)
+	}
+	ops, ok := meta.GetConfOperator(key)
+	if !ok {
+		return false
+	}
+	for _, k := range ops.GetConfKeys() {
+		if k == confKey {
+			return true
+		}
+	}
+	return false
+}
+
+// filterByNamespace returns the names owned by the namespace of the request. All the names are returned for the
+// requests without namespace.
+func filterByNamespace(r *http.Request, kind string, names []string) []string {
+	ns := middleware.NamespaceFromContext(r.Context())
+	if ns == "" || namespaceProcessor == nil {
+		return names
+	}
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if namespaceProcessor.GetOwner(kind, name) == ns {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// namespaceDetail is the namespace with its usage and tokens
+type namespaceDetail struct {
+	*processor.Namespace
+	Resources map[string][]string         `json:"resources"`
+	Tokens    []*processor.NamespaceToken `json:"tokens"`
+}
+
+// list or create namespaces
+func namespacesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		ns, err := namespaceProcessor.Create(string(body))
+		if err != nil {
+			handleError(w, err, "create namespace error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, "Namespace %s was created successfully.", ns.Name)
+	case http.MethodGet:
+		content, err := namespaceProcessor.List()
+		if err != nil {
+			handleError(w, err, "list namespaces error", logger)
+			return
+		}
+		listResponse(content, w, r, logger)
+	}
+}
+
+// describe, update or delete a namespace
+func namespaceHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodGet:
+		ns, err := namespaceProcessor.Get(name)
+		if err != nil {
+			handleError(w, err, "describe namespace error", logger)
+			return
+		}
+		d := &namespaceDetail{Namespace: ns}
+		if d.Resources, err = namespaceProcessor.Resources(name); err != nil {
+			handleError(w, err, "describe namespace error", logger)
+			return
+		}
+		if d.Tokens, err = namespaceProcessor.ListTokens(name); err != nil {
+			handleError(w, err, "describe namespace error", logger)
+			return
+		}
+		jsonResponse(d, w, logger)
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		if _, err := namespaceProcessor.Update(name, string(body)); err != nil {
+			handleError(w, err, "update namespace error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(w, "Namespace %s was updated successfully.", name)
+	case http.MethodDelete:
+		if err := namespaceProcessor.Delete(name); err != nil {
+			handleError(w, err, "delete namespace error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(w, "Namespace %s was deleted.", name)
+	}
+}
+
+// list or create the tokens of a namespace
+func namespaceTokensHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := namespaceProcessor.ListTokens(name)
+		if err != nil {
+			handleError(w, err, "list namespace tokens error", logger)
+			return
+		}
+		listResponse(tokens, w, r, logger)
+	case http.MethodPost:
+		token, t, err := namespaceProcessor.CreateToken(name)
+		if err != nil {
+			handleError(w, err, "create namespace token error", logger)
+			return
+		}
+		// The token is only shown once
+		w.Header().Set(ContentType, ContentTypeJSON)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": t.Id, "token": token})
+	}
+}
+
+// revoke a token of a namespace
+func namespaceTokenHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := namespaceProcessor.DeleteToken(vars["name"], vars["id"]); err != nil {
+		handleError(w, err, "delete namespace token error", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "Token %s of namespace %s was deleted.", vars["id"], vars["name"])
+}
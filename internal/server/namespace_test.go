@@ -0,0 +1,168 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/meta"
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func (suite *RestTestSuite) TestNamespace() {
+	for _, r := range []string{"nsRule1", "nsRule2"} {
+		_ = registry.DeleteRule(r)
+		_ = namespaceProcessor.RemoveOwner(processor.ResourceRule, r)
+	}
+	for _, s := range []string{"nsDemo", "adminDemo"} {
+		_, _ = streamProcessor.DropStream(s, ast.TypeStream)
+		_ = namespaceProcessor.RemoveOwner(processor.ResourceStream, s)
+	}
+	_ = namespaceProcessor.RemoveOwner(processor.ResourceConfig, "sinks/memory/nsKey")
+	_ = namespaceProcessor.Delete("team1")
+	if meta.ConfigManager == nil {
+		meta.InitYamlConfigManager()
+	}
+	suite.r.Use(namespaceMiddleware)
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		var req *http.Request
+		if body == "" {
+			req, _ = http.NewRequest(method, "http://localhost:8080"+path, http.NoBody)
+		} else {
+			req, _ = http.NewRequest(method, "http://localhost:8080"+path, bytes.NewBufferString(body))
+		}
+		if token != "" {
+			req.Header.Set(NamespaceTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/namespaces", `{"name":"team1","quota":{"rules":1}}`, "")
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	w = do(http.MethodPost, "/namespaces/team1/tokens", "", "")
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	tk := map[string]string{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &tk))
+	token := tk["token"]
+	w = do(http.MethodPost, "/streams", `{"sql":"CREATE stream adminDemo() WITH (DATASOURCE=\"admin\", TYPE=\"memory\", FORMAT=\"json\")"}`, "")
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	// Streams are isolated
+	w = do(http.MethodPost, "/streams", `{"sql":"CREATE stream nsDemo() WITH (DATASOURCE=\"team1/ns\", TYPE=\"memory\", FORMAT=\"json\")"}`, token)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	w = do(http.MethodGet, "/streams", "", token)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.JSONEq(suite.T(), `["nsDemo"]`, w.Body.String())
+	w = do(http.MethodGet, "/streams/nsDemo", "", token)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodGet, "/streams/adminDemo", "", token)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code, w.Body.String())
+	w = do(http.MethodPost, "/streams", `{"sql":"DROP STREAM adminDemo"}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+
+	// Streams can only use the config keys, memory topics and file paths of the namespace
+	w = do(http.MethodPost, "/streams", `{"sql":"CREATE stream nsDemo2() WITH (DATASOURCE=\"admin\", TYPE=\"memory\", FORMAT=\"json\")"}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), "memory topic admin is not in namespace team1")
+	w = do(http.MethodPost, "/streams", `{"sql":"CREATE stream nsDemo2() WITH (DATASOURCE=\"team1/../etc/passwd\", TYPE=\"file\", FORMAT=\"json\")"}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	w = do(http.MethodPost, "/streams", `{"sql":"CREATE stream nsDemo2() WITH (DATASOURCE=\"topic\", CONF_KEY=\"adminKey\", FORMAT=\"json\")"}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), "config key sources/mqtt/adminKey is not found in namespace team1")
+	w = do(http.MethodPut, "/streams/nsDemo", `{"sql":"CREATE stream nsDemo() WITH (DATASOURCE=\"admin\", TYPE=\"memory\", FORMAT=\"json\")"}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	w = do(http.MethodPut, "/metadata/sinks/memory/confKeys/nsKey", `{"topic":"admin"}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	w = do(http.MethodPut, "/metadata/sinks/memory/confKeys/nsKey", `{"topic":"team1/out","connectionSelector":"adminConn"}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), "config key connections/memory/adminConn is not found in namespace team1")
+	w = do(http.MethodPut, "/metadata/sinks/memory/confKeys/nsKey", `{"topic":"team1/out"}`, token)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// Rule actions can only use the config keys, connections and memory topics of the namespace
+	w = do(http.MethodPost, "/rules/validate", `{"id":"nsRule1","sql":"select * from nsDemo","actions":[{"memory":{"resourceId":"nsKey"}}]}`, token)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodPost, "/rules", `{"id":"nsRule1","sql":"select * from nsDemo","actions":[{"memory":{"resourceId":"adminKey","topic":"team1/out"}}]}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), "config key sinks/memory/adminKey is not found in namespace team1")
+	w = do(http.MethodPost, "/rules", `{"id":"nsRule1","sql":"select * from nsDemo","actions":[{"memory":{"topic":"admin"}}]}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	w = do(http.MethodPost, "/rules", `{"id":"nsRule1","sql":"select * from nsDemo","actions":[{"mqtt":{"server":"tcp://127.0.0.1:1883","topic":"out","connectionSelector":"adminConn"}}]}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+
+	// Rules can only use the streams in the namespace and are limited by the quota
+	w = do(http.MethodPost, "/rules", `{"id":"nsRule1","triggered":false,"sql":"select * from adminDemo","actions":[{"log":{}}]}`, token)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), "stream or table adminDemo is not found in namespace team1")
+	// The statements are checked too, and their names can be used by the later statements and the rule SQL
+	w = do(http.MethodPost, "/rules", `{"id":"nsRule1","triggered":false,"statements":[{"name":"s1","sql":"select * from adminDemo"}],"sql":"select * from s1","actions":[{"log":{}}]}`, token)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), "stream or table adminDemo is not found in namespace team1")
+	w = do(http.MethodPost, "/rules/validate", `{"id":"nsRule1","statements":[{"name":"s1","sql":"select * from nsDemo"},{"name":"s2","sql":"select * from s1"}],"sql":"select * from s2","actions":[{"log":{}}]}`, token)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodPost, "/rules", `{"id":"nsRule1","triggered":false,"sql":"select * from nsDemo","actions":[{"log":{}}]}`, token)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	w = do(http.MethodPost, "/rules", `{"id":"nsRule2","triggered":false,"sql":"select * from nsDemo","actions":[{"log":{}}]}`, token)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), "the quota of rule in namespace team1 is exceeded, the limit is 1")
+	w = do(http.MethodGet, "/rules", "", token)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	var rules []map[string]any
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &rules))
+	require.Len(suite.T(), rules, 1)
+	assert.Equal(suite.T(), "nsRule1", rules[0]["id"])
+
+	// Admin apis are not accessible
+	w = do(http.MethodGet, "/namespaces", "", token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	// Plugins run code in the process, they are only for the admin
+	suite.r.HandleFunc("/plugins/portables", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodPost)
+	w = do(http.MethodPost, "/plugins/portables", `{"name":"nsPlugin","file":"file:///tmp/plugin.zip"}`, token)
+	require.Equal(suite.T(), http.StatusForbidden, w.Code, w.Body.String())
+	w = do(http.MethodGet, "/streams", "", "invalid")
+	require.Equal(suite.T(), http.StatusUnauthorized, w.Code, w.Body.String())
+
+	w = do(http.MethodGet, "/namespaces/team1", "", "")
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	detail := &namespaceDetail{}
+	require.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), detail))
+	assert.Equal(suite.T(), map[string][]string{"config": {"sinks/memory/nsKey"}, "rule": {"nsRule1"}, "stream": {"nsDemo"}}, detail.Resources)
+	require.Len(suite.T(), detail.Tokens, 1)
+	assert.Equal(suite.T(), tk["id"], detail.Tokens[0].Id)
+	w = do(http.MethodDelete, "/namespaces/team1", "", "")
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodDelete, "/rules/nsRule1", "", token)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodDelete, "/streams/nsDemo", "", token)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodDelete, "/metadata/sinks/memory/confKeys/nsKey", "", token)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodDelete, "/namespaces/team1", "", "")
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodGet, "/streams", "", token)
+	require.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+	w = do(http.MethodDelete, "/streams/adminDemo", "", "")
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+}
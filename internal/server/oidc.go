@@ -0,0 +1,52 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/oidc"
+)
+
+// oidcConfig is the login setting for the UI
+type oidcConfig struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorizationEndpoint"`
+	TokenEndpoint         string   `json:"tokenEndpoint"`
+	EndSessionEndpoint    string   `json:"endSessionEndpoint,omitempty"`
+	ClientId              string   `json:"clientId"`
+	Scopes                []string `json:"scopes"`
+}
+
+// oidcConfigHandler returns the setting for the UI to log in with the provider. It is public before the login.
+func oidcConfigHandler(p *oidc.Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d, err := p.Discovery()
+		if err != nil {
+			handleError(w, err, "", logger)
+			return
+		}
+		c := conf.Config.Basic.Oidc
+		jsonResponse(&oidcConfig{
+			Issuer:                d.Issuer,
+			AuthorizationEndpoint: d.AuthorizationEndpoint,
+			TokenEndpoint:         d.TokenEndpoint,
+			EndSessionEndpoint:    d.EndSessionEndpoint,
+			ClientId:              c.ClientId,
+			Scopes:                c.Scopes,
+		}, w, logger)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build onnx
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/extensions/impl/onnx"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/v2/pkg/validate"
+)
+
+func init() {
+	components["onnx"] = onnxComp{}
+}
+
+type onnxComp struct{}
+
+func (o onnxComp) register() {
+	// do nothing
+}
+
+func (o onnxComp) rest(r *mux.Router) {
+	r.HandleFunc("/models/onnx", onnxModelsHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/models/onnx/{name}", onnxModelHandler).Methods(http.MethodGet, http.MethodDelete)
+}
+
+type onnxModelRequest struct {
+	Name string `json:"name"`
+	// The url of the model file, supports http(s) and file scheme
+	File string `json:"file"`
+}
+
+func onnxModelsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodGet:
+		models, err := onnx.ListModels()
+		if err != nil {
+			handleError(w, err, "onnx models list command error", logger)
+			return
+		}
+		jsonResponse(models, w, logger)
+	case http.MethodPost:
+		var name string
+		if r.Header.Get("Content-Type") == "application/json" {
+			req := &onnxModelRequest{}
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				handleError(w, err, "Invalid body: Error decoding the onnx model json", logger)
+				return
+			}
+			if req.Name == "" || req.File == "" {
+				handleError(w, fmt.Errorf("name and file are required"), "Invalid body", logger)
+				return
+			}
+			if err := validate.ValidatePath(req.File); err != nil {
+				handleError(w, err, "", logger)
+				return
+			}
+			rc, err := httpx.ReadFile(req.File)
+			if err != nil {
+				handleError(w, err, "Read onnx model file error", logger)
+				return
+			}
+			defer rc.Close()
+			name = req.Name
+			if err := onnx.SaveModel(name, rc); err != nil {
+				handleError(w, err, "onnx model upload command error", logger)
+				return
+			}
+		} else {
+			// Maximum upload of 1 GB files
+			if err := r.ParseMultipartForm(1024 << 20); err != nil {
+				handleError(w, err, "Error parse the multi part form", logger)
+				return
+			}
+			file, handler, err := r.FormFile("uploadFile")
+			if err != nil {
+				handleError(w, err, "Error Retrieving the File", logger)
+				return
+			}
+			defer file.Close()
+			name = r.FormValue("name")
+			if name == "" {
+				name = strings.TrimSuffix(filepath.Base(handler.Filename), ".onnx")
+			}
+			if err := onnx.SaveModel(name, file); err != nil {
+				handleError(w, err, "onnx model upload command error", logger)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "onnx model %s is uploaded", name)
+	}
+}
+
+func onnxModelHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodGet:
+		m, err := onnx.GetModel(name)
+		if err != nil {
+			handleError(w, err, fmt.Sprintf("describe onnx model %s error", name), logger)
+			return
+		}
+		jsonResponse(m, w, logger)
+	case http.MethodDelete:
+		if err := onnx.DeleteModel(name); err != nil {
+			handleError(w, err, fmt.Sprintf("delete onnx model %s error", name), logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "onnx model %s is deleted", name)
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
+)
+
+// The OpenAPI 3 document of the REST API. It is generated by walking the registered routes so that
+// it is always consistent with the handlers, including the routes of the optional components.
+
+type openAPIDoc struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components *openAPIComponents                      `json:"components,omitempty"`
+	Security   []map[string][]string                   `json:"security,omitempty"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	OperationId string                      `json:"operationId"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	// Security overrides the global security, it is empty for the public operations
+	Security *[]map[string][]string `json:"security,omitempty"`
+	// Handler is the name of the handler function which serves the operation
+	Handler string `json:"x-handler,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   map[string]any `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string `json:"description"`
+}
+
+type openAPIComponents struct {
+	SecuritySchemes map[string]any `json:"securitySchemes,omitempty"`
+}
+
+// generateOpenAPI generates the document from the routes of the router. The bearer auth is declared if needToken is true.
+func generateOpenAPI(r *mux.Router, needToken bool) (*openAPIDoc, error) {
+	doc := &openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   "eKuiper REST API",
+			Version: version,
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+	}
+	if needToken {
+		doc.Components = &openAPIComponents{
+			SecuritySchemes: map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		}
+		doc.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+	err := r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			// the route without path such as the middleware
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		path, params := parsePathTemplate(tpl)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = map[string]*openAPIOperation{}
+			doc.Paths[path] = item
+		}
+		for _, m := range methods {
+			op := &openAPIOperation{
+				OperationId: operationId(m, path),
+				Parameters:  params,
+				Responses: map[string]*openAPIResponse{
+					"2XX": {Description: "Successful operation"},
+					"400": {Description: "Invalid request"},
+				},
+				Handler: handlerName(route.GetHandler()),
+			}
+			if tag := pathTag(path); tag != "" {
+				op.Tags = []string{tag}
+			}
+			if len(params) > 0 {
+				op.Responses["404"] = &openAPIResponse{Description: "Resource not found"}
+			}
+			if needToken {
+				if middleware.IsPublic(path) {
+					op.Security = &[]map[string][]string{}
+				} else {
+					op.Responses["401"] = &openAPIResponse{Description: "Unauthorized"}
+				}
+			}
+			item[strings.ToLower(m)] = op
+		}
+		return nil
+	})
+	return doc, err
+}
+
+// parsePathTemplate converts the mux path template like /rules/{name}/versions/{version:[0-9]+} to the OpenAPI path and parameters
+func parsePathTemplate(tpl string) (string, []*openAPIParameter) {
+	var (
+		params []*openAPIParameter
+		b      strings.Builder
+	)
+	for i := 0; i < len(tpl); i++ {
+		if tpl[i] != '{' {
+			b.WriteByte(tpl[i])
+			continue
+		}
+		// find the matched brace as the pattern may contain braces such as {id:[0-9]{3}}
+		level, end := 0, i
+		for ; end < len(tpl); end++ {
+			if tpl[end] == '{' {
+				level++
+			} else if tpl[end] == '}' {
+				level--
+				if level == 0 {
+					break
+				}
+			}
+		}
+		name, pattern, _ := strings.Cut(tpl[i+1:end], ":")
+		schema := map[string]any{"type": "string"}
+		if pattern != "" {
+			schema["pattern"] = "^" + pattern + "$"
+		}
+		params = append(params, &openAPIParameter{Name: name, In: "path", Required: true, Schema: schema})
+		b.WriteString("{" + name + "}")
+		i = end
+	}
+	return b.String(), params
+}
+
+// operationId composes the unique id by the method and the path, such as getRulesNameStatus for GET /rules/{name}/status
+func operationId(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, seg := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '_' || r == '-' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(seg[:1]) + seg[1:])
+	}
+	return b.String()
+}
+
+// pathTag groups the operations by the first path segment except the version prefix
+func pathTag(path string) string {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segs) > 1 && segs[0] == "v2" {
+		segs = segs[1:]
+	}
+	if strings.HasPrefix(segs[0], "{") {
+		return ""
+	}
+	return segs[0]
+}
+
+func handlerName(h http.Handler) string {
+	if h == nil {
+		return ""
+	}
+	v := reflect.ValueOf(h)
+	if v.Kind() != reflect.Func {
+		return fmt.Sprintf("%T", h)
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// serve the OpenAPI document of all the registered routes
+func openAPIHandler(r *mux.Router, needToken bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		doc, err := generateOpenAPI(r, needToken)
+		if err != nil {
+			handleError(w, err, "generate OpenAPI document error", logger)
+			return
+		}
+		jsonResponse(doc, w, logger)
+	}
+}
@@ -78,7 +78,7 @@ func pluginsHandler(w http.ResponseWriter, r *http.Request, t plugin.PluginType)
 	switch r.Method {
 	case http.MethodGet:
 		content := nativeManager.List(t)
-		jsonResponse(content, w, logger)
+		listResponse(content, w, r, logger)
 	case http.MethodPost:
 		sd := plugin.NewPluginByType(t)
 		err := json.NewDecoder(r.Body).Decode(sd)
@@ -64,7 +64,7 @@ func portablesHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		content := portableManager.List()
-		jsonResponse(content, w, logger)
+		listResponse(content, w, r, logger)
 	case http.MethodPost:
 		sd := plugin.NewPluginByType(plugin.PORTABLE)
 		err := json.NewDecoder(r.Body).Decode(sd)
@@ -38,6 +38,8 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/httpx"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/label"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/oidc"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/v2/internal/processor"
 	"github.com/lf-edge/ekuiper/v2/internal/server/middleware"
@@ -66,7 +68,8 @@ var (
 )
 
 type statementDescriptor struct {
-	Sql string `json:"sql,omitempty"`
+	Sql    string            `json:"sql,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 func decodeStatementDescriptor(reader io.ReadCloser) (statementDescriptor, error) {
@@ -170,6 +173,8 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 
 	r := mux.NewRouter()
 	r.Use(traceMiddleware)
+	r.Use(auditMiddleware)
+	r.Use(namespaceMiddleware)
 	r.HandleFunc("/", rootHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/stop", stopHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/ping", pingHandler).Methods(http.MethodGet)
@@ -182,20 +187,39 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 	r.HandleFunc("/tables/{name}", tableHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
 	r.HandleFunc("/tables/{name}/schema", tableSchemaHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules", rulesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/rules/bulk/{action}", bulkRulesHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}", ruleHandler).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules/status/all", getAllRuleStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/status/events", ruleEventsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/{name}/status", getStatusRuleHandler).Methods(http.MethodGet)
 	r.HandleFunc("/v2/rules/{name}/status", getStatusV2RulHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/{name}/start", startRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/stop", stopRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/restart", restartRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/topo", getTopoRuleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/params", ruleParamsHandler).Methods(http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules/{name}/trace/start", enableRuleTraceHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/trace/stop", disableRuleTraceHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/usage/cpu", rulesTopCpuUsageHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/validate", validateRuleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/dryrun", dryRunRuleHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/test", testRuleSpecHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/reset_state", ruleStateHandler).Methods(http.MethodPut)
+	r.HandleFunc("/rules/{name}/state", inspectRuleStateHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules/{name}/explain", explainRuleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/canary", ruleCanaryHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/rules/{name}/canary/{action:promote|abort}", ruleCanaryActionHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/{name}/versions", ruleVersionsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/diff", ruleVersionDiffHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/{version:[0-9]+}", ruleVersionHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/{version:[0-9]+}/rollback", ruleRollbackHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/{name}/savepoints", ruleSavepointsHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/rules/{name}/savepoints/{id:[0-9]+}", ruleSavepointHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/rules/{name}/savepoints/{id:[0-9]+}/restore", ruleSavepointRestoreHandler).Methods(http.MethodPost)
+	r.HandleFunc("/ruletemplates", ruleTemplatesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/ruletemplates/{name}", ruleTemplateHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/ruletemplates/{name}/instances", ruleTemplateInstancesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/dependencies", dependenciesHandler).Methods(http.MethodGet)
 	r.HandleFunc("/ruleset/export", exportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruleset/import", importHandler).Methods(http.MethodPost)
 	r.HandleFunc("/configs", configurationUpdateHandler).Methods(http.MethodPatch)
@@ -222,14 +246,41 @@ func createRestServer(ip string, port int, needToken bool) *http.Server {
 
 	// dump metrics
 	r.HandleFunc("/metrics/dump", dumpMetricsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/namespaces", namespacesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/namespaces/{name}", namespaceHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/namespaces/{name}/tokens", namespaceTokensHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/namespaces/{name}/tokens/{id}", namespaceTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/audit", auditHandler).Methods(http.MethodGet)
+	r.HandleFunc("/audit/verify", auditVerifyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/node/backup", nodeBackupHandler).Methods(http.MethodGet)
+	r.HandleFunc("/node/restore", nodeRestoreHandler).Methods(http.MethodPost)
+	r.HandleFunc("/gitops/status", gitOpsStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/gitops/sync", gitOpsSyncHandler).Methods(http.MethodPost)
 	// Register extended routes
 	for k, v := range components {
 		logger.Infof("register rest endpoint for component %s", k)
 		v.rest(r)
 	}
+	r.HandleFunc("/openapi.json", openAPIHandler(r, needToken)).Methods(http.MethodGet)
 
 	if needToken {
-		r.Use(middleware.Auth)
+		if conf.Config.Basic.Oidc.Enable {
+			p, err := oidc.NewProvider(conf.Config.Basic.Oidc)
+			if err != nil {
+				panic(err)
+			}
+			r.HandleFunc("/oidc/config", oidcConfigHandler(p)).Methods(http.MethodGet)
+			r.Use(middleware.NewAuth(p))
+		} else {
+			r.Use(middleware.Auth)
+		}
+		if conf.Config.Basic.Rbac.Enable {
+			rb, err := middleware.NewRBAC(conf.Config.Basic.Rbac)
+			if err != nil {
+				panic(err)
+			}
+			r.Use(rb.Middleware)
+		}
 	}
 
 	server := &http.Server{
@@ -300,6 +351,11 @@ func getFile(file *fileContent) error {
 	return nil
 }
 
+const (
+	defaultExplainAnalyzeDuration = 5 * time.Second
+	maxExplainAnalyzeDuration     = time.Minute
+)
+
 func explainRuleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	vars := mux.Vars(r)
@@ -319,14 +375,41 @@ func explainRuleHandler(w http.ResponseWriter, r *http.Request) {
 		handleError(w, errors.New("only support explain sql now"), "explain rules error", logger)
 		return
 	}
-	var explainInfo string
-	explainInfo, err = planner.GetExplainInfoFromLogicalPlan(rule)
-	if err != nil {
-		handleError(w, err, "explain rules error", logger)
-		return
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "":
+		var explainInfo string
+		explainInfo, err = planner.GetExplainInfoFromLogicalPlan(rule)
+		if err != nil {
+			handleError(w, err, "explain rules error", logger)
+			return
+		}
+		// resp := planner.BuildExplainResultFromLp(lp, 0)
+		w.Write([]byte(explainInfo))
+	case "physical":
+		result, err := planner.ExplainPhysicalPlan(rule)
+		if err != nil {
+			handleError(w, err, "explain rules error", logger)
+			return
+		}
+		jsonResponse(result, w, logger)
+	case "analyze":
+		duration := defaultExplainAnalyzeDuration
+		if d := r.URL.Query().Get("duration"); d != "" {
+			duration, err = time.ParseDuration(d)
+			if err != nil || duration <= 0 || duration > maxExplainAnalyzeDuration {
+				handleError(w, fmt.Errorf("invalid duration %s, it must be a positive duration no longer than %s", d, maxExplainAnalyzeDuration), "explain rules error", logger)
+				return
+			}
+		}
+		result, err := planner.ExplainAnalyze(rule, duration)
+		if err != nil {
+			handleError(w, err, "explain rules error", logger)
+			return
+		}
+		jsonResponse(result, w, logger)
+	default:
+		handleError(w, fmt.Errorf("invalid explain mode %s, supported modes are physical and analyze", mode), "explain rules error", logger)
 	}
-	// resp := planner.BuildExplainResultFromLp(lp, 0)
-	w.Write([]byte(explainInfo))
 }
 
 func fileUploadHandler(w http.ResponseWriter, r *http.Request) {
@@ -440,6 +523,8 @@ type information struct {
 	CpuUsage      string `json:"cpuUsage,omitempty"`
 	MemoryUsed    string `json:"memoryUsed,omitempty"`
 	MemoryTotal   string `json:"memoryTotal"`
+	// MemoryBackpressure is true when the sources are paused by the memory manager
+	MemoryBackpressure bool `json:"memoryBackpressure,omitempty"`
 }
 
 func stopHandler(w http.ResponseWriter, r *http.Request) {
@@ -467,6 +552,7 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 			info.MemoryUsed = sysMetrics.GetMemoryUsage()
 		}
 		info.MemoryTotal = fmt.Sprintf("%d", memory.GetMemoryTotal())
+		info.MemoryBackpressure = memory.Paused()
 		byteInfo, _ := json.Marshal(info)
 		w.Write(byteInfo)
 	}
@@ -493,12 +579,26 @@ func sourceDetailsManageHandler(w http.ResponseWriter, r *http.Request, st ast.S
 			kind = ""
 		}
 	}
+	selector, err := label.Parse(r.URL.Query().Get("labels"))
+	if err != nil {
+		handleError(w, err, "Invalid labels", logger)
+		return
+	}
 	content, err = streamProcessor.ShowStreamOrTableDetails(kind, st)
 	if err != nil {
 		handleError(w, err, fmt.Sprintf("%s command error", cases.Title(language.Und).String(ast.StreamTypeMap[st])), logger)
 		return
 	}
-	jsonResponse(content, w, logger)
+	if len(selector) > 0 {
+		filtered := make([]processor.StreamDetail, 0, len(content))
+		for _, d := range content {
+			if selector.Matches(d.Labels) {
+				filtered = append(filtered, d)
+			}
+		}
+		content = filtered
+	}
+	listResponse(content, w, r, logger)
 }
 
 func sourcesManageHandler(w http.ResponseWriter, r *http.Request, st ast.StreamType) {
@@ -520,6 +620,11 @@ func sourcesManageHandler(w http.ResponseWriter, r *http.Request, st ast.StreamT
 				kind = ""
 			}
 		}
+		selector, err := label.Parse(r.URL.Query().Get("labels"))
+		if err != nil {
+			handleError(w, err, "Invalid labels", logger)
+			return
+		}
 		if kind != "" {
 			content, err = streamProcessor.ShowTable(kind)
 		} else {
@@ -529,14 +634,18 @@ func sourcesManageHandler(w http.ResponseWriter, r *http.Request, st ast.StreamT
 			handleError(w, err, fmt.Sprintf("%s command error", cases.Title(language.Und).String(ast.StreamTypeMap[st])), logger)
 			return
 		}
-		jsonResponse(content, w, logger)
+		nsKind := processor.ResourceStream
+		if st == ast.TypeTable {
+			nsKind = processor.ResourceTable
+		}
+		listResponse(filterByNamespace(r, nsKind, streamProcessor.FilterByLabels(content, selector)), w, r, logger)
 	case http.MethodPost:
 		v, err := decodeStatementDescriptor(r.Body)
 		if err != nil {
 			handleError(w, err, "Invalid body", logger)
 			return
 		}
-		content, err := streamProcessor.ExecStreamSql(v.Sql)
+		content, err := streamProcessor.ExecStreamSqlWithLabels(v.Sql, v.Labels)
 		if err != nil {
 			handleError(w, err, fmt.Sprintf("%s command error", cases.Title(language.Und).String(ast.StreamTypeMap[st])), logger)
 			return
@@ -607,7 +716,7 @@ func sourceManageHandler(w http.ResponseWriter, r *http.Request, st ast.StreamTy
 			handleError(w, err, "Invalid body", logger)
 			return
 		}
-		content, err := streamProcessor.ExecReplaceStream(name, v.Sql, st)
+		content, err := streamProcessor.ExecReplaceStream(name, v.Sql, v.Labels, st)
 		if err != nil {
 			handleError(w, err, fmt.Sprintf("%s command error", cases.Title(language.Und).String(ast.StreamTypeMap[st])), logger)
 			return
@@ -683,12 +792,26 @@ func rulesHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusCreated)
 		fmt.Fprintf(w, "Rule %s was created successfully.", id)
 	case http.MethodGet:
-		content, err := registry.GetAllRulesWithStatus()
+		selector, err := label.Parse(r.URL.Query().Get("labels"))
+		if err != nil {
+			handleError(w, err, "Invalid labels", logger)
+			return
+		}
+		content, err := registry.GetRulesWithStatusByLabels(selector)
 		if err != nil {
 			handleError(w, err, "Show rules error", logger)
 			return
 		}
-		jsonResponse(content, w, logger)
+		if ns := middleware.NamespaceFromContext(r.Context()); ns != "" {
+			result := make([]map[string]any, 0, len(content))
+			for _, c := range content {
+				if id, _ := c["id"].(string); namespaceProcessor.GetOwner(processor.ResourceRule, id) == ns {
+					result = append(result, c)
+				}
+			}
+			content = result
+		}
+		listResponse(content, w, r, logger)
 	}
 }
 
@@ -885,6 +1008,38 @@ func getTopoRuleHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(content))
 }
 
+// get or replace the runtime params of a rule
+func ruleParamsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	switch r.Method {
+	case http.MethodGet:
+		params, err := registry.GetRuleParams(name)
+		if err != nil {
+			handleError(w, err, "get rule params error", logger)
+			return
+		}
+		if params == nil {
+			params = map[string]any{}
+		}
+		jsonResponse(params, w, logger)
+	case http.MethodPut:
+		defer r.Body.Close()
+		params := make(map[string]any)
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			handleError(w, err, "Invalid body: Error decoding the rule params", logger)
+			return
+		}
+		if err := registry.UpdateRuleParams(name, params); err != nil {
+			handleError(w, err, "update rule params error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(w, "Rule %s params were updated successfully.", name)
+	}
+}
+
 // validate a rule
 func validateRuleHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
@@ -996,6 +1151,37 @@ func testRuleHandler(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(result, w, logger)
 }
 
+// run a rule against the sample inputs and return the outputs of each operator
+func dryRunRuleHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	result, err := trial.DryRun(string(body))
+	if err != nil {
+		handleError(w, err, "dry run rule error", logger)
+		return
+	}
+	jsonResponse(result, w, logger)
+}
+
+func testRuleSpecHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	results, err := runTestSpecs(body)
+	if err != nil {
+		handleError(w, err, "test rule error", logger)
+		return
+	}
+	jsonResponse(results, w, logger)
+}
+
 func testRuleStartHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	vars := mux.Vars(r)
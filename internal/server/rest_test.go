@@ -15,7 +15,9 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -23,6 +25,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -34,6 +37,7 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/io/http/httpserver"
+	"github.com/lf-edge/ekuiper/v2/internal/meta"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/v2/internal/processor"
@@ -49,6 +53,9 @@ func init() {
 	streamProcessor = processor.NewStreamProcessor()
 	ruleProcessor = processor.NewRuleProcessor()
 	rulesetProcessor = processor.NewRulesetProcessor(ruleProcessor, streamProcessor)
+	ruleMigrationProcessor = NewRuleMigrationProcessor(ruleProcessor, streamProcessor)
+	ruleTemplateProcessor = processor.NewRuleTemplateProcessor()
+	namespaceProcessor = processor.NewNamespaceProcessor()
 	registry = &RuleRegistry{internal: make(map[string]*rule.State)}
 	uploadsDb, _ = store.GetKV("uploads")
 	uploadsStatusDb, _ = store.GetKV("uploadsStatusDb")
@@ -70,6 +77,16 @@ func (suite *RestTestSuite) SetupTest() {
 	r := mux.NewRouter()
 	r.HandleFunc("/", rootHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/ping", pingHandler).Methods(http.MethodGet)
+	r.HandleFunc("/namespaces", namespacesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/namespaces/{name}", namespaceHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/namespaces/{name}/tokens", namespaceTokensHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/namespaces/{name}/tokens/{id}", namespaceTokenHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/audit", auditHandler).Methods(http.MethodGet)
+	r.HandleFunc("/audit/verify", auditVerifyHandler).Methods(http.MethodGet)
+	r.HandleFunc("/node/backup", nodeBackupHandler).Methods(http.MethodGet)
+	r.HandleFunc("/node/restore", nodeRestoreHandler).Methods(http.MethodPost)
+	r.HandleFunc("/gitops/status", gitOpsStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/gitops/sync", gitOpsSyncHandler).Methods(http.MethodPost)
 	r.HandleFunc("/streams", streamsHandler).Methods(http.MethodGet, http.MethodPost)
 	r.HandleFunc("/streamdetails", streamDetailsHandler).Methods(http.MethodGet)
 	r.HandleFunc("/streams/{name}", streamHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
@@ -79,6 +96,7 @@ func (suite *RestTestSuite) SetupTest() {
 	r.HandleFunc("/tables/{name}", tableHandler).Methods(http.MethodGet, http.MethodDelete, http.MethodPut)
 	r.HandleFunc("/tables/{name}/schema", tableSchemaHandler).Methods(http.MethodGet)
 	r.HandleFunc("/rules", rulesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/rules/bulk/{action}", bulkRulesHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}", ruleHandler).Methods(http.MethodDelete, http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules/{name}/status", getStatusRuleHandler).Methods(http.MethodGet)
 	r.HandleFunc("/v2/rules/{name}/status", getStatusV2RulHandler).Methods(http.MethodGet)
@@ -86,12 +104,27 @@ func (suite *RestTestSuite) SetupTest() {
 	r.HandleFunc("/rules/{name}/stop", stopRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/restart", restartRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/topo", getTopoRuleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/params", ruleParamsHandler).Methods(http.MethodGet, http.MethodPut)
 	r.HandleFunc("/rules/{name}/reset_state", ruleStateHandler).Methods(http.MethodPut)
 	r.HandleFunc("/rules/{name}/explain", explainRuleHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/canary", ruleCanaryHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/rules/{name}/canary/{action:promote|abort}", ruleCanaryActionHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/{name}/versions", ruleVersionsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/diff", ruleVersionDiffHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/{version:[0-9]+}", ruleVersionHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/{name}/versions/{version:[0-9]+}/rollback", ruleRollbackHandler).Methods(http.MethodPost)
+	r.HandleFunc("/rules/{name}/savepoints", ruleSavepointsHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/rules/{name}/savepoints/{id:[0-9]+}", ruleSavepointHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/rules/{name}/savepoints/{id:[0-9]+}/restore", ruleSavepointRestoreHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/trace/start", enableRuleTraceHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/{name}/trace/stop", disableRuleTraceHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/validate", validateRuleHandler).Methods(http.MethodPost)
 	r.HandleFunc("/rules/status/all", getAllRuleStatusHandler).Methods(http.MethodGet)
+	r.HandleFunc("/rules/status/events", ruleEventsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/ruletemplates", ruleTemplatesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/ruletemplates/{name}", ruleTemplateHandler).Methods(http.MethodGet, http.MethodPut, http.MethodDelete)
+	r.HandleFunc("/ruletemplates/{name}/instances", ruleTemplateInstancesHandler).Methods(http.MethodGet, http.MethodPost)
+	r.HandleFunc("/dependencies", dependenciesHandler).Methods(http.MethodGet)
 	r.HandleFunc("/ruleset/export", exportHandler).Methods(http.MethodPost)
 	r.HandleFunc("/ruleset/import", importHandler).Methods(http.MethodPost)
 	r.HandleFunc("/configs", configurationUpdateHandler).Methods(http.MethodPatch)
@@ -910,6 +943,376 @@ func (suite *RestTestSuite) TestCreateDuplicateRule() {
 	require.Equal(suite.T(), `{"error":1000,"message":"rule test12345 already exists"}`+"\n", string(returnVal))
 }
 
+func (suite *RestTestSuite) TestRuleParams() {
+	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demoParam() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
+	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
+	w1 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+
+	ruleJson := `{"id":"paramRule","triggered":false,"sql":"select * from demoParam where temperature > param(\"threshold\")","actions":[{"log":{}}],"params":{"threshold":30}}`
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(ruleJson))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/paramRule/params", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ := io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `{"threshold":30}`, string(returnVal))
+
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/rules/paramRule/params", bytes.NewBufferString(`{"threshold":40,"devices":["d1"]}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/paramRule/params", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `{"threshold":40,"devices":["d1"]}`, string(returnVal))
+	// The params are saved in the rule
+	r, err := ruleProcessor.GetRuleById("paramRule")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), map[string]any{"threshold": float64(40), "devices": []any{"d1"}}, r.Params)
+
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/rules/notExist/params", bytes.NewBufferString(`{"threshold":40}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/rules/paramRule", bytes.NewBufferString("any"))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *RestTestSuite) TestRuleVersions() {
+	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demoVersion() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
+	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
+	w1 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+
+	ruleJson := `{"id":"versionRule","triggered":false,"sql":"select * from demoVersion","actions":[{"log":{}}]}`
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(ruleJson))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	// No history before update
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/versionRule/versions", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ := io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[]`, string(returnVal))
+
+	updated := `{"id":"versionRule","triggered":false,"sql":"select * from demoVersion where a > 1","actions":[{"log":{}}]}`
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/rules/versionRule", bytes.NewBufferString(updated))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/versionRule/versions", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	var versions []*processor.RuleVersion
+	require.NoError(suite.T(), json.NewDecoder(w.Body).Decode(&versions))
+	require.Len(suite.T(), versions, 2)
+	require.Equal(suite.T(), 1, versions[0].Version)
+	require.Equal(suite.T(), 2, versions[1].Version)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/versionRule/versions/1", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	rv := &processor.RuleVersion{}
+	require.NoError(suite.T(), json.NewDecoder(w.Body).Decode(rv))
+	require.Equal(suite.T(), ruleJson, rv.Rule)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/versionRule/versions/diff?from=1", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"path":"sql","from":"select * from demoVersion","to":"select * from demoVersion where a > 1"}]`, string(returnVal))
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/versionRule/versions/1/rollback?restoreState=true", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/versionRule/versions/1/rollback", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	r, err := ruleProcessor.GetRuleJson("versionRule")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), ruleJson, r)
+	// Rollback is recorded as a new version
+	versions, err = ruleProcessor.GetRuleVersions("versionRule")
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), versions, 3)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/versionRule/versions/9", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/rules/versionRule", bytes.NewBufferString("any"))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	_, err = ruleProcessor.GetRuleVersion("versionRule", 1)
+	require.Error(suite.T(), err)
+}
+
+func (suite *RestTestSuite) TestHotUpdateState() {
+	_ = ruleProcessor.ExecDrop("hotRule")
+	_, _ = streamProcessor.DropStream("demoHot", ast.TypeStream)
+	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demoHot() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
+	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
+	w1 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+
+	ruleJson := `{"id":"hotRule","triggered":false,"sql":"select count(*) from demoHot group by tumblingwindow(ss, 10)","actions":[{"log":{}}],"options":{"qos":1}}`
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(ruleJson))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	ts, err := store.GetStateTS("hotRule")
+	require.NoError(suite.T(), err)
+	_, err = ts.Set(1000, map[string]any{
+		"demoHot":   map[string]any{"offset": "1"},
+		"3_window":  map[string]any{"buffer": "w"},
+		"4_project": map[string]any{},
+	})
+	require.NoError(suite.T(), err)
+
+	// The analytic function shifts the window operator, and the window state is kept
+	updated := `{"id":"hotRule","triggered":false,"sql":"select lag(a) as l, count(*) from demoHot group by tumblingwindow(ss, 10)","actions":[{"log":{}}],"options":{"qos":1}}`
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/rules/hotRule", bytes.NewBufferString(updated))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	var m map[string]any
+	_, err = ts.Last(&m)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), map[string]any{
+		"demoHot":  map[string]any{"offset": "1"},
+		"4_window": map[string]any{"buffer": "w"},
+	}, m)
+
+	// The window is changed so that its state is dropped
+	updated = `{"id":"hotRule","triggered":false,"sql":"select lag(a) as l, count(*) from demoHot group by tumblingwindow(ss, 20)","actions":[{"log":{}}],"options":{"qos":1}}`
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/rules/hotRule", bytes.NewBufferString(updated))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	m = nil
+	_, err = ts.Last(&m)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), map[string]any{
+		"demoHot": map[string]any{"offset": "1"},
+	}, m)
+
+	require.NoError(suite.T(), ruleProcessor.ExecDrop("hotRule"))
+	_, err = streamProcessor.DropStream("demoHot", ast.TypeStream)
+	require.NoError(suite.T(), err)
+}
+
+func (suite *RestTestSuite) TestRuleCanary() {
+	_ = ruleProcessor.ExecDrop("canaryRule_canary")
+	_ = ruleProcessor.ExecDrop("canaryRule")
+	_ = ruleProcessor.DeleteCanary("canaryRule")
+	_, _ = streamProcessor.DropStream("demoCanary", ast.TypeStream)
+	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demoCanary() WITH (DATASOURCE=\"canary\", TYPE=\"memory\", FORMAT=\"json\")"}`))
+	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
+	w1 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+
+	ruleJson := `{"id":"canaryRule","triggered":false,"sql":"select * from demoCanary","actions":[{"log":{}}]}`
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(ruleJson))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	newJson := `{"id":"canaryRule","triggered":false,"sql":"select a from demoCanary","actions":[{"log":{}}]}`
+	for _, tc := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{name: "nonexist", body: `{"rule":` + newJson + `,"weight":20}`, code: http.StatusNotFound},
+		{name: "canaryRule", body: `{"rule":` + newJson + `,"weight":100}`, code: http.StatusBadRequest},
+		{name: "canaryRule", body: `{"rule":{"id":"canaryRule","sql":"select"},"weight":20}`, code: http.StatusBadRequest},
+	} {
+		req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/"+tc.name+"/canary", bytes.NewBufferString(tc.body))
+		w = httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		require.Equal(suite.T(), tc.code, w.Code, w.Body.String())
+	}
+
+	// Split the traffic
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/canaryRule/canary", bytes.NewBufferString(`{"rule":`+newJson+`,"weight":20}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/canaryRule/canary", bytes.NewBufferString(`{"rule":`+newJson+`,"weight":20}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code, w.Body.String())
+
+	rs, ok := registry.load("canaryRule")
+	require.True(suite.T(), ok)
+	require.Equal(suite.T(), &def.TrafficSplit{From: 20, To: 100}, rs.Rule.Options.TrafficSplit)
+	crs, ok := registry.load("canaryRule_canary")
+	require.True(suite.T(), ok)
+	require.Equal(suite.T(), &def.TrafficSplit{From: 0, To: 20}, crs.Rule.Options.TrafficSplit)
+	require.Equal(suite.T(), "select a from demoCanary", crs.Rule.Sql)
+	require.Contains(suite.T(), crs.GetTopoGraph().Edges, "op_demoCanary_split")
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/canaryRule/canary", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	status := make(map[string]any)
+	require.NoError(suite.T(), json.NewDecoder(w.Body).Decode(&status))
+	require.Equal(suite.T(), "canaryRule_canary", status["canaryId"])
+	require.Equal(suite.T(), float64(20), status["weight"])
+	require.Contains(suite.T(), status, "ruleStatus")
+	require.Contains(suite.T(), status, "canaryStatus")
+
+	// Abort restores the rule
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/canaryRule/canary/abort", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(suite.T(), "Canary of rule canaryRule was aborted.", w.Body.String())
+	_, ok = registry.load("canaryRule_canary")
+	require.False(suite.T(), ok)
+	rs, _ = registry.load("canaryRule")
+	require.Nil(suite.T(), rs.Rule.Options.TrafficSplit)
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/canaryRule/canary", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	// Shadow mode does not affect the rule
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/canaryRule/canary", bytes.NewBufferString(`{"rule":`+newJson+`,"shadow":true}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	rs, _ = registry.load("canaryRule")
+	require.Nil(suite.T(), rs.Rule.Options.TrafficSplit)
+	crs, ok = registry.load("canaryRule_canary")
+	require.True(suite.T(), ok)
+	require.Nil(suite.T(), crs.Rule.Options.TrafficSplit)
+	require.Equal(suite.T(), []map[string]any{{"nop": map[string]any{}}}, crs.Rule.Actions)
+
+	// Promote replaces the rule
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/canaryRule/canary/promote", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	_, ok = registry.load("canaryRule_canary")
+	require.False(suite.T(), ok)
+	r, err := ruleProcessor.GetRuleJson("canaryRule")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), newJson, r)
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/canaryRule/canary/promote", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	// Delete the rule deletes its canary
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/canaryRule/canary", bytes.NewBufferString(`{"rule":`+ruleJson+`,"weight":50}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(suite.T(), registry.DeleteRule("canaryRule"))
+	_, ok = registry.load("canaryRule_canary")
+	require.False(suite.T(), ok)
+	_, err = ruleProcessor.GetCanary("canaryRule")
+	require.Error(suite.T(), err)
+	_, err = streamProcessor.DropStream("demoCanary", ast.TypeStream)
+	require.NoError(suite.T(), err)
+}
+
+func (suite *RestTestSuite) TestBundleImport() {
+	if meta.ConfigManager == nil {
+		meta.InitYamlConfigManager()
+	}
+	cleanup := func() {
+		for _, path := range []string{"rules/bundleRule", "rules/bundleRule_1", "streams/demoBundle", "streams/demoBundle_1"} {
+			req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080/"+path, bytes.NewBufferString("any"))
+			suite.r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+	cleanup()
+	defer cleanup()
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", bytes.NewBufferString(`{"sql":"CREATE stream demoBundle() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(`{"id":"bundleRule","triggered":false,"sql":"select demoBundle.a from demoBundle where a > 'demoBundle'","actions":[{"log":{}}]}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/data/export", bytes.NewBufferString(`["bundleRule"]`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	bundle, _ := io.ReadAll(w.Result().Body)
+	exported := &Configuration{}
+	require.NoError(suite.T(), json.Unmarshal(bundle, exported))
+	require.Contains(suite.T(), exported.Streams, "demoBundle")
+	require.Contains(suite.T(), exported.Rules, "bundleRule")
+	body, _ := json.Marshal(map[string]string{"content": string(bundle)})
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/data/import?partial=1&conflict=skip", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	status := &ImportConfigurationStatus{}
+	require.NoError(suite.T(), json.NewDecoder(w.Body).Decode(status))
+	require.Equal(suite.T(), "skipped", status.Conflicts["streams.demoBundle"])
+	require.Equal(suite.T(), "skipped", status.Conflicts["rules.bundleRule"])
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/data/import?partial=1&conflict=rename", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	status = &ImportConfigurationStatus{}
+	require.NoError(suite.T(), json.NewDecoder(w.Body).Decode(status))
+	require.Equal(suite.T(), "demoBundle_1", status.Conflicts["streams.demoBundle"])
+	require.Equal(suite.T(), "bundleRule_1", status.Conflicts["rules.bundleRule"])
+	_, err := streamProcessor.GetStream("demoBundle_1", ast.TypeStream)
+	require.NoError(suite.T(), err)
+	r, err := ruleProcessor.GetRuleById("bundleRule_1")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "select demoBundle_1.a from demoBundle_1 where a > 'demoBundle'", r.Sql)
+
+	missing := `{"content":"{\"rules\":{\"missingRule\":\"{\\\"id\\\":\\\"missingRule\\\",\\\"sql\\\":\\\"select * from notExist\\\",\\\"actions\\\":[{\\\"log\\\":{}}]}\"}}"}`
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/data/import?partial=1", bytes.NewBufferString(missing))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	require.Contains(suite.T(), w.Body.String(), "missing dependencies: rule missingRule requires stream notExist")
+	_, err = ruleProcessor.GetRuleById("missingRule")
+	require.Error(suite.T(), err)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/data/import?partial=1&conflict=merge", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
 func (suite *RestTestSuite) TestGetAllRuleStatus() {
 	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demo456() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
 	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
@@ -1001,3 +1404,444 @@ func (suite *RestTestSuite) TestWaitStopRule() {
 	require.True(suite.T(), end.Sub(now) >= 300*time.Millisecond)
 	waitAllRuleStop()
 }
+
+func (suite *RestTestSuite) TestRuleTemplate() {
+	// clean up the leftovers of the previous runs
+	_ = ruleProcessor.ExecDrop("tplRule1")
+	_ = ruleProcessor.ExecDrop("tplRule2")
+	_ = ruleTemplateProcessor.Delete("alertTpl")
+	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demoTpl() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
+	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
+	w1 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+
+	tpl := `{"id":"alertTpl","params":{"threshold":30},"rule":{"triggered":false,"sql":"select * from demoTpl where deviceId = \"${deviceId}\" and temperature > ${threshold}","actions":[{"log":{}}],"options":{"bufferLength":"${threshold}"}}}`
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/ruletemplates", bytes.NewBufferString(tpl))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/ruletemplates", bytes.NewBufferString(tpl))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	instances := `[{"id":"tplRule1","params":{"deviceId":"dev1"}},{"id":"tplRule2","params":{"deviceId":"dev2","threshold":50}},{"id":"tplRule3"},{"id":"tplRule4","params":{"deviceId":"dev4","unknown":1}}]`
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/ruletemplates/alertTpl/instances", bytes.NewBufferString(instances))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	returnVal, _ := io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"id":"tplRule1"},{"id":"tplRule2"},{"id":"tplRule3","error":"render rule tplRule3 with template alertTpl error: missing param deviceId"},{"id":"tplRule4","error":"unknown param unknown of rule template alertTpl"}]`, string(returnVal))
+
+	r, err := ruleProcessor.GetRuleById("tplRule2")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), `select * from demoTpl where deviceId = "dev2" and temperature > 50`, r.Sql)
+	require.Equal(suite.T(), 50, r.Options.BufferLength)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/ruletemplates/alertTpl/instances", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"id":"tplRule1","template":"alertTpl","params":{"deviceId":"dev1"}},{"id":"tplRule2","template":"alertTpl","params":{"deviceId":"dev2","threshold":50}}]`, string(returnVal))
+
+	// update the template re-instantiates all the rules
+	updated := `{"params":{"threshold":30},"rule":{"triggered":false,"sql":"select deviceId from demoTpl where deviceId = \"${deviceId}\" and temperature > ${threshold}","actions":[{"log":{}}]}}`
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/ruletemplates/alertTpl", bytes.NewBufferString(updated))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"id":"tplRule1"},{"id":"tplRule2"}]`, string(returnVal))
+	r, err = ruleProcessor.GetRuleById("tplRule1")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), `select deviceId from demoTpl where deviceId = "dev1" and temperature > 30`, r.Sql)
+
+	// a deleted rule is no longer an instance
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/rules/tplRule2", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	insts, err := ruleTemplateProcessor.GetInstances("alertTpl")
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), insts, 1)
+
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/ruletemplates/alertTpl", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/ruletemplates/alertTpl", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+	// the rule is kept after the template is deleted
+	_, err = ruleProcessor.GetRuleById("tplRule1")
+	require.NoError(suite.T(), err)
+	require.NoError(suite.T(), registry.DeleteRule("tplRule1"))
+}
+
+func (suite *RestTestSuite) TestBulkRules() {
+	for _, id := range []string{"bulk_line1_a", "bulk_line1_b", "bulk_line2_a"} {
+		_ = ruleProcessor.ExecDrop(id)
+	}
+	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demoBulk() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
+	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
+	w1 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+	rules := []string{
+		`{"id":"bulk_line1_a","tags":["line1","temp"],"triggered":false,"sql":"select * from demoBulk","actions":[{"log":{}}]}`,
+		`{"id":"bulk_line1_b","tags":["line1"],"triggered":false,"sql":"select * from demoBulk","actions":[{"log":{}}]}`,
+		`{"id":"bulk_line2_a","tags":["line2","temp"],"triggered":false,"sql":"select * from demoBulk","actions":[{"log":{}}]}`,
+	}
+	for _, r := range rules {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(r))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules/bulk/start", bytes.NewBufferString(`{"tags":["temp"]}`))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	returnVal, _ := io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"id":"bulk_line1_a"},{"id":"bulk_line2_a"}]`, string(returnVal))
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/bulk/stop", bytes.NewBufferString(`{"tags":["temp"],"pattern":"bulk_line1_*"}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"id":"bulk_line1_a"}]`, string(returnVal))
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/bulk/pause", bytes.NewBufferString(`{"pattern":"bulk_*"}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/bulk/delete", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/bulk/delete", bytes.NewBufferString(`{"pattern":"bulk_*"}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"id":"bulk_line1_a"},{"id":"bulk_line1_b"},{"id":"bulk_line2_a"}]`, string(returnVal))
+	ids, err := registry.SelectRules(&RuleSelector{Pattern: "bulk_*"})
+	require.NoError(suite.T(), err)
+	require.Empty(suite.T(), ids)
+}
+
+func (suite *RestTestSuite) TestLabels() {
+	for _, id := range []string{"label_sh_1", "label_sh_2", "label_bj_1"} {
+		_ = ruleProcessor.ExecDrop(id)
+	}
+	_, _ = streamProcessor.DropStream("labelStream1", ast.TypeStream)
+	_, _ = streamProcessor.DropStream("labelStream2", ast.TypeStream)
+
+	buf := bytes.NewBufferString(`{"sql":"CREATE stream labelStream1() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")","labels":{"site":"sh"}}`)
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf)
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	buf = bytes.NewBufferString(`{"sql":"CREATE stream labelStream2() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")","labels":{"site":"bj","team":"ops"}}`)
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	buf = bytes.NewBufferString(`{"sql":"CREATE stream labelStream3() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")","labels":{"site":"-bj"}}`)
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/streams?labels=site=sh", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ := io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `["labelStream1"]`, string(returnVal))
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/streamdetails?labels=team", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"name":"labelStream2","type":"mqtt","format":"json","labels":{"site":"bj","team":"ops"}}]`, string(returnVal))
+
+	// replace without labels keeps the labels
+	buf = bytes.NewBufferString(`{"sql":"CREATE stream labelStream2() WITH (DATASOURCE=\"1\", TYPE=\"mqtt\")"}`)
+	req, _ = http.NewRequest(http.MethodPut, "http://localhost:8080/streams/labelStream2", buf)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	labels, err := streamProcessor.GetLabels("labelStream2")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), map[string]string{"site": "bj", "team": "ops"}, labels)
+
+	rules := []string{
+		`{"id":"label_sh_1","labels":{"site":"sh","line":"1"},"triggered":false,"sql":"select * from labelStream1","actions":[{"log":{}}]}`,
+		`{"id":"label_sh_2","labels":{"site":"sh","line":"2"},"triggered":false,"sql":"select * from labelStream1","actions":[{"log":{}}]}`,
+		`{"id":"label_bj_1","labels":{"site":"bj","line":"1"},"triggered":false,"sql":"select * from labelStream2","actions":[{"log":{}}]}`,
+	}
+	for _, r := range rules {
+		req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(r))
+		w = httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	}
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(`{"id":"label_invalid","labels":{"":"sh"},"sql":"select * from labelStream1","actions":[{"log":{}}]}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules?labels=site=sh,line!=2", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"id":"label_sh_1","name":"label_sh_1","status":"stopped","trace":false,"labels":{"site":"sh","line":"1"}}]`, string(returnVal))
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules?labels=site=s%20h", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/bulk/delete", bytes.NewBufferString(`{"pattern":"label_*","labels":"line=1"}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	returnVal, _ = io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `[{"id":"label_bj_1"},{"id":"label_sh_1"}]`, string(returnVal))
+	_ = ruleProcessor.ExecDrop("label_sh_2")
+}
+
+func (suite *RestTestSuite) TestDependencies() {
+	for _, id := range []string{"dep_r1", "dep_r2"} {
+		_ = ruleProcessor.ExecDrop(id)
+	}
+	_, _ = streamProcessor.DropStream("depRaw", ast.TypeStream)
+	_, _ = streamProcessor.DropStream("depMem", ast.TypeStream)
+	_, _ = streamProcessor.DropStream("depTable", ast.TypeTable)
+
+	sqls := []string{
+		`{"sql":"CREATE stream depRaw() WITH (DATASOURCE=\"dep/raw\", TYPE=\"mqtt\")"}`,
+		`{"sql":"CREATE stream depMem() WITH (DATASOURCE=\"dep/+\", TYPE=\"memory\")"}`,
+		`{"sql":"CREATE table depTable() WITH (DATASOURCE=\"dep/a\", TYPE=\"memory\")"}`,
+	}
+	for _, s := range sqls {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", bytes.NewBufferString(s))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	}
+	rules := []string{
+		`{"id":"dep_r1","triggered":false,"sql":"select * from depRaw","actions":[{"memory":{"topic":"dep/a"}}]}`,
+		`{"id":"dep_r2","triggered":false,"sql":"select * from depMem","actions":[{"log":{}}]}`,
+	}
+	for _, r := range rules {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(r))
+		w := httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:8080/dependencies?rule=dep_r1", http.NoBody)
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	returnVal, _ := io.ReadAll(w.Result().Body)
+	require.JSONEq(suite.T(), `{
+		"nodes": [
+			{"id":"rule:dep_r1","type":"rule","name":"dep_r1"},
+			{"id":"rule:dep_r2","type":"rule","name":"dep_r2"},
+			{"id":"stream:depMem","type":"stream","name":"depMem"},
+			{"id":"table:depTable","type":"table","name":"depTable"},
+			{"id":"topic:dep/a","type":"topic","name":"dep/a"}
+		],
+		"edges": [
+			{"from":"rule:dep_r1","to":"topic:dep/a"},
+			{"from":"stream:depMem","to":"rule:dep_r2"},
+			{"from":"topic:dep/a","to":"stream:depMem"},
+			{"from":"topic:dep/a","to":"table:depTable"}
+		]
+	}`, string(returnVal))
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/dependencies", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	g := &DependencyGraph{}
+	require.NoError(suite.T(), json.NewDecoder(w.Body).Decode(g))
+	require.Contains(suite.T(), g.Edges, &DependencyEdge{From: "stream:depRaw", To: "rule:dep_r1"})
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/dependencies?rule=dep_none", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	for _, id := range []string{"dep_r1", "dep_r2"} {
+		_ = ruleProcessor.ExecDrop(id)
+	}
+}
+
+func (suite *RestTestSuite) TestRuleEvents() {
+	_ = ruleProcessor.ExecDrop("event_r1")
+	_, _ = streamProcessor.DropStream("eventStream", ast.TypeStream)
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", bytes.NewBufferString(`{"sql":"CREATE stream eventStream() WITH (DATASOURCE=\"event/a\", TYPE=\"memory\")"}`))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(`{"id":"event_r1","triggered":false,"sql":"select * from eventStream","actions":[{"log":{}}]}`))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/status/events?interval=1m1", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	ts := httptest.NewServer(suite.r)
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/rules/status/events?rules=event_r1&interval=100ms", http.NoBody)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	require.Equal(suite.T(), "text/event-stream", resp.Header.Get(ContentType))
+	events := make(chan *RuleEvent, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "data: ") {
+				e := &RuleEvent{}
+				if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), e) == nil {
+					events <- e
+				}
+			}
+		}
+	}()
+	nextStatus := func() *RuleEvent {
+		for {
+			select {
+			case e := <-events:
+				if e.Type == RuleEventStatus {
+					return e
+				}
+			case <-time.After(5 * time.Second):
+				suite.T().Fatal("timeout waiting for the status event")
+				return nil
+			}
+		}
+	}
+	e := nextStatus()
+	require.Equal(suite.T(), "event_r1", e.RuleId)
+	require.Equal(suite.T(), "stopped", e.Status)
+	require.NoError(suite.T(), registry.StartRule("event_r1"))
+	e = nextStatus()
+	require.Equal(suite.T(), "running", e.Status)
+	require.NoError(suite.T(), registry.DeleteRule("event_r1"))
+	e = nextStatus()
+	require.Equal(suite.T(), "deleted", e.Status)
+}
+
+func (suite *RestTestSuite) TestListOptions() {
+	for _, id := range []string{"page_c", "page_a", "page_b"} {
+		_ = ruleProcessor.ExecDrop(id)
+	}
+	_, _ = streamProcessor.DropStream("pageStream", ast.TypeStream)
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", bytes.NewBufferString(`{"sql":"CREATE stream pageStream() WITH (DATASOURCE=\"page\", TYPE=\"memory\")","labels":{"test":"page"}}`))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	for _, id := range []string{"page_c", "page_a", "page_b"} {
+		req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(fmt.Sprintf(`{"id":"%s","name":"name_%s","labels":{"test":"page"},"triggered":false,"sql":"select * from pageStream","actions":[{"log":{}}]}`, id, id)))
+		w = httptest.NewRecorder()
+		suite.r.ServeHTTP(w, req)
+		require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules?labels=test=page&sort=-id&limit=2&fields=id,name", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(suite.T(), "3", w.Header().Get(TotalCountHeader))
+	require.JSONEq(suite.T(), `[{"id":"page_c","name":"name_page_c"},{"id":"page_b","name":"name_page_b"}]`, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules?labels=test=page&offset=2&fields=id", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	require.JSONEq(suite.T(), `[{"id":"page_c"}]`, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules?labels=test=page&offset=5", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(suite.T(), "[]", w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules?limit=-1", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/streams?labels=test=page&sort=-name&limit=1", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	require.Equal(suite.T(), "1", w.Header().Get(TotalCountHeader))
+	require.JSONEq(suite.T(), `["pageStream"]`, w.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/streamdetails?labels=test=page&fields=name,type", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	require.JSONEq(suite.T(), `[{"name":"pageStream","type":"memory"}]`, w.Body.String())
+
+	for _, id := range []string{"page_c", "page_a", "page_b"} {
+		_ = ruleProcessor.ExecDrop(id)
+	}
+}
+
+func (suite *RestTestSuite) TestOpenAPI() {
+	ts := httptest.NewServer(openAPIHandler(suite.r, true))
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	require.NoError(suite.T(), err)
+	defer resp.Body.Close()
+	require.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	doc := &openAPIDoc{}
+	require.NoError(suite.T(), json.NewDecoder(resp.Body).Decode(doc))
+	require.Equal(suite.T(), "3.0.3", doc.OpenAPI)
+	require.Equal(suite.T(), []map[string][]string{{"bearerAuth": {}}}, doc.Security)
+
+	rules := doc.Paths["/rules"]
+	require.Len(suite.T(), rules, 2)
+	require.Equal(suite.T(), "getRules", rules["get"].OperationId)
+	require.Equal(suite.T(), []string{"rules"}, rules["get"].Tags)
+	require.Equal(suite.T(), "server.rulesHandler", rules["post"].Handler)
+	require.Contains(suite.T(), rules["post"].Responses, "401")
+
+	rollback := doc.Paths["/rules/{name}/versions/{version}/rollback"]["post"]
+	require.NotNil(suite.T(), rollback)
+	require.Equal(suite.T(), "postRulesNameVersionsVersionRollback", rollback.OperationId)
+	require.Equal(suite.T(), []*openAPIParameter{
+		{Name: "name", In: "path", Required: true, Schema: map[string]any{"type": "string"}},
+		{Name: "version", In: "path", Required: true, Schema: map[string]any{"type": "string", "pattern": "^[0-9]+$"}},
+	}, rollback.Parameters)
+	require.Contains(suite.T(), rollback.Responses, "404")
+
+	ping := doc.Paths["/ping"]["get"]
+	require.NotNil(suite.T(), ping)
+	require.NotNil(suite.T(), ping.Security)
+	require.Empty(suite.T(), *ping.Security)
+	require.NotContains(suite.T(), ping.Responses, "401")
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule"
+	"github.com/lf-edge/ekuiper/v2/internal/trial"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
 )
@@ -64,7 +65,7 @@ func (r *rpcComp) serve() {
 		WriteTimeout: time.Second * 15,
 		ReadTimeout:  time.Second * 15,
 		IdleTimeout:  time.Second * 60,
-		Handler:      rpcSrv,
+		Handler:      rpcHandler(rpcSrv),
 	}
 	r.s = srvRpc
 	go func() {
@@ -87,28 +88,66 @@ func (r *rpcComp) close() {
 type Server int
 
 func (t *Server) CreateQuery(sql string, reply *string) error {
-	if _, ok := registry.load(QueryRuleId); ok {
-		stopQuery()
+	if _, err := createQuery(sql); err != nil {
+		return err
 	}
-	tp, err := ruleProcessor.ExecQuery(QueryRuleId, sql)
+	msg := fmt.Sprintf("Query was submit successfully.")
+	logger.Println(msg)
+	*reply = fmt.Sprint(msg)
+	return nil
+}
+
+// CreateTimedQuery creates the query which is stopped after the timeout. The timeout is ignored if it is not positive.
+func (t *Server) CreateTimedQuery(arg *model.QueryDesc, reply *string) error {
+	rs, err := createQuery(arg.Sql)
 	if err != nil {
 		return err
+	}
+	if arg.Timeout > 0 {
+		time.AfterFunc(arg.Timeout, func() {
+			// only stop the query if it is not replaced by a new one
+			if cur, ok := registry.load(QueryRuleId); ok && cur == rs {
+				logger.Infof("stop the query after timeout %v", arg.Timeout)
+				stopQuery()
+			}
+		})
+		*reply = fmt.Sprintf("Query was submit successfully and will stop in %v.", arg.Timeout)
 	} else {
-		rs := rule.NewState(def.GetDefaultRule(QueryRuleId, sql))
-		rs.WithTopo(tp)
-		registry.register(QueryRuleId, rs)
-		_ = rs.Start()
-		msg := fmt.Sprintf("Query was submit successfully.")
-		logger.Println(msg)
-		*reply = fmt.Sprint(msg)
+		*reply = "Query was submit successfully."
 	}
 	return nil
 }
 
+func (t *Server) StopQuery(_ string, reply *string) error {
+	stopQuery()
+	*reply = "Query was stopped."
+	return nil
+}
+
+func createQuery(sql string) (*rule.State, error) {
+	if _, ok := registry.load(QueryRuleId); ok {
+		stopQuery()
+	}
+	tp, err := ruleProcessor.ExecQuery(QueryRuleId, sql)
+	if err != nil {
+		return nil, err
+	}
+	rs := rule.NewState(def.GetDefaultRule(QueryRuleId, sql))
+	rs.WithTopo(tp)
+	registry.register(QueryRuleId, rs)
+	_ = rs.Start()
+	return rs, nil
+}
+
 func stopQuery() {
 	if rs, ok := registry.load(QueryRuleId); ok {
 		logger.Printf("stop the query.")
 		_ = rs.Delete()
+		registry.Lock()
+		if registry.internal[QueryRuleId] == rs {
+			delete(registry.internal, QueryRuleId)
+		}
+		registry.Unlock()
 	}
 }
 
@@ -116,11 +155,13 @@ func stopQuery() {
  * qid is not currently used.
  */
 func (t *Server) GetQueryResult(_ string, reply *string) error {
-	if rs, ok := registry.load(QueryRuleId); ok {
-		st := rs.GetState()
-		if st == rule.Stopped || st == rule.StoppedByErr {
-			return fmt.Errorf("query rule is stopped: %s", rs.GetLastWill())
-		}
+	rs, ok := registry.load(QueryRuleId)
+	if !ok {
+		return fmt.Errorf("query is stopped")
+	}
+	st := rs.GetState()
+	if st == rule.Stopped || st == rule.StoppedByErr {
+		return fmt.Errorf("query rule is stopped: %s", rs.GetLastWill())
 	}
 
 	sink.QR.LastFetch = time.Now()
@@ -257,6 +298,46 @@ func (t *Server) ValidateRule(rule *model.RPCArgDesc, reply *string) error {
 	return nil
 }
 
+// TestRule runs the rule test specs and replies the results in json
+func (t *Server) TestRule(specs string, reply *string) error {
+	results, err := runTestSpecs([]byte(specs))
+	if err != nil {
+		return fmt.Errorf("Test rule error : %s.", err)
+	}
+	r, err := marshalDesc(results)
+	if err != nil {
+		return err
+	}
+	*reply = r
+	return nil
+}
+
+// BenchRule generates load against a rule or a canned scenario and replies the report in json
+func (t *Server) BenchRule(benchDef string, reply *string) error {
+	bd := &trial.BenchDef{}
+	if err := json.Unmarshal([]byte(benchDef), bd); err != nil {
+		return fmt.Errorf("Bench rule error : invalid bench definition %s.", err)
+	}
+	var r *def.Rule
+	if bd.RuleId != "" {
+		var err error
+		r, err = ruleProcessor.GetRuleById(bd.RuleId)
+		if err != nil {
+			return fmt.Errorf("Bench rule error : %s.", err)
+		}
+	}
+	report, err := trial.Bench(bd, r)
+	if err != nil {
+		return fmt.Errorf("Bench rule error : %s.", err)
+	}
+	result, err := marshalDesc(report)
+	if err != nil {
+		return err
+	}
+	*reply = result
+	return nil
+}
+
 func (t *Server) Import(file string, reply *string) error {
 	f, err := os.Open(file)
 	if err != nil {
@@ -329,7 +410,7 @@ func (t *Server) ImportConfiguration(arg *model.ImportDataDesc, reply *string) e
 		configurationReset()
 		result = configurationImport(context.Background(), content, arg.Stop)
 	} else {
-		result = configurationPartialImport(context.Background(), content)
+		result = configurationPartialImport(context.Background(), content, arg.Conflict)
 	}
 	marshal, _ := json.Marshal(result)
 
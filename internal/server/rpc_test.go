@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
@@ -83,6 +84,31 @@ func (suite *ServerTestSuite) TestStream() {
 	stopQuery()
 }
 
+func (suite *ServerTestSuite) TestTimedQuery() {
+	var reply string
+	_ = suite.s.Stream(`Create Stream timedQuery () WITH (FORMAT="JSON", type="simulator");`, &reply)
+	reply = ""
+	err := suite.s.CreateTimedQuery(&model.QueryDesc{Sql: "SELECT * FROM timedQuery", Timeout: 500 * time.Millisecond}, &reply)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "Query was submit successfully and will stop in 500ms.", reply)
+	var result string
+	require.NoError(suite.T(), suite.s.GetQueryResult("", &result))
+	require.Eventually(suite.T(), func() bool {
+		return suite.s.GetQueryResult("", &result) != nil
+	}, 3*time.Second, 100*time.Millisecond)
+
+	err = suite.s.CreateTimedQuery(&model.QueryDesc{Sql: "SELECT * FROM timedQuery", Timeout: 0}, &reply)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), "Query was submit successfully.", reply)
+	require.NoError(suite.T(), suite.s.StopQuery("", &reply))
+	require.Equal(suite.T(), "Query was stopped.", reply)
+	require.Error(suite.T(), suite.s.GetQueryResult("", &result))
+
+	err = suite.s.CreateTimedQuery(&model.QueryDesc{Sql: "SELECT * FROM notExist", Timeout: time.Second}, &reply)
+	require.Error(suite.T(), err)
+	_ = suite.s.Stream("DROP STREAM timedQuery", &reply)
+}
+
 func (suite *ServerTestSuite) TestRule() {
 	sql := `Create Stream test () WITH (DATASOURCE="../internal/server/rpc_test_data/test.json", FORMAT="JSON", type="file");`
 	var reply string
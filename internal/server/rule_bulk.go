@@ -0,0 +1,153 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/label"
+)
+
+// RuleResult is the result of an operation on a rule in a batch. Error is empty if succeeded.
+type RuleResult struct {
+	Id    string `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+func newRuleResult(id string, err error) *RuleResult {
+	r := &RuleResult{Id: id}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// RuleSelector selects the rules for the bulk operations. A rule is selected if it matches all the set conditions.
+type RuleSelector struct {
+	// Ids are the exact rule ids
+	Ids []string `json:"ids,omitempty"`
+	// Tags are the tags which the rule must all have
+	Tags []string `json:"tags,omitempty"`
+	// Pattern is the glob pattern of the rule id such as line1_*
+	Pattern string `json:"pattern,omitempty"`
+	// Labels is the label selector such as site=sh,line!=1
+	Labels string `json:"labels,omitempty"`
+}
+
+func (s *RuleSelector) validate() error {
+	if len(s.Ids) == 0 && len(s.Tags) == 0 && s.Pattern == "" && s.Labels == "" {
+		return fmt.Errorf("rule selector must have at least one of ids, tags, pattern and labels")
+	}
+	if s.Pattern != "" {
+		if _, err := path.Match(s.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %s: %v", s.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// SelectRules returns the sorted ids of the rules matched by the selector
+func (rr *RuleRegistry) SelectRules(s *RuleSelector) ([]string, error) {
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	selector, err := label.Parse(s.Labels)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := ruleProcessor.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0)
+	for _, id := range ids {
+		if len(s.Ids) > 0 && !slices.Contains(s.Ids, id) {
+			continue
+		}
+		if s.Pattern != "" {
+			if ok, _ := path.Match(s.Pattern, id); !ok {
+				continue
+			}
+		}
+		if len(s.Tags) > 0 || len(selector) > 0 {
+			r, err := ruleProcessor.GetRuleById(id)
+			if err != nil || !hasAllTags(r.Tags, s.Tags) || !selector.Matches(r.Labels) {
+				continue
+			}
+		}
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// BulkOperate runs the action on all the selected rules one by one. The failure of a rule does not stop the others.
+func (rr *RuleRegistry) BulkOperate(action string, s *RuleSelector) ([]*RuleResult, error) {
+	var op func(string) error
+	switch action {
+	case "start":
+		op = rr.StartRule
+	case "stop":
+		op = rr.StopRule
+	case "restart":
+		op = rr.RestartRule
+	case "delete":
+		op = rr.DeleteRule
+	default:
+		return nil, fmt.Errorf("unknown bulk action %s, expect start, stop, restart or delete", action)
+	}
+	ids, err := rr.SelectRules(s)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*RuleResult, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, newRuleResult(id, op(id)))
+	}
+	return results, nil
+}
+
+func hasAllTags(tags []string, required []string) bool {
+	for _, t := range required {
+		if !slices.Contains(tags, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// run the action on the rules selected by ids, tags, id pattern or labels
+func bulkRulesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	action := mux.Vars(r)["action"]
+	s := &RuleSelector{}
+	if err := json.NewDecoder(r.Body).Decode(s); err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	results, err := registry.BulkOperate(action, s)
+	if err != nil {
+		handleError(w, err, fmt.Sprintf("bulk %s rules error", action), logger)
+		return
+	}
+	jsonResponse(results, w, logger)
+}
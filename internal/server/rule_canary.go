@@ -0,0 +1,223 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// canaryRequest starts a canary with the new rule json
+type canaryRequest struct {
+	Rule   json.RawMessage `json:"rule"`
+	Weight int             `json:"weight"`
+	Shadow bool            `json:"shadow"`
+}
+
+// canaryStatus is the canary with the status of the rule and the canary rule to compare
+type canaryStatus struct {
+	*processor.RuleCanary
+	RuleStatus   map[string]any `json:"ruleStatus,omitempty"`
+	CanaryStatus map[string]any `json:"canaryStatus,omitempty"`
+}
+
+// StartCanary runs the new version of the rule as a canary rule alongside the rule. The canary processes the weight
+// percent of the events and the rule processes the rest. In shadow mode, the canary processes all the events with
+// its actions replaced by nop sinks, and the rule is not affected.
+func (rr *RuleRegistry) StartCanary(ruleId, ruleJson string, weight int, shadow bool) (*processor.RuleCanary, error) {
+	if _, ok := rr.load(ruleId); !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found in registry, please check if it is created", ruleId))
+	}
+	if _, err := ruleProcessor.GetCanary(ruleId); err == nil {
+		return nil, fmt.Errorf("rule %s already has a canary", ruleId)
+	}
+	if !shadow && (weight <= 0 || weight >= 100) {
+		return nil, fmt.Errorf("canary weight must be between 1 and 99 but got %d", weight)
+	}
+	r, err := ruleProcessor.GetRuleByJson(ruleId, ruleJson)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rule json: %v", err)
+	}
+	if r.Sql == "" {
+		return nil, fmt.Errorf("canary only supports sql rule")
+	}
+	c := &processor.RuleCanary{
+		RuleId:    ruleId,
+		CanaryId:  processor.CanaryId(ruleId),
+		Weight:    weight,
+		Shadow:    shadow,
+		CreatedAt: timex.GetNowInMilli(),
+		Rule:      ruleJson,
+	}
+	canaryJson, err := canaryRuleJson(c, len(r.Actions))
+	if err != nil {
+		return nil, err
+	}
+	// Save the canary before creating the rules so that the traffic split is applied when planning
+	if err := ruleProcessor.SaveCanary(c); err != nil {
+		return nil, err
+	}
+	if _, err := rr.CreateRule(c.CanaryId, canaryJson); err != nil {
+		_ = ruleProcessor.DeleteCanary(ruleId)
+		return nil, fmt.Errorf("create canary rule error: %v", err)
+	}
+	if !shadow {
+		if err := rr.reloadRule(ruleId); err != nil {
+			_ = rr.DeleteRule(c.CanaryId)
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// canaryRuleJson converts the new rule json to the canary rule json with the canary id
+func canaryRuleJson(c *processor.RuleCanary, actionCount int) (string, error) {
+	m := make(map[string]any)
+	if err := json.Unmarshal([]byte(c.Rule), &m); err != nil {
+		return "", fmt.Errorf("Invalid rule json: %v", err)
+	}
+	m["id"] = c.CanaryId
+	m["triggered"] = true
+	if c.Shadow {
+		actions := make([]map[string]any, 0, actionCount)
+		for i := 0; i < actionCount; i++ {
+			actions = append(actions, map[string]any{"nop": map[string]any{}})
+		}
+		m["actions"] = actions
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// PromoteCanary replaces the rule with the new version of the canary and removes the canary rule. The state of the
+// unchanged operators of the rule is kept.
+func (rr *RuleRegistry) PromoteCanary(ruleId string) error {
+	c, err := ruleProcessor.GetCanary(ruleId)
+	if err != nil {
+		return err
+	}
+	if err := ruleProcessor.DeleteCanary(ruleId); err != nil {
+		return err
+	}
+	if err := rr.DeleteRule(c.CanaryId); err != nil {
+		logger.Warnf("delete canary rule %s error: %v", c.CanaryId, err)
+	}
+	return rr.UpdateRule(ruleId, c.Rule)
+}
+
+// AbortCanary removes the canary rule and the rule processes all the events again
+func (rr *RuleRegistry) AbortCanary(ruleId string) error {
+	c, err := ruleProcessor.GetCanary(ruleId)
+	if err != nil {
+		return err
+	}
+	return rr.DeleteRule(c.CanaryId)
+}
+
+// cleanCanary removes the canary of the rule to be deleted. If the canary rule is deleted, the canary is aborted.
+func (rr *RuleRegistry) cleanCanary(name string) {
+	if c, err := ruleProcessor.GetCanary(name); err == nil {
+		_ = ruleProcessor.DeleteCanary(name)
+		if err := rr.DeleteRule(c.CanaryId); err != nil {
+			logger.Warnf("delete canary rule %s error: %v", c.CanaryId, err)
+		}
+	} else if c, ok := ruleProcessor.GetCanaryOf(name); ok {
+		_ = ruleProcessor.DeleteCanary(c.RuleId)
+		if !c.Shadow {
+			if err := rr.reloadRule(c.RuleId); err != nil {
+				logger.Warnf("reload rule %s after aborting canary error: %v", c.RuleId, err)
+			}
+		}
+	}
+}
+
+// reloadRule replans the rule with its current json to apply the runtime options such as the traffic split
+func (rr *RuleRegistry) reloadRule(ruleId string) error {
+	ruleJson, err := ruleProcessor.GetRuleJson(ruleId)
+	if err != nil {
+		return err
+	}
+	return rr.updateRule(ruleId, ruleJson, nil)
+}
+
+func (rr *RuleRegistry) GetCanaryStatus(ruleId string) (*canaryStatus, error) {
+	c, err := ruleProcessor.GetCanary(ruleId)
+	if err != nil {
+		return nil, err
+	}
+	result := &canaryStatus{RuleCanary: c}
+	result.RuleStatus, _ = rr.GetRuleStatusV2(ruleId)
+	result.CanaryStatus, _ = rr.GetRuleStatusV2(c.CanaryId)
+	return result, nil
+}
+
+// start a canary or get the canary status of a rule
+func ruleCanaryHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodGet:
+		status, err := registry.GetCanaryStatus(name)
+		if err != nil {
+			handleError(w, err, "get rule canary error", logger)
+			return
+		}
+		jsonResponse(status, w, logger)
+	case http.MethodPost:
+		defer r.Body.Close()
+		req := &canaryRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			handleError(w, err, "Invalid body: Error decoding the canary request", logger)
+			return
+		}
+		if _, err := registry.StartCanary(name, string(req.Rule), req.Weight, req.Shadow); err != nil {
+			handleError(w, err, "start rule canary error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, "Canary of rule %s was started.", name)
+	}
+}
+
+// promote or abort the canary of a rule
+func ruleCanaryActionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	var (
+		err  error
+		done string
+	)
+	switch vars["action"] {
+	case "promote":
+		err, done = registry.PromoteCanary(name), "promoted"
+	case "abort":
+		err, done = registry.AbortCanary(name), "aborted"
+	}
+	if err != nil {
+		handleError(w, err, "rule canary error", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "Canary of rule %s was %s.", name, done)
+}
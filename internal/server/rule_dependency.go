@@ -0,0 +1,317 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+const (
+	DependencyRule   = "rule"
+	DependencyStream = "stream"
+	DependencyTable  = "table"
+	DependencyTopic  = "topic"
+)
+
+// DependencyNode is a rule, stream, table or memory topic in the dependency graph.
+// The id is composed of the type and the name such as stream:demo to avoid the name conflicts between the types.
+type DependencyNode struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// DependencyEdge is the data flow from the producer to the consumer
+type DependencyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DependencyGraph describes who produces and consumes what among the rules, streams, tables and memory topics
+type DependencyGraph struct {
+	Nodes []*DependencyNode `json:"nodes"`
+	Edges []*DependencyEdge `json:"edges"`
+}
+
+type dependencyBuilder struct {
+	nodes map[string]*DependencyNode
+	edges map[DependencyEdge]struct{}
+	// the memory topics produced by the rules
+	produced map[string]struct{}
+	// the topic filters subscribed by the streams, tables and graph rules
+	subscribed []subscription
+}
+
+type subscription struct {
+	id     string
+	filter string
+}
+
+func dependencyId(typ, name string) string {
+	return typ + ":" + name
+}
+
+func (b *dependencyBuilder) addNode(typ, name string) string {
+	id := dependencyId(typ, name)
+	if _, ok := b.nodes[id]; !ok {
+		b.nodes[id] = &DependencyNode{Id: id, Type: typ, Name: name}
+	}
+	return id
+}
+
+func (b *dependencyBuilder) addEdge(from, to string) {
+	b.edges[DependencyEdge{From: from, To: to}] = struct{}{}
+}
+
+func (b *dependencyBuilder) addSource(st ast.StreamType, name string) {
+	typ := DependencyStream
+	if st == ast.TypeTable {
+		typ = DependencyTable
+	}
+	id := b.addNode(typ, name)
+	stmt, err := streamProcessor.DescStream(name, st)
+	if err != nil {
+		return
+	}
+	if s, ok := stmt.(*ast.StreamStmt); ok && strings.EqualFold(s.Options.TYPE, "memory") && s.Options.DATASOURCE != "" {
+		b.subscribed = append(b.subscribed, subscription{id: id, filter: s.Options.DATASOURCE})
+	}
+}
+
+func (b *dependencyBuilder) addRule(r *def.Rule) {
+	id := b.addNode(DependencyRule, r.Id)
+	if r.Graph != nil {
+		for _, gn := range r.Graph.Nodes {
+			switch gn.Type {
+			case "source":
+				sm := &def.SourceMeta{}
+				_ = cast.MapToStruct(gn.Props, sm)
+				if sm.SourceName != "" {
+					typ := DependencyStream
+					if sm.SourceType == "table" {
+						typ = DependencyTable
+					}
+					b.addEdge(b.addNode(typ, sm.SourceName), id)
+				} else if gn.NodeType == "memory" {
+					if topic, ok := gn.Props["datasource"].(string); ok && topic != "" {
+						b.subscribed = append(b.subscribed, subscription{id: id, filter: topic})
+					}
+				}
+			case "sink":
+				if gn.NodeType == "memory" {
+					b.addProducedTopic(id, gn.Props)
+				}
+			}
+		}
+		return
+	}
+	streams, err := planner.GetRuleStreams(r)
+	if err == nil {
+		for _, s := range streams {
+			typ := DependencyStream
+			if _, ok := b.nodes[dependencyId(DependencyTable, s)]; ok {
+				typ = DependencyTable
+			}
+			b.addEdge(b.addNode(typ, s), id)
+		}
+	}
+	for _, m := range r.Actions {
+		if props, ok := m["memory"].(map[string]any); ok {
+			b.addProducedTopic(id, props)
+		}
+	}
+}
+
+func (b *dependencyBuilder) addProducedTopic(ruleId string, props map[string]any) {
+	if topic, ok := props["topic"].(string); ok && topic != "" {
+		b.addEdge(ruleId, b.addNode(DependencyTopic, topic))
+		b.produced[topic] = struct{}{}
+	}
+}
+
+// link connects the produced topics to the subscribers. A wildcard filter which matches no produced topic is kept as a topic node.
+func (b *dependencyBuilder) link() {
+	for _, sub := range b.subscribed {
+		matched := false
+		for topic := range b.produced {
+			if topicMatch(sub.filter, topic) {
+				b.addEdge(dependencyId(DependencyTopic, topic), sub.id)
+				matched = true
+			}
+		}
+		if !matched {
+			b.addEdge(b.addNode(DependencyTopic, sub.filter), sub.id)
+		}
+	}
+}
+
+func (b *dependencyBuilder) graph() *DependencyGraph {
+	g := &DependencyGraph{
+		Nodes: make([]*DependencyNode, 0, len(b.nodes)),
+		Edges: make([]*DependencyEdge, 0, len(b.edges)),
+	}
+	for _, n := range b.nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	for e := range b.edges {
+		g.Edges = append(g.Edges, &DependencyEdge{From: e.From, To: e.To})
+	}
+	sortDependencyGraph(g)
+	return g
+}
+
+func sortDependencyGraph(g *DependencyGraph) {
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].Id < g.Nodes[j].Id
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+}
+
+// topicMatch checks if the memory topic matches the filter which may contain the wildcards + and #
+func topicMatch(filter, topic string) bool {
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tl) || (f != "+" && f != tl[i]) {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
+
+// GetDependencyGraph builds the dependency graph of all the rules, streams, tables and memory topics
+func (rr *RuleRegistry) GetDependencyGraph() (*DependencyGraph, error) {
+	ids, err := ruleProcessor.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*def.Rule, 0, len(ids))
+	for _, id := range ids {
+		r, err := ruleProcessor.GetRuleById(id)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return buildDependencyGraph(rules)
+}
+
+// buildDependencyGraph builds the dependency graph of the rules with all the streams, tables and memory topics
+func buildDependencyGraph(rules []*def.Rule) (*DependencyGraph, error) {
+	b := &dependencyBuilder{
+		nodes:    map[string]*DependencyNode{},
+		edges:    map[DependencyEdge]struct{}{},
+		produced: map[string]struct{}{},
+	}
+	for _, st := range []ast.StreamType{ast.TypeStream, ast.TypeTable} {
+		names, err := streamProcessor.ShowStream(st)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			b.addSource(st, name)
+		}
+	}
+	for _, r := range rules {
+		b.addRule(r)
+	}
+	b.link()
+	return b.graph(), nil
+}
+
+// GetDownstreamGraph returns the sub graph which is reachable from the rule, which includes everything affected by stopping the rule
+func (rr *RuleRegistry) GetDownstreamGraph(ruleId string) (*DependencyGraph, error) {
+	g, err := rr.GetDependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+	start := dependencyId(DependencyRule, ruleId)
+	reached := map[string]struct{}{}
+	for _, n := range g.Nodes {
+		if n.Id == start {
+			reached[start] = struct{}{}
+			break
+		}
+	}
+	if len(reached) == 0 {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found", ruleId))
+	}
+	next := map[string][]string{}
+	for _, e := range g.Edges {
+		next[e.From] = append(next[e.From], e.To)
+	}
+	queue := []string{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, to := range next[cur] {
+			if _, ok := reached[to]; !ok {
+				reached[to] = struct{}{}
+				queue = append(queue, to)
+			}
+		}
+	}
+	result := &DependencyGraph{
+		Nodes: make([]*DependencyNode, 0, len(reached)),
+		Edges: make([]*DependencyEdge, 0),
+	}
+	for _, n := range g.Nodes {
+		if _, ok := reached[n.Id]; ok {
+			result.Nodes = append(result.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if _, ok := reached[e.From]; ok {
+			result.Edges = append(result.Edges, e)
+		}
+	}
+	return result, nil
+}
+
+// show the dependency graph of all the rules or the downstream of a rule
+func dependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	var (
+		g   *DependencyGraph
+		err error
+	)
+	if ruleId := r.URL.Query().Get("rule"); ruleId != "" {
+		g, err = registry.GetDownstreamGraph(ruleId)
+	} else {
+		g, err = registry.GetDependencyGraph()
+	}
+	if err != nil {
+		handleError(w, err, "Get dependencies error", logger)
+		return
+	}
+	jsonResponse(g, w, logger)
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const (
+	RuleEventStatus  = "status"
+	RuleEventMetrics = "metrics"
+	RuleEventError   = "error"
+
+	defaultEventInterval = time.Second
+	minEventInterval     = 100 * time.Millisecond
+)
+
+// RuleEvent is an event pushed to the subscribers of the rule status stream
+type RuleEvent struct {
+	Type      string `json:"type"`
+	RuleId    string `json:"ruleId"`
+	Timestamp int64  `json:"timestamp"`
+	// Status and Message are set for the status event. The status is deleted if the rule is removed.
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Metrics are set for the metrics event. The counters are the increments since the last event and others are the latest values.
+	Metrics map[string]any `json:"metrics,omitempty"`
+	// Operator and Error are set for the error event
+	Operator string `json:"operator,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ruleEventWatcher polls the status of the rules and produces the events by comparing with the last snapshot
+type ruleEventWatcher struct {
+	ids  map[string]struct{}
+	last map[string]map[string]any
+}
+
+func newRuleEventWatcher(ids []string) *ruleEventWatcher {
+	w := &ruleEventWatcher{
+		last: map[string]map[string]any{},
+	}
+	if len(ids) > 0 {
+		w.ids = make(map[string]struct{}, len(ids))
+		for _, id := range ids {
+			w.ids[id] = struct{}{}
+		}
+	}
+	return w
+}
+
+func (w *ruleEventWatcher) poll() []*RuleEvent {
+	registry.RLock()
+	current := make(map[string]map[string]any, len(registry.internal))
+	for id, rs := range registry.internal {
+		if w.ids != nil {
+			if _, ok := w.ids[id]; !ok {
+				continue
+			}
+		}
+		current[id] = rs.GetStatusMap()
+	}
+	registry.RUnlock()
+
+	ts := timex.GetNowInMilli()
+	var events []*RuleEvent
+	for _, id := range slices.Sorted(maps.Keys(current)) {
+		events = append(events, diffRuleStatus(id, w.last[id], current[id], ts)...)
+	}
+	for _, id := range slices.Sorted(maps.Keys(w.last)) {
+		if _, ok := current[id]; !ok {
+			events = append(events, &RuleEvent{Type: RuleEventStatus, RuleId: id, Timestamp: ts, Status: "deleted"})
+		}
+	}
+	w.last = current
+	return events
+}
+
+// diffRuleStatus compares the status maps of a rule. The first snapshot only produces the status event as the baseline.
+func diffRuleStatus(id string, prev, cur map[string]any, ts int64) []*RuleEvent {
+	var events []*RuleEvent
+	if prev == nil || prev["status"] != cur["status"] {
+		msg, _ := cur["message"].(string)
+		status, _ := cur["status"].(string)
+		events = append(events, &RuleEvent{Type: RuleEventStatus, RuleId: id, Timestamp: ts, Status: status, Message: msg})
+	}
+	if prev == nil {
+		return events
+	}
+	metrics := make(map[string]any)
+	for k, v := range cur {
+		switch k {
+		case "status", "message", "lastStartTimestamp", "lastStopTimestamp", "nextStartTimestamp":
+			continue
+		}
+		pv, existed := prev[k]
+		if existed && reflect.DeepEqual(pv, v) {
+			continue
+		}
+		if strings.HasSuffix(k, "_"+metric.LastException) {
+			if e, ok := v.(string); ok && e != "" {
+				events = append(events, &RuleEvent{Type: RuleEventError, RuleId: id, Timestamp: ts, Operator: strings.TrimSuffix(k, "_"+metric.LastException), Error: e})
+			}
+			continue
+		}
+		if strings.HasSuffix(k, "_total") {
+			c, ok1 := v.(int64)
+			p, ok2 := pv.(int64)
+			// the counters are reset when the rule restarts
+			if ok1 && ok2 && c >= p {
+				metrics[k] = c - p
+				continue
+			}
+		}
+		metrics[k] = v
+	}
+	if len(metrics) > 0 {
+		events = append(events, &RuleEvent{Type: RuleEventMetrics, RuleId: id, Timestamp: ts, Metrics: metrics})
+	}
+	return events
+}
+
+// stream the rule status changes, the metrics deltas and the errors as server-sent events
+func ruleEventsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	interval := defaultEventInterval
+	if v := r.URL.Query().Get("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			handleError(w, fmt.Errorf("invalid interval %s: %v", v, err), "", logger)
+			return
+		}
+		if d < minEventInterval {
+			d = minEventInterval
+		}
+		interval = d
+	}
+	var ids []string
+	if v := r.URL.Query().Get("rules"); v != "" {
+		ids = strings.Split(v, ",")
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		handleError(w, fmt.Errorf("streaming is not supported"), "", logger)
+		return
+	}
+	// the event stream lives longer than the write timeout of the server
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set(ContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	watcher := newRuleEventWatcher(ids)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		events := watcher.poll()
+		for _, e := range events {
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRuleStatus(t *testing.T) {
+	prev := map[string]any{
+		"status":                          "running",
+		"message":                         "",
+		"lastStartTimestamp":              int64(1),
+		"source_demo_0_records_in_total":  int64(10),
+		"source_demo_0_buffer_length":     int64(0),
+		"op_project_0_last_exception":     "",
+		"op_project_0_records_out_total":  int64(5),
+		"sink_log_0_0_records_in_total":   int64(5),
+		"sink_log_0_0_process_latency_us": int64(20),
+	}
+	// the first snapshot is the baseline
+	events := diffRuleStatus("r1", nil, prev, 100)
+	require.Equal(t, []*RuleEvent{{Type: RuleEventStatus, RuleId: "r1", Timestamp: 100, Status: "running"}}, events)
+
+	cur := map[string]any{
+		"status":                          "running",
+		"message":                         "",
+		"lastStartTimestamp":              int64(1),
+		"source_demo_0_records_in_total":  int64(15),
+		"source_demo_0_buffer_length":     int64(2),
+		"op_project_0_last_exception":     "invalid field",
+		"op_project_0_records_out_total":  int64(5),
+		"sink_log_0_0_records_in_total":   int64(5),
+		"sink_log_0_0_process_latency_us": int64(20),
+	}
+	events = diffRuleStatus("r1", prev, cur, 200)
+	require.Equal(t, []*RuleEvent{
+		{Type: RuleEventError, RuleId: "r1", Timestamp: 200, Operator: "op_project_0", Error: "invalid field"},
+		{Type: RuleEventMetrics, RuleId: "r1", Timestamp: 200, Metrics: map[string]any{
+			"source_demo_0_records_in_total": int64(5),
+			"source_demo_0_buffer_length":    int64(2),
+		}},
+	}, events)
+
+	stopped := map[string]any{
+		"status":  "stopped by error",
+		"message": "connection lost",
+	}
+	events = diffRuleStatus("r1", cur, stopped, 300)
+	require.Equal(t, []*RuleEvent{{Type: RuleEventStatus, RuleId: "r1", Timestamp: 300, Status: "stopped by error", Message: "connection lost"}}, events)
+}
@@ -195,7 +195,21 @@ func handleScheduleRule(now time.Time, r *def.Rule) scheduleRuleAction {
 	if options == nil {
 		return scheduleRuleActionDoNothing
 	}
-	isInRange, err := schedule.IsInScheduleRanges(now, options.CronDatetimeRange)
+	loc, err := schedule.LoadLocation(options.CronTimezone)
+	if err != nil {
+		conf.Log.Errorf("check rule %v schedule failed, err:%v", r.Id, err)
+		return scheduleRuleActionDoNothing
+	}
+	now = now.In(loc)
+	isExcluded, err := schedule.IsExcluded(now, options.CronExclusions)
+	if err != nil {
+		conf.Log.Errorf("check rule %v schedule failed, err:%v", r.Id, err)
+		return scheduleRuleActionDoNothing
+	}
+	if isExcluded {
+		return scheduleRuleActionStop
+	}
+	isInRange, err := schedule.IsInScheduleRangesInLocation(now, options.CronDatetimeRange, loc)
 	if err != nil {
 		conf.Log.Errorf("check rule %v schedule failed, err:%v", r.Id, err)
 		return scheduleRuleActionDoNothing
@@ -203,6 +217,14 @@ func handleScheduleRule(now time.Time, r *def.Rule) scheduleRuleAction {
 	if !isInRange {
 		return scheduleRuleActionStop
 	}
+	isInTime, err := schedule.IsInTimeRanges(now, options.CronTimeRanges)
+	if err != nil {
+		conf.Log.Errorf("check rule %v schedule failed, err:%v", r.Id, err)
+		return scheduleRuleActionDoNothing
+	}
+	if !isInTime {
+		return scheduleRuleActionStop
+	}
 	if options.Cron == "" && options.Duration == "" {
 		return scheduleRuleActionStart
 	}
@@ -16,6 +16,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -23,7 +24,10 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/label"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/internal/topo"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
@@ -77,6 +81,13 @@ func (rr *RuleRegistry) update(id string, ruleJson string) error {
 	return err
 }
 
+func (rr *RuleRegistry) updateParams(id string, params map[string]any) error {
+	rr.Lock()
+	defer rr.Unlock()
+	_, err := ruleProcessor.ExecReplaceRuleParams(id, params)
+	return err
+}
+
 func (rr *RuleRegistry) updateTrigger(id string, trigger bool) error {
 	rr.Lock()
 	defer rr.Unlock()
@@ -162,6 +173,66 @@ func (rr *RuleRegistry) RecoverRule(r *def.Rule) string {
 
 // UpdateRule validates the new rule, then update the db, then restart the rule
 func (rr *RuleRegistry) UpdateRule(ruleId, ruleJson string) error {
+	return rr.updateRule(ruleId, ruleJson, nil)
+}
+
+// RollbackRule updates the rule to the json of a prior version. If restoreState is true, the rule restarts from the state
+// snapshot of that version.
+func (rr *RuleRegistry) RollbackRule(ruleId string, version int, restoreState bool) error {
+	rv, err := ruleProcessor.GetRuleVersion(ruleId, version)
+	if err != nil {
+		return err
+	}
+	var beforeStart func() error
+	if restoreState {
+		if !rv.HasState {
+			return fmt.Errorf("version %d of rule %s has no state snapshot", version, ruleId)
+		}
+		beforeStart = func() error {
+			return ruleProcessor.RestoreRuleState(ruleId, version)
+		}
+	}
+	return rr.updateRule(ruleId, rv.Rule, beforeStart)
+}
+
+// CreateSavepoint triggers a checkpoint of the running rule and keeps it as a savepoint. If the rule is not running,
+// the latest checkpoint is kept.
+func (rr *RuleRegistry) CreateSavepoint(name string) (*processor.Savepoint, error) {
+	rs, ok := registry.load(name)
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found in registry, please check if it is created", name))
+	}
+	if rs.Rule.Options == nil || rs.Rule.Options.Qos < def.AtLeastOnce {
+		return nil, fmt.Errorf("rule %s has no state to save, savepoint requires qos >= 1", name)
+	}
+	if rs.GetState() == rule.Running {
+		if _, err := rs.TriggerCheckpoint(savepointTimeout); err != nil {
+			return nil, fmt.Errorf("trigger checkpoint of rule %s error: %v", name, err)
+		}
+	}
+	return ruleProcessor.SaveSavepoint(name)
+}
+
+// RestoreSavepoint restarts the rule from the state of the savepoint. If ruleJson is not empty, the rule is updated to
+// it at the same time.
+func (rr *RuleRegistry) RestoreSavepoint(name string, spId int64, ruleJson string) error {
+	if _, err := ruleProcessor.GetSavepoint(name, spId); err != nil {
+		return err
+	}
+	if ruleJson == "" {
+		r, err := ruleProcessor.GetRuleJson(name)
+		if err != nil {
+			return err
+		}
+		ruleJson = r
+	}
+	return rr.updateRule(name, ruleJson, func() error {
+		return ruleProcessor.RestoreSavepoint(name, spId)
+	})
+}
+
+// updateRule replaces the rule json and reruns the rule. The beforeStart function runs after the old rule stops.
+func (rr *RuleRegistry) updateRule(ruleId, ruleJson string, beforeStart func() error) error {
 	ruleJson = replace.ReplaceRuleJson(ruleJson, conf.IsTesting)
 	// Validate the rule json
 	r, err := ruleProcessor.GetRuleByJson(ruleId, ruleJson)
@@ -186,6 +257,13 @@ func (rr *RuleRegistry) UpdateRule(ruleId, ruleJson string) error {
 	err1 := rr.update(r.Id, ruleJson)
 	// ReRun the rule
 	rs.Stop()
+	if beforeStart != nil {
+		if err := beforeStart(); err != nil {
+			err1 = errors.Join(err1, err)
+		}
+	} else {
+		migrateRuleState(oldRule, r, newTopo)
+	}
 	rs.WithTopo(newTopo)
 	if r.Triggered {
 		err2 := rs.Start()
@@ -198,7 +276,30 @@ func (rr *RuleRegistry) UpdateRule(ruleId, ruleJson string) error {
 	return err1
 }
 
+// migrateRuleState keeps the checkpointed state of the operators unchanged by the update such as the windows and the
+// analytic functions, and drops the state of the changed ones. Only the sql rules with checkpoint (qos >= 1) have state
+// to migrate.
+func migrateRuleState(oldRule, newRule *def.Rule, newTopo *topo.Topo) {
+	if oldRule.Sql == "" || newRule.Sql == "" || oldRule.Options.Qos < def.AtLeastOnce || newRule.Options.Qos < def.AtLeastOnce {
+		return
+	}
+	oldSigs, err := planner.OperatorSignatures(oldRule)
+	if err != nil {
+		logger.Warnf("rule %s drops the state for failing to plan the old version: %v", newRule.Id, err)
+		oldSigs = nil
+	}
+	kept, err := ruleProcessor.MigrateRuleState(newRule.Id, planner.MatchOperators(oldSigs, newTopo.GetOpSignatures()))
+	if err != nil {
+		logger.Warnf("rule %s migrate state error: %v", newRule.Id, err)
+		return
+	}
+	if len(kept) > 0 {
+		logger.Infof("rule %s keeps the state of operators %v after update", newRule.Id, kept)
+	}
+}
+
 func (rr *RuleRegistry) DeleteRule(name string) error {
+	rr.cleanCanary(name)
 	// lock registry and db. rs level has its own lock
 	rs, err := rr.delete(name)
 	if rs != nil {
@@ -207,6 +308,12 @@ func (rr *RuleRegistry) DeleteRule(name string) error {
 			logger.Errorf("delete rule %s error: %v", name, err)
 		}
 		deleteRuleMetrics(name)
+		if ruleTemplateProcessor != nil {
+			_ = ruleTemplateProcessor.DeleteInstance(name)
+		}
+		if namespaceProcessor != nil {
+			_ = namespaceProcessor.RemoveOwner(processor.ResourceRule, name)
+		}
 	}
 	return err
 }
@@ -250,6 +357,28 @@ func (rr *RuleRegistry) RestartRule(name string) error {
 	}
 }
 
+// UpdateRuleParams saves the new params to db and applies them to the rule without restarting it
+func (rr *RuleRegistry) UpdateRuleParams(name string, params map[string]any) error {
+	rs, ok := registry.load(name)
+	if !ok {
+		return errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found in registry, please check if it is created", name))
+	}
+	if err := rr.updateParams(name, params); err != nil {
+		return err
+	}
+	rs.UpdateParams(params)
+	return nil
+}
+
+// GetRuleParams returns the current params of the rule
+func (rr *RuleRegistry) GetRuleParams(name string) (map[string]any, error) {
+	rs, ok := registry.load(name)
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found in registry, please check if it is created", name))
+	}
+	return rs.GetParams(), nil
+}
+
 func (rr *RuleRegistry) GetAllRuleStatus() (string, error) {
 	rules, err := ruleProcessor.GetAllRules()
 	if err != nil {
@@ -268,15 +397,27 @@ func (rr *RuleRegistry) GetAllRuleStatus() (string, error) {
 }
 
 func (rr *RuleRegistry) GetAllRulesWithStatus() ([]map[string]any, error) {
+	return rr.GetRulesWithStatusByLabels(nil)
+}
+
+// GetRulesWithStatusByLabels returns the status of the rules whose labels match the selector
+func (rr *RuleRegistry) GetRulesWithStatusByLabels(selector label.Selector) ([]map[string]any, error) {
 	ruleIds, err := ruleProcessor.GetAllRules()
 	if err != nil {
 		return nil, err
 	}
 	sort.Strings(ruleIds)
-	result := make([]map[string]interface{}, len(ruleIds))
-	for i, id := range ruleIds {
+	result := make([]map[string]interface{}, 0, len(ruleIds))
+	for _, id := range ruleIds {
 		ruleName := id
 		ruleDef, _ := ruleProcessor.GetRuleById(id)
+		var labels map[string]string
+		if ruleDef != nil {
+			labels = ruleDef.Labels
+		}
+		if !selector.Matches(labels) {
+			continue
+		}
 		if ruleDef != nil && ruleDef.Name != "" {
 			ruleName = ruleDef.Name
 		}
@@ -294,12 +435,16 @@ func (rr *RuleRegistry) GetAllRulesWithStatus() ([]map[string]any, error) {
 				trace = rs.IsTraceEnabled()
 			}
 		}
-		result[i] = map[string]interface{}{
+		item := map[string]interface{}{
 			"id":     id,
 			"name":   ruleName,
 			"status": str,
 			"trace":  trace,
 		}
+		if len(labels) > 0 {
+			item["labels"] = labels
+		}
+		result = append(result, item)
 	}
 	return result, nil
 }
@@ -345,12 +490,14 @@ func (rr *RuleRegistry) ValidateRule(name, ruleJson string) ([]string, bool, err
 	}
 	var sources []string
 	if len(ruleDef.Sql) > 0 {
-		stmt, _ := xsql.GetStatementFromSql(ruleDef.Sql)
 		s, err := store.GetKV("stream")
 		if err != nil {
 			return nil, false, err
 		}
-		sources = xsql.GetStreams(stmt)
+		sources, err = planner.GetRuleStreams(ruleDef)
+		if err != nil {
+			return nil, false, err
+		}
 		for _, result := range sources {
 			_, err := xsql.GetDataSource(s, result)
 			if err != nil {
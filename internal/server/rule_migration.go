@@ -29,9 +29,11 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/processor"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/graph"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
 )
 
 type RuleMigrationProcessor struct {
@@ -83,7 +85,10 @@ func ruleTraverse(rule *def.Rule, de *dependencies) {
 			return
 		}
 		// streams
-		streamsFromStmt := xsql.GetStreams(stmt)
+		streamsFromStmt, err := planner.GetRuleStreams(rule)
+		if err != nil {
+			return
+		}
 		for _, s := range streamsFromStmt {
 			streamStmt, err := xsql.GetDataSource(store, s)
 			if err != nil {
@@ -111,8 +116,7 @@ func ruleTraverse(rule *def.Rule, de *dependencies) {
 
 			// get schema id
 			if streamStmt.Options.SCHEMAID != "" {
-				r := strings.Split(streamStmt.Options.SCHEMAID, ".")
-				de.schemas = append(de.schemas, streamStmt.Options.FORMAT+"_"+r[0])
+				de.schemas = append(de.schemas, schemaKey(streamStmt.Options.FORMAT, streamStmt.Options.SCHEMAID))
 			}
 		}
 		// actions
@@ -143,13 +147,21 @@ func ruleTraverse(rule *def.Rule, de *dependencies) {
 			}
 		}
 		// function
-		ast.WalkFunc(stmt, func(n ast.Node) bool {
-			switch f := n.(type) {
-			case *ast.Call:
-				de.functions = append(de.functions, f.Name)
+		stmts := []ast.Node{stmt}
+		for _, rs := range rule.Statements {
+			if s, err := xsql.GetStatementFromSql(rs.Sql); err == nil {
+				stmts = append(stmts, s)
 			}
-			return true
-		})
+		}
+		for _, s := range stmts {
+			ast.WalkFunc(s, func(n ast.Node) bool {
+				switch f := n.(type) {
+				case *ast.Call:
+					de.functions = append(de.functions, f.Name)
+				}
+				return true
+			})
+		}
 
 		// Rules
 		de.rules = append(de.rules, rule.Id)
@@ -176,8 +188,7 @@ func ruleTraverse(rule *def.Rule, de *dependencies) {
 				}
 				// get schema id
 				if sourceOption.SCHEMAID != "" {
-					r := strings.Split(sourceOption.SCHEMAID, ".")
-					de.schemas = append(de.schemas, sourceOption.FORMAT+"_"+r[0])
+					de.schemas = append(de.schemas, schemaKey(sourceOption.FORMAT, sourceOption.SCHEMAID))
 				}
 			case "sink":
 				sinkType := gn.NodeType
@@ -433,7 +444,7 @@ func (p *RuleMigrationProcessor) exportSelected(de *dependencies, config *Config
 		}
 	}
 
-	config.Uploads = uploadsExport()
+	config.Uploads = referencedUploads(config)
 }
 
 func parsePick(props map[string]interface{}) (*ast.SelectStatement, error) {
@@ -580,3 +591,16 @@ func parseJoin(props map[string]interface{}) (*ast.SelectStatement, error) {
 		return p, nil
 	}
 }
+
+// schemaKey returns the key of the schema in the schema store by the stream format and schema id.
+// The json format refers to the schema of jsonschema type and the can format refers to the dbc type.
+func schemaKey(format string, schemaId string) string {
+	r := strings.Split(schemaId, ".")
+	switch strings.ToLower(format) {
+	case message.FormatJson:
+		format = string(def.JSONSCHEMA)
+	case message.FormatCan:
+		format = string(def.DBC)
+	}
+	return format + "_" + r[0]
+}
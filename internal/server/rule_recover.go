@@ -0,0 +1,125 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+)
+
+// recoverRules restores the rules concurrently at startup. The rules are restored level by level by the memory topic
+// dependency. The consumer rules are in the lower level than the producer rules, so that a memory topic is subscribed
+// before the rules start to produce to it. The rules in the same level are restored with at most parallelism goroutines.
+func (rr *RuleRegistry) recoverRules(rules []*def.Rule, parallelism int) {
+	start := time.Now()
+	levels := recoverLevels(rules)
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	for _, level := range levels {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, parallelism)
+		for _, r := range level {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(r *def.Rule) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				if reply := rr.RecoverRule(r); reply != "" {
+					logger.Info(reply)
+				}
+			}(r)
+		}
+		wg.Wait()
+	}
+	logger.Infof("Restored %d rules in %d levels with parallelism %d in %v", len(rules), len(levels), parallelism, time.Since(start))
+}
+
+// recoverLevels groups the rules by the depth of their downstream rules through the memory topics.
+// The rules without downstream rules are in the first level. A dependency cycle is broken at the rule visited first.
+func recoverLevels(rules []*def.Rule) [][]*def.Rule {
+	downstream := map[string][]string{}
+	if g, err := buildDependencyGraph(rules); err != nil {
+		logger.Warnf("Build the rule dependency error, restore the rules without order: %v", err)
+	} else {
+		downstream = downstreamRules(g)
+	}
+	depths := make(map[string]int, len(rules))
+	visiting := make(map[string]bool)
+	var depth func(id string) int
+	depth = func(id string) int {
+		if d, ok := depths[id]; ok {
+			return d
+		}
+		// Break the cycle
+		if visiting[id] {
+			return 0
+		}
+		visiting[id] = true
+		d := 0
+		for _, next := range downstream[id] {
+			if nd := depth(next) + 1; nd > d {
+				d = nd
+			}
+		}
+		delete(visiting, id)
+		depths[id] = d
+		return d
+	}
+	var levels [][]*def.Rule
+	for _, r := range rules {
+		d := depth(r.Id)
+		for len(levels) <= d {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], r)
+	}
+	return levels
+}
+
+// downstreamRules returns the rules which consume the data produced by each rule through the streams and topics
+func downstreamRules(g *DependencyGraph) map[string][]string {
+	next := map[string][]string{}
+	for _, e := range g.Edges {
+		next[e.From] = append(next[e.From], e.To)
+	}
+	result := map[string][]string{}
+	for _, n := range g.Nodes {
+		if n.Type != DependencyRule {
+			continue
+		}
+		reached := map[string]bool{n.Id: true}
+		queue := next[n.Id]
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			if reached[cur] {
+				continue
+			}
+			reached[cur] = true
+			if id, ok := strings.CutPrefix(cur, dependencyId(DependencyRule, "")); ok {
+				result[n.Name] = append(result[n.Name], id)
+				continue
+			}
+			queue = append(queue, next[cur]...)
+		}
+	}
+	return result
+}
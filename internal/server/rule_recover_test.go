@@ -0,0 +1,70 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestRecoverLevels(t *testing.T) {
+	streams := map[string]string{
+		"recoverIn":  `CREATE STREAM recoverIn() WITH (DATASOURCE="in", FORMAT="json")`,
+		"recoverMid": `CREATE STREAM recoverMid() WITH (DATASOURCE="recover/+", TYPE="memory", FORMAT="json")`,
+		"recoverOut": `CREATE STREAM recoverOut() WITH (DATASOURCE="recoverOut", TYPE="memory", FORMAT="json")`,
+	}
+	for name, sql := range streams {
+		_, _ = streamProcessor.DropStream(name, ast.TypeStream)
+		_, err := streamProcessor.ExecStmt(sql)
+		require.NoError(t, err)
+		defer func(name string) {
+			_, _ = streamProcessor.DropStream(name, ast.TypeStream)
+		}(name)
+	}
+	memorySink := func(topic string) []map[string]any {
+		return []map[string]any{{"memory": map[string]any{"topic": topic}}}
+	}
+	// The chain is head -> recover/a -> mid -> recoverOut -> tail
+	head := &def.Rule{Id: "recoverHead", Sql: "SELECT * FROM recoverIn", Actions: memorySink("recover/a")}
+	mid := &def.Rule{Id: "recoverMid", Sql: "SELECT * FROM recoverMid", Actions: memorySink("recoverOut")}
+	tail := &def.Rule{Id: "recoverTail", Sql: "SELECT * FROM recoverOut", Actions: []map[string]any{{"log": map[string]any{}}}}
+	alone := &def.Rule{Id: "recoverAlone", Sql: "SELECT * FROM recoverIn", Actions: []map[string]any{{"log": map[string]any{}}}}
+	// The rule reading the topic produced by itself does not depend on itself
+	loop := &def.Rule{Id: "recoverLoop", Sql: "SELECT * FROM recoverMid", Actions: memorySink("recover/b")}
+
+	levels := recoverLevels([]*def.Rule{head, mid, tail, alone, loop})
+	assert.Equal(t, [][]*def.Rule{{tail, alone}, {mid}, {loop}, {head}}, levels)
+}
+
+func TestRecoverRules(t *testing.T) {
+	var rules []*def.Rule
+	for _, id := range []string{"recover1", "recover2", "recover3"} {
+		r := def.GetDefaultRule(id, "SELECT * FROM demo")
+		r.Triggered = false
+		rules = append(rules, r)
+	}
+	registry.recoverRules(rules, 2)
+	for _, r := range rules {
+		rs, ok := registry.load(r.Id)
+		require.True(t, ok)
+		assert.Equal(t, r.Id, rs.Rule.Id)
+		_, _ = registry.delete(r.Id)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// savepointTimeout is the max time to wait for the triggered checkpoint to complete
+const savepointTimeout = time.Minute
+
+// list the savepoints of a rule or trigger a new one
+func ruleSavepointsHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodGet:
+		savepoints, err := ruleProcessor.GetSavepoints(name)
+		if err != nil {
+			handleError(w, err, "get rule savepoints error", logger)
+			return
+		}
+		jsonResponse(savepoints, w, logger)
+	case http.MethodPost:
+		sp, err := registry.CreateSavepoint(name)
+		if err != nil {
+			handleError(w, err, "create rule savepoint error", logger)
+			return
+		}
+		jsonResponse(sp, w, logger)
+	}
+}
+
+// delete a savepoint of a rule
+func ruleSavepointHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	id, _ := strconv.ParseInt(vars["id"], 10, 64)
+	if err := ruleProcessor.DeleteSavepoint(name, id); err != nil {
+		handleError(w, err, "delete rule savepoint error", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "Savepoint %d of rule %s is deleted.", id, name)
+}
+
+// restart a rule from a savepoint. If the body is set, the rule is updated to the new rule json at the same time.
+func ruleSavepointRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	vars := mux.Vars(r)
+	name := vars["name"]
+	id, _ := strconv.ParseInt(vars["id"], 10, 64)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		handleError(w, err, "Invalid body", logger)
+		return
+	}
+	if err := registry.RestoreSavepoint(name, id, string(body)); err != nil {
+		handleError(w, err, "restore rule savepoint error", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "Rule %s was restored from savepoint %d successfully.", name, id)
+}
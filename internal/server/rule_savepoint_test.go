@@ -0,0 +1,124 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func (suite *RestTestSuite) TestRuleSavepoints() {
+	_ = ruleProcessor.ExecDrop("spRule")
+	// Drop the state left by the previous runs, the table must be opened to drop
+	_, _ = store.GetStateTS("spRule")
+	_ = store.DropStateTS("spRule")
+	_, _ = streamProcessor.DropStream("demoSp", ast.TypeStream)
+	buf1 := bytes.NewBuffer([]byte(`{"sql":"CREATE stream demoSp() WITH (DATASOURCE=\"0\", TYPE=\"mqtt\")"}`))
+	req1, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/streams", buf1)
+	w1 := httptest.NewRecorder()
+	suite.r.ServeHTTP(w1, req1)
+	defer func() {
+		_, _ = streamProcessor.DropStream("demoSp", ast.TypeStream)
+	}()
+
+	ruleJson := `{"id":"spRule","triggered":false,"sql":"select count(*) from demoSp group by tumblingwindow(ss, 10)","actions":[{"log":{}}],"options":{"qos":1}}`
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/rules", bytes.NewBufferString(ruleJson))
+	w := httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusCreated, w.Code, w.Body.String())
+
+	// No checkpoint to save
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/spRule/savepoints", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusBadRequest, w.Code, w.Body.String())
+
+	ts, err := store.GetStateTS("spRule")
+	require.NoError(suite.T(), err)
+	saved := map[string]any{
+		"demoSp":   map[string]any{"offset": "1"},
+		"3_window": map[string]any{"buffer": "w1"},
+	}
+	_, err = ts.Set(1000, saved)
+	require.NoError(suite.T(), err)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/spRule/savepoints", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	sp := &processor.Savepoint{}
+	require.NoError(suite.T(), json.NewDecoder(w.Body).Decode(sp))
+	require.Equal(suite.T(), int64(1000), sp.Id)
+	require.Equal(suite.T(), []string{"3_window", "demoSp"}, sp.Operators)
+
+	// The later checkpoints do not change the savepoint
+	_, err = ts.Set(2000, map[string]any{
+		"demoSp":   map[string]any{"offset": "2"},
+		"3_window": map[string]any{"buffer": "w2"},
+	})
+	require.NoError(suite.T(), err)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:8080/rules/spRule/savepoints", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	var savepoints []*processor.Savepoint
+	require.NoError(suite.T(), json.NewDecoder(w.Body).Decode(&savepoints))
+	require.Len(suite.T(), savepoints, 1)
+	require.Equal(suite.T(), int64(1000), savepoints[0].Id)
+
+	// Restore and update the rule
+	updated := `{"id":"spRule","triggered":false,"sql":"select count(*) as c from demoSp group by tumblingwindow(ss, 10)","actions":[{"log":{}}],"options":{"qos":1}}`
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/spRule/savepoints/1000/restore", bytes.NewBufferString(updated))
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	r, err := ruleProcessor.GetRuleJson("spRule")
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), updated, r)
+	ts, err = store.GetStateTS("spRule")
+	require.NoError(suite.T(), err)
+	var m map[string]any
+	_, err = ts.Last(&m)
+	require.NoError(suite.T(), err)
+	require.Equal(suite.T(), saved, m)
+
+	req, _ = http.NewRequest(http.MethodPost, "http://localhost:8080/rules/spRule/savepoints/9/restore", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/rules/spRule/savepoints/1000", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/rules/spRule/savepoints/1000", nil)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost:8080/rules/spRule", http.NoBody)
+	w = httptest.NewRecorder()
+	suite.r.ServeHTTP(w, req)
+	require.Equal(suite.T(), http.StatusOK, w.Code)
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/v2/internal/trial"
+)
+
+// runTestSpecs runs the rule test specs in order. The spec referring to an existing rule is filled with its sql and
+// options.
+func runTestSpecs(content []byte) ([]*trial.SpecResult, error) {
+	specs, err := trial.ParseTestSpecs(content)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*trial.SpecResult, 0, len(specs))
+	for _, spec := range specs {
+		if spec.Rule != "" && spec.Sql == "" {
+			r, err := ruleProcessor.GetRuleById(spec.Rule)
+			if err != nil {
+				return nil, fmt.Errorf("spec %s: %v", spec.Name, err)
+			}
+			if r.Sql == "" {
+				return nil, fmt.Errorf("spec %s: rule %s is not defined by sql", spec.Name, spec.Rule)
+			}
+			spec.Sql = r.Sql
+			if spec.Options == nil {
+				spec.Options = r.Options
+			}
+		}
+		result, err := trial.RunTestSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -18,12 +18,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/failpoint"
 
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/rule"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 )
 
 type UpdateRuleStateType int
@@ -33,6 +37,14 @@ const (
 	UpdateRuleOffset
 )
 
+const (
+	// The default and max entries of each operator in the state inspection
+	defaultStateLimit = 20
+	maxStateLimit     = 1000
+	// The time to wait for the operators to summarize the state
+	stateInspectTimeout = 3 * time.Second
+)
+
 func ruleStateHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	vars := mux.Vars(r)
@@ -102,3 +114,36 @@ func updateRuleOffset(ruleID string, param map[string]interface{}) error {
 	}
 	return rs.ResetStreamOffset(req.StreamName, req.Input)
 }
+
+// InspectRuleState summarizes the state of the operators of a running rule
+func (rr *RuleRegistry) InspectRuleState(name, op string, limit int) ([]*node.StateSummary, error) {
+	rs, ok := rr.load(name)
+	if !ok {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("Rule %s is not found", name))
+	}
+	return rs.InspectState(op, limit, stateInspectTimeout)
+}
+
+// dump the state summary of the operators of a running rule
+func inspectRuleStateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	limit := defaultStateLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			handleError(w, fmt.Errorf("invalid limit %s", l), "inspect rule state error", logger)
+			return
+		}
+		if limit > maxStateLimit {
+			limit = maxStateLimit
+		}
+	}
+	result, err := registry.InspectRuleState(name, r.URL.Query().Get("op"), limit)
+	if err != nil {
+		handleError(w, err, "inspect rule state error", logger)
+		return
+	}
+	jsonResponse(result, w, logger)
+}
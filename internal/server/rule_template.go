@@ -0,0 +1,179 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+)
+
+// InstantiateTemplate creates the rules of the instances from the template. If the rule is already an instance of the
+// template, it is updated with the new params.
+func (rr *RuleRegistry) InstantiateTemplate(templateId string, instances []*processor.TemplateInstance) ([]*RuleResult, error) {
+	t, err := ruleTemplateProcessor.Get(templateId)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*RuleResult, 0, len(instances))
+	for _, inst := range instances {
+		inst.Template = templateId
+		results = append(results, newRuleResult(inst.Id, rr.instantiate(t, inst)))
+	}
+	return results, nil
+}
+
+// UpdateTemplate saves the template and re-instantiates all its rules with their params
+func (rr *RuleRegistry) UpdateTemplate(templateId, content string) ([]*RuleResult, error) {
+	t, err := ruleTemplateProcessor.Update(templateId, content)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := ruleTemplateProcessor.GetInstances(templateId)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*RuleResult, 0, len(instances))
+	for _, inst := range instances {
+		results = append(results, newRuleResult(inst.Id, rr.instantiate(t, inst)))
+	}
+	return results, nil
+}
+
+func (rr *RuleRegistry) instantiate(t *processor.RuleTemplate, inst *processor.TemplateInstance) error {
+	if inst.Id == "" {
+		return fmt.Errorf("missing rule id")
+	}
+	ruleJson, err := t.Render(inst)
+	if err != nil {
+		return err
+	}
+	if _, ok := rr.load(inst.Id); ok {
+		old, err := ruleTemplateProcessor.GetInstance(inst.Id)
+		if err != nil {
+			return err
+		}
+		if old == nil || old.Template != t.Id {
+			return fmt.Errorf("rule %s already exists and is not an instance of template %s", inst.Id, t.Id)
+		}
+		err = rr.UpdateRule(inst.Id, ruleJson)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err = rr.CreateRule(inst.Id, ruleJson)
+		if err != nil {
+			return err
+		}
+	}
+	return ruleTemplateProcessor.SaveInstance(inst)
+}
+
+// list or create rule templates
+func ruleTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	switch r.Method {
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		t, err := ruleTemplateProcessor.Create(string(body))
+		if err != nil {
+			handleError(w, err, "create rule template error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, "Rule template %s was created successfully.", t.Id)
+	case http.MethodGet:
+		content, err := ruleTemplateProcessor.List()
+		if err != nil {
+			handleError(w, err, "list rule templates error", logger)
+			return
+		}
+		listResponse(content, w, r, logger)
+	}
+}
+
+// describe, update or delete a rule template
+func ruleTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodGet:
+		t, err := ruleTemplateProcessor.Get(name)
+		if err != nil {
+			handleError(w, err, "describe rule template error", logger)
+			return
+		}
+		jsonResponse(t, w, logger)
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		results, err := registry.UpdateTemplate(name, string(body))
+		if err != nil {
+			handleError(w, err, "update rule template error", logger)
+			return
+		}
+		jsonResponse(results, w, logger)
+	case http.MethodDelete:
+		if err := ruleTemplateProcessor.Delete(name); err != nil {
+			handleError(w, err, "delete rule template error", logger)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(w, "Rule template %s was deleted.", name)
+	}
+}
+
+// list the instances of a rule template or instantiate rules from it
+func ruleTemplateInstancesHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodGet:
+		if _, err := ruleTemplateProcessor.Get(name); err != nil {
+			handleError(w, err, "list rule template instances error", logger)
+			return
+		}
+		instances, err := ruleTemplateProcessor.GetInstances(name)
+		if err != nil {
+			handleError(w, err, "list rule template instances error", logger)
+			return
+		}
+		jsonResponse(instances, w, logger)
+	case http.MethodPost:
+		var instances []*processor.TemplateInstance
+		if err := json.NewDecoder(r.Body).Decode(&instances); err != nil {
+			handleError(w, err, "Invalid body", logger)
+			return
+		}
+		results, err := registry.InstantiateTemplate(name, instances)
+		if err != nil {
+			handleError(w, err, "instantiate rule template error", logger)
+			return
+		}
+		jsonResponse(results, w, logger)
+	}
+}
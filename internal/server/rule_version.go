@@ -0,0 +1,84 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// list the versions of a rule
+func ruleVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	versions, err := ruleProcessor.GetRuleVersions(name)
+	if err != nil {
+		handleError(w, err, "get rule versions error", logger)
+		return
+	}
+	jsonResponse(versions, w, logger)
+}
+
+// describe a version of a rule
+func ruleVersionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	version, _ := strconv.Atoi(vars["version"])
+	rv, err := ruleProcessor.GetRuleVersion(vars["name"], version)
+	if err != nil {
+		handleError(w, err, "describe rule version error", logger)
+		return
+	}
+	jsonResponse(rv, w, logger)
+}
+
+// diff two versions of a rule. If the to version is not set, compare with the current rule
+func ruleVersionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		handleError(w, fmt.Errorf("invalid from version: %v", err), "diff rule versions error", logger)
+		return
+	}
+	to := 0
+	if t := r.URL.Query().Get("to"); t != "" {
+		to, err = strconv.Atoi(t)
+		if err != nil {
+			handleError(w, fmt.Errorf("invalid to version: %v", err), "diff rule versions error", logger)
+			return
+		}
+	}
+	diffs, err := ruleProcessor.DiffRuleVersions(name, from, to)
+	if err != nil {
+		handleError(w, err, "diff rule versions error", logger)
+		return
+	}
+	jsonResponse(diffs, w, logger)
+}
+
+// roll back a rule to a prior version
+func ruleRollbackHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	version, _ := strconv.Atoi(vars["version"])
+	restoreState, _ := strconv.ParseBool(r.URL.Query().Get("restoreState"))
+	if err := registry.RollbackRule(name, version, restoreState); err != nil {
+		handleError(w, err, "rollback rule error", logger)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "Rule %s was rolled back to version %d successfully.", name, version)
+}
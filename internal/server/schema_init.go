@@ -64,7 +64,7 @@ func schemasHandler(w http.ResponseWriter, r *http.Request) {
 			handleError(w, err, "", logger)
 			return
 		}
-		jsonResponse(l, w, logger)
+		listResponse(l, w, r, logger)
 	case http.MethodPost:
 		sch := &schema.Info{Type: def.SchemaType(st)}
 		err := json.NewDecoder(r.Body).Decode(sch)
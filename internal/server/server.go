@@ -38,6 +38,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/keyedstate"
 	meta2 "github.com/lf-edge/ekuiper/v2/internal/meta"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/async"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/sig"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/definition"
@@ -48,6 +49,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/metrics"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
+	"github.com/lf-edge/ekuiper/v2/pkg/memory"
 	"github.com/lf-edge/ekuiper/v2/pkg/modules"
 	"github.com/lf-edge/ekuiper/v2/pkg/tracer"
 )
@@ -61,6 +63,8 @@ var (
 	streamProcessor        *processor.StreamProcessor
 	rulesetProcessor       *processor.RulesetProcessor
 	ruleMigrationProcessor *RuleMigrationProcessor
+	ruleTemplateProcessor  *processor.RuleTemplateProcessor
+	namespaceProcessor     *processor.NamespaceProcessor
 	stopSignal             chan struct{}
 	cpuProfiler            = &ekuiperProfile{}
 )
@@ -117,6 +121,7 @@ func getStoreConfigByKuiperConfig(c *conf.KuiperConf) (*store.StoreConf, error)
 	sc := &store.StoreConf{
 		Type:         c.Store.Type,
 		ExtStateType: c.Store.ExtStateType,
+		StateType:    c.Store.StateType,
 		RedisConfig: definition.RedisConfig{
 			Host:     c.Store.Redis.Host,
 			Port:     c.Store.Redis.Port,
@@ -130,6 +135,13 @@ func getStoreConfigByKuiperConfig(c *conf.KuiperConf) (*store.StoreConf, error)
 		FdbConfig: definition.FdbConfig{
 			Path: c.Store.Fdb.Path,
 		},
+		PebbleConfig: definition.PebbleConfig{
+			Path:                     dataDir,
+			MemTableSize:             c.Store.Pebble.MemTableSize,
+			BlockCacheSize:           c.Store.Pebble.BlockCacheSize,
+			MaxConcurrentCompactions: c.Store.Pebble.MaxConcurrentCompactions,
+			CompactionInterval:       time.Duration(c.Store.Pebble.CompactionInterval),
+		},
 	}
 	return sc, nil
 }
@@ -167,6 +179,9 @@ func StartUp(Version string) {
 	if err != nil {
 		panic(err)
 	}
+	if err := applyRestore(); err != nil {
+		panic(err)
+	}
 	err = store.SetupWithConfig(sc)
 	if err != nil {
 		panic(err)
@@ -192,6 +207,11 @@ func StartUp(Version string) {
 	streamProcessor = processor.NewStreamProcessor()
 	rulesetProcessor = processor.NewRulesetProcessor(ruleProcessor, streamProcessor)
 	ruleMigrationProcessor = NewRuleMigrationProcessor(ruleProcessor, streamProcessor)
+	ruleTemplateProcessor = processor.NewRuleTemplateProcessor()
+	namespaceProcessor = processor.NewNamespaceProcessor()
+	if err := initAuditLog(); err != nil {
+		panic(err)
+	}
 	sysMetrics = NewMetrics()
 
 	// register all extensions
@@ -227,21 +247,25 @@ func StartUp(Version string) {
 		logger.Infof("Start rules error: %s", err)
 	} else {
 		logger.Info("Starting rules")
-		var reply string
+		defs := make([]*def.Rule, 0, len(rules))
 		for _, name := range rules {
 			rule, err := ruleProcessor.GetRuleById(name)
 			if err != nil {
 				logger.Error(err)
 				continue
 			}
-			reply = registry.RecoverRule(rule)
-			if 0 != len(reply) {
-				logger.Info(reply)
-			}
+			defs = append(defs, rule)
 		}
+		registry.recoverRules(defs, conf.Config.Basic.RuleRestoreParallelism)
 	}
 	go runScheduleRuleChecker(serverCtx)
 	metrics.InitMetricsDumpJob(serverCtx)
+	if err := initGitOps(serverCtx); err != nil {
+		logger.Errorf("init gitops error: %v", err)
+	}
+	if mc := conf.Config.Basic.Memory; mc.Limit > 0 {
+		memory.NewManager(mc.Limit, mc.HighWatermark, mc.LowWatermark, time.Duration(mc.MaxPause)).Start(serverCtx, time.Duration(mc.CheckInterval))
+	}
 	async.InitManager()
 
 	// Start rest service
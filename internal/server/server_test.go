@@ -156,6 +156,60 @@ func TestHandleScheduleRule(t *testing.T) {
 			},
 			action: scheduleRuleActionDoNothing,
 		},
+		{
+			Options: &def.RuleOption{
+				Cron:         "4 23 * * *",
+				Duration:     "10s",
+				CronTimezone: "Asia/Shanghai",
+			},
+			action: scheduleRuleActionStart,
+		},
+		{
+			Options: &def.RuleOption{
+				CronTimeRanges: []schedule.TimeRange{
+					{Begin: "08:00", End: "17:00", Weekdays: []int{1, 2, 3, 4, 5}},
+				},
+			},
+			action: scheduleRuleActionStart,
+		},
+		{
+			Options: &def.RuleOption{
+				CronTimeRanges: []schedule.TimeRange{
+					{Begin: "08:00", End: "17:00", Weekdays: []int{0, 6}},
+				},
+			},
+			action: scheduleRuleActionStop,
+		},
+		{
+			Options: &def.RuleOption{
+				CronTimezone: "Asia/Shanghai",
+				CronTimeRanges: []schedule.TimeRange{
+					{Begin: "08:00", End: "12:00"},
+					{Begin: "22:00", End: "06:00"},
+				},
+			},
+			action: scheduleRuleActionStart,
+		},
+		{
+			Options: &def.RuleOption{
+				CronExclusions: []string{"2006-01-02"},
+			},
+			action: scheduleRuleActionStop,
+		},
+		{
+			Options: &def.RuleOption{
+				CronTimezone:   "Asia/Tokyo",
+				CronExclusions: []string{"2006-01-02"},
+			},
+			action: scheduleRuleActionStart,
+		},
+		{
+			Options: &def.RuleOption{
+				CronTimezone:   "Not/Exist",
+				CronExclusions: []string{"2006-01-02"},
+			},
+			action: scheduleRuleActionDoNothing,
+		},
 	}
 	for i, tc := range testcases {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
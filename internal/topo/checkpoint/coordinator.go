@@ -15,6 +15,8 @@
 package checkpoint
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -92,6 +94,7 @@ type Coordinator struct {
 	tasksToTrigger          []Responder
 	tasksToWaitFor          []Responder
 	sinkTasks               []SinkTask
+	operators               []NonSourceTask
 	pendingCheckpoints      *sync.Map
 	completedCheckpoints    *checkpointStore
 	ruleId                  string
@@ -103,6 +106,16 @@ type Coordinator struct {
 	store                   api.Store
 	ctx                     api.StreamContext
 	activated               bool
+	// manual receives the requests to trigger a checkpoint immediately, the waiters are replied when it is completed
+	manual        chan chan checkpointResult
+	waiters       map[int64]chan checkpointResult
+	waitersMu     sync.Mutex
+	lastTriggered int64
+}
+
+type checkpointResult struct {
+	checkpointId int64
+	err          error
 }
 
 func NewCoordinator(ruleId string, sources []StreamTask, operators []NonSourceTask, sinks []SinkTask, qos def.Qos, store api.Store, interval time.Duration, ctx api.StreamContext) *Coordinator {
@@ -138,6 +151,7 @@ func NewCoordinator(ruleId string, sources []StreamTask, operators []NonSourceTa
 		tasksToTrigger:     sourceResponders,
 		tasksToWaitFor:     allResponders,
 		sinkTasks:          sinks,
+		operators:          operators,
 		pendingCheckpoints: new(sync.Map),
 		completedCheckpoints: &checkpointStore{
 			maxNum: 3,
@@ -148,6 +162,8 @@ func NewCoordinator(ruleId string, sources []StreamTask, operators []NonSourceTa
 		store:          store,
 		ctx:            ctx,
 		cleanThreshold: 100,
+		manual:         make(chan chan checkpointResult),
+		waiters:        make(map[int64]chan checkpointResult),
 	}
 }
 
@@ -182,24 +198,16 @@ func (c *Coordinator) Activate() error {
 					// TODO Check if all tasks are running
 
 					// Create a pending checkpoint
-					checkpointId := cast.TimeToUnixMilli(n)
-					checkpoint := newPendingCheckpoint(checkpointId, c.tasksToWaitFor)
-					logger.Debugf("Create checkpoint %d", checkpointId)
-					c.pendingCheckpoints.Store(checkpointId, checkpoint)
-					// Let the sources send out a barrier
-					for _, r := range c.tasksToTrigger {
-						go func(t Responder) {
-							if err := t.TriggerCheckpoint(checkpointId); err != nil {
-								logger.Infof("Fail to trigger checkpoint for source %s with error %v, cancel it", t.GetName(), err)
-								c.cancel(checkpointId)
-							}
-						}(r)
-					}
+					c.trigger(cast.TimeToUnixMilli(n))
 					toBeClean++
 					if toBeClean >= c.cleanThreshold {
 						c.store.Clean()
 						toBeClean = 0
 					}
+				case w := <-c.manual:
+					c.waitersMu.Lock()
+					c.waiters[c.trigger(timex.GetNowInMilli())] = w
+					c.waitersMu.Unlock()
 				case s := <-c.signal:
 					switch s.Message {
 					case STOP:
@@ -238,6 +246,63 @@ func (c *Coordinator) Activate() error {
 	return nil
 }
 
+// trigger creates a pending checkpoint and lets the sources send out the barrier. It returns the checkpoint id which
+// is increasing even if the clock goes back.
+func (c *Coordinator) trigger(checkpointId int64) int64 {
+	logger := c.ctx.GetLogger()
+	if checkpointId <= c.lastTriggered {
+		checkpointId = c.lastTriggered + 1
+	}
+	c.lastTriggered = checkpointId
+	checkpoint := newPendingCheckpoint(checkpointId, c.tasksToWaitFor)
+	logger.Debugf("Create checkpoint %d", checkpointId)
+	c.pendingCheckpoints.Store(checkpointId, checkpoint)
+	// Let the sources send out a barrier
+	for _, r := range c.tasksToTrigger {
+		go func(t Responder) {
+			if err := t.TriggerCheckpoint(checkpointId); err != nil {
+				logger.Infof("Fail to trigger checkpoint for source %s with error %v, cancel it", t.GetName(), err)
+				c.cancel(checkpointId)
+			}
+		}(r)
+	}
+	return checkpointId
+}
+
+// TriggerCheckpoint triggers a checkpoint immediately and waits until it is completed. It returns the id of the
+// completed checkpoint which may be a later one if the triggered one is superseded.
+func (c *Coordinator) TriggerCheckpoint(timeout time.Duration) (int64, error) {
+	if !c.activated {
+		return 0, errors.New("checkpoint is not activated")
+	}
+	w := make(chan checkpointResult, 1)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case c.manual <- w:
+	case <-timer.C:
+		return 0, errors.New("trigger checkpoint timeout")
+	}
+	select {
+	case r := <-w:
+		return r.checkpointId, r.err
+	case <-timer.C:
+		return 0, errors.New("wait checkpoint timeout")
+	}
+}
+
+// notify replies the waiters of the checkpoints up to the checkpoint id
+func (c *Coordinator) notify(checkpointId int64, err error) {
+	c.waitersMu.Lock()
+	defer c.waitersMu.Unlock()
+	for id, w := range c.waiters {
+		if (err == nil && id <= checkpointId) || id == checkpointId {
+			w <- checkpointResult{checkpointId: checkpointId, err: err}
+			delete(c.waiters, id)
+		}
+	}
+}
+
 func (c *Coordinator) Deactivate() error {
 	if c.ticker != nil {
 		c.ticker.Stop()
@@ -251,6 +316,7 @@ func (c *Coordinator) cancel(checkpointId int64) {
 	if checkpoint, ok := c.pendingCheckpoints.Load(checkpointId); ok {
 		c.pendingCheckpoints.Delete(checkpointId)
 		checkpoint.(*pendingCheckpoint).dispose(true)
+		c.notify(checkpointId, fmt.Errorf("checkpoint %d is cancelled", checkpointId))
 	} else {
 		logger.Debugf("Cancel for non existing checkpoint %d. Just ignored", checkpointId)
 	}
@@ -264,6 +330,7 @@ func (c *Coordinator) complete(checkpointId int64) {
 		if err != nil {
 			logger.Infof("Cannot save checkpoint %d due to storage error: %v", checkpointId, err)
 			// TODO handle checkpoint error
+			c.notify(checkpointId, fmt.Errorf("save checkpoint %d error: %v", checkpointId, err))
 			return
 		}
 		c.completedCheckpoints.add(ccp.(*pendingCheckpoint).finalize())
@@ -279,6 +346,18 @@ func (c *Coordinator) complete(checkpointId int64) {
 			}
 			return true
 		})
+		c.notify(checkpointId, nil)
+		for _, t := range c.sinkTasks {
+			if tt, ok := t.(TransactionalTask); ok {
+				tt.Commit(checkpointId)
+			}
+		}
+		// The operators may keep the data for the checkpoint, such as the spilled window inputs
+		for _, t := range c.operators {
+			if tt, ok := t.(TransactionalTask); ok {
+				tt.Commit(checkpointId)
+			}
+		}
 		logger.Debugf("Totally complete checkpoint %d", checkpointId)
 	} else {
 		logger.Infof("Cannot find checkpoint %d to complete", checkpointId)
@@ -46,6 +46,13 @@ type SinkTask interface {
 	NonSourceTask
 }
 
+// TransactionalTask is the task which pre-commits its output or data when it receives the barrier and commits it when
+// the checkpoint completes
+type TransactionalTask interface {
+	PreCommit(checkpointId int64) error
+	Commit(checkpointId int64)
+}
+
 type BufferOrEvent struct {
 	Data    interface{}
 	Channel string
@@ -59,6 +59,14 @@ func (re *ResponderExecutor) TriggerCheckpoint(checkpointId int64) error {
 	if nonSink, ok := re.task.(NonSinkTask); ok {
 		nonSink.Broadcast(barrier)
 	}
+	// End the transaction before the snapshot so that it is saved in the checkpoint
+	if tt, ok := re.task.(TransactionalTask); ok {
+		if err := tt.PreCommit(checkpointId); err != nil {
+			logger.Infof("pre-commit checkpoint %d on task %s error %s", checkpointId, name, err)
+			re.responder <- &Signal{Message: DEC, Barrier: *barrier}
+			return err
+		}
+	}
 	// Save key state to the global state
 	err := sctx.Snapshot()
 	if err != nil {
@@ -15,7 +15,6 @@
 package context
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"regexp"
@@ -32,6 +31,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/transform"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/pool"
 )
 
 const (
@@ -39,6 +39,8 @@ const (
 	RuleStartKey     = "$$ruleStart"
 	RuleWaitGroupKey = "$$ruleWaitGroup"
 	TraceStrategyKey = "$$TraceStrategyKey"
+	// StateLimitKey is the key of the StateLimit of the rule. It is unset if the keyed states are unbounded.
+	StateLimitKey = "$$stateLimit"
 )
 
 const (
@@ -196,8 +198,9 @@ func (c *DefaultContext) ParseTemplate(prop string, data interface{}) (string, e
 			return prop, nil
 		}
 	}
-	var output bytes.Buffer
-	err = tp.Execute(&output, data)
+	output := pool.GetBuffer()
+	defer pool.PutBuffer(output)
+	err = tp.Execute(output, data)
 	if err != nil {
 		return fmt.Sprintf("%v", data), err
 	}
@@ -18,11 +18,16 @@ import (
 	"fmt"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/metrics"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
 type DefaultFuncContext struct {
 	api.StreamContext
 	funcId int
+	// tracker evicts the state keys by the state limit, it is nil if unlimited
+	tracker *KeyTracker
 }
 
 func NewDefaultFuncContext(ctx api.StreamContext, id int) *DefaultFuncContext {
@@ -40,18 +45,55 @@ func (c *DefaultFuncContext) GetCounter(key string) (int, error) {
 	return c.StreamContext.GetCounter(c.convertKey(key))
 }
 
+// LimitState evicts the state keys by the limit. The keyed functions such as the analytic functions set it because
+// their keys come from the data.
+func (c *DefaultFuncContext) LimitState(limit StateLimit) {
+	if limit.Enabled() {
+		c.tracker = NewKeyTracker(limit)
+	}
+}
+
 func (c *DefaultFuncContext) PutState(key string, value interface{}) error {
-	return c.StreamContext.PutState(c.convertKey(key), value)
+	err := c.StreamContext.PutState(c.convertKey(key), value)
+	if err == nil {
+		c.touch(key)
+	}
+	return err
 }
 
 func (c *DefaultFuncContext) GetState(key string) (interface{}, error) {
-	return c.StreamContext.GetState(c.convertKey(key))
+	v, err := c.StreamContext.GetState(c.convertKey(key))
+	if err == nil && v != nil {
+		c.touch(key)
+	}
+	return v, err
 }
 
 func (c *DefaultFuncContext) DeleteState(key string) error {
+	if c.tracker != nil {
+		c.tracker.Remove(key)
+	}
 	return c.StreamContext.DeleteState(c.convertKey(key))
 }
 
+func (c *DefaultFuncContext) touch(key string) {
+	if c.tracker == nil {
+		return
+	}
+	expired, overflow := c.tracker.Touch(key, timex.GetNow())
+	c.evict(expired, metrics.LblEvictTTL)
+	c.evict(overflow, metrics.LblEvictSize)
+}
+
+func (c *DefaultFuncContext) evict(keys []string, reason string) {
+	for _, k := range keys {
+		if err := c.StreamContext.DeleteState(c.convertKey(k)); err != nil {
+			c.GetLogger().Warnf("evict state %s error: %v", k, err)
+		}
+	}
+	metrics.AddStateEvictions(c.GetRuleId(), c.GetOpId(), reason, len(keys))
+}
+
 func (c *DefaultFuncContext) GetFuncId() int {
 	return c.funcId
 }
@@ -0,0 +1,117 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// StateLimit bounds the keyed states such as the partitions of the analytic functions, whose key space may be unbounded.
+type StateLimit struct {
+	// TTL evicts the keys which are not accessed for the duration
+	TTL time.Duration
+	// MaxKeys evicts the least recently accessed keys beyond the count
+	MaxKeys int
+}
+
+func (l StateLimit) Enabled() bool {
+	return l.TTL > 0 || l.MaxKeys > 0
+}
+
+type trackedKey struct {
+	key        string
+	lastAccess time.Time
+}
+
+// KeyTracker tracks the access of the keys of a keyed state to decide the keys to evict. It does not hold the states,
+// the owner must delete the evicted keys from its state.
+type KeyTracker struct {
+	mu    sync.Mutex
+	limit StateLimit
+	keys  map[string]*list.Element
+	// The front is the most recently accessed key
+	lru *list.List
+}
+
+func NewKeyTracker(limit StateLimit) *KeyTracker {
+	return &KeyTracker{
+		limit: limit,
+		keys:  make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+// Touch records the access of the key and returns the keys to evict. The expired keys are not accessed for the TTL
+// and the overflow keys are the least recently accessed ones beyond the max keys.
+func (t *KeyTracker) Touch(key string, now time.Time) (expired []string, overflow []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.keys[key]; ok {
+		e.Value.(*trackedKey).lastAccess = now
+		t.lru.MoveToFront(e)
+	} else {
+		t.keys[key] = t.lru.PushFront(&trackedKey{key: key, lastAccess: now})
+	}
+	expired = t.expire(now)
+	if t.limit.MaxKeys > 0 {
+		for t.lru.Len() > t.limit.MaxKeys {
+			overflow = append(overflow, t.remove(t.lru.Back()))
+		}
+	}
+	return expired, overflow
+}
+
+// Expire returns the keys which are not accessed for the TTL and stops tracking them
+func (t *KeyTracker) Expire(now time.Time) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.expire(now)
+}
+
+func (t *KeyTracker) Remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e, ok := t.keys[key]; ok {
+		t.remove(e)
+	}
+}
+
+func (t *KeyTracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lru.Len()
+}
+
+func (t *KeyTracker) expire(now time.Time) []string {
+	if t.limit.TTL <= 0 {
+		return nil
+	}
+	var result []string
+	for e := t.lru.Back(); e != nil; e = t.lru.Back() {
+		if now.Sub(e.Value.(*trackedKey).lastAccess) < t.limit.TTL {
+			break
+		}
+		result = append(result, t.remove(e))
+	}
+	return result
+}
+
+func (t *KeyTracker) remove(e *list.Element) string {
+	k := t.lru.Remove(e).(*trackedKey).key
+	delete(t.keys, k)
+	return k
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/topotest/mockclock"
+)
+
+func TestKeyTracker(t *testing.T) {
+	tr := NewKeyTracker(StateLimit{TTL: 10 * time.Second, MaxKeys: 3})
+	t0 := time.UnixMilli(100000)
+	tests := []struct {
+		key      string
+		ts       time.Duration
+		expired  []string
+		overflow []string
+	}{
+		{key: "a", ts: 0},
+		{key: "b", ts: time.Second},
+		{key: "c", ts: 2 * time.Second},
+		// a is accessed again, so b is the least recently accessed
+		{key: "a", ts: 3 * time.Second},
+		{key: "d", ts: 4 * time.Second, overflow: []string{"b"}},
+		// c is not accessed since 2s
+		{key: "d", ts: 12 * time.Second, expired: []string{"c"}},
+		{key: "e", ts: 20 * time.Second, expired: []string{"a"}},
+	}
+	for i, tt := range tests {
+		expired, overflow := tr.Touch(tt.key, t0.Add(tt.ts))
+		assert.Equal(t, tt.expired, expired, "case %d", i)
+		assert.Equal(t, tt.overflow, overflow, "case %d", i)
+	}
+	assert.Equal(t, 2, tr.Len())
+	tr.Remove("e")
+	assert.Equal(t, []string{"d"}, tr.Expire(t0.Add(30*time.Second)))
+	assert.Equal(t, 0, tr.Len())
+}
+
+func TestFuncContextStateLimit(t *testing.T) {
+	mockclock.ResetClock(100000)
+	clock := mockclock.GetMockClock()
+	st, err := state.CreateStore("testFuncStateLimit", def.AtMostOnce)
+	require.NoError(t, err)
+	ctx := Background().WithMeta("testFuncStateLimit", "op1", st)
+	fctx := NewDefaultFuncContext(ctx, 1)
+	fctx.LimitState(StateLimit{TTL: time.Minute, MaxKeys: 2})
+	require.NoError(t, fctx.PutState("k1", 1))
+	require.NoError(t, fctx.PutState("k2", 2))
+	clock.Add(time.Second)
+	// Read k1 so that k2 is evicted by k3
+	v, err := fctx.GetState("k1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	require.NoError(t, fctx.PutState("k3", 3))
+	v, err = fctx.GetState("k2")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	// k1 expires
+	clock.Add(30 * time.Second)
+	require.NoError(t, fctx.PutState("k3", 4))
+	clock.Add(31 * time.Second)
+	require.NoError(t, fctx.PutState("k3", 5))
+	v, err = fctx.GetState("k1")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+	v, err = ctx.GetState("$$func1_k3")
+	require.NoError(t, err)
+	assert.Equal(t, 5, v)
+	// Not limited by default
+	fctx = NewDefaultFuncContext(ctx, 2)
+	for _, k := range []string{"a", "b", "c"} {
+		require.NoError(t, fctx.PutState(k, k))
+	}
+	v, err = fctx.GetState("a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", v)
+}
@@ -159,6 +159,18 @@ var OpIO = map[string][]*IOType{
 		{Type: IOINPUT_TYPE_ANY, RowType: IOROW_TYPE_ANY, CollectionType: IOCOLLECTION_TYPE_ANY},
 		{Type: IOINPUT_TYPE_SAME},
 	},
+	"union": {
+		{Type: IOINPUT_TYPE_ANY, RowType: IOROW_TYPE_ANY, CollectionType: IOCOLLECTION_TYPE_ANY, AllowMulti: true},
+		{Type: IOINPUT_TYPE_SAME},
+	},
+	"pattern": {
+		{Type: IOINPUT_TYPE_ROW, RowType: IOROW_TYPE_ANY, CollectionType: IOCOLLECTION_TYPE_ANY},
+		{Type: IOINPUT_TYPE_ROW, RowType: IOROW_TYPE_SINGLE},
+	},
+	"dedup": {
+		{Type: IOINPUT_TYPE_ROW, RowType: IOROW_TYPE_SINGLE},
+		{Type: IOINPUT_TYPE_SAME},
+	},
 	"script": {
 		{Type: IOINPUT_TYPE_ANY, RowType: IOROW_TYPE_ANY, CollectionType: IOCOLLECTION_TYPE_ANY},
 		{Type: IOINPUT_TYPE_SAME},
@@ -14,6 +14,8 @@
 
 package graph
 
+import "time"
+
 type Function struct {
 	Expr string `json:"expr"`
 }
@@ -62,9 +64,31 @@ type Orderby struct {
 type Switch struct {
 	Cases            []string `json:"cases"`
 	StopAtFirstMatch bool     `json:"stopAtFirstMatch"`
+	Default          bool     `json:"default"`
 }
 
 type Script struct {
 	Script string `json:"script"`
 	IsAgg  bool   `json:"isAgg"`
 }
+
+type Pattern struct {
+	PartitionBy string        `json:"partitionBy"`
+	Sequence    []PatternStep `json:"sequence"`
+	Within      time.Duration `json:"within"`
+	Measures    []string      `json:"measures"`
+	MaxPartials int           `json:"maxPartials"`
+	MaxEvents   int           `json:"maxEvents"`
+}
+
+type Dedup struct {
+	Key string        `json:"key"`
+	TTL time.Duration `json:"ttl"`
+}
+
+type PatternStep struct {
+	Name       string `json:"name"`
+	Condition  string `json:"condition"`
+	Quantifier string `json:"quantifier"`
+	Negated    bool   `json:"negated"`
+}
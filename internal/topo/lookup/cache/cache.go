@@ -15,6 +15,7 @@
 package cache
 
 import (
+	"container/list"
 	"context"
 	"sync"
 	"time"
@@ -24,23 +25,33 @@ import (
 )
 
 type item struct {
+	key        string
 	data       []map[string]any
 	expiration time.Time
+	// the lookup values to refresh the item
+	values []any
+	// whether the item is read since set, only the read items are refreshed ahead
+	hit bool
 }
 
+// Cache is the lookup cache. If maxEntries is set, the least recently used item is evicted when the cache is full.
 type Cache struct {
 	expireTime      time.Duration
 	cacheMissingKey bool
+	maxEntries      int
 	cancel          context.CancelFunc
-	items           map[string]*item
-	sync.RWMutex
+	items           map[string]*list.Element
+	lru             *list.List
+	sync.Mutex
 }
 
-func NewCache(expireTime time.Duration, cacheMissingKey bool) *Cache {
+func NewCache(expireTime time.Duration, cacheMissingKey bool, maxEntries int) *Cache {
 	c := &Cache{
 		expireTime:      expireTime,
 		cacheMissingKey: cacheMissingKey,
-		items:           make(map[string]*item),
+		maxEntries:      maxEntries,
+		items:           make(map[string]*list.Element),
+		lru:             list.New(),
 	}
 	if expireTime > 0 {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -67,42 +78,86 @@ func (c *Cache) run(ctx context.Context) {
 func (c *Cache) deleteExpired() {
 	now := timex.GetNow()
 	c.Lock()
-	for k, v := range c.items {
+	for k, e := range c.items {
+		v := e.Value.(*item)
 		if !v.expiration.IsZero() && now.After(v.expiration) {
+			c.lru.Remove(e)
 			delete(c.items, k)
 		}
 	}
 	c.Unlock()
 }
 
-func (c *Cache) Set(key string, value []map[string]any) {
+// Set caches the lookup result of the key. The values are the lookup values of the key to refresh it.
+func (c *Cache) Set(key string, value []map[string]any, values ...any) {
 	if len(value) == 0 && !c.cacheMissingKey {
 		return
 	}
 	c.Lock()
 	defer c.Unlock()
+	it := &item{key: key, data: value, values: values}
 	if c.expireTime > 0 {
-		c.items[key] = &item{data: value, expiration: timex.GetNow().Add(c.expireTime)}
-	} else {
-		c.items[key] = &item{data: value}
+		it.expiration = timex.GetNow().Add(c.expireTime)
+	}
+	if e, ok := c.items[key]; ok {
+		e.Value = it
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.lru.PushFront(it)
+	if c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*item).key)
 	}
 }
 
 func (c *Cache) Get(key string) ([]map[string]any, bool) {
-	c.RLock()
-	defer c.RUnlock()
-	if v, ok := c.items[key]; ok {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.items[key]; ok {
+		v := e.Value.(*item)
 		if !v.expiration.IsZero() && timex.GetNow().After(v.expiration) {
 			return nil, false
 		}
+		v.hit = true
+		c.lru.MoveToFront(e)
 		return v.data, true
 	}
 	return nil, false
 }
 
+// Len returns the count of the cached items including the expired ones which are not deleted yet
+func (c *Cache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.lru.Len()
+}
+
+// ToRefresh returns the lookup values of the items which are read since set and will expire within the duration
+func (c *Cache) ToRefresh(ahead time.Duration) [][]any {
+	if c.expireTime <= 0 {
+		return nil
+	}
+	bound := timex.GetNow().Add(ahead)
+	c.Lock()
+	defer c.Unlock()
+	var result [][]any
+	for _, e := range c.items {
+		v := e.Value.(*item)
+		if v.hit && v.values != nil && v.expiration.Before(bound) {
+			result = append(result, v.values)
+		}
+	}
+	return result
+}
+
 func (c *Cache) Close() {
 	if c.cancel != nil {
 		c.cancel()
 	}
+	c.Lock()
 	c.items = nil
+	c.lru = nil
+	c.Unlock()
 }
@@ -19,13 +19,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/lf-edge/ekuiper/v2/internal/topo/topotest/mockclock"
 )
 
 func TestExpiration(t *testing.T) {
 	mockclock.ResetClock(0)
 	clock := mockclock.GetMockClock()
-	c := NewCache(20*time.Second, false)
+	c := NewCache(20*time.Second, false, 0)
 	defer c.Close()
 	expects := [][]map[string]any{
 		{{"a": 1}},
@@ -88,7 +90,7 @@ func TestExpiration(t *testing.T) {
 func TestNoExpiration(t *testing.T) {
 	mockclock.ResetClock(0)
 	clock := mockclock.GetMockClock()
-	c := NewCache(0, true)
+	c := NewCache(0, true, 0)
 	defer c.Close()
 
 	expects := [][]map[string]any{
@@ -124,3 +126,44 @@ func TestNoExpiration(t *testing.T) {
 		return
 	}
 }
+
+func TestMaxEntries(t *testing.T) {
+	c := NewCache(0, false, 2)
+	defer c.Close()
+	c.Set("a", []map[string]any{{"a": 1}})
+	c.Set("b", []map[string]any{{"a": 2}})
+	// Read a so that b is the least recently used
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	c.Set("c", []map[string]any{{"a": 3}})
+	require.Equal(t, 2, c.Len())
+	_, ok = c.Get("b")
+	require.False(t, ok)
+	r, ok := c.Get("a")
+	require.True(t, ok)
+	require.Equal(t, []map[string]any{{"a": 1}}, r)
+	_, ok = c.Get("c")
+	require.True(t, ok)
+	// Update the existing key does not evict
+	c.Set("a", []map[string]any{{"a": 4}})
+	require.Equal(t, 2, c.Len())
+}
+
+func TestToRefresh(t *testing.T) {
+	mockclock.ResetClock(0)
+	clock := mockclock.GetMockClock()
+	c := NewCache(20*time.Second, false, 0)
+	defer c.Close()
+	c.Set("[1]", []map[string]any{{"a": 1}}, int64(1))
+	c.Set("[2]", []map[string]any{{"a": 2}}, int64(2))
+	require.Empty(t, c.ToRefresh(5*time.Second))
+	_, ok := c.Get("[1]")
+	require.True(t, ok)
+	_, ok = c.Get("[2]")
+	require.True(t, ok)
+	clock.Add(10 * time.Second)
+	c.Set("[2]", []map[string]any{{"a": 3}}, int64(2))
+	clock.Add(6 * time.Second)
+	// Only the read item which will expire soon needs refreshing
+	require.Equal(t, [][]any{{int64(1)}}, c.ToRefresh(5*time.Second))
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// The weight of the new sample in the moving average
+	batchEwmaAlpha = 0.2
+	// The min duration to sample the input rate
+	batchRateWindow = 100 * time.Millisecond
+	// The batch is bigger than the items arrived during a send so that the sink catches up the backlog
+	batchHeadroom = 2
+	// The default max linger if the lingerInterval is not set
+	defaultMaxLinger = 100 * time.Millisecond
+)
+
+// BatchController decides the batch size and linger of the adaptive batch by the input rate observed by the batch op
+// and the send latency observed by the sink. If the sink can send the items one by one before the next arrives, the
+// batch has only one item to send it immediately. Otherwise, the batch holds the items arrived during a send. The size
+// and linger are bounded by the configured batchSize and lingerInterval.
+type BatchController struct {
+	maxSize   int
+	maxLinger time.Duration
+
+	mu sync.Mutex
+	// rate is the moving average of the input items per second
+	rate float64
+	// latency is the moving average of the seconds to send a batch
+	latency     float64
+	windowStart time.Time
+	windowCount int
+}
+
+func NewBatchController(maxSize int, maxLinger time.Duration) *BatchController {
+	if maxLinger <= 0 {
+		maxLinger = defaultMaxLinger
+	}
+	return &BatchController{
+		maxSize:   maxSize,
+		maxLinger: maxLinger,
+	}
+}
+
+// ObserveArrival records n items arrived at now
+func (c *BatchController) ObserveArrival(now time.Time, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The first arrival starts the window
+	if c.windowStart.IsZero() {
+		c.windowStart = now
+		return
+	}
+	c.windowCount += n
+	elapsed := now.Sub(c.windowStart)
+	if elapsed < batchRateWindow {
+		return
+	}
+	c.rate = ewma(c.rate, float64(c.windowCount)/elapsed.Seconds())
+	c.windowStart = now
+	c.windowCount = 0
+}
+
+// ObserveLatency records the duration of a send
+func (c *BatchController) ObserveLatency(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.latency = ewma(c.latency, d.Seconds())
+}
+
+// Target returns the current batch size and linger
+func (c *BatchController) Target() (int, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The items arrived during a send
+	load := c.rate * c.latency
+	size := int(math.Ceil(load * batchHeadroom))
+	if size <= 1 {
+		return 1, 0
+	}
+	if size > c.maxSize {
+		size = c.maxSize
+	}
+	// Wait for the batch to fill at the current rate
+	linger := time.Duration(float64(size) / c.rate * float64(time.Second))
+	if linger > c.maxLinger {
+		linger = c.maxLinger
+	}
+	return size, linger
+}
+
+func ewma(prev, sample float64) float64 {
+	if prev == 0 {
+		return sample
+	}
+	return prev*(1-batchEwmaAlpha) + sample*batchEwmaAlpha
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/topotest/mockclock"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestBatchController(t *testing.T) {
+	c := NewBatchController(100, time.Second)
+	// No observation, send immediately
+	size, linger := c.Target()
+	assert.Equal(t, 1, size)
+	assert.Equal(t, time.Duration(0), linger)
+
+	t0 := time.UnixMilli(0)
+	// Light load: 10 items/s with 10ms latency, the sink catches up one by one
+	for i := 0; i <= 10; i++ {
+		c.ObserveArrival(t0.Add(time.Duration(i)*100*time.Millisecond), 1)
+	}
+	c.ObserveLatency(10 * time.Millisecond)
+	size, linger = c.Target()
+	assert.Equal(t, 1, size)
+	assert.Equal(t, time.Duration(0), linger)
+
+	// Heavy load: 1000 items/s with 10ms latency, 10 items arrive during a send
+	c = NewBatchController(100, time.Second)
+	for i := 0; i <= 10; i++ {
+		c.ObserveArrival(t0.Add(time.Duration(i)*100*time.Millisecond), 100)
+	}
+	c.ObserveLatency(10 * time.Millisecond)
+	size, linger = c.Target()
+	assert.Equal(t, 20, size)
+	assert.Equal(t, 20*time.Millisecond, linger)
+
+	// Bounded by the max size and linger
+	c = NewBatchController(5, 0)
+	for i := 0; i <= 10; i++ {
+		c.ObserveArrival(t0.Add(time.Duration(i)*100*time.Millisecond), 1)
+	}
+	c.ObserveLatency(10 * time.Second)
+	size, linger = c.Target()
+	assert.Equal(t, 5, size)
+	assert.Equal(t, defaultMaxLinger, linger)
+}
+
+func TestAdaptiveBatchOp(t *testing.T) {
+	mc := mockclock.GetMockClock()
+	op, err := NewBatchOp("test", &def.RuleOption{BufferLength: 10, SendError: true}, 10, 0)
+	require.NoError(t, err)
+	ctrl := NewBatchController(10, time.Second)
+	op.SetController(ctrl)
+	out := make(chan any, 100)
+	require.NoError(t, op.AddOutput(out, "test"))
+	ctx, cancel := mockContext.NewMockContext("test1", "adaptive_batch_test").WithCancel()
+	defer cancel()
+	op.Exec(ctx, make(chan error))
+
+	// Without latency, the items are sent one by one
+	op.input <- &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1}}
+	w := (<-out).(*xsql.WindowTuples)
+	assert.Len(t, w.Content, 1)
+
+	// Slow sink with 10 items/s, the batch holds the items until the linger expires
+	ctrl.ObserveLatency(time.Second)
+	ctrl.ObserveArrival(mc.Now().Add(time.Second), 10)
+	size, linger := ctrl.Target()
+	require.Equal(t, 10, size)
+	require.Equal(t, time.Second, linger)
+	for i := 0; i < 3; i++ {
+		op.input <- &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": i}}
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-out:
+		require.Fail(t, "should wait for the linger")
+	default:
+	}
+	mc.Add(linger)
+	w = (<-out).(*xsql.WindowTuples)
+	assert.Len(t, w.Content, 3)
+}
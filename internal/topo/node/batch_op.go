@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 	"github.com/pingcap/failpoint"
 	"go.opentelemetry.io/otel/trace"
@@ -44,6 +45,8 @@ type BatchOp struct {
 	nextSpan    trace.Span
 	rowHandle   map[xsql.Row]trace.Span
 	currIndex   int
+	// ctrl adapts the batch size and linger if the adaptive batch is enabled
+	ctrl *BatchController
 }
 
 func NewBatchOp(name string, rOpt *def.RuleOption, batchSize int, lingerInterval time.Duration) (*BatchOp, error) {
@@ -66,10 +69,17 @@ func NewBatchOp(name string, rOpt *def.RuleOption, batchSize int, lingerInterval
 	return o, nil
 }
 
+// SetController enables the adaptive batch. The batchSize and lingerInterval are the upper bounds.
+func (b *BatchOp) SetController(ctrl *BatchController) {
+	b.ctrl = ctrl
+}
+
 func (b *BatchOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 	b.prepareExec(ctx, errCh, "op")
 	b.handleNextWindowTupleSpan(ctx)
 	switch {
+	case b.ctrl != nil:
+		b.runAdaptive(ctx, errCh)
 	case b.batchSize > 0 && b.lingerInterval > 0:
 		b.runWithTickerAndBatchSize(ctx, errCh)
 	case b.batchSize > 0 && b.lingerInterval == 0:
@@ -203,6 +213,64 @@ func (b *BatchOp) runWithTicker(ctx api.StreamContext, errCh chan<- error) {
 	}()
 }
 
+// runAdaptive sends the batch when it reaches the size decided by the controller or the linger since the first item
+// of the batch expires. The target is checked for each ingest, so it follows the change of the load.
+func (b *BatchOp) runAdaptive(ctx api.StreamContext, errCh chan<- error) {
+	var (
+		timer   *clock.Timer
+		timerCh <-chan time.Time
+	)
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+			timerCh = nil
+		}
+	}
+	go func() {
+		err := infra.SafeRun(func() error {
+			defer func() {
+				stopTimer()
+				b.Close()
+			}()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case d := <-b.input:
+					before := b.currIndex
+					b.ingest(ctx, d, false)
+					added := b.currIndex - before
+					// The control items do not add to the batch, EOF may send the batch out
+					if added <= 0 {
+						if b.currIndex == 0 {
+							stopTimer()
+						}
+						break
+					}
+					b.ctrl.ObserveArrival(timex.GetNow(), added)
+					size, linger := b.ctrl.Target()
+					switch {
+					case b.currIndex >= size || linger <= 0:
+						stopTimer()
+						b.send(ctx)
+					case timer == nil:
+						timer = timex.GetTimer(linger)
+						timerCh = timer.C
+					}
+				case <-timerCh:
+					timer = nil
+					timerCh = nil
+					b.send(ctx)
+				}
+			}
+		})
+		if err != nil {
+			infra.DrainError(ctx, err, errCh)
+		}
+	}()
+}
+
 func (b *BatchOp) handleNextWindowTupleSpan(ctx api.StreamContext) {
 	traced, spanCtx, span := tracenode.StartTraceBackground(ctx, "batch_op")
 	if traced {
@@ -0,0 +1,264 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// The interval to check whether the downstream has consumed the buffer below the low watermark
+const spillCheckInterval = 10 * time.Millisecond
+
+// spillRecord is the encodable form of a spilled item
+type spillRecord struct {
+	// Channel is set if the item is wrapped in a BufferOrEvent
+	Channel string
+	Tuple   *xsql.Tuple
+	Raw     *xsql.RawTuple
+}
+
+// bufferSpill is the disk-backed queue of an output buffer of a node. Once the buffer reaches the high watermark,
+// the items are appended to the queue instead, and they are read back in order when the downstream consumes the
+// buffer below the low watermark. The items which cannot be encoded such as the windows and the control messages are
+// kept in memory in the queue order. The spilled items are kept in the store of the rule, so the items not sent yet
+// are sent after the rule restarts.
+type bufferSpill struct {
+	out     chan any
+	high    int
+	low     int
+	max     int
+	kv      kv.KeyValue
+	prefix  string
+	mu      sync.Mutex
+	inMem   map[int]any
+	started bool
+	// the spilled items are in [head, tail)
+	head int
+	tail int
+	// the items before it are spilled by the previous run whose in-memory items are lost
+	restored int
+}
+
+func newBufferSpill(ctx api.StreamContext, output string, out chan any, conf *def.BufferSpill) (*bufferSpill, error) {
+	s, err := store.GetSpillKV(ctx.GetRuleId())
+	if err != nil {
+		return nil, err
+	}
+	high := int(math.Ceil(conf.HighWatermark * float64(cap(out))))
+	if high < 1 {
+		high = 1
+	}
+	q := &bufferSpill{
+		out:    out,
+		high:   high,
+		low:    int(conf.LowWatermark * float64(cap(out))),
+		max:    conf.MaxItems,
+		kv:     s,
+		prefix: fmt.Sprintf("buffer/%s/%s/", ctx.GetOpId(), output),
+		inMem:  make(map[int]any),
+	}
+	if err := q.restore(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// restore finds the items spilled by the previous run to send them first
+func (q *bufferSpill) restore() error {
+	keys, err := q.kv.Keys()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, k := range keys {
+		if !strings.HasPrefix(k, q.prefix) {
+			continue
+		}
+		i, err := strconv.Atoi(strings.TrimPrefix(k, q.prefix))
+		if err != nil {
+			continue
+		}
+		if !found || i < q.head {
+			q.head = i
+		}
+		if !found || i >= q.tail {
+			q.tail = i + 1
+		}
+		found = true
+	}
+	q.restored = q.tail
+	return nil
+}
+
+func (q *bufferSpill) key(i int) string {
+	return q.prefix + strconv.Itoa(i)
+}
+
+// resume starts to send the items spilled by the previous run
+func (q *bufferSpill) resume(ctx api.StreamContext) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.head < q.tail {
+		ctx.GetLogger().Infof("resume sending %d spilled items", q.tail-q.head)
+		q.start(ctx)
+	}
+}
+
+func (q *bufferSpill) start(ctx api.StreamContext) {
+	if !q.started {
+		q.started = true
+		go q.run(ctx, timex.GetTicker(spillCheckInterval))
+	}
+}
+
+// push sends the item to the buffer or spills it. It returns false if the queue is full so that the caller falls
+// back to discard or block.
+func (q *bufferSpill) push(ctx api.StreamContext, val any) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// Keep the order, send to the buffer only if nothing is spilled
+	if q.head == q.tail && len(q.out) < q.high {
+		select {
+		case q.out <- val:
+			return true
+		default:
+		}
+	}
+	if q.max > 0 && q.tail-q.head >= q.max {
+		return false
+	}
+	if r, ok := toSpillRecord(val); ok {
+		if err := q.kv.Set(q.key(q.tail), r); err != nil {
+			ctx.GetLogger().Warnf("fail to spill the buffer: %v", err)
+			return false
+		}
+	} else {
+		q.inMem[q.tail] = val
+	}
+	if q.head == q.tail {
+		ctx.GetLogger().Debugf("buffer reaches the high watermark %d, start spilling", q.high)
+	}
+	q.tail++
+	q.start(ctx)
+	return true
+}
+
+// run reads back the spilled items when the buffer is consumed below the low watermark
+func (q *bufferSpill) run(ctx api.StreamContext, ticker *clock.Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.load(ctx)
+		}
+	}
+}
+
+// load moves the spilled items back to the buffer until the buffer reaches the high watermark
+func (q *bufferSpill) load(ctx api.StreamContext) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.head == q.tail || len(q.out) > q.low {
+		return
+	}
+	for q.head < q.tail && len(q.out) < q.high {
+		key := q.head
+		val, ok := q.inMem[key]
+		if ok {
+			delete(q.inMem, key)
+		} else {
+			r := &spillRecord{}
+			found, err := q.kv.Get(q.key(key), r)
+			if err != nil {
+				ctx.GetLogger().Errorf("fail to read the spilled buffer %d: %v", key, err)
+				return
+			}
+			if !found {
+				// The control messages of the previous run are not kept
+				if key >= q.restored {
+					ctx.GetLogger().Errorf("the spilled buffer %d is lost", key)
+				}
+				q.head++
+				continue
+			}
+			val = r.toItem()
+			_ = q.kv.Delete(q.key(key))
+		}
+		q.head++
+		select {
+		case q.out <- val:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if q.head == q.tail {
+		ctx.GetLogger().Debugf("all the spilled items are read back")
+	}
+}
+
+// spilled returns the count of the items in the queue
+func (q *bufferSpill) spilled() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.tail - q.head
+}
+
+func toSpillRecord(val any) (*spillRecord, bool) {
+	r := &spillRecord{}
+	if boe, ok := val.(*checkpoint.BufferOrEvent); ok {
+		r.Channel = boe.Channel
+		val = boe.Data
+	}
+	switch t := val.(type) {
+	case *xsql.Tuple:
+		// The context is not encodable and not needed by the downstream
+		r.Tuple = &xsql.Tuple{Emitter: t.Emitter, Message: t.Message, Timestamp: t.Timestamp, Metadata: t.Metadata, Props: t.Props, Rawdata: t.Rawdata}
+	case *xsql.RawTuple:
+		r.Raw = &xsql.RawTuple{Emitter: t.Emitter, Timestamp: t.Timestamp, Rawdata: t.Rawdata, Metadata: t.Metadata, Props: t.Props}
+	default:
+		return nil, false
+	}
+	return r, true
+}
+
+func (r *spillRecord) toItem() any {
+	var val any
+	if r.Tuple != nil {
+		val = r.Tuple
+	} else {
+		val = r.Raw
+	}
+	if r.Channel != "" {
+		return &checkpoint.BufferOrEvent{Data: val, Channel: r.Channel}
+	}
+	return val
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/topotest/mockclock"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestBufferSpill(t *testing.T) {
+	mc := mockclock.GetMockClock()
+	ctx, cancel := mockContext.NewMockContext("TestBufferSpill", "op").WithCancel()
+	defer cancel()
+	o := newDefaultNode("op", &def.RuleOption{
+		BufferLength: 4,
+		BufferSpill:  &def.BufferSpill{HighWatermark: 0.5, LowWatermark: 0.25, MaxItems: 6},
+	})
+	o.ctx = ctx
+	out := make(chan any, 4)
+	require.NoError(t, o.AddOutput(out, "next"))
+	// The downstream is down, the items beyond the high watermark are spilled
+	for i := int64(0); i < 7; i++ {
+		o.Broadcast(&xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": i}, Timestamp: time.UnixMilli(i)})
+	}
+	o.Broadcast(&xsql.WatermarkTuple{Timestamp: time.UnixMilli(7)})
+	assert.Len(t, out, 2)
+	q := o.spills["next"]
+	require.NotNil(t, q)
+	assert.Equal(t, 6, q.spilled())
+	// The queue is full, fallback to drop the oldest in the buffer
+	o.Broadcast(&xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": int64(8)}})
+	assert.Len(t, out, 3)
+	// Nothing is read back until the buffer is consumed below the low watermark
+	mc.Add(spillCheckInterval)
+	assert.Len(t, out, 3)
+
+	var result []any
+	for len(out) > 0 {
+		result = append(result, (<-out).(*xsql.Tuple).Message["ts"])
+	}
+	assert.Equal(t, []any{int64(0), int64(1), int64(8)}, result)
+	result = nil
+	for q.spilled() > 0 {
+		mc.Add(spillCheckInterval)
+		require.Eventually(t, func() bool { return len(out) > 0 }, time.Second, time.Millisecond)
+		for len(out) > 0 {
+			switch d := (<-out).(type) {
+			case *xsql.Tuple:
+				result = append(result, d.Message["ts"])
+				assert.Equal(t, d.Message["ts"], d.Timestamp.UnixMilli())
+			case *xsql.WatermarkTuple:
+				result = append(result, "watermark")
+			}
+		}
+	}
+	// The spilled items are read back in order with the control messages
+	assert.Equal(t, []any{int64(2), int64(3), int64(4), int64(5), int64(6), "watermark"}, result)
+
+	// The buffer is not spilled if it is below the high watermark
+	o.Broadcast(&xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": int64(9)}})
+	assert.Equal(t, 0, q.spilled())
+	assert.Len(t, out, 1)
+}
+
+func TestBufferSpillRestart(t *testing.T) {
+	mc := mockclock.GetMockClock()
+	options := &def.RuleOption{
+		BufferLength: 2,
+		BufferSpill:  &def.BufferSpill{HighWatermark: 0.5},
+	}
+	ctx, cancel := mockContext.NewMockContext("TestBufferSpillRestart", "op").WithCancel()
+	o := newDefaultNode("op", options)
+	o.ctx = ctx
+	out := make(chan any, 2)
+	require.NoError(t, o.AddOutput(out, "next"))
+	for i := int64(0); i < 3; i++ {
+		o.Broadcast(&xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": i}, Timestamp: time.UnixMilli(i)})
+	}
+	o.Broadcast(&xsql.WatermarkTuple{Timestamp: time.UnixMilli(3)})
+	assert.Len(t, out, 1)
+	assert.Equal(t, 3, o.spills["next"].spilled())
+	cancel()
+
+	// The spilled items are sent after the rule restarts except the control messages
+	ctx, cancel = mockContext.NewMockContext("TestBufferSpillRestart", "op").WithCancel()
+	defer cancel()
+	o = newDefaultNode("op", options)
+	out = make(chan any, 2)
+	require.NoError(t, o.AddOutput(out, "next"))
+	o.prepareExec(ctx, make(chan error, 1), "op")
+	defer o.Close()
+	q := o.spills["next"]
+	require.NotNil(t, q)
+	assert.Equal(t, 2, q.spilled())
+	var result []any
+	for q.spilled() > 0 {
+		mc.Add(spillCheckInterval)
+		require.Eventually(t, func() bool { return len(out) > 0 || q.spilled() == 0 }, time.Second, time.Millisecond)
+		for len(out) > 0 {
+			result = append(result, (<-out).(*xsql.Tuple).Message["ts"])
+		}
+	}
+	assert.Equal(t, []any{int64(1), int64(2)}, result)
+
+	// The spilled data is dropped with the rule
+	o.Broadcast(&xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": int64(4)}})
+	o.Broadcast(&xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": int64(5)}})
+	require.NoError(t, store.DropSpillKV("TestBufferSpillRestart"))
+	s, err := store.GetSpillKV("TestBufferSpillRestart")
+	require.NoError(t, err)
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestSpillRecord(t *testing.T) {
+	_, ok := toSpillRecord(&xsql.WindowTuples{})
+	assert.False(t, ok)
+	r, ok := toSpillRecord(&checkpoint.BufferOrEvent{Data: &xsql.RawTuple{Emitter: "demo", Rawdata: []byte("hello")}, Channel: "op"})
+	require.True(t, ok)
+	assert.Equal(t, &checkpoint.BufferOrEvent{Data: &xsql.RawTuple{Emitter: "demo", Rawdata: []byte("hello")}, Channel: "op"}, r.toItem())
+}
@@ -32,76 +32,102 @@ func runWithOrder(ctx api.StreamContext, node *defaultSinkNode, numWorkers int,
 }
 
 func runWithOrderAndInterval(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, wf workerFunc, sendInterval time.Duration) {
+	runWorkersWithOrder(ctx, node, numWorkers, func(_ int) workerFunc { return wf }, sendInterval)
+}
+
+// runWorkersWithOrder runs the workers created by newWorker concurrently and merges their results in the order of the input.
+// The input is distributed by the keyBy field of the node if set, otherwise by round-robin.
+func runWorkersWithOrder(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, newWorker func(i int) workerFunc, sendInterval time.Duration) {
 	workerChans := make([]chan any, numWorkers)
 	workerOutChans := make([]chan []any, numWorkers)
 	for i := range workerChans {
 		workerChans[i] = make(chan any)
 		workerOutChans[i] = make(chan []any)
 	}
+	// the worker index of each input in order, so that the merger can restore the order for any distribution
+	order := make(chan int, numWorkers*2)
 
 	// Start worker goroutines
 	for i := 0; i < numWorkers; i++ {
-		go worker(ctx, node, i, wf, workerChans[i], workerOutChans[i])
+		go worker(ctx, node, i, newWorker(i), workerChans[i], workerOutChans[i])
 	}
 	// start merger goroutine
 	output := make(chan any)
-	go merge(ctx, node, sendInterval, output, workerOutChans...)
+	go merge(ctx, node, sendInterval, output, order, workerOutChans...)
 
 	// Distribute input data to workers
-	distribute(ctx, node, numWorkers, workerChans)
+	distribute(ctx, node, numWorkers, workerChans, order)
 }
 
 // Merge multiple channels into one preserving the order
-func merge(ctx api.StreamContext, node *defaultSinkNode, sendInterval time.Duration, output chan any, channels ...chan []any) {
+func merge(ctx api.StreamContext, node *defaultSinkNode, sendInterval time.Duration, output chan any, order <-chan int, channels ...chan []any) {
 	defer close(output)
-	// Start a goroutine for each input channel
 	for {
-		for _, ch := range channels {
-			select {
-			case data := <-ch:
-				for _, d := range data {
-					if derr, ok := d.(error); ok {
-						node.onError(ctx, derr)
-						continue
-					}
-					dd, processed := node.commonIngest(ctx, d)
-					if processed {
-						continue
-					}
-					node.Broadcast(dd)
-					node.onSend(ctx, dd)
-					if sendInterval > 0 {
-						time.Sleep(sendInterval)
-					}
+		var ch chan []any
+		select {
+		case i := <-order:
+			ch = channels[i]
+		case <-ctx.Done():
+			ctx.GetLogger().Infof("merge done")
+			return
+		}
+		select {
+		case data := <-ch:
+			for _, d := range data {
+				if derr, ok := d.(error); ok {
+					node.onError(ctx, derr)
+					continue
+				}
+				dd, processed := node.commonIngest(ctx, d)
+				if processed {
+					continue
+				}
+				node.Broadcast(dd)
+				node.onSend(ctx, dd)
+				if sendInterval > 0 {
+					time.Sleep(sendInterval)
 				}
-			case <-ctx.Done():
-				ctx.GetLogger().Infof("merge done")
-				return
 			}
+		case <-ctx.Done():
+			ctx.GetLogger().Infof("merge done")
+			return
 		}
 	}
 }
 
-func distribute(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, workerChans []chan any) {
+func distribute(ctx api.StreamContext, node *defaultSinkNode, numWorkers int, workerChans []chan any, order chan<- int) {
 	var counter int
 	for {
 		node.statManager.SetBufferLength(int64(len(node.input)))
-		// Round-robin
-		if counter == numWorkers {
-			counter = 0
-		}
 		select {
 		case <-ctx.Done():
 			ctx.GetLogger().Infof("distribute done")
 			return
 		case item := <-node.input: // Just send out all inputs even they are control tuples
+			if r, ok := item.(*StateRequest); ok && numWorkers > 1 {
+				node.inspectStateKeys(ctx, r)
+				continue
+			}
+			i := node.partition(item, numWorkers)
+			if i < 0 {
+				// Round-robin
+				if counter >= numWorkers {
+					counter = 0
+				}
+				i = counter
+				counter++
+			}
 			select {
-			case workerChans[counter] <- item:
+			case order <- i:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case workerChans[i] <- item:
 			case <-ctx.Done():
 				return
 			}
 		}
-		counter++
 	}
 }
 
@@ -109,19 +135,19 @@ func worker(ctx api.StreamContext, node *defaultSinkNode, i int, wf workerFunc,
 	for {
 		select {
 		case data := <-inputRaw:
-			item, processed := node.preprocess(ctx, data)
-			if processed {
-				break
-			}
 			var result []any
-			switch item.(type) {
-			case error, *xsql.WatermarkTuple, xsql.EOFTuple:
-				result = []any{item}
-			default:
-				node.onProcessStart(ctx, item)
-				result = wf(ctx, item)
-				node.onProcessEnd(ctx)
+			item, processed := node.preprocess(ctx, data)
+			if !processed {
+				switch item.(type) {
+				case error, *xsql.WatermarkTuple, xsql.EOFTuple:
+					result = []any{item}
+				default:
+					node.onProcessStart(ctx, item)
+					result = wf(ctx, item)
+					node.onProcessEnd(ctx)
+				}
 			}
+			// Always send the result even it is empty to let the merger move on
 			select {
 			case output <- result:
 			case <-ctx.Done():
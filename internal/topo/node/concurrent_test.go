@@ -0,0 +1,135 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+// slowOp sleeps longer for the smaller id to shuffle the finish order of the workers, and records the worker of each key
+type slowOp struct {
+	mu      sync.Mutex
+	workers map[any]map[*xsql.FunctionValuer]struct{}
+}
+
+func (s *slowOp) Apply(_ api.StreamContext, data any, fv *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) any {
+	row := data.(*xsql.Tuple)
+	id := row.Message["id"].(int)
+	time.Sleep(time.Duration(10-id) * time.Millisecond)
+	s.mu.Lock()
+	key := row.Message["key"]
+	if s.workers[key] == nil {
+		s.workers[key] = make(map[*xsql.FunctionValuer]struct{})
+	}
+	s.workers[key][fv] = struct{}{}
+	s.mu.Unlock()
+	if id == 3 {
+		return nil
+	}
+	return row
+}
+
+func TestUnaryOperatorParallel(t *testing.T) {
+	tests := []struct {
+		name  string
+		keyBy string
+		keys  []ast.Expr
+	}{
+		{name: "round robin"},
+		{name: "keyed", keyBy: "key"},
+		{name: "keyed by expressions", keys: []ast.Expr{&ast.FieldRef{Name: "key", StreamName: ast.DefaultStream}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sop := &slowOp{workers: make(map[any]map[*xsql.FunctionValuer]struct{})}
+			op := New("parallel", &def.RuleOption{BufferLength: 10, Concurrency: 1})
+			op.SetOperation(sop)
+			if tt.keys != nil {
+				op.SetKeyedParallelism(4, tt.keys)
+			} else {
+				op.SetParallelism(4, tt.keyBy)
+			}
+			out := make(chan any, 100)
+			require.NoError(t, op.AddOutput(out, "test"))
+			ctx, cancel := mockContext.NewMockContext("test1", "parallel_test").WithCancel()
+			defer cancel()
+			op.Exec(ctx, make(chan error))
+			for i := 0; i < 8; i++ {
+				op.input <- &xsql.Tuple{Emitter: "test", Message: map[string]any{"id": i, "key": i % 2}}
+			}
+			op.input <- xsql.EOFTuple(0)
+			var ids []int
+			for i := 0; i < 8; i++ {
+				r := <-out
+				if _, ok := r.(xsql.EOFTuple); ok {
+					break
+				}
+				ids = append(ids, r.(*xsql.Tuple).Message["id"].(int))
+			}
+			// The order is restored and the filtered item is omitted
+			assert.Equal(t, []int{0, 1, 2, 4, 5, 6, 7}, ids)
+			if tt.keyBy != "" || tt.keys != nil {
+				for k, ws := range sop.workers {
+					assert.Len(t, ws, 1, "key %v", k)
+				}
+			} else {
+				assert.Len(t, sop.workers[0], 2)
+			}
+		})
+	}
+}
+
+func TestPartition(t *testing.T) {
+	n := newDefaultSinkNode("test", &def.RuleOption{})
+	row := &xsql.Tuple{Message: map[string]any{"id": 1}}
+	assert.Equal(t, -1, n.partition(row, 4))
+	n.SetParallelism(4, "id")
+	assert.Equal(t, 4, n.concurrency)
+	p := n.partition(row, 4)
+	assert.True(t, p >= 0 && p < 4)
+	assert.Equal(t, p, n.partition(&xsql.Tuple{Message: map[string]any{"id": 1, "a": 2}}, 4))
+	assert.Equal(t, -1, n.partition(&xsql.RawTuple{}, 4))
+	assert.Equal(t, -1, n.partition(row, 1))
+}
+
+func TestPartitionByKeys(t *testing.T) {
+	n := newDefaultSinkNode("test", &def.RuleOption{})
+	n.SetKeyedParallelism(4, []ast.Expr{
+		&ast.FieldRef{Name: "a", StreamName: ast.DefaultStream},
+		&ast.FieldRef{Name: "b", StreamName: ast.DefaultStream},
+	})
+	assert.Equal(t, 4, n.concurrency)
+	counts := make(map[int]int)
+	for i := 0; i < 100; i++ {
+		p := n.partition(&xsql.Tuple{Message: map[string]any{"a": i, "b": "x", "c": i}}, 4)
+		require.True(t, p >= 0 && p < 4)
+		// The non key field does not change the partition
+		assert.Equal(t, p, n.partition(&xsql.Tuple{Message: map[string]any{"a": i, "b": "x", "c": -i}}, 4))
+		counts[p]++
+	}
+	assert.Len(t, counts, 4)
+	assert.Equal(t, -1, n.partition(xsql.EOFTuple(0), 4))
+}
@@ -83,8 +83,15 @@ func GetSourceConf(sourceType string, options *ast.Options) map[string]interface
 	props["delimiter"] = options.DELIMITER
 	props["retainSize"] = options.RETAIN_SIZE
 	props["strictValidation"] = options.STRICT_VALIDATION
+	props["schemaPolicy"] = options.SCHEMA_POLICY
 	props["timestamp"] = options.TIMESTAMP
 	props["timestampFormat"] = options.TIMESTAMP_FORMAT
+	if options.DECOMPRESSION != "" {
+		props["decompression"] = options.DECOMPRESSION
+	}
+	if options.DECRYPTION != "" {
+		props["decryption"] = options.DECRYPTION
+	}
 	conf.Log.Infof("get conf for %s with conf key %s: %v", sourceType, confkey, printable(props))
 	return props
 }
@@ -65,6 +65,7 @@ func TestGetSourceConf(t *testing.T) {
 				"delimiter":          "",
 				"retainSize":         0,
 				"schemaId":           "",
+				"schemaPolicy":       "",
 				"strictValidation":   false,
 				"timestamp":          "",
 				"timestampFormat":    "",
@@ -90,6 +91,7 @@ func TestGetSourceConf(t *testing.T) {
 				"delimiter":          "",
 				"retainSize":         0,
 				"schemaId":           "",
+				"schemaPolicy":       "",
 				"strictValidation":   false,
 				"timestamp":          "",
 				"timestampFormat":    "",
@@ -115,6 +117,7 @@ func TestGetSourceConf(t *testing.T) {
 				"delimiter":          "",
 				"retainSize":         0,
 				"schemaId":           "",
+				"schemaPolicy":       "",
 				"strictValidation":   false,
 				"timestamp":          "",
 				"timestampFormat":    "",
@@ -41,6 +41,10 @@ type DecodeOp struct {
 	// This is for first level decode, add the payload field to schema to make sure it is decoded
 	forPayload     bool
 	additionSchema string
+	// keepRaw keeps the raw payload in the tuple for the raw passthrough sinks. The converter only decodes the fields
+	// used by the rule, and fullConverter decodes the list payload whose items have no raw payload of their own.
+	keepRaw       bool
+	fullConverter message.Converter
 }
 
 type dconf struct {
@@ -54,6 +58,8 @@ type dconf struct {
 	PayloadFormat     string            `json:"payloadFormat"`
 	PayloadSchemaId   string            `json:"payloadSchemaId"`
 	PayloadDelimiter  string            `json:"payloadDelimiter"`
+	// StreamDecode decodes the items of a large payload one by one and sends them with backpressure
+	StreamDecode bool `json:"streamDecode"`
 }
 
 func NewDecodeOp(ctx api.StreamContext, forPayload bool, name, StreamName string, rOpt *def.RuleOption, schema map[string]*ast.JsonStreamField, props map[string]any) (*DecodeOp, error) {
@@ -93,6 +99,11 @@ func NewDecodeOp(ctx api.StreamContext, forPayload bool, name, StreamName string
 			msg := fmt.Sprintf("cannot get converter from format %s, schemaId %s: %v", dc.Format, dc.SchemaId, err)
 			return nil, errors.New(msg)
 		}
+		if dc.StreamDecode {
+			if _, ok := converterTool.(message.StreamDecoder); !ok {
+				return nil, fmt.Errorf("format %s does not support streamDecode", dc.Format)
+			}
+		}
 	}
 
 	o := &DecodeOp{
@@ -107,6 +118,17 @@ func NewDecodeOp(ctx api.StreamContext, forPayload bool, name, StreamName string
 	return o, nil
 }
 
+// EnableRawPassthrough keeps the raw payload in the decoded tuples so that the sinks send it as is
+func (o *DecodeOp) EnableRawPassthrough(ctx api.StreamContext, props map[string]any) error {
+	c, err := converter.GetOrCreateConverter(ctx, o.c.Format, o.c.SchemaId, nil, props)
+	if err != nil {
+		return err
+	}
+	o.keepRaw = true
+	o.fullConverter = c
+	return nil
+}
+
 // Exec decode op receives raw data and converts it to message
 func (o *DecodeOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 	o.prepareExec(ctx, errCh, "op")
@@ -114,6 +136,16 @@ func (o *DecodeOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 		defer func() {
 			o.Close()
 		}()
+		if !o.forPayload && o.c.StreamDecode {
+			err := infra.SafeRun(func() error {
+				o.runStream(ctx)
+				return nil
+			})
+			if err != nil {
+				infra.DrainError(ctx, err, errCh)
+			}
+			return
+		}
 		var w workerFunc
 		if o.forPayload {
 			if o.c.PayloadBatchField != "" {
@@ -137,14 +169,28 @@ func (o *DecodeOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 func (o *DecodeOp) Worker(ctx api.StreamContext, item any) []any {
 	switch d := item.(type) {
 	case *xsql.RawTuple:
-		result, err := o.converter.Decode(ctx, d.Raw())
+		result, err := o.decode(ctx, d.Raw(), d.Metadata)
 		if err != nil {
+			if o.emitSideOutput(ctx, SideOutputDecodeError, err, d, nil) {
+				o.onErrorOpt(ctx, err, false)
+				return nil
+			}
 			return []any{err}
 		}
 
+		if _, ok := result.(map[string]any); !ok && o.keepRaw {
+			result, err = o.fullConverter.Decode(ctx, d.Raw())
+			if err != nil {
+				return []any{err}
+			}
+		}
+
 		switch r := result.(type) {
 		case map[string]interface{}:
 			tuple := toTupleFromRawTuple(ctx, r, d)
+			if o.keepRaw {
+				tuple.Rawdata = d.Raw()
+			}
 			return []any{tuple}
 		case []map[string]interface{}:
 			rr := make([]any, len(r))
@@ -171,6 +217,64 @@ func (o *DecodeOp) Worker(ctx api.StreamContext, item any) []any {
 	}
 }
 
+// runStream decodes the raw tuples one by one in order. The decoded items are sent as soon as they are decoded and
+// the decoding is blocked when the downstream is busy, so that the large payload is never fully materialized.
+func (o *DecodeOp) runStream(ctx api.StreamContext) {
+	sd := o.converter.(message.StreamDecoder)
+	for {
+		o.statManager.SetBufferLength(int64(len(o.input)))
+		select {
+		case <-ctx.Done():
+			ctx.GetLogger().Infof("decode op done")
+			return
+		case item := <-o.input:
+			data, processed := o.preprocess(ctx, item)
+			if processed {
+				continue
+			}
+			switch d := data.(type) {
+			case error, *xsql.WatermarkTuple, xsql.EOFTuple:
+				o.send(d)
+			case *xsql.RawTuple:
+				o.onProcessStart(ctx, d)
+				err := sd.DecodeStream(ctx, d.Raw(), func(m map[string]any) error {
+					tuple := toTupleFromRawTuple(ctx, m, d)
+					o.send(tuple)
+					o.onSend(ctx, tuple)
+					if sendInterval := time.Duration(o.c.SendInterval); sendInterval > 0 {
+						time.Sleep(sendInterval)
+					}
+					return ctx.Err()
+				})
+				if err != nil && ctx.Err() == nil {
+					o.onErrorOpt(ctx, err, false)
+					if !o.emitSideOutput(ctx, SideOutputDecodeError, err, d, nil) {
+						o.send(err)
+					}
+				}
+				o.onProcessEnd(ctx)
+			default:
+				err := fmt.Errorf("unsupported data received: %v", d)
+				o.onErrorOpt(ctx, err, false)
+				o.send(err)
+			}
+		}
+	}
+}
+
+// send waits for the downstream to consume instead of dropping the oldest when the buffer is full
+func (o *DecodeOp) send(val any) {
+	o.BroadcastCustomized(val, o.doBlockingBroadcast)
+}
+
+// decode passes the metadata to the converter which needs it, such as the topic for sparkplug
+func (o *DecodeOp) decode(ctx api.StreamContext, raw []byte, meta map[string]any) (any, error) {
+	if md, ok := o.converter.(message.MetaDecoder); ok {
+		return md.DecodeWithMeta(ctx, raw, meta)
+	}
+	return o.converter.Decode(ctx, raw)
+}
+
 func (o *DecodeOp) AttachSchema(ctx api.StreamContext, dataSource string, schema map[string]*ast.JsonStreamField, isWildcard bool) {
 	if fastDecoder, ok := o.converter.(message.SchemaResetAbleConverter); ok {
 		ctx.GetLogger().Infof("attach schema to shared stream")
@@ -233,8 +337,14 @@ func (o *DecodeOp) PayloadDecodeWorker(ctx api.StreamContext, item any) []any {
 		if err != nil {
 			return []any{fmt.Errorf("payload is not bytes: %v", err)}
 		}
-		result, err := o.converter.Decode(ctx, raw)
+		result, err := o.decode(ctx, raw, d.Metadata)
 		if err != nil {
+			// Restore the payload for the side output
+			d.Message[o.c.PayloadField] = payload
+			if o.emitSideOutput(ctx, SideOutputDecodeError, err, d, nil) {
+				o.onErrorOpt(ctx, err, false)
+				return nil
+			}
 			return []any{err}
 		}
 		return transTuple(d, result)
@@ -334,7 +444,7 @@ func (o *DecodeOp) PayloadBatchDecodeWorker(ctx api.StreamContext, item any) []a
 				ctx.GetLogger().Warnf("payload is not bytes: %v", err)
 				continue
 			}
-			result, err := o.converter.Decode(ctx, raw)
+			result, err := o.decode(ctx, raw, d.Metadata)
 			if err != nil {
 				ctx.GetLogger().Warnf("cannot decode payload: %v", err)
 				continue
@@ -91,6 +91,55 @@ func TestJSON(t *testing.T) {
 	}
 }
 
+func TestStreamDecode(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "Test")
+	op, err := NewDecodeOp(ctx, false, "test", "streamName", &def.RuleOption{BufferLength: 10, SendError: true}, nil, map[string]any{
+		"streamDecode": true,
+	})
+	require.NoError(t, err)
+	// The output buffer is smaller than the decoded items, no item should be dropped
+	out := make(chan any, 1)
+	err = op.AddOutput(out, "test")
+	require.NoError(t, err)
+	ctx = mockContext.NewMockContext("test1", "decode_test")
+	errCh := make(chan error)
+	op.Exec(ctx, errCh)
+
+	meta := map[string]any{"topic": "demo"}
+	cases := []any{
+		&xsql.RawTuple{Emitter: "test", Rawdata: []byte("{\"a\":1}\n\n{\"a\":2}\n{\"a\":3}\n"), Timestamp: time.UnixMilli(111), Metadata: meta},
+		&xsql.RawTuple{Emitter: "test", Rawdata: []byte(" [{\"a\":4},{\"a\":5}]"), Timestamp: time.UnixMilli(111), Metadata: meta},
+		errors.New("go through error"),
+		&xsql.RawTuple{Emitter: "test", Rawdata: []byte("{\"a\":6}\n{\"a\":"), Timestamp: time.UnixMilli(111), Metadata: meta},
+		"invalid",
+	}
+	expects := []any{
+		&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1.0}, Timestamp: time.UnixMilli(111), Metadata: meta},
+		&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 2.0}, Timestamp: time.UnixMilli(111), Metadata: meta},
+		&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 3.0}, Timestamp: time.UnixMilli(111), Metadata: meta},
+		&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 4.0}, Timestamp: time.UnixMilli(111), Metadata: meta},
+		&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 5.0}, Timestamp: time.UnixMilli(111), Metadata: meta},
+		errors.New("go through error"),
+		&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 6.0}, Timestamp: time.UnixMilli(111), Metadata: meta},
+		errors.New("item 1: unexpected EOF"),
+		errors.New("unsupported data received: invalid"),
+	}
+	go func() {
+		for _, c := range cases {
+			op.input <- c
+		}
+	}()
+	for _, e := range expects {
+		r := <-out
+		switch tr := r.(type) {
+		case error:
+			require.Equal(t, e.(error).Error(), tr.Error())
+		default:
+			assert.Equal(t, e, r)
+		}
+	}
+}
+
 // Concurrency 1 - BenchmarkThrougput-16                  1        1548680100 ns/op
 // Concurrency 10 - BenchmarkThrougput-16           1000000000               0.1553 ns/op
 // This is useful when a node is much slower
@@ -113,6 +162,22 @@ func BenchmarkThrougput(b *testing.B) {
 	}
 }
 
+func TestDecodeKeepRaw(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "Test")
+	op, err := NewDecodeOp(ctx, false, "test", "streamName", &def.RuleOption{BufferLength: 10, SendError: true}, map[string]*ast.JsonStreamField{"a": nil}, map[string]any{})
+	require.NoError(t, err)
+	require.NoError(t, op.EnableRawPassthrough(ctx, map[string]any{}))
+	raw := []byte(`{"a":1,"b":{"c":[1,2]}}`)
+	r := op.Worker(ctx, &xsql.RawTuple{Emitter: "test", Rawdata: raw, Timestamp: time.UnixMilli(111)})
+	assert.Equal(t, []any{&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1.0}, Timestamp: time.UnixMilli(111), Rawdata: raw}}, r)
+	// The items of the list are decoded as a whole without the raw payload
+	r = op.Worker(ctx, &xsql.RawTuple{Emitter: "test", Rawdata: []byte(`[{"a":1,"b":2},{"a":3}]`), Timestamp: time.UnixMilli(111)})
+	assert.Equal(t, []any{
+		&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 1.0, "b": 2.0}, Timestamp: time.UnixMilli(111)},
+		&xsql.Tuple{Emitter: "test", Message: map[string]any{"a": 3.0}, Timestamp: time.UnixMilli(111)},
+	}, r)
+}
+
 func TestJSONWithSchema(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -247,6 +312,8 @@ func TestValidate(t *testing.T) {
 	_, err = NewDecodeOp(ctx, true, "test", "streamName", &def.RuleOption{BufferLength: 10, SendError: true}, nil, map[string]any{"payloadField": "abc", "payloadFormat": "test"})
 	assert.Error(t, err)
 	assert.EqualError(t, err, "cannot get converter from format test, schemaId : format type test not supported")
+	_, err = NewDecodeOp(ctx, false, "test", "streamName", &def.RuleOption{BufferLength: 10, SendError: true}, nil, map[string]any{"format": "delimited", "streamDecode": true})
+	assert.EqualError(t, err, "format delimited does not support streamDecode")
 }
 
 func TestPayloadDecodeWithSchema(t *testing.T) {
@@ -0,0 +1,76 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/encryptor"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestNewDecryptOp(t *testing.T) {
+	_, err := NewDecryptOp("test", &def.RuleOption{}, "non", nil)
+	assert.EqualError(t, err, "get decryptor non fail with error: decryptor 'non' is not supported")
+}
+
+func TestDecryptOp_Exec(t *testing.T) {
+	conf.InitConf()
+	conf.Config.EncryptionKeys = map[string][]byte{"device": []byte("0123456789abcdef0123456789abcdef")}
+	props := map[string]any{"keyId": "device"}
+	enc, err := encryptor.GetEncryptor("chacha20", props)
+	assert.NoError(t, err)
+	secret, err := enc.Encrypt([]byte(`{"a":1}`))
+	assert.NoError(t, err)
+
+	op, err := NewDecryptOp("test", &def.RuleOption{BufferLength: 10, SendError: true}, "chacha20", props)
+	assert.NoError(t, err)
+	out := make(chan any, 100)
+	err = op.AddOutput(out, "test")
+	assert.NoError(t, err)
+	ctx := mockContext.NewMockContext("test1", "decrypt_test")
+	errCh := make(chan error)
+	op.Exec(ctx, errCh)
+
+	cases := []any{
+		&xsql.RawTuple{Emitter: "test", Rawdata: secret, Timestamp: time.UnixMilli(111)},
+		&xsql.RawTuple{Emitter: "test", Rawdata: []byte("abc"), Timestamp: time.UnixMilli(111)},
+		errors.New("go through error"),
+		"invalid",
+	}
+	expects := []any{
+		&xsql.RawTuple{Emitter: "test", Rawdata: []byte(`{"a":1}`), Timestamp: time.UnixMilli(111)},
+		errors.New("decrypt error: ciphertext too short"),
+		errors.New("go through error"),
+		errors.New("unsupported data received: invalid"),
+	}
+	for i, c := range cases {
+		op.input <- c
+		r := <-out
+		switch e := expects[i].(type) {
+		case error:
+			assert.EqualError(t, r.(error), e.Error())
+		default:
+			assert.Equal(t, e, r)
+		}
+	}
+}
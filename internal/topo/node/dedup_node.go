@@ -0,0 +1,179 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/metrics"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const DedupKey = "$$dedupKeys"
+
+func init() {
+	gob.Register(map[string]int64{})
+}
+
+// DedupNode drops the rows whose key has been seen in the last ttl duration.
+// The ttl starts from the first occurrence of the key; the duplicates do not extend it.
+// The seen keys are saved in the state, so they will survive a restart if the rule qos is at least once.
+// If the rule option stateMaxKeys is set, the oldest keys beyond it are evicted.
+type DedupNode struct {
+	*defaultSinkNode
+	key ast.Expr
+	ttl time.Duration
+	// state: key -> expire time in unix milli
+	seen map[string]int64
+	// tracker evicts the keys in the order of their first occurrence
+	tracker *kctx.KeyTracker
+}
+
+func NewDedupNode(name string, key ast.Expr, ttl time.Duration, options *def.RuleOption) (*DedupNode, error) {
+	if key == nil {
+		return nil, fmt.Errorf("dedup key is required")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("dedup ttl must be positive but got %v", ttl)
+	}
+	return &DedupNode{
+		defaultSinkNode: newDefaultSinkNode(name, options),
+		key:             key,
+		ttl:             ttl,
+		seen:            make(map[string]int64),
+		tracker:         kctx.NewKeyTracker(kctx.StateLimit{TTL: ttl, MaxKeys: options.StateMaxKeys}),
+	}, nil
+}
+
+func (n *DedupNode) Exec(ctx api.StreamContext, errCh chan<- error) {
+	n.prepareExec(ctx, errCh, "op")
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	go func() {
+		defer func() {
+			n.Close()
+		}()
+		err := infra.SafeRun(func() error {
+			if s, err := ctx.GetState(DedupKey); err == nil {
+				switch st := s.(type) {
+				case map[string]int64:
+					n.restore(st)
+					ctx.GetLogger().Infof("Restore dedup state with %d keys", len(n.seen))
+				case nil:
+					ctx.GetLogger().Debugf("Restore dedup state, nothing")
+				default:
+					return fmt.Errorf("restore dedup state %v error, invalid type", st)
+				}
+			} else {
+				ctx.GetLogger().Warnf("Restore dedup state fails: %s", err)
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					ctx.GetLogger().Infof("dedup node %s is finished", n.name)
+					return nil
+				case item := <-n.input:
+					data, processed := n.commonIngest(ctx, item)
+					if processed {
+						break
+					}
+					n.onProcessStart(ctx, data)
+					switch d := data.(type) {
+					case xsql.Row:
+						dup, err := n.isDuplicate(d, fv, timex.GetNow())
+						if err != nil {
+							n.onError(ctx, err)
+						} else if !dup {
+							n.Broadcast(d)
+							n.onSend(ctx, d)
+						}
+						_ = ctx.PutState(DedupKey, n.seen)
+					default:
+						n.onError(ctx, fmt.Errorf("run dedup op error: expect xsql.Row type but got %[1]T(%[1]v)", d))
+					}
+					n.onProcessEnd(ctx)
+					n.statManager.SetBufferLength(int64(len(n.input)))
+				}
+			}
+		})
+		if err != nil {
+			infra.DrainError(ctx, err, errCh)
+		}
+	}()
+}
+
+// isDuplicate returns whether the key of the row has been seen and records it if not.
+// Rows with a nil key are never duplicated.
+func (n *DedupNode) isDuplicate(row xsql.Row, fv *xsql.FunctionValuer, now time.Time) (bool, error) {
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(row, fv)}
+	k := ve.Eval(n.key)
+	switch kt := k.(type) {
+	case error:
+		return false, fmt.Errorf("evaluate dedup key error: %v", kt)
+	case nil:
+		return false, nil
+	}
+	n.sweep(now)
+	key := fmt.Sprintf("%v", k)
+	nowMilli := now.UnixMilli()
+	if exp, ok := n.seen[key]; ok && exp > nowMilli {
+		return true, nil
+	}
+	n.seen[key] = now.Add(n.ttl).UnixMilli()
+	expired, overflow := n.tracker.Touch(key, now)
+	n.evict(expired, metrics.LblEvictTTL)
+	n.evict(overflow, metrics.LblEvictSize)
+	return false, nil
+}
+
+// sweep removes the expired keys
+func (n *DedupNode) sweep(now time.Time) {
+	n.evict(n.tracker.Expire(now), metrics.LblEvictTTL)
+}
+
+func (n *DedupNode) evict(keys []string, reason string) {
+	for _, k := range keys {
+		delete(n.seen, k)
+	}
+	if n.ctx != nil {
+		metrics.AddStateEvictions(n.ctx.GetRuleId(), n.ctx.GetOpId(), reason, len(keys))
+	}
+}
+
+// restore rebuilds the keys and their order of occurrence from the saved state
+func (n *DedupNode) restore(st map[string]int64) {
+	keys := make([]string, 0, len(st))
+	for k := range st {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return st[keys[i]] < st[keys[j]]
+	})
+	n.seen = st
+	for _, k := range keys {
+		expired, overflow := n.tracker.Touch(k, time.UnixMilli(st[k]).Add(-n.ttl))
+		n.evict(expired, metrics.LblEvictTTL)
+		n.evict(overflow, metrics.LblEvictSize)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestDedup(t *testing.T) {
+	n, err := NewDedupNode("test", &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}, 10*time.Second, &def.RuleOption{BufferLength: 10})
+	require.NoError(t, err)
+	ctx := context.NewMockContext("testDedup", "test")
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	t0 := time.UnixMilli(100000)
+	tests := []struct {
+		msg map[string]any
+		ts  time.Duration
+		dup bool
+	}{
+		{msg: map[string]any{"id": 1}, ts: 0, dup: false},
+		{msg: map[string]any{"id": 2}, ts: time.Second, dup: false},
+		{msg: map[string]any{"id": 1}, ts: 5 * time.Second, dup: true},
+		// nil key is never duplicated
+		{msg: map[string]any{"a": 1}, ts: 6 * time.Second, dup: false},
+		{msg: map[string]any{"a": 1}, ts: 6 * time.Second, dup: false},
+		// ttl is not extended by the duplicate
+		{msg: map[string]any{"id": 1}, ts: 10 * time.Second, dup: false},
+		{msg: map[string]any{"id": 2}, ts: 10 * time.Second, dup: true},
+		{msg: map[string]any{"id": 2}, ts: 11 * time.Second, dup: false},
+	}
+	for i, tt := range tests {
+		dup, err := n.isDuplicate(&xsql.Tuple{Message: tt.msg}, fv, t0.Add(tt.ts))
+		require.NoError(t, err)
+		assert.Equal(t, tt.dup, dup, "case %d", i)
+	}
+	n.sweep(t0.Add(30 * time.Second))
+	assert.Len(t, n.seen, 0)
+}
+
+func TestDedupValidate(t *testing.T) {
+	_, err := NewDedupNode("test", nil, time.Second, &def.RuleOption{})
+	assert.EqualError(t, err, "dedup key is required")
+	_, err = NewDedupNode("test", &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}, 0, &def.RuleOption{})
+	assert.EqualError(t, err, "dedup ttl must be positive but got 0s")
+}
+
+func TestDedupMaxKeys(t *testing.T) {
+	n, err := NewDedupNode("test", &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}, 10*time.Second, &def.RuleOption{BufferLength: 10, StateMaxKeys: 2})
+	require.NoError(t, err)
+	ctx := context.NewMockContext("testDedupMaxKeys", "test")
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	t0 := time.UnixMilli(100000)
+	for i, id := range []int{1, 2, 1, 3} {
+		_, err := n.isDuplicate(&xsql.Tuple{Message: map[string]any{"id": id}}, fv, t0.Add(time.Duration(i)*time.Second))
+		require.NoError(t, err)
+	}
+	// The oldest key 1 is evicted, so it is not a duplicate anymore
+	assert.Equal(t, map[string]int64{"2": 111000, "3": 113000}, n.seen)
+	dup, err := n.isDuplicate(&xsql.Tuple{Message: map[string]any{"id": 1}}, fv, t0.Add(4*time.Second))
+	require.NoError(t, err)
+	assert.False(t, dup)
+	assert.Len(t, n.seen, 2)
+
+	// Restore keeps the newest keys
+	r, err := NewDedupNode("test", &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}, 10*time.Second, &def.RuleOption{BufferLength: 10, StateMaxKeys: 1})
+	require.NoError(t, err)
+	r.restore(map[string]int64{"a": 112000, "b": 111000})
+	assert.Equal(t, map[string]int64{"a": 112000}, r.seen)
+}
@@ -16,6 +16,7 @@ package node
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
@@ -113,9 +114,21 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 	log := ctx.GetLogger()
 	nextWindowEndTs := timex.Maxtime
 	prevWindowEndTs := time.Time{}
+	lastWatermarkTs := time.Time{}
 	var lastTicked bool
+	var earlyC <-chan time.Time
+	if o.earlyInterval > 0 {
+		earlyTicker := timex.GetTicker(o.earlyInterval)
+		defer earlyTicker.Stop()
+		earlyC = earlyTicker.C
+	}
 	for {
 		select {
+		// The early results are fired by the processing time up to the current watermark
+		case <-earlyC:
+			if !lastWatermarkTs.IsZero() {
+				o.fireEarly(ctx, inputs, lastWatermarkTs)
+			}
 		// process incoming item
 		case item := <-o.input:
 			data, processed := o.ingest(ctx, item)
@@ -126,6 +139,10 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 			case *xsql.WatermarkTuple:
 				ctx.GetLogger().Debug("WatermarkTuple", d.GetTimestamp())
 				watermarkTs := d.GetTimestamp()
+				lastWatermarkTs = watermarkTs
+				if o.allowedLateness > 0 {
+					o.gcLateInputs(ctx, watermarkTs)
+				}
 				if o.window.Type == ast.SLIDING_WINDOW {
 					for len(o.delayTS) > 0 && (watermarkTs.After(o.delayTS[0]) || watermarkTs.Equal(o.delayTS[0])) {
 						inputs = o.scan(inputs, o.delayTS[0], ctx)
@@ -140,7 +157,8 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 					if o.window.Type == ast.SESSION_WINDOW {
 						windowEndTs, ticked = o.trigger.getNextSessionWindow(inputs, watermarkTs)
 					} else {
-						windowEndTs = o.trigger.getNextWindow(inputs, prevWindowEndTs, watermarkTs)
+						// The first window end is calculated by the earliest input which may be spilled
+						windowEndTs = o.trigger.getNextWindow(o.withSpilledHead(inputs), prevWindowEndTs, watermarkTs)
 					}
 				}
 				for !windowEndTs.IsZero() && (windowEndTs.Before(watermarkTs) || windowEndTs.Equal(watermarkTs)) {
@@ -183,10 +201,15 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 				if o.triggerTime.IsZero() {
 					o.triggerTime = d.Timestamp
 				}
-				if o.window.Type == ast.SLIDING_WINDOW && o.isMatchCondition(ctx, d) {
-					o.triggerTS = append(o.triggerTS, d.Timestamp)
+				if o.allowedLateness > 0 && d.Timestamp.Before(lastWatermarkTs) {
+					inputs = o.handleLateTuple(ctx, inputs, d, prevWindowEndTs)
+				} else {
+					if o.window.Type == ast.SLIDING_WINDOW && o.isMatchCondition(ctx, d) {
+						o.triggerTS = append(o.triggerTS, d.Timestamp)
+					}
+					inputs = append(inputs, d)
+					inputs = o.spillInputs(ctx, inputs)
 				}
-				inputs = append(inputs, d)
 				o.span = nil
 				o.onProcessEnd(ctx)
 				_ = ctx.PutState(WindowInputsKey, inputs)
@@ -204,6 +227,73 @@ func (o *WindowOperator) execEventWindow(ctx api.StreamContext, inputs []*xsql.T
 	}
 }
 
+// handleLateTuple adds the tuple arriving after the watermark and fires the updated results of the windows
+// which have been fired and contain the tuple. Only tumbling and hopping windows are supported.
+func (o *WindowOperator) handleLateTuple(ctx api.StreamContext, inputs []*xsql.Tuple, d *xsql.Tuple, prevWindowEndTs time.Time) []*xsql.Tuple {
+	log := ctx.GetLogger()
+	if o.window.Type != ast.TUMBLING_WINDOW && o.window.Type != ast.HOPPING_WINDOW {
+		log.Debugf("drop late tuple at %d for unsupported window type", d.Timestamp.UnixMilli())
+		return inputs
+	}
+	length := o.window.Length
+	inputs = o.loadInputs(ctx, inputs)
+	// The tuple is still in a window which is not fired yet
+	if prevWindowEndTs.IsZero() || !d.Timestamp.Before(prevWindowEndTs.Add(o.trigger.interval).Add(-length)) {
+		return insertTuple(inputs, d)
+	}
+	o.lateInputs = insertTuple(o.lateInputs, d)
+	_ = ctx.PutState(LateInputsKey, o.lateInputs)
+	// The fired windows are aligned to the last fired window end
+	for end := prevWindowEndTs; end.After(d.Timestamp); end = end.Add(-o.trigger.interval) {
+		start := end.Add(-length)
+		if start.After(d.Timestamp) {
+			continue
+		}
+		content := make([]xsql.Row, 0)
+		for _, ts := range [][]*xsql.Tuple{o.lateInputs, inputs} {
+			for _, t := range ts {
+				if !t.Timestamp.Before(start) && t.Timestamp.Before(end) {
+					content = append(content, t)
+				}
+			}
+		}
+		sort.SliceStable(content, func(i, j int) bool {
+			return content[i].(*xsql.Tuple).Timestamp.Before(content[j].(*xsql.Tuple).Timestamp)
+		})
+		results := &xsql.WindowTuples{
+			Content:     content,
+			WindowRange: xsql.NewWindowRange(start.UnixMilli(), end.UnixMilli()),
+		}
+		log.Debugf("window %s fire updated result [%d, %d) for late tuple at %d", o.name, start.UnixMilli(), end.UnixMilli(), d.Timestamp.UnixMilli())
+		o.Broadcast(results)
+		o.onSend(ctx, results)
+	}
+	return inputs
+}
+
+// gcLateInputs removes the fired tuples which cannot be in any window updated by the tuples within the allowed lateness
+func (o *WindowOperator) gcLateInputs(ctx api.StreamContext, watermarkTs time.Time) {
+	bound := watermarkTs.Add(-o.allowedLateness).Add(-o.window.Length)
+	i := sort.Search(len(o.lateInputs), func(i int) bool {
+		return !o.lateInputs[i].Timestamp.Before(bound)
+	})
+	if i > 0 {
+		o.lateInputs = o.lateInputs[i:]
+		_ = ctx.PutState(LateInputsKey, o.lateInputs)
+	}
+}
+
+// insertTuple inserts the tuple into the tuples sorted by timestamp
+func insertTuple(tuples []*xsql.Tuple, d *xsql.Tuple) []*xsql.Tuple {
+	index := sort.Search(len(tuples), func(i int) bool {
+		return tuples[i].Timestamp.After(d.Timestamp)
+	})
+	tuples = append(tuples, nil)
+	copy(tuples[index+1:], tuples[index:])
+	tuples[index] = d
+	return tuples
+}
+
 func getEarliestEventTs(inputs []*xsql.Tuple, startTs time.Time, endTs time.Time) time.Time {
 	minTs := timex.Maxtime
 	for _, t := range inputs {
@@ -0,0 +1,250 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const (
+	IntervalJoinStateKey = "$$intervalJoinState"
+	// intervalJoinCleanInterval is the interval to clean up the expired rows in processing time mode
+	intervalJoinCleanInterval = time.Second
+)
+
+// IntervalJoinConf is the config of the interval join. The right time minus the left time must be in [Lower, Upper].
+type IntervalJoinConf struct {
+	Left      string
+	Right     string
+	JoinType  ast.JoinType
+	Condition ast.Expr
+	LeftTime  ast.Expr
+	RightTime ast.Expr
+	Lower     int64
+	Upper     int64
+}
+
+// IntervalJoinState is the rows of both streams which may still be joined
+type IntervalJoinState struct {
+	Lefts  []*IntervalJoinRow
+	Rights []*IntervalJoinRow
+}
+
+type IntervalJoinRow struct {
+	Tuple  *xsql.Tuple
+	Ts     int64
+	Joined bool
+}
+
+func init() {
+	gob.Register(&IntervalJoinState{})
+}
+
+// IntervalJoinNode joins two streams without window. Each row is joined with the rows of the other stream whose time is
+// within the interval relative to its time. The rows are kept until they can not be joined anymore which is decided by
+// the watermark in event time mode or the processing time otherwise.
+type IntervalJoinNode struct {
+	*defaultSinkNode
+	conf        *IntervalJoinConf
+	isEventTime bool
+	state       *IntervalJoinState
+}
+
+func NewIntervalJoinNode(name string, conf *IntervalJoinConf, options *def.RuleOption) *IntervalJoinNode {
+	return &IntervalJoinNode{
+		defaultSinkNode: newDefaultSinkNode(name, options),
+		conf:            conf,
+		isEventTime:     options.IsEventTime,
+		state:           &IntervalJoinState{},
+	}
+}
+
+func (n *IntervalJoinNode) Exec(ctx api.StreamContext, errCh chan<- error) {
+	n.prepareExec(ctx, errCh, "op")
+	log := ctx.GetLogger()
+	go func() {
+		defer func() {
+			n.Close()
+		}()
+		err := infra.SafeRun(func() error {
+			if s, err := ctx.GetState(IntervalJoinStateKey); err == nil {
+				switch st := s.(type) {
+				case *IntervalJoinState:
+					n.state = st
+					log.Infof("Restore interval join state %+v", st)
+				case nil:
+					log.Debugf("Restore interval join state, nothing")
+				default:
+					return fmt.Errorf("restore interval join state %v error, invalid type", st)
+				}
+			} else {
+				log.Warnf("Restore interval join state fails: %s", err)
+			}
+			fv, _ := xsql.NewFunctionValuersForOp(ctx)
+			var tickCh <-chan time.Time
+			if !n.isEventTime {
+				ticker := timex.GetTicker(intervalJoinCleanInterval)
+				defer ticker.Stop()
+				tickCh = ticker.C
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					log.Info("Cancelling interval join node....")
+					return nil
+				case <-tickCh:
+					n.expire(ctx, timex.GetNowInMilli())
+				case item := <-n.input:
+					if wm, ok := item.(*xsql.WatermarkTuple); ok {
+						n.expire(ctx, wm.GetTimestamp().UnixMilli())
+					}
+					data, processed := n.commonIngest(ctx, item)
+					if processed {
+						break
+					}
+					n.onProcessStart(ctx, data)
+					switch d := data.(type) {
+					case *xsql.Tuple:
+						if err := n.join(ctx, d, fv); err != nil {
+							n.onError(ctx, err)
+						}
+						if !n.isEventTime {
+							n.expire(ctx, timex.GetNowInMilli())
+						}
+					default:
+						n.onError(ctx, fmt.Errorf("run interval join error: expect *xsql.Tuple type but got %[1]T(%[1]v)", d))
+					}
+					n.onProcessEnd(ctx)
+					n.statManager.SetBufferLength(int64(len(n.input)))
+				}
+			}
+		})
+		if err != nil {
+			infra.DrainError(ctx, err, errCh)
+		}
+	}()
+}
+
+// join joins the row with the kept rows of the other stream and keeps the row
+func (n *IntervalJoinNode) join(ctx api.StreamContext, d *xsql.Tuple, fv *xsql.FunctionValuer) error {
+	var (
+		isLeft   bool
+		timeExpr ast.Expr
+		others   []*IntervalJoinRow
+	)
+	switch d.Emitter {
+	case n.conf.Left:
+		isLeft, timeExpr, others = true, n.conf.LeftTime, n.state.Rights
+	case n.conf.Right:
+		timeExpr, others = n.conf.RightTime, n.state.Lefts
+	default:
+		return fmt.Errorf("run interval join error: unknown stream %s", d.Emitter)
+	}
+	tv := (&xsql.ValuerEval{Valuer: xsql.MultiValuer(d, fv)}).Eval(timeExpr)
+	if e, ok := tv.(error); ok {
+		return fmt.Errorf("run interval join error: %v", e)
+	}
+	ts, err := cast.InterfaceToUnixMilli(tv, "")
+	if err != nil {
+		return fmt.Errorf("run interval join error: invalid time %s of stream %s: %v", timeExpr, d.Emitter, err)
+	}
+	row := &IntervalJoinRow{Tuple: d, Ts: ts}
+	sets := &xsql.JoinTuples{Content: make([]*xsql.JoinTuple, 0)}
+	for _, o := range others {
+		left, right := row, o
+		if !isLeft {
+			left, right = o, row
+		}
+		if diff := right.Ts - left.Ts; diff < n.conf.Lower || diff > n.conf.Upper {
+			continue
+		}
+		merged := &xsql.JoinTuple{}
+		merged.AddTuple(left.Tuple)
+		merged.AddTuple(right.Tuple)
+		if n.conf.Condition != nil {
+			ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(merged, fv)}
+			switch r := ve.Eval(n.conf.Condition).(type) {
+			case error:
+				return fmt.Errorf("run interval join error: %v", r)
+			case bool:
+				if !r {
+					continue
+				}
+			default:
+				return fmt.Errorf("run interval join error: invalid join condition that returns non-bool value %[1]T(%[1]v)", r)
+			}
+		}
+		left.Joined, right.Joined = true, true
+		sets.Content = append(sets.Content, merged)
+	}
+	if isLeft {
+		n.state.Lefts = append(n.state.Lefts, row)
+	} else {
+		n.state.Rights = append(n.state.Rights, row)
+	}
+	_ = ctx.PutState(IntervalJoinStateKey, n.state)
+	n.emit(ctx, sets)
+	return nil
+}
+
+// expire removes the rows which can not be joined by the rows later than now.
+// For left join, the left rows which are never joined are sent out when expired.
+func (n *IntervalJoinNode) expire(ctx api.StreamContext, now int64) {
+	sets := &xsql.JoinTuples{Content: make([]*xsql.JoinTuple, 0)}
+	lefts := n.state.Lefts[:0]
+	for _, l := range n.state.Lefts {
+		if l.Ts+n.conf.Upper >= now {
+			lefts = append(lefts, l)
+			continue
+		}
+		if n.conf.JoinType == ast.LEFT_JOIN && !l.Joined {
+			merged := &xsql.JoinTuple{}
+			merged.AddTuple(l.Tuple)
+			sets.Content = append(sets.Content, merged)
+		}
+	}
+	changed := len(lefts) != len(n.state.Lefts)
+	n.state.Lefts = lefts
+	rights := n.state.Rights[:0]
+	for _, r := range n.state.Rights {
+		if r.Ts-n.conf.Lower >= now {
+			rights = append(rights, r)
+		}
+	}
+	changed = changed || len(rights) != len(n.state.Rights)
+	n.state.Rights = rights
+	if changed {
+		_ = ctx.PutState(IntervalJoinStateKey, n.state)
+	}
+	n.emit(ctx, sets)
+}
+
+func (n *IntervalJoinNode) emit(ctx api.StreamContext, sets *xsql.JoinTuples) {
+	if sets.Len() > 0 {
+		n.Broadcast(sets)
+		n.onSend(ctx, sets)
+	}
+}
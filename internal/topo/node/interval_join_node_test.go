@@ -0,0 +1,132 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestIntervalJoin(t *testing.T) {
+	newTuple := func(emitter string, id int, ts int64) *xsql.Tuple {
+		return &xsql.Tuple{Emitter: emitter, Message: map[string]any{"id": id, "ts": ts}}
+	}
+	joined := func(rows ...*xsql.Tuple) *xsql.JoinTuple {
+		jt := &xsql.JoinTuple{}
+		for _, r := range rows {
+			jt.AddTuple(r)
+		}
+		return jt
+	}
+	a1, a2, a3 := newTuple("a", 1, 1000), newTuple("a", 2, 1000), newTuple("a", 1, 3000)
+	b1, b2, b3 := newTuple("b", 1, 1500), newTuple("b", 2, 7000), newTuple("b", 1, 3500)
+	tests := []struct {
+		name     string
+		joinType ast.JoinType
+		// the input tuple or the watermark
+		inputs  []any
+		outputs [][]*xsql.JoinTuple
+	}{
+		{
+			name:     "inner join",
+			joinType: ast.INNER_JOIN,
+			inputs:   []any{a1, a2, b1, b2, int64(6100), a3, b3},
+			outputs: [][]*xsql.JoinTuple{
+				nil,
+				nil,
+				{joined(a1, b1)},
+				// a2 is out of the interval
+				nil,
+				// a1 and a2 are expired
+				nil,
+				nil,
+				{joined(a3, b3)},
+			},
+		},
+		{
+			name:     "left join",
+			joinType: ast.LEFT_JOIN,
+			inputs:   []any{a1, a2, b1, b2, int64(6100), a3, b3},
+			outputs: [][]*xsql.JoinTuple{
+				nil,
+				nil,
+				{joined(a1, b1)},
+				nil,
+				// a2 is never joined
+				{joined(a2)},
+				nil,
+				{joined(a3, b3)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := mockContext.NewMockContext("test", "intervalJoin")
+			n := NewIntervalJoinNode("test", &IntervalJoinConf{
+				Left:      "a",
+				Right:     "b",
+				JoinType:  tt.joinType,
+				Condition: &ast.BinaryExpr{OP: ast.EQ, LHS: &ast.FieldRef{StreamName: "a", Name: "id"}, RHS: &ast.FieldRef{StreamName: "b", Name: "id"}},
+				LeftTime:  &ast.FieldRef{StreamName: "a", Name: "ts"},
+				RightTime: &ast.FieldRef{StreamName: "b", Name: "ts"},
+				Lower:     0,
+				Upper:     5000,
+			}, &def.RuleOption{IsEventTime: true})
+			n.prepareExec(ctx, make(chan error, 10), "op")
+			out := make(chan any, 10)
+			n.outputs["mock"] = out
+			fv, _ := xsql.NewFunctionValuersForOp(ctx)
+			for i, in := range tt.inputs {
+				switch v := in.(type) {
+				case *xsql.Tuple:
+					require.NoError(t, n.join(ctx, v, fv))
+				case int64:
+					n.expire(ctx, v)
+				}
+				var result []*xsql.JoinTuple
+				select {
+				case r := <-out:
+					result = r.(*xsql.JoinTuples).Content
+				default:
+				}
+				assert.Equal(t, tt.outputs[i], result, "output %d", i)
+			}
+		})
+	}
+}
+
+func TestIntervalJoinError(t *testing.T) {
+	ctx := mockContext.NewMockContext("test", "intervalJoin")
+	n := NewIntervalJoinNode("test", &IntervalJoinConf{
+		Left:      "a",
+		Right:     "b",
+		JoinType:  ast.INNER_JOIN,
+		LeftTime:  &ast.FieldRef{StreamName: "a", Name: "ts"},
+		RightTime: &ast.FieldRef{StreamName: "b", Name: "ts"},
+		Upper:     5000,
+	}, &def.RuleOption{})
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	err := n.join(ctx, &xsql.Tuple{Emitter: "c", Message: map[string]any{"ts": 1}}, fv)
+	assert.EqualError(t, err, "run interval join error: unknown stream c")
+	err = n.join(ctx, &xsql.Tuple{Emitter: "a", Message: map[string]any{"ts": true}}, fv)
+	assert.EqualError(t, err, "run interval join error: invalid time a.ts of stream a: unsupported type to convert to timestamp true")
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/lookup"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/lookup/cache"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
@@ -37,6 +38,17 @@ type LookupConf struct {
 	Cache           bool              `json:"cache"`
 	CacheTTL        cast.DurationConf `json:"cacheTtl"`
 	CacheMissingKey bool              `json:"cacheMissingKey"`
+	CacheMaxEntries int               `json:"cacheMaxEntries"`
+	// CacheRefreshAhead is the duration before the expiration to reload the read cache items in the background
+	CacheRefreshAhead cast.DurationConf `json:"cacheRefreshAhead"`
+	// CachePreload loads all the rows of the table into the cache when the rule starts
+	CachePreload bool `json:"cachePreload"`
+	// VersionField is the field of the lookup rows to indicate the time from which the version is valid.
+	// If set, the row is joined with the version valid at its timestamp.
+	VersionField string `json:"versionField"`
+	// EntityFields are the fields to identify an entity among the rows of a lookup. The valid version of each entity is
+	// joined. Default to the lookup keys.
+	EntityFields []string `json:"entityFields"`
 }
 
 type srcConf struct {
@@ -61,6 +73,13 @@ type LookupNode struct {
 	isBytesLookup  bool
 	formatDecoder  message.Converter
 	payloadDecoder message.Converter
+	// source is the lookup source owned by the node instead of the shared table instance, such as a mocked table
+	source api.Source
+}
+
+// SetSource sets the lookup source owned by this node. The shared instance of the table will not be attached.
+func (n *LookupNode) SetSource(s api.Source) {
+	n.source = s
 }
 
 func NewLookupNode(ctx api.StreamContext, name string, isBytesLookup bool, fields []string, keys []string, joinType ast.JoinType, vals []ast.Expr, srcOptions *ast.Options, options *def.RuleOption, props map[string]any) (*LookupNode, error) {
@@ -71,6 +90,12 @@ func NewLookupNode(ctx api.StreamContext, name string, isBytesLookup bool, field
 			return nil, err
 		}
 	}
+	if lookupConf.CacheRefreshAhead > 0 && (lookupConf.CacheTTL <= 0 || lookupConf.CacheRefreshAhead >= lookupConf.CacheTTL) {
+		return nil, fmt.Errorf("lookup cacheRefreshAhead must be less than cacheTtl")
+	}
+	if lookupConf.CacheMaxEntries < 0 {
+		return nil, fmt.Errorf("lookup cacheMaxEntries must not be negative")
+	}
 	n := &LookupNode{
 		fields:        fields,
 		keys:          keys,
@@ -129,21 +154,38 @@ func (n *LookupNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 			n.Close()
 		}()
 		err := infra.SafeRun(func() error {
-			ns, err := lookup.Attach(n.name)
-			if err != nil {
-				return err
+			ns := n.source
+			if ns == nil {
+				var err error
+				ns, err = lookup.Attach(n.name)
+				if err != nil {
+					return err
+				}
+				defer lookup.Detach(n.name)
 			}
-			defer lookup.Detach(n.name)
 			fv, _ := xsql.NewFunctionValuersForOp(ctx)
-			var c *cache.Cache
+			var (
+				c        *cache.Cache
+				refreshC <-chan time.Time
+			)
 			if n.conf.Cache {
-				c = cache.NewCache(time.Duration(n.conf.CacheTTL), n.conf.CacheMissingKey)
+				c = cache.NewCache(time.Duration(n.conf.CacheTTL), n.conf.CacheMissingKey, n.conf.CacheMaxEntries)
 				defer c.Close()
+				if n.conf.CachePreload {
+					n.preload(ctx, ns, c)
+				}
+				if n.conf.CacheRefreshAhead > 0 {
+					refreshTicker := timex.GetTicker(time.Duration(n.conf.CacheRefreshAhead) / 2)
+					defer refreshTicker.Stop()
+					refreshC = refreshTicker.C
+				}
 			}
 			// Start the lookup source loop
 			for {
 				log.Debugf("LookupNode %s is looping", n.name)
 				select {
+				case <-refreshC:
+					n.refresh(ctx, ns, c)
 				// process incoming item from both streams(transformed) and tables
 				case item := <-n.input:
 					data, processed := n.commonIngest(ctx, item)
@@ -223,20 +265,32 @@ func (n *LookupNode) lookup(ctx api.StreamContext, d xsql.Row, fv *xsql.Function
 		if c != nil {
 			k := fmt.Sprintf("%v", cvs)
 			r, ok = c.Get(k)
+			metric.IncLookupCache(ctx.GetRuleId(), n.name, ok)
 			if !ok {
-				r, e = n.doLookup(ctx, ns, cvs)
+				r, e = n.doLookup(ctx, ns, n.keys, cvs)
 				if e != nil {
 					return e
 				}
-				c.Set(k, r)
+				c.Set(k, r, cvs...)
 			}
 		} else {
-			r, e = n.doLookup(ctx, ns, cvs)
+			r, e = n.doLookup(ctx, ns, n.keys, cvs)
 		}
 	}
 	if e != nil {
 		return e
 	} else {
+		if n.conf.VersionField != "" {
+			ts := timex.GetNowInMilli()
+			if ev, ok := d.(xsql.Event); ok {
+				ts = ev.GetTimestamp().UnixMilli()
+			}
+			entityFields := n.conf.EntityFields
+			if len(entityFields) == 0 {
+				entityFields = n.keys
+			}
+			r = pickVersion(ctx, r, n.conf.VersionField, entityFields, ts)
+		}
 		if len(r) == 0 {
 			if n.joinType == ast.LEFT_JOIN {
 				merged := &xsql.JoinTuple{}
@@ -264,9 +318,92 @@ func (n *LookupNode) lookup(ctx api.StreamContext, d xsql.Row, fv *xsql.Function
 	}
 }
 
-func (n *LookupNode) doLookup(ctx api.StreamContext, ns api.Source, cvs []any) ([]map[string]any, error) {
+// pickVersion returns the version valid at the timestamp of each entity, which is the latest version not after it.
+// The entities are identified by the values of the entity fields and are returned in the order of their first rows.
+func pickVersion(ctx api.StreamContext, rows []map[string]any, versionField string, entityFields []string, ts int64) []map[string]any {
+	type picked struct {
+		row     map[string]any
+		version int64
+	}
+	var (
+		order []string
+		group = make(map[string]*picked)
+	)
+	for _, row := range rows {
+		v, ok := row[versionField]
+		if !ok {
+			continue
+		}
+		vt, err := cast.InterfaceToUnixMilli(v, "")
+		if err != nil {
+			ctx.GetLogger().Warnf("invalid version %v of lookup row %v: %v", v, row, err)
+			continue
+		}
+		if vt > ts {
+			continue
+		}
+		entity := make([]any, len(entityFields))
+		for i, f := range entityFields {
+			entity[i] = row[f]
+		}
+		key := fmt.Sprintf("%v", entity)
+		p, ok := group[key]
+		if !ok {
+			group[key] = &picked{row: row, version: vt}
+			order = append(order, key)
+		} else if vt >= p.version {
+			p.row, p.version = row, vt
+		}
+	}
+	if len(order) == 0 {
+		return nil
+	}
+	result := make([]map[string]any, 0, len(order))
+	for _, key := range order {
+		result = append(result, group[key].row)
+	}
+	return result
+}
+
+// preload loads all the rows of the lookup table into the cache grouped by the key values
+func (n *LookupNode) preload(ctx api.StreamContext, ns api.Source, c *cache.Cache) {
+	rows, err := n.doLookup(ctx, ns, nil, nil)
+	if err != nil {
+		ctx.GetLogger().Warnf("preload lookup cache of %s error: %v", n.name, err)
+		return
+	}
+	groups := make(map[string][]map[string]any)
+	values := make(map[string][]any)
+	for _, row := range rows {
+		cvs := make([]any, len(n.keys))
+		for i, k := range n.keys {
+			cvs[i] = row[k]
+		}
+		k := fmt.Sprintf("%v", cvs)
+		groups[k] = append(groups[k], row)
+		values[k] = cvs
+	}
+	for k, g := range groups {
+		c.Set(k, g, values[k]...)
+	}
+	ctx.GetLogger().Infof("preload %d rows into the lookup cache of %s", len(rows), n.name)
+}
+
+// refresh reloads the read cache items which will expire soon so that the lookup does not wait for the source
+func (n *LookupNode) refresh(ctx api.StreamContext, ns api.Source, c *cache.Cache) {
+	for _, cvs := range c.ToRefresh(time.Duration(n.conf.CacheRefreshAhead)) {
+		r, err := n.doLookup(ctx, ns, n.keys, cvs)
+		if err != nil {
+			ctx.GetLogger().Warnf("refresh lookup cache of %v error: %v", cvs, err)
+			continue
+		}
+		c.Set(fmt.Sprintf("%v", cvs), r, cvs...)
+	}
+}
+
+func (n *LookupNode) doLookup(ctx api.StreamContext, ns api.Source, keys []string, cvs []any) ([]map[string]any, error) {
 	if n.isBytesLookup {
-		rawRows, err := ns.(api.LookupBytesSource).Lookup(ctx, n.fields, n.keys, cvs)
+		rawRows, err := ns.(api.LookupBytesSource).Lookup(ctx, n.fields, keys, cvs)
 		if err != nil {
 			return nil, err
 		}
@@ -288,7 +425,7 @@ func (n *LookupNode) doLookup(ctx api.StreamContext, ns api.Source, cvs []any) (
 		}
 		return result, nil
 	} else {
-		return ns.(api.LookupSource).Lookup(ctx, n.fields, n.keys, cvs)
+		return ns.(api.LookupSource).Lookup(ctx, n.fields, keys, cvs)
 	}
 }
 
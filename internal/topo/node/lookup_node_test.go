@@ -22,9 +22,12 @@ import (
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/lookup"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/lookup/cache"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/topotest/mockclock"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
@@ -63,6 +66,11 @@ func TestLookupInit(t *testing.T) {
 		assert.Error(t, err)
 		assert.EqualError(t, err, "cannot get payload converter from payloadFormat json1, schemaId : format type json1 not supported")
 	})
+
+	t.Run("refresh ahead without ttl", func(t *testing.T) {
+		_, err := NewLookupNode(ctx, "test2", false, []string{"la", "lb"}, []string{"test1"}, ast.LEFT_JOIN, []ast.Expr{}, &ast.Options{TYPE: "mock", FORMAT: "json"}, &def.RuleOption{BufferLength: 10, SendError: true}, map[string]any{"lookup": map[string]any{"cache": true, "cacheRefreshAhead": "1s"}})
+		assert.EqualError(t, err, "lookup cacheRefreshAhead must be less than cacheTtl")
+	})
 }
 
 func TestLookup(t *testing.T) {
@@ -857,3 +865,118 @@ func (m *MockLookupBytes) Lookup(ctx api.StreamContext, fields []string, keys []
 		}, nil
 	}
 }
+
+func TestPickVersion(t *testing.T) {
+	ctx := mockContext.NewMockContext("test1", "testPickVersion")
+	rows := []map[string]any{
+		{"id": 1, "name": "v2", "validFrom": int64(2000)},
+		{"id": 1, "name": "v1", "validFrom": 1000},
+		{"id": 1, "name": "v3", "validFrom": time.UnixMilli(3000)},
+		{"id": 1, "name": "invalid", "validFrom": true},
+		{"id": 1, "name": "noVersion"},
+	}
+	tests := []struct {
+		name   string
+		ts     int64
+		result []map[string]any
+	}{
+		{
+			name: "before all versions",
+			ts:   999,
+		},
+		{
+			name:   "first version",
+			ts:     1500,
+			result: []map[string]any{rows[1]},
+		},
+		{
+			name:   "exact version",
+			ts:     2000,
+			result: []map[string]any{rows[0]},
+		},
+		{
+			name:   "latest version",
+			ts:     5000,
+			result: []map[string]any{rows[2]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.result, pickVersion(ctx, rows, "validFrom", []string{"id"}, tt.ts))
+		})
+	}
+}
+
+func TestPickVersionMultiEntity(t *testing.T) {
+	ctx := mockContext.NewMockContext("test1", "testPickVersionMultiEntity")
+	rows := []map[string]any{
+		{"group": "a", "id": 1, "name": "1v1", "validFrom": 1000},
+		{"group": "a", "id": 2, "name": "2v1", "validFrom": 1000},
+		{"group": "a", "id": 1, "name": "1v2", "validFrom": 2000},
+		{"group": "a", "id": 3, "name": "3v1", "validFrom": 3000},
+		{"group": "a", "id": 2, "name": "2v2", "validFrom": 4000},
+	}
+	// Each entity has its own valid version
+	assert.Equal(t, []map[string]any{rows[2], rows[1], rows[3]}, pickVersion(ctx, rows, "validFrom", []string{"id"}, 3500))
+	// The entity without a valid version is not joined
+	assert.Equal(t, []map[string]any{rows[0], rows[1]}, pickVersion(ctx, rows, "validFrom", []string{"id"}, 1500))
+	// All the rows are one entity by the lookup key
+	assert.Equal(t, []map[string]any{rows[3]}, pickVersion(ctx, rows, "validFrom", []string{"group"}, 3500))
+}
+
+type mockPreloadLookup struct {
+	rows  []map[string]any
+	calls [][]any
+}
+
+func (m *mockPreloadLookup) Provision(_ api.StreamContext, _ map[string]any) error {
+	return nil
+}
+
+func (m *mockPreloadLookup) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func (m *mockPreloadLookup) Connect(_ api.StreamContext, _ api.StatusChangeHandler) error {
+	return nil
+}
+
+func (m *mockPreloadLookup) Lookup(_ api.StreamContext, _ []string, keys []string, values []any) ([]map[string]any, error) {
+	m.calls = append(m.calls, values)
+	result := make([]map[string]any, 0)
+	for _, row := range m.rows {
+		if len(keys) == 0 || row[keys[0]] == values[0] {
+			result = append(result, row)
+		}
+	}
+	return result, nil
+}
+
+func TestLookupPreloadAndRefresh(t *testing.T) {
+	mockclock.ResetClock(0)
+	ctx := mockContext.NewMockContext("testRule", "testPreload")
+	n, err := NewLookupNode(ctx, "testPreload", false, nil, []string{"id"}, ast.INNER_JOIN, []ast.Expr{}, &ast.Options{TYPE: "mock", FORMAT: "json"}, &def.RuleOption{BufferLength: 10}, map[string]any{"lookup": map[string]any{
+		"cache": true, "cacheTtl": "10s", "cacheRefreshAhead": "2s", "cachePreload": true,
+	}})
+	require.NoError(t, err)
+	src := &mockPreloadLookup{rows: []map[string]any{{"id": 1, "v": "a"}, {"id": 2, "v": "b"}, {"id": 1, "v": "c"}}}
+	c := cache.NewCache(10*time.Second, false, 0)
+	defer c.Close()
+	n.preload(ctx, src, c)
+	require.Equal(t, [][]any{nil}, src.calls)
+	r, ok := c.Get("[1]")
+	require.True(t, ok)
+	require.Equal(t, []map[string]any{{"id": 1, "v": "a"}, {"id": 1, "v": "c"}}, r)
+
+	src.rows[0]["v"] = "d"
+	mockclock.GetMockClock().Add(9 * time.Second)
+	n.refresh(ctx, src, c)
+	// Only the read key is refreshed
+	require.Equal(t, [][]any{nil, {1}}, src.calls)
+	mockclock.GetMockClock().Add(5 * time.Second)
+	r, ok = c.Get("[1]")
+	require.True(t, ok)
+	require.Equal(t, []map[string]any{{"id": 1, "v": "d"}, {"id": 1, "v": "c"}}, r)
+	_, ok = c.Get("[2]")
+	require.False(t, ok)
+}
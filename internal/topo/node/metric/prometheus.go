@@ -47,6 +47,10 @@ type MetricGroup struct {
 
 type PrometheusMetrics struct {
 	vecs []*MetricGroup
+	// SchemaWarnings counts the data which does not match the stream schema but is tolerated by the schema policy
+	SchemaWarnings *prometheus.CounterVec
+	// LookupCache counts the hits and misses of the lookup cache
+	LookupCache *prometheus.CounterVec
 }
 
 func newPrometheusMetrics() *PrometheusMetrics {
@@ -107,7 +111,17 @@ func newPrometheusMetrics() *PrometheusMetrics {
 		vecs = append(vecs, mg)
 
 	}
-	return &PrometheusMetrics{vecs: vecs}
+	schemaWarnings := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kuiper_stream_" + SchemaWarningsTotal,
+		Help: "Total number of the stream data which does not match the schema and is tolerated by the schema policy",
+	}, []string{"rule", "stream", "reason"})
+	_ = prometheus.Register(schemaWarnings)
+	lookupCache := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kuiper_op_" + LookupCacheTotal,
+		Help: "Total number of the lookup cache hits and misses of the lookup table",
+	}, []string{"rule", "op", "result"})
+	_ = prometheus.Register(lookupCache)
+	return &PrometheusMetrics{vecs: vecs, SchemaWarnings: schemaWarnings, LookupCache: lookupCache}
 }
 
 func (m *PrometheusMetrics) GetMetricsGroup(opType string) *MetricGroup {
@@ -36,6 +36,8 @@ const (
 	ConnectionLastDisconnectedTime    = "connection_last_disconnected_time"
 	ConnectionLastDisconnectedMessage = "connection_last_disconnected_message"
 	ConnectionLastTryTime             = "connection_last_try_time"
+	SchemaWarningsTotal               = "schema_warnings_total"
+	LookupCacheTotal                  = "lookup_cache_total"
 )
 
 var MetricNames = []string{RecordsInTotal, RecordsOutTotal, MessagesProcessedTotal, ProcessLatencyUs, BufferLength, LastInvocation, ExceptionsTotal, LastException, LastExceptionTime, ConnectionStatus, ConnectionLastConnectedTime, ConnectionLastDisconnectedTime, ConnectionLastDisconnectedMessage, ConnectionLastTryTime}
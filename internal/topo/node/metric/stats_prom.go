@@ -121,6 +121,7 @@ func (sm *PrometheusStatManager) Clean(ruleId string) {
 		if mg.ConnectionStatus != nil {
 			mg.ConnectionStatus.DeleteLabelValues(ruleId, sm.opType, sm.opId, strInId)
 		}
+		GetPrometheusMetrics().SchemaWarnings.DeletePartialMatch(prometheus.Labels{"rule": ruleId})
 		conf.Log.Debugf("finish removing rule:%v, opType:%v, opId:%v, InId:%v prometheus metrics", ruleId, sm.opType, sm.opId, strInId)
 	}
 }
@@ -136,3 +137,21 @@ func (sm *PrometheusStatManager) SetConnectionState(state string, message string
 	}
 	setMemConnState(sm.connectionState, state, message)
 }
+
+// IncSchemaWarnings counts the stream data which does not match the schema but is tolerated by the schema policy
+func IncSchemaWarnings(ruleId string, stream string, reason string) {
+	if conf.Config != nil && conf.Config.Basic.Prometheus {
+		GetPrometheusMetrics().SchemaWarnings.WithLabelValues(ruleId, stream, reason).Inc()
+	}
+}
+
+// IncLookupCache counts the lookup cache hit or miss of the lookup table
+func IncLookupCache(ruleId string, op string, hit bool) {
+	if conf.Config != nil && conf.Config.Basic.Prometheus {
+		result := "miss"
+		if hit {
+			result = "hit"
+		}
+		GetPrometheusMetrics().LookupCache.WithLabelValues(ruleId, op, result).Inc()
+	}
+}
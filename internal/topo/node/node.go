@@ -16,29 +16,45 @@ package node
 
 import (
 	"fmt"
+	"hash/fnv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/lf-edge/ekuiper/v2/internal/binder/io"
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/util"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/tracenode"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/sched"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
 type defaultNode struct {
 	name        string
 	concurrency int
-	sendError   bool
+	// keyBy is the field to distribute the rows to the concurrent workers. Use round-robin if it is empty
+	keyBy string
+	// keys are the expressions to distribute the rows of a stateful node such as the PARTITION BY expressions
+	keys []ast.Expr
+	// keyValuer evaluates the function calls in the keys
+	keyValuer *xsql.FunctionValuer
+	sendError bool
+	// sideOutput is the memory topic to route the data which cannot be processed
+	sideOutput  string
 	statManager metric.StatManager
 	ctx         api.StreamContext
 	ctrlCh      chan<- error
@@ -50,6 +66,13 @@ type defaultNode struct {
 	span                     trace.Span
 	spanCtx                  api.StreamContext
 	disableBufferFullDiscard bool
+	// bufferSpill spills the items to the disk when the output buffers are full
+	bufferSpill *def.BufferSpill
+	spillMu     sync.Mutex
+	spills      map[string]*bufferSpill
+	// sched is the scheduling class of the rule to admit the processing
+	sched     *sched.Class
+	procStart atomic.Int64
 }
 
 func newDefaultNode(name string, options *def.RuleOption) *defaultNode {
@@ -57,13 +80,38 @@ func newDefaultNode(name string, options *def.RuleOption) *defaultNode {
 	if c < 1 {
 		c = 1
 	}
-	return &defaultNode{
+	o := &defaultNode{
 		name:                     name,
 		outputs:                  make(map[string]chan any),
 		concurrency:              c,
 		sendError:                options.SendError,
 		disableBufferFullDiscard: options.DisableBufferFullDiscard,
+		bufferSpill:              options.BufferSpill,
 	}
+	if options.SchedulingClass != "" {
+		sc, err := sched.Get(options.SchedulingClass)
+		if err != nil {
+			conf.Log.Warnf("node %s runs without scheduling: %v", name, err)
+		} else {
+			o.sched = sc
+		}
+	}
+	return o
+}
+
+// SetParallelism overrides the concurrency of the rule option for this node
+func (o *defaultNode) SetParallelism(concurrency int, keyBy string) {
+	if concurrency > 0 {
+		o.concurrency = concurrency
+	}
+	o.keyBy = keyBy
+}
+
+// SetKeyedParallelism runs a stateful node with multiple workers. The rows are distributed by the hash of the keys, so
+// the state and the order of a key are kept in a single worker.
+func (o *defaultNode) SetKeyedParallelism(concurrency int, keys []ast.Expr) {
+	o.SetParallelism(concurrency, "")
+	o.keys = keys
 }
 
 func (o *defaultNode) AddOutput(output chan any, name string) error {
@@ -130,6 +178,15 @@ func (o *defaultNode) BroadcastCustomized(val any, broadcastFunc func(val any))
 }
 
 func (o *defaultNode) doBroadcast(val any) {
+	o.broadcastTo(val, o.disableBufferFullDiscard)
+}
+
+// doBlockingBroadcast waits for the buffer to be consumed instead of dropping the oldest to apply backpressure to the node
+func (o *defaultNode) doBlockingBroadcast(val any) {
+	o.broadcastTo(val, true)
+}
+
+func (o *defaultNode) broadcastTo(val any, blocking bool) {
 	o.outputMu.RLock()
 	defer o.outputMu.RUnlock()
 	first := true
@@ -150,8 +207,13 @@ func (o *defaultNode) doBroadcast(val any) {
 		if vt, ok := val.(xsql.HasTracerCtx); ok && vt.GetTracerCtx() == nil {
 			vt.SetTracerCtx(o.spanCtx)
 		}
+		if o.bufferSpill != nil {
+			if q := o.getSpill(name, out); q != nil && q.push(o.ctx, val) {
+				continue
+			}
+		}
 		// wait buffer consume if buffer full
-		if o.disableBufferFullDiscard {
+		if blocking {
 			select {
 			case out <- val:
 				continue
@@ -178,6 +240,26 @@ func (o *defaultNode) doBroadcast(val any) {
 	}
 }
 
+// getSpill returns the disk-backed queue of the output. It returns nil if the queue cannot be created.
+func (o *defaultNode) getSpill(name string, out chan any) *bufferSpill {
+	o.spillMu.Lock()
+	defer o.spillMu.Unlock()
+	if o.spills == nil {
+		o.spills = make(map[string]*bufferSpill)
+	}
+	q, ok := o.spills[name]
+	if !ok {
+		var err error
+		q, err = newBufferSpill(o.ctx, name, out, o.bufferSpill)
+		if err != nil {
+			o.ctx.GetLogger().Errorf("fail to create the buffer spill of %s, fallback to the memory buffer: %v", name, err)
+			q = nil
+		}
+		o.spills[name] = q
+	}
+	return q
+}
+
 func (o *defaultNode) GetStreamContext() api.StreamContext {
 	return o.ctx
 }
@@ -224,6 +306,23 @@ func (o *defaultNode) prepareExec(ctx api.StreamContext, errCh chan<- error, opT
 		o.opsWg.Add(1)
 	}
 	o.ctrlCh = errCh
+	if o.sideOutput != "" {
+		pubsub.CreatePub(o.sideOutput)
+	}
+	if o.bufferSpill != nil {
+		o.resumeSpills()
+	}
+}
+
+// resumeSpills sends the items spilled by the previous run of the rule
+func (o *defaultNode) resumeSpills() {
+	o.outputMu.RLock()
+	defer o.outputMu.RUnlock()
+	for name, out := range o.outputs {
+		if q := o.getSpill(name, out); q != nil {
+			q.resume(o.ctx)
+		}
+	}
 }
 
 func (o *defaultNode) finishExec() {
@@ -231,6 +330,9 @@ func (o *defaultNode) finishExec() {
 }
 
 func (o *defaultNode) Close() {
+	if o.sideOutput != "" {
+		pubsub.RemovePub(o.sideOutput)
+	}
 	if o.opsWg != nil {
 		o.ctx.GetLogger().Infof("node %s is closing", o.name)
 		o.opsWg.Done()
@@ -240,6 +342,10 @@ func (o *defaultNode) Close() {
 }
 
 func (o *defaultSinkNode) preprocess(ctx api.StreamContext, item any) (any, bool) {
+	if r, ok := item.(*StateRequest); ok {
+		o.inspectState(ctx, r)
+		return nil, true
+	}
 	if o.qos >= def.AtLeastOnce {
 		b, ok := item.(*checkpoint.BufferOrEvent)
 		if ok {
@@ -256,6 +362,36 @@ func (o *defaultSinkNode) preprocess(ctx api.StreamContext, item any) (any, bool
 	return item, false
 }
 
+// partition returns the worker index of the item by the hash of the keys or the keyBy field. Return -1 to use
+// round-robin if the item is not a row such as the raw data and the control tuples.
+func (o *defaultSinkNode) partition(item any, numWorkers int) int {
+	if (o.keyBy == "" && len(o.keys) == 0) || numWorkers <= 1 {
+		return -1
+	}
+	if b, ok := item.(*checkpoint.BufferOrEvent); ok {
+		item = b.Data
+	}
+	row, ok := item.(xsql.Row)
+	if !ok {
+		return -1
+	}
+	h := fnv.New32a()
+	if len(o.keys) > 0 {
+		if o.keyValuer == nil {
+			o.keyValuer, _ = xsql.NewFunctionValuersForOp(o.ctx)
+		}
+		ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(row, o.keyValuer)}
+		for _, k := range o.keys {
+			_, _ = fmt.Fprint(h, ve.Eval(k))
+			_, _ = h.Write([]byte{0})
+		}
+	} else {
+		v, _ := row.Value(o.keyBy, "")
+		_, _ = h.Write([]byte(fmt.Sprint(v)))
+	}
+	return int(h.Sum32() % uint32(numWorkers))
+}
+
 func (o *defaultSinkNode) commonIngest(ctx api.StreamContext, item any) (any, bool) {
 	ctx.GetLogger().Debugf("op %s_%d receive %v", ctx.GetOpId(), ctx.GetInstanceId(), item)
 	item, processed := o.preprocess(ctx, item)
@@ -286,6 +422,7 @@ func (o *defaultSinkNode) handleEof(ctx api.StreamContext, d xsql.EOFTuple) {
 
 // onProcessStart do the common works(metric, trace) when receiving a message from upstream
 func (o *defaultNode) onProcessStart(ctx api.StreamContext, val any) {
+	o.schedStart(ctx)
 	o.statManager.IncTotalRecordsIn()
 	o.statManager.ProcessTimeStart()
 	// Source just pass nil val so that no trace. The trace will start after extracting trace id
@@ -301,6 +438,7 @@ func (o *defaultNode) onProcessStart(ctx api.StreamContext, val any) {
 
 // onProcessEnd do the common works(metric, trace) after processing a message from upstream
 func (o *defaultNode) onProcessEnd(ctx api.StreamContext) {
+	o.schedEnd()
 	o.statManager.ProcessTimeEnd()
 	o.statManager.IncTotalMessagesProcessed(1)
 	if o.span != nil {
@@ -309,6 +447,25 @@ func (o *defaultNode) onProcessEnd(ctx api.StreamContext) {
 	}
 }
 
+// schedStart waits for the scheduling class to admit the processing
+func (o *defaultNode) schedStart(ctx api.StreamContext) {
+	if o.sched == nil {
+		return
+	}
+	o.sched.Admit(ctx)
+	o.procStart.Store(timex.GetNow().UnixNano())
+}
+
+// schedEnd charges the processing time to the scheduling class
+func (o *defaultNode) schedEnd() {
+	if o.sched == nil {
+		return
+	}
+	if start := o.procStart.Load(); start > 0 {
+		o.sched.Charge(timex.GetNow().Sub(time.Unix(0, start)))
+	}
+}
+
 // onSend do the common works(metric, trace) after sending a message to downstream
 func (o *defaultNode) onSend(ctx api.StreamContext, val any) {
 	o.statManager.IncTotalRecordsOut()
@@ -21,7 +21,9 @@ import (
 	"github.com/pingcap/failpoint"
 
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
 )
 
@@ -42,6 +44,12 @@ type UnaryOperator struct {
 	*defaultSinkNode
 	op        UnOperation
 	cancelled bool
+	// parallel is set by the parallelism hint. The rule concurrency option does not apply to unary operators
+	parallel bool
+	// batchSize is the max count of the input items to process in one round in the batch execution mode
+	batchSize int
+	// batchOut sends the results of a round as one *xsql.ItemBatch. The downstream must be a unary operator.
+	batchOut bool
 }
 
 // New NewUnary creates *UnaryOperator value
@@ -56,6 +64,28 @@ func (o *UnaryOperator) SetOperation(op UnOperation) {
 	o.op = op
 }
 
+// SetParallelism runs the operation with multiple workers. Each worker has its own function valuers,
+// so the stateful functions keep their states per worker.
+func (o *UnaryOperator) SetParallelism(concurrency int, keyBy string) {
+	o.defaultSinkNode.SetParallelism(concurrency, keyBy)
+	o.parallel = true
+}
+
+// SetKeyedParallelism runs a stateful operation with multiple workers partitioned by the keys. The function states
+// are in the operator context by the partition key, so each key sees its own state in order.
+func (o *UnaryOperator) SetKeyedParallelism(concurrency int, keys []ast.Expr) {
+	o.defaultSinkNode.SetKeyedParallelism(concurrency, keys)
+	o.parallel = true
+}
+
+// SetExecBatch processes the items queued in the input together, up to the size, in one round. It does not wait for
+// the batch to fill, so no latency is added. The process time is measured per round. If batchOut is set, the results
+// of a round are sent out as one batch.
+func (o *UnaryOperator) SetExecBatch(size int, batchOut bool) {
+	o.batchSize = size
+	o.batchOut = batchOut
+}
+
 // Exec is the entry point for the executor
 func (o *UnaryOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 	o.prepareExec(ctx, errCh, "op")
@@ -71,7 +101,11 @@ func (o *UnaryOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 			o.Close()
 		}()
 		err := infra.SafeRun(func() error {
-			o.doOp(ctx.WithInstance(0), errCh)
+			if o.parallel && o.concurrency > 1 {
+				o.doParallelOp(ctx.WithInstance(0))
+			} else {
+				o.doOp(ctx.WithInstance(0), errCh)
+			}
 			return nil
 		})
 		if err != nil {
@@ -99,6 +133,11 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 		select {
 		// process incoming item
 		case item := <-o.input:
+			// The tracing is per item, so the batch execution is disabled when tracing
+			if _, ok := item.(*xsql.ItemBatch); ok || (o.batchSize > 1 && !ctx.IsTraceEnabled()) {
+				o.doBatch(ctx, exeCtx, item, fv, afv)
+				break
+			}
 			data, processed := o.commonIngest(ctx, item)
 			if processed {
 				break
@@ -109,7 +148,7 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 			case nil:
 				// ends, do nothing
 			case error:
-				o.onError(ctx, val)
+				o.onErrorOpt(ctx, val, !o.emitSideOutput(ctx, SideOutputInvalid, val, data, nil))
 			case []xsql.Row:
 				for _, v := range val {
 					o.Broadcast(v)
@@ -129,3 +168,130 @@ func (o *UnaryOperator) doOp(ctx api.StreamContext, errCh chan<- error) {
 		}
 	}
 }
+
+// doBatch processes the first item and the items already in the input in one round. The results are kept in order.
+// Before a control item like the watermark, EOF and checkpoint barrier, the pending results are sent out first.
+func (o *UnaryOperator) doBatch(ctx, exeCtx api.StreamContext, first any, fv *xsql.FunctionValuer, afv *xsql.AggregateFunctionValuer) {
+	var (
+		out   []any
+		count int64
+	)
+	flush := func() {
+		if len(out) == 0 {
+			return
+		}
+		if o.batchOut {
+			o.Broadcast(&xsql.ItemBatch{Items: out})
+		} else {
+			for _, v := range out {
+				o.Broadcast(v)
+			}
+		}
+		for _, v := range out {
+			o.onSend(ctx, v)
+		}
+		out = nil
+	}
+	apply := func(data any) {
+		if count == 0 {
+			o.schedStart(ctx)
+			o.statManager.ProcessTimeStart()
+		}
+		count++
+		o.statManager.IncTotalRecordsIn()
+		switch val := o.op.Apply(exeCtx, data, fv, afv).(type) {
+		case nil:
+		case error:
+			flush()
+			o.onErrorOpt(ctx, val, !o.emitSideOutput(ctx, SideOutputInvalid, val, data, nil))
+		case []xsql.Row:
+			for _, v := range val {
+				out = append(out, v)
+			}
+		default:
+			out = append(out, val)
+		}
+	}
+	process := func(item any) {
+		if !isBatchData(item) {
+			flush()
+		}
+		data, processed := o.commonIngest(ctx, item)
+		if processed {
+			return
+		}
+		if b, ok := data.(*xsql.ItemBatch); ok {
+			for _, d := range b.Items {
+				apply(d)
+			}
+		} else {
+			apply(data)
+		}
+	}
+	process(first)
+drain:
+	for i := 1; i < o.batchSize; i++ {
+		select {
+		case item := <-o.input:
+			process(item)
+		default:
+			break drain
+		}
+	}
+	flush()
+	if count > 0 {
+		o.schedEnd()
+		o.statManager.ProcessTimeEnd()
+		o.statManager.IncTotalMessagesProcessed(count)
+	}
+	o.statManager.SetBufferLength(int64(len(o.input)))
+}
+
+// isBatchData checks if the item is the data which can be processed in a batch rather than a control item
+func isBatchData(item any) bool {
+	if b, ok := item.(*checkpoint.BufferOrEvent); ok {
+		item = b.Data
+	}
+	switch item.(type) {
+	case xsql.Row, xsql.Collection, *xsql.ItemBatch:
+		return true
+	default:
+		return false
+	}
+}
+
+func (o *UnaryOperator) doParallelOp(ctx api.StreamContext) {
+	logger := ctx.GetLogger()
+	if o.op == nil {
+		logger.Info("Unary operator missing operation")
+		return
+	}
+	exeCtx, cancel := ctx.WithCancel()
+	defer func() {
+		logger.Infof("unary operator %s with %d workers done, cancelling future items", o.name, o.concurrency)
+		cancel()
+	}()
+	runWorkersWithOrder(ctx, o.defaultSinkNode, o.concurrency, func(_ int) workerFunc {
+		fv, afv := xsql.NewFunctionValuersForOp(exeCtx)
+		return func(_ api.StreamContext, item any) []any {
+			switch val := o.op.Apply(exeCtx, item, fv, afv).(type) {
+			case nil:
+				return nil
+			case error:
+				if o.emitSideOutput(ctx, SideOutputInvalid, val, item, nil) {
+					o.onErrorOpt(ctx, val, false)
+					return nil
+				}
+				return []any{val}
+			case []xsql.Row:
+				result := make([]any, len(val))
+				for i, v := range val {
+					result[i] = v
+				}
+				return result
+			default:
+				return []any{val}
+			}
+		}
+	}, 0)
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+// evenOp drops the rows with odd id and fails for the id 5
+type evenOp struct{}
+
+func (evenOp) Apply(_ api.StreamContext, data any, _ *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) any {
+	row := data.(*xsql.Tuple)
+	id := row.Message["id"].(int)
+	switch {
+	case id == 5:
+		return errors.New("invalid id 5")
+	case id%2 == 1:
+		return nil
+	}
+	return row
+}
+
+type passOp struct{}
+
+func (passOp) Apply(_ api.StreamContext, data any, _ *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) any {
+	return data
+}
+
+func newIdTuple(id int) *xsql.Tuple {
+	return &xsql.Tuple{Emitter: "test", Message: map[string]any{"id": id}}
+}
+
+func TestUnaryOperatorBatch(t *testing.T) {
+	ctx, cancel := mockContext.NewMockContext("test1", "batch_test").WithCancel()
+	defer cancel()
+	opts := &def.RuleOption{BufferLength: 20, SendError: true}
+	first := New("first", opts)
+	first.SetOperation(evenOp{})
+	first.SetExecBatch(4, true)
+	mid := make(chan any, 20)
+	require.NoError(t, first.AddOutput(mid, "second"))
+	// Queue the items before running so that they are drained in batches
+	wm := &xsql.WatermarkTuple{Timestamp: time.UnixMilli(10)}
+	for i := 0; i < 4; i++ {
+		first.input <- newIdTuple(i)
+	}
+	first.input <- wm
+	for i := 4; i < 8; i++ {
+		first.input <- newIdTuple(i)
+	}
+	first.input <- xsql.EOFTuple(0)
+	first.Exec(ctx, make(chan error))
+
+	var batches []any
+	for {
+		r := <-mid
+		batches = append(batches, r)
+		if _, ok := r.(xsql.EOFTuple); ok {
+			break
+		}
+	}
+	// The control items and the error are sent in order with the batches
+	assert.Equal(t, []any{
+		&xsql.ItemBatch{Items: []any{newIdTuple(0), newIdTuple(2)}},
+		wm,
+		&xsql.ItemBatch{Items: []any{newIdTuple(4)}},
+		errors.New("invalid id 5"),
+		&xsql.ItemBatch{Items: []any{newIdTuple(6)}},
+		xsql.EOFTuple(0),
+	}, batches)
+
+	second := New("second", opts)
+	second.SetOperation(passOp{})
+	second.SetExecBatch(4, false)
+	out := make(chan any, 20)
+	require.NoError(t, second.AddOutput(out, "test"))
+	for _, b := range batches {
+		second.input <- b
+	}
+	second.Exec(ctx, make(chan error))
+	var results []any
+	for {
+		r := <-out
+		results = append(results, r)
+		if _, ok := r.(xsql.EOFTuple); ok {
+			break
+		}
+	}
+	// The batches are unpacked
+	assert.Equal(t, []any{newIdTuple(0), newIdTuple(2), wm, newIdTuple(4), errors.New("invalid id 5"), newIdTuple(6), xsql.EOFTuple(0)}, results)
+}
+
+// BenchmarkUnaryOperatorBatch/batch_0         	  500000	      1143 ns/op
+// BenchmarkUnaryOperatorBatch/batch_64        	  500000	       533.5 ns/op
+func BenchmarkUnaryOperatorBatch(b *testing.B) {
+	for _, size := range []int{0, 64} {
+		b.Run(fmt.Sprintf("batch %d", size), func(b *testing.B) {
+			ctx, cancel := mockContext.NewMockContext("bench", "batch_bench").WithCancel()
+			defer cancel()
+			opts := &def.RuleOption{BufferLength: 1024, DisableBufferFullDiscard: true}
+			first := New("filter", opts)
+			first.SetOperation(passOp{})
+			first.SetExecBatch(size, true)
+			second := New("project", opts)
+			second.SetOperation(passOp{})
+			second.SetExecBatch(size, false)
+			out := make(chan any, 1024)
+			require.NoError(b, first.AddOutput(second.input, "project"))
+			require.NoError(b, second.AddOutput(out, "out"))
+			first.Exec(ctx, make(chan error))
+			second.Exec(ctx, make(chan error))
+			row := newIdTuple(0)
+			b.ResetTimer()
+			go func() {
+				for i := 0; i < b.N; i++ {
+					first.input <- row
+				}
+			}()
+			for i := 0; i < b.N; i++ {
+				<-out
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+)
+
+// PatternStep is one element of a pattern sequence.
+// Max is -1 if the step is unbounded such as `+` or `*`
+type PatternStep struct {
+	Name      string
+	Condition ast.Expr
+	Min       int
+	Max       int
+	// Negated step means the events matching the condition must not occur between the previous and the next step
+	Negated bool
+}
+
+const (
+	defaultMaxPartials = 1000
+	defaultMaxEvents   = 1000
+)
+
+type PatternConfig struct {
+	PartitionBy ast.Expr
+	Steps       []*PatternStep
+	Within      time.Duration
+	Measures    ast.Fields
+	// MaxPartials is the max number of partial matches of each key. The oldest one is evicted when exceeded.
+	MaxPartials int
+	// MaxEvents is the max number of events kept for each step of a partial match. The oldest event of the step is
+	// evicted when exceeded.
+	MaxEvents int
+}
+
+// PatternNode detects sequences of events per partition key.
+// The matching uses relaxed contiguity: events which match no step are skipped.
+// Quantified steps are greedy and the match is emitted once the last step is satisfied.
+// After a match, all the partial matches of the same key are discarded (skip past last row).
+type PatternNode struct {
+	*defaultSinkNode
+	conf         *PatternConfig
+	lastPositive int
+	// state
+	partials  map[string][]*partialMatch
+	lastSweep time.Time
+}
+
+type partialMatch struct {
+	start time.Time
+	// the index of the current step
+	step  int
+	count int
+	// the matched rows indexed by step
+	events [][]xsql.Row
+}
+
+func NewPatternNode(name string, conf *PatternConfig, options *def.RuleOption) (*PatternNode, error) {
+	if len(conf.Steps) == 0 {
+		return nil, fmt.Errorf("pattern must have at least one step")
+	}
+	if conf.Steps[0].Negated || conf.Steps[0].Min < 1 {
+		return nil, fmt.Errorf("the first step %s of the pattern must occur at least once", conf.Steps[0].Name)
+	}
+	last := len(conf.Steps) - 1
+	if conf.Steps[last].Negated || conf.Steps[last].Min < 1 {
+		return nil, fmt.Errorf("the last step %s of the pattern must occur at least once", conf.Steps[last].Name)
+	}
+	names := make(map[string]struct{}, len(conf.Steps))
+	for _, s := range conf.Steps {
+		if _, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate pattern step name %s", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		if s.Max >= 0 && s.Max < s.Min {
+			return nil, fmt.Errorf("pattern step %s has invalid quantifier: max %d is less than min %d", s.Name, s.Max, s.Min)
+		}
+	}
+	if conf.MaxPartials <= 0 {
+		conf.MaxPartials = defaultMaxPartials
+	}
+	if conf.MaxEvents <= 0 {
+		conf.MaxEvents = defaultMaxEvents
+	}
+	return &PatternNode{
+		defaultSinkNode: newDefaultSinkNode(name, options),
+		conf:            conf,
+		lastPositive:    last,
+		partials:        make(map[string][]*partialMatch),
+	}, nil
+}
+
+func (n *PatternNode) Exec(ctx api.StreamContext, errCh chan<- error) {
+	n.prepareExec(ctx, errCh, "op")
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	go func() {
+		defer func() {
+			n.Close()
+		}()
+		err := infra.SafeRun(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					ctx.GetLogger().Infof("pattern node %s is finished", n.name)
+					return nil
+				case item := <-n.input:
+					data, processed := n.commonIngest(ctx, item)
+					if processed {
+						break
+					}
+					n.onProcessStart(ctx, data)
+					switch d := data.(type) {
+					case xsql.Row:
+						result, err := n.match(d, fv)
+						if err != nil {
+							n.onError(ctx, err)
+						} else if result != nil {
+							n.Broadcast(result)
+							n.onSend(ctx, result)
+						}
+					default:
+						n.onError(ctx, fmt.Errorf("run pattern op error: expect xsql.Row type but got %[1]T(%[1]v)", d))
+					}
+					n.onProcessEnd(ctx)
+					n.statManager.SetBufferLength(int64(len(n.input)))
+				}
+			}
+		})
+		if err != nil {
+			infra.DrainError(ctx, err, errCh)
+		}
+	}()
+}
+
+// match feeds the row into the partial matches of its partition and returns the match result if any
+func (n *PatternNode) match(row xsql.Row, fv *xsql.FunctionValuer) (*xsql.Tuple, error) {
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(row, fv)}
+	key := ""
+	if n.conf.PartitionBy != nil {
+		k := ve.Eval(n.conf.PartitionBy)
+		if e, ok := k.(error); ok {
+			return nil, e
+		}
+		key = fmt.Sprintf("%v", k)
+	}
+	matches := make([]bool, len(n.conf.Steps))
+	for i, s := range n.conf.Steps {
+		r := ve.Eval(s.Condition)
+		switch rt := r.(type) {
+		case error:
+			return nil, fmt.Errorf("evaluate pattern step %s error: %v", s.Name, rt)
+		case bool:
+			matches[i] = rt
+		case nil:
+		default:
+			return nil, fmt.Errorf("pattern step %s condition returns non-bool value %v", s.Name, rt)
+		}
+	}
+	ts := eventTime(row)
+	n.sweep(ts)
+	var (
+		kept         []*partialMatch
+		startedByRow bool
+	)
+	for _, pm := range n.partials[key] {
+		if n.conf.Within > 0 && ts.Sub(pm.start) > n.conf.Within {
+			continue
+		}
+		consumed, aborted := n.advance(pm, row, matches)
+		if aborted {
+			continue
+		}
+		if consumed && pm.step == 0 {
+			startedByRow = true
+		}
+		if n.isComplete(pm) {
+			delete(n.partials, key)
+			return n.result(pm, ts, fv)
+		}
+		kept = append(kept, pm)
+	}
+	if matches[0] && !startedByRow {
+		pm := &partialMatch{
+			start:  ts,
+			count:  1,
+			events: make([][]xsql.Row, len(n.conf.Steps)),
+		}
+		pm.events[0] = []xsql.Row{row}
+		if n.isComplete(pm) {
+			delete(n.partials, key)
+			return n.result(pm, ts, fv)
+		}
+		// The partial matches are in the order of the start, so evict the oldest ones
+		if len(kept) >= n.conf.MaxPartials {
+			kept = kept[len(kept)-n.conf.MaxPartials+1:]
+		}
+		kept = append(kept, pm)
+	}
+	if len(kept) > 0 {
+		n.partials[key] = kept
+	} else {
+		delete(n.partials, key)
+	}
+	return nil, nil
+}
+
+// advance tries to consume the row into the partial match. Return whether the row is consumed and whether the partial match is aborted.
+func (n *PatternNode) advance(pm *partialMatch, row xsql.Row, matches []bool) (bool, bool) {
+	steps := n.conf.Steps
+	i := pm.step
+	for k := i + 1; k < len(steps) && steps[k].Negated; k++ {
+		if matches[k] {
+			return false, true
+		}
+	}
+	s := steps[i]
+	if matches[i] && (s.Max < 0 || pm.count < s.Max) {
+		pm.count++
+		if len(pm.events[i]) >= n.conf.MaxEvents {
+			pm.events[i] = pm.events[i][len(pm.events[i])-n.conf.MaxEvents+1:]
+		}
+		pm.events[i] = append(pm.events[i], row)
+		return true, false
+	}
+	if pm.count < s.Min {
+		return false, false
+	}
+	for j := i + 1; j < len(steps); j++ {
+		if steps[j].Negated {
+			continue
+		}
+		if matches[j] {
+			pm.step = j
+			pm.count = 1
+			pm.events[j] = append(pm.events[j], row)
+			return true, false
+		}
+		// Only optional steps can be skipped
+		if steps[j].Min > 0 {
+			break
+		}
+	}
+	return false, false
+}
+
+func (n *PatternNode) isComplete(pm *partialMatch) bool {
+	return pm.step == n.lastPositive && pm.count >= n.conf.Steps[n.lastPositive].Min
+}
+
+// sweep removes the expired partial matches of all partitions periodically
+func (n *PatternNode) sweep(now time.Time) {
+	if n.conf.Within <= 0 || now.Sub(n.lastSweep) < n.conf.Within {
+		return
+	}
+	n.lastSweep = now
+	for key, pms := range n.partials {
+		var kept []*partialMatch
+		for _, pm := range pms {
+			if now.Sub(pm.start) <= n.conf.Within {
+				kept = append(kept, pm)
+			}
+		}
+		if len(kept) > 0 {
+			n.partials[key] = kept
+		} else {
+			delete(n.partials, key)
+		}
+	}
+}
+
+// result composes the matched rows into a tuple. Each step is a field in the tuple.
+// Single occurrence step is a map and the quantified step is an array of maps.
+// If measures are defined, only the measures are returned.
+func (n *PatternNode) result(pm *partialMatch, ts time.Time, fv *xsql.FunctionValuer) (*xsql.Tuple, error) {
+	msg := make(map[string]any, len(n.conf.Steps))
+	for i, s := range n.conf.Steps {
+		if s.Negated {
+			continue
+		}
+		rows := pm.events[i]
+		if s.Max == 1 {
+			if len(rows) > 0 {
+				msg[s.Name] = rows[0].ToMap()
+			} else {
+				msg[s.Name] = nil
+			}
+		} else {
+			arr := make([]any, len(rows))
+			for j, r := range rows {
+				arr[j] = r.ToMap()
+			}
+			msg[s.Name] = arr
+		}
+	}
+	t := &xsql.Tuple{Emitter: n.name, Message: msg, Timestamp: ts}
+	if len(n.conf.Measures) == 0 {
+		return t, nil
+	}
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(t, fv)}
+	result := make(map[string]any, len(n.conf.Measures))
+	for _, f := range n.conf.Measures {
+		v := ve.Eval(f.Expr)
+		if e, ok := v.(error); ok {
+			return nil, fmt.Errorf("evaluate pattern measure %s error: %v", f.GetName(), e)
+		}
+		result[f.GetName()] = v
+	}
+	return &xsql.Tuple{Emitter: n.name, Message: result, Timestamp: ts}, nil
+}
+
+func eventTime(row xsql.Row) time.Time {
+	if e, ok := row.(xsql.Event); ok {
+		return e.GetTimestamp()
+	}
+	return time.Time{}
+}
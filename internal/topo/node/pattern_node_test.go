@@ -0,0 +1,199 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func parsePatternCond(t *testing.T, cond string) ast.Expr {
+	exp, err := xsql.NewParser(strings.NewReader("where " + cond)).ParseCondition()
+	require.NoError(t, err)
+	return exp
+}
+
+func TestPatternMatch(t *testing.T) {
+	stmt, err := xsql.NewParser(strings.NewReader("select id, A.temp AS startTemp, B.temp - A.temp AS delta from demo")).Parse()
+	require.NoError(t, err)
+	pn, err := NewPatternNode("test", &PatternConfig{
+		PartitionBy: &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream},
+		Steps: []*PatternStep{
+			{Name: "A", Condition: parsePatternCond(t, "temp > 30"), Min: 1, Max: 1},
+			{Name: "C", Condition: parsePatternCond(t, "status = \"reset\""), Negated: true},
+			{Name: "B", Condition: parsePatternCond(t, "temp > 50"), Min: 1, Max: 1},
+		},
+		Within:   30 * time.Second,
+		Measures: stmt.Fields[1:],
+	}, &def.RuleOption{BufferLength: 10})
+	require.NoError(t, err)
+	ctx := context.NewMockContext("testPattern", "test")
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	inputs := []*xsql.Tuple{
+		{Message: map[string]any{"id": 1, "temp": 35}, Timestamp: time.UnixMilli(0)},
+		{Message: map[string]any{"id": 2, "temp": 40}, Timestamp: time.UnixMilli(1000)},
+		{Message: map[string]any{"id": 1, "temp": 20}, Timestamp: time.UnixMilli(2000)},
+		{Message: map[string]any{"id": 2, "temp": 10, "status": "reset"}, Timestamp: time.UnixMilli(3000)},
+		{Message: map[string]any{"id": 2, "temp": 45}, Timestamp: time.UnixMilli(4000)},
+		{Message: map[string]any{"id": 1, "temp": 55}, Timestamp: time.UnixMilli(5000)},
+		{Message: map[string]any{"id": 2, "temp": 70}, Timestamp: time.UnixMilli(40000)},
+		{Message: map[string]any{"id": 2, "temp": 80}, Timestamp: time.UnixMilli(41000)},
+	}
+	var results []map[string]any
+	for _, in := range inputs {
+		r, err := pn.match(in, fv)
+		require.NoError(t, err)
+		if r != nil {
+			results = append(results, r.Message)
+		}
+	}
+	assert.Equal(t, []map[string]any{
+		{"startTemp": 35, "delta": int64(20)},
+		{"startTemp": 70, "delta": int64(10)},
+	}, results)
+	assert.Len(t, pn.partials, 0)
+}
+
+func TestPatternQuantifier(t *testing.T) {
+	pn, err := NewPatternNode("test", &PatternConfig{
+		Steps: []*PatternStep{
+			{Name: "A", Condition: parsePatternCond(t, "temp > 30"), Min: 2, Max: -1},
+			{Name: "B", Condition: parsePatternCond(t, "temp < 10"), Min: 1, Max: 1},
+		},
+	}, &def.RuleOption{BufferLength: 10})
+	require.NoError(t, err)
+	ctx := context.NewMockContext("testPattern", "test")
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	inputs := []*xsql.Tuple{
+		{Message: map[string]any{"temp": 35}},
+		{Message: map[string]any{"temp": 5}},
+		{Message: map[string]any{"temp": 40}},
+		{Message: map[string]any{"temp": 20}},
+		{Message: map[string]any{"temp": 45}},
+		{Message: map[string]any{"temp": 3}},
+	}
+	var results []map[string]any
+	for _, in := range inputs {
+		r, err := pn.match(in, fv)
+		require.NoError(t, err)
+		if r != nil {
+			results = append(results, r.Message)
+		}
+	}
+	assert.Equal(t, []map[string]any{
+		{
+			"A": []any{map[string]any{"temp": 35}, map[string]any{"temp": 40}, map[string]any{"temp": 45}},
+			"B": map[string]any{"temp": 3},
+		},
+	}, results)
+}
+
+func TestPatternCaps(t *testing.T) {
+	ctx := context.NewMockContext("testPattern", "test")
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	// Without within, the unmatched start events only keep the latest partial matches
+	pn, err := NewPatternNode("test", &PatternConfig{
+		Steps: []*PatternStep{
+			{Name: "A", Condition: parsePatternCond(t, "temp > 30"), Min: 1, Max: 1},
+			{Name: "B", Condition: parsePatternCond(t, "temp < 10"), Min: 1, Max: 1},
+		},
+		MaxPartials: 3,
+	}, &def.RuleOption{BufferLength: 10})
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		r, err := pn.match(&xsql.Tuple{Message: map[string]any{"temp": 31 + i}}, fv)
+		require.NoError(t, err)
+		require.Nil(t, r)
+		require.LessOrEqual(t, len(pn.partials[""]), 3)
+	}
+	require.Len(t, pn.partials[""], 3)
+	r, err := pn.match(&xsql.Tuple{Message: map[string]any{"temp": 5}}, fv)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"A": map[string]any{"temp": 128}, "B": map[string]any{"temp": 5}}, map[string]any(r.Message))
+	// The unbounded step only keeps the latest events
+	pn, err = NewPatternNode("test", &PatternConfig{
+		Steps: []*PatternStep{
+			{Name: "A", Condition: parsePatternCond(t, "temp > 30"), Min: 1, Max: -1},
+			{Name: "B", Condition: parsePatternCond(t, "temp < 10"), Min: 1, Max: 1},
+		},
+		MaxEvents: 2,
+	}, &def.RuleOption{BufferLength: 10})
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		_, err := pn.match(&xsql.Tuple{Message: map[string]any{"temp": 31 + i}}, fv)
+		require.NoError(t, err)
+		require.Len(t, pn.partials[""], 1)
+		require.LessOrEqual(t, len(pn.partials[""][0].events[0]), 2)
+	}
+	r, err = pn.match(&xsql.Tuple{Message: map[string]any{"temp": 5}}, fv)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"A": []any{map[string]any{"temp": 129}, map[string]any{"temp": 130}},
+		"B": map[string]any{"temp": 5},
+	}, map[string]any(r.Message))
+}
+
+func TestPatternValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []*PatternStep
+		err   string
+	}{
+		{
+			name:  "empty",
+			steps: nil,
+			err:   "pattern must have at least one step",
+		},
+		{
+			name: "optional first",
+			steps: []*PatternStep{
+				{Name: "A", Condition: &ast.BooleanLiteral{Val: true}, Min: 0, Max: 1},
+				{Name: "B", Condition: &ast.BooleanLiteral{Val: true}, Min: 1, Max: 1},
+			},
+			err: "the first step A of the pattern must occur at least once",
+		},
+		{
+			name: "negated last",
+			steps: []*PatternStep{
+				{Name: "A", Condition: &ast.BooleanLiteral{Val: true}, Min: 1, Max: 1},
+				{Name: "B", Condition: &ast.BooleanLiteral{Val: true}, Negated: true},
+			},
+			err: "the last step B of the pattern must occur at least once",
+		},
+		{
+			name: "duplicate",
+			steps: []*PatternStep{
+				{Name: "A", Condition: &ast.BooleanLiteral{Val: true}, Min: 1, Max: 1},
+				{Name: "A", Condition: &ast.BooleanLiteral{Val: true}, Min: 1, Max: 1},
+			},
+			err: "duplicate pattern step name A",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPatternNode("test", &PatternConfig{Steps: tt.steps}, &def.RuleOption{})
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
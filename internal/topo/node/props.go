@@ -36,10 +36,15 @@ type SinkConf struct {
 	DataField      string            `json:"dataField"`
 	BatchSize      int               `json:"batchSize"`
 	LingerInterval cast.DurationConf `json:"lingerInterval"`
-	Compression    string            `json:"compression"`
-	Encryption     string            `json:"encryption"`
-	EncProps       map[string]any    `json:"encProps"`
-	HasHeader      bool              `json:"hasHeader"`
+	// AdaptiveBatch adapts the batch size and linger to the load, bounded by the batchSize and lingerInterval
+	AdaptiveBatch bool           `json:"adaptiveBatch"`
+	Compression   string         `json:"compression"`
+	Encryption    string         `json:"encryption"`
+	EncProps      map[string]any `json:"encProps"`
+	HasHeader     bool           `json:"hasHeader"`
+	// MaxInFlight is the max concurrent collects of the sink. OrderKey is the dynamic prop to keep the order by key.
+	MaxInFlight int    `json:"maxInFlight"`
+	OrderKey    string `json:"orderKey"`
 	conf.SinkConf
 }
 
@@ -75,6 +80,12 @@ func ParseConf(logger api.Logger, props map[string]any) (*SinkConf, error) {
 	if sconf.BatchSize < 0 {
 		return nil, fmt.Errorf("invalid batchSize %d", sconf.BatchSize)
 	}
+	if sconf.AdaptiveBatch && sconf.BatchSize <= 0 {
+		return nil, fmt.Errorf("adaptiveBatch requires batchSize as the max batch size")
+	}
+	if sconf.MaxInFlight < 0 {
+		return nil, fmt.Errorf("invalid maxInFlight %d, must not be negative", sconf.MaxInFlight)
+	}
 	if sconf.LingerInterval < 0 {
 		return nil, fmt.Errorf("invalid lingerInterval %v, must be positive", sconf.LingerInterval)
 	}
@@ -0,0 +1,131 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+)
+
+// SideOutputTopicPrefix is the prefix of the default memory topic of the rule side output
+const SideOutputTopicPrefix = "sideOutput/"
+
+// The reasons of the data sent to the side output, which are set in the metadata
+const (
+	SideOutputLate        = "late"
+	SideOutputDecodeError = "decodeError"
+	SideOutputInvalid     = "invalid"
+)
+
+// SideOutputTopic returns the memory topic of the rule side output. Return empty if the side output is not enabled.
+func SideOutputTopic(ruleId string, so *def.SideOutput) string {
+	if so == nil {
+		return ""
+	}
+	if so.Topic != "" {
+		return so.Topic
+	}
+	return SideOutputTopicPrefix + ruleId
+}
+
+// EnableSideOutput routes the data which cannot be processed by this node to the memory topic instead of
+// sending out the error
+func (o *defaultNode) EnableSideOutput(topic string) {
+	o.sideOutput = topic
+}
+
+// emitSideOutput sends the data with the error info in its metadata to the side output.
+// Return false if the side output is not enabled.
+func (o *defaultNode) emitSideOutput(ctx api.StreamContext, reason string, err error, data any, extra map[string]any) bool {
+	if o.sideOutput == "" {
+		return false
+	}
+	t := &xsql.Tuple{}
+	var meta map[string]any
+	switch d := data.(type) {
+	case *xsql.RawTuple:
+		t.Emitter, t.Timestamp, meta = d.Emitter, d.Timestamp, d.Metadata
+		t.Message = map[string]any{"payload": string(d.Raw())}
+	case *xsql.Tuple:
+		t.Emitter, t.Timestamp, meta = d.Emitter, d.Timestamp, d.Metadata
+		t.Message = make(map[string]any, len(d.Message))
+		for k, v := range d.Message {
+			t.Message[k] = v
+		}
+	default:
+		t.Message = map[string]any{"payload": fmt.Sprint(d)}
+	}
+	t.Metadata = make(map[string]any, len(meta)+len(extra)+4)
+	for k, v := range meta {
+		t.Metadata[k] = v
+	}
+	for k, v := range extra {
+		t.Metadata[k] = v
+	}
+	t.Metadata["reason"] = reason
+	t.Metadata["error"] = err.Error()
+	t.Metadata["ruleId"] = ctx.GetRuleId()
+	t.Metadata["op"] = o.name
+	pubsub.Produce(ctx, o.sideOutput, t)
+	ctx.GetLogger().Debugf("send %s data to side output %s", reason, o.sideOutput)
+	return true
+}
+
+// SideOutputNode subscribes the side output of the rule and sends the data to the dedicated actions
+type SideOutputNode struct {
+	*defaultSinkNode
+	topic string
+}
+
+func NewSideOutputNode(name string, topic string, options *def.RuleOption) *SideOutputNode {
+	return &SideOutputNode{
+		defaultSinkNode: newDefaultSinkNode(name, options),
+		topic:           topic,
+	}
+}
+
+func (s *SideOutputNode) Exec(ctx api.StreamContext, errCh chan<- error) {
+	s.prepareExec(ctx, errCh, "op")
+	subId := fmt.Sprintf("%s_%s", ctx.GetRuleId(), s.name)
+	ch := pubsub.CreateSub(s.topic, nil, subId, cap(s.input))
+	go func() {
+		defer func() {
+			pubsub.CloseSourceConsumerChannel(s.topic, subId)
+			s.Close()
+		}()
+		err := infra.SafeRun(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case item := <-ch:
+					s.onProcessStart(ctx, item)
+					s.Broadcast(item)
+					s.onSend(ctx, item)
+					s.onProcessEnd(ctx)
+				}
+			}
+		})
+		if err != nil {
+			infra.DrainError(ctx, err, errCh)
+		}
+	}()
+}
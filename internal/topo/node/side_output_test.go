@@ -0,0 +1,112 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestSideOutputTopic(t *testing.T) {
+	assert.Equal(t, "", SideOutputTopic("rule1", nil))
+	assert.Equal(t, "sideOutput/rule1", SideOutputTopic("rule1", &def.SideOutput{}))
+	assert.Equal(t, "invalid", SideOutputTopic("rule1", &def.SideOutput{Topic: "invalid"}))
+}
+
+func TestEmitSideOutput(t *testing.T) {
+	ctx := mockContext.NewMockContext("testSideOutput", "op1")
+	ch := pubsub.CreateSub("testSideOutput", nil, "testSideOutputSub", 10)
+	defer pubsub.CloseSourceConsumerChannel("testSideOutput", "testSideOutputSub")
+	n := newDefaultNode("op1", &def.RuleOption{})
+	assert.False(t, n.emitSideOutput(ctx, SideOutputInvalid, errors.New("invalid"), &xsql.Tuple{}, nil))
+	n.EnableSideOutput("testSideOutput")
+	tests := []struct {
+		reason string
+		data   any
+		extra  map[string]any
+		result *xsql.Tuple
+	}{
+		{
+			reason: SideOutputDecodeError,
+			data:   &xsql.RawTuple{Emitter: "demo", Rawdata: []byte("{a"), Timestamp: time.UnixMilli(10), Metadata: map[string]any{"topic": "demo"}},
+			result: &xsql.Tuple{
+				Emitter:   "demo",
+				Message:   map[string]any{"payload": "{a"},
+				Metadata:  map[string]any{"topic": "demo", "reason": "decodeError", "error": "mock error", "ruleId": "testSideOutput", "op": "op1"},
+				Timestamp: time.UnixMilli(10),
+			},
+		},
+		{
+			reason: SideOutputLate,
+			data:   &xsql.Tuple{Emitter: "demo", Message: map[string]any{"a": 1}, Timestamp: time.UnixMilli(20)},
+			extra:  map[string]any{"lateBy": int64(5)},
+			result: &xsql.Tuple{
+				Emitter:   "demo",
+				Message:   map[string]any{"a": 1},
+				Metadata:  map[string]any{"lateBy": int64(5), "reason": "late", "error": "mock error", "ruleId": "testSideOutput", "op": "op1"},
+				Timestamp: time.UnixMilli(20),
+			},
+		},
+	}
+	for _, tt := range tests {
+		require.True(t, n.emitSideOutput(ctx, tt.reason, errors.New("mock error"), tt.data, tt.extra))
+		select {
+		case r := <-ch:
+			assert.Equal(t, tt.result, r)
+		case <-time.After(time.Second):
+			require.Fail(t, "receive side output timeout", tt.reason)
+		}
+	}
+}
+
+func TestDecodeErrorSideOutput(t *testing.T) {
+	ctx := mockContext.NewMockContext("testDecodeSideOutput", "decode_test")
+	ch := pubsub.CreateSub("decodeSideOutput", nil, "testDecodeSideOutputSub", 10)
+	defer pubsub.CloseSourceConsumerChannel("decodeSideOutput", "testDecodeSideOutputSub")
+	op, err := NewDecodeOp(ctx, false, "test", "streamName", &def.RuleOption{BufferLength: 10, SendError: true}, map[string]*ast.JsonStreamField{
+		"a": {
+			Type: "bigint",
+		},
+	}, map[string]any{})
+	require.NoError(t, err)
+	op.EnableSideOutput("decodeSideOutput")
+	out := make(chan any, 10)
+	require.NoError(t, op.AddOutput(out, "test"))
+	op.Exec(ctx, make(chan error))
+	op.input <- &xsql.RawTuple{Emitter: "test", Rawdata: []byte("{\"a\":"), Timestamp: time.UnixMilli(111)}
+	op.input <- &xsql.RawTuple{Emitter: "test", Rawdata: []byte("{\"a\":1}"), Timestamp: time.UnixMilli(112)}
+	// The invalid data goes to the side output instead of the output error
+	r := <-out
+	assert.Equal(t, &xsql.Tuple{Emitter: "test", Message: map[string]any{"a": int64(1)}, Timestamp: time.UnixMilli(112)}, r)
+	select {
+	case r := <-ch:
+		st := r.(*xsql.Tuple)
+		assert.Equal(t, map[string]any{"payload": "{\"a\":"}, map[string]any(st.Message))
+		assert.Equal(t, SideOutputDecodeError, st.Metadata["reason"])
+		assert.NotEmpty(t, st.Metadata["error"])
+	case <-time.After(time.Second):
+		require.Fail(t, "receive side output timeout")
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// asyncRequest is an in-flight collect of the async sink
+type asyncRequest struct {
+	data  any
+	start time.Time
+	// firstErr is the error of the first try if it is resent
+	firstErr error
+	err      error
+	done     bool
+}
+
+// SetAsync lets the sink collect up to maxInFlight items concurrently. The results are acknowledged in the order of
+// the input, so the metrics, the errors and the resend queue see the same order as the synchronous sink. If the
+// orderKey is set, it is the dynamic prop such as {{.deviceId}} to decide the key, the items of the same key are
+// collected one by one in order. Otherwise, the items are collected in any order.
+// The sink must be safe to collect concurrently. It is ignored by the transactional sink which commits the data
+// before a barrier as a whole.
+func (s *SinkNode) SetAsync(ctx api.StreamContext, maxInFlight int, orderKey string) {
+	if maxInFlight <= 1 {
+		return
+	}
+	if s.txnSink != nil {
+		ctx.GetLogger().Warnf("sink %s is transactional, ignore maxInFlight %d", s.name, maxInFlight)
+		return
+	}
+	s.maxInFlight = maxInFlight
+	s.orderKey = orderKey
+}
+
+// runAsync is the loop of the async sink. The collects run in the lanes. The results are acknowledged in this
+// goroutine by the order of the input so that the stats are only accessed here.
+func (s *SinkNode) runAsync(ctx api.StreamContext) {
+	var (
+		lanes   = make([]chan *asyncRequest, s.maxInFlight)
+		acks    = make(chan *asyncRequest, s.maxInFlight)
+		pending []*asyncRequest
+		next    int
+		wg      sync.WaitGroup
+	)
+	for i := range lanes {
+		lanes[i] = make(chan *asyncRequest, s.maxInFlight)
+		wg.Add(1)
+		go func(lane <-chan *asyncRequest) {
+			defer wg.Done()
+			for req := range lane {
+				s.collectAsync(ctx, req)
+				select {
+				case acks <- req:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(lanes[i])
+	}
+	// Wait for the lanes before the sink closes
+	defer func() {
+		for _, lane := range lanes {
+			close(lane)
+		}
+		wg.Wait()
+	}()
+
+	ack := func(req *asyncRequest) {
+		req.done = true
+		for len(pending) > 0 && pending[0].done {
+			s.ackAsync(ctx, pending[0])
+			pending = pending[1:]
+		}
+	}
+	for {
+		input := s.input
+		// Stop reading when all the slots are in use
+		if len(pending) >= s.maxInFlight {
+			input = nil
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-acks:
+			ack(req)
+		case d := <-input:
+			if !isAsyncData(d) {
+				// The control items are handled after all the previous data are acknowledged
+				for len(pending) > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case req := <-acks:
+						ack(req)
+					}
+				}
+			}
+			data, processed := s.ingest(ctx, d)
+			if processed {
+				break
+			}
+			s.statManager.IncTotalRecordsIn()
+			req := &asyncRequest{data: data, start: time.Now()}
+			pending = append(pending, req)
+			lane := s.lane(data, next)
+			next++
+			lanes[lane] <- req
+		}
+	}
+}
+
+// collectAsync runs in the lane. It retries the io error in the lane if the resendInterval is set without the alter
+// queue, so the later items of the same key wait for it.
+func (s *SinkNode) collectAsync(ctx api.StreamContext, req *asyncRequest) {
+	start := time.Now()
+	req.err = s.doCollect(ctx, s.sink, req.data)
+	s.observeLatency(start)
+	if req.err == nil || s.resendOut != nil || s.resendInterval <= 0 || !errorx.IsIOError(req.err) {
+		return
+	}
+	req.firstErr = req.err
+	ticker := timex.GetTicker(s.resendInterval)
+	defer ticker.Stop()
+	for req.err != nil && errorx.IsIOError(req.err) {
+		ctx.GetLogger().Debugf("wait resending %v", req.data)
+		select {
+		case <-ctx.Done():
+			ctx.GetLogger().Infof("rule stop, exit retry for %v", req.data)
+			return
+		case <-ticker.C:
+			req.err = s.doCollect(ctx, s.sink, req.data)
+		}
+	}
+}
+
+// ackAsync does the same works as the synchronous sink after the collect
+func (s *SinkNode) ackAsync(ctx api.StreamContext, req *asyncRequest) {
+	s.statManager.SetProcessTimeStart(req.start)
+	if req.firstErr != nil {
+		s.onError(ctx, req.firstErr)
+		if req.err == nil {
+			ctx.GetLogger().Debugf("resend success %v", req.data)
+			s.onSend(ctx, req.data)
+		} else {
+			ctx.GetLogger().Debugf("no io error %v", req.err)
+		}
+	} else if req.err != nil {
+		s.onError(ctx, req.err)
+		if s.resendOut != nil {
+			s.BroadcastCustomized(req.data, func(val any) {
+				select {
+				case s.resendOut <- val:
+				case <-ctx.Done():
+				default:
+					s.onError(ctx, fmt.Errorf("buffer full, drop message from %s to resend sink", s.name))
+				}
+			})
+		} else if s.resendInterval > 0 {
+			ctx.GetLogger().Errorf("no io error %v, drop %v", req.err, req.data)
+		}
+	} else {
+		s.onSend(ctx, req.data)
+	}
+	s.onProcessEnd(ctx)
+	s.statManager.SetBufferLength(int64(len(s.input)))
+}
+
+// lane returns the lane index of the data by the hash of the order key, or round-robin if there is no order key
+func (s *SinkNode) lane(data any, next int) int {
+	if s.orderKey == "" {
+		return next % s.maxInFlight
+	}
+	var key string
+	if dp, ok := data.(api.HasDynamicProps); ok {
+		key, _ = dp.DynamicProps(s.orderKey)
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(s.maxInFlight))
+}
+
+// isAsyncData returns whether the item is collected by the sink. The others such as the barriers and EOF must wait
+// for the in-flight data.
+func isAsyncData(item any) bool {
+	if b, ok := item.(*checkpoint.BufferOrEvent); ok {
+		item = b.Data
+	}
+	switch item.(type) {
+	case *checkpoint.Barrier, *xsql.WatermarkTuple, xsql.EOFTuple:
+		return false
+	default:
+		return true
+	}
+}
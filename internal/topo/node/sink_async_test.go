@@ -0,0 +1,149 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+const (
+	orderKeyProp = "{{.key}}"
+	totalKey     = "$total"
+)
+
+// mockSlowSink takes longer for the earlier items so that the later ones finish first
+type mockSlowSink struct {
+	sync.Mutex
+	fail     bool
+	inFlight map[string]int
+	// maxInFlight is the max concurrent collects of each key and the total
+	maxInFlight map[string]int
+	collected   map[string][]int64
+}
+
+func newMockSlowSink(fail bool) *mockSlowSink {
+	return &mockSlowSink{
+		fail:        fail,
+		inFlight:    map[string]int{},
+		maxInFlight: map[string]int{},
+		collected:   map[string][]int64{},
+	}
+}
+
+func (m *mockSlowSink) Provision(_ api.StreamContext, _ map[string]any) error {
+	return nil
+}
+
+func (m *mockSlowSink) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func (m *mockSlowSink) Connect(_ api.StreamContext, _ api.StatusChangeHandler) error {
+	return nil
+}
+
+func (m *mockSlowSink) Collect(_ api.StreamContext, item api.RawTuple) error {
+	key, _ := item.(api.HasDynamicProps).DynamicProps(orderKeyProp)
+	ts := item.(*xsql.RawTuple).Timestamp.UnixMilli()
+	m.Lock()
+	m.inFlight[key]++
+	m.inFlight[totalKey]++
+	m.maxInFlight[key] = max(m.maxInFlight[key], m.inFlight[key])
+	m.maxInFlight[totalKey] = max(m.maxInFlight[totalKey], m.inFlight[totalKey])
+	m.Unlock()
+	time.Sleep(time.Duration(10-ts) * 5 * time.Millisecond)
+	m.Lock()
+	defer m.Unlock()
+	m.inFlight[key]--
+	m.inFlight[totalKey]--
+	m.collected[key] = append(m.collected[key], ts)
+	if m.fail {
+		return errorx.NewIOErr("fake error")
+	}
+	return nil
+}
+
+func TestAsyncSinkOrderedAck(t *testing.T) {
+	ctx, cancel := mockContext.NewMockContext("asyncAck", "sink").WithCancel()
+	defer cancel()
+	s := newMockSlowSink(true)
+	n, err := NewBytesSinkNode(ctx, "async_sink", s, def.RuleOption{BufferLength: 1024}, 1, &conf.SinkConf{
+		EnableCache:          true,
+		MemoryCacheThreshold: 10,
+		ResendAlterQueue:     true,
+	}, false)
+	require.NoError(t, err)
+	n.SetAsync(ctx, 4, "")
+	resendCh := make(chan any, 10)
+	n.SetResendOutput(resendCh)
+	n.Exec(ctx, make(chan error, 1))
+	for i := 0; i < 8; i++ {
+		n.input <- &xsql.RawTuple{Timestamp: time.UnixMilli(int64(i))}
+	}
+	// The failed items are sent to the resend queue by the input order though the later ones finish first
+	for i := 0; i < 8; i++ {
+		select {
+		case d := <-resendCh:
+			assert.Equal(t, int64(i), d.(*xsql.RawTuple).Timestamp.UnixMilli())
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timeout waiting for the resend")
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	assert.Greater(t, s.maxInFlight[totalKey], 1)
+	assert.LessOrEqual(t, s.maxInFlight[totalKey], 4)
+}
+
+func TestAsyncSinkOrderKey(t *testing.T) {
+	ctx, cancel := mockContext.NewMockContext("asyncKey", "sink").WithCancel()
+	defer cancel()
+	s := newMockSlowSink(false)
+	n, err := NewBytesSinkNode(ctx, "async_sink", s, def.RuleOption{BufferLength: 1024}, 1, &conf.SinkConf{MemoryCacheThreshold: 10}, false)
+	require.NoError(t, err)
+	n.SetAsync(ctx, 4, orderKeyProp)
+	errCh := make(chan error, 1)
+	n.Exec(ctx, errCh)
+	keys := []string{"a", "b", "a", "b", "a", "b"}
+	for i, k := range keys {
+		n.input <- &xsql.RawTuple{Timestamp: time.UnixMilli(int64(i)), Props: map[string]string{orderKeyProp: k}}
+	}
+	// EOF is handled after all the items are acknowledged
+	n.input <- xsql.EOFTuple(0)
+	select {
+	case err := <-errCh:
+		assert.True(t, errorx.IsEOF(err))
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout waiting for EOF")
+	}
+	s.Lock()
+	defer s.Unlock()
+	// The items of the same key are collected one by one in order
+	assert.Equal(t, map[string][]int64{"a": {0, 2, 4}, "b": {1, 3, 5}}, s.collected)
+	assert.Equal(t, 1, s.maxInFlight["a"])
+	assert.Equal(t, 1, s.maxInFlight["b"])
+}
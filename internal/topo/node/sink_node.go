@@ -17,6 +17,7 @@ package node
 import (
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
@@ -42,6 +43,16 @@ type SinkNode struct {
 	doCollect      func(ctx api.StreamContext, sink api.Sink, data any) error
 	// channel for resend
 	resendOut chan<- any
+	// txnSink is set if the sink commits with the checkpoint. The commitSignal notifies to commit the transactions up
+	// to the committable checkpoint.
+	txnSink      model.TwoPhaseCommit
+	committable  atomic.Int64
+	commitSignal chan struct{}
+	// maxInFlight is the max concurrent collects of the async sink. The sink is synchronous if it is not bigger than 1.
+	maxInFlight int
+	orderKey    string
+	// batchCtrl receives the send latency to adapt the batch if the adaptive batch is enabled
+	batchCtrl *BatchController
 }
 
 // Caching:
@@ -77,18 +88,27 @@ func (s *SinkNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 				s.sink.Close(ctx)
 				s.Close()
 			}()
+			s.recoverTxns(ctx)
 			s.currentEof = 0
+			if s.maxInFlight > 1 {
+				s.runAsync(ctx)
+				return nil
+			}
 			for {
 				select {
 				case <-ctx.Done():
 					return nil
+				case <-s.commitSignal:
+					s.commit(ctx, s.committable.Load())
 				case d := <-s.input:
 					data, processed := s.ingest(ctx, d)
 					if processed {
 						break
 					}
 					s.onProcessStart(ctx, data)
+					start := time.Now()
 					err = s.doCollect(ctx, s.sink, data)
+					s.observeLatency(start)
 					if err != nil { // resend handling when enabling cache. Two cases: 1. send to alter queue with resendOUt. 2. retry (blocking) until success or unrecoverable error if resendInterval is set
 						s.onError(ctx, err)
 						if s.resendOut != nil {
@@ -145,6 +165,23 @@ func (s *SinkNode) SetResendOutput(output chan<- any) {
 	s.resendOut = output
 }
 
+func (s *SinkNode) SetBatchController(ctrl *BatchController) {
+	s.batchCtrl = ctrl
+}
+
+// observeLatency reports the duration of the first send to the adaptive batch. For the async sink, the latency is
+// shared by the in-flight sends.
+func (s *SinkNode) observeLatency(start time.Time) {
+	if s.batchCtrl == nil {
+		return
+	}
+	d := time.Since(start)
+	if s.maxInFlight > 1 {
+		d /= time.Duration(s.maxInFlight)
+	}
+	s.batchCtrl.ObserveLatency(d)
+}
+
 func (s *SinkNode) connectionStatusChange(status string, message string) {
 	if status == api.ConnectionDisconnected {
 		s.statManager.IncTotalExceptions(message)
@@ -183,6 +220,7 @@ func NewBytesSinkNode(ctx api.StreamContext, name string, sink api.BytesCollecto
 	n := newSinkNode(ctx, name, rOpt, eoflimit, sc, isRetry)
 	n.sink = sink
 	n.doCollect = bytesCollect
+	n.enableTransaction(ctx, rOpt)
 	return n, nil
 }
 
@@ -207,6 +245,7 @@ func NewTupleSinkNode(ctx api.StreamContext, name string, sink api.TupleCollecto
 	n := newSinkNode(ctx, name, rOpt, eoflimit, sc, isRetry)
 	n.sink = sink
 	n.doCollect = tupleCollect
+	n.enableTransaction(ctx, rOpt)
 	return n, nil
 }
 
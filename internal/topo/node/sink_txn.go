@@ -0,0 +1,130 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+// txnStateKey is the state key of the pre-committed transactions which are not committed yet
+const txnStateKey = "$$txn"
+
+func init() {
+	gob.Register(map[int64]any{})
+}
+
+// enableTransaction lets the sink commit with the checkpoint if the rule is exactly once
+func (s *SinkNode) enableTransaction(ctx api.StreamContext, rOpt def.RuleOption) {
+	if rOpt.Qos < def.ExactlyOnce {
+		return
+	}
+	tc, ok := s.sink.(model.TwoPhaseCommit)
+	if !ok {
+		ctx.GetLogger().Warnf("sink %s does not support two-phase commit, the output may be duplicated after recovery", s.name)
+		return
+	}
+	tc.SetTransactional()
+	s.txnSink = tc
+	s.commitSignal = make(chan struct{}, 1)
+}
+
+// PreCommit ends the transaction and keeps it in the state. It runs in the sink goroutine when the barrier arrives, so
+// the transaction has exactly the data before the barrier.
+func (s *SinkNode) PreCommit(checkpointId int64) error {
+	if s.txnSink == nil {
+		return nil
+	}
+	txn, err := s.txnSink.PreCommit(s.ctx, checkpointId)
+	if err != nil || txn == nil {
+		return err
+	}
+	pending := s.pendingTxns()
+	pending[checkpointId] = txn
+	return s.ctx.PutState(txnStateKey, pending)
+}
+
+// Commit is called by the coordinator when the checkpoint completes. The commit runs in the sink goroutine.
+func (s *SinkNode) Commit(checkpointId int64) {
+	if s.txnSink == nil {
+		return
+	}
+	for {
+		old := s.committable.Load()
+		if checkpointId <= old || s.committable.CompareAndSwap(old, checkpointId) {
+			break
+		}
+	}
+	select {
+	case s.commitSignal <- struct{}{}:
+	default:
+	}
+}
+
+// commit commits the pending transactions up to the checkpoint in order. The failed one and the following ones are
+// retried in the next commit.
+func (s *SinkNode) commit(ctx api.StreamContext, upTo int64) {
+	pending := s.pendingTxns()
+	ids := make([]int64, 0, len(pending))
+	for id := range pending {
+		if id <= upTo {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if err := s.txnSink.Commit(ctx, id, pending[id]); err != nil {
+			s.onError(ctx, fmt.Errorf("commit the transaction of checkpoint %d error: %v", id, err))
+			break
+		}
+		ctx.GetLogger().Debugf("commit the transaction of checkpoint %d", id)
+		delete(pending, id)
+	}
+	_ = ctx.PutState(txnStateKey, pending)
+}
+
+// recoverTxns commits the transactions restored from the checkpoint. Their checkpoint has completed, but the commit may
+// not be done before the rule stopped.
+func (s *SinkNode) recoverTxns(ctx api.StreamContext) {
+	if s.txnSink != nil {
+		s.commit(ctx, math.MaxInt64)
+	}
+}
+
+// pendingTxns returns a copy of the pending transactions. The state map is never modified in place because it may be
+// in a snapshot which is being saved.
+func (s *SinkNode) pendingTxns() map[int64]any {
+	result := make(map[int64]any)
+	v, _ := s.ctx.GetState(txnStateKey)
+	if m, ok := v.(map[int64]any); ok {
+		for k, txn := range m {
+			result[k] = txn
+		}
+	}
+	return result
+}
+
+var _ checkpoint.TransactionalTask = (*SinkNode)(nil)
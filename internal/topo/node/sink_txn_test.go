@@ -0,0 +1,157 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/model"
+)
+
+func TestSinkTransaction(t *testing.T) {
+	ctx, cancel := mockContext.NewMockContext("txnRule", "sink").WithCancel()
+	s := &mockTxnSink{}
+	n, err := NewBytesSinkNode(ctx, "txn_sink", s, def.RuleOption{BufferLength: 10, Qos: def.ExactlyOnce}, 1, &conf.SinkConf{MemoryCacheThreshold: 10}, false)
+	require.NoError(t, err)
+	require.True(t, s.transactional)
+	n.SetQos(def.ExactlyOnce)
+	n.SetBarrierHandler(checkpoint.NewBarrierTracker(&mockTxnResponder{n: n}, 1))
+	errCh := make(chan error, 1)
+	n.Exec(ctx, errCh)
+
+	n.input <- &xsql.RawTuple{Rawdata: []byte("a")}
+	n.input <- &checkpoint.BufferOrEvent{Data: &checkpoint.Barrier{CheckpointId: 1, OpId: "src"}}
+	n.input <- &xsql.RawTuple{Rawdata: []byte("b")}
+	n.input <- &checkpoint.BufferOrEvent{Data: &checkpoint.Barrier{CheckpointId: 2, OpId: "src"}}
+	n.input <- &xsql.RawTuple{Rawdata: []byte("c")}
+	assert.Eventually(t, func() bool {
+		return len(s.getCollected()) == 3
+	}, time.Second, 10*time.Millisecond)
+	// Nothing is written before the checkpoint completes
+	assert.Empty(t, s.getCommitted())
+
+	n.Commit(2)
+	assert.Eventually(t, func() bool {
+		return len(s.getCommitted()) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"1:a", "2:b"}, s.getCommitted())
+	cancel()
+
+	// Recover the pre-committed transactions from the state
+	ctx, cancel = mockContext.NewMockContext("txnRule", "sink").WithCancel()
+	defer cancel()
+	require.NoError(t, ctx.PutState(txnStateKey, map[int64]any{3: []string{"c"}}))
+	s = &mockTxnSink{}
+	n, err = NewBytesSinkNode(ctx, "txn_sink", s, def.RuleOption{BufferLength: 10, Qos: def.ExactlyOnce}, 1, &conf.SinkConf{MemoryCacheThreshold: 10}, false)
+	require.NoError(t, err)
+	n.Exec(ctx, errCh)
+	assert.Eventually(t, func() bool {
+		return len(s.getCommitted()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"3:c"}, s.getCommitted())
+}
+
+type mockTxnResponder struct {
+	n *SinkNode
+}
+
+func (m *mockTxnResponder) TriggerCheckpoint(checkpointId int64) error {
+	return m.n.PreCommit(checkpointId)
+}
+
+func (m *mockTxnResponder) GetName() string {
+	return m.n.name
+}
+
+type mockTxnSink struct {
+	sync.Mutex
+	transactional bool
+	txn           []string
+	collected     []string
+	committed     []string
+}
+
+func (m *mockTxnSink) Provision(_ api.StreamContext, _ map[string]any) error {
+	return nil
+}
+
+func (m *mockTxnSink) Close(_ api.StreamContext) error {
+	return nil
+}
+
+func (m *mockTxnSink) Connect(_ api.StreamContext, _ api.StatusChangeHandler) error {
+	return nil
+}
+
+func (m *mockTxnSink) Collect(_ api.StreamContext, item api.RawTuple) error {
+	m.Lock()
+	defer m.Unlock()
+	m.txn = append(m.txn, string(item.Raw()))
+	m.collected = append(m.collected, string(item.Raw()))
+	return nil
+}
+
+func (m *mockTxnSink) SetTransactional() {
+	m.transactional = true
+}
+
+func (m *mockTxnSink) PreCommit(_ api.StreamContext, _ int64) (any, error) {
+	m.Lock()
+	defer m.Unlock()
+	if len(m.txn) == 0 {
+		return nil, nil
+	}
+	txn := m.txn
+	m.txn = nil
+	return txn, nil
+}
+
+func (m *mockTxnSink) Commit(_ api.StreamContext, checkpointId int64, txn any) error {
+	m.Lock()
+	defer m.Unlock()
+	for _, v := range txn.([]string) {
+		m.committed = append(m.committed, fmt.Sprintf("%d:%s", checkpointId, v))
+	}
+	return nil
+}
+
+func (m *mockTxnSink) getCollected() []string {
+	m.Lock()
+	defer m.Unlock()
+	return append([]string{}, m.collected...)
+}
+
+func (m *mockTxnSink) getCommitted() []string {
+	m.Lock()
+	defer m.Unlock()
+	return append([]string{}, m.committed...)
+}
+
+var (
+	_ api.BytesCollector   = &mockTxnSink{}
+	_ model.TwoPhaseCommit = &mockTxnSink{}
+)
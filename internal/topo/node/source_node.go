@@ -30,6 +30,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/memory"
 	"github.com/lf-edge/ekuiper/v2/pkg/model"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
@@ -51,6 +52,13 @@ type sourceConf struct {
 
 // NewSourceNode creates a SourceConnectorNode
 func NewSourceNode(ctx api.StreamContext, name string, ss api.Source, props map[string]any, rOpt *def.RuleOption) (*SourceNode, error) {
+	if rOpt.Mode == def.RuleModeBatch {
+		ro, ok := ss.(model.RunOnce)
+		if !ok {
+			return nil, fmt.Errorf("source %s does not support the batch mode", name)
+		}
+		ro.SetRunOnce()
+	}
 	err := ss.Provision(ctx, props)
 	if err != nil {
 		return nil, err
@@ -85,6 +93,8 @@ func (m *SourceNode) Open(ctx api.StreamContext, ctrlCh chan<- error) {
 
 func (m *SourceNode) ingestBytes(ctx api.StreamContext, data []byte, meta map[string]any, ts time.Time) {
 	ctx.GetLogger().Debugf("source connector %s receive data %+v", m.name, data)
+	// Block the source connector under memory pressure so that it stops consuming
+	memory.Wait(ctx)
 	m.onProcessStart(ctx, nil)
 	if meta == nil {
 		meta = make(map[string]any)
@@ -139,6 +149,8 @@ func (m *SourceNode) traceStart(ctx api.StreamContext, meta map[string]any, tupl
 
 func (m *SourceNode) ingestAnyTuple(ctx api.StreamContext, data any, meta map[string]any, ts time.Time) {
 	ctx.GetLogger().Debugf("source connector %s receive data %+v", m.name, data)
+	// Block the source connector under memory pressure so that it stops consuming
+	memory.Wait(ctx)
 	m.onProcessStart(ctx, nil)
 	if meta == nil {
 		meta = make(map[string]any)
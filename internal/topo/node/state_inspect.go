@@ -0,0 +1,227 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+)
+
+const (
+	// The max elements of a collection to estimate the size, the size of the rest is extrapolated
+	stateSampleSize = 100
+	// The max depth to estimate the size of the nested values
+	stateMaxDepth = 8
+)
+
+// StateRequest asks a node to summarize its state. It is sent through the input of the node so that the state is
+// read in the node goroutine instead of racing with the update.
+type StateRequest struct {
+	// Limit is the max entries in the summary
+	Limit  int
+	result chan *StateSummary
+}
+
+func NewStateRequest(limit int) *StateRequest {
+	return &StateRequest{Limit: limit, result: make(chan *StateSummary, 1)}
+}
+
+// Result waits for the summary until the context is done
+func (r *StateRequest) Result(ctx context.Context) (*StateSummary, bool) {
+	select {
+	case s := <-r.result:
+		return s, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+func (r *StateRequest) reply(s *StateSummary) {
+	select {
+	case r.result <- s:
+	default:
+	}
+}
+
+// StateSummary is the summary of the state of a node. The sizes are estimated in bytes.
+type StateSummary struct {
+	Op           string        `json:"op"`
+	BufferLength int           `json:"bufferLength"`
+	Keys         int           `json:"keys"`
+	Bytes        int64         `json:"bytes"`
+	Entries      []*StateEntry `json:"entries"`
+	// Truncated is true if the entries are more than the limit, the entries are sorted by the size descending
+	Truncated bool   `json:"truncated,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// StateEntry is a keyed state of a node such as the window inputs, the dedup keys or the function states
+type StateEntry struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	// Count is the count of the items if the state is a collection
+	Count int   `json:"count"`
+	Bytes int64 `json:"bytes"`
+	// Earliest and Latest are the timestamp range in milliseconds of the tuples such as the window contents
+	Earliest int64 `json:"earliest,omitempty"`
+	Latest   int64 `json:"latest,omitempty"`
+}
+
+type allStateContext interface {
+	GetAllState() map[string]any
+}
+
+// inspectState summarizes the state in the context of the node. It must be called in the node goroutine.
+func (o *defaultNode) inspectState(ctx api.StreamContext, r *StateRequest) {
+	s := &StateSummary{Op: o.name, Entries: []*StateEntry{}}
+	if sc, ok := ctx.(allStateContext); ok {
+		for k, v := range sc.GetAllState() {
+			e := summarizeState(k, v)
+			s.Keys++
+			s.Bytes += e.Bytes
+			s.Entries = append(s.Entries, e)
+		}
+	}
+	sortStateEntries(s, r.Limit)
+	r.reply(s)
+}
+
+// inspectStateKeys lists the keys without reading the values, which may be updated by the concurrent workers
+func (o *defaultNode) inspectStateKeys(ctx api.StreamContext, r *StateRequest) {
+	s := &StateSummary{Op: o.name, Entries: []*StateEntry{}, Message: "the node runs concurrently, only the keys are listed"}
+	if sc, ok := ctx.(allStateContext); ok {
+		for k := range sc.GetAllState() {
+			s.Keys++
+			s.Entries = append(s.Entries, &StateEntry{Key: k})
+		}
+	}
+	sortStateEntries(s, r.Limit)
+	r.reply(s)
+}
+
+func sortStateEntries(s *StateSummary, limit int) {
+	sort.Slice(s.Entries, func(i, j int) bool {
+		if s.Entries[i].Bytes != s.Entries[j].Bytes {
+			return s.Entries[i].Bytes > s.Entries[j].Bytes
+		}
+		return s.Entries[i].Key < s.Entries[j].Key
+	})
+	if limit > 0 && len(s.Entries) > limit {
+		s.Entries = s.Entries[:limit]
+		s.Truncated = true
+	}
+}
+
+func summarizeState(key string, v any) *StateEntry {
+	e := &StateEntry{Key: key}
+	if v == nil {
+		e.Type = "nil"
+		return e
+	}
+	rv := reflect.ValueOf(v)
+	e.Type = rv.Type().String()
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		e.Count = rv.Len()
+	}
+	if tuples, ok := v.([]*xsql.Tuple); ok && len(tuples) > 0 {
+		e.Earliest = tuples[0].Timestamp.UnixMilli()
+		e.Latest = tuples[len(tuples)-1].Timestamp.UnixMilli()
+	}
+	e.Bytes = estimateSize(rv, stateMaxDepth)
+	return e
+}
+
+// The contexts such as the context of the tuple are shared by the rule, they are not counted
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// estimateSize estimates the memory of the value including what it references
+func estimateSize(v reflect.Value, depth int) int64 {
+	if !v.IsValid() {
+		return 0
+	}
+	return int64(v.Type().Size()) + indirectSize(v, depth)
+}
+
+// indirectSize estimates the memory referenced by the value. The collections are sampled and the shared references
+// are counted repeatedly, so the result is an estimation.
+func indirectSize(v reflect.Value, depth int) int64 {
+	if depth <= 0 {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() || v.Type().Implements(contextType) {
+			return 0
+		}
+		return estimateSize(v.Elem(), depth-1)
+	case reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+		return int64(v.Cap())*int64(v.Type().Elem().Size()) + sampleSize(v.Len(), func(i int) int64 {
+			return indirectSize(v.Index(i), depth-1)
+		})
+	case reflect.Array:
+		return sampleSize(v.Len(), func(i int) int64 {
+			return indirectSize(v.Index(i), depth-1)
+		})
+	case reflect.Map:
+		n := v.Len()
+		if n == 0 {
+			return 0
+		}
+		var (
+			sum     int64
+			sampled int
+		)
+		iter := v.MapRange()
+		for iter.Next() && sampled < stateSampleSize {
+			sum += estimateSize(iter.Key(), depth-1) + estimateSize(iter.Value(), depth-1)
+			sampled++
+		}
+		return sum * int64(n) / int64(sampled)
+	case reflect.Struct:
+		var sum int64
+		for i := 0; i < v.NumField(); i++ {
+			sum += indirectSize(v.Field(i), depth-1)
+		}
+		return sum
+	default:
+		return 0
+	}
+}
+
+func sampleSize(n int, size func(i int) int64) int64 {
+	if n == 0 {
+		return 0
+	}
+	sampled := n
+	if sampled > stateSampleSize {
+		sampled = stateSampleSize
+	}
+	var sum int64
+	for i := 0; i < sampled; i++ {
+		sum += size(i)
+	}
+	return sum * int64(n) / int64(sampled)
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestInspectState(t *testing.T) {
+	n, err := NewDedupNode("dedup", &ast.FieldRef{Name: "id", StreamName: ast.DefaultStream}, time.Hour, &def.RuleOption{BufferLength: 10})
+	require.NoError(t, err)
+	out := make(chan any, 100)
+	require.NoError(t, n.AddOutput(out, "test"))
+	ctx, cancel := mockContext.NewMockContext("testInspect", "dedup").WithCancel()
+	defer cancel()
+	n.Exec(ctx, make(chan error, 10))
+	for i := 0; i < 3; i++ {
+		n.input <- &xsql.Tuple{Message: map[string]any{"id": i}}
+	}
+	_ = ctx.PutState("window", []*xsql.Tuple{
+		{Message: map[string]any{"a": "hello"}, Timestamp: time.UnixMilli(1000)},
+		{Message: map[string]any{"a": "world"}, Timestamp: time.UnixMilli(2000)},
+	})
+
+	req := NewStateRequest(1)
+	n.input <- req
+	tctx, tcancel := context.WithTimeout(context.Background(), time.Second)
+	defer tcancel()
+	s, ok := req.Result(tctx)
+	require.True(t, ok)
+	assert.Equal(t, "dedup", s.Op)
+	assert.Equal(t, 2, s.Keys)
+	assert.True(t, s.Truncated)
+	require.Len(t, s.Entries, 1)
+	// The largest entry is the first
+	e := s.Entries[0]
+	assert.Equal(t, "window", e.Key)
+	assert.Equal(t, "[]*xsql.Tuple", e.Type)
+	assert.Equal(t, 2, e.Count)
+	assert.Equal(t, int64(1000), e.Earliest)
+	assert.Equal(t, int64(2000), e.Latest)
+	assert.Greater(t, s.Bytes, e.Bytes)
+	// The request is not sent to the downstream
+	assert.Len(t, out, 3)
+}
+
+func TestEstimateSize(t *testing.T) {
+	assert.Equal(t, int64(16+5), estimateSize(reflect.ValueOf("hello"), stateMaxDepth))
+	m := map[string]int64{"a": 1, "b": 2}
+	assert.Equal(t, int64(8+2*(16+1+8)), estimateSize(reflect.ValueOf(m), stateMaxDepth))
+	// The collections are sampled
+	large := make([]int64, stateSampleSize*10)
+	assert.Equal(t, int64(24+8*len(large)), estimateSize(reflect.ValueOf(large), stateMaxDepth))
+	// The context is not counted
+	tuple := &xsql.Tuple{Ctx: mockContext.NewMockContext("testSize", "op")}
+	assert.Equal(t, estimateSize(reflect.ValueOf(&xsql.Tuple{}), stateMaxDepth), estimateSize(reflect.ValueOf(tuple), stateMaxDepth))
+}
@@ -28,6 +28,8 @@ import (
 type SwitchConfig struct {
 	Cases            []ast.Expr
 	StopAtFirstMatch bool
+	// Default adds an extra output after the cases' outputs for the events which do not match any case
+	Default bool
 }
 
 type SwitchNode struct {
@@ -43,6 +45,11 @@ func (n *SwitchNode) GetEmitter(outputIndex int) Emitter {
 	return &n.outputNodes[outputIndex]
 }
 
+// OutputCount returns the number of the outlets including the default one
+func (n *SwitchNode) OutputCount() int {
+	return len(n.outputNodes)
+}
+
 // AddOutput SwitchNode overrides the defaultSinkNode's AddOutput to add output to the outputNodes
 // SwitchNode itself has multiple outlets defined by the outputNodes.
 // This default function will add the output to the first outlet
@@ -58,8 +65,12 @@ func NewSwitchNode(name string, conf *SwitchConfig, options *def.RuleOption) (*S
 		conf: conf,
 	}
 	sn.defaultSinkNode = newDefaultSinkNode(name, options)
-	outputs := make([]defaultNode, len(conf.Cases))
-	for i := range conf.Cases {
+	l := len(conf.Cases)
+	if conf.Default {
+		l++
+	}
+	outputs := make([]defaultNode, l)
+	for i := range outputs {
 		outputs[i] = *newDefaultNode(fmt.Sprintf("name_%d", i), options)
 	}
 	sn.outputNodes = outputs
@@ -105,6 +116,7 @@ func (n *SwitchNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 						n.onError(ctx, fmt.Errorf("run switch node error: invalid input type but got %[1]T(%[1]v)", d))
 						break
 					}
+					matched := false
 				caseLoop:
 					for i, c := range n.conf.Cases {
 						result := ve.Eval(c)
@@ -113,6 +125,7 @@ func (n *SwitchNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 							n.onError(ctx, r)
 						case bool:
 							if r {
+								matched = true
 								n.outputNodes[i].Broadcast(item)
 								if n.conf.StopAtFirstMatch {
 									break caseLoop
@@ -124,6 +137,9 @@ func (n *SwitchNode) Exec(ctx api.StreamContext, errCh chan<- error) {
 							n.onError(ctx, fmt.Errorf("run switch node %s, case %s error: invalid condition that returns non-bool value %[1]T(%[1]v)", n.name, c, r))
 						}
 					}
+					if !matched && n.conf.Default {
+						n.outputNodes[len(n.conf.Cases)].Broadcast(item)
+					}
 					n.onProcessEnd(ctx)
 					n.statManager.SetBufferLength(int64(len(n.input)))
 				case <-ctx.Done():
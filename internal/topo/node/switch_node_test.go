@@ -398,3 +398,56 @@ outterFor:
 		t.Errorf("Expected: %v, actual: %v", outputs, actualOuts)
 	}
 }
+
+func TestSwitchDefault(t *testing.T) {
+	sn, err := NewSwitchNode("test", &SwitchConfig{
+		Cases: []ast.Expr{
+			&ast.BinaryExpr{
+				LHS: &ast.FieldRef{Name: "f2"},
+				OP:  ast.GT,
+				RHS: &ast.NumberLiteral{Val: 40},
+			},
+		},
+		Default: true,
+	}, &def.RuleOption{})
+	if err != nil {
+		t.Fatalf("Failed to create switch node: %v", err)
+	}
+	if sn.OutputCount() != 2 {
+		t.Fatalf("Expect 2 outputs but got %d", sn.OutputCount())
+	}
+	contextLogger := conf.Log.WithField("rule", "TestSwitchDefault")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	errCh := make(chan error)
+	output1 := make(chan interface{}, 10)
+	output2 := make(chan interface{}, 10)
+	sn.outputNodes[0].AddOutput(output1, "output1")
+	sn.outputNodes[1].AddOutput(output2, "output2")
+	go sn.Exec(ctx, errCh)
+	inputs := []*xsql.Tuple{
+		{Message: map[string]interface{}{"f2": 45.6}},
+		{Message: map[string]interface{}{"f2": 26.6}},
+		{Message: map[string]interface{}{"f1": "v1"}},
+	}
+	for _, input := range inputs {
+		sn.input <- input
+	}
+	expected := [][]*xsql.Tuple{{inputs[0]}, {inputs[1], inputs[2]}}
+	actualOuts := make([][]*xsql.Tuple, 2)
+outterFor:
+	for {
+		select {
+		case err := <-errCh:
+			t.Fatalf("Error received: %v", err)
+		case out1 := <-output1:
+			actualOuts[0] = append(actualOuts[0], out1.(*xsql.Tuple))
+		case out2 := <-output2:
+			actualOuts[1] = append(actualOuts[1], out2.(*xsql.Tuple))
+		case <-time.After(100 * time.Millisecond):
+			break outterFor
+		}
+	}
+	if !reflect.DeepEqual(actualOuts, expected) {
+		t.Errorf("Expected: %v, actual: %v", expected, actualOuts)
+	}
+}
@@ -25,7 +25,9 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/transform"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/metrics"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+	"github.com/lf-edge/ekuiper/v2/pkg/pool"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
@@ -95,6 +97,16 @@ func (t *TransformOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 
 // Worker do not need to process error and control messages
 func (t *TransformOp) Worker(ctx api.StreamContext, item any) []any {
+	// raw passthrough, the bytes are sent as is
+	if r, ok := item.(*xsql.RawTuple); ok {
+		metrics.IncRawPassthrough(ctx.GetRuleId(), t.name)
+		return []any{r}
+	}
+	// raw passthrough filtered by the decoded fields, the original bytes are sent as is
+	if r, ok := item.(*xsql.Tuple); ok && r.Rawdata != nil {
+		metrics.IncRawPassthrough(ctx.GetRuleId(), t.name)
+		return []any{&xsql.RawTuple{Ctx: r.Ctx, Emitter: r.Emitter, Timestamp: r.Timestamp, Rawdata: r.Rawdata, Metadata: r.Metadata, Props: r.Props}}
+	}
 	if ic, ok := item.(xsql.Collection); ok && t.omitIfEmpty && ic.Len() == 0 {
 		ctx.GetLogger().Debugf("receive empty collection, dropped")
 		return nil
@@ -174,8 +186,9 @@ func (t *TransformOp) doTransform(d any) (any, error) {
 		e           error
 	)
 	if t.dt != nil {
-		var output bytes.Buffer
-		err := t.dt.Execute(&output, d)
+		output := pool.GetBuffer()
+		defer pool.PutBuffer(output)
+		err := t.dt.Execute(output, d)
 		if err != nil {
 			return nil, fmt.Errorf("fail to encode data %v with dataTemplate for error %v", d, err)
 		}
@@ -194,7 +207,8 @@ func (t *TransformOp) doTransform(d any) (any, error) {
 	// if only do data template
 	if transformed && !selected {
 		if t.isTextFormat {
-			return bs, nil
+			// The output buffer is reused, copy the result
+			return bytes.Clone(bs), nil
 		} else {
 			err := json.Unmarshal(bs, &m)
 			if err != nil {
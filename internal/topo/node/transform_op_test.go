@@ -96,6 +96,31 @@ func TestTransformRun(t *testing.T) {
 				&xsql.Tuple{Message: map[string]any{}, Timestamp: time.UnixMilli(0)},
 			},
 		},
+		{
+			name: "raw passthrough",
+			sc: &SinkConf{
+				Format: "binary",
+			},
+			cases: []any{
+				&xsql.RawTuple{Rawdata: []byte{0x01, 0x02}, Emitter: "bin"},
+			},
+			expects: []any{
+				&xsql.RawTuple{Rawdata: []byte{0x01, 0x02}, Emitter: "bin"},
+			},
+		},
+		{
+			name: "raw passthrough with partial decode",
+			sc: &SinkConf{
+				Format:     "json",
+				SendSingle: true,
+			},
+			cases: []any{
+				&xsql.Tuple{Message: map[string]any{"a": 1}, Rawdata: []byte(`{"a":1,"b":2}`), Emitter: "json"},
+			},
+			expects: []any{
+				&xsql.RawTuple{Rawdata: []byte(`{"a":1,"b":2}`), Emitter: "json"},
+			},
+		},
 		{
 			name: "allow empty",
 			sc: &SinkConf{
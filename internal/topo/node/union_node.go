@@ -0,0 +1,65 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/pkg/infra"
+)
+
+// UnionNode merges the events of multiple branches, such as the outputs of a switch node, back into one flow.
+// The events are forwarded as is in the order of arrival. An event routed to multiple merged branches is sent multiple times.
+type UnionNode struct {
+	*defaultSinkNode
+}
+
+func NewUnionNode(name string, options *def.RuleOption) *UnionNode {
+	return &UnionNode{
+		defaultSinkNode: newDefaultSinkNode(name, options),
+	}
+}
+
+func (n *UnionNode) Exec(ctx api.StreamContext, errCh chan<- error) {
+	n.prepareExec(ctx, errCh, "op")
+	go func() {
+		defer func() {
+			n.Close()
+		}()
+		err := infra.SafeRun(func() error {
+			for {
+				select {
+				case <-ctx.Done():
+					ctx.GetLogger().Infof("union node %s is finished", n.name)
+					return nil
+				case item := <-n.input:
+					data, processed := n.commonIngest(ctx, item)
+					if processed {
+						break
+					}
+					n.onProcessStart(ctx, data)
+					n.Broadcast(data)
+					n.onSend(ctx, data)
+					n.onProcessEnd(ctx)
+					n.statManager.SetBufferLength(int64(len(n.input)))
+				}
+			}
+		})
+		if err != nil {
+			infra.DrainError(ctx, err, errCh)
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestUnion(t *testing.T) {
+	n := NewUnionNode("test", &def.RuleOption{BufferLength: 10})
+	ctx := context.NewMockContext("testUnion", "test")
+	errCh := make(chan error)
+	out := make(chan any, 10)
+	require.NoError(t, n.AddOutput(out, "output"))
+	n.Exec(ctx, errCh)
+	inputs := []any{
+		&xsql.Tuple{Message: map[string]any{"a": 1}},
+		&xsql.Tuple{Message: map[string]any{"b": 2}},
+		xsql.EOFTuple(0),
+	}
+	// the branches share the same input channel of the union node
+	for _, in := range inputs {
+		n.input <- in
+	}
+	for i, exp := range inputs {
+		select {
+		case err := <-errCh:
+			t.Fatal(err)
+		case r := <-out:
+			require.Equal(t, exp, r, "case %d", i)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for output %d", i)
+		}
+	}
+}
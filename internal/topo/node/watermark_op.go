@@ -34,8 +34,12 @@ import (
 type WatermarkOp struct {
 	*defaultSinkNode
 	// config
-	lateTolerance time.Duration
-	sendWatermark bool
+	lateTolerance   time.Duration
+	sendWatermark   bool
+	latePolicy      string
+	allowedLateness time.Duration
+	lateTopic       string
+	ruleSideOutput  *def.SideOutput
 	// state
 	events          []*xsql.Tuple // All the cached events in order
 	rowHandle       map[any]trace.Span
@@ -49,6 +53,8 @@ const (
 	WatermarkKey  = "$$wartermark"
 	EventInputKey = "$$eventinputs"
 	StreamWMKey   = "$$streamwms"
+	// LateDataTopicPrefix is the prefix of the default memory topic of the late rows
+	LateDataTopicPrefix = "late/"
 )
 
 func NewWatermarkOp(name string, sendWatermark bool, streams []string, options *def.RuleOption) *WatermarkOp {
@@ -60,6 +66,10 @@ func NewWatermarkOp(name string, sendWatermark bool, streams []string, options *
 		defaultSinkNode: newDefaultSinkNode(name, options),
 		lateTolerance:   time.Duration(options.LateTol),
 		sendWatermark:   sendWatermark,
+		latePolicy:      options.LateDataPolicy,
+		allowedLateness: time.Duration(options.AllowedLateness),
+		lateTopic:       options.LateDataTopic,
+		ruleSideOutput:  options.SideOutput,
 		streamWMs:       wms,
 		lastWatermarkTs: time.Time{},
 		rowHandle:       make(map[any]trace.Span),
@@ -67,6 +77,14 @@ func NewWatermarkOp(name string, sendWatermark bool, streams []string, options *
 }
 
 func (w *WatermarkOp) Exec(ctx api.StreamContext, errCh chan<- error) {
+	// The late events go to the lateDataTopic, then the rule side output, then the default late data topic
+	if w.latePolicy == def.LateDataSideOutput && w.lateTopic != "" {
+		w.sideOutput = w.lateTopic
+	} else if t := SideOutputTopic(ctx.GetRuleId(), w.ruleSideOutput); t != "" {
+		w.sideOutput = t
+	} else if w.latePolicy == def.LateDataSideOutput {
+		w.sideOutput = LateDataTopicPrefix + ctx.GetRuleId()
+	}
 	w.prepareExec(ctx, errCh, "op")
 	// restore state
 	if s, err := ctx.GetState(WatermarkKey); err == nil && s != nil {
@@ -126,6 +144,8 @@ func (w *WatermarkOp) Exec(ctx api.StreamContext, errCh chan<- error) {
 						if w.track(ctx, d.Emitter, d.Timestamp) {
 							// If not drop, check if it can be sent out
 							w.addAndTrigger(ctx, d)
+						} else {
+							w.handleLate(ctx, d)
 						}
 					default:
 						w.onError(ctx, fmt.Errorf("run watermark op error: expect *xsql.Tuple type but got %[1]T(%[1]v)", d))
@@ -152,6 +172,31 @@ func (w *WatermarkOp) track(ctx api.StreamContext, emitter string, ts time.Time)
 	return r
 }
 
+// handleLate handles the event which arrives after the watermark by the late data policy
+func (w *WatermarkOp) handleLate(ctx api.StreamContext, d *xsql.Tuple) {
+	defer func() {
+		if span, ok := w.rowHandle[d]; ok {
+			span.End()
+			delete(w.rowHandle, d)
+		}
+	}()
+	if w.latePolicy == def.LateDataUpdate && !d.Timestamp.Before(w.lastWatermarkTs.Add(-w.allowedLateness)) {
+		// Send out directly so that the downstream window can fire the updated result
+		w.Broadcast(d)
+		w.onSend(ctx, d)
+		ctx.GetLogger().Debugf("send out late event at %d with watermark %d", d.Timestamp.UnixMilli(), w.lastWatermarkTs.UnixMilli())
+		return
+	}
+	lateBy := w.lastWatermarkTs.Sub(d.Timestamp).Milliseconds()
+	if w.emitSideOutput(ctx, SideOutputLate, fmt.Errorf("event is %d ms later than the watermark", lateBy), d, map[string]any{
+		"watermark": w.lastWatermarkTs.UnixMilli(),
+		"lateBy":    lateBy,
+	}) {
+		return
+	}
+	ctx.GetLogger().Debugf("drop late event at %d with watermark %d", d.Timestamp.UnixMilli(), w.lastWatermarkTs.UnixMilli())
+}
+
 // Add an event and check if watermark proceeds
 // If yes, send out all events before the watermark
 func (w *WatermarkOp) addAndTrigger(ctx api.StreamContext, d *xsql.Tuple) {
@@ -19,13 +19,16 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
 )
 
 func TestSingleStreamWatermark(t *testing.T) {
@@ -506,3 +509,74 @@ func TestMultiStreamWatermark(t *testing.T) {
 		})
 	}
 }
+
+func TestWatermarkLateDataPolicy(t *testing.T) {
+	tuple := func(ts int64) *xsql.Tuple {
+		return &xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": ts}, Timestamp: time.UnixMilli(ts)}
+	}
+	tests := []struct {
+		name    string
+		policy  string
+		outputs []int64
+		late    []int64
+	}{
+		{
+			name:    "drop",
+			outputs: []int64{10, 20, 30},
+		},
+		{
+			name:    "update",
+			policy:  def.LateDataUpdate,
+			outputs: []int64{10, 20, 15, 30},
+		},
+		{
+			name:    "side output",
+			policy:  def.LateDataSideOutput,
+			outputs: []int64{10, 20, 30},
+			late:    []int64{15, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lateCh := pubsub.CreateSub(LateDataTopicPrefix+"TestLateWatermark", nil, "lateTest", 10)
+			defer pubsub.CloseSourceConsumerChannel(LateDataTopicPrefix+"TestLateWatermark", "lateTest")
+			w := NewWatermarkOp("mock", false, []string{"demo"}, &def.RuleOption{
+				IsEventTime:     true,
+				AllowedLateness: cast.DurationConf(10 * time.Millisecond),
+				LateDataPolicy:  tt.policy,
+			})
+			outputCh := make(chan any, 50)
+			w.outputs["mock"] = outputCh
+			ctx, cancel := mockContext.NewMockContext("TestLateWatermark", "test").WithCancel()
+			defer cancel()
+			w.Exec(ctx, make(chan error))
+			// 15 is late within the allowed lateness and 5 is beyond it
+			for _, ts := range []int64{10, 20, 15, 5, 30} {
+				w.input <- tuple(ts)
+			}
+			result := make([]int64, 0, len(tt.outputs))
+			for range tt.outputs {
+				select {
+				case r := <-outputCh:
+					result = append(result, r.(*xsql.Tuple).Message["ts"].(int64))
+				case <-time.After(5 * time.Second):
+					require.Fail(t, "receive output timeout")
+				}
+			}
+			assert.Equal(t, tt.outputs, result)
+			for _, ts := range tt.late {
+				select {
+				case r := <-lateCh:
+					lt := r.(*xsql.Tuple)
+					assert.Equal(t, ts, lt.Message["ts"])
+					assert.Equal(t, int64(20), lt.Metadata["watermark"])
+					assert.Equal(t, 20-ts, lt.Metadata["lateBy"])
+					assert.Equal(t, SideOutputLate, lt.Metadata["reason"])
+				case <-time.After(5 * time.Second):
+					require.Fail(t, "receive late output timeout")
+				}
+			}
+			assert.Len(t, lateCh, 0)
+		})
+	}
+}
@@ -36,6 +36,8 @@ type HoppingWindowIncAggEventOpState struct {
 func NewHoppingWindowIncAggEventOp(o *WindowIncAggOperator) *HoppingWindowIncAggEventOp {
 	op := &HoppingWindowIncAggEventOp{}
 	op.op = NewHoppingWindowIncAggOp(o)
+	// The event time windows are calculated in the window list of this op, so the retractor is not used
+	op.op.retractor = nil
 	op.HoppingWindowIncAggEventOpState.CurrWindowList = make([]*IncAggWindow, 0)
 	return op
 }
@@ -146,6 +148,7 @@ type SlidingWindowIncAggEventOpState struct {
 func NewSlidingWindowIncAggEventOp(o *WindowIncAggOperator) *SlidingWindowIncAggEventOp {
 	op := &SlidingWindowIncAggEventOp{}
 	op.op = NewSlidingWindowIncAggOp(o)
+	op.op.retractor = nil
 	op.CurrWindowList = make([]*IncAggWindow, 0)
 	op.EmitList = make([]*IncAggWindow, 0)
 	return op
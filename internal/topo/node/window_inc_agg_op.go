@@ -446,11 +446,14 @@ type SlidingWindowIncAggOp struct {
 	Length           time.Duration
 	Delay            time.Duration
 	taskCh           chan *IncAggOpTask
+	// retractor is used instead of the window list if all aggregate functions can retract
+	retractor *incAggRetractor
 	SlidingWindowIncAggOpState
 }
 
 type SlidingWindowIncAggOpState struct {
 	CurrWindowList []*IncAggWindow
+	RetractState   *IncAggRetractState
 }
 
 type IncAggOpTask struct {
@@ -464,12 +467,16 @@ func NewSlidingWindowIncAggOp(o *WindowIncAggOperator) *SlidingWindowIncAggOp {
 		Length:               o.windowConfig.Length,
 		Delay:                o.windowConfig.Delay,
 		taskCh:               make(chan *IncAggOpTask, 1024),
+		retractor:            newIncAggRetractor(o.aggFields),
 	}
 	op.SlidingWindowIncAggOpState.CurrWindowList = make([]*IncAggWindow, 0)
 	return op
 }
 
 func (so *SlidingWindowIncAggOp) PutState(ctx api.StreamContext) {
+	if so.retractor != nil {
+		so.RetractState = so.retractor.state()
+	}
 	for index, window := range so.CurrWindowList {
 		window.GenerateAllFunctionState()
 		so.CurrWindowList[index] = window
@@ -495,7 +502,10 @@ func (so *SlidingWindowIncAggOp) RestoreFromState(ctx api.StreamContext) error {
 		so.CurrWindowList[index] = window
 	}
 	now := timex.GetNow()
-	so.CurrWindowList = gcIncAggWindow(so.CurrWindowList, so.Length, now)
+	if so.retractor != nil && so.RetractState != nil {
+		so.retractor.restore(so.RetractState)
+	}
+	so.gc(now, so.Length)
 	return nil
 }
 
@@ -517,7 +527,7 @@ func (so *SlidingWindowIncAggOp) exec(ctx api.StreamContext, errCh chan<- error)
 			}
 			switch row := data.(type) {
 			case *xsql.Tuple:
-				so.CurrWindowList = gcIncAggWindow(so.CurrWindowList, so.Length+so.Delay, now)
+				so.gc(now, so.Length+so.Delay)
 				so.appendIncAggWindow(ctx, errCh, fv, row, now)
 				if so.isMatchCondition(ctx, fv, row) {
 					if so.Delay > 0 {
@@ -532,24 +542,55 @@ func (so *SlidingWindowIncAggOp) exec(ctx api.StreamContext, errCh chan<- error)
 							}
 						}(t)
 					} else {
-						so.emit(ctx, errCh, so.CurrWindowList[0], now)
+						so.emitCurrent(ctx, errCh, now)
 					}
 				}
 				so.PutState(ctx)
 			}
 		case <-so.taskCh:
 			now := timex.GetNow()
-			so.CurrWindowList = gcIncAggWindow(so.CurrWindowList, so.Length+so.Delay, now)
-			if len(so.CurrWindowList) > 0 {
-				so.emit(ctx, errCh, so.CurrWindowList[0], now)
-			}
+			so.gc(now, so.Length+so.Delay)
+			so.emitCurrent(ctx, errCh, now)
 			so.PutState(ctx)
 		}
 	}
 }
 
+func (so *SlidingWindowIncAggOp) gc(now time.Time, length time.Duration) {
+	if so.retractor != nil {
+		so.retractor.expire(func(ts time.Time) bool {
+			return now.Sub(ts) >= length
+		})
+		return
+	}
+	so.CurrWindowList = gcIncAggWindow(so.CurrWindowList, length, now)
+}
+
+// emitCurrent emits the window which ends at now
+func (so *SlidingWindowIncAggOp) emitCurrent(ctx api.StreamContext, errCh chan<- error, now time.Time) {
+	if so.retractor != nil {
+		if !so.retractor.isEmpty() {
+			results := &xsql.WindowTuples{
+				Content:     so.retractor.results(),
+				WindowRange: xsql.NewWindowRange(so.retractor.startTime().UnixMilli(), now.UnixMilli()),
+			}
+			so.Broadcast(results)
+		}
+		return
+	}
+	if len(so.CurrWindowList) > 0 {
+		so.emit(ctx, errCh, so.CurrWindowList[0], now)
+	}
+}
+
 func (so *SlidingWindowIncAggOp) appendIncAggWindow(ctx api.StreamContext, errCh chan<- error, fv *xsql.FunctionValuer, row *xsql.Tuple, now time.Time) {
 	name := calDimension(fv, so.Dimensions, row)
+	if so.retractor != nil {
+		if err := so.retractor.add(fv, name, row, now); err != nil {
+			so.onError(ctx, err)
+		}
+		return
+	}
 	so.CurrWindowList = append(so.CurrWindowList, newIncAggWindow(ctx, now))
 	for _, incWindow := range so.CurrWindowList {
 		if incWindow.StartTime.Compare(now) <= 0 && incWindow.StartTime.Add(so.Length+so.Delay).After(now) {
@@ -601,11 +642,15 @@ type HoppingWindowIncAggOp struct {
 	Length     time.Duration
 	Interval   time.Duration
 	taskCh     chan *IncAggOpTask
+	// retractor is used to calculate the aggregation if all aggregate functions can retract.
+	// The window list is only used to track the window start time in this case.
+	retractor *incAggRetractor
 	HoppingWindowIncAggOpState
 }
 
 type HoppingWindowIncAggOpState struct {
 	CurrWindowList []*IncAggWindow
+	RetractState   *IncAggRetractState
 }
 
 func NewHoppingWindowIncAggOp(o *WindowIncAggOperator) *HoppingWindowIncAggOp {
@@ -614,12 +659,16 @@ func NewHoppingWindowIncAggOp(o *WindowIncAggOperator) *HoppingWindowIncAggOp {
 		Length:               o.windowConfig.Length,
 		Interval:             o.windowConfig.Interval,
 		taskCh:               make(chan *IncAggOpTask, 1024),
+		retractor:            newIncAggRetractor(o.aggFields),
 	}
 	op.HoppingWindowIncAggOpState.CurrWindowList = make([]*IncAggWindow, 0)
 	return op
 }
 
 func (ho *HoppingWindowIncAggOp) PutState(ctx api.StreamContext) {
+	if ho.retractor != nil {
+		ho.RetractState = ho.retractor.state()
+	}
 	for index, window := range ho.CurrWindowList {
 		window.GenerateAllFunctionState()
 		ho.CurrWindowList[index] = window
@@ -644,6 +693,9 @@ func (ho *HoppingWindowIncAggOp) RestoreFromState(ctx api.StreamContext) error {
 		window.restoreState(ctx)
 		ho.CurrWindowList[index] = window
 	}
+	if ho.retractor != nil && ho.RetractState != nil {
+		ho.retractor.restore(ho.RetractState)
+	}
 	now := time.Now()
 	ho.CurrWindowList = gcIncAggWindow(ho.CurrWindowList, ho.Length, now)
 	for _, window := range ho.CurrWindowList {
@@ -746,6 +798,18 @@ func (ho *HoppingWindowIncAggOp) newIncWindow(ctx api.StreamContext, now time.Ti
 }
 
 func (ho *HoppingWindowIncAggOp) emit(ctx api.StreamContext, errCh chan<- error, window *IncAggWindow, now time.Time) {
+	if ho.retractor != nil {
+		// The windows are emitted in the order of start time, so the rows before the window start will never be used
+		ho.retractor.expire(func(ts time.Time) bool {
+			return ts.Before(window.StartTime)
+		})
+		results := &xsql.WindowTuples{
+			Content:     ho.retractor.results(),
+			WindowRange: xsql.NewWindowRange(window.StartTime.UnixMilli(), now.UnixMilli()),
+		}
+		ho.Broadcast(results)
+		return
+	}
 	results := &xsql.WindowTuples{
 		Content: make([]xsql.Row, 0),
 	}
@@ -761,6 +825,12 @@ func (ho *HoppingWindowIncAggOp) emit(ctx api.StreamContext, errCh chan<- error,
 
 func (ho *HoppingWindowIncAggOp) calIncAggWindow(ctx api.StreamContext, fv *xsql.FunctionValuer, row *xsql.Tuple, now time.Time) {
 	name := calDimension(fv, ho.Dimensions, row)
+	if ho.retractor != nil {
+		if err := ho.retractor.add(fv, name, row, now); err != nil {
+			ho.onError(ctx, err)
+		}
+		return
+	}
 	for _, incWindow := range ho.CurrWindowList {
 		if incWindow.StartTime.Compare(now) <= 0 && incWindow.StartTime.Add(ho.Length).After(now) {
 			incAggCal(ctx, name, row, incWindow, ho.aggFields)
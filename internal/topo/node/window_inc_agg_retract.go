@@ -0,0 +1,345 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// retractableIncAggFuncs are the incremental aggregate functions which can retract the expired values
+var retractableIncAggFuncs = map[string]func() retractAccumulator{
+	"inc_count": func() retractAccumulator { return &countAccumulator{} },
+	"inc_sum":   func() retractAccumulator { return &sumAccumulator{} },
+	"inc_avg":   func() retractAccumulator { return &sumAccumulator{avg: true} },
+	"inc_max":   func() retractAccumulator { return &extremeAccumulator{max: true} },
+	"inc_min":   func() retractAccumulator { return &extremeAccumulator{} },
+}
+
+// incAggRetractor calculates the aggregation of the rows in a sliding range such as sliding window and hopping window.
+// Instead of calculating each row for all the overlapped windows, each row is added into the accumulators
+// once and retracted from them once it is expired. The rows are kept in arrival order, so the retraction is FIFO.
+type incAggRetractor struct {
+	fields []*retractAggField
+	ranges map[string]*retractAggRange
+	// the rows of all dimensions in arrival order
+	entries []*IncAggRetractEntry
+}
+
+type retractAggField struct {
+	name   string
+	fn     string
+	arg    ast.Expr
+	create func() retractAccumulator
+}
+
+type retractAggRange struct {
+	accs    []retractAccumulator
+	count   int
+	lastRow *xsql.Tuple
+}
+
+// IncAggRetractEntry is the evaluated aggregate arguments of a row
+type IncAggRetractEntry struct {
+	Timestamp time.Time
+	Dimension string
+	Values    []any
+}
+
+// IncAggRetractState is the snapshot of the retractor. The accumulators are rebuilt by replaying the entries.
+type IncAggRetractState struct {
+	Entries  []*IncAggRetractEntry
+	LastRows map[string]*xsql.Tuple
+}
+
+// newIncAggRetractor returns nil if any of the aggregate functions cannot retract
+func newIncAggRetractor(aggFields []*ast.Field) *incAggRetractor {
+	fields := make([]*retractAggField, 0, len(aggFields))
+	for _, f := range aggFields {
+		c, ok := f.Expr.(*ast.Call)
+		if !ok || len(c.Args) != 1 {
+			return nil
+		}
+		create, ok := retractableIncAggFuncs[c.Name]
+		if !ok {
+			return nil
+		}
+		name := f.Name
+		if len(f.AName) > 0 {
+			name = f.AName
+		}
+		fields = append(fields, &retractAggField{name: name, fn: c.Name, arg: c.Args[0], create: create})
+	}
+	return &incAggRetractor{
+		fields: fields,
+		ranges: make(map[string]*retractAggRange),
+	}
+}
+
+// add evaluates the aggregate arguments of the row and adds them into the accumulators of the dimension
+func (r *incAggRetractor) add(fv *xsql.FunctionValuer, dimension string, row *xsql.Tuple, ts time.Time) error {
+	cloneRow := cloneTuple(row)
+	ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(cloneRow, fv, &xsql.WildcardValuer{Data: cloneRow})}
+	values := make([]any, len(r.fields))
+	for i, f := range r.fields {
+		v := ve.Eval(f.arg)
+		if e, ok := v.(error); ok {
+			return e
+		}
+		nv, err := normalizeRetractValue(f.fn, v)
+		if err != nil {
+			return err
+		}
+		values[i] = nv
+	}
+	entry := &IncAggRetractEntry{Timestamp: ts, Dimension: dimension, Values: values}
+	r.entries = append(r.entries, entry)
+	r.addEntry(entry).lastRow = cloneRow
+	return nil
+}
+
+func (r *incAggRetractor) addEntry(entry *IncAggRetractEntry) *retractAggRange {
+	rg, ok := r.ranges[entry.Dimension]
+	if !ok {
+		rg = &retractAggRange{accs: make([]retractAccumulator, len(r.fields))}
+		for i, f := range r.fields {
+			rg.accs[i] = f.create()
+		}
+		r.ranges[entry.Dimension] = rg
+	}
+	for i, v := range entry.Values {
+		rg.accs[i].add(v)
+	}
+	rg.count++
+	return rg
+}
+
+// expire retracts the oldest rows until the first row which is not expired
+func (r *incAggRetractor) expire(isExpired func(ts time.Time) bool) {
+	index := 0
+	for ; index < len(r.entries); index++ {
+		entry := r.entries[index]
+		if !isExpired(entry.Timestamp) {
+			break
+		}
+		rg := r.ranges[entry.Dimension]
+		for i, v := range entry.Values {
+			rg.accs[i].retract(v)
+		}
+		rg.count--
+		if rg.count <= 0 {
+			delete(r.ranges, entry.Dimension)
+		}
+	}
+	r.entries = r.entries[index:]
+}
+
+func (r *incAggRetractor) isEmpty() bool {
+	return len(r.entries) == 0
+}
+
+func (r *incAggRetractor) startTime() time.Time {
+	if len(r.entries) == 0 {
+		return time.Time{}
+	}
+	return r.entries[0].Timestamp
+}
+
+// results returns the last row of each dimension with the current aggregate results
+func (r *incAggRetractor) results() []xsql.Row {
+	rows := make([]xsql.Row, 0, len(r.ranges))
+	for _, rg := range r.ranges {
+		row := cloneTuple(rg.lastRow)
+		for i, f := range r.fields {
+			row.Set(f.name, rg.accs[i].result())
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (r *incAggRetractor) state() *IncAggRetractState {
+	s := &IncAggRetractState{
+		Entries:  r.entries,
+		LastRows: make(map[string]*xsql.Tuple, len(r.ranges)),
+	}
+	for k, rg := range r.ranges {
+		s.LastRows[k] = rg.lastRow
+	}
+	return s
+}
+
+func (r *incAggRetractor) restore(s *IncAggRetractState) {
+	r.ranges = make(map[string]*retractAggRange)
+	r.entries = s.Entries
+	for _, entry := range r.entries {
+		r.addEntry(entry)
+	}
+	for k, rg := range r.ranges {
+		rg.lastRow = s.LastRows[k]
+		if rg.lastRow == nil {
+			rg.lastRow = &xsql.Tuple{Message: map[string]any{}}
+		}
+	}
+}
+
+// normalizeRetractValue converts the value to the type the accumulator uses. Thus, the state only saves the needed value.
+func normalizeRetractValue(fn string, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch fn {
+	case "inc_count":
+		return true, nil
+	case "inc_sum", "inc_avg":
+		return cast.ToFloat64(v, cast.CONVERT_ALL)
+	default:
+		switch vt := v.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return cast.ToInt64(vt, cast.CONVERT_SAMEKIND)
+		case float32, float64:
+			return cast.ToFloat64(vt, cast.CONVERT_SAMEKIND)
+		case string:
+			return vt, nil
+		default:
+			return nil, fmt.Errorf("run %s function error: found invalid arg %[2]T(%[2]v)", fn, v)
+		}
+	}
+}
+
+// retractAccumulator is an accumulator which supports to retract the added values in FIFO order
+type retractAccumulator interface {
+	add(v any)
+	retract(v any)
+	result() any
+}
+
+type countAccumulator struct {
+	count int64
+}
+
+func (c *countAccumulator) add(v any) {
+	if v != nil {
+		c.count++
+	}
+}
+
+func (c *countAccumulator) retract(v any) {
+	if v != nil {
+		c.count--
+	}
+}
+
+func (c *countAccumulator) result() any {
+	return c.count
+}
+
+type sumAccumulator struct {
+	avg   bool
+	sum   float64
+	count int64
+}
+
+func (s *sumAccumulator) add(v any) {
+	if f, ok := v.(float64); ok {
+		s.sum += f
+		s.count++
+	}
+}
+
+func (s *sumAccumulator) retract(v any) {
+	if f, ok := v.(float64); ok {
+		s.sum -= f
+		s.count--
+		if s.count == 0 {
+			// clear the accumulated float error
+			s.sum = 0
+		}
+	}
+}
+
+func (s *sumAccumulator) result() any {
+	if s.count == 0 {
+		return nil
+	}
+	if s.avg {
+		return s.sum / float64(s.count)
+	}
+	return s.sum
+}
+
+// extremeAccumulator calculates max or min by a monotonic deque.
+// The deque keeps the candidates in arrival order and their values are in descending order for max.
+type extremeAccumulator struct {
+	max       bool
+	deque     []extremeEntry
+	added     int64
+	retracted int64
+}
+
+type extremeEntry struct {
+	index int64
+	value any
+}
+
+func (e *extremeAccumulator) add(v any) {
+	if v == nil {
+		return
+	}
+	for len(e.deque) > 0 && !e.before(e.deque[len(e.deque)-1].value, v) {
+		e.deque = e.deque[:len(e.deque)-1]
+	}
+	e.deque = append(e.deque, extremeEntry{index: e.added, value: v})
+	e.added++
+}
+
+func (e *extremeAccumulator) retract(v any) {
+	if v == nil {
+		return
+	}
+	e.retracted++
+	for len(e.deque) > 0 && e.deque[0].index < e.retracted {
+		e.deque = e.deque[1:]
+	}
+}
+
+func (e *extremeAccumulator) result() any {
+	if len(e.deque) == 0 {
+		return nil
+	}
+	return e.deque[0].value
+}
+
+// before returns whether the old candidate a still ranks before the new value b
+func (e *extremeAccumulator) before(a, b any) bool {
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			if e.max {
+				return as > bs
+			}
+			return as < bs
+		}
+	}
+	af, _ := cast.ToFloat64(a, cast.CONVERT_SAMEKIND)
+	bf, _ := cast.ToFloat64(b, cast.CONVERT_SAMEKIND)
+	if e.max {
+		return af > bf
+	}
+	return af < bf
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestExtremeAccumulator(t *testing.T) {
+	values := []any{int64(3), int64(1), int64(4), int64(1), nil, int64(5), int64(2)}
+	maxAcc := &extremeAccumulator{max: true}
+	minAcc := &extremeAccumulator{}
+	for _, v := range values {
+		maxAcc.add(v)
+		minAcc.add(v)
+	}
+	require.Equal(t, int64(5), maxAcc.result())
+	require.Equal(t, int64(1), minAcc.result())
+	expMax := []any{int64(5), int64(5), int64(5), int64(5), int64(5), int64(2), nil}
+	expMin := []any{int64(1), int64(1), int64(1), int64(2), int64(2), int64(2), nil}
+	for i, v := range values {
+		maxAcc.retract(v)
+		minAcc.retract(v)
+		require.Equal(t, expMax[i], maxAcc.result(), "max %d", i)
+		require.Equal(t, expMin[i], minAcc.result(), "min %d", i)
+	}
+}
+
+func TestIncAggRetractor(t *testing.T) {
+	a := &ast.FieldRef{Name: "a", StreamName: ast.DefaultStream}
+	fields := []*ast.Field{
+		{Name: "c", Expr: &ast.Call{Name: "inc_count", Args: []ast.Expr{&ast.Wildcard{Token: ast.ASTERISK}}}},
+		{Name: "s", Expr: &ast.Call{Name: "inc_sum", Args: []ast.Expr{a}}},
+		{Name: "v", Expr: &ast.Call{Name: "inc_avg", Args: []ast.Expr{a}}},
+		{Name: "mx", Expr: &ast.Call{Name: "inc_max", Args: []ast.Expr{a}}},
+		{Name: "mn", Expr: &ast.Call{Name: "inc_min", Args: []ast.Expr{a}}},
+	}
+	r := newIncAggRetractor(fields)
+	require.NotNil(t, r)
+	ctx := context.NewMockContext("testRetract", "op")
+	fv, _ := xsql.NewFunctionValuersForOp(ctx)
+	t0 := time.UnixMilli(0)
+	for i, v := range []int64{1, 5, 3, 2} {
+		require.NoError(t, r.add(fv, "dim_", &xsql.Tuple{Message: map[string]any{"a": v}}, t0.Add(time.Duration(i)*time.Second)))
+	}
+	require.Equal(t, []map[string]any{
+		{"a": int64(2), "c": int64(4), "s": float64(11), "v": 2.75, "mx": int64(5), "mn": int64(1)},
+	}, retractResults(r))
+	r.expire(func(ts time.Time) bool {
+		return ts.Before(t0.Add(2 * time.Second))
+	})
+	require.Equal(t, t0.Add(2*time.Second), r.startTime())
+	require.Equal(t, []map[string]any{
+		{"a": int64(2), "c": int64(2), "s": float64(5), "v": 2.5, "mx": int64(3), "mn": int64(2)},
+	}, retractResults(r))
+
+	// restore from state
+	r2 := newIncAggRetractor(fields)
+	r2.restore(r.state())
+	require.Equal(t, retractResults(r), retractResults(r2))
+
+	r.expire(func(ts time.Time) bool { return true })
+	require.True(t, r.isEmpty())
+	require.Len(t, r.results(), 0)
+
+	require.Nil(t, newIncAggRetractor([]*ast.Field{
+		{Name: "c", Expr: &ast.Call{Name: "inc_collect", Args: []ast.Expr{a}}},
+	}))
+}
+
+func retractResults(r *incAggRetractor) []map[string]any {
+	return (&xsql.WindowTuples{Content: r.results()}).ToMaps()
+}
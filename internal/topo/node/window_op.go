@@ -58,8 +58,15 @@ type WindowOperator struct {
 	isEventTime     bool
 	isOverlapWindow bool
 	trigger         *EventTimeTrigger // For event time only
+	allowedLateness time.Duration     // For event time only, the late tuples within it will fire the updated windows
 
 	ticker *clock.Ticker // For processing time only
+	// The interval to fire the partial results of the current window. The final results are not sent if emitFinal is false
+	earlyInterval time.Duration
+	emitFinal     bool
+	// The max inputs kept in memory, the overflowing inputs are spilled to the disk. Only for tumbling and hopping windows
+	spillLimit int
+	spill      *windowSpill
 	// states
 	triggerTime      time.Time
 	msgCount         int
@@ -67,6 +74,8 @@ type WindowOperator struct {
 	triggerTS        []time.Time
 	triggerCondition ast.Expr
 	stateFuncs       []*ast.Call
+	// The tuples of the fired windows which may be updated by the late tuples
+	lateInputs []*xsql.Tuple
 
 	nextLink     trace.Link
 	nextSpanCtx  context.Context
@@ -78,6 +87,7 @@ const (
 	WindowInputsKey = "$$windowInputs"
 	TriggerTimeKey  = "$$triggerTime"
 	MsgCountKey     = "$$msgCount"
+	LateInputsKey   = "$$windowLateInputs"
 )
 
 func init() {
@@ -102,6 +112,25 @@ func NewWindowOp(name string, w WindowConfig, options *def.RuleOption) (*WindowO
 		} else {
 			o.trigger = w
 		}
+		if options.LateDataPolicy == def.LateDataUpdate {
+			o.allowedLateness = time.Duration(options.AllowedLateness)
+		}
+	}
+	o.emitFinal = true
+	if wt := options.WindowTrigger; wt != nil && (wt.Mode == def.WindowEmitEarly || wt.Mode == def.WindowEmitBoth) {
+		switch w.Type {
+		case ast.TUMBLING_WINDOW, ast.HOPPING_WINDOW, ast.SESSION_WINDOW:
+		default:
+			return nil, fmt.Errorf("windowTrigger mode %s only supports tumbling, hopping and session windows", wt.Mode)
+		}
+		o.earlyInterval = time.Duration(wt.EarlyInterval)
+		o.emitFinal = wt.Mode == def.WindowEmitBoth
+	}
+	if options.WindowBufferLimit > 0 {
+		if w.Type != ast.TUMBLING_WINDOW && w.Type != ast.HOPPING_WINDOW {
+			return nil, fmt.Errorf("windowBufferLimit only supports tumbling and hopping windows")
+		}
+		o.spillLimit = options.WindowBufferLimit
 	}
 	if w.TriggerCondition != nil {
 		o.triggerCondition = w.TriggerCondition
@@ -159,6 +188,22 @@ func (o *WindowOperator) Exec(ctx api.StreamContext, errCh chan<- error) {
 			return
 		}
 	}
+	if s, err := ctx.GetState(LateInputsKey); err == nil && s != nil {
+		if si, ok := s.([]*xsql.Tuple); ok {
+			o.lateInputs = si
+		} else {
+			infra.DrainError(ctx, fmt.Errorf("restore window state `lateInputs` %v error, invalid type", s), errCh)
+			return
+		}
+	}
+	if o.spillLimit > 0 {
+		sp, err := newWindowSpill(ctx, o.spillLimit, o.qos >= def.AtLeastOnce)
+		if err != nil {
+			infra.DrainError(ctx, fmt.Errorf("create window spill store error: %v", err), errCh)
+			return
+		}
+		o.spill = sp
+	}
 	log.Infof("Start with window state triggerTime: %d, msgCount: %d", o.triggerTime.UnixMilli(), o.msgCount)
 	o.handleNextWindowTupleSpan(ctx)
 	go func() {
@@ -237,6 +282,7 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 		firstC      <-chan time.Time
 		timeout     <-chan time.Time
 		c           <-chan time.Time
+		earlyC      <-chan time.Time
 	)
 	switch o.window.Type {
 	case ast.NOT_WINDOW:
@@ -317,9 +363,16 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 			}
 		}
 	}
+	if o.earlyInterval > 0 {
+		earlyTicker := timex.GetTicker(o.earlyInterval)
+		defer earlyTicker.Stop()
+		earlyC = earlyTicker.C
+	}
 	delayCh := make(chan time.Time, 100)
 	for {
 		select {
+		case now := <-earlyC:
+			o.fireEarly(ctx, inputs, now)
 		case delayTS := <-delayCh:
 			o.statManager.ProcessTimeStart()
 			inputs = o.scan(inputs, delayTS, ctx)
@@ -338,6 +391,7 @@ func (o *WindowOperator) execProcessingWindow(ctx api.StreamContext, inputs []*x
 				log.Debugf("Event window receive tuple %s", d.Message)
 				o.handleTraceIngestTuple(ctx, d)
 				inputs = append(inputs, d)
+				inputs = o.spillInputs(ctx, inputs)
 				switch o.window.Type {
 				case ast.NOT_WINDOW:
 					inputs = o.scan(inputs, d.Timestamp, ctx)
@@ -633,7 +687,14 @@ func (o *WindowOperator) scan(inputs []*xsql.Tuple, triggerTime time.Time, ctx a
 		windowEnd   = triggerTime
 	)
 	length := o.window.Length + o.window.Delay
+	inputs, held, partial := o.loadWindow(ctx, inputs, triggerTime)
 	inputs, discarded, content := o.handleInputs(ctx, inputs, triggerTime)
+	inputs = o.unloadWindow(ctx, inputs, held, partial)
+	if o.allowedLateness > 0 && len(discarded) > 0 {
+		// Keep the fired tuples to calculate the updated windows when late tuples come
+		o.lateInputs = append(o.lateInputs, discarded...)
+		_ = ctx.PutState(LateInputsKey, o.lateInputs)
+	}
 	results := &xsql.WindowTuples{
 		Content: content,
 	}
@@ -652,15 +713,56 @@ func (o *WindowOperator) scan(inputs []*xsql.Tuple, triggerTime time.Time, ctx a
 	}
 	results.WindowRange = xsql.NewWindowRange(windowStart, windowEnd.UnixMilli())
 	log.Debugf("window %s triggered for %d tuples", o.name, len(inputs))
-	log.Debugf("Sent: %v", results)
-	o.Broadcast(results)
-	o.onSend(ctx, results)
+	if o.emitFinal {
+		log.Debugf("Sent: %v", results)
+		o.Broadcast(results)
+		o.onSend(ctx, results)
+	}
 
 	o.triggerTime = triggerTime
 	log.Debugf("new trigger time %d", o.triggerTime.UnixMilli())
 	return inputs
 }
 
+// fireEarly emits the partial result of the current window up to the fire time. The inputs are not discarded so that
+// they are still in the final result when the window closes.
+func (o *WindowOperator) fireEarly(ctx api.StreamContext, inputs []*xsql.Tuple, end time.Time) {
+	if len(inputs) == 0 && (o.spill == nil || !o.spill.pending()) {
+		return
+	}
+	var start time.Time
+	switch o.window.Type {
+	case ast.SESSION_WINDOW:
+		start = inputs[0].Timestamp
+	case ast.HOPPING_WINDOW:
+		// The current window ends at an interval after the last trigger
+		start = o.triggerTime.Add(o.window.Interval).Add(-o.window.Length)
+	default:
+		start = o.triggerTime
+	}
+	content := make([]xsql.Row, 0, len(inputs))
+	collect := func(tuples []*xsql.Tuple) {
+		for _, t := range tuples {
+			if !t.Timestamp.Before(start) && t.Timestamp.Before(end) {
+				content = append(content, t)
+			}
+		}
+	}
+	// The spilled inputs are read segment by segment and only the ones in the window are kept
+	o.rangeSpilled(ctx, collect)
+	collect(inputs)
+	if len(content) == 0 {
+		return
+	}
+	results := &xsql.WindowTuples{
+		Content:     content,
+		WindowRange: xsql.NewEarlyWindowRange(start.UnixMilli(), end.UnixMilli()),
+	}
+	ctx.GetLogger().Debugf("window %s fire early result [%d, %d) for %d tuples", o.name, start.UnixMilli(), end.UnixMilli(), len(content))
+	o.Broadcast(results)
+	o.onSend(ctx, results)
+}
+
 func (o *WindowOperator) calDelta(triggerTime time.Time, log api.Logger) time.Duration {
 	var delta time.Duration
 	lastTriggerTime := o.triggerTime
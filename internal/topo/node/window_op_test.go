@@ -22,9 +22,14 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/memory"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
 var fivet = []*xsql.Tuple{
@@ -378,3 +383,206 @@ func TestGCInputsForConditionNotMatch(t *testing.T) {
 		},
 	}, inputs)
 }
+
+func TestEventWindowLateUpdate(t *testing.T) {
+	o, err := NewWindowOp("window", WindowConfig{
+		Type:        ast.TUMBLING_WINDOW,
+		Length:      10 * time.Millisecond,
+		RawInterval: 10,
+		TimeUnit:    ast.MS,
+	}, &def.RuleOption{
+		IsEventTime:     true,
+		BufferLength:    10,
+		AllowedLateness: cast.DurationConf(20 * time.Millisecond),
+		LateDataPolicy:  def.LateDataUpdate,
+	})
+	require.NoError(t, err)
+	out := make(chan any, 10)
+	o.outputs["mock"] = out
+	ctx, cancel := mockContext.NewMockContext("TestEventWindowLateUpdate", "window").WithCancel()
+	defer cancel()
+	o.Exec(ctx, make(chan error))
+	tuple := func(ts int64) *xsql.Tuple {
+		return &xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": ts}, Timestamp: time.UnixMilli(ts)}
+	}
+	watermark := func(ts int64) *xsql.WatermarkTuple {
+		return &xsql.WatermarkTuple{Timestamp: time.UnixMilli(ts)}
+	}
+	tests := []struct {
+		name   string
+		inputs []any
+		start  int64
+		end    int64
+		ts     []int64
+	}{
+		{
+			name:   "fire window",
+			inputs: []any{tuple(1), tuple(5), watermark(10)},
+			start:  1,
+			end:    10,
+			ts:     []int64{1, 5},
+		},
+		{
+			name:   "fire next window",
+			inputs: []any{tuple(12), watermark(20)},
+			start:  10,
+			end:    20,
+			ts:     []int64{12},
+		},
+		{
+			name:   "late tuple updates the first window",
+			inputs: []any{tuple(3)},
+			start:  0,
+			end:    10,
+			ts:     []int64{1, 3, 5},
+		},
+		{
+			name:   "late tuple updates the second window",
+			inputs: []any{tuple(15)},
+			start:  10,
+			end:    20,
+			ts:     []int64{12, 15},
+		},
+		{
+			name:   "fire window after late tuples",
+			inputs: []any{tuple(22), watermark(35)},
+			start:  20,
+			end:    30,
+			ts:     []int64{22},
+		},
+	}
+	for _, tt := range tests {
+		for _, in := range tt.inputs {
+			o.input <- in
+		}
+		select {
+		case r := <-out:
+			wt, ok := r.(*xsql.WindowTuples)
+			require.True(t, ok, tt.name)
+			require.Equal(t, xsql.NewWindowRange(tt.start, tt.end), wt.WindowRange, tt.name)
+			ts := make([]int64, 0, len(wt.Content))
+			for _, row := range wt.Content {
+				ts = append(ts, row.(*xsql.Tuple).Message["ts"].(int64))
+			}
+			require.Equal(t, tt.ts, ts, tt.name)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "receive window timeout", tt.name)
+		}
+	}
+	// The tuples before watermark - allowedLateness - length are released
+	require.Equal(t, 4, len(o.lateInputs))
+}
+
+func TestEventWindowEarlyFire(t *testing.T) {
+	o, err := NewWindowOp("window", WindowConfig{
+		Type:        ast.TUMBLING_WINDOW,
+		Length:      10 * time.Millisecond,
+		RawInterval: 10,
+		TimeUnit:    ast.MS,
+	}, &def.RuleOption{
+		IsEventTime:   true,
+		BufferLength:  10,
+		WindowTrigger: &def.WindowTrigger{Mode: def.WindowEmitBoth, EarlyInterval: cast.DurationConf(5 * time.Millisecond)},
+	})
+	require.NoError(t, err)
+	out := make(chan any, 10)
+	o.outputs["mock"] = out
+	ctx, cancel := mockContext.NewMockContext("TestEventWindowEarlyFire", "window").WithCancel()
+	defer cancel()
+	o.Exec(ctx, make(chan error))
+	tuple := func(ts int64) *xsql.Tuple {
+		return &xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": ts}, Timestamp: time.UnixMilli(ts)}
+	}
+	receive := func(tick bool) *xsql.WindowTuples {
+		for i := 0; i < 50; i++ {
+			if tick {
+				timex.Add(5 * time.Millisecond)
+			}
+			select {
+			case r := <-out:
+				wt, ok := r.(*xsql.WindowTuples)
+				require.True(t, ok)
+				return wt
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		require.Fail(t, "receive window timeout")
+		return nil
+	}
+	o.input <- tuple(1)
+	o.input <- tuple(5)
+	o.input <- &xsql.WatermarkTuple{Timestamp: time.UnixMilli(7)}
+	wt := receive(true)
+	require.Equal(t, xsql.NewEarlyWindowRange(1, 7), wt.WindowRange)
+	require.Equal(t, 2, wt.Len())
+	v, _ := wt.WindowRange.FuncValue("window_trigger")
+	require.Equal(t, "early", v)
+
+	o.input <- tuple(12)
+	o.input <- &xsql.WatermarkTuple{Timestamp: time.UnixMilli(10)}
+	wt = receive(false)
+	require.Equal(t, xsql.NewWindowRange(1, 10), wt.WindowRange)
+	require.Equal(t, 2, wt.Len())
+	v, _ = wt.WindowRange.FuncValue("window_trigger")
+	require.Equal(t, "final", v)
+}
+
+func TestWindowTriggerUnsupported(t *testing.T) {
+	_, err := NewWindowOp("window", WindowConfig{
+		Type:   ast.SLIDING_WINDOW,
+		Length: 10 * time.Millisecond,
+	}, &def.RuleOption{
+		BufferLength:  10,
+		WindowTrigger: &def.WindowTrigger{Mode: def.WindowEmitEarly, EarlyInterval: cast.DurationConf(time.Second)},
+	})
+	require.EqualError(t, err, "windowTrigger mode early only supports tumbling, hopping and session windows")
+}
+
+func TestEventWindowUnderMemoryPause(t *testing.T) {
+	// Do not set the tiny budget as the memory limit of the runtime
+	t.Setenv("GOMEMLIMIT", "off")
+	o, err := NewWindowOp("window", WindowConfig{
+		Type:        ast.TUMBLING_WINDOW,
+		Length:      10 * time.Millisecond,
+		RawInterval: 10,
+		TimeUnit:    ast.MS,
+	}, &def.RuleOption{
+		BufferLength: 10,
+		IsEventTime:  true,
+	})
+	require.NoError(t, err)
+	ctx, cancel := mockContext.NewMockContext("TestEventWindowUnderMemoryPause", "window").WithCancel()
+	defer cancel()
+	out := make(chan any, 10)
+	require.NoError(t, o.AddOutput(out, "out"))
+	o.Exec(ctx, make(chan error, 10))
+	// The heap is always above the budget, so the sources are paused after every check
+	memory.NewManager(1, 0.9, 0.5, 50*time.Millisecond).Start(ctx, 10*time.Millisecond)
+	require.Eventually(t, memory.Paused, time.Second, time.Millisecond)
+	go func() {
+		for i := int64(0); i < 20; i++ {
+			// Wait like the source node before sending each event
+			memory.Wait(ctx)
+			ts := time.UnixMilli(i)
+			select {
+			case o.input <- &xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": i}, Timestamp: ts}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case o.input <- &xsql.WatermarkTuple{Timestamp: ts}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	// The window fires because the pause is bounded
+	select {
+	case r := <-out:
+		wt, ok := r.(*xsql.WindowTuples)
+		require.True(t, ok, "%v", r)
+		require.Len(t, wt.Content, 10)
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "the event time window does not fire under the memory pause")
+	}
+}
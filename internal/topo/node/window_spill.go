@@ -0,0 +1,363 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"encoding/gob"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const WindowSpillKey = "$$windowSpill"
+
+func init() {
+	gob.Register(windowSpillState{})
+}
+
+// spillSegment is the metadata of the inputs spilled together
+type spillSegment struct {
+	Id    int
+	Count int
+	Start time.Time
+	End   time.Time
+}
+
+// windowSpillState is saved in the checkpoint so that the spilled segments are restored with the inputs in memory
+type windowSpillState struct {
+	// The segments in arrival order
+	Segments []spillSegment
+	Next     int
+}
+
+type releasedSegment struct {
+	id int
+	// The checkpoint after which the segment is released, 0 if no checkpoint is started yet
+	checkpointId int64
+}
+
+// windowSpill keeps the window inputs in the disk-backed store of the rule when the inputs in memory reach the limit.
+// The inputs are spilled as segments in arrival order and loaded back segment by segment when the window fires.
+type windowSpill struct {
+	limit  int
+	kv     kv.KeyValue
+	prefix string
+	state  windowSpillState
+	// If the rule has checkpoints, the loaded segments are deleted after a checkpoint without them completes
+	deferred bool
+	mu       sync.Mutex
+	released []releasedSegment
+}
+
+func newWindowSpill(ctx api.StreamContext, limit int, deferred bool) (*windowSpill, error) {
+	s, err := store.GetSpillKV(ctx.GetRuleId())
+	if err != nil {
+		return nil, err
+	}
+	sp := &windowSpill{limit: limit, kv: s, prefix: fmt.Sprintf("window/%s/", ctx.GetOpId()), deferred: deferred}
+	if st, err := ctx.GetState(WindowSpillKey); err == nil && st != nil {
+		ws, ok := st.(windowSpillState)
+		if !ok {
+			return nil, fmt.Errorf("restore window state `spill` %v error, invalid type", st)
+		}
+		sp.state = ws
+	}
+	// Remove the segments which are not in the restored checkpoint
+	keys, err := s.Keys()
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[string]struct{}, len(sp.state.Segments))
+	for _, seg := range sp.state.Segments {
+		kept[sp.key(seg.Id)] = struct{}{}
+	}
+	for _, k := range keys {
+		if _, ok := kept[k]; !ok && strings.HasPrefix(k, sp.prefix) {
+			_ = s.Delete(k)
+		}
+	}
+	return sp, nil
+}
+
+func (s *windowSpill) key(id int) string {
+	return s.prefix + strconv.Itoa(id)
+}
+
+func (s *windowSpill) pending() bool {
+	return len(s.state.Segments) > 0
+}
+
+// spill saves the inputs as a segment if they reach the limit and returns the inputs kept in memory
+func (s *windowSpill) spill(ctx api.StreamContext, inputs []*xsql.Tuple) ([]*xsql.Tuple, error) {
+	if len(inputs) < s.limit {
+		return inputs, nil
+	}
+	seg, err := s.write(inputs)
+	if err != nil {
+		return inputs, err
+	}
+	s.state.Segments = append(s.state.Segments, seg)
+	s.save(ctx)
+	return nil, nil
+}
+
+// unshift saves the inputs as a segment before all the spilled segments
+func (s *windowSpill) unshift(ctx api.StreamContext, inputs []*xsql.Tuple) error {
+	seg, err := s.write(inputs)
+	if err != nil {
+		return err
+	}
+	s.state.Segments = append([]spillSegment{seg}, s.state.Segments...)
+	s.save(ctx)
+	return nil
+}
+
+func (s *windowSpill) write(inputs []*xsql.Tuple) (spillSegment, error) {
+	seg := spillSegment{Id: s.state.Next, Count: len(inputs), Start: inputs[0].Timestamp, End: inputs[0].Timestamp}
+	tuples := make([]*xsql.Tuple, len(inputs))
+	for i, t := range inputs {
+		// The context is not encodable and not needed by the window
+		tuples[i] = &xsql.Tuple{Emitter: t.Emitter, Message: t.Message, Timestamp: t.Timestamp, Metadata: t.Metadata, Props: t.Props}
+		if t.Timestamp.Before(seg.Start) {
+			seg.Start = t.Timestamp
+		}
+		if t.Timestamp.After(seg.End) {
+			seg.End = t.Timestamp
+		}
+	}
+	if err := s.kv.Set(s.key(seg.Id), tuples); err != nil {
+		return seg, err
+	}
+	s.state.Next++
+	return seg, nil
+}
+
+func (s *windowSpill) read(seg spillSegment) ([]*xsql.Tuple, error) {
+	var tuples []*xsql.Tuple
+	ok, err := s.kv.Get(s.key(seg.Id), &tuples)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("spilled window segment %d not found", seg.Id)
+	}
+	return tuples, nil
+}
+
+// load reads back the segments in order until the segment starting from the right bound. The segments ending
+// before the left bound are expired and released without reading.
+func (s *windowSpill) load(ctx api.StreamContext, left, right time.Time) ([]*xsql.Tuple, error) {
+	var (
+		result []*xsql.Tuple
+		err    error
+		i      int
+	)
+	for ; i < len(s.state.Segments); i++ {
+		seg := s.state.Segments[i]
+		if !seg.Start.Before(right) {
+			break
+		}
+		if !seg.End.Before(left) {
+			var tuples []*xsql.Tuple
+			tuples, err = s.read(seg)
+			if err != nil {
+				break
+			}
+			result = append(result, tuples...)
+		}
+		s.release(seg.Id)
+	}
+	if i > 0 {
+		s.state.Segments = s.state.Segments[i:]
+		s.save(ctx)
+	}
+	return result, err
+}
+
+// rangeSegments reads the segments one by one without releasing them
+func (s *windowSpill) rangeSegments(f func(tuples []*xsql.Tuple)) error {
+	for _, seg := range s.state.Segments {
+		tuples, err := s.read(seg)
+		if err != nil {
+			return err
+		}
+		f(tuples)
+	}
+	return nil
+}
+
+func (s *windowSpill) earliest() time.Time {
+	r := timex.Maxtime
+	for _, seg := range s.state.Segments {
+		if seg.Start.Before(r) {
+			r = seg.Start
+		}
+	}
+	return r
+}
+
+// save puts a copy of the state because the state is snapshot asynchronously
+func (s *windowSpill) save(ctx api.StreamContext) {
+	segs := make([]spillSegment, len(s.state.Segments))
+	copy(segs, s.state.Segments)
+	_ = ctx.PutState(WindowSpillKey, windowSpillState{Segments: segs, Next: s.state.Next})
+}
+
+// release deletes the segment, or keeps it until the checkpoint without it completes because the previous
+// checkpoint may be restored
+func (s *windowSpill) release(id int) {
+	if !s.deferred {
+		_ = s.kv.Delete(s.key(id))
+		return
+	}
+	s.mu.Lock()
+	s.released = append(s.released, releasedSegment{id: id})
+	s.mu.Unlock()
+}
+
+// preCommit marks the released segments to be deleted when the checkpoint completes. It runs in the window
+// goroutine before the state snapshot.
+func (s *windowSpill) preCommit(checkpointId int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.released {
+		if s.released[i].checkpointId == 0 {
+			s.released[i].checkpointId = checkpointId
+		}
+	}
+}
+
+// commit deletes the segments released before the completed checkpoint
+func (s *windowSpill) commit(checkpointId int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := 0
+	for ; i < len(s.released); i++ {
+		r := s.released[i]
+		if r.checkpointId == 0 || r.checkpointId > checkpointId {
+			break
+		}
+		_ = s.kv.Delete(s.key(r.id))
+	}
+	s.released = s.released[i:]
+}
+
+// PreCommit implements checkpoint.TransactionalTask to keep the loaded segments until the checkpoint completes
+func (o *WindowOperator) PreCommit(checkpointId int64) error {
+	if o.spill != nil {
+		o.spill.preCommit(checkpointId)
+	}
+	return nil
+}
+
+func (o *WindowOperator) Commit(checkpointId int64) {
+	if o.spill != nil {
+		o.spill.commit(checkpointId)
+	}
+}
+
+// spillInputs spills the inputs to the disk if the window buffer is full
+func (o *WindowOperator) spillInputs(ctx api.StreamContext, inputs []*xsql.Tuple) []*xsql.Tuple {
+	if o.spill == nil {
+		return inputs
+	}
+	r, err := o.spill.spill(ctx, inputs)
+	if err != nil {
+		ctx.GetLogger().Errorf("spill window inputs error, keep them in memory: %v", err)
+		return inputs
+	}
+	if len(r) < len(inputs) {
+		ctx.GetLogger().Debugf("window %s spills %d inputs", o.name, len(inputs))
+		o.handleTraceDiscardTuple(ctx, inputs)
+	}
+	return r
+}
+
+// loadInputs loads all the spilled inputs back to memory
+func (o *WindowOperator) loadInputs(ctx api.StreamContext, inputs []*xsql.Tuple) []*xsql.Tuple {
+	if o.spill == nil || !o.spill.pending() {
+		return inputs
+	}
+	r, err := o.spill.load(ctx, time.Time{}, timex.Maxtime)
+	if err != nil {
+		o.onError(ctx, fmt.Errorf("load spilled window inputs error: %v", err))
+	}
+	return append(r, inputs...)
+}
+
+// loadWindow loads the spilled inputs which may be in the window ending at the right bound. If some segments are
+// after the window, the inputs in memory are after them too, so only the loaded inputs are returned and the inputs in
+// memory are returned as held.
+func (o *WindowOperator) loadWindow(ctx api.StreamContext, inputs []*xsql.Tuple, right time.Time) ([]*xsql.Tuple, []*xsql.Tuple, bool) {
+	if o.spill == nil || !o.spill.pending() {
+		return inputs, nil, false
+	}
+	var left time.Time
+	if o.window.Type == ast.HOPPING_WINDOW {
+		left = right.Add(-o.window.Length - o.window.Delay).Add(-o.calDelta(right, ctx.GetLogger()))
+	}
+	r, err := o.spill.load(ctx, left, right)
+	if err != nil {
+		o.onError(ctx, fmt.Errorf("load spilled window inputs error: %v", err))
+	}
+	if o.spill.pending() {
+		return r, inputs, true
+	}
+	return append(r, inputs...), nil, false
+}
+
+// unloadWindow puts back the inputs retained after the window fires. If the window is loaded partially, the retained
+// inputs are spilled before the remaining segments.
+func (o *WindowOperator) unloadWindow(ctx api.StreamContext, retained []*xsql.Tuple, held []*xsql.Tuple, partial bool) []*xsql.Tuple {
+	if !partial {
+		return o.spillInputs(ctx, retained)
+	}
+	if len(retained) == 0 {
+		return held
+	}
+	if err := o.spill.unshift(ctx, retained); err != nil {
+		ctx.GetLogger().Errorf("spill window inputs error, keep them in memory: %v", err)
+		return append(retained, held...)
+	}
+	return held
+}
+
+// rangeSpilled calls the function with the spilled inputs segment by segment
+func (o *WindowOperator) rangeSpilled(ctx api.StreamContext, f func(tuples []*xsql.Tuple)) {
+	if o.spill == nil {
+		return
+	}
+	if err := o.spill.rangeSegments(f); err != nil {
+		o.onError(ctx, fmt.Errorf("load spilled window inputs error: %v", err))
+	}
+}
+
+// withSpilledHead puts the earliest spilled input time before the inputs to calculate the first window end
+func (o *WindowOperator) withSpilledHead(inputs []*xsql.Tuple) []*xsql.Tuple {
+	if o.spill == nil || !o.spill.pending() {
+		return inputs
+	}
+	return append([]*xsql.Tuple{{Timestamp: o.spill.earliest()}}, inputs...)
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestWindowSpill(t *testing.T) {
+	o, err := NewWindowOp("window", WindowConfig{
+		Type:        ast.TUMBLING_WINDOW,
+		Length:      10 * time.Millisecond,
+		RawInterval: 10,
+		TimeUnit:    ast.MS,
+	}, &def.RuleOption{
+		BufferLength:      10,
+		WindowBufferLimit: 2,
+	})
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("TestWindowSpill", "window")
+	o.spill, err = newWindowSpill(ctx, o.spillLimit, false)
+	require.NoError(t, err)
+	inputs := spillTuples(o, ctx, nil, 1, 5)
+	require.Len(t, inputs, 1)
+	require.Len(t, o.spill.state.Segments, 2)
+	inputs = o.loadInputs(ctx, inputs)
+	require.Equal(t, []int64{1, 2, 3, 4, 5}, tupleTs(t, inputs))
+	require.False(t, o.spill.pending())
+	// Nothing to load after loaded
+	require.Equal(t, inputs, o.loadInputs(ctx, inputs))
+	keys, err := o.spill.kv.Keys()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	// Only the segments before the window end are loaded
+	inputs = spillTuples(o, ctx, nil, 1, 7)
+	require.Len(t, o.spill.state.Segments, 3)
+	loaded, held, partial := o.loadWindow(ctx, inputs, time.UnixMilli(4))
+	require.True(t, partial)
+	require.Equal(t, []int64{1, 2, 3, 4}, tupleTs(t, loaded))
+	require.Equal(t, []int64{7}, tupleTs(t, held))
+	inputs = o.unloadWindow(ctx, loaded[3:], held, partial)
+	require.Equal(t, []int64{7}, tupleTs(t, inputs))
+	require.Len(t, o.spill.state.Segments, 2)
+	require.Equal(t, time.UnixMilli(4), o.spill.earliest())
+	require.Equal(t, []int64{4, 5, 6, 7}, tupleTs(t, o.loadInputs(ctx, inputs)))
+
+	_, err = NewWindowOp("window", WindowConfig{
+		Type:   ast.SLIDING_WINDOW,
+		Length: 10 * time.Millisecond,
+	}, &def.RuleOption{
+		BufferLength:      10,
+		WindowBufferLimit: 2,
+	})
+	require.EqualError(t, err, "windowBufferLimit only supports tumbling and hopping windows")
+}
+
+func TestWindowSpillCheckpoint(t *testing.T) {
+	o, err := NewWindowOp("window", WindowConfig{
+		Type:        ast.TUMBLING_WINDOW,
+		Length:      10 * time.Millisecond,
+		RawInterval: 10,
+		TimeUnit:    ast.MS,
+	}, &def.RuleOption{
+		BufferLength:      10,
+		WindowBufferLimit: 2,
+	})
+	require.NoError(t, err)
+	ctx := mockContext.NewMockContext("TestWindowSpillCheckpoint", "window")
+	o.spill, err = newWindowSpill(ctx, o.spillLimit, true)
+	require.NoError(t, err)
+	inputs := spillTuples(o, ctx, nil, 1, 4)
+	require.Empty(t, inputs)
+	// The spilled segments are restored by the state
+	o.spill, err = newWindowSpill(ctx, o.spillLimit, true)
+	require.NoError(t, err)
+	require.Len(t, o.spill.state.Segments, 2)
+
+	// The loaded segments are kept until the checkpoint completes
+	inputs = o.loadInputs(ctx, inputs)
+	require.Len(t, inputs, 4)
+	keys, err := o.spill.kv.Keys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	o.Commit(1)
+	keys, _ = o.spill.kv.Keys()
+	require.Len(t, keys, 2)
+	require.NoError(t, o.PreCommit(2))
+	o.Commit(2)
+	keys, _ = o.spill.kv.Keys()
+	require.Empty(t, keys)
+
+	// The segments not in the restored state are removed
+	spillTuples(o, ctx, nil, 5, 6)
+	o.spill, err = newWindowSpill(mockContext.NewMockContext("TestWindowSpillCheckpoint", "window"), o.spillLimit, true)
+	require.NoError(t, err)
+	require.False(t, o.spill.pending())
+	keys, _ = o.spill.kv.Keys()
+	require.Empty(t, keys)
+
+	// The spilled data is dropped with the rule
+	spillTuples(o, ctx, nil, 5, 6)
+	require.NoError(t, store.DropSpillKV("TestWindowSpillCheckpoint"))
+	s, err := store.GetSpillKV("TestWindowSpillCheckpoint")
+	require.NoError(t, err)
+	keys, _ = s.Keys()
+	require.Empty(t, keys)
+}
+
+func spillTuples(o *WindowOperator, ctx api.StreamContext, inputs []*xsql.Tuple, from, to int64) []*xsql.Tuple {
+	for i := from; i <= to; i++ {
+		inputs = append(inputs, &xsql.Tuple{Emitter: "demo", Message: map[string]any{"ts": i}, Timestamp: time.UnixMilli(i)})
+		inputs = o.spillInputs(ctx, inputs)
+	}
+	return inputs
+}
+
+func tupleTs(t *testing.T, inputs []*xsql.Tuple) []int64 {
+	ts := make([]int64, 0, len(inputs))
+	for _, tuple := range inputs {
+		ts = append(ts, tuple.Message["ts"].(int64))
+		require.Equal(t, tuple.Message["ts"], tuple.Timestamp.UnixMilli())
+	}
+	return ts
+}
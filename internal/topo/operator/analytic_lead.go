@@ -0,0 +1,132 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// leadBuffer calculates the lead functions. Each row waits in the buffer until
+// the n-th following valid row of the same partition arrives for all lead functions.
+type leadBuffer struct {
+	calls       []*ast.Call
+	offsets     []int64
+	ignoreNulls []bool
+	// the partitions of each lead call
+	parts []map[string]*leadPartition
+}
+
+type leadPartition struct {
+	validCount int64
+	// the waiting rows in arrival order, their target is non-decreasing
+	waiting []*leadWaiting
+}
+
+type leadWaiting struct {
+	row    *leadRow
+	target int64
+}
+
+type leadRow struct {
+	row        xsql.Row
+	unresolved int
+}
+
+func newLeadBuffer(calls []*ast.Call) *leadBuffer {
+	b := &leadBuffer{
+		calls:       calls,
+		offsets:     make([]int64, len(calls)),
+		ignoreNulls: make([]bool, len(calls)),
+		parts:       make([]map[string]*leadPartition, len(calls)),
+	}
+	for i, c := range calls {
+		b.offsets[i] = 1
+		if len(c.Args) > 1 {
+			if il, ok := c.Args[1].(*ast.IntegerLiteral); ok {
+				b.offsets[i] = il.Val
+			}
+		}
+		if len(c.Args) > 2 {
+			if bl, ok := c.Args[2].(*ast.BooleanLiteral); ok {
+				b.ignoreNulls[i] = bl.Val
+			}
+		}
+		b.parts[i] = make(map[string]*leadPartition)
+	}
+	return b
+}
+
+// add puts the row into the buffer and returns the rows whose lead values are all resolved by it
+func (b *leadBuffer) add(ve *xsql.ValuerEval, row xsql.Row) ([]xsql.Row, error) {
+	lr := &leadRow{row: row, unresolved: len(b.calls)}
+	var resolved []xsql.Row
+	for i, c := range b.calls {
+		valid := true
+		if c.WhenExpr != nil {
+			if w, ok := ve.Eval(c.WhenExpr).(bool); ok {
+				valid = w
+			}
+		}
+		key := "self"
+		if c.Partition != nil && len(c.Partition.Exprs) > 0 {
+			key = ""
+			for _, pe := range c.Partition.Exprs {
+				temp := ve.Eval(pe)
+				if e, ok := temp.(error); ok {
+					return nil, e
+				}
+				key += fmt.Sprintf("%v", temp)
+			}
+		}
+		v := ve.Eval(c.Args[0])
+		if e, ok := v.(error); ok {
+			return nil, fmt.Errorf("run lead function error: %v", e)
+		}
+		part, ok := b.parts[i][key]
+		if !ok {
+			part = &leadPartition{}
+			b.parts[i][key] = part
+		}
+		if valid && (!b.ignoreNulls[i] || v != nil) {
+			part.validCount++
+			for len(part.waiting) > 0 && part.waiting[0].target <= part.validCount {
+				w := part.waiting[0]
+				part.waiting = part.waiting[1:]
+				w.row.row.Set(c.CachedField, v)
+				w.row.unresolved--
+				if w.row.unresolved == 0 {
+					resolved = append(resolved, w.row.row)
+				}
+			}
+		}
+		part.waiting = append(part.waiting, &leadWaiting{row: lr, target: part.validCount + b.offsets[i]})
+	}
+	return resolved, nil
+}
+
+// flush sets the lead values of all the waiting rows to nil
+func (b *leadBuffer) flush() {
+	for i, c := range b.calls {
+		for _, part := range b.parts[i] {
+			for _, w := range part.waiting {
+				w.row.row.Set(c.CachedField, nil)
+			}
+		}
+		b.parts[i] = make(map[string]*leadPartition)
+	}
+}
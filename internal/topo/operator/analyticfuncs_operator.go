@@ -16,6 +16,7 @@ package operator
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
@@ -26,11 +27,32 @@ import (
 type AnalyticFuncsOp struct {
 	Funcs      []*ast.Call
 	FieldFuncs []*ast.Call
+	// lead functions need the following rows, so they are calculated by the op and the rows are buffered until resolved
+	// The op may be applied by parallel workers, so it is only initialized once.
+	once      sync.Once
+	leadCalls []*ast.Call
+	leads     *leadBuffer
+}
+
+func (p *AnalyticFuncsOp) init() {
+	for _, calls := range [][]*ast.Call{p.Funcs, p.FieldFuncs} {
+		for _, c := range calls {
+			if c.Name == "lead" {
+				p.leadCalls = append(p.leadCalls, c)
+			}
+		}
+	}
+	if len(p.leadCalls) > 0 {
+		p.leads = newLeadBuffer(p.leadCalls)
+	}
 }
 
 func (p *AnalyticFuncsOp) evalTupleFunc(calls []*ast.Call, ve *xsql.ValuerEval, input xsql.Row) (xsql.Row, error) {
 	for _, call := range calls {
 		f := call
+		if f.Name == "lead" {
+			continue
+		}
 		result := ve.Eval(f)
 		if e, ok := result.(error); ok {
 			return nil, e
@@ -45,6 +67,9 @@ func (p *AnalyticFuncsOp) evalCollectionFunc(calls []*ast.Call, fv *xsql.Functio
 		ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(row, &xsql.WindowRangeValuer{WindowRange: input.GetWindowRange()}, fv, &xsql.WildcardValuer{Data: row})}
 		for _, call := range calls {
 			f := call
+			if f.Name == "lead" {
+				continue
+			}
 			result := ve.Eval(f)
 			if e, ok := result.(error); ok {
 				return false, e
@@ -61,6 +86,7 @@ func (p *AnalyticFuncsOp) evalCollectionFunc(calls []*ast.Call, fv *xsql.Functio
 
 func (p *AnalyticFuncsOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) (got interface{}) {
 	ctx.GetLogger().Debugf("AnalyticFuncsOp receive: %v", data)
+	p.once.Do(p.init)
 	var err error
 	switch input := data.(type) {
 	case error:
@@ -75,6 +101,17 @@ func (p *AnalyticFuncsOp) Apply(ctx api.StreamContext, data interface{}, fv *xsq
 		if err != nil {
 			return err
 		}
+		if p.leads != nil {
+			// the row is emitted once the values of all its lead functions are known
+			rows, err := p.leads.add(ve, input)
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				return nil
+			}
+			return rows
+		}
 		data = input
 	case xsql.Collection:
 		input, err = p.evalCollectionFunc(p.FieldFuncs, fv, input)
@@ -85,6 +122,19 @@ func (p *AnalyticFuncsOp) Apply(ctx api.StreamContext, data interface{}, fv *xsq
 		if err != nil {
 			return err
 		}
+		if len(p.leadCalls) > 0 {
+			// the collection is bounded, so the lead functions are calculated inside it
+			lb := newLeadBuffer(p.leadCalls)
+			err = input.RangeSet(func(_ int, row xsql.Row) (bool, error) {
+				ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(row, &xsql.WindowRangeValuer{WindowRange: input.GetWindowRange()}, fv, &xsql.WildcardValuer{Data: row})}
+				_, e := lb.add(ve, row)
+				return e == nil, e
+			})
+			if err != nil {
+				return err
+			}
+			lb.flush()
+		}
 		data = input
 	default:
 		return fmt.Errorf("run analytic funcs op error: invalid input %[1]T(%[1]v)", input)
@@ -190,3 +190,74 @@ func TestAnalyticFuncs(t *testing.T) {
 		}
 	}
 }
+
+func TestLeadFunc(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "TestLeadFunc")
+	tempStore, _ := state.CreateStore("mockRuleLead", def.AtMostOnce)
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("mockRuleLead", "project", tempStore)
+	pp := &AnalyticFuncsOp{FieldFuncs: []*ast.Call{
+		{
+			Name:        "lead",
+			Args:        []ast.Expr{&ast.FieldRef{Name: "temp"}},
+			FuncId:      0,
+			CachedField: "$$a_lead_0",
+			Partition:   &ast.PartitionExpr{Exprs: []ast.Expr{&ast.FieldRef{Name: "id"}}},
+		},
+		{
+			Name:        "lead",
+			Args:        []ast.Expr{&ast.FieldRef{Name: "temp"}, &ast.IntegerLiteral{Val: 2}},
+			FuncId:      1,
+			CachedField: "$$a_lead_1",
+		},
+	}}
+	fv, afv := xsql.NewFunctionValuersForOp(ctx)
+	data := []map[string]interface{}{
+		{"id": 1, "temp": 10},
+		{"id": 2, "temp": 20},
+		{"id": 1, "temp": 11},
+		{"id": 2, "temp": 21},
+	}
+	var r [][]map[string]interface{}
+	for _, d := range data {
+		opResult := pp.Apply(ctx, &xsql.Tuple{Emitter: "test", Message: d}, fv, afv)
+		if opResult == nil {
+			r = append(r, nil)
+			continue
+		}
+		rows := opResult.([]xsql.Row)
+		rr := make([]map[string]interface{}, 0, len(rows))
+		for _, row := range rows {
+			rr = append(rr, row.(*xsql.Tuple).CalCols)
+		}
+		r = append(r, rr)
+	}
+	exp := [][]map[string]interface{}{
+		nil,
+		nil,
+		{{"$$a_lead_0": 11, "$$a_lead_1": 11}},
+		{{"$$a_lead_0": 21, "$$a_lead_1": 21}},
+	}
+	if !reflect.DeepEqual(exp, r) {
+		t.Errorf("result mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", exp, r)
+	}
+	// lead in a collection is bounded by the collection
+	pp = &AnalyticFuncsOp{FieldFuncs: []*ast.Call{
+		{
+			Name:        "lead",
+			Args:        []ast.Expr{&ast.FieldRef{Name: "temp"}},
+			FuncId:      0,
+			CachedField: "$$a_lead_0",
+		},
+	}}
+	input := &xsql.WindowTuples{Content: []xsql.Row{
+		&xsql.Tuple{Emitter: "test", Message: xsql.Message{"temp": 10}},
+		&xsql.Tuple{Emitter: "test", Message: xsql.Message{"temp": 11}},
+	}}
+	opResult := pp.Apply(ctx, input, fv, afv)
+	wr := opResult.(*xsql.WindowTuples)
+	exp2 := []map[string]interface{}{{"$$a_lead_0": 11}, {"$$a_lead_0": nil}}
+	got2 := []map[string]interface{}{wr.Content[0].(*xsql.Tuple).CalCols, wr.Content[1].(*xsql.Tuple).CalCols}
+	if !reflect.DeepEqual(exp2, got2) {
+		t.Errorf("collection result mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", exp2, got2)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// ChangelogOp converts the results to the changelog records. The result of a new key is an insert record and the
+// changed result of an existing key is an update record. The unchanged results are dropped. If DeleteAbsent is set,
+// each collection is regarded as the full snapshot and the delete records are emitted for the absent keys.
+type ChangelogOp struct {
+	Keys         []string
+	OpField      string
+	DeleteAbsent bool
+	// MaxKeys is the max number of keys whose last emitted result is kept
+	MaxKeys int
+	// the last emitted result of each key
+	last *lastResults
+}
+
+func (p *ChangelogOp) Apply(ctx api.StreamContext, data interface{}, _ *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) interface{} {
+	ctx.GetLogger().Debugf("changelog plan receive %v", data)
+	if p.last == nil {
+		p.last = newLastResults("$$changelog/", p.MaxKeys)
+	}
+	p.last.load(ctx)
+	var records []xsql.Row
+	switch input := data.(type) {
+	case error:
+		return input
+	case xsql.Row:
+		if r := p.change(ctx, input.ToMap()); r != nil {
+			return r
+		}
+		return nil
+	case []xsql.Row:
+		records = make([]xsql.Row, 0, len(input))
+		for _, row := range input {
+			if r := p.change(ctx, row.ToMap()); r != nil {
+				records = append(records, r)
+			}
+		}
+	case xsql.Collection:
+		maps := input.ToMaps()
+		records = make([]xsql.Row, 0, len(maps))
+		var present map[string]struct{}
+		if p.DeleteAbsent {
+			present = make(map[string]struct{}, len(maps))
+		}
+		for _, m := range maps {
+			if present != nil {
+				present[resultKey(p.Keys, m)] = struct{}{}
+			}
+			if r := p.change(ctx, m); r != nil {
+				records = append(records, r)
+			}
+		}
+		if present != nil {
+			absent := make([]string, 0)
+			p.last.rangeKeys(func(k string, _ map[string]any) {
+				if _, ok := present[k]; !ok {
+					absent = append(absent, k)
+				}
+			})
+			sort.Strings(absent)
+			for _, k := range absent {
+				prev, _ := p.last.get(k)
+				records = append(records, p.record(prev, ast.RowkindDelete))
+				p.last.remove(ctx, k)
+			}
+		}
+		if len(records) > 0 {
+			return &xsql.WindowTuples{Content: records}
+		}
+		return nil
+	default:
+		return fmt.Errorf("run changelog op error: invalid input %[1]T(%[1]v)", input)
+	}
+	if len(records) > 0 {
+		return records
+	}
+	return nil
+}
+
+// change compares the result with the last emitted result of the same key and returns the changelog record.
+// Return nil if the result is unchanged.
+func (p *ChangelogOp) change(ctx api.StreamContext, m map[string]any) xsql.Row {
+	key := resultKey(p.Keys, m)
+	op := ast.RowkindInsert
+	if prev, ok := p.last.get(key); ok {
+		if reflect.DeepEqual(prev, m) {
+			return nil
+		}
+		op = ast.RowkindUpdate
+	}
+	// Copy the result in case it is modified by the downstream nodes
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	p.last.put(ctx, key, c)
+	return p.record(c, op)
+}
+
+func (p *ChangelogOp) record(m map[string]any, op string) xsql.Row {
+	msg := make(map[string]any, len(m)+1)
+	for k, v := range m {
+		msg[k] = v
+	}
+	msg[p.OpField] = op
+	return &xsql.Tuple{Message: msg}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+)
+
+func TestChangelogOp(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "TestChangelogOp")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("rule1", "op1", &state.MemoryStore{})
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	op := &ChangelogOp{Keys: []string{"id"}, OpField: "op", DeleteAbsent: true}
+	window := func(rows ...xsql.Message) *xsql.WindowTuples {
+		content := make([]xsql.Row, 0, len(rows))
+		for _, r := range rows {
+			content = append(content, &xsql.Tuple{Message: r})
+		}
+		return &xsql.WindowTuples{Content: content}
+	}
+	tests := []struct {
+		name   string
+		data   any
+		result any
+	}{
+		{
+			name:   "insert",
+			data:   window(xsql.Message{"id": "d1", "c": 1}, xsql.Message{"id": "d2", "c": 2}),
+			result: window(xsql.Message{"id": "d1", "c": 1, "op": "insert"}, xsql.Message{"id": "d2", "c": 2, "op": "insert"}),
+		},
+		{
+			name:   "update and delete",
+			data:   window(xsql.Message{"id": "d1", "c": 3}),
+			result: window(xsql.Message{"id": "d1", "c": 3, "op": "update"}, xsql.Message{"id": "d2", "c": 2, "op": "delete"}),
+		},
+		{
+			name:   "unchanged",
+			data:   window(xsql.Message{"id": "d1", "c": 3}),
+			result: nil,
+		},
+		{
+			name:   "insert after delete",
+			data:   &xsql.Tuple{Message: xsql.Message{"id": "d2", "c": 4}},
+			result: &xsql.Tuple{Message: xsql.Message{"id": "d2", "c": 4, "op": "insert"}},
+		},
+		{
+			name:   "rows",
+			data:   []xsql.Row{&xsql.Tuple{Message: xsql.Message{"id": "d2", "c": 4}}, &xsql.Tuple{Message: xsql.Message{"id": "d3", "c": 5}}},
+			result: []xsql.Row{&xsql.Tuple{Message: xsql.Message{"id": "d3", "c": 5, "op": "insert"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := op.Apply(ctx, tt.data, fv, afv)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
+
+func TestChangelogOpState(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "TestChangelogOpState")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("rule1", "op1", &state.MemoryStore{})
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	op := &ChangelogOp{Keys: []string{"id"}, OpField: "op", DeleteAbsent: true, MaxKeys: 2}
+	op.Apply(ctx, &xsql.WindowTuples{Content: []xsql.Row{
+		&xsql.Tuple{Message: xsql.Message{"id": "d1", "c": 1}},
+		&xsql.Tuple{Message: xsql.Message{"id": "d2", "c": 2}},
+		&xsql.Tuple{Message: xsql.Message{"id": "d3", "c": 3}},
+	}}, fv, afv)
+	// d1 is evicted
+	assert.Equal(t, 2, len(op.last.keys))
+	// The last results are restored from the state
+	op = &ChangelogOp{Keys: []string{"id"}, OpField: "op", DeleteAbsent: true, MaxKeys: 2}
+	result := op.Apply(ctx, &xsql.WindowTuples{Content: []xsql.Row{
+		&xsql.Tuple{Message: xsql.Message{"id": "d1", "c": 1}},
+		&xsql.Tuple{Message: xsql.Message{"id": "d3", "c": 4}},
+	}}, fv, afv)
+	assert.Equal(t, &xsql.WindowTuples{Content: []xsql.Row{
+		&xsql.Tuple{Message: xsql.Message{"id": "d1", "c": 1, "op": "insert"}},
+		&xsql.Tuple{Message: xsql.Message{"id": "d3", "c": 4, "op": "update"}},
+	}}, result)
+	assert.Equal(t, 2, len(op.last.keys))
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+)
+
+// EmitOnChangeOp suppresses the results which are the same as the previously emitted results of the same key.
+// The numeric values are regarded as the same if their difference is within the tolerance.
+type EmitOnChangeOp struct {
+	Keys      []string
+	Fields    []string
+	Tolerance float64
+	// MaxKeys is the max number of keys whose last emitted result is kept
+	MaxKeys int
+	// the last emitted result of each key
+	last *lastResults
+}
+
+func (p *EmitOnChangeOp) Apply(ctx api.StreamContext, data interface{}, _ *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) interface{} {
+	ctx.GetLogger().Debugf("emit on change plan receive %v", data)
+	if p.last == nil {
+		p.last = newLastResults("$$emitChange/", p.MaxKeys)
+	}
+	p.last.load(ctx)
+	switch input := data.(type) {
+	case error:
+		return input
+	case xsql.Row:
+		if p.changed(ctx, input.ToMap()) {
+			return input
+		}
+	case []xsql.Row:
+		result := make([]xsql.Row, 0, len(input))
+		for _, r := range input {
+			if p.changed(ctx, r.ToMap()) {
+				result = append(result, r)
+			}
+		}
+		if len(result) > 0 {
+			return result
+		}
+	case xsql.Collection:
+		maps := input.ToMaps()
+		indexes := make([]int, 0, len(maps))
+		for i, m := range maps {
+			if p.changed(ctx, m) {
+				indexes = append(indexes, i)
+			}
+		}
+		switch len(indexes) {
+		case 0:
+		case len(maps):
+			return input
+		default:
+			return input.Filter(indexes)
+		}
+	default:
+		return fmt.Errorf("run emit on change op error: invalid input %[1]T(%[1]v)", input)
+	}
+	ctx.GetLogger().Debugf("emit on change plan suppresses unchanged result")
+	return nil
+}
+
+// changed compares the result with the last emitted result of the same key and records it if changed
+func (p *EmitOnChangeOp) changed(ctx api.StreamContext, m map[string]any) bool {
+	key := resultKey(p.Keys, m)
+	if prev, ok := p.last.get(key); ok && p.equal(prev, m) {
+		return false
+	}
+	// Copy the result in case it is modified by the downstream nodes
+	c := make(map[string]any, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	p.last.put(ctx, key, c)
+	return true
+}
+
+func (p *EmitOnChangeOp) equal(prev map[string]any, m map[string]any) bool {
+	fields := p.Fields
+	if len(fields) == 0 {
+		if len(prev) != len(m) {
+			return false
+		}
+		fields = make([]string, 0, len(m))
+		for k := range m {
+			fields = append(fields, k)
+		}
+	}
+	for _, f := range fields {
+		a, ok1 := prev[f]
+		b, ok2 := m[f]
+		if ok1 != ok2 {
+			return false
+		}
+		fa, ok1 := toFloat(a)
+		fb, ok2 := toFloat(b)
+		if ok1 && ok2 {
+			if math.Abs(fa-fb) > p.Tolerance {
+				return false
+			}
+		} else if !reflect.DeepEqual(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
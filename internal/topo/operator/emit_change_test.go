@@ -0,0 +1,144 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+)
+
+func TestEmitOnChangeOp(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "TestEmitOnChangeOp")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("rule1", "op1", &state.MemoryStore{})
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	op := &EmitOnChangeOp{Keys: []string{"id"}, Tolerance: 0.5}
+	tests := []struct {
+		name   string
+		data   any
+		result any
+	}{
+		{
+			name:   "first result",
+			data:   &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.0}},
+			result: &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.0}},
+		},
+		{
+			name:   "within tolerance",
+			data:   &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.4}},
+			result: nil,
+		},
+		{
+			name:   "other key",
+			data:   &xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 20.4}},
+			result: &xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 20.4}},
+		},
+		{
+			name:   "compare with last emitted",
+			data:   &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.8}},
+			result: &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.8}},
+		},
+		{
+			name:   "new field",
+			data:   &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.8, "status": "ok"}},
+			result: &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.8, "status": "ok"}},
+		},
+		{
+			name:   "non numeric change",
+			data:   &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.8, "status": "bad"}},
+			result: &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20.8, "status": "bad"}},
+		},
+		{
+			name: "collection",
+			data: &xsql.WindowTuples{
+				Content: []xsql.Row{
+					&xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 21, "status": "bad"}},
+					&xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 23}},
+				},
+			},
+			result: &xsql.WindowTuples{
+				Content: []xsql.Row{
+					&xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 23}},
+				},
+			},
+		},
+		{
+			name: "collection unchanged",
+			data: &xsql.WindowTuples{
+				Content: []xsql.Row{
+					&xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 23}},
+				},
+			},
+			result: nil,
+		},
+		{
+			name: "rows",
+			data: []xsql.Row{
+				&xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 23}},
+				&xsql.Tuple{Message: xsql.Message{"id": "d3", "temp": 23}},
+			},
+			result: []xsql.Row{
+				&xsql.Tuple{Message: xsql.Message{"id": "d3", "temp": 23}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := op.Apply(ctx, tt.data, fv, afv)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
+
+func TestEmitOnChangeOpFields(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "TestEmitOnChangeOpFields")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("rule1", "op1", &state.MemoryStore{})
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	op := &EmitOnChangeOp{Fields: []string{"c"}}
+	r := op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"c": int64(3), "ts": 1000}}, fv, afv)
+	assert.NotNil(t, r)
+	// ts is not compared
+	r = op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"c": 3.0, "ts": 2000}}, fv, afv)
+	assert.Nil(t, r)
+	r = op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"c": int64(4), "ts": 3000}}, fv, afv)
+	assert.NotNil(t, r)
+}
+
+func TestEmitOnChangeOpState(t *testing.T) {
+	contextLogger := conf.Log.WithField("rule", "TestEmitOnChangeOpState")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger).WithMeta("rule1", "op1", &state.MemoryStore{})
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	op := &EmitOnChangeOp{Keys: []string{"id"}, MaxKeys: 2}
+	assert.NotNil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20}}, fv, afv))
+	assert.NotNil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 20}}, fv, afv))
+	assert.Nil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20}}, fv, afv))
+	assert.NotNil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d3", "temp": 20}}, fv, afv))
+	// d2 is evicted as the least recently used key
+	assert.Equal(t, 2, len(op.last.keys))
+	assert.NotNil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 20}}, fv, afv))
+	// The last results are restored from the state
+	op = &EmitOnChangeOp{Keys: []string{"id"}, MaxKeys: 2}
+	assert.NotNil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d3", "temp": 21}}, fv, afv))
+	assert.Nil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 20}}, fv, afv))
+	// d2 is used more recently than d3, which is evicted by d1
+	assert.NotNil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d1", "temp": 20}}, fv, afv))
+	assert.Nil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d2", "temp": 20}}, fv, afv))
+	assert.NotNil(t, op.Apply(ctx, &xsql.Tuple{Message: xsql.Message{"id": "d3", "temp": 21}}, fv, afv))
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2022 EMQ Technologies Co., Ltd.
+// Copyright 2021-2024 EMQ Technologies Co., Ltd.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -20,6 +20,9 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/v2/internal/xsql"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
@@ -30,21 +33,42 @@ import (
 type defaultFieldProcessor struct {
 	streamFields    map[string]*ast.JsonStreamField
 	timestampFormat string
+	// schemaPolicy decides how to handle the fields which are missing or of mismatched type
+	schemaPolicy string
 }
 
-func (p *defaultFieldProcessor) validateAndConvert(tuple *xsql.Tuple) error {
-	_, err := p.validateAndConvertMessage(p.streamFields, tuple.Message)
+// The reasons of the schema warnings when the mismatched data is tolerated by the schema policy
+const (
+	schemaWarningMissing  = "missing"
+	schemaWarningMismatch = "mismatch"
+	schemaWarningWiden    = "widen"
+)
+
+func (p *defaultFieldProcessor) validateAndConvert(ctx api.StreamContext, tuple *xsql.Tuple) error {
+	_, err := p.validateAndConvertMessage(ctx, tuple.Emitter, p.streamFields, tuple.Message)
 	return err
 }
 
-func (p *defaultFieldProcessor) validateAndConvertMessage(schema map[string]*ast.JsonStreamField, message xsql.Message) (map[string]interface{}, error) {
+func (p *defaultFieldProcessor) validateAndConvertMessage(ctx api.StreamContext, stream string, schema map[string]*ast.JsonStreamField, message xsql.Message) (map[string]interface{}, error) {
 	for name, sf := range schema {
 		v, ok := message.Value(name, "")
 		if !ok {
-			return nil, fmt.Errorf("field %s is not found", name)
+			if !p.isTolerant() {
+				return nil, fmt.Errorf("field %s is not found", name)
+			}
+			p.warnSchema(ctx, stream, name, schemaWarningMissing)
+			message[name] = nil
+			continue
 		}
-		if nv, err := p.validateAndConvertField(sf, v); err != nil {
-			return nil, fmt.Errorf("field %s type mismatch: %v", name, err)
+		if v == nil && p.isTolerant() {
+			continue
+		}
+		if nv, err := p.validateAndConvertField(ctx, stream, name, sf, v); err != nil {
+			if !p.isTolerant() {
+				return nil, fmt.Errorf("field %s type mismatch: %v", name, err)
+			}
+			p.warnSchema(ctx, stream, name, schemaWarningMismatch)
+			message[name] = nil
 		} else {
 			message[name] = nv
 		}
@@ -52,8 +76,18 @@ func (p *defaultFieldProcessor) validateAndConvertMessage(schema map[string]*ast
 	return message, nil
 }
 
+// isTolerant returns whether the missing fields and the mismatched types are set to null
+func (p *defaultFieldProcessor) isTolerant() bool {
+	return p.schemaPolicy == ast.SchemaPolicyNullable || p.schemaPolicy == ast.SchemaPolicyWiden
+}
+
+func (p *defaultFieldProcessor) warnSchema(ctx api.StreamContext, stream string, field string, reason string) {
+	ctx.GetLogger().Warnf("stream %s field %s does not match the schema: %s", stream, field, reason)
+	metric.IncSchemaWarnings(ctx.GetRuleId(), stream, reason)
+}
+
 // Validate and convert field value to the type defined in schema
-func (p *defaultFieldProcessor) validateAndConvertField(sf *ast.JsonStreamField, t interface{}) (interface{}, error) {
+func (p *defaultFieldProcessor) validateAndConvertField(ctx api.StreamContext, stream string, name string, sf *ast.JsonStreamField, t interface{}) (interface{}, error) {
 	v := reflect.ValueOf(t)
 	jtype := v.Kind()
 	switch sf.Type {
@@ -61,6 +95,16 @@ func (p *defaultFieldProcessor) validateAndConvertField(sf *ast.JsonStreamField,
 		if jtype == reflect.Int64 {
 			return t, nil
 		}
+		switch p.schemaPolicy {
+		case ast.SchemaPolicyFailFast:
+			// Do not truncate the decimals silently
+			return cast.ToInt64(t, cast.STRICT)
+		case ast.SchemaPolicyWiden:
+			if f, ok := t.(float64); ok && f != float64(int64(f)) {
+				p.warnSchema(ctx, stream, name, schemaWarningWiden)
+				return f, nil
+			}
+		}
 		return cast.ToInt64(t, cast.CONVERT_SAMEKIND)
 	case (ast.FLOAT).String():
 		if jtype == reflect.Float64 {
@@ -90,7 +134,10 @@ func (p *defaultFieldProcessor) validateAndConvertField(sf *ast.JsonStreamField,
 				return nil, fmt.Errorf("cannot convert %v to []interface{}", t)
 			}
 			for i, e := range a {
-				ne, err := p.validateAndConvertField(sf.Items, e)
+				if e == nil && p.isTolerant() {
+					continue
+				}
+				ne, err := p.validateAndConvertField(ctx, stream, name, sf.Items, e)
 				if err != nil {
 					return nil, fmt.Errorf("array element type mismatch: %v", err)
 				}
@@ -122,7 +169,7 @@ func (p *defaultFieldProcessor) validateAndConvertField(sf *ast.JsonStreamField,
 		} else {
 			return nil, fmt.Errorf("expect struct but found %[1]T(%[1]v)", t)
 		}
-		return p.validateAndConvertMessage(sf.Properties, nextJ)
+		return p.validateAndConvertMessage(ctx, stream, sf.Properties, nextJ)
 	default:
 		return nil, fmt.Errorf("unsupported type %s", sf.Type)
 	}
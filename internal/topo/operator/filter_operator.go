@@ -39,8 +39,9 @@ func (p *FilterOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.Funct
 	switch input := data.(type) {
 	case error:
 		return input
-	case xsql.Row:
-		ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(input, fv)}
+	case xsql.Row, *xsql.RawTuple:
+		// The raw tuple of the raw passthrough rule is only filtered by the metadata
+		ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(input.(xsql.Valuer), fv)}
 		result := ve.Eval(p.Condition)
 		switch r := result.(type) {
 		case error:
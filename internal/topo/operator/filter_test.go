@@ -597,6 +597,29 @@ func TestFilterPlan_Apply(t *testing.T) {
 				},
 			},
 		},
+		// Raw tuple of the raw passthrough rule
+		{
+			sql: `SELECT * FROM tbl WHERE meta(topic) = "a/b"`,
+			data: &xsql.RawTuple{
+				Emitter:  "tbl",
+				Rawdata:  []byte(`{"abc":6}`),
+				Metadata: xsql.Metadata{"topic": "a/b"},
+			},
+			result: &xsql.RawTuple{
+				Emitter:  "tbl",
+				Rawdata:  []byte(`{"abc":6}`),
+				Metadata: xsql.Metadata{"topic": "a/b"},
+			},
+		},
+		{
+			sql: `SELECT * FROM tbl WHERE startswith(meta(topic), "c/")`,
+			data: &xsql.RawTuple{
+				Emitter:  "tbl",
+				Rawdata:  []byte(`{"abc":6}`),
+				Metadata: xsql.Metadata{"topic": "a/b"},
+			},
+			result: nil,
+		},
 	}
 
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
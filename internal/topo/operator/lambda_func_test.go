@@ -0,0 +1,141 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+)
+
+func TestLambdaFunc_Apply(t *testing.T) {
+	// the project op consumes the message of the tuple, so each case has a new one
+	newData := func() *xsql.Tuple {
+		return &xsql.Tuple{
+			Emitter: "test",
+			Message: xsql.Message{
+				"readings": []interface{}{
+					map[string]interface{}{"id": int64(1), "temp": 45.5},
+					map[string]interface{}{"id": int64(2), "temp": 60.0},
+					map[string]interface{}{"id": int64(3), "temp": 75.5},
+				},
+				"nums":      []interface{}{int64(1), int64(2), int64(3)},
+				"matrix":    []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{int64(3)}},
+				"threshold": 50.0,
+				"tags":      map[string]interface{}{"a": int64(1), "b": int64(5)},
+			},
+		}
+	}
+	tests := []struct {
+		sql    string
+		result []map[string]interface{}
+	}{
+		{
+			sql: "SELECT array_filter(readings, x -> x.temp > 50) AS r FROM test",
+			result: []map[string]interface{}{{
+				"r": []interface{}{
+					map[string]interface{}{"id": int64(2), "temp": 60.0},
+					map[string]interface{}{"id": int64(3), "temp": 75.5},
+				},
+			}},
+		},
+		{
+			sql: "SELECT array_filter(readings, x -> x.temp > threshold) AS r FROM test",
+			result: []map[string]interface{}{{
+				"r": []interface{}{
+					map[string]interface{}{"id": int64(2), "temp": 60.0},
+					map[string]interface{}{"id": int64(3), "temp": 75.5},
+				},
+			}},
+		},
+		{
+			sql: "SELECT array_map(readings, x -> x.id) AS r, array_map(nums, n -> n * 2) AS d FROM test",
+			result: []map[string]interface{}{{
+				"r": []interface{}{int64(1), int64(2), int64(3)},
+				"d": []interface{}{int64(2), int64(4), int64(6)},
+			}},
+		},
+		{
+			sql: "SELECT array_reduce(nums, 0, (acc, x) -> acc + x) AS r FROM test",
+			result: []map[string]interface{}{{
+				"r": int64(6),
+			}},
+		},
+		{
+			sql: "SELECT array_any_match(readings, x -> x.temp > 70) AS r, array_all_match(readings, x -> x.temp > 70) AS a FROM test",
+			result: []map[string]interface{}{{
+				"r": true,
+				"a": false,
+			}},
+		},
+		{
+			sql: "SELECT array_map(matrix, item -> array_reduce(item, 0, (acc, x) -> acc + item[0] * x)) AS r FROM test",
+			result: []map[string]interface{}{{
+				"r": []interface{}{int64(3), int64(9)},
+			}},
+		},
+		{
+			sql: "SELECT map_filter(tags, (k, v) -> v > 2) AS r, map_transform_values(tags, (k, v) -> concat(k, cast(v, \"string\"))) AS t FROM test",
+			result: []map[string]interface{}{{
+				"r": map[string]interface{}{"b": int64(5)},
+				"t": map[string]interface{}{"a": "a1", "b": "b5"},
+			}},
+		},
+		{
+			sql:    "SELECT array_filter(nosuch, x -> x > 1) AS r FROM test",
+			result: []map[string]interface{}{{}},
+		},
+	}
+	contextLogger := conf.Log.WithField("rule", "TestLambdaFunc_Apply")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+			require.NoError(t, err)
+			pp := &ProjectOp{}
+			parseStmt(pp, stmt.Fields)
+			fv, afv := xsql.NewFunctionValuersForOp(nil)
+			opResult := pp.Apply(ctx, newData(), fv, afv)
+			result, err := parseResult(opResult, pp.IsAggregate)
+			require.NoError(t, err)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
+
+func TestLambdaFunc_Error(t *testing.T) {
+	data := &xsql.Tuple{
+		Emitter: "test",
+		Message: xsql.Message{
+			"nums": []interface{}{int64(1), int64(2)},
+		},
+	}
+	stmt, err := xsql.NewParser(strings.NewReader("SELECT array_filter(nums, x -> x + 1) AS r FROM test")).Parse()
+	require.NoError(t, err)
+	pp := &ProjectOp{}
+	parseStmt(pp, stmt.Fields)
+	fv, afv := xsql.NewFunctionValuersForOp(nil)
+	contextLogger := conf.Log.WithField("rule", "TestLambdaFunc_Error")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	opResult := pp.Apply(ctx, data, fv, afv)
+	assert.Equal(t, errors.New("run Select error: alias: r expr: Call:{ name:array_filter, args:[$$default.nums, lambda:{ params:[x], body:{ binaryExpr:{ lambdaParam:x + 1 } } }] } meet error, err:call func array_filter error: the lambda expression should return a bool but got int64(2)"), opResult)
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"container/list"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+)
+
+// defaultMaxKeys is the default max number of keys whose last result is kept
+const defaultMaxKeys = 10000
+
+func init() {
+	gob.Register(lastResult{})
+}
+
+// lastResult is the last emitted result of a key saved in the state slot of the key
+type lastResult struct {
+	Key    string
+	Result map[string]any
+	// Seq is the order of the updates to restore the recency of the keys
+	Seq int64
+}
+
+type lastEntry struct {
+	slot int
+	lastResult
+}
+
+// lastResults keeps the last emitted result of each key in the operator state so that they are restored from the
+// checkpoint. Each key takes a state slot, so only the changed key is saved for each update. When the keys reach the
+// max, the least recently used key is evicted and its slot is reused.
+type lastResults struct {
+	prefix  string
+	maxKeys int
+	loaded  bool
+	seq     int64
+	// The front is the most recently used
+	order *list.List
+	keys  map[string]*list.Element
+	free  []int
+	next  int
+}
+
+func newLastResults(prefix string, maxKeys int) *lastResults {
+	if maxKeys <= 0 {
+		maxKeys = defaultMaxKeys
+	}
+	return &lastResults{prefix: prefix, maxKeys: maxKeys, order: list.New(), keys: make(map[string]*list.Element)}
+}
+
+// resultKey returns the key of the result by the values of the key fields
+func resultKey(keys []string, m map[string]any) string {
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%v,", m[k])
+	}
+	return b.String()
+}
+
+func (l *lastResults) slotKey(slot int) string {
+	return l.prefix + strconv.Itoa(slot)
+}
+
+// load restores the last results from the state once
+func (l *lastResults) load(ctx api.StreamContext) {
+	if l.loaded {
+		return
+	}
+	l.loaded = true
+	entries := make([]*lastEntry, 0)
+	for i := 0; i < l.maxKeys; i++ {
+		s, err := ctx.GetState(l.slotKey(i))
+		if err != nil || s == nil {
+			continue
+		}
+		r, ok := s.(lastResult)
+		if !ok {
+			ctx.GetLogger().Warnf("restore state %s %v error, invalid type", l.slotKey(i), s)
+			continue
+		}
+		entries = append(entries, &lastEntry{slot: i, lastResult: r})
+		l.next = i + 1
+	}
+	used := make(map[int]struct{}, len(entries))
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	for _, e := range entries {
+		l.keys[e.Key] = l.order.PushFront(e)
+		used[e.slot] = struct{}{}
+		l.seq = e.Seq
+	}
+	for i := 0; i < l.next; i++ {
+		if _, ok := used[i]; !ok {
+			l.free = append(l.free, i)
+		}
+	}
+}
+
+// get returns the last result of the key and marks the key as recently used
+func (l *lastResults) get(key string) (map[string]any, bool) {
+	e, ok := l.keys[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(e)
+	return e.Value.(*lastEntry).Result, true
+}
+
+// put saves the result of the key. The result must not be modified afterward because it is in the state.
+func (l *lastResults) put(ctx api.StreamContext, key string, result map[string]any) {
+	l.seq++
+	var entry *lastEntry
+	if e, ok := l.keys[key]; ok {
+		entry = e.Value.(*lastEntry)
+		l.order.MoveToFront(e)
+	} else {
+		entry = &lastEntry{slot: l.allocate(ctx)}
+		l.keys[key] = l.order.PushFront(entry)
+	}
+	entry.lastResult = lastResult{Key: key, Result: result, Seq: l.seq}
+	_ = ctx.PutState(l.slotKey(entry.slot), entry.lastResult)
+}
+
+// allocate returns a free slot, or the slot of the evicted key if the keys reach the max
+func (l *lastResults) allocate(ctx api.StreamContext) int {
+	if len(l.keys) >= l.maxKeys {
+		e := l.order.Back()
+		evicted := e.Value.(*lastEntry)
+		l.order.Remove(e)
+		delete(l.keys, evicted.Key)
+		ctx.GetLogger().Debugf("the last results reach the max keys %d, evict key %s", l.maxKeys, evicted.Key)
+		return evicted.slot
+	}
+	if n := len(l.free); n > 0 {
+		slot := l.free[n-1]
+		l.free = l.free[:n-1]
+		return slot
+	}
+	l.next++
+	return l.next - 1
+}
+
+func (l *lastResults) remove(ctx api.StreamContext, key string) {
+	e, ok := l.keys[key]
+	if !ok {
+		return
+	}
+	entry := e.Value.(*lastEntry)
+	l.order.Remove(e)
+	delete(l.keys, key)
+	l.free = append(l.free, entry.slot)
+	_ = ctx.DeleteState(l.slotKey(entry.slot))
+}
+
+// rangeKeys calls the function with each key and its last result
+func (l *lastResults) rangeKeys(f func(key string, result map[string]any)) {
+	for k, e := range l.keys {
+		f(k, e.Value.(*lastEntry).Result)
+	}
+}
@@ -16,6 +16,8 @@ package operator
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
@@ -28,7 +30,7 @@ import (
 
 // Preprocessor only planned when
 // 1. eventTime, to convert the timestamp field
-// 2. schema validate and convert, when strict_validation is on and field type is not binary
+// 2. schema validate and convert, when strict_validation or schema_policy is on and field type is not binary
 // Do not convert types
 type Preprocessor struct {
 	// Pruned stream fields. Could be streamField(with data type info) or string
@@ -39,17 +41,33 @@ type Preprocessor struct {
 	timestampField string
 	checkSchema    bool
 	isBinary       bool
+	// the path of the nested timestamp field like header.ts
+	timestampPath []string
+	// the location to parse the timestamp string without zone info
+	timestampLoc    *time.Location
+	timestampPolicy string
 }
 
-func NewPreprocessor(isSchemaless bool, fields map[string]*ast.JsonStreamField, _ bool, _ []string, iet bool, timestampField string, timestampFormat string, isBinary bool, strictValidation bool) (*Preprocessor, error) {
+func NewPreprocessor(isSchemaless bool, fields map[string]*ast.JsonStreamField, _ bool, _ []string, iet bool, timestampField string, timestampFormat string, isBinary bool, strictValidation bool, schemaPolicy string, timestampTz string, timestampPolicy string) (*Preprocessor, error) {
 	p := &Preprocessor{
-		isEventTime: iet, timestampField: timestampField, isBinary: isBinary,
+		isEventTime: iet, timestampField: timestampField, isBinary: isBinary, timestampPolicy: timestampPolicy,
+	}
+	if strings.Contains(timestampField, ".") {
+		p.timestampPath = strings.Split(timestampField, ".")
+	}
+	if timestampTz != "" {
+		loc, err := time.LoadLocation(timestampTz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp timezone %s: %v", timestampTz, err)
+		}
+		p.timestampLoc = loc
 	}
 	p.defaultFieldProcessor = defaultFieldProcessor{
 		timestampFormat: timestampFormat,
+		schemaPolicy:    schemaPolicy,
 	}
-	conf.Log.Infof("preprocessor isSchemaless %v, strictValidation %v, isBinary %v", isSchemaless, strictValidation, strictValidation)
-	if !isSchemaless && (strictValidation || isBinary) {
+	conf.Log.Infof("preprocessor isSchemaless %v, strictValidation %v, isBinary %v, schemaPolicy %s", isSchemaless, strictValidation, strictValidation, schemaPolicy)
+	if !isSchemaless && (strictValidation || isBinary || schemaPolicy != "") {
 		p.checkSchema = true
 		conf.Log.Infof("preprocessor check schema")
 		p.defaultFieldProcessor.streamFields = fields
@@ -71,7 +89,7 @@ func (p *Preprocessor) Apply(ctx api.StreamContext, data interface{}, _ *xsql.Fu
 	log.Debugf("preprocessor receive %s", tuple.Message)
 	if p.checkSchema {
 		if !p.isBinary {
-			err := p.validateAndConvert(tuple)
+			err := p.validateAndConvert(ctx, tuple)
 			if err != nil {
 				return fmt.Errorf("error in preprocessor: %s", err)
 			}
@@ -84,15 +102,20 @@ func (p *Preprocessor) Apply(ctx api.StreamContext, data interface{}, _ *xsql.Fu
 		}
 	}
 	if p.isEventTime {
-		if t, ok := tuple.Message[p.timestampField]; ok {
-			if ts, err := cast.InterfaceToTime(t, p.timestampFormat); err != nil {
-				return fmt.Errorf("cannot convert timestamp field %s to timestamp with error %v", p.timestampField, err)
-			} else {
-				tuple.Timestamp = ts
-				log.Debugf("preprocessor calculate timestamp %d", tuple.Timestamp.UnixMilli())
+		ts, err := p.eventTime(tuple)
+		if err != nil {
+			switch p.timestampPolicy {
+			case ast.TimestampPolicyProcessing:
+				log.Debugf("%v, use the processing time %d", err, tuple.Timestamp.UnixMilli())
+			case ast.TimestampPolicyDrop:
+				log.Debugf("%v, drop the event", err)
+				return nil
+			default:
+				return err
 			}
 		} else {
-			return fmt.Errorf("cannot find timestamp field %s in tuple %v", p.timestampField, tuple.Message)
+			tuple.Timestamp = ts
+			log.Debugf("preprocessor calculate timestamp %d", tuple.Timestamp.UnixMilli())
 		}
 	}
 	// No need to reconstruct meta as the memory has been allocated earlier
@@ -107,3 +130,31 @@ func (p *Preprocessor) Apply(ctx api.StreamContext, data interface{}, _ *xsql.Fu
 	//}
 	return tuple
 }
+
+// eventTime extracts the event time from the timestamp field which can be a nested field like header.ts
+func (p *Preprocessor) eventTime(tuple *xsql.Tuple) (time.Time, error) {
+	t, ok := tuple.Message[p.timestampField]
+	if !ok && len(p.timestampPath) > 0 {
+		var v any = map[string]any(tuple.Message)
+		for _, k := range p.timestampPath {
+			m, isMap := v.(map[string]any)
+			if !isMap {
+				ok = false
+				break
+			}
+			v, ok = m[k]
+			if !ok {
+				break
+			}
+		}
+		t = v
+	}
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot find timestamp field %s in tuple %v", p.timestampField, tuple.Message)
+	}
+	ts, err := cast.InterfaceToTimeInLocation(t, p.timestampFormat, p.timestampLoc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot convert timestamp field %s to timestamp with error %v", p.timestampField, err)
+	}
+	return ts, nil
+}
@@ -695,7 +695,7 @@ func TestPreprocessorTime_Apply(t *testing.T) {
 		if tt.stmt.Options != nil {
 			timestampFormat = tt.stmt.Options.TIMESTAMP_FORMAT
 		}
-		pp, e := NewPreprocessor(false, tt.stmt.StreamFields.ToJsonSchema(), false, nil, false, "", timestampFormat, false, true)
+		pp, e := NewPreprocessor(false, tt.stmt.StreamFields.ToJsonSchema(), false, nil, false, "", timestampFormat, false, true, "", "", "")
 		assert.NoError(t, e)
 		dm := make(map[string]interface{})
 		if e := json.Unmarshal(tt.data, &dm); e != nil {
@@ -904,6 +904,70 @@ func TestPreprocessorEventtime_Apply(t *testing.T) {
 	}
 }
 
+func TestPreprocessorNestedEventtime(t *testing.T) {
+	err := cast.SetTimeZone("UTC")
+	require.NoError(t, err)
+	tests := []struct {
+		name   string
+		tz     string
+		policy string
+		format string
+		data   map[string]any
+		result any
+	}{
+		{
+			name:   "nested int",
+			data:   map[string]any{"header": map[string]any{"ts": int64(1568854515000)}},
+			result: int64(1568854515000),
+		},
+		{
+			name:   "nested string with timezone",
+			tz:     "Asia/Shanghai",
+			format: "yyyy-MM-dd HH:mm:ss",
+			data:   map[string]any{"header": map[string]any{"ts": "2019-09-19 08:55:15"}},
+			result: int64(1568854515000),
+		},
+		{
+			name:   "missing with processing policy",
+			policy: ast.TimestampPolicyProcessing,
+			data:   map[string]any{"header": map[string]any{}},
+			result: int64(100),
+		},
+		{
+			name:   "malformed with drop policy",
+			policy: ast.TimestampPolicyDrop,
+			data:   map[string]any{"header": map[string]any{"ts": true}},
+			result: nil,
+		},
+		{
+			name:   "missing with default policy",
+			data:   map[string]any{"header": "abc"},
+			result: errors.New("cannot find timestamp field header.ts in tuple map[header:abc]"),
+		},
+	}
+	contextLogger := conf.Log.WithField("rule", "TestPreprocessorNestedEventtime")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp, err := NewPreprocessor(true, nil, false, nil, true, "header.ts", tt.format, false, false, "", tt.tz, tt.policy)
+			require.NoError(t, err)
+			tuple := &xsql.Tuple{Message: tt.data, Timestamp: time.UnixMilli(100)}
+			fv, afv := xsql.NewFunctionValuersForOp(nil)
+			result := pp.Apply(ctx, tuple, fv, afv)
+			switch r := tt.result.(type) {
+			case int64:
+				rt, ok := result.(*xsql.Tuple)
+				require.True(t, ok)
+				assert.Equal(t, r, rt.Timestamp.UnixMilli())
+			default:
+				assert.Equal(t, tt.result, result)
+			}
+		})
+	}
+	_, err = NewPreprocessor(true, nil, false, nil, true, "ts", "", false, false, "", "Mars/Base", "")
+	assert.EqualError(t, err, "invalid timestamp timezone Mars/Base: unknown time zone Mars/Base")
+}
+
 func TestPreprocessorError(t *testing.T) {
 	tests := []struct {
 		stmt   *ast.StreamStmt
@@ -977,6 +1041,89 @@ func TestPreprocessorError(t *testing.T) {
 	}
 }
 
+func TestPreprocessorSchemaPolicy(t *testing.T) {
+	fields := ast.StreamFields{
+		{Name: "a", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+		{Name: "b", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+		{Name: "c", FieldType: &ast.RecType{
+			StreamFields: []ast.StreamField{
+				{Name: "d", FieldType: &ast.BasicType{Type: ast.FLOAT}},
+			},
+		}},
+		{Name: "e", FieldType: &ast.ArrayType{
+			Type: ast.BIGINT,
+		}},
+	}
+	tests := []struct {
+		name   string
+		policy string
+		data   string
+		result any
+	}{
+		{
+			name:   "failfast exact",
+			policy: ast.SchemaPolicyFailFast,
+			data:   `{"a": 2, "b": "hello", "c": {"d": 1.5}, "e": [1, 2]}`,
+			result: xsql.Message{"a": int64(2), "b": "hello", "c": map[string]any{"d": 1.5}, "e": []any{int64(1), int64(2)}},
+		},
+		{
+			name:   "failfast lossy",
+			policy: ast.SchemaPolicyFailFast,
+			data:   `{"a": 2.5, "b": "hello", "c": {"d": 1.5}, "e": [1, 2]}`,
+			result: errors.New("error in preprocessor: field a type mismatch: cannot convert float64(2.5) to int64"),
+		},
+		{
+			name:   "failfast missing",
+			policy: ast.SchemaPolicyFailFast,
+			data:   `{"a": 2, "b": "hello", "c": {"d": 1.5}}`,
+			result: errors.New("error in preprocessor: field e is not found"),
+		},
+		{
+			name:   "nullable",
+			policy: ast.SchemaPolicyNullable,
+			data:   `{"a": 2.5, "b": {"x": 1}, "c": {}, "e": [1, null, "x"]}`,
+			result: xsql.Message{"a": int64(2), "b": nil, "c": map[string]any{"d": nil}, "e": nil},
+		},
+		{
+			name:   "nullable null values",
+			policy: ast.SchemaPolicyNullable,
+			data:   `{"a": null, "c": null, "e": [1, null]}`,
+			result: xsql.Message{"a": nil, "b": nil, "c": nil, "e": []any{int64(1), nil}},
+		},
+		{
+			name:   "widen",
+			policy: ast.SchemaPolicyWiden,
+			data:   `{"a": 2.5, "b": "hello", "e": [1, 2.5]}`,
+			result: xsql.Message{"a": 2.5, "b": "hello", "c": nil, "e": []any{int64(1), 2.5}},
+		},
+		{
+			name:   "widen integral",
+			policy: ast.SchemaPolicyWiden,
+			data:   `{"a": 2, "b": 3, "c": {"d": 3}, "e": []}`,
+			result: xsql.Message{"a": int64(2), "b": nil, "c": map[string]any{"d": float64(3)}, "e": []any{}},
+		},
+	}
+	defer conf.CloseLogger()
+	contextLogger := conf.Log.WithField("rule", "TestPreprocessorSchemaPolicy")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp, err := NewPreprocessor(false, fields.ToJsonSchema(), false, nil, false, "", "", false, false, tt.policy, "", "")
+			require.NoError(t, err)
+			dm := make(map[string]any)
+			require.NoError(t, json.Unmarshal([]byte(tt.data), &dm))
+			fv, afv := xsql.NewFunctionValuersForOp(nil)
+			result := pp.Apply(ctx, &xsql.Tuple{Emitter: "demo", Message: dm}, fv, afv)
+			switch r := result.(type) {
+			case *xsql.Tuple:
+				assert.Equal(t, tt.result, r.Message)
+			default:
+				assert.Equal(t, tt.result, r)
+			}
+		})
+	}
+}
+
 func TestPreprocessorForBinary(t *testing.T) {
 	image, b64img := mocknode.GetImg()
 	tests := []struct {
@@ -2399,6 +2399,32 @@ func TestProjectPlan_AggFuncs(t *testing.T) {
 				"all": 3,
 			}},
 		},
+		{
+			sql: "SELECT count(*) FILTER (WHERE a > 30) as high, count(*) FILTER (WHERE a <= 30) as low, sum(a) FILTER (WHERE b = \"x\") as s, count(*) as all FROM test GROUP BY TumblingWindow(ss, 10)",
+			data: &xsql.WindowTuples{
+				Content: []xsql.Row{
+					&xsql.Tuple{
+						Emitter: "src1",
+						Message: xsql.Message{"a": 53, "b": "x"},
+					}, &xsql.Tuple{
+						Emitter: "src1",
+						Message: xsql.Message{"a": 27, "b": "x"},
+					}, &xsql.Tuple{
+						Emitter: "src1",
+						Message: xsql.Message{"a": 40, "b": "y"},
+					}, &xsql.Tuple{
+						Emitter: "src1",
+						Message: xsql.Message{"b": "y"},
+					},
+				},
+			},
+			result: []map[string]interface{}{{
+				"high": 2,
+				"low":  1,
+				"s":    int64(80),
+				"all":  4,
+			}},
+		},
 	}
 	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
 	contextLogger := conf.Log.WithField("rule", "TestProjectPlan_AggFuncs")
@@ -0,0 +1,70 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+// StatementOutputOp converts the results of an intermediate rule statement to the tuples of the statement name,
+// so that the later statements can read them like a stream
+type StatementOutputOp struct {
+	Emitter string
+}
+
+func (p *StatementOutputOp) Apply(ctx api.StreamContext, data any, _ *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) any {
+	ctx.GetLogger().Debugf("statement output op receive %v", data)
+	switch input := data.(type) {
+	case xsql.Row:
+		ts := timex.GetNow()
+		if e, ok := input.(xsql.Event); ok {
+			ts = e.GetTimestamp()
+		}
+		return p.newTuple(input.ToMap(), ts)
+	case []xsql.Row:
+		result := make([]xsql.Row, 0, len(input))
+		for _, r := range input {
+			if t, ok := p.Apply(ctx, r, nil, nil).(xsql.Row); ok {
+				result = append(result, t)
+			}
+		}
+		return result
+	case xsql.Collection:
+		ts := timex.GetNow()
+		if wr := input.GetWindowRange(); wr != nil {
+			if end, ok := wr.FuncValue("window_end"); ok {
+				ts = time.UnixMilli(end.(int64))
+			}
+		}
+		maps := input.ToMaps()
+		result := make([]xsql.Row, len(maps))
+		for i, m := range maps {
+			result[i] = p.newTuple(m, ts)
+		}
+		return result
+	default:
+		return fmt.Errorf("run statement output op error: invalid input %[1]T(%[1]v)", input)
+	}
+}
+
+func (p *StatementOutputOp) newTuple(m map[string]any, ts time.Time) *xsql.Tuple {
+	return &xsql.Tuple{Emitter: p.Emitter, Message: m, Timestamp: ts}
+}
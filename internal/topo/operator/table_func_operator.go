@@ -0,0 +1,86 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// TableFuncOp evaluates the table functions for each row and generates a new row for each element of the function result.
+// The element is set to the column named by the alias of the table function. For example, FROM demo, generate_series(1, 2) AS s
+// transforms {"a":1} => {"a":1,"s":1},{"a":1,"s":2}
+type TableFuncOp struct {
+	Funcs []*ast.TableFunc
+}
+
+func (p *TableFuncOp) Apply(ctx api.StreamContext, data interface{}, fv *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) interface{} {
+	ctx.GetLogger().Debugf("TableFuncOp receive: %v", data)
+	switch input := data.(type) {
+	case error:
+		return input
+	case xsql.Row:
+		rows := []xsql.Row{input}
+		for _, f := range p.Funcs {
+			var err error
+			rows, err = p.expand(f, rows, fv)
+			if err != nil {
+				return err
+			}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return rows
+	default:
+		return fmt.Errorf("run table function error: invalid input %[1]T(%[1]v)", input)
+	}
+}
+
+func (p *TableFuncOp) expand(f *ast.TableFunc, rows []xsql.Row, fv *xsql.FunctionValuer) ([]xsql.Row, error) {
+	col := f.ColName()
+	result := make([]xsql.Row, 0, len(rows))
+	for _, row := range rows {
+		ve := &xsql.ValuerEval{Valuer: xsql.MultiValuer(row, fv)}
+		v := ve.Eval(f.Call)
+		var values []interface{}
+		switch vt := v.(type) {
+		case error:
+			return nil, fmt.Errorf("run table function %s error: %v", f.Call.Name, vt)
+		case nil:
+			// no rows generated
+		case []interface{}:
+			values = vt
+		case []map[string]interface{}:
+			values = make([]interface{}, len(vt))
+			for i, m := range vt {
+				values[i] = m
+			}
+		default:
+			values = []interface{}{vt}
+		}
+		for _, value := range values {
+			newRow := row.Clone()
+			newRow.SetTracerCtx(row.GetTracerCtx())
+			newRow.Set(col, value)
+			result = append(result, newRow)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestTableFuncOp_Apply(t *testing.T) {
+	tests := []struct {
+		sql    string
+		data   any
+		result []map[string]any
+		err    string
+	}{
+		{
+			sql:  `SELECT * FROM demo, generate_series(1, a) AS s`,
+			data: &xsql.Tuple{Emitter: "demo", Message: xsql.Message{"a": 2}},
+			result: []map[string]any{
+				{"a": 2, "s": int64(1)},
+				{"a": 2, "s": int64(2)},
+			},
+		},
+		{
+			sql:  `SELECT * FROM demo, unnest(arr) AS v, generate_series(1, 2)`,
+			data: &xsql.Tuple{Emitter: "demo", Message: xsql.Message{"arr": []any{"x", map[string]any{"y": 1}}}},
+			result: []map[string]any{
+				{"arr": []any{"x", map[string]any{"y": 1}}, "v": "x", "generate_series": int64(1)},
+				{"arr": []any{"x", map[string]any{"y": 1}}, "v": "x", "generate_series": int64(2)},
+				{"arr": []any{"x", map[string]any{"y": 1}}, "v": map[string]any{"y": 1}, "generate_series": int64(1)},
+				{"arr": []any{"x", map[string]any{"y": 1}}, "v": map[string]any{"y": 1}, "generate_series": int64(2)},
+			},
+		},
+		{
+			sql:  `SELECT * FROM demo, generate_series(3, a) AS s`,
+			data: &xsql.Tuple{Emitter: "demo", Message: xsql.Message{"a": 2}},
+		},
+		{
+			sql:  `SELECT * FROM demo, generate_series(1, a, b) AS s`,
+			data: &xsql.Tuple{Emitter: "demo", Message: xsql.Message{"a": 2, "b": 0}},
+			err:  "the step cannot be 0",
+		},
+	}
+	contextLogger := conf.Log.WithField("rule", "TestTableFuncOp_Apply")
+	ctx := context.WithValue(context.Background(), context.LoggerKey, contextLogger)
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+			require.NoError(t, err)
+			var funcs []*ast.TableFunc
+			for _, s := range stmt.Sources {
+				if tf, ok := s.(*ast.TableFunc); ok {
+					funcs = append(funcs, tf)
+				}
+			}
+			op := &TableFuncOp{Funcs: funcs}
+			fv, afv := xsql.NewFunctionValuersForOp(ctx)
+			result := op.Apply(ctx, tt.data, fv, afv)
+			switch r := result.(type) {
+			case []xsql.Row:
+				maps := make([]map[string]any, 0, len(r))
+				for _, row := range r {
+					maps = append(maps, row.ToMap())
+				}
+				assert.Equal(t, tt.result, maps)
+			case error:
+				assert.ErrorContains(t, r, tt.err)
+			default:
+				assert.Nil(t, tt.result)
+				assert.Empty(t, tt.err)
+			}
+		})
+	}
+}
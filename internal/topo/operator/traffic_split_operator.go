@@ -0,0 +1,66 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+)
+
+// TrafficSplitOp passes the events whose hash bucket modulo 100 is in [From, To) and drops the others.
+// A rule and its canary read the same source with the complementary ranges to split the traffic. The bucket is
+// decided by the content of the event, so both sides agree on each event regardless of when they start.
+type TrafficSplitOp struct {
+	From int
+	To   int
+}
+
+func (p *TrafficSplitOp) Apply(_ api.StreamContext, data interface{}, _ *xsql.FunctionValuer, _ *xsql.AggregateFunctionValuer) interface{} {
+	if _, ok := data.(error); ok {
+		return data
+	}
+	n := splitBucket(data)
+	if n >= p.From && n < p.To {
+		return data
+	}
+	return nil
+}
+
+// splitBucket hashes the event into [0, 100). The map is encoded by json with the sorted keys to be stable.
+func splitBucket(data any) int {
+	h := fnv.New64a()
+	switch t := data.(type) {
+	case *xsql.RawTuple:
+		_, _ = h.Write(t.Raw())
+	case *xsql.Tuple:
+		writeHash(h, t.ToMap())
+	default:
+		writeHash(h, t)
+	}
+	return int(h.Sum64() % 100)
+}
+
+func writeHash(h interface{ Write([]byte) (int, error) }, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b = []byte(fmt.Sprintf("%v", v))
+	}
+	_, _ = h.Write(b)
+}
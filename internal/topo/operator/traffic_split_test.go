@@ -0,0 +1,59 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestTrafficSplitOp(t *testing.T) {
+	ctx := mockContext.NewMockContext("testSplit", "op1")
+	rule := &TrafficSplitOp{From: 20, To: 100}
+	canary := &TrafficSplitOp{From: 0, To: 20}
+	ruleCount, canaryCount := 0, 0
+	for i := 0; i < 250; i++ {
+		row := &xsql.Tuple{Message: map[string]any{"a": i}}
+		r := rule.Apply(ctx, row, nil, nil)
+		c := canary.Apply(ctx, row, nil, nil)
+		// Each event goes to exactly one of them
+		assert.True(t, (r == nil) != (c == nil), i)
+		if r != nil {
+			ruleCount++
+		} else {
+			canaryCount++
+		}
+	}
+	// The split is roughly by the weight
+	assert.InDelta(t, 200, ruleCount, 30)
+	assert.InDelta(t, 50, canaryCount, 30)
+	// The same event goes to the same side even if the operators see different sequences
+	late := &TrafficSplitOp{From: 0, To: 20}
+	for i := 100; i < 250; i++ {
+		row := &xsql.Tuple{Message: map[string]any{"a": i}}
+		assert.Equal(t, canary.Apply(ctx, row, nil, nil) == nil, late.Apply(ctx, row, nil, nil) == nil, i)
+	}
+	raw := &xsql.RawTuple{Rawdata: []byte(`{"a":1}`)}
+	assert.True(t, (rule.Apply(ctx, raw, nil, nil) == nil) != (canary.Apply(ctx, raw, nil, nil) == nil))
+	// Errors are not split
+	err := errors.New("test")
+	assert.Equal(t, err, rule.Apply(ctx, err, nil, nil))
+	assert.Equal(t, err, canary.Apply(ctx, err, nil, nil))
+}
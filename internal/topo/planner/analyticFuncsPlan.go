@@ -15,6 +15,8 @@
 package planner
 
 import (
+	"fmt"
+
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 )
 
@@ -22,6 +24,7 @@ type AnalyticFuncsPlan struct {
 	baseLogicalPlan
 	funcs      []*ast.Call
 	fieldFuncs []*ast.Call
+	parallel   *parallelHint
 }
 
 func (p AnalyticFuncsPlan) Init() *AnalyticFuncsPlan {
@@ -67,3 +70,28 @@ func (p *AnalyticFuncsPlan) PruneColumns(fields []ast.Expr) error {
 	}
 	return p.baseLogicalPlan.PruneColumns(fields)
 }
+
+// partitionKeys returns the PARTITION BY expressions shared by all the analytic functions, so that the rows can be
+// distributed to the parallel workers without splitting the state of a partition
+func (p *AnalyticFuncsPlan) partitionKeys() ([]ast.Expr, error) {
+	var (
+		keys []ast.Expr
+		sig  string
+	)
+	for _, calls := range [][]*ast.Call{p.funcs, p.fieldFuncs} {
+		for _, c := range calls {
+			if c.Name == "lead" {
+				return nil, fmt.Errorf("hint PARALLEL does not support the analytic function lead which buffers the rows in the operator")
+			}
+			if c.Partition == nil || len(c.Partition.Exprs) == 0 {
+				return nil, fmt.Errorf("hint PARALLEL requires the analytic function %s to have PARTITION BY", c.Name)
+			}
+			if keys == nil {
+				keys, sig = c.Partition.Exprs, c.Partition.String()
+			} else if c.Partition.String() != sig {
+				return nil, fmt.Errorf("hint PARALLEL requires all the analytic functions to have the same PARTITION BY")
+			}
+		}
+	}
+	return keys, nil
+}
@@ -72,6 +72,10 @@ func decorateStmt(s *ast.SelectStatement, store kv.KeyValue, opt *def.RuleOption
 				fieldsMap.reserve(field.Name, streamStmt.stmt.Name)
 			}
 		}
+		// the columns generated by the table functions are set to the rows of the stream
+		for _, tf := range getTableFuncs(s) {
+			fieldsMap.reserve(tf.ColName(), streamStmts[0].stmt.Name)
+		}
 	}
 	var (
 		walkErr            error
@@ -167,6 +171,9 @@ func decorateStmt(s *ast.SelectStatement, store kv.KeyValue, opt *def.RuleOption
 	if walkErr != nil {
 		return nil, nil, nil, walkErr
 	}
+	if err := validateNestedFields(s, streamStmts); err != nil {
+		return nil, nil, nil, err
+	}
 	walkErr = validate(s)
 	// Collect all analytic function calls so that we can let them run firstly
 	ast.WalkFunc(s, func(n ast.Node) bool {
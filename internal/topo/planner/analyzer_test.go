@@ -46,6 +46,13 @@ func newErrorStruct(err string) *errorStruct {
 	}
 }
 
+func newErrorStructWithS(err string, serr string) *errorStruct {
+	return &errorStruct{
+		err:  err,
+		serr: &serr,
+	}
+}
+
 func (e *errorStruct) Serr() string {
 	if e.serr != nil {
 		return *e.serr
@@ -141,6 +148,22 @@ var tests = []struct {
 		sql: "select a + 1 as b, b * 2 as c, c + 1 as a from src1",
 		r:   newErrorStruct("select fields have cycled alias"),
 	},
+	{
+		sql: "SELECT next->nosuch FROM src1",
+		r:   newErrorStructWithS("unknown field next.nosuch", ""),
+	},
+	{
+		sql: "SELECT next->NAME, next->nid FROM src1",
+		r:   newErrorStruct(""),
+	},
+	{
+		sql: "SELECT temp->a FROM src1",
+		r:   newErrorStructWithS("field temp is not a struct, cannot access its field a", ""),
+	},
+	{
+		sql: "SELECT name[0] FROM src1",
+		r:   newErrorStructWithS("field name is not an array, cannot access it by index", ""),
+	},
 	//{ // 19 already captured in parser
 	//	sql: `SELECT * FROM src1 GROUP BY SlidingWindow(ss,5) Over (WHEN abs(sum(a)) > 1) HAVING last_agg_hit_count() < 3`,
 	//	r:   newErrorStruct("error compile sql: Not allowed to call aggregate functions in GROUP BY clause."),
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/lf-edge/ekuiper/v2/internal/converter/merge"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
 	"github.com/lf-edge/ekuiper/v2/pkg/message"
 )
@@ -28,6 +29,8 @@ import (
 type DataSourcePlan struct {
 	baseLogicalPlan
 	name ast.StreamName
+	// parallelism of the decode operators
+	parallel *parallelHint
 	// calculated properties
 	// initialized with stream definition, pruned with rule
 	metaFields []string
@@ -48,6 +51,12 @@ type DataSourcePlan struct {
 	fields      map[string]*ast.JsonStreamField
 	metaMap     map[string]string
 	pruneFields []string
+	// the output of the rule statement if the data source is an intermediate statement
+	statementOutput node.Emitter
+	// pass the raw bytes to the sinks without decoding
+	rawPassthrough bool
+	// the fields to decode for the filter of the raw passthrough, nil if the payload is not decoded at all
+	rawFields map[string]*ast.JsonStreamField
 }
 
 func (p DataSourcePlan) Init() *DataSourcePlan {
@@ -193,14 +202,23 @@ func (p *DataSourcePlan) PruneColumns(fields []ast.Expr) error {
 		p.metaMap = make(map[string]string)
 	}
 	if p.timestampField != "" {
+		// The nested timestamp field like header.ts requires the whole root field
+		tsName := p.timestampField
 		if !p.isSchemaless {
-			tsf, ok := p.streamFields[p.timestampField]
+			tsf, ok := p.streamFields[tsName]
+			if !ok && strings.Contains(tsName, ".") {
+				tsName = strings.Split(tsName, ".")[0]
+				tsf, ok = p.streamFields[tsName]
+			}
 			if !ok {
 				return fmt.Errorf("timestamp field %s not found", p.timestampField)
 			}
-			p.fields[p.timestampField] = tsf
+			p.fields[tsName] = tsf
 		} else {
-			p.fields[p.timestampField] = nil
+			p.fields[tsName] = nil
+			if strings.Contains(tsName, ".") {
+				p.fields[strings.Split(tsName, ".")[0]] = nil
+			}
 		}
 	}
 	arrowFileds := make([]*ast.BinaryExpr, 0)
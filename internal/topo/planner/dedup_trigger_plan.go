@@ -14,7 +14,11 @@
 
 package planner
 
-import "github.com/lf-edge/ekuiper/v2/pkg/ast"
+import (
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
 
 type DedupTriggerPlan struct {
 	baseLogicalPlan
@@ -27,9 +31,14 @@ type DedupTriggerPlan struct {
 
 func (p DedupTriggerPlan) Init() *DedupTriggerPlan {
 	p.baseLogicalPlan.self = &p
+	p.baseLogicalPlan.setPlanType(DEDUPTRIGGER)
 	return &p
 }
 
+func (p *DedupTriggerPlan) BuildExplainInfo() {
+	p.baseLogicalPlan.ExplainInfo.Info = fmt.Sprintf("alias:%s, start:%s, end:%s, now:%s, expire:%d", p.aliasName, p.startField, p.endField, p.nowField, p.expire)
+}
+
 func (p *DedupTriggerPlan) PruneColumns(fields []ast.Expr) error {
 	return p.baseLogicalPlan.PruneColumns(append(fields, p.startField, p.endField, p.nowField))
 }
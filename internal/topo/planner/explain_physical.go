@@ -0,0 +1,240 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	store2 "github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/topo"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+const explainRulePrefix = "$$explain_"
+
+// PhysicalExplain is the explain result of a rule including the optimized logical plan, the applied optimizations
+// and the physical operators which will run
+type PhysicalExplain struct {
+	Rule          string              `json:"rule"`
+	LogicalPlan   string              `json:"logicalPlan"`
+	Optimizations []string            `json:"optimizations"`
+	Topo          *def.PrintableTopo  `json:"topo"`
+	Operators     []*PhysicalOperator `json:"operators"`
+	// Duration is the running time in milliseconds of the analyze mode
+	Duration int64 `json:"duration,omitempty"`
+}
+
+type PhysicalOperator struct {
+	Name    string         `json:"name"`
+	Kind    string         `json:"kind"`
+	Shared  bool           `json:"shared,omitempty"`
+	Outputs []string       `json:"outputs,omitempty"`
+	Stats   *OperatorStats `json:"stats,omitempty"`
+}
+
+// OperatorStats is the actual runtime statistics of an operator collected by the analyze mode
+type OperatorStats struct {
+	RecordsIn        int64  `json:"recordsIn"`
+	RecordsOut       int64  `json:"recordsOut"`
+	Exceptions       int64  `json:"exceptions"`
+	ProcessLatencyUs int64  `json:"processLatencyUs"`
+	LastException    string `json:"lastException,omitempty"`
+}
+
+// ExplainPhysicalPlan plans the rule without running it and returns the physical operators with the applied optimizations
+func ExplainPhysicalPlan(rule *def.Rule) (*PhysicalExplain, error) {
+	r := explainRule(rule, false)
+	result, err := explainLogicalPlan(r)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := PlanSQLWithSourcesAndSinks(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tp.Release()
+		tp.RemoveMetrics()
+	}()
+	buildPhysicalOperators(result, tp, false)
+	return result, nil
+}
+
+// ExplainAnalyze runs the rule for the duration with its actions replaced by nop sinks, and annotates the physical
+// operators with the actual row counts and latencies. The run stops early if all the sources reach EOF.
+func ExplainAnalyze(rule *def.Rule, duration time.Duration) (*PhysicalExplain, error) {
+	r := explainRule(rule, true)
+	result, err := explainLogicalPlan(r)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := PlanSQLWithSourcesAndSinks(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tp.RemoveMetrics()
+	start := time.Now()
+	select {
+	case err = <-tp.Open():
+		if errorx.IsUnexpectedErr(err) {
+			tp.Cancel()
+			return nil, fmt.Errorf("explain analyze run error: %v", err)
+		}
+	case <-time.After(duration):
+	}
+	result.Duration = time.Since(start).Milliseconds()
+	buildPhysicalOperators(result, tp, true)
+	tp.Cancel()
+	tp.WaitClose()
+	return result, nil
+}
+
+// explainRule copies the rule with a temporary id to avoid conflicting with the running rule
+func explainRule(rule *def.Rule, analyze bool) *def.Rule {
+	r := *rule
+	r.Id = explainRulePrefix + rule.Id
+	opt := def.GetDefaultRule(r.Id, r.Sql).Options
+	if rule.Options != nil {
+		o := *rule.Options
+		opt = &o
+	}
+	if analyze {
+		// Do not write checkpoints or send data to the real sinks
+		opt.Qos = def.AtMostOnce
+		r.Actions = make([]map[string]any, 0, len(rule.Actions))
+		for range rule.Actions {
+			r.Actions = append(r.Actions, map[string]any{"nop": map[string]any{}})
+		}
+	}
+	r.Options = opt
+	return &r
+}
+
+func explainLogicalPlan(rule *def.Rule) (*PhysicalExplain, error) {
+	if rule.Sql == "" {
+		return nil, fmt.Errorf("only support explain sql now")
+	}
+	stmt, err := xsql.GetStatementFromSql(rule.Sql)
+	if err != nil {
+		return nil, err
+	}
+	store, err := store2.GetKV("stream")
+	if err != nil {
+		return nil, err
+	}
+	trace := make([]string, 0)
+	lp, err := createLogicalPlanWithTrace(stmt, rule.Options, store, &trace)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ExplainFromLogicalPlan(lp, rule.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &PhysicalExplain{
+		Rule:          strings.TrimPrefix(rule.Id, explainRulePrefix),
+		LogicalPlan:   info,
+		Optimizations: trace,
+	}, nil
+}
+
+// buildPhysicalOperators lists the operators of the topo in the topological order from the sources
+func buildPhysicalOperators(result *PhysicalExplain, tp *topo.Topo, withStats bool) {
+	pt := tp.GetTopo()
+	result.Topo = pt
+	shared := tp.GetSharedSubTopos()
+	var stats map[string]*OperatorStats
+	if withStats {
+		keys, values := tp.GetMetrics()
+		stats = parseOperatorStats(keys, values)
+	}
+	visited := make(map[string]bool)
+	queue := append([]string{}, pt.Sources...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		op := &PhysicalOperator{
+			Name:   name,
+			Kind:   name[:strings.Index(name, "_")],
+			Shared: isSharedOperator(name, shared),
+		}
+		for _, o := range pt.Edges[name] {
+			if s, ok := o.(string); ok {
+				op.Outputs = append(op.Outputs, s)
+				queue = append(queue, s)
+			}
+		}
+		if withStats {
+			op.Stats = stats[name]
+			if op.Stats == nil {
+				op.Stats = &OperatorStats{}
+			}
+		}
+		result.Operators = append(result.Operators, op)
+	}
+}
+
+// isSharedOperator checks if the operator belongs to a shared sub topo whose nodes are named
+// source_{subTopo} or op_{subTopo}_{op}
+func isSharedOperator(name string, shared []string) bool {
+	for _, s := range shared {
+		if name == "source_"+s || strings.HasPrefix(name, "op_"+s+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOperatorStats groups the metrics like op_2_project_0_records_in_total by operator name
+func parseOperatorStats(keys []string, values []any) map[string]*OperatorStats {
+	result := make(map[string]*OperatorStats)
+	for i, key := range keys {
+		for _, mn := range metric.MetricNames {
+			name, found := strings.CutSuffix(key, "_0_"+mn)
+			if !found {
+				continue
+			}
+			st, ok := result[name]
+			if !ok {
+				st = &OperatorStats{}
+				result[name] = st
+			}
+			switch mn {
+			case metric.RecordsInTotal:
+				st.RecordsIn, _ = cast.ToInt64(values[i], cast.CONVERT_SAMEKIND)
+			case metric.RecordsOutTotal:
+				st.RecordsOut, _ = cast.ToInt64(values[i], cast.CONVERT_SAMEKIND)
+			case metric.ExceptionsTotal:
+				st.Exceptions, _ = cast.ToInt64(values[i], cast.CONVERT_SAMEKIND)
+			case metric.ProcessLatencyUs:
+				st.ProcessLatencyUs, _ = cast.ToInt64(values[i], cast.CONVERT_SAMEKIND)
+			case metric.LastException:
+				st.LastException, _ = values[i].(string)
+			}
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/topo"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestExplainPhysicalPlan(t *testing.T) {
+	require.NoError(t, prepareStream())
+	r := def.GetDefaultRule("explainPhysical", "select a from sharedStream where a > 1")
+	r.Actions = []map[string]any{{"nop": map[string]any{}}}
+	result, err := ExplainPhysicalPlan(r)
+	require.NoError(t, err)
+	assert.Equal(t, "explainPhysical", result.Rule)
+	assert.Contains(t, result.Optimizations, "columnPruner")
+	assert.Contains(t, result.LogicalPlan, "StreamName: sharedStream, StreamFields:[ a ]")
+	require.NotEmpty(t, result.Operators)
+	assert.Equal(t, "source", result.Operators[0].Kind)
+	assert.True(t, result.Operators[0].Shared)
+	assert.Equal(t, result.Topo.Sources, []string{result.Operators[0].Name})
+	last := result.Operators[len(result.Operators)-1]
+	assert.Equal(t, "sink_nop_0", last.Name)
+	assert.False(t, last.Shared)
+	assert.Nil(t, last.Stats)
+	// The shared sub topo created by explain must be released
+	_, existed := topo.GetOrCreateSubTopo("sharedStream")
+	assert.False(t, existed)
+	topo.RemoveSubTopo("sharedStream")
+
+	_, err = ExplainPhysicalPlan(def.GetDefaultRule("explainErr", "select a from nonexist"))
+	assert.Error(t, err)
+}
+
+func TestExplainAnalyze(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	s, err := json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM memExplain (a BIGINT) WITH (DATASOURCE="explain/test", TYPE="memory", FORMAT="json");`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("memExplain", string(s)))
+	r := def.GetDefaultRule("explainAnalyze", "select a from memExplain")
+	r.Actions = []map[string]any{{"log": map[string]any{}}}
+	result, err := ExplainAnalyze(r, 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, result.Duration, int64(100))
+	require.NotEmpty(t, result.Operators)
+	for _, op := range result.Operators {
+		assert.NotNil(t, op.Stats, op.Name)
+	}
+	// The actions are replaced by nop sinks to avoid sending data out
+	assert.Equal(t, "sink_nop_0", result.Operators[len(result.Operators)-1].Name)
+}
+
+func TestParseOperatorStats(t *testing.T) {
+	keys := []string{
+		"source_demo_0_records_in_total", "source_demo_0_records_out_total", "source_demo_0_exceptions_total", "source_demo_0_last_exception",
+		"op_2_project_0_records_in_total", "op_2_project_0_process_latency_us", "op_2_project_0_last_exception_time",
+		"sink_log_0_0_records_out_total",
+	}
+	values := []any{int64(3), int64(2), int64(1), "decode error", int64(2), int64(150), int64(0), int64(2)}
+	assert.Equal(t, map[string]*OperatorStats{
+		"source_demo":  {RecordsIn: 3, RecordsOut: 2, Exceptions: 1, LastException: "decode error"},
+		"op_2_project": {RecordsIn: 2, ProcessLatencyUs: 150},
+		"sink_log_0":   {RecordsOut: 2},
+	}, parseOperatorStats(keys, values))
+}
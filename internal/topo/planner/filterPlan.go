@@ -23,6 +23,7 @@ type FilterPlan struct {
 	baseLogicalPlan
 	condition  ast.Expr
 	stateFuncs []*ast.Call
+	parallel   *parallelHint
 }
 
 func (p FilterPlan) Init() *FilterPlan {
@@ -0,0 +1,150 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// IntervalJoinPlan joins two streams by a time bound condition like b.ts BETWEEN a.ts AND a.ts + INTERVAL '5' SECOND
+// without window. The right time minus the left time must be in [lower, upper].
+type IntervalJoinPlan struct {
+	baseLogicalPlan
+	left      string
+	right     string
+	join      ast.Join
+	leftTime  ast.Expr
+	rightTime ast.Expr
+	lower     int64
+	upper     int64
+}
+
+func (p IntervalJoinPlan) Init() *IntervalJoinPlan {
+	p.baseLogicalPlan.self = &p
+	p.baseLogicalPlan.setPlanType(INTERVALJOIN)
+	return &p
+}
+
+func (p *IntervalJoinPlan) BuildExplainInfo() {
+	info := fmt.Sprintf("Join:{ joinType:%s, %s }, Interval:[ %d, %d ]", p.join.JoinType, p.join.Expr, p.lower, p.upper)
+	p.baseLogicalPlan.ExplainInfo.Info = info
+}
+
+func (p *IntervalJoinPlan) PushDownPredicate(condition ast.Expr) (ast.Expr, LogicalPlan) {
+	multipleSourcesCondition, singleSourceCondition := extractCondition(condition)
+	rest, _ := p.baseLogicalPlan.PushDownPredicate(singleSourceCondition)
+	// never swallow anything
+	return combine(multipleSourcesCondition, rest), p
+}
+
+func (p *IntervalJoinPlan) PruneColumns(fields []ast.Expr) error {
+	f := getFields(p.join.Expr)
+	return p.baseLogicalPlan.PruneColumns(append(fields, f...))
+}
+
+// extractIntervalJoin finds the time bound condition of the join between two streams.
+// Return nil if there is no time bound condition.
+func extractIntervalJoin(from *ast.Table, joins ast.Joins) (*IntervalJoinPlan, error) {
+	if len(joins) != 1 || joins[0].Expr == nil {
+		return nil, nil
+	}
+	join := joins[0]
+	left, right := from.Name, join.Name
+	if from.Alias != "" {
+		left = from.Alias
+	}
+	if join.Alias != "" {
+		right = join.Alias
+	}
+	for _, c := range splitConjunctions(join.Expr) {
+		be, ok := c.(*ast.BinaryExpr)
+		if !ok || be.OP != ast.BETWEEN {
+			continue
+		}
+		bt, ok := be.RHS.(*ast.BetweenExpr)
+		if !ok {
+			continue
+		}
+		lowerBase, lower, ok1 := parseTimeBound(bt.Lower)
+		upperBase, upper, ok2 := parseTimeBound(bt.Higher)
+		if !ok1 || !ok2 || lowerBase.String() != upperBase.String() {
+			continue
+		}
+		ts, baseTs := singleSource(be.LHS), singleSource(lowerBase)
+		var p *IntervalJoinPlan
+		switch {
+		case ts == right && baseTs == left:
+			p = IntervalJoinPlan{leftTime: lowerBase, rightTime: be.LHS, lower: lower, upper: upper}.Init()
+		case ts == left && baseTs == right:
+			// left - right in [lower, upper] means right - left in [-upper, -lower]
+			p = IntervalJoinPlan{leftTime: be.LHS, rightTime: lowerBase, lower: -upper, upper: -lower}.Init()
+		default:
+			continue
+		}
+		if join.JoinType != ast.INNER_JOIN && join.JoinType != ast.LEFT_JOIN {
+			return nil, fmt.Errorf("interval join only supports INNER JOIN and LEFT JOIN, but got %s", join.JoinType)
+		}
+		if p.lower > p.upper {
+			return nil, errors.New("the lower bound of the interval join must not be larger than the upper bound")
+		}
+		p.left, p.right, p.join = left, right, join
+		return p, nil
+	}
+	return nil, nil
+}
+
+// parseTimeBound parses the bound expression like a.ts, a.ts + INTERVAL '5' SECOND or a.ts - 1000 into the base
+// expression and the offset in milliseconds
+func parseTimeBound(expr ast.Expr) (ast.Expr, int64, bool) {
+	if pe, ok := expr.(*ast.ParenExpr); ok {
+		return parseTimeBound(pe.Expr)
+	}
+	be, ok := expr.(*ast.BinaryExpr)
+	if !ok || (be.OP != ast.ADD && be.OP != ast.SUB) {
+		return expr, 0, true
+	}
+	var offset int64
+	switch v := be.RHS.(type) {
+	case *ast.IntervalLiteral:
+		offset = v.Millis()
+	case *ast.IntegerLiteral:
+		offset = v.Val
+	default:
+		return nil, 0, false
+	}
+	if be.OP == ast.SUB {
+		offset = -offset
+	}
+	return be.LHS, offset, true
+}
+
+// singleSource returns the stream name if the expression only refers to one stream
+func singleSource(expr ast.Expr) string {
+	s, hasDefault := getRefSources(expr)
+	if hasDefault || len(s) != 1 {
+		return ""
+	}
+	return string(s[0])
+}
+
+func splitConjunctions(expr ast.Expr) []ast.Expr {
+	if be, ok := expr.(*ast.BinaryExpr); ok && be.OP == ast.AND {
+		return append(splitConjunctions(be.LHS), splitConjunctions(be.RHS)...)
+	}
+	return []ast.Expr{expr}
+}
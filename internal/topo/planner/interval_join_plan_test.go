@@ -0,0 +1,136 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestExtractIntervalJoin(t *testing.T) {
+	tests := []struct {
+		sql       string
+		leftTime  string
+		rightTime string
+		lower     int64
+		upper     int64
+		isNil     bool
+		err       string
+	}{
+		{
+			sql:       `SELECT * FROM a INNER JOIN b ON a.id = b.id AND b.ts BETWEEN a.ts AND a.ts + INTERVAL '5' SECOND`,
+			leftTime:  "a.ts",
+			rightTime: "b.ts",
+			lower:     0,
+			upper:     5000,
+		},
+		{
+			sql:       `SELECT * FROM a LEFT JOIN b ON a.ts BETWEEN b.ts - interval 1 mi AND (b.ts + 10) AND a.id = b.id`,
+			leftTime:  "a.ts",
+			rightTime: "b.ts",
+			lower:     -10,
+			upper:     60000,
+		},
+		{
+			sql:   `SELECT * FROM a INNER JOIN b ON a.id = b.id`,
+			isNil: true,
+		},
+		{
+			sql:   `SELECT * FROM a INNER JOIN b ON b.ts BETWEEN a.ts AND b.ts + 1000`,
+			isNil: true,
+		},
+		{
+			sql:   `SELECT * FROM a INNER JOIN b ON b.ts BETWEEN a.ts AND a.ts * 2`,
+			isNil: true,
+		},
+		{
+			sql: `SELECT * FROM a RIGHT JOIN b ON b.ts BETWEEN a.ts AND a.ts + 1000`,
+			err: "interval join only supports INNER JOIN and LEFT JOIN, but got RIGHT_JOIN",
+		},
+		{
+			sql: `SELECT * FROM a INNER JOIN b ON b.ts BETWEEN a.ts + 1000 AND a.ts`,
+			err: "the lower bound of the interval join must not be larger than the upper bound",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+			require.NoError(t, err)
+			p, err := extractIntervalJoin(stmt.Sources[0].(*ast.Table), stmt.Joins)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.isNil {
+				assert.Nil(t, p)
+				return
+			}
+			require.NotNil(t, p)
+			assert.Equal(t, "a", p.left)
+			assert.Equal(t, "b", p.right)
+			assert.Equal(t, tt.leftTime, p.leftTime.String())
+			assert.Equal(t, tt.rightTime, p.rightTime.String())
+			assert.Equal(t, tt.lower, p.lower)
+			assert.Equal(t, tt.upper, p.upper)
+		})
+	}
+}
+
+func TestIntervalJoinPlan(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	require.NoError(t, prepareStream())
+	require.NoError(t, prepareEventTimeStream("leftStream", "rightStream"))
+	stmt, err := xsql.NewParser(strings.NewReader(`SELECT leftStream.a, rightStream.b FROM leftStream INNER JOIN rightStream ON leftStream.a = rightStream.a AND rightStream.ts BETWEEN leftStream.ts AND leftStream.ts + INTERVAL '1' SECOND`)).Parse()
+	require.NoError(t, err)
+	lp, err := createLogicalPlan(stmt, &def.RuleOption{IsEventTime: true}, kv)
+	require.NoError(t, err)
+	var (
+		ip *IntervalJoinPlan
+		wp *WatermarkPlan
+	)
+	var walk func(p LogicalPlan)
+	walk = func(p LogicalPlan) {
+		switch pt := p.(type) {
+		case *IntervalJoinPlan:
+			ip = pt
+		case *WatermarkPlan:
+			wp = pt
+		}
+		for _, c := range p.Children() {
+			walk(c)
+		}
+	}
+	walk(lp)
+	require.NotNil(t, ip)
+	assert.Equal(t, int64(0), ip.lower)
+	assert.Equal(t, int64(1000), ip.upper)
+	require.NotNil(t, wp)
+	assert.True(t, wp.SendWatermark)
+
+	stmt, err = xsql.NewParser(strings.NewReader(`SELECT * FROM stream INNER JOIN sharedStream ON stream.a = sharedStream.a`)).Parse()
+	require.NoError(t, err)
+	_, err = createLogicalPlan(stmt, &def.RuleOption{}, kv)
+	assert.EqualError(t, err, "a time window or count window is required to join multiple streams")
+}
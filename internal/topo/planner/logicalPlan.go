@@ -157,4 +157,7 @@ const (
 	WINDOWFUNC    PlanType = "WindowFuncPlan"
 	WATERMARK     PlanType = "WatermarkPlan"
 	IncAggWindow  PlanType = "IncAggWindowPlan"
+	DEDUPTRIGGER  PlanType = "DedupTriggerPlan"
+	TABLEFUNC     PlanType = "TableFuncPlan"
+	INTERVALJOIN  PlanType = "IntervalJoinPlan"
 )
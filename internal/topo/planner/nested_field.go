@@ -0,0 +1,99 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// validateNestedFields checks the nested field access like `a.b.c` and `a[0].b` against the struct and array types
+// defined in the stream schema. The fields of schemaless streams are not checked.
+func validateNestedFields(s *ast.SelectStatement, streamStmts []*streamInfo) error {
+	var walkErr error
+	ast.WalkFunc(s, func(n ast.Node) bool {
+		if walkErr != nil {
+			return false
+		}
+		if be, ok := n.(*ast.BinaryExpr); ok && (be.OP == ast.ARROW || be.OP == ast.SUBSET) {
+			_, _, walkErr = nestedFieldType(be, streamStmts)
+		}
+		return walkErr == nil
+	})
+	return walkErr
+}
+
+// nestedFieldType returns the schema type and the path of the field expression. The type is nil if it is unknown.
+func nestedFieldType(expr ast.Expr, streamStmts []*streamInfo) (ast.FieldType, string, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return nestedFieldType(e.Expr, streamStmts)
+	case *ast.FieldRef:
+		if e.IsAlias() {
+			return nil, e.Name, nil
+		}
+		for _, si := range streamStmts {
+			if si.schema == nil || si.stmt.Name != e.StreamName {
+				continue
+			}
+			for _, sf := range si.schema {
+				if strings.EqualFold(sf.Name, e.Name) {
+					return sf.FieldType, e.Name, nil
+				}
+			}
+		}
+		return nil, e.Name, nil
+	case *ast.BinaryExpr:
+		lt, path, err := nestedFieldType(e.LHS, streamStmts)
+		if err != nil || lt == nil {
+			return nil, path, err
+		}
+		switch e.OP {
+		case ast.ARROW:
+			jf, ok := e.RHS.(*ast.JsonFieldRef)
+			if !ok {
+				return nil, path, nil
+			}
+			rt, ok := lt.(*ast.RecType)
+			if !ok {
+				return nil, path, fmt.Errorf("field %s is not a struct, cannot access its field %s", path, jf.Name)
+			}
+			for _, sf := range rt.StreamFields {
+				if strings.EqualFold(sf.Name, jf.Name) {
+					return sf.FieldType, path + "." + jf.Name, nil
+				}
+			}
+			return nil, path, fmt.Errorf("unknown field %s.%s", path, jf.Name)
+		case ast.SUBSET:
+			at, ok := lt.(*ast.ArrayType)
+			if !ok {
+				if bt, ok := lt.(*ast.BasicType); ok && bt.Type == ast.BYTEA {
+					return nil, path, nil
+				}
+				return nil, path, fmt.Errorf("field %s is not an array, cannot access it by index", path)
+			}
+			if _, ok := e.RHS.(*ast.ColonExpr); ok {
+				return at, path + "[]", nil
+			}
+			if at.FieldType != nil {
+				return at.FieldType, path + "[]", nil
+			}
+			return &ast.BasicType{Type: at.Type}, path + "[]", nil
+		}
+	}
+	return nil, "", nil
+}
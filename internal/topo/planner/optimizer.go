@@ -14,7 +14,11 @@
 
 package planner
 
-import "github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+import (
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+)
 
 var optRuleList = []logicalOptRule{
 	&columnPruner{},
@@ -24,12 +28,40 @@ var optRuleList = []logicalOptRule{
 }
 
 func optimize(p LogicalPlan, options *def.RuleOption) (LogicalPlan, error) {
+	return optimizeWithTrace(p, options, nil)
+}
+
+// optimizeWithTrace optimizes the plan and records the name of the rules which have changed the plan into the trace
+func optimizeWithTrace(p LogicalPlan, options *def.RuleOption, trace *[]string) (LogicalPlan, error) {
 	var err error
 	for _, rule := range optRuleList {
+		before := ""
+		if trace != nil {
+			before = planDigest(p)
+		}
 		p, err = rule.optimize(p, options)
 		if err != nil {
 			return nil, err
 		}
+		if trace != nil && planDigest(p) != before {
+			*trace = append(*trace, rule.name())
+		}
 	}
 	return p, err
 }
+
+// planDigest returns the explain info of the whole plan tree to compare the plan before and after optimization
+func planDigest(p LogicalPlan) string {
+	b := &strings.Builder{}
+	var walk func(p LogicalPlan, level int)
+	walk = func(p LogicalPlan, level int) {
+		p.BuildExplainInfo()
+		b.WriteString(strings.Repeat("\t", level))
+		b.WriteString(p.Explain())
+		for _, c := range p.Children() {
+			walk(c, level+1)
+		}
+	}
+	walk(p, 0)
+	return b.String()
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+)
+
+// parallelHint is the parallelism of an operator set by the PARALLEL hint or the graph node props.
+// The rows are distributed to the workers by the hash of keyBy field, or by round-robin if it is empty.
+type parallelHint struct {
+	parallelism int
+	keyBy       string
+	// keys are the expressions to distribute the rows of a stateful operator. They are decided by the planner.
+	keys []ast.Expr
+}
+
+// parallelNode is the node which can run with multiple workers
+type parallelNode interface {
+	SetParallelism(concurrency int, keyBy string)
+}
+
+// keyedParallelNode is the stateful node which can run with multiple workers partitioned by keys
+type keyedParallelNode interface {
+	SetKeyedParallelism(concurrency int, keys []ast.Expr)
+}
+
+// parseParallelHints parses the hints like PARALLEL(project, 4, deviceId) into a map of operator kind to parallelism
+func parseParallelHints(hints []*ast.Hint) (map[string]*parallelHint, error) {
+	if len(hints) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]*parallelHint, len(hints))
+	for _, h := range hints {
+		switch h.Name {
+		case "PARALLEL":
+			if len(h.Args) < 2 || len(h.Args) > 3 {
+				return nil, fmt.Errorf("hint PARALLEL expects arguments (operator, parallelism[, keyBy]) but got %d", len(h.Args))
+			}
+			op := strings.ToLower(h.Args[0])
+			switch op {
+			case "decode", "filter", "project", "analytic":
+			default:
+				return nil, fmt.Errorf("hint PARALLEL does not support operator %s, the supported operators are decode, filter, project and analytic", op)
+			}
+			n, err := strconv.Atoi(h.Args[1])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("the parallelism of hint PARALLEL must be a positive integer but got %s", h.Args[1])
+			}
+			ph := &parallelHint{parallelism: n}
+			if len(h.Args) == 3 {
+				switch op {
+				case "decode":
+					return nil, fmt.Errorf("hint PARALLEL cannot distribute the decode operator by key")
+				case "analytic":
+					return nil, fmt.Errorf("hint PARALLEL distributes the analytic operator by the PARTITION BY expressions, the keyBy argument is not allowed")
+				}
+				ph.keyBy = h.Args[2]
+			}
+			result[op] = ph
+		default:
+			return nil, fmt.Errorf("unknown hint %s", h.Name)
+		}
+	}
+	return result, nil
+}
+
+// applyParallelHints sets the parallelism of the logical plans which will be built into concurrent operators
+func applyParallelHints(lp LogicalPlan, hints map[string]*parallelHint) error {
+	if len(hints) == 0 {
+		return nil
+	}
+	switch p := lp.(type) {
+	case *DataSourcePlan:
+		p.parallel = hints["decode"]
+	case *FilterPlan:
+		p.parallel = hints["filter"]
+	case *ProjectPlan:
+		p.parallel = hints["project"]
+	case *AnalyticFuncsPlan:
+		if ph, ok := hints["analytic"]; ok {
+			keys, err := p.partitionKeys()
+			if err != nil {
+				return err
+			}
+			p.parallel = &parallelHint{parallelism: ph.parallelism, keys: keys}
+		}
+	}
+	for _, c := range lp.Children() {
+		if err := applyParallelHints(c, hints); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseParallelProps reads the parallelism and keyBy props of a graph node
+func parseParallelProps(props map[string]any) (*parallelHint, error) {
+	p, ok := props["parallelism"]
+	if !ok {
+		return nil, nil
+	}
+	n, err := cast.ToInt(p, cast.CONVERT_SAMEKIND)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("parallelism must be a positive integer but got %v", p)
+	}
+	ph := &parallelHint{parallelism: n}
+	if k, ok := props["keyBy"]; ok {
+		ph.keyBy, err = cast.ToString(k, cast.CONVERT_SAMEKIND)
+		if err != nil {
+			return nil, fmt.Errorf("keyBy must be a string but got %v", k)
+		}
+	}
+	return ph, nil
+}
+
+// setParallelismByProps sets the parallelism of the graph operator node by its props
+func setParallelismByProps(op any, props map[string]any) error {
+	ph, err := parseParallelProps(props)
+	if err != nil {
+		return err
+	}
+	setParallelism(op, ph)
+	return nil
+}
+
+func setParallelism(op any, ph *parallelHint) {
+	if ph == nil {
+		return
+	}
+	if len(ph.keys) > 0 {
+		if pn, ok := op.(keyedParallelNode); ok {
+			pn.SetKeyedParallelism(ph.parallelism, ph.keys)
+		}
+	} else if pn, ok := op.(parallelNode); ok {
+		pn.SetParallelism(ph.parallelism, ph.keyBy)
+	}
+}
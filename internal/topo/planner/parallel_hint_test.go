@@ -0,0 +1,202 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestParseParallelHints(t *testing.T) {
+	tests := []struct {
+		sql    string
+		result map[string]*parallelHint
+		err    error
+	}{
+		{
+			sql: `SELECT /*+ PARALLEL(project, 4, deviceId) PARALLEL(FILTER, 2) PARALLEL(decode, 3) */ a FROM demo`,
+			result: map[string]*parallelHint{
+				"project": {parallelism: 4, keyBy: "deviceId"},
+				"filter":  {parallelism: 2},
+				"decode":  {parallelism: 3},
+			},
+		},
+		{
+			sql: `SELECT a FROM demo`,
+		},
+		{
+			sql: `SELECT /*+ PARALLEL(project) */ a FROM demo`,
+			err: errors.New("hint PARALLEL expects arguments (operator, parallelism[, keyBy]) but got 1"),
+		},
+		{
+			sql: `SELECT /*+ PARALLEL(window, 2) */ a FROM demo`,
+			err: errors.New("hint PARALLEL does not support operator window, the supported operators are decode, filter, project and analytic"),
+		},
+		{
+			sql: `SELECT /*+ PARALLEL(project, 0) */ a FROM demo`,
+			err: errors.New("the parallelism of hint PARALLEL must be a positive integer but got 0"),
+		},
+		{
+			sql: `SELECT /*+ PARALLEL(decode, 2, a) */ a FROM demo`,
+			err: errors.New("hint PARALLEL cannot distribute the decode operator by key"),
+		},
+		{
+			sql: `SELECT /*+ PARALLEL(analytic, 2, a) */ a FROM demo`,
+			err: errors.New("hint PARALLEL distributes the analytic operator by the PARTITION BY expressions, the keyBy argument is not allowed"),
+		},
+		{
+			sql: `SELECT /*+ BROADCAST(demo) */ a FROM demo`,
+			err: errors.New("unknown hint BROADCAST"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+			require.NoError(t, err)
+			r, err := parseParallelHints(stmt.Hints)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.result, r)
+		})
+	}
+}
+
+func TestApplyParallelHints(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	require.NoError(t, prepareStream())
+	stmt, err := xsql.NewParser(strings.NewReader(`SELECT /*+ PARALLEL(project, 4, b) PARALLEL(decode, 2) */ a FROM stream WHERE a > 1`)).Parse()
+	require.NoError(t, err)
+	lp, err := createLogicalPlan(stmt, &def.RuleOption{}, kv)
+	require.NoError(t, err)
+	pp, ok := lp.(*ProjectPlan)
+	require.True(t, ok)
+	assert.Equal(t, &parallelHint{parallelism: 4, keyBy: "b"}, pp.parallel)
+	var ds *DataSourcePlan
+	var walk func(p LogicalPlan)
+	walk = func(p LogicalPlan) {
+		if d, ok := p.(*DataSourcePlan); ok {
+			ds = d
+		}
+		for _, c := range p.Children() {
+			walk(c)
+		}
+	}
+	walk(lp)
+	require.NotNil(t, ds)
+	assert.Equal(t, &parallelHint{parallelism: 2}, ds.parallel)
+
+	stmt, err = xsql.NewParser(strings.NewReader(`SELECT /*+ PARALLEL(join, 4) */ a FROM stream`)).Parse()
+	require.NoError(t, err)
+	_, err = createLogicalPlan(stmt, &def.RuleOption{}, kv)
+	assert.EqualError(t, err, "hint PARALLEL does not support operator join, the supported operators are decode, filter, project and analytic")
+}
+
+func TestApplyAnalyticParallelHint(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	require.NoError(t, prepareStream())
+	tests := []struct {
+		sql  string
+		keys string
+		err  string
+	}{
+		{
+			sql:  `SELECT /*+ PARALLEL(analytic, 4) */ lag(a) OVER (PARTITION BY b) AS la, acc_sum(a) OVER (PARTITION BY b) FROM stream`,
+			keys: "b",
+		},
+		{
+			sql: `SELECT /*+ PARALLEL(analytic, 4) */ lag(a) AS la FROM stream`,
+			err: "hint PARALLEL requires the analytic function lag to have PARTITION BY",
+		},
+		{
+			sql: `SELECT /*+ PARALLEL(analytic, 4) */ lag(a) OVER (PARTITION BY b) AS la, lag(b) OVER (PARTITION BY a) AS lb FROM stream`,
+			err: "hint PARALLEL requires all the analytic functions to have the same PARTITION BY",
+		},
+		{
+			sql: `SELECT /*+ PARALLEL(analytic, 4) */ lead(a) OVER (PARTITION BY b) AS la FROM stream`,
+			err: "hint PARALLEL does not support the analytic function lead which buffers the rows in the operator",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+			require.NoError(t, err)
+			lp, err := createLogicalPlan(stmt, &def.RuleOption{}, kv)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			var ap *AnalyticFuncsPlan
+			var walk func(p LogicalPlan)
+			walk = func(p LogicalPlan) {
+				if a, ok := p.(*AnalyticFuncsPlan); ok {
+					ap = a
+				}
+				for _, c := range p.Children() {
+					walk(c)
+				}
+			}
+			walk(lp)
+			require.NotNil(t, ap)
+			require.NotNil(t, ap.parallel)
+			assert.Equal(t, 4, ap.parallel.parallelism)
+			require.Len(t, ap.parallel.keys, 1)
+			assert.Equal(t, tt.keys, ap.parallel.keys[0].(*ast.FieldRef).Name)
+		})
+	}
+}
+
+func TestParseParallelProps(t *testing.T) {
+	tests := []struct {
+		props  map[string]any
+		result *parallelHint
+		err    string
+	}{
+		{
+			props: map[string]any{"expr": "a > 1"},
+		},
+		{
+			props:  map[string]any{"parallelism": float64(4), "keyBy": "deviceId"},
+			result: &parallelHint{parallelism: 4, keyBy: "deviceId"},
+		},
+		{
+			props: map[string]any{"parallelism": -1},
+			err:   "parallelism must be a positive integer but got -1",
+		},
+		{
+			props: map[string]any{"parallelism": 2, "keyBy": []string{"a"}},
+			err:   "keyBy must be a string but got [a]",
+		},
+	}
+	for _, tt := range tests {
+		r, err := parseParallelProps(tt.props)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tt.result, r)
+		}
+	}
+}
@@ -72,12 +72,20 @@ func PlanSQLWithSourcesAndSinks(rule *def.Rule, mockSourcesProp map[string]map[s
 	if err != nil {
 		return nil, err
 	}
+	statements, store, statementStreams, err := createStatementPlans(rule, store)
+	if err != nil {
+		return nil, err
+	}
+	streamsFromStmt, err = mergeStatementStreams(statements, statementStreams, streamsFromStmt, store)
+	if err != nil {
+		return nil, err
+	}
 	// Create the logical plan and optimize. Logical plans are a linked list
 	lp, err := createLogicalPlan(stmt, rule.Options, store)
 	if err != nil {
 		return nil, err
 	}
-	tp, err := createTopo(rule, lp, mockSourcesProp, streamsFromStmt)
+	tp, err := createTopo(rule, lp, mockSourcesProp, streamsFromStmt, statements)
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +106,7 @@ func validateStmt(stmt *ast.SelectStatement) error {
 	return vErr
 }
 
-func createTopo(rule *def.Rule, lp LogicalPlan, mockSourcesProp map[string]map[string]any, streamsFromStmt []string) (t *topo.Topo, err error) {
+func createTopo(rule *def.Rule, lp LogicalPlan, mockSourcesProp map[string]map[string]any, streamsFromStmt []string, statements []*statementPlan) (t *topo.Topo, err error) {
 	defer func() {
 		if err != nil {
 			err = errorx.NewWithCode(errorx.ExecutorError, err.Error())
@@ -112,16 +120,35 @@ func createTopo(rule *def.Rule, lp LogicalPlan, mockSourcesProp map[string]map[s
 	}
 	tp.SetStreams(streamsFromStmt)
 
-	input, _, err := buildOps(lp, tp, rule.Options, mockSourcesProp, streamsFromStmt, 0)
+	index, err := buildStatementOps(statements, lp, tp, rule.Options, mockSourcesProp, streamsFromStmt)
+	if err != nil {
+		return nil, err
+	}
+	markRawPassthrough(rule, lp)
+	input, ni, err := buildOps(lp, tp, rule.Options, mockSourcesProp, streamsFromStmt, index)
 	if err != nil {
 		return nil, err
 	}
+	if es := rule.Options.EmitStrategy; es != nil {
+		op := Transform(&operator.EmitOnChangeOp{Keys: es.Keys, Fields: es.Fields, Tolerance: es.Tolerance, MaxKeys: es.MaxKeys}, fmt.Sprintf("%d_emit", ni+1), rule.Options)
+		tp.AddOperator([]node.Emitter{input}, op)
+		input = op
+	}
+	if cl := rule.Options.Changelog; cl != nil {
+		op := Transform(&operator.ChangelogOp{Keys: cl.Keys, OpField: cl.OpField, DeleteAbsent: cl.DeleteAbsent, MaxKeys: cl.MaxKeys}, fmt.Sprintf("%d_changelog", ni+2), rule.Options)
+		tp.AddOperator([]node.Emitter{input}, op)
+		input = op
+	}
 	inputs := []node.Emitter{input}
 	// Add actions
 	err = buildActions(tp, rule, inputs, len(streamsFromStmt))
 	if err != nil {
 		return nil, err
 	}
+	err = buildSideOutputActions(tp, rule, len(streamsFromStmt))
+	if err != nil {
+		return nil, err
+	}
 
 	return tp, nil
 }
@@ -144,12 +171,29 @@ func GetExplainInfoFromLogicalPlan(rule *def.Rule) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	statements, store, _, err := createStatementPlans(rule, store)
+	if err != nil {
+		return "", err
+	}
 	// Create logical plan and optimize. Logical plans are a linked list
 	lp, err := createLogicalPlan(stmt, rule.Options, store)
 	if err != nil {
 		return "", err
 	}
-	return ExplainFromLogicalPlan(lp, rule.Id)
+	var b strings.Builder
+	for _, sp := range statements {
+		info, err := ExplainFromLogicalPlan(sp.lp, rule.Id)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(fmt.Sprintf("Statement %s:\n%s\n\n", sp.name, info))
+	}
+	info, err := ExplainFromLogicalPlan(lp, rule.Id)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(info)
+	return b.String(), nil
 }
 
 func ExplainFromLogicalPlan(lp LogicalPlan, ruleID string) (string, error) {
@@ -207,15 +251,21 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *def.RuleOption, sources ma
 	)
 	switch t := lp.(type) {
 	case *DataSourcePlan:
+		if t.statementOutput != nil {
+			// The operators of the statement have been built
+			return t.statementOutput, newIndex, nil
+		}
 		srcNode, emitters, indexInc, err := transformSourceNode(tp.GetContext(), t, sources, tp.GetName(), options, newIndex)
 		if err != nil {
 			return nil, 0, err
 		}
 		tp.AddSrc(srcNode)
+		tp.SetOpSignature(srcNode.GetName(), operatorSignature(t))
 		inputs = []node.Emitter{srcNode}
 		op = srcNode
 		if len(emitters) > 0 {
 			for i, e := range emitters {
+				tp.SetErrorClass(e.GetName(), def.ErrorClassSource)
 				if i < len(emitters)-1 {
 					tp.AddOperator(inputs, e)
 					inputs = []node.Emitter{e}
@@ -228,8 +278,11 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *def.RuleOption, sources ma
 		}
 	case *WatermarkPlan:
 		op = node.NewWatermarkOp(fmt.Sprintf("%d_watermark", newIndex), t.SendWatermark, t.Emitters, options)
+	case *TableFuncPlan:
+		op = Transform(&operator.TableFuncOp{Funcs: t.funcs}, fmt.Sprintf("%d_tableFunc", newIndex), options)
 	case *AnalyticFuncsPlan:
 		op = Transform(&operator.AnalyticFuncsOp{Funcs: t.funcs, FieldFuncs: t.fieldFuncs}, fmt.Sprintf("%d_analytic", newIndex), options)
+		setParallelism(op, t.parallel)
 	case *IncWindowPlan:
 		if t.Condition != nil {
 			wfilterOp := Transform(&operator.FilterOp{Condition: t.Condition}, fmt.Sprintf("%d_windowFilter", newIndex), options)
@@ -290,14 +343,26 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *def.RuleOption, sources ma
 	case *DedupTriggerPlan:
 		op = node.NewDedupTriggerNode(fmt.Sprintf("%d_dedup_trigger", newIndex), options, t.aliasName, t.startField.Name, t.endField.Name, t.nowField.Name, t.expire)
 	case *LookupPlan:
-		op, err = planLookupSource(tp.GetContext(), t, options)
+		op, err = planLookupSource(tp.GetContext(), t, options, sources[t.joinExpr.Name])
 	case *JoinAlignPlan:
 		op, err = node.NewJoinAlignNode(fmt.Sprintf("%d_join_aligner", newIndex), t.Emitters, t.Sizes, options)
 	case *JoinPlan:
 		op = Transform(&operator.JoinOp{Joins: t.joins, From: t.from}, fmt.Sprintf("%d_join", newIndex), options)
+	case *IntervalJoinPlan:
+		op = node.NewIntervalJoinNode(fmt.Sprintf("%d_interval_join", newIndex), &node.IntervalJoinConf{
+			Left:      t.left,
+			Right:     t.right,
+			JoinType:  t.join.JoinType,
+			Condition: t.join.Expr,
+			LeftTime:  t.leftTime,
+			RightTime: t.rightTime,
+			Lower:     t.lower,
+			Upper:     t.upper,
+		}, options)
 	case *FilterPlan:
 		t.ExtractStateFunc()
 		op = Transform(&operator.FilterOp{Condition: t.condition, StateFuncs: t.stateFuncs}, fmt.Sprintf("%d_filter", newIndex), options)
+		setParallelism(op, t.parallel)
 	case *AggregatePlan:
 		op = Transform(&operator.AggregateOp{Dimensions: t.dimensions}, fmt.Sprintf("%d_aggregate", newIndex), options)
 	case *HavingPlan:
@@ -306,7 +371,23 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *def.RuleOption, sources ma
 	case *OrderPlan:
 		op = Transform(&operator.OrderOp{SortFields: t.SortFields}, fmt.Sprintf("%d_order", newIndex), options)
 	case *ProjectPlan:
-		op = Transform(&operator.ProjectOp{ColNames: t.colNames, AliasNames: t.aliasNames, AliasFields: t.aliasFields, ExprFields: t.exprFields, ExceptNames: t.exceptNames, IsAggregate: t.isAggregate, AllWildcard: t.allWildcard, WildcardEmitters: t.wildcardEmitters, ExprNames: t.exprNames, SendMeta: t.sendMeta, SendNil: t.sendNil, LimitCount: t.limitCount, EnableLimit: t.enableLimit}, fmt.Sprintf("%d_project", newIndex), options)
+		if t.rawSource != nil && t.rawSource.rawPassthrough {
+			// The raw bytes are sent as is
+			conf.Log.Infof("rule %s runs in raw passthrough mode", tp.GetName())
+			return inputs[0], newIndex, nil
+		}
+		pop := Transform(&operator.ProjectOp{ColNames: t.colNames, AliasNames: t.aliasNames, AliasFields: t.aliasFields, ExprFields: t.exprFields, ExceptNames: t.exceptNames, IsAggregate: t.isAggregate, AllWildcard: t.allWildcard, WildcardEmitters: t.wildcardEmitters, ExprNames: t.exprNames, SendMeta: t.sendMeta, SendNil: t.sendNil, LimitCount: t.limitCount, EnableLimit: t.enableLimit}, fmt.Sprintf("%d_project", newIndex), options)
+		setParallelism(pop, t.parallel)
+		if options.ExecBatchSize > 1 && t.parallel == nil {
+			pop.SetExecBatch(options.ExecBatchSize, false)
+			// The filter sends the batches to the project directly
+			if fp, ok := lp.Children()[0].(*FilterPlan); ok && fp.parallel == nil {
+				if fop, ok := inputs[0].(*node.UnaryOperator); ok {
+					fop.SetExecBatch(options.ExecBatchSize, true)
+				}
+			}
+		}
+		op = pop
 	case *ProjectSetPlan:
 		op = Transform(&operator.ProjectSetOperator{SrfMapping: t.SrfMapping, LimitCount: t.limitCount, EnableLimit: t.enableLimit}, fmt.Sprintf("%d_projectset", newIndex), options)
 	case *WindowFuncPlan:
@@ -319,6 +400,13 @@ func buildOps(lp LogicalPlan, tp *topo.Topo, options *def.RuleOption, sources ma
 	}
 	if onode, ok := op.(node.OperatorNode); ok {
 		tp.AddOperator(inputs, onode)
+		tp.SetOpSignature(onode.GetName(), operatorSignature(lp))
+	}
+	if ds, ok := lp.(*DataSourcePlan); ok && options.TrafficSplit != nil {
+		// The split op is not indexed, so that the names of the other ops are the same as the rule without split
+		splitOp := Transform(&operator.TrafficSplitOp{From: options.TrafficSplit.From, To: options.TrafficSplit.To}, fmt.Sprintf("%s_split", ds.name), options)
+		tp.AddOperator([]node.Emitter{op}, splitOp)
+		op = splitOp
 	}
 	return op, newIndex, nil
 }
@@ -344,7 +432,12 @@ func CreateLogicalPlan(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.
 	return createLogicalPlan(stmt, opt, store)
 }
 
-func createLogicalPlan(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.KeyValue) (lp LogicalPlan, err error) {
+func createLogicalPlan(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.KeyValue) (LogicalPlan, error) {
+	return createLogicalPlanWithTrace(stmt, opt, store, nil)
+}
+
+// createLogicalPlanWithTrace creates the optimized logical plan and records the applied optimization rules into trace if it is not nil
+func createLogicalPlanWithTrace(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.KeyValue, trace *[]string) (lp LogicalPlan, err error) {
 	defer func() {
 		if err != nil {
 			err = errorx.NewWithCode(errorx.PlanError, err.Error())
@@ -364,6 +457,10 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.
 		ds                  ast.Dimensions
 	)
 
+	hints, err := parseParallelHints(stmt.Hints)
+	if err != nil {
+		return nil, err
+	}
 	streamStmts, analyticFuncs, analyticFieldFuncs, err := decorateStmt(stmt, store, opt)
 	if err != nil {
 		return nil, err
@@ -409,14 +506,36 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.
 		}
 	}
 	hasWindow := dimensions != nil && dimensions.GetWindow() != nil
+	var intervalJoin *IntervalJoinPlan
+	if stmt.Joins != nil && !hasWindow && len(lookupTableChildren) == 0 && len(scanTableChildren) == 0 {
+		if from, ok := stmt.Sources[0].(*ast.Table); ok {
+			intervalJoin, err = extractIntervalJoin(from, stmt.Joins)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 	if opt.IsEventTime {
 		p = WatermarkPlan{
-			SendWatermark: hasWindow,
+			SendWatermark: hasWindow || intervalJoin != nil,
 			Emitters:      streamEmitters,
 		}.Init()
 		p.SetChildren(children)
 		children = []LogicalPlan{p}
 	}
+	if tableFuncs := getTableFuncs(stmt); len(tableFuncs) > 0 {
+		if len(children) == 0 {
+			return nil, errors.New("cannot run table function for TABLE sources")
+		}
+		if stmt.Joins != nil {
+			return nil, errors.New("table function cannot be used with join")
+		}
+		p = TableFuncPlan{
+			funcs: tableFuncs,
+		}.Init()
+		p.SetChildren(children)
+		children = []LogicalPlan{p}
+	}
 	if len(analyticFuncs) > 0 || len(analyticFieldFuncs) > 0 {
 		p = AnalyticFuncsPlan{
 			funcs:      analyticFuncs,
@@ -431,6 +550,14 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.
 			if len(children) == 0 {
 				return nil, errors.New("cannot run window for TABLE sources")
 			}
+			if opt.IsEventTime && opt.LateDataPolicy == def.LateDataUpdate {
+				if w.WindowType != ast.TUMBLING_WINDOW && w.WindowType != ast.HOPPING_WINDOW {
+					return nil, errors.New("lateDataPolicy update only supports tumbling and hopping windows")
+				}
+				if len(rewriteRes.incAggFields) > 0 {
+					return nil, errors.New("lateDataPolicy update does not support incremental window")
+				}
+			}
 			if len(rewriteRes.incAggFields) > 0 {
 				incWp := IncWindowPlan{
 					WType:            w.WindowType,
@@ -488,7 +615,11 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.
 			}
 		}
 	}
-	if stmt.Joins != nil {
+	if intervalJoin != nil {
+		p = intervalJoin
+		p.SetChildren(children)
+		children = []LogicalPlan{p}
+	} else if stmt.Joins != nil {
 		if len(lookupTableChildren) == 0 && len(scanTableChildren) == 0 && w == nil {
 			return nil, errors.New("a time window or count window is required to join multiple streams")
 		}
@@ -651,7 +782,14 @@ func createLogicalPlan(stmt *ast.SelectStatement, opt *def.RuleOption, store kv.
 		p.SetChildren(children)
 	}
 
-	return optimize(p, opt)
+	p, err = optimizeWithTrace(p, opt, trace)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyParallelHints(p, hints); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 // extractSRFMapping extracts the set-returning-function in the field
@@ -764,7 +902,7 @@ func extractNodeIncAgg(node ast.Node, index *int) ([]*ast.Field, bool) {
 		case *ast.Call:
 			if f.FuncType == ast.FuncTypeAgg {
 				hasAgg = true
-				if !function.IsSupportedIncAgg(f.Name) {
+				if !function.IsSupportedIncAgg(f.Name) || f.Filter != nil {
 					canIncAgg = false
 					return false
 				}
@@ -17,6 +17,7 @@ package planner
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -94,6 +95,7 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 			inputs := []node.Emitter{srcNode}
 			for _, e := range ops {
 				tp.AddOperator(inputs, e)
+				tp.SetErrorClass(e.GetName(), def.ErrorClassSource)
 				inputs = []node.Emitter{e}
 				nodeMap[srcName] = e
 			}
@@ -134,6 +136,9 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 					return nil, fmt.Errorf("parse function %s with %v error: %w", nodeName, gn.Props, err)
 				}
 				op := Transform(fop, nodeName, rule.Options)
+				if err := setParallelismByProps(op, gn.Props); err != nil {
+					return nil, fmt.Errorf("parse function %s with %v error: %w", nodeName, gn.Props, err)
+				}
 				nodeMap[nodeName] = op
 			case "aggfunc":
 				fop, err := parseFunc(gn.Props, sourceNames)
@@ -149,6 +154,9 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 					return nil, fmt.Errorf("parse filter %s with %v error: %w", nodeName, gn.Props, err)
 				}
 				op := Transform(fop, nodeName, rule.Options)
+				if err := setParallelismByProps(op, gn.Props); err != nil {
+					return nil, fmt.Errorf("parse filter %s with %v error: %w", nodeName, gn.Props, err)
+				}
 				nodeMap[nodeName] = op
 			case "pick":
 				pop, err := parsePick(gn.Props, sourceNames)
@@ -156,6 +164,9 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 					return nil, fmt.Errorf("parse pick %s with %v error: %w", nodeName, gn.Props, err)
 				}
 				op := Transform(pop, nodeName, rule.Options)
+				if err := setParallelismByProps(op, gn.Props); err != nil {
+					return nil, fmt.Errorf("parse pick %s with %v error: %w", nodeName, gn.Props, err)
+				}
 				nodeMap[nodeName] = op
 			case "window":
 				wconf, err := parseWindow(gn.Props)
@@ -193,7 +204,7 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 								if !lookupPlan.validateAndExtractCondition() {
 									return nil, fmt.Errorf("parse join %s with %v error: join condition %s is invalid, at least one equi-join predicate is required", nodeName, gn.Props, join.Expr)
 								}
-								op, err := planLookupSource(tp.GetContext(), &lookupPlan, rule.Options)
+								op, err := planLookupSource(tp.GetContext(), &lookupPlan, rule.Options, nil)
 								if err != nil {
 									return nil, fmt.Errorf("parse join %s with %v error: fail to create lookup node", nodeName, gn.Props)
 								}
@@ -238,6 +249,28 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 					return nil, fmt.Errorf("create switch %s with %v error: %w", nodeName, gn.Props, err)
 				}
 				nodeMap[nodeName] = op
+			case "union":
+				nodeMap[nodeName] = node.NewUnionNode(nodeName, rule.Options)
+			case "pattern":
+				pconf, err := parsePattern(gn.Props, sourceNames)
+				if err != nil {
+					return nil, fmt.Errorf("parse pattern %s with %v error: %w", nodeName, gn.Props, err)
+				}
+				op, err := node.NewPatternNode(nodeName, pconf, rule.Options)
+				if err != nil {
+					return nil, fmt.Errorf("create pattern %s with %v error: %w", nodeName, gn.Props, err)
+				}
+				nodeMap[nodeName] = op
+			case "dedup":
+				key, ttl, err := parseDedup(gn.Props, sourceNames)
+				if err != nil {
+					return nil, fmt.Errorf("parse dedup %s with %v error: %w", nodeName, gn.Props, err)
+				}
+				op, err := node.NewDedupNode(nodeName, key, ttl, rule.Options)
+				if err != nil {
+					return nil, fmt.Errorf("create dedup %s with %v error: %w", nodeName, gn.Props, err)
+				}
+				nodeMap[nodeName] = op
 			default:
 				gnf, ok := extNodes[nt]
 				if !ok {
@@ -349,6 +382,10 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 						if err != nil {
 							return nil, fmt.Errorf("node %s output does not match node %s input: %v", innode, n, err)
 						}
+						// the merged branches must be the same type so that the downstream can handle them all
+						if gn.NodeType == "union" && dataFlow[innode].Type != dataFlow[innodes[0]].Type {
+							return nil, fmt.Errorf("union node %s cannot merge the inputs of different types", n)
+						}
 					}
 				} else {
 					return nil, fmt.Errorf("operator %s of type %s does not allow multiple inputs", n, gn.NodeType)
@@ -391,6 +428,9 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 				} else {
 					switch sn := nodeMap[from].(type) {
 					case *node.SwitchNode:
+						if i >= sn.OutputCount() {
+							return nil, fmt.Errorf("switch node %s has %d outputs but edge %d is defined", from, sn.OutputCount(), i)
+						}
 						inputs = append(inputs, sn.GetEmitter(i))
 					default:
 						return nil, fmt.Errorf("node %s is not a switch node but have multiple output", from)
@@ -413,6 +453,9 @@ func PlanByGraph(rule *def.Rule) (*topo.Topo, error) {
 			tp.AddOperator(inputs, n.(node.OperatorNode))
 		}
 	}
+	if err = buildSideOutputActions(tp, rule, len(sourceNames)); err != nil {
+		return nil, err
+	}
 	return tp, nil
 }
 
@@ -820,5 +863,129 @@ func parseSwitch(props map[string]interface{}, sourceNames []string) (*node.Swit
 	return &node.SwitchConfig{
 		Cases:            caseExprs,
 		StopAtFirstMatch: n.StopAtFirstMatch,
+		Default:          n.Default,
 	}, nil
 }
+
+func parseDedup(props map[string]interface{}, sourceNames []string) (ast.Expr, time.Duration, error) {
+	n := &graph.Dedup{}
+	err := cast.MapToStruct(props, n)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n.Key == "" {
+		return nil, 0, fmt.Errorf("dedup key is required")
+	}
+	stmt, err := xsql.NewParserWithSources(strings.NewReader("select "+n.Key+" from nonexist"), sourceNames).Parse()
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse key error: %v", err)
+	}
+	return stmt.Fields[0].Expr, n.TTL, nil
+}
+
+func parsePattern(props map[string]interface{}, sourceNames []string) (*node.PatternConfig, error) {
+	n := &graph.Pattern{}
+	err := cast.MapToStruct(props, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(n.Sequence) == 0 {
+		return nil, fmt.Errorf("pattern node must have at least one step in sequence")
+	}
+	if n.Within < 0 {
+		return nil, fmt.Errorf("pattern within %v is invalid", n.Within)
+	}
+	if n.MaxPartials < 0 {
+		return nil, fmt.Errorf("pattern maxPartials %d is invalid", n.MaxPartials)
+	}
+	if n.MaxEvents < 0 {
+		return nil, fmt.Errorf("pattern maxEvents %d is invalid", n.MaxEvents)
+	}
+	pc := &node.PatternConfig{
+		Within:      n.Within,
+		Steps:       make([]*node.PatternStep, len(n.Sequence)),
+		MaxPartials: n.MaxPartials,
+		MaxEvents:   n.MaxEvents,
+	}
+	if n.PartitionBy != "" {
+		stmt, err := xsql.NewParserWithSources(strings.NewReader("select "+n.PartitionBy+" from nonexist"), sourceNames).Parse()
+		if err != nil {
+			return nil, fmt.Errorf("parse partitionBy error: %v", err)
+		}
+		pc.PartitionBy = stmt.Fields[0].Expr
+	}
+	for i, step := range n.Sequence {
+		if step.Name == "" {
+			return nil, fmt.Errorf("pattern step %d must have a name", i)
+		}
+		p := xsql.NewParserWithSources(strings.NewReader("where "+step.Condition), sourceNames)
+		exp, err := p.ParseCondition()
+		if err != nil {
+			return nil, fmt.Errorf("parse pattern step %s condition error: %v", step.Name, err)
+		}
+		if exp == nil {
+			return nil, fmt.Errorf("pattern step %s must have a condition", step.Name)
+		}
+		minN, maxN, err := parseQuantifier(step.Quantifier)
+		if err != nil {
+			return nil, fmt.Errorf("pattern step %s: %v", step.Name, err)
+		}
+		if step.Negated && step.Quantifier != "" {
+			return nil, fmt.Errorf("negated pattern step %s does not support quantifier", step.Name)
+		}
+		pc.Steps[i] = &node.PatternStep{
+			Name:      step.Name,
+			Condition: exp,
+			Min:       minN,
+			Max:       maxN,
+			Negated:   step.Negated,
+		}
+	}
+	if len(n.Measures) > 0 {
+		stmt, err := xsql.NewParserWithSources(strings.NewReader("select "+strings.Join(n.Measures, ",")+" from nonexist"), sourceNames).Parse()
+		if err != nil {
+			return nil, fmt.Errorf("parse pattern measures error: %v", err)
+		}
+		pc.Measures = stmt.Fields
+	}
+	return pc, nil
+}
+
+// parseQuantifier parses the regex like quantifiers: "", "?", "+", "*", "{n}", "{n,}" and "{n,m}".
+// Returns the min and max times. Max is -1 for unbounded.
+func parseQuantifier(q string) (int, int, error) {
+	q = strings.TrimSpace(q)
+	switch q {
+	case "":
+		return 1, 1, nil
+	case "?":
+		return 0, 1, nil
+	case "+":
+		return 1, -1, nil
+	case "*":
+		return 0, -1, nil
+	}
+	if !strings.HasPrefix(q, "{") || !strings.HasSuffix(q, "}") {
+		return 0, 0, fmt.Errorf("invalid quantifier %s", q)
+	}
+	parts := strings.Split(q[1:len(q)-1], ",")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("invalid quantifier %s", q)
+	}
+	minN, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || minN < 0 {
+		return 0, 0, fmt.Errorf("invalid quantifier %s", q)
+	}
+	if len(parts) == 1 {
+		return minN, minN, nil
+	}
+	ms := strings.TrimSpace(parts[1])
+	if ms == "" {
+		return minN, -1, nil
+	}
+	maxN, err := strconv.Atoi(ms)
+	if err != nil || maxN < minN || maxN == 0 {
+		return 0, 0, fmt.Errorf("invalid quantifier %s", q)
+	}
+	return minN, maxN, nil
+}
@@ -616,6 +616,233 @@ func TestPlannerGraphValidate(t *testing.T) {
 		}`,
 			err: "",
 		},
+		{
+			graph: `{
+  "nodes": {
+    "abc": {
+      "type": "source",
+      "nodeType": "mqtt",
+      "props": {
+        "datasource": "demo"
+      }
+    },
+    "switch": {
+      "type": "operator",
+      "nodeType": "switch",
+      "props": {
+        "cases": [
+          "temperature > 20"
+        ],
+        "default": true
+      }
+    },
+    "hotfunc": {
+      "type": "operator",
+      "nodeType": "function",
+      "props": {
+        "expr": "abs(temperature) as t"
+      }
+    },
+    "normalfunc": {
+      "type": "operator",
+      "nodeType": "function",
+      "props": {
+        "expr": "log(temperature) as t"
+      }
+    },
+    "merge": {
+      "type": "operator",
+      "nodeType": "union"
+    },
+    "log": {
+      "type": "sink",
+      "nodeType": "log",
+      "props": {}
+    }
+  },
+  "topo": {
+    "sources": [
+      "abc"
+    ],
+    "edges": {
+      "abc": [
+        "switch"
+      ],
+      "switch": [
+        [
+          "hotfunc"
+        ],
+        [
+          "normalfunc"
+        ]
+      ],
+      "normalfunc": [
+        "merge"
+      ],
+      "hotfunc": [
+        "merge"
+      ],
+      "merge": [
+        "log"
+      ]
+    }
+  }
+}`,
+			err: "",
+		},
+		{
+			graph: `{
+  "nodes": {
+    "abc": {
+      "type": "source",
+      "nodeType": "mqtt",
+      "props": {
+        "datasource": "demo"
+      }
+    },
+    "switch": {
+      "type": "operator",
+      "nodeType": "switch",
+      "props": {
+        "cases": [
+          "temperature > 20"
+        ],
+        "default": true
+      }
+    },
+    "hotfunc": {
+      "type": "operator",
+      "nodeType": "function",
+      "props": {
+        "expr": "abs(temperature) as t"
+      }
+    },
+    "window": {
+      "type": "operator",
+      "nodeType": "window",
+      "props": {
+        "type": "tumblingwindow",
+        "unit": "ss",
+        "size": 10
+      }
+    },
+    "merge": {
+      "type": "operator",
+      "nodeType": "union"
+    },
+    "log": {
+      "type": "sink",
+      "nodeType": "log",
+      "props": {}
+    }
+  },
+  "topo": {
+    "sources": [
+      "abc"
+    ],
+    "edges": {
+      "abc": [
+        "switch"
+      ],
+      "switch": [
+        [
+          "hotfunc"
+        ],
+        [
+          "window"
+        ]
+      ],
+      "window": [
+        "merge"
+      ],
+      "hotfunc": [
+        "merge"
+      ],
+      "merge": [
+        "log"
+      ]
+    }
+  }
+}`,
+			err: "union node merge cannot merge the inputs of different types",
+		},
+		{
+			graph: `{
+  "nodes": {
+    "abc": {
+      "type": "source",
+      "nodeType": "mqtt",
+      "props": {
+        "datasource": "demo"
+      }
+    },
+    "switch": {
+      "type": "operator",
+      "nodeType": "switch",
+      "props": {
+        "cases": [
+          "temperature > 20"
+        ],
+        "default": true
+      }
+    },
+    "hotfunc": {
+      "type": "operator",
+      "nodeType": "function",
+      "props": {
+        "expr": "abs(temperature) as t"
+      }
+    },
+    "normalfunc": {
+      "type": "operator",
+      "nodeType": "function",
+      "props": {
+        "expr": "log(temperature) as t"
+      }
+    },
+    "merge": {
+      "type": "operator",
+      "nodeType": "union"
+    },
+    "log": {
+      "type": "sink",
+      "nodeType": "log",
+      "props": {}
+    }
+  },
+  "topo": {
+    "sources": [
+      "abc"
+    ],
+    "edges": {
+      "abc": [
+        "switch"
+      ],
+      "switch": [
+        [
+          "hotfunc"
+        ],
+        [
+          "normalfunc"
+        ],
+        [
+          "merge"
+        ]
+      ],
+      "normalfunc": [
+        "merge"
+      ],
+      "hotfunc": [
+        "merge"
+      ],
+      "merge": [
+        "log"
+      ]
+    }
+  }
+}`,
+			err: "switch node switch has 2 outputs but edge 2 is defined",
+		},
 	}
 
 	t.Logf("The test bucket size is %d.\n\n", len(tests))
@@ -996,3 +1223,60 @@ func TestPlannerGraphWithStream(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQuantifier(t *testing.T) {
+	tests := []struct {
+		q   string
+		min int
+		max int
+		err string
+	}{
+		{q: "", min: 1, max: 1},
+		{q: "?", min: 0, max: 1},
+		{q: "+", min: 1, max: -1},
+		{q: "*", min: 0, max: -1},
+		{q: "{3}", min: 3, max: 3},
+		{q: "{2,}", min: 2, max: -1},
+		{q: "{2, 5}", min: 2, max: 5},
+		{q: "{5,2}", err: "invalid quantifier {5,2}"},
+		{q: "{a}", err: "invalid quantifier {a}"},
+		{q: "++", err: "invalid quantifier ++"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			minN, maxN, err := parseQuantifier(tt.q)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("expect error %s but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if minN != tt.min || maxN != tt.max {
+				t.Errorf("expect {%d,%d} but got {%d,%d}", tt.min, tt.max, minN, maxN)
+			}
+		})
+	}
+}
+
+func TestParseDedup(t *testing.T) {
+	key, ttl, err := parseDedup(map[string]any{
+		"key": "concat(deviceId, \"-\", seq)",
+		"ttl": "10m",
+	}, []string{"demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl != 10*time.Minute {
+		t.Errorf("expect ttl 10m but got %v", ttl)
+	}
+	if c, ok := key.(*ast.Call); !ok || c.Name != "concat" {
+		t.Errorf("expect concat call but got %v", key)
+	}
+	_, _, err = parseDedup(map[string]any{"ttl": "10m"}, []string{"demo"})
+	if err == nil || err.Error() != "dedup key is required" {
+		t.Errorf("expect key required error but got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"errors"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
+)
+
+const sideOutputName = "sideOutput"
+
+// sideOutputNode is the node which can route its invalid data to the rule side output
+type sideOutputNode interface {
+	EnableSideOutput(topic string)
+}
+
+// enableSideOutput routes the invalid data of the node to the rule side output if it is configured
+func enableSideOutput(op any, ruleId string, options *def.RuleOption) {
+	topic := node.SideOutputTopic(ruleId, options.SideOutput)
+	if topic == "" {
+		return
+	}
+	if sn, ok := op.(sideOutputNode); ok {
+		sn.EnableSideOutput(topic)
+	}
+}
+
+// buildSideOutputActions plans the dedicated actions which subscribe the rule side output
+func buildSideOutputActions(tp *topo.Topo, rule *def.Rule, streamCount int) error {
+	so := rule.Options.SideOutput
+	if so == nil || len(so.Actions) == 0 {
+		return nil
+	}
+	if rule.Options.Qos >= def.AtLeastOnce {
+		return errors.New("the actions of sideOutput are not supported when qos is enabled, please consume the side output topic by another rule instead")
+	}
+	sn := node.NewSideOutputNode(sideOutputName, node.SideOutputTopic(rule.Id, so), rule.Options)
+	tp.AddOperator(nil, sn)
+	return planActions(tp, rule, so.Actions, sideOutputName+"_", []node.Emitter{sn}, streamCount)
+}
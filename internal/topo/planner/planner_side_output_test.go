@@ -0,0 +1,45 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+)
+
+func TestSideOutputActions(t *testing.T) {
+	require.NoError(t, prepareStream())
+	r := def.GetDefaultRule("sideOutputRule", "select a from stream")
+	r.Actions = []map[string]any{{"nop": map[string]any{}}}
+	r.Options.SideOutput = &def.SideOutput{
+		Actions: []map[string]any{{"log": map[string]any{}}},
+	}
+	tp, err := PlanSQLWithSourcesAndSinks(r, nil)
+	require.NoError(t, err)
+	defer tp.Release()
+	assert.Equal(t, []any{"op_sideOutput_log_0_0_transform"}, tp.GetTopo().Edges["op_sideOutput"])
+
+	r = def.GetDefaultRule("sideOutputQos", "select a from stream")
+	r.Options.Qos = def.AtLeastOnce
+	r.Options.SideOutput = &def.SideOutput{
+		Actions: []map[string]any{{"log": map[string]any{}}},
+	}
+	_, err = PlanSQLWithSourcesAndSinks(r, nil)
+	assert.EqualError(t, err, "the actions of sideOutput are not supported when qos is enabled, please consume the side output topic by another rule instead")
+}
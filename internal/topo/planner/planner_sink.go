@@ -33,7 +33,12 @@ import (
 // It will split the sink plan into multiple sink nodes according to its sink configurations.
 
 func buildActions(tp *topo.Topo, rule *def.Rule, inputs []node.Emitter, streamCount int) error {
-	for i, m := range rule.Actions {
+	return planActions(tp, rule, rule.Actions, "", inputs, streamCount)
+}
+
+// planActions plans the sinks of the actions whose names are prefixed by namePrefix
+func planActions(tp *topo.Topo, rule *def.Rule, actions []map[string]any, namePrefix string, inputs []node.Emitter, streamCount int) error {
+	for i, m := range actions {
 		for name, action := range m {
 			props, ok := action.(map[string]any)
 			if !ok {
@@ -43,7 +48,7 @@ func buildActions(tp *topo.Topo, rule *def.Rule, inputs []node.Emitter, streamCo
 			if err != nil {
 				return err
 			}
-			sinkName := fmt.Sprintf("%s_%d", name, i)
+			sinkName := fmt.Sprintf("%s%s_%d", namePrefix, name, i)
 			cn, err := SinkToComp(tp, name, sinkName, props, rule, streamCount)
 			if err != nil {
 				return err
@@ -69,6 +74,7 @@ func PlanSinkOps(tp *topo.Topo, inputs []node.Emitter, cn node.CompNode) {
 				preSink = nil
 			} else {
 				tp.AddOperator(newInputs, nt)
+				tp.SetErrorClass(nt.GetName(), def.ErrorClassSink)
 			}
 			newInputs = []node.Emitter{nt}
 		}
@@ -86,7 +92,11 @@ func SinkToComp(tp *topo.Topo, sinkType string, sinkName string, props map[strin
 	}
 	templates := findTemplateProps(props)
 	// Split sink node
-	sinkOps, err := splitSink(tp, s, sinkName, rule.Options, commonConf, templates)
+	var batchCtrl *node.BatchController
+	if commonConf.AdaptiveBatch {
+		batchCtrl = node.NewBatchController(commonConf.BatchSize, time.Duration(commonConf.LingerInterval))
+	}
+	sinkOps, err := splitSink(tp, s, sinkName, rule.Options, commonConf, templates, batchCtrl)
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +109,7 @@ func SinkToComp(tp *topo.Topo, sinkType string, sinkName string, props map[strin
 		name:  sinkName,
 		nodes: sinkOps,
 	}
-	var snk node.DataSinkNode
+	var snk *node.SinkNode
 	switch ss := s.(type) {
 	case api.BytesCollector:
 		snk, err = node.NewBytesSinkNode(tp.GetContext(), sinkName, ss, *rule.Options, streamCount, &commonConf.SinkConf, false)
@@ -111,6 +121,8 @@ func SinkToComp(tp *topo.Topo, sinkType string, sinkName string, props map[strin
 	if err != nil {
 		return nil, err
 	}
+	snk.SetAsync(tp.GetContext(), commonConf.MaxInFlight, commonConf.OrderKey)
+	snk.SetBatchController(batchCtrl)
 	result.nodes = append(result.nodes, snk)
 	// Cache in alter queue, the topo becomes sink (fail) -> cache -> resendSink
 	// If no alter queue, the topo is cache -> sink
@@ -167,7 +179,7 @@ func findTemplateProps(props map[string]any) []string {
 }
 
 // Split sink node according to the sink configuration. Return the new input emitters.
-func splitSink(tp *topo.Topo, s api.Sink, sinkName string, options *def.RuleOption, sc *node.SinkConf, templates []string) ([]node.TopNode, error) {
+func splitSink(tp *topo.Topo, s api.Sink, sinkName string, options *def.RuleOption, sc *node.SinkConf, templates []string, batchCtrl *node.BatchController) ([]node.TopNode, error) {
 	index := 0
 	result := make([]node.TopNode, 0)
 	// Batch enabled
@@ -176,6 +188,7 @@ func splitSink(tp *topo.Topo, s api.Sink, sinkName string, options *def.RuleOpti
 		if err != nil {
 			return nil, err
 		}
+		batchOp.SetController(batchCtrl)
 		index++
 		result = append(result, batchOp)
 	}
@@ -30,11 +30,26 @@ import (
 	"github.com/lf-edge/ekuiper/v2/pkg/model"
 )
 
+// MockTapKey in the mock source props means to use the real source of the stream instead of mocking it
+const MockTapKey = "$$tap"
+
 func transformSourceNode(ctx api.StreamContext, t *DataSourcePlan, mockSourcesProp map[string]map[string]any, ruleId string, options *def.RuleOption, index int) (node.DataSourceNode, []node.OperatorNode, int, error) {
 	isSchemaless := t.isSchemaless
+	if options.Mode == def.RuleModeBatch {
+		// The batch rule reads its own inputs to the end, so the source cannot be shared with the other rules
+		t.streamStmt.Options.SHARED = false
+	}
 	mockProps, isMock := mockSourcesProp[string(t.name)]
 	if isMock {
-		t.streamStmt.Options.TYPE = "simulator"
+		if tap, _ := mockProps[MockTapKey].(bool); tap {
+			// Read the live data of the stream with the shared source so that the connection of the running rules is reused
+			t.streamStmt.Options.SHARED = true
+			mockProps = nil
+		} else {
+			// The mock source is owned by the rule, it must not be shared with the rules reading the real source
+			t.streamStmt.Options.TYPE = "simulator"
+			t.streamStmt.Options.SHARED = false
+		}
 	}
 	strType := t.streamStmt.Options.TYPE
 	if strType == "" {
@@ -54,8 +69,8 @@ func transformSourceNode(ctx api.StreamContext, t *DataSourcePlan, mockSourcesPr
 		return nil, nil, 0, fmt.Errorf("source type %s not found", strType)
 	}
 	var pp node.UnOperation
-	if t.iet || (!isSchemaless && (t.streamStmt.Options.STRICT_VALIDATION || t.isBinary)) {
-		pp, err = operator.NewPreprocessor(isSchemaless, t.streamFields, t.allMeta, t.metaFields, t.iet, t.timestampField, t.timestampFormat, t.isBinary, t.streamStmt.Options.STRICT_VALIDATION)
+	if t.iet || (!isSchemaless && (t.streamStmt.Options.STRICT_VALIDATION || t.isBinary || t.streamStmt.Options.SCHEMA_POLICY != "")) {
+		pp, err = operator.NewPreprocessor(isSchemaless, t.streamFields, t.allMeta, t.metaFields, t.iet, t.timestampField, t.timestampFormat, t.isBinary, t.streamStmt.Options.STRICT_VALIDATION, t.streamStmt.Options.SCHEMA_POLICY, t.streamStmt.Options.TIMESTAMP_TZ, t.streamStmt.Options.TIMESTAMP_POLICY)
 		if err != nil {
 			return nil, nil, 0, err
 		}
@@ -140,6 +155,15 @@ func splitSource(ctx api.StreamContext, t *DataSourcePlan, ss api.Source, option
 		ops = append(ops, rlOp)
 	}
 
+	if featureSet.needDecryption {
+		deo, err := node.NewDecryptOp(fmt.Sprintf("%d_decrypt", index), options, sp.Decryption, sp.DecProps)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		index++
+		ops = append(ops, deo)
+	}
+
 	if featureSet.needCompression {
 		dco, err := node.NewDecompressOp(fmt.Sprintf("%d_decompress", index), options, sp.Decompression)
 		if err != nil {
@@ -149,16 +173,34 @@ func splitSource(ctx api.StreamContext, t *DataSourcePlan, ss api.Source, option
 		ops = append(ops, dco)
 	}
 
-	if featureSet.needDecode {
+	if t.rawPassthrough && (!featureSet.needDecode || featureSet.needPayloadDecode || featureSet.needRatelimitMerge || pp != nil ||
+		(t.rawFields != nil && sp.StreamDecode)) {
+		t.rawPassthrough = false
+		t.rawFields = nil
+	}
+	if featureSet.needDecode && (!t.rawPassthrough || t.rawFields != nil) {
 		schema := t.streamFields
 		if t.isWildCard {
 			schema = nil
 		}
+		if t.rawPassthrough {
+			// Only decode the fields to filter, the raw payload is sent to the sinks
+			schema = t.rawFields
+		}
 		// Create the decode node
 		decodeNode, err := node.NewDecodeOp(ctx, false, fmt.Sprintf("%d_decoder", index), string(t.streamStmt.Name), options, schema, props)
 		if err != nil {
 			return nil, nil, 0, err
 		}
+		if t.rawPassthrough {
+			if err := decodeNode.EnableRawPassthrough(ctx, props); err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		setParallelism(decodeNode, t.parallel)
+		if !t.streamStmt.Options.SHARED {
+			enableSideOutput(decodeNode, ruleId, options)
+		}
 		index++
 		ops = append(ops, decodeNode)
 	}
@@ -182,13 +224,21 @@ func splitSource(ctx api.StreamContext, t *DataSourcePlan, ss api.Source, option
 		if err != nil {
 			return nil, nil, 0, err
 		}
+		setParallelism(payloadDecodeNode, t.parallel)
+		if !t.streamStmt.Options.SHARED {
+			enableSideOutput(payloadDecodeNode, ruleId, options)
+		}
 		index++
 		ops = append(ops, payloadDecodeNode)
 	}
 
 	// Create the preprocessor node if needed
 	if pp != nil {
-		ops = append(ops, Transform(pp, fmt.Sprintf("%d_preprocessor", index), options))
+		ppNode := Transform(pp, fmt.Sprintf("%d_preprocessor", index), options)
+		if !t.streamStmt.Options.SHARED {
+			enableSideOutput(ppNode, ruleId, options)
+		}
+		ops = append(ops, ppNode)
 		index++
 	}
 
@@ -212,6 +262,8 @@ func splitSource(ctx api.StreamContext, t *DataSourcePlan, ss api.Source, option
 
 type SourcePropsForSplit struct {
 	Decompression string            `json:"decompression"`
+	Decryption    string            `json:"decryption"`
+	DecProps      map[string]any    `json:"decProps"`
 	SelId         string            `json:"connectionSelector"`
 	PayloadFormat string            `json:"payloadFormat"`
 	Interval      cast.DurationConf `json:"interval"`
@@ -219,10 +271,13 @@ type SourcePropsForSplit struct {
 	MergeField string `json:"mergeField"`
 	Merger     string `json:"merger"`
 	Format     string `json:"format"`
+	// StreamDecode decodes the items one by one which cannot keep the raw payload
+	StreamDecode bool `json:"streamDecode"`
 }
 
 type traits struct {
 	needConnection    bool
+	needDecryption    bool
 	needCompression   bool
 	needDecode        bool
 	needPayloadDecode bool
@@ -254,6 +309,7 @@ func checkFeatures(ss api.Source, sp *SourcePropsForSplit, props map[string]any)
 	}
 	r := traits{
 		needConnection:    sp.SelId != "",
+		needDecryption:    sp.Decryption != "" && info.NeedDecode,
 		needCompression:   sp.Decompression != "" && (!info.HasCompress || info.NeedBatchDecode),
 		needDecode:        info.NeedDecode,
 		needPayloadDecode: sp.PayloadFormat != "",
@@ -311,7 +367,10 @@ func checkByteSource(ss api.Source) model.NodeInfo {
 	}
 }
 
-func planLookupSource(ctx api.StreamContext, t *LookupPlan, ruleOption *def.RuleOption) (node.Emitter, error) {
+func planLookupSource(ctx api.StreamContext, t *LookupPlan, ruleOption *def.RuleOption, mockProps map[string]any) (node.Emitter, error) {
+	if mockProps != nil {
+		return planMockLookupSource(ctx, t, ruleOption, mockProps)
+	}
 	si, err := io.LookupSource(t.options.TYPE)
 	if err != nil {
 		return nil, err
@@ -331,3 +390,23 @@ func planLookupSource(ctx api.StreamContext, t *LookupPlan, ruleOption *def.Rule
 	}
 	return nil, fmt.Errorf("lookup source type %s is found but not a valid lookup source", t.options.TYPE)
 }
+
+// planMockLookupSource plans the lookup node with a simulator lookup source of the mock rows owned by the node
+func planMockLookupSource(ctx api.StreamContext, t *LookupPlan, ruleOption *def.RuleOption, mockProps map[string]any) (node.Emitter, error) {
+	si, err := io.LookupSource("simulator")
+	if err != nil {
+		return nil, err
+	}
+	if err = si.Provision(ctx, mockProps); err != nil {
+		return nil, err
+	}
+	if err = si.Connect(ctx, func(string, string) {}); err != nil {
+		return nil, err
+	}
+	n, err := node.NewLookupNode(ctx, t.joinExpr.Name, false, t.fields, t.keys, t.joinExpr.JoinType, t.valvars, t.options, ruleOption, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	n.SetSource(si)
+	return n, nil
+}
@@ -537,7 +537,7 @@ func TestPlanError(t *testing.T) {
 func TestPlanLookup(t *testing.T) {
 	ctx := mockContext.NewMockContext("Test", "test")
 	t.Run("undefined source", func(t *testing.T) {
-		_, err := planLookupSource(ctx, &LookupPlan{options: &ast.Options{TYPE: "none"}}, &def.RuleOption{})
+		_, err := planLookupSource(ctx, &LookupPlan{options: &ast.Options{TYPE: "none"}}, &def.RuleOption{}, nil)
 		assert.Error(t, err)
 		assert.EqualError(t, err, "lookup source type none not found")
 	})
@@ -545,13 +545,13 @@ func TestPlanLookup(t *testing.T) {
 		modules.RegisterLookupSource("mock", func() api.Source {
 			return &MockLookupBytes{}
 		})
-		_, err := planLookupSource(ctx, &LookupPlan{options: &ast.Options{TYPE: "mock"}}, &def.RuleOption{})
+		_, err := planLookupSource(ctx, &LookupPlan{options: &ast.Options{TYPE: "mock"}}, &def.RuleOption{}, nil)
 		assert.Error(t, err)
 		assert.EqualError(t, err, "lookup source type mock must specify format")
 	})
 	t.Run("register wrong source", func(t *testing.T) {
 		modules.RegisterLookupSource("mock", mqtt.GetSource)
-		_, err := planLookupSource(ctx, &LookupPlan{options: &ast.Options{TYPE: "mock"}}, &def.RuleOption{})
+		_, err := planLookupSource(ctx, &LookupPlan{options: &ast.Options{TYPE: "mock"}}, &def.RuleOption{}, nil)
 		assert.Error(t, err)
 		assert.EqualError(t, err, "got non lookup source mock")
 	})
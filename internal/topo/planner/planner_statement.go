@@ -0,0 +1,204 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/operator"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+)
+
+var statementNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// statementPlan is the logical plan of an intermediate statement of the rule
+type statementPlan struct {
+	name    string
+	lp      LogicalPlan
+	streams []string
+}
+
+// statementStore resolves the names of the earlier rule statements as schemaless streams.
+// Other names are resolved by the stream store.
+type statementStore struct {
+	kv.KeyValue
+	statements map[string]string
+}
+
+func (s *statementStore) Get(key string, val any) (bool, error) {
+	if info, ok := s.statements[key]; ok {
+		if v, ok := val.(*string); ok {
+			*v = info
+			return true, nil
+		}
+	}
+	return s.KeyValue.Get(key, val)
+}
+
+func (s *statementStore) add(name string) error {
+	info, err := json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  fmt.Sprintf("CREATE STREAM %s () WITH (DATASOURCE=\"%s\")", name, name),
+	})
+	if err != nil {
+		return err
+	}
+	s.statements[name] = string(info)
+	return nil
+}
+
+// createStatementPlans creates the logical plans of the rule statements in order. It returns the store to resolve
+// the statement names for the rule SQL and all the streams read by the statements.
+func createStatementPlans(rule *def.Rule, store kv.KeyValue) ([]*statementPlan, kv.KeyValue, []string, error) {
+	if len(rule.Statements) == 0 {
+		return nil, store, nil, nil
+	}
+	ss := &statementStore{KeyValue: store, statements: make(map[string]string, len(rule.Statements))}
+	plans := make([]*statementPlan, 0, len(rule.Statements))
+	var streams []string
+	for _, rs := range rule.Statements {
+		if rs == nil || !statementNameRegex.MatchString(rs.Name) {
+			return nil, nil, nil, fmt.Errorf("invalid statement name, it must start with a letter or underscore and only contain letters, digits and underscores")
+		}
+		if _, ok := ss.statements[rs.Name]; ok {
+			return nil, nil, nil, fmt.Errorf("duplicate statement name %s", rs.Name)
+		}
+		if _, err := xsql.GetDataSourceStatement(store, rs.Name); err == nil {
+			return nil, nil, nil, fmt.Errorf("statement name %s conflicts with the existing stream or table", rs.Name)
+		}
+		stmt, err := xsql.GetStatementFromSql(rs.Sql)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid statement %s: %v", rs.Name, err)
+		}
+		if err := validateStmt(stmt); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid statement %s: %v", rs.Name, err)
+		}
+		lp, err := createLogicalPlan(stmt, rule.Options, ss)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("fail to plan statement %s: %v", rs.Name, err)
+		}
+		sp := &statementPlan{name: rs.Name, lp: lp}
+		for _, s := range xsql.GetStreams(stmt) {
+			if _, ok := ss.statements[s]; !ok {
+				sp.streams = append(sp.streams, s)
+			}
+		}
+		streams = append(streams, sp.streams...)
+		plans = append(plans, sp)
+		if err := ss.add(rs.Name); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return plans, ss, streams, nil
+}
+
+// mergeStatementStreams merges the streams of the rule SQL and the statements. Each stream can only be read
+// by one statement because the source nodes of a topology cannot have the same name. The lookup tables have no
+// source node, so they can be shared.
+func mergeStatementStreams(plans []*statementPlan, statementStreams []string, streamsFromStmt []string, store kv.KeyValue) ([]string, error) {
+	if len(plans) == 0 {
+		return streamsFromStmt, nil
+	}
+	names := make(map[string]struct{}, len(plans))
+	for _, sp := range plans {
+		names[sp.name] = struct{}{}
+	}
+	result := make([]string, 0, len(statementStreams)+len(streamsFromStmt))
+	existed := make(map[string]struct{}, len(statementStreams)+len(streamsFromStmt))
+	for _, s := range append(statementStreams, streamsFromStmt...) {
+		if _, ok := names[s]; ok {
+			continue
+		}
+		if _, ok := existed[s]; ok {
+			if st, err := xsql.GetDataSource(store, s); err == nil && st.StreamType == ast.TypeTable && st.Options.KIND == ast.StreamKindLookup {
+				continue
+			}
+			return nil, fmt.Errorf("stream %s is read by multiple statements of the rule, read it in one statement and refer to the statement output instead", s)
+		}
+		existed[s] = struct{}{}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// buildStatementOps builds the operators of the statements into the topology. The output of each statement is bound
+// to the data source plans of the later statements and the rule SQL which read it.
+func buildStatementOps(plans []*statementPlan, lp LogicalPlan, tp *topo.Topo, options *def.RuleOption, sources map[string]map[string]any, streamsFromStmt []string) (int, error) {
+	index := 0
+	outputs := make(map[string]node.Emitter, len(plans))
+	for _, sp := range plans {
+		bindStatementOutputs(sp.lp, outputs)
+		input, ni, err := buildOps(sp.lp, tp, options, sources, streamsFromStmt, index)
+		if err != nil {
+			return 0, err
+		}
+		index = ni + 1
+		op := Transform(&operator.StatementOutputOp{Emitter: sp.name}, fmt.Sprintf("%d_%s_output", index, sp.name), options)
+		tp.AddOperator([]node.Emitter{input}, op)
+		outputs[sp.name] = op
+	}
+	bindStatementOutputs(lp, outputs)
+	return index, nil
+}
+
+func bindStatementOutputs(lp LogicalPlan, outputs map[string]node.Emitter) {
+	if ds, ok := lp.(*DataSourcePlan); ok {
+		if output, ok := outputs[string(ds.name)]; ok {
+			ds.statementOutput = output
+		}
+	}
+	for _, c := range lp.Children() {
+		bindStatementOutputs(c, outputs)
+	}
+}
+
+// GetRuleStreams returns the streams and tables read by the rule SQL and its statements.
+// The names of the rule statements are excluded.
+func GetRuleStreams(rule *def.Rule) ([]string, error) {
+	stmt, err := xsql.GetStatementFromSql(rule.Sql)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{}, len(rule.Statements))
+	var result []string
+	for _, rs := range rule.Statements {
+		if rs == nil {
+			continue
+		}
+		s, err := xsql.GetStatementFromSql(rs.Sql)
+		if err != nil {
+			return nil, fmt.Errorf("invalid statement %s: %v", rs.Name, err)
+		}
+		for _, name := range xsql.GetStreams(s) {
+			if _, ok := names[name]; !ok {
+				result = append(result, name)
+			}
+		}
+		names[rs.Name] = struct{}{}
+	}
+	for _, name := range xsql.GetStreams(stmt) {
+		if _, ok := names[name]; !ok {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
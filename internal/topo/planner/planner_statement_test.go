@@ -0,0 +1,124 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+)
+
+func TestPlanStatements(t *testing.T) {
+	require.NoError(t, prepareStream())
+	r := def.GetDefaultRule("statementRule", "SELECT * FROM s2 WHERE b > 2")
+	r.Statements = []*def.RuleStatement{
+		{Name: "s1", Sql: "SELECT a, b FROM stream WHERE a > 1"},
+		{Name: "s2", Sql: "SELECT a, avg(b) AS b FROM s1 GROUP BY a, TumblingWindow(ss, 10)"},
+	}
+	tp, err := PlanSQLWithSourcesAndSinks(r, nil)
+	require.NoError(t, err)
+	defer tp.Release()
+	assert.Equal(t, []string{"stream"}, tp.GetStreams())
+	edges := tp.GetTopo().Edges
+	findTarget := func(suffix string) []any {
+		for k, v := range edges {
+			if strings.HasSuffix(k, suffix) {
+				return v
+			}
+		}
+		return nil
+	}
+	s1 := findTarget("_s1_output")
+	require.Len(t, s1, 1)
+	assert.True(t, strings.HasSuffix(s1[0].(string), "_window"))
+	s2 := findTarget("_s2_output")
+	require.Len(t, s2, 1)
+	assert.True(t, strings.HasSuffix(s2[0].(string), "_filter"))
+
+	info, err := GetExplainInfoFromLogicalPlan(r)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(info, "Statement s1:\n"))
+	assert.Contains(t, info, "\n\nStatement s2:\n")
+}
+
+func TestPlanStatementsError(t *testing.T) {
+	require.NoError(t, prepareStream())
+	tests := []struct {
+		name       string
+		statements []*def.RuleStatement
+		sql        string
+		err        string
+	}{
+		{
+			name:       "invalid name",
+			statements: []*def.RuleStatement{{Name: "1a", Sql: "SELECT a FROM stream"}},
+			sql:        "SELECT * FROM stream",
+			err:        "invalid statement name, it must start with a letter or underscore and only contain letters, digits and underscores",
+		},
+		{
+			name: "duplicate name",
+			statements: []*def.RuleStatement{
+				{Name: "s1", Sql: "SELECT a FROM stream"},
+				{Name: "s1", Sql: "SELECT a FROM s1"},
+			},
+			sql: "SELECT * FROM s1",
+			err: "duplicate statement name s1",
+		},
+		{
+			name:       "conflict with stream",
+			statements: []*def.RuleStatement{{Name: "sharedStream", Sql: "SELECT a FROM stream"}},
+			sql:        "SELECT * FROM sharedStream",
+			err:        "statement name sharedStream conflicts with the existing stream or table",
+		},
+		{
+			name: "read later statement",
+			statements: []*def.RuleStatement{
+				{Name: "s1", Sql: "SELECT a FROM s2"},
+				{Name: "s2", Sql: "SELECT a FROM stream"},
+			},
+			sql: "SELECT * FROM s1",
+			err: "fail to plan statement s1: fail to get stream s2, please check if stream is created",
+		},
+		{
+			name:       "read stream twice",
+			statements: []*def.RuleStatement{{Name: "s1", Sql: "SELECT a FROM stream"}},
+			sql:        "SELECT * FROM s1 INNER JOIN stream ON s1.a = stream.a",
+			err:        "stream stream is read by multiple statements of the rule, read it in one statement and refer to the statement output instead",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := def.GetDefaultRule("statementErrRule", tt.sql)
+			r.Statements = tt.statements
+			_, err := PlanSQLWithSourcesAndSinks(r, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestGetRuleStreams(t *testing.T) {
+	r := def.GetDefaultRule("statementStreams", "SELECT * FROM s2 INNER JOIN table1 ON s2.a = table1.a")
+	r.Statements = []*def.RuleStatement{
+		{Name: "s1", Sql: "SELECT a FROM demo"},
+		{Name: "s2", Sql: "SELECT a FROM s1 INNER JOIN demo2 ON s1.a = demo2.a"},
+	}
+	streams, err := GetRuleStreams(r)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"demo", "demo2", "table1"}, streams)
+}
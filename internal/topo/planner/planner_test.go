@@ -4609,3 +4609,65 @@ func TestTransformSourceNode(t *testing.T) {
 		})
 	}
 }
+
+func TestLateDataPolicyWindow(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	assert.NoError(t, err)
+	assert.NoError(t, prepareEventTimeStream("eventStream"))
+	opt := &def.RuleOption{IsEventTime: true, LateDataPolicy: def.LateDataUpdate, AllowedLateness: cast.DurationConf(time.Second)}
+	tests := []struct {
+		sql string
+		err string
+	}{
+		{
+			sql: "SELECT count(*) FROM eventStream GROUP BY TumblingWindow(ss, 10)",
+		},
+		{
+			sql: "SELECT count(*) FROM eventStream GROUP BY HoppingWindow(ss, 10, 5)",
+		},
+		{
+			sql: "SELECT count(*) FROM eventStream GROUP BY SessionWindow(ss, 10, 5)",
+			err: "lateDataPolicy update only supports tumbling and hopping windows",
+		},
+		{
+			sql: "SELECT count(*) FROM eventStream GROUP BY SlidingWindow(ss, 10)",
+			err: "lateDataPolicy update only supports tumbling and hopping windows",
+		},
+	}
+	for _, tt := range tests {
+		stmt, err := xsql.NewParser(strings.NewReader(tt.sql)).Parse()
+		assert.NoError(t, err)
+		_, err = createLogicalPlan(stmt, opt, kv)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.sql)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.sql)
+		}
+	}
+	stmt, err := xsql.NewParser(strings.NewReader("SELECT count(*) FROM eventStream GROUP BY TumblingWindow(ss, 10)")).Parse()
+	assert.NoError(t, err)
+	opt.PlanOptimizeStrategy = &def.PlanOptimizeStrategy{EnableIncrementalWindow: true}
+	_, err = createLogicalPlan(stmt, opt, kv)
+	assert.EqualError(t, err, "lateDataPolicy update does not support incremental window")
+}
+
+// prepareEventTimeStream creates the streams with the TIMESTAMP option for the event time rules
+func prepareEventTimeStream(names ...string) error {
+	kv, err := store.GetKV("stream")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		s, err := json.Marshal(&xsql.StreamInfo{
+			StreamType: ast.TypeStream,
+			Statement:  fmt.Sprintf(`CREATE STREAM %s (a BIGINT, b BIGINT, ts BIGINT) WITH (DATASOURCE="%s", TIMESTAMP="ts");`, name, name),
+		})
+		if err != nil {
+			return err
+		}
+		if err = kv.Set(name, string(s)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -22,6 +22,7 @@ import (
 
 type ProjectPlan struct {
 	baseLogicalPlan
+	parallel         *parallelHint
 	isAggregate      bool
 	allWildcard      bool
 	sendMeta         bool
@@ -36,6 +37,8 @@ type ProjectPlan struct {
 	exprFields       ast.Fields
 	enableLimit      bool
 	limitCount       int
+	// rawSource is the source which may pass the raw bytes to the sinks without project
+	rawSource *DataSourcePlan
 }
 
 func (p ProjectPlan) Init() *ProjectPlan {
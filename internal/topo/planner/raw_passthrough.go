@@ -0,0 +1,181 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"strings"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/binder/function"
+	"github.com/lf-edge/ekuiper/v2/internal/binder/io"
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
+	nodeConf "github.com/lf-edge/ekuiper/v2/internal/topo/node/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/message"
+)
+
+// markRawPassthrough detects the pure routing rule like `SELECT * FROM binStream` whose sinks all send the bytes out
+// as is. In that case, the raw bytes are passed from the source to the sinks directly without decode, project and encode.
+// The rule can also filter by the metadata like `SELECT * FROM demo WHERE meta(topic) = "a"` because the metadata is
+// available without decoding the payload. If the rule filters a json payload like `SELECT * FROM demo WHERE temp > 20`,
+// only the fields in the condition are decoded and the raw bytes are kept in the tuple to be sent as is.
+func markRawPassthrough(rule *def.Rule, lp LogicalPlan) {
+	pp, ok := lp.(*ProjectPlan)
+	if !ok || len(pp.Children()) != 1 {
+		return
+	}
+	child := pp.Children()[0]
+	var condition ast.Expr
+	if fp, ok := child.(*FilterPlan); ok {
+		if len(fp.Children()) != 1 {
+			return
+		}
+		condition = fp.condition
+		child = fp.Children()[0]
+	}
+	ds, ok := child.(*DataSourcePlan)
+	if !ok {
+		return
+	}
+	if !isPureWildcard(pp) || !canPassRaw(ds) {
+		return
+	}
+	var rawFields map[string]*ast.JsonStreamField
+	if condition != nil && !isMetaCondition(condition) {
+		// Only the json payload can be partially decoded
+		if rawFormat(ds) != message.FormatJson {
+			return
+		}
+		rawFields, ok = conditionFields(condition)
+		if !ok {
+			return
+		}
+	}
+	if rule.Options.EmitStrategy != nil || rule.Options.Changelog != nil || len(rule.Actions) == 0 {
+		return
+	}
+	format := rawFormat(ds)
+	for _, m := range rule.Actions {
+		for name, action := range m {
+			props, ok := action.(map[string]any)
+			if !ok || !isRawSink(name, props, format) {
+				return
+			}
+		}
+	}
+	ds.rawPassthrough = true
+	ds.rawFields = rawFields
+	pp.rawSource = ds
+}
+
+func isPureWildcard(p *ProjectPlan) bool {
+	if !p.allWildcard || p.isAggregate || p.enableLimit || len(p.fields) != 1 {
+		return false
+	}
+	w, ok := p.fields[0].Expr.(*ast.Wildcard)
+	return ok && p.fields[0].AName == "" && len(w.Except) == 0 && len(w.Replace) == 0
+}
+
+// isMetaCondition checks if the condition only reads the metadata by the meta or mqtt functions
+func isMetaCondition(expr ast.Expr) bool {
+	if expr == nil {
+		return false
+	}
+	result := true
+	ast.WalkFunc(expr, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.Call:
+			if e.Name == "meta" || e.Name == "mqtt" {
+				return false
+			}
+			if e.FuncType != ast.FuncTypeScalar || function.IsAnalyticFunc(e.Name) {
+				result = false
+			}
+		case *ast.FieldRef, *ast.Wildcard, *ast.JsonFieldRef:
+			result = false
+		}
+		return result
+	})
+	return result
+}
+
+// conditionFields returns the fields read by the condition. Return false if the condition needs the whole row or the
+// state across the rows.
+func conditionFields(expr ast.Expr) (map[string]*ast.JsonStreamField, bool) {
+	fields := make(map[string]*ast.JsonStreamField)
+	result := true
+	ast.WalkFunc(expr, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.Call:
+			if e.Name == "meta" || e.Name == "mqtt" {
+				return false
+			}
+			if e.FuncType != ast.FuncTypeScalar || function.IsAnalyticFunc(e.Name) {
+				result = false
+			}
+		case *ast.FieldRef:
+			if e.IsAlias() {
+				result = false
+			} else {
+				fields[e.Name] = nil
+			}
+		case *ast.Wildcard:
+			result = false
+		}
+		return result
+	})
+	return fields, result && len(fields) > 0
+}
+
+func canPassRaw(ds *DataSourcePlan) bool {
+	f := rawFormat(ds)
+	return (f == message.FormatBinary || f == message.FormatJson) && ds.isSchemaless && !ds.iet && ds.statementOutput == nil &&
+		len(ds.colAliasMapping) == 0 && ds.streamStmt.StreamType == ast.TypeStream && !ds.streamStmt.Options.SHARED
+}
+
+func rawFormat(ds *DataSourcePlan) string {
+	if ds.streamStmt.Options.FORMAT == "" {
+		return message.FormatJson
+	}
+	return strings.ToLower(ds.streamStmt.Options.FORMAT)
+}
+
+// isRawSink checks if the sink writes the payload of the format without any transformation
+func isRawSink(name string, props map[string]any, format string) bool {
+	props, err := nodeConf.OverwriteByConnectionConf(name, props)
+	if err != nil {
+		return false
+	}
+	s, _ := io.Sink(name)
+	if _, ok := s.(api.BytesCollector); !ok {
+		return false
+	}
+	sc, err := node.ParseConf(conf.Log, props)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(sc.Format, format) || sc.DataTemplate != "" || len(sc.Fields) > 0 || sc.DataField != "" ||
+		sc.BatchSize > 0 || sc.LingerInterval > 0 {
+		return false
+	}
+	// A json sink sends a list of the rows unless sendSingle is set
+	if format == message.FormatJson && (!sc.SendSingle || sc.Omitempty) {
+		return false
+	}
+	return len(findTemplateProps(props)) == 0
+}
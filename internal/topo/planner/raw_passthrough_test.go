@@ -0,0 +1,158 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+func TestRawPassthrough(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	s, err := json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM binStream () WITH (DATASOURCE="bin", FORMAT="binary")`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("binStream", string(s)))
+	s, err = json.Marshal(&xsql.StreamInfo{
+		StreamType: ast.TypeStream,
+		Statement:  `CREATE STREAM jsonStream () WITH (DATASOURCE="json")`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, kv.Set("jsonStream", string(s)))
+	require.NoError(t, prepareStream())
+
+	binarySink := map[string]any{"nop": map[string]any{"format": "binary"}}
+	tests := []struct {
+		name    string
+		sql     string
+		actions []map[string]any
+		raw     bool
+		// only the fields to filter are decoded
+		lazy bool
+	}{
+		{
+			name:    "pure routing",
+			sql:     "SELECT * FROM binStream",
+			actions: []map[string]any{binarySink, {"nop": map[string]any{"format": "BINARY"}}},
+			raw:     true,
+		},
+		{
+			name:    "filter",
+			sql:     "SELECT * FROM binStream WHERE bytes_len(self) > 2",
+			actions: []map[string]any{binarySink},
+		},
+		{
+			name:    "project",
+			sql:     "SELECT bytes_slice(self, 2) AS self FROM binStream",
+			actions: []map[string]any{binarySink},
+		},
+		{
+			name:    "not binary stream",
+			sql:     "SELECT * FROM stream",
+			actions: []map[string]any{binarySink},
+		},
+		{
+			name:    "json sink",
+			sql:     "SELECT * FROM binStream",
+			actions: []map[string]any{binarySink, {"nop": map[string]any{}}},
+		},
+		{
+			name:    "tuple sink",
+			sql:     "SELECT * FROM binStream",
+			actions: []map[string]any{{"log": map[string]any{}}},
+		},
+		{
+			name:    "dynamic props",
+			sql:     "SELECT * FROM binStream",
+			actions: []map[string]any{{"nop": map[string]any{"format": "binary", "topic": "{{.self}}"}}},
+		},
+		{
+			name:    "batch",
+			sql:     "SELECT * FROM binStream",
+			actions: []map[string]any{{"nop": map[string]any{"format": "binary", "batchSize": 10}}},
+		},
+		{
+			name:    "filter by metadata",
+			sql:     `SELECT * FROM binStream WHERE meta(topic) = "a" OR startswith(mqtt(topic), "b/")`,
+			actions: []map[string]any{binarySink},
+			raw:     true,
+		},
+		{
+			name:    "filter by metadata and payload",
+			sql:     `SELECT * FROM binStream WHERE meta(topic) = "a" AND bytes_len(self) > 2`,
+			actions: []map[string]any{binarySink},
+		},
+		{
+			name:    "json bridging",
+			sql:     `SELECT * FROM jsonStream WHERE meta(topic) = "a"`,
+			actions: []map[string]any{{"nop": map[string]any{"sendSingle": true}}},
+			raw:     true,
+		},
+		{
+			name:    "json filter by payload",
+			sql:     `SELECT * FROM jsonStream WHERE temperature > 20 AND meta(topic) = "a"`,
+			actions: []map[string]any{{"nop": map[string]any{"sendSingle": true}}},
+			raw:     true,
+			lazy:    true,
+		},
+		{
+			name:    "json filter by analytic function",
+			sql:     `SELECT * FROM jsonStream WHERE lag(temperature) > 20`,
+			actions: []map[string]any{{"nop": map[string]any{"sendSingle": true}}},
+		},
+		{
+			name:    "json list",
+			sql:     "SELECT * FROM jsonStream",
+			actions: []map[string]any{{"nop": map[string]any{}}},
+		},
+		{
+			name:    "json to binary",
+			sql:     "SELECT * FROM jsonStream",
+			actions: []map[string]any{binarySink},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := def.GetDefaultRule("rawRule", tt.sql)
+			r.Actions = tt.actions
+			tp, err := PlanSQLWithSourcesAndSinks(r, nil)
+			require.NoError(t, err)
+			defer tp.Release()
+			hasDecoder, hasProject := false, false
+			for k := range tp.GetTopo().Edges {
+				if strings.HasSuffix(k, "_decoder") {
+					hasDecoder = true
+				}
+				if strings.HasSuffix(k, "_project") {
+					hasProject = true
+				}
+			}
+			assert.Equal(t, !tt.raw || tt.lazy, hasDecoder)
+			assert.Equal(t, !tt.raw, hasProject)
+		})
+	}
+}
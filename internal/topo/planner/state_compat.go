@@ -0,0 +1,147 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// operatorSignature describes the configuration of the operator built from the logical plan. The operators with the
+// same signature process the data in the same way, so the state of one can be taken over by the other. The function
+// states are saved by the function id, so the ids of the stateful functions are part of the signature.
+func operatorSignature(lp LogicalPlan) string {
+	var extra string
+	switch t := lp.(type) {
+	case *DataSourcePlan:
+		// The source state such as the offset only depends on the stream
+		return fmt.Sprintf("%s:%s", t.Type(), t.name)
+	case *WindowPlan:
+		extra = fmt.Sprintf("{ interval:%d, delay:%d, timeUnit:%d, eventTime:%t, funcIds:%v }", t.interval, t.delay, t.timeUnit, t.isEventTime, funcIds(t.stateFuncs))
+	case *IncWindowPlan:
+		calls := make([]*ast.Call, 0, len(t.IncAggFuncs))
+		for _, f := range t.IncAggFuncs {
+			ast.WalkFunc(f.Expr, func(n ast.Node) bool {
+				if c, ok := n.(*ast.Call); ok {
+					calls = append(calls, c)
+				}
+				return true
+			})
+		}
+		extra = fmt.Sprintf("{ length:%d, interval:%d, delay:%d, timeUnit:%d, funcIds:%v }", t.Length, t.Interval, t.Delay, t.TimeUnit, funcIds(calls))
+	case *AnalyticFuncsPlan:
+		extra = fmt.Sprintf("{ funcIds:%v, fieldFuncIds:%v }", funcIds(t.funcs), funcIds(t.fieldFuncs))
+	case *FilterPlan:
+		extra = fmt.Sprintf("{ funcIds:%v }", funcIds(t.stateFuncs))
+	case *HavingPlan:
+		extra = fmt.Sprintf("{ funcIds:%v }", funcIds(t.stateFuncs))
+	}
+	lp.BuildExplainInfo()
+	if e, ok := lp.(interface{ explainInfo() string }); ok {
+		return fmt.Sprintf("%s:%s%s", lp.Type(), e.explainInfo(), extra)
+	}
+	return lp.Type() + extra
+}
+
+func funcIds(calls []*ast.Call) []int {
+	ids := make([]int, 0, len(calls))
+	for _, c := range calls {
+		ids = append(ids, c.FuncId)
+	}
+	return ids
+}
+
+func (p *baseLogicalPlan) explainInfo() string {
+	return p.ExplainInfo.Info
+}
+
+// OperatorSignatures plans the sql rule without running it and returns the signatures of its operators by name
+func OperatorSignatures(rule *def.Rule) (map[string]string, error) {
+	if rule.Sql == "" {
+		return nil, fmt.Errorf("only support sql rule")
+	}
+	tp, err := PlanSQLWithSourcesAndSinks(explainRule(rule, false), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tp.Release()
+		tp.RemoveMetrics()
+	}()
+	return tp.GetOpSignatures(), nil
+}
+
+// MatchOperators pairs the operators of the new rule with the unchanged operators of the old rule by their signatures.
+// The result maps the new operator name to the old one. The operator keeps its own name if possible. Otherwise, it
+// takes over the old operator of the same signature in the planned order, which happens when other operators are
+// added or removed ahead of it.
+func MatchOperators(oldSigs, newSigs map[string]string) map[string]string {
+	result := make(map[string]string)
+	used := make(map[string]bool)
+	for name, sig := range newSigs {
+		if oldSigs[name] == sig {
+			result[name] = name
+			used[name] = true
+		}
+	}
+	candidates := make(map[string][]string)
+	for _, name := range sortedOpNames(oldSigs) {
+		if !used[name] {
+			candidates[oldSigs[name]] = append(candidates[oldSigs[name]], name)
+		}
+	}
+	for _, name := range sortedOpNames(newSigs) {
+		if _, ok := result[name]; ok {
+			continue
+		}
+		sig := newSigs[name]
+		if olds := candidates[sig]; len(olds) > 0 {
+			result[name] = olds[0]
+			candidates[sig] = olds[1:]
+		}
+	}
+	return result
+}
+
+// sortedOpNames sorts the operator names like 2_window by the index prefix
+func sortedOpNames(sigs map[string]string) []string {
+	names := make([]string, 0, len(sigs))
+	for name := range sigs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ii, ok1 := opIndex(names[i])
+		ij, ok2 := opIndex(names[j])
+		if ok1 && ok2 && ii != ij {
+			return ii < ij
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func opIndex(name string) (int, bool) {
+	prefix, _, found := strings.Cut(name, "_")
+	if !found {
+		return 0, false
+	}
+	i, err := strconv.Atoi(prefix)
+	return i, err == nil
+}
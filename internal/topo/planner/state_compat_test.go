@@ -0,0 +1,66 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+)
+
+func TestOperatorSignatures(t *testing.T) {
+	require.NoError(t, prepareStream())
+	sigs := func(sql string) map[string]string {
+		r := def.GetDefaultRule("sigRule", sql)
+		r.Actions = []map[string]any{{"nop": map[string]any{}}}
+		result, err := OperatorSignatures(r)
+		require.NoError(t, err)
+		return result
+	}
+	old := sigs("select count(*) from stream group by tumblingwindow(ss, 10)")
+	require.Contains(t, old, "3_window")
+	// Change the projection only
+	assert.Equal(t, map[string]string{"stream": "stream", "2_decoder": "2_decoder", "3_window": "3_window"},
+		MatchOperators(old, sigs("select count(*) as c, avg(a) from stream group by tumblingwindow(ss, 10)")))
+	// Change the window
+	assert.Equal(t, map[string]string{"stream": "stream", "2_decoder": "2_decoder", "4_project": "4_project"},
+		MatchOperators(old, sigs("select count(*) from stream group by tumblingwindow(ss, 20)")))
+	// Add an operator ahead of the window
+	assert.Equal(t, map[string]string{"stream": "stream", "2_decoder": "2_decoder", "4_window": "3_window"},
+		MatchOperators(old, sigs("select lag(a) as l, count(*) from stream group by tumblingwindow(ss, 10)")))
+
+	_, err := OperatorSignatures(&def.Rule{Id: "graphRule"})
+	assert.Error(t, err)
+}
+
+func TestMatchOperators(t *testing.T) {
+	oldSigs := map[string]string{
+		"2_analytic": "a",
+		"3_window":   "w",
+		"9_window":   "w",
+		"10_filter":  "f",
+	}
+	newSigs := map[string]string{
+		"3_window":  "w",
+		"4_window":  "w",
+		"10_window": "w",
+		"11_filter": "f2",
+	}
+	assert.Equal(t, map[string]string{"3_window": "3_window", "4_window": "9_window"}, MatchOperators(oldSigs, newSigs))
+	assert.Empty(t, MatchOperators(nil, newSigs))
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+)
+
+// TableFuncPlan generates rows by the table functions in the FROM clause for each row of the stream
+type TableFuncPlan struct {
+	baseLogicalPlan
+	funcs []*ast.TableFunc
+}
+
+func (p TableFuncPlan) Init() *TableFuncPlan {
+	p.baseLogicalPlan.self = &p
+	p.baseLogicalPlan.setPlanType(TABLEFUNC)
+	return &p
+}
+
+func (p *TableFuncPlan) BuildExplainInfo() {
+	info := "Funcs:[ "
+	for i, f := range p.funcs {
+		info += f.Call.String() + " AS " + f.ColName()
+		if i != len(p.funcs)-1 {
+			info += ", "
+		}
+	}
+	info += " ]"
+	p.baseLogicalPlan.ExplainInfo.Info = info
+}
+
+// PushDownPredicate the condition may refer to the generated columns, so it cannot be pushed down
+func (p *TableFuncPlan) PushDownPredicate(condition ast.Expr) (ast.Expr, LogicalPlan) {
+	return condition, p
+}
+
+// PruneColumns removes the generated columns which do not exist in the stream and adds the fields used by the function arguments
+func (p *TableFuncPlan) PruneColumns(fields []ast.Expr) error {
+	cols := make(map[string]struct{}, len(p.funcs))
+	for _, f := range p.funcs {
+		cols[f.ColName()] = struct{}{}
+	}
+	result := make([]ast.Expr, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := cols[rootFieldName(field)]; ok {
+			continue
+		}
+		result = append(result, field)
+	}
+	for _, f := range p.funcs {
+		result = append(result, getFields(f.Call)...)
+	}
+	return p.baseLogicalPlan.PruneColumns(result)
+}
+
+func getTableFuncs(stmt *ast.SelectStatement) []*ast.TableFunc {
+	var result []*ast.TableFunc
+	for _, s := range stmt.Sources {
+		if tf, ok := s.(*ast.TableFunc); ok {
+			result = append(result, tf)
+		}
+	}
+	return result
+}
+
+// rootFieldName returns the name of the field ref or the root field ref of the arrow expression such as a->b->c
+func rootFieldName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.FieldRef:
+		return e.Name
+	case *ast.BinaryExpr:
+		if e.OP == ast.ARROW {
+			return rootFieldName(e.LHS)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+)
+
+func TestTableFuncPlan(t *testing.T) {
+	kv, err := store.GetKV("stream")
+	require.NoError(t, err)
+	require.NoError(t, prepareStream())
+	stmt, err := xsql.NewParser(strings.NewReader(`SELECT s, s->x FROM stream, generate_series(1, b) AS s WHERE s > 1`)).Parse()
+	require.NoError(t, err)
+	lp, err := createLogicalPlan(stmt, &def.RuleOption{}, kv)
+	require.NoError(t, err)
+	var (
+		tp *TableFuncPlan
+		ds *DataSourcePlan
+	)
+	var walk func(p LogicalPlan)
+	walk = func(p LogicalPlan) {
+		switch pt := p.(type) {
+		case *TableFuncPlan:
+			tp = pt
+		case *DataSourcePlan:
+			ds = pt
+		}
+		for _, c := range p.Children() {
+			walk(c)
+		}
+	}
+	walk(lp)
+	require.NotNil(t, tp)
+	require.Len(t, tp.Children(), 1)
+	assert.Equal(t, ds, tp.Children()[0])
+	assert.Equal(t, "s", tp.funcs[0].ColName())
+	// The generated column is not read from the stream while the field of the function argument is kept
+	assert.Len(t, ds.streamFields, 1)
+	assert.Contains(t, ds.streamFields, "b")
+
+	stmt, err = xsql.NewParser(strings.NewReader(`SELECT * FROM stream, generate_series(1, 3) LEFT JOIN sharedStream ON stream.a = sharedStream.a GROUP BY CountWindow(2)`)).Parse()
+	require.NoError(t, err)
+	_, err = createLogicalPlan(stmt, &def.RuleOption{}, kv)
+	assert.EqualError(t, err, "table function cannot be used with join")
+}
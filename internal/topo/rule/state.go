@@ -30,10 +30,15 @@ import (
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/ruleparam"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/schedule"
 	"github.com/lf-edge/ekuiper/v2/internal/topo"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
 	kctx "github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
@@ -92,6 +97,8 @@ type State struct {
 	lastStopTimestamp  int64
 	lastWill           string
 	stoppedMetrics     []any
+	// the summary of the last run which finishes the bounded inputs in batch mode
+	batchSummary map[string]any
 }
 
 // NewState provision a state instance only.
@@ -182,6 +189,12 @@ func (s *State) GetStatusMessage() string {
 	result.WriteString(`"nextStartTimestamp": `)
 	result.WriteString(strconv.FormatInt(nextStartTimestamp, 10))
 	result.WriteString(`,`)
+	if s.batchSummary != nil {
+		bs, _ := json.Marshal(s.batchSummary)
+		result.WriteString(`"batchSummary": `)
+		result.Write(bs)
+		result.WriteString(`,`)
+	}
 	// Compose metrics
 	var (
 		keys   []string
@@ -231,6 +244,9 @@ func (s *State) GetStatusMap() map[string]any {
 	result["lastStopTimestamp"] = s.lastStopTimestamp
 	nextStartTimestamp := s.Rule.GetNextScheduleStartTime()
 	result["nextStartTimestamp"] = nextStartTimestamp
+	if s.batchSummary != nil {
+		result["batchSummary"] = s.batchSummary
+	}
 	// Compose metrics
 	var (
 		keys   []string
@@ -399,7 +415,8 @@ func (s *State) ScheduleStop() {
 	s.logger.Infof("schedule to stop rule %s", s.Rule.Id)
 	err := s.doStop()
 	// currentState may be accessed concurrently
-	if schedule.IsAfterTimeRanges(timex.GetNow(), s.Rule.Options.CronDatetimeRange) {
+	loc, _ := schedule.LoadLocation(s.Rule.Options.CronTimezone)
+	if schedule.IsAfterTimeRangesInLocation(timex.GetNow(), s.Rule.Options.CronDatetimeRange, loc) {
 		s.transit(ScheduledStop, errors.New("schedule terminated"))
 	} else {
 		s.transit(ScheduledStop, err)
@@ -444,10 +461,12 @@ func (s *State) doStart() error {
 				s.topoGraph = s.topology.GetTopo()
 			}
 		}
+		ruleparam.Set(s.Rule.Id, s.Rule.Params)
 		ctx, cancel := context.WithCancel(context.Background())
 		s.cancelRetry = cancel
 		s.lastStartTimestamp = timex.GetNowInMilli()
 		s.lastWill = ""
+		s.batchSummary = nil
 		go s.runTopo(ctx, s.topology, s.Rule.Options.RestartStrategy)
 		return nil
 	})
@@ -473,11 +492,12 @@ func (s *State) doStop() error {
 
 // This is called async
 func (s *State) runTopo(ctx context.Context, tp *topo.Topo, rs *def.RestartStrategy) {
+	finished := false
 	err := infra.SafeRun(func() error {
-		count := 0
-		d := time.Duration(rs.Delay)
+		// The restart counts of each policy. The nil key is for the default strategy
+		counts := make(map[*def.RestartPolicy]int)
 		var er error
-		ticker := time.NewTicker(d)
+		ticker := time.NewTicker(time.Duration(rs.Delay))
 		defer ticker.Stop()
 		for {
 			select {
@@ -489,7 +509,12 @@ func (s *State) runTopo(ctx context.Context, tp *topo.Topo, rs *def.RestartStrat
 					tp.Cancel()
 				} else { // exit normally
 					if errorx.IsEOF(er) {
-						s.lastWill = "done"
+						if s.Rule.Options.Mode == def.RuleModeBatch {
+							s.lastWill = "finished"
+							finished = true
+						} else {
+							s.lastWill = "done"
+						}
 					}
 					tp.Cancel()
 					return nil
@@ -497,19 +522,34 @@ func (s *State) runTopo(ctx context.Context, tp *topo.Topo, rs *def.RestartStrat
 				// Although it is stopped, it is still retrying, so the status is still RUNNING
 				s.lastWill = "retrying after error: " + er.Error()
 			}
-			if count < rs.Attempts {
-				if d > time.Duration(rs.MaxDelay) {
-					d = time.Duration(rs.MaxDelay)
+			class := tp.ErrorClass(er)
+			policy := matchRestartPolicy(rs, class, er)
+			attempts, delay := restartLimit(rs, policy)
+			count := counts[policy]
+			if attempts < 0 || count < attempts {
+				d := delay
+				if rs.Multiplier > 0 {
+					d = time.Duration(math.Min(float64(delay)*math.Pow(rs.Multiplier, float64(count)), float64(math.MaxInt64)))
+				}
+				maxDelay := time.Duration(rs.MaxDelay)
+				if maxDelay < delay {
+					maxDelay = delay
+				}
+				if d > maxDelay {
+					d = maxDelay
 				}
 				if rs.JitterFactor > 0 {
 					d = time.Duration(math.Round(float64(d.Milliseconds())*((rand.Float64()*2-1)*rs.JitterFactor+1))) * time.Millisecond
 					// make sure d is always in range
-					for d <= 0 || d > time.Duration(rs.MaxDelay) {
+					for d <= 0 || d > maxDelay {
 						d = time.Duration(math.Round(float64(d.Milliseconds())*((rand.Float64()*2-1)*rs.JitterFactor+1))) * time.Millisecond
 					}
-					s.logger.Infof("Rule will restart with jitterred delay %d", d)
+					s.logger.Infof("Rule will restart for %s error with jitterred delay %d", class, d)
 				} else {
-					s.logger.Infof("Rule will restart with delay %d", d)
+					s.logger.Infof("Rule will restart for %s error with delay %d", class, d)
+				}
+				if d > 0 {
+					ticker.Reset(d)
 				}
 				// retry after delay
 				select {
@@ -519,11 +559,11 @@ func (s *State) runTopo(ctx context.Context, tp *topo.Topo, rs *def.RestartStrat
 					s.logger.Errorf("stop Rule retry as cancelled")
 					return nil
 				}
-				count++
-				if rs.Multiplier > 0 {
-					d = time.Duration(rs.Delay) * time.Duration(math.Pow(rs.Multiplier, float64(count)))
-				}
+				counts[policy] = count + 1
 			} else {
+				if policy != nil && policy.AlertTopic != "" {
+					s.alert(policy.AlertTopic, class, er)
+				}
 				return er
 			}
 		}
@@ -533,6 +573,9 @@ func (s *State) runTopo(ctx context.Context, tp *topo.Topo, rs *def.RestartStrat
 		keys, values := s.topology.GetMetrics()
 		s.stoppedMetrics = []any{keys, values}
 		s.topology = nil
+		if finished {
+			s.batchSummary = summarizeBatch(keys, values, s.lastStartTimestamp, timex.GetNowInMilli())
+		}
 	}
 	if err != nil { // Exit after retries
 		s.logger.Error(err)
@@ -542,8 +585,91 @@ func (s *State) runTopo(ctx context.Context, tp *topo.Topo, rs *def.RestartStrat
 	}
 }
 
+// summarizeBatch sums up the metrics of a finished batch run
+func summarizeBatch(keys []string, values []any, start, end int64) map[string]any {
+	var recordsIn, recordsOut, exceptions int64
+	for i, key := range keys {
+		v, err := cast.ToInt64(values[i], cast.CONVERT_SAMEKIND)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(key, "source_") && strings.HasSuffix(key, "_"+metric.RecordsInTotal):
+			recordsIn += v
+		case strings.HasPrefix(key, "sink_") && strings.HasSuffix(key, "_"+metric.RecordsOutTotal):
+			recordsOut += v
+		case strings.HasSuffix(key, "_"+metric.ExceptionsTotal):
+			exceptions += v
+		}
+	}
+	return map[string]any{
+		"recordsIn":      recordsIn,
+		"recordsOut":     recordsOut,
+		"exceptions":     exceptions,
+		"startTimestamp": start,
+		"endTimestamp":   end,
+		"durationMs":     end - start,
+	}
+}
+
+// matchRestartPolicy returns the first policy matching the error. It returns nil to use the default strategy.
+func matchRestartPolicy(rs *def.RestartStrategy, class string, err error) *def.RestartPolicy {
+	for _, p := range rs.Policies {
+		if p.Matches(class, err) {
+			return p
+		}
+	}
+	return nil
+}
+
+// restartLimit returns the max restart attempts and the base delay of the policy. The negative attempts means no limit.
+func restartLimit(rs *def.RestartStrategy, p *def.RestartPolicy) (int, time.Duration) {
+	attempts, delay := rs.Attempts, time.Duration(rs.Delay)
+	if p == nil {
+		return attempts, delay
+	}
+	if p.Delay > 0 {
+		delay = time.Duration(p.Delay)
+	}
+	switch p.Action {
+	case def.RestartActionRetry:
+		attempts = -1
+	case def.RestartActionStop:
+		attempts = 0
+	default:
+		if p.Attempts > 0 {
+			attempts = p.Attempts
+		}
+	}
+	return attempts, delay
+}
+
+// alert publishes the error which stops the rule to the memory topic
+func (s *State) alert(topic, class string, err error) {
+	pubsub.CreatePub(topic)
+	defer pubsub.RemovePub(topic)
+	pubsub.ProduceAny(kctx.Background(), topic, map[string]any{
+		"ruleId":     s.Rule.Id,
+		"errorClass": class,
+		"error":      err.Error(),
+		"timestamp":  timex.GetNowInMilli(),
+	})
+	s.logger.Infof("Rule stopped for %s error, alert is sent to topic %s", class, topic)
+}
+
 // Other APIs
 
+// InspectState summarizes the state of the operators of the running rule
+func (s *State) InspectState(op string, limit int, timeout time.Duration) ([]*node.StateSummary, error) {
+	s.RLock()
+	tp := s.topology
+	s.RUnlock()
+	if tp == nil {
+		return nil, fmt.Errorf("rule %s is not running", s.Rule.Id)
+	}
+	return tp.InspectState(op, limit, timeout)
+}
+
 func (s *State) GetTopoGraph() *def.PrintableTopo {
 	s.RLock()
 	defer s.RUnlock()
@@ -583,6 +709,7 @@ func (s *State) Delete() (err error) {
 	}()
 	s.Lock()
 	defer s.Unlock()
+	ruleparam.Delete(s.Rule.Id)
 	if s.topology != nil {
 		s.topology.RemoveMetrics()
 		s.topology.Cancel()
@@ -591,6 +718,20 @@ func (s *State) Delete() (err error) {
 	return nil
 }
 
+// UpdateParams replaces the params of the rule. The running rule will read the new params without restarting.
+func (s *State) UpdateParams(params map[string]any) {
+	s.Lock()
+	defer s.Unlock()
+	s.Rule.Params = params
+	ruleparam.Set(s.Rule.Id, params)
+}
+
+func (s *State) GetParams() map[string]any {
+	s.RLock()
+	defer s.RUnlock()
+	return s.Rule.Params
+}
+
 func (s *State) GetMetrics() ([]string, []any) {
 	s.RLock()
 	defer s.RUnlock()
@@ -609,6 +750,20 @@ func (s *State) GetStreams() []string {
 	return nil
 }
 
+// TriggerCheckpoint triggers a checkpoint of the running rule with qos >= 1 and waits until it is completed
+func (s *State) TriggerCheckpoint(timeout time.Duration) (int64, error) {
+	s.RLock()
+	var c *checkpoint.Coordinator
+	if s.topology != nil && s.currentState == Running {
+		c = s.topology.GetCoordinator()
+	}
+	s.RUnlock()
+	if c == nil {
+		return 0, fmt.Errorf("rule %s is not running with checkpoint", s.Rule.Id)
+	}
+	return c.TriggerCheckpoint(timeout)
+}
+
 func (s *State) GetLastWill() string {
 	s.RLock()
 	defer s.RUnlock()
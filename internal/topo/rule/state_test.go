@@ -15,6 +15,7 @@
 package rule
 
 import (
+	"errors"
 	"regexp"
 	"sync"
 	"testing"
@@ -22,10 +23,14 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/schedule"
 	"github.com/lf-edge/ekuiper/v2/internal/processor"
 	"github.com/lf-edge/ekuiper/v2/internal/testx"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
 
@@ -269,3 +274,99 @@ func sendAction(st *State, a ActionSignal) {
 func TestRuleRestart(t *testing.T) {
 	// TODO added later
 }
+
+func TestRestartPolicy(t *testing.T) {
+	sp := processor.NewStreamProcessor()
+	_, err := sp.ExecStmt(`CREATE STREAM demoPolicy () WITH (FORMAT="JSON", TYPE="memory", DATASOURCE="test")`)
+	assert.NoError(t, err)
+	defer sp.ExecStmt(`DROP STREAM demoPolicy`)
+	tp, err := planner.Plan(def.GetDefaultRule("testPolicy", "select * from demoPolicy"))
+	assert.NoError(t, err)
+	defer tp.Cancel()
+	assert.Equal(t, def.ErrorClassSource, tp.ErrorClass(errorx.NewNodeError("demoPolicy", errors.New("connection lost"))))
+	assert.Equal(t, def.ErrorClassOperator, tp.ErrorClass(errorx.NewNodeError("2_project", errors.New("runtime error"))))
+	assert.Equal(t, def.ErrorClassSink, tp.ErrorClass(errorx.NewNodeError("logToMemory_0_1_encode", errors.New("encode error"))))
+	assert.Equal(t, def.ErrorClassSink, tp.ErrorClass(errorx.NewNodeError("logToMemory_0", errors.New("connect error"))))
+	assert.Equal(t, def.ErrorClassOther, tp.ErrorClass(errors.New("checkpoint error")))
+
+	rs := &def.RestartStrategy{
+		Attempts: 3,
+		Delay:    cast.DurationConf(time.Second),
+		Policies: []*def.RestartPolicy{
+			{ErrorClass: def.ErrorClassSource, Action: def.RestartActionRetry, Delay: cast.DurationConf(5 * time.Second)},
+			{ErrorClass: def.ErrorClassSink, Match: `status code 4\d\d`, Action: def.RestartActionStop, AlertTopic: "alert"},
+			{ErrorClass: def.ErrorClassAny, Match: "timeout", Action: def.RestartActionRestart, Attempts: 10},
+		},
+	}
+	tests := []struct {
+		class    string
+		err      error
+		policy   int
+		attempts int
+		delay    time.Duration
+	}{
+		{def.ErrorClassSource, errors.New("connection lost"), 0, -1, 5 * time.Second},
+		{def.ErrorClassSink, errors.New("status code 404"), 1, 0, time.Second},
+		{def.ErrorClassSink, errors.New("status code 500"), -1, 3, time.Second},
+		{def.ErrorClassOperator, errors.New("timeout"), 2, 10, time.Second},
+		{def.ErrorClassOperator, errors.New("invalid type"), -1, 3, time.Second},
+	}
+	for _, tt := range tests {
+		p := matchRestartPolicy(rs, tt.class, tt.err)
+		if tt.policy < 0 {
+			assert.Nil(t, p)
+		} else {
+			assert.Equal(t, rs.Policies[tt.policy], p)
+		}
+		attempts, delay := restartLimit(rs, p)
+		assert.Equal(t, tt.attempts, attempts)
+		assert.Equal(t, tt.delay, delay)
+	}
+}
+
+func TestRestartAlert(t *testing.T) {
+	alerts := pubsub.CreateSub("ruleAlert", nil, "testAlert", 10)
+	defer pubsub.CloseSourceConsumerChannel("ruleAlert", "testAlert")
+	st := NewState(def.GetDefaultRule("testAlert", "select * from demo"))
+	st.alert("ruleAlert", def.ErrorClassSink, errors.New("status code 404"))
+	select {
+	case v := <-alerts:
+		m, ok := v.(map[string]any)
+		assert.True(t, ok)
+		assert.Equal(t, "testAlert", m["ruleId"])
+		assert.Equal(t, def.ErrorClassSink, m["errorClass"])
+		assert.Equal(t, "status code 404", m["error"])
+	case <-time.After(time.Second):
+		assert.Fail(t, "should receive alert")
+	}
+}
+
+func TestSummarizeBatch(t *testing.T) {
+	keys := []string{"source_demo_0_records_in_total", "source_demo_0_exceptions_total", "op_2_project_0_records_in_total", "op_2_project_0_exceptions_total", "sink_log_0_0_records_out_total", "sink_log_0_0_last_exception"}
+	values := []any{int64(10), int64(1), int64(10), int64(2), int64(8), "error"}
+	summary := summarizeBatch(keys, values, 1000, 3500)
+	assert.Equal(t, map[string]any{
+		"recordsIn":      int64(10),
+		"recordsOut":     int64(8),
+		"exceptions":     int64(3),
+		"startTimestamp": int64(1000),
+		"endTimestamp":   int64(3500),
+		"durationMs":     int64(2500),
+	}, summary)
+	st := NewState(def.GetDefaultRule("testBatch", "select * from demo"))
+	st.lastWill = "finished"
+	st.batchSummary = summary
+	assert.Equal(t, summary, st.GetStatusMap()["batchSummary"])
+	assert.Equal(t, "{\n  \"status\": \"stopped\",\n  \"message\": \"finished\",\n  \"lastStartTimestamp\": 0,\n  \"lastStopTimestamp\": 0,\n  \"nextStartTimestamp\": 0,\n  \"batchSummary\": {\n    \"durationMs\": 2500,\n    \"endTimestamp\": 3500,\n    \"exceptions\": 3,\n    \"recordsIn\": 10,\n    \"recordsOut\": 8,\n    \"startTimestamp\": 1000\n  }\n}", st.GetStatusMessage())
+}
+
+func TestBatchModeUnsupported(t *testing.T) {
+	sp := processor.NewStreamProcessor()
+	_, err := sp.ExecStmt(`CREATE STREAM batchDemo () WITH (FORMAT="JSON", TYPE="memory", DATASOURCE="test")`)
+	assert.NoError(t, err)
+	defer sp.ExecStmt(`DROP STREAM batchDemo`)
+	r := def.GetDefaultRule("testBatchUnsupported", "select * from batchDemo")
+	r.Options.Mode = def.RuleModeBatch
+	_, err = planner.Plan(r)
+	assert.EqualError(t, err, "source batchDemo does not support the batch mode")
+}
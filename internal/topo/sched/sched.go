@@ -0,0 +1,184 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sched shares the cpu among the scheduling classes of the rules. The operators of a rule with a class are
+// admitted to process an item only if the class has budget in the current period. The budget of a class is the cpu
+// capacity minus the usage of the higher priority classes in the previous period, bounded by the cpu limit of the
+// class. So the latency-critical rules keep running under load while the bulk rules use the leftovers.
+// The usage is the processing time of the operators, so the cpu limit is a soft limit in the same sense as the cgroup
+// cpu quota.
+package sched
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/cgroup"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const (
+	// The period to account the usage like the cgroup cpu period
+	period = 100 * time.Millisecond
+	// The min cores of a class so that it is not starved by the higher priority classes
+	minShare = 0.05
+)
+
+// Class is a scheduling class. All the rules of the class share its budget.
+type Class struct {
+	name     string
+	priority int
+	limit    float64
+	s        *scheduler
+	// usage is the processing time in the current period and prev is in the previous period
+	usage time.Duration
+	prev  time.Duration
+}
+
+type scheduler struct {
+	mu   sync.Mutex
+	cpus float64
+	// classes are sorted by priority descending
+	classes     []*Class
+	byName      map[string]*Class
+	periodStart time.Time
+}
+
+var (
+	global *scheduler
+	once   sync.Once
+)
+
+// Get returns the class defined in the global configuration
+func Get(name string) (*Class, error) {
+	once.Do(func() {
+		global = newScheduler(conf.Config.Basic.Scheduling)
+	})
+	c, ok := global.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("scheduling class %s is not defined", name)
+	}
+	return c, nil
+}
+
+func newScheduler(c conf.SchedulingConf) *scheduler {
+	s := &scheduler{
+		cpus:   c.Cpus,
+		byName: make(map[string]*Class, len(c.Classes)),
+	}
+	if s.cpus <= 0 {
+		s.cpus = availableCpus()
+	}
+	for name, cc := range c.Classes {
+		cl := &Class{name: name, priority: cc.Priority, limit: cc.CpuLimit, s: s}
+		s.classes = append(s.classes, cl)
+		s.byName[name] = cl
+	}
+	sort.Slice(s.classes, func(i, j int) bool {
+		return s.classes[i].priority > s.classes[j].priority
+	})
+	conf.Log.Infof("scheduler shares %.2f cpus among %d classes", s.cpus, len(s.classes))
+	return s
+}
+
+// availableCpus returns the cgroup cpu quota in the container or the count of the cpus
+func availableCpus() float64 {
+	cpus := float64(runtime.NumCPU())
+	if cgroup.InContainer() {
+		q, err := cgroup.CPUQuotaCGroup()
+		if err != nil {
+			conf.Log.Warnf("get cgroup cpu quota failed, err:%v", err)
+		} else if q > 0 && q < cpus {
+			cpus = q
+		}
+	}
+	return cpus
+}
+
+// Admit waits until the class has budget to process
+func (c *Class) Admit(ctx api.StreamContext) {
+	for {
+		wait := c.s.admit(c, timex.GetNow())
+		if wait <= 0 {
+			return
+		}
+		timer := timex.GetTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Charge adds the processing time to the usage of the class
+func (c *Class) Charge(d time.Duration) {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	c.s.roll(timex.GetNow())
+	c.usage += d
+}
+
+// admit returns 0 if the class has budget, otherwise the duration to the next period
+func (s *scheduler) admit(c *Class, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(now)
+	if c.usage < s.budget(c) {
+		return 0
+	}
+	return s.periodStart.Add(period).Sub(now)
+}
+
+// roll starts a new period if the current one is over
+func (s *scheduler) roll(now time.Time) {
+	elapsed := now.Sub(s.periodStart)
+	if elapsed < period {
+		return
+	}
+	for _, c := range s.classes {
+		if elapsed < 2*period {
+			c.prev = c.usage
+		} else {
+			// Idle in the previous period
+			c.prev = 0
+		}
+		c.usage = 0
+	}
+	s.periodStart = now.Truncate(period)
+}
+
+// budget returns the processing time the class can use in the current period
+func (s *scheduler) budget(c *Class) time.Duration {
+	avail := s.cpus
+	for _, h := range s.classes {
+		if h.priority <= c.priority {
+			break
+		}
+		avail -= h.prev.Seconds() / period.Seconds()
+	}
+	avail = math.Max(avail, minShare)
+	if c.limit > 0 {
+		avail = math.Min(avail, c.limit)
+	}
+	return time.Duration(avail * float64(period))
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sched
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/topotest/mockclock"
+	mockContext "github.com/lf-edge/ekuiper/v2/pkg/mock/context"
+)
+
+func TestBudget(t *testing.T) {
+	s := newScheduler(conf.SchedulingConf{
+		Cpus: 2,
+		Classes: map[string]*conf.SchedulingClass{
+			"control": {Priority: 100},
+			"default": {Priority: 50, CpuLimit: 1.5},
+			"bulk":    {Priority: 0, CpuLimit: 0.5},
+		},
+	})
+	control, def, bulk := s.byName["control"], s.byName["default"], s.byName["bulk"]
+	// Idle, each class is bounded by its limit
+	assert.Equal(t, 200*time.Millisecond, s.budget(control))
+	assert.Equal(t, 150*time.Millisecond, s.budget(def))
+	assert.Equal(t, 50*time.Millisecond, s.budget(bulk))
+	// The control class used 1.2 cpus in the previous period
+	control.prev = 120 * time.Millisecond
+	assert.Equal(t, 200*time.Millisecond, s.budget(control))
+	assert.Equal(t, 80*time.Millisecond, s.budget(def))
+	assert.Equal(t, 50*time.Millisecond, s.budget(bulk))
+	// The higher classes use all the cpus, the bulk class gets the min share
+	def.prev = 80 * time.Millisecond
+	assert.Equal(t, 5*time.Millisecond, s.budget(bulk))
+}
+
+func TestAdmit(t *testing.T) {
+	mc := mockclock.GetMockClock()
+	s := newScheduler(conf.SchedulingConf{
+		Cpus: 1,
+		Classes: map[string]*conf.SchedulingClass{
+			"control": {Priority: 100},
+			"bulk":    {Priority: 0},
+		},
+	})
+	control, bulk := s.byName["control"], s.byName["bulk"]
+	ctx, cancel := mockContext.NewMockContext("TestAdmit", "op").WithCancel()
+	defer cancel()
+	// Start a new period
+	mc.Add(period - mc.Now().Sub(mc.Now().Truncate(period)))
+	bulk.Admit(ctx)
+	control.Admit(ctx)
+	control.Charge(90 * time.Millisecond)
+	bulk.Charge(10 * time.Millisecond)
+	mc.Add(period)
+	// The control class used 0.9 cpu, the bulk class gets 0.1
+	bulk.Admit(ctx)
+	bulk.Charge(10 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		bulk.Admit(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+		require.Fail(t, "bulk should wait for the next period")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// The control class is not throttled
+	control.Admit(ctx)
+	require.Eventually(t, func() bool {
+		mc.Add(10 * time.Millisecond)
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 5*time.Millisecond)
+}
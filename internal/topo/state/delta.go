@@ -0,0 +1,165 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store/encoding"
+	"github.com/lf-edge/ekuiper/v2/pkg/kv"
+)
+
+const (
+	// DefaultFullSnapshotEvery is the default count of checkpoints to save a full snapshot
+	DefaultFullSnapshotEvery = 10
+	// deltaKey marks a checkpoint which only has the changes since the previous checkpoint. A checkpoint without it is
+	// a full snapshot of the operator states.
+	deltaKey = "$$delta"
+)
+
+// digestState returns the digest of the value of each key of each operator. It returns nil if the state of an
+// operator is not a map, then the delta cannot be calculated.
+func digestState(state map[string]interface{}) (map[string]map[string]uint64, error) {
+	result := make(map[string]map[string]uint64, len(state))
+	for op, v := range state {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		ds := make(map[string]uint64, len(m))
+		for k, val := range m {
+			if val == nil {
+				ds[k] = 0
+				continue
+			}
+			b, err := encoding.Encode(val)
+			if err != nil {
+				return nil, fmt.Errorf("encode state %s of %s error: %v", k, op, err)
+			}
+			h := fnv.New64a()
+			_, _ = h.Write(b)
+			ds[k] = h.Sum64()
+		}
+		result[op] = ds
+	}
+	return result, nil
+}
+
+// diffState returns the delta of the state since the previous checkpoint. The changed keys are in set and the removed
+// keys are in del, both are grouped by the operators.
+func diffState(state map[string]interface{}, digests, prevDigests map[string]map[string]uint64, prev int64) map[string]interface{} {
+	set := make(map[string]interface{})
+	del := make(map[string]interface{})
+	for op, ds := range digests {
+		m := state[op].(map[string]interface{})
+		old := prevDigests[op]
+		changed := make(map[string]interface{})
+		for k, d := range ds {
+			if od, ok := old[k]; !ok || od != d {
+				changed[k] = m[k]
+			}
+		}
+		if len(changed) > 0 {
+			set[op] = changed
+		}
+		removed := make(map[string]interface{})
+		for k := range old {
+			if _, ok := ds[k]; !ok {
+				removed[k] = true
+			}
+		}
+		if len(removed) > 0 {
+			del[op] = removed
+		}
+	}
+	for op, old := range prevDigests {
+		if _, ok := digests[op]; ok {
+			continue
+		}
+		removed := make(map[string]interface{}, len(old))
+		for k := range old {
+			removed[k] = true
+		}
+		del[op] = removed
+	}
+	return map[string]interface{}{"prev": prev, "set": set, "del": del}
+}
+
+// LoadCheckpoint returns the id and the operator states of the latest checkpoint. The states are rebuilt by applying
+// the deltas to the last full snapshot.
+func LoadCheckpoint(db kv.Tskv) (int64, map[string]interface{}, error) {
+	k, _, m, err := loadCheckpoint(db)
+	return k, m, err
+}
+
+// loadCheckpoint also returns the id of the full snapshot which the latest checkpoint is based on
+func loadCheckpoint(db kv.Tskv) (int64, int64, map[string]interface{}, error) {
+	var last map[string]interface{}
+	k, err := db.Last(&last)
+	if err != nil || k <= 0 {
+		return k, k, last, err
+	}
+	var deltas []map[string]interface{}
+	base, rec := k, last
+	for {
+		d, ok := rec[deltaKey].(map[string]interface{})
+		if !ok {
+			break
+		}
+		deltas = append(deltas, d)
+		prev, _ := d["prev"].(int64)
+		if prev <= 0 || prev >= base {
+			return 0, 0, nil, fmt.Errorf("invalid previous checkpoint %d of checkpoint %d", prev, base)
+		}
+		var r map[string]interface{}
+		found, err := db.Get(prev, &r)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if !found {
+			return 0, 0, nil, fmt.Errorf("previous checkpoint %d of checkpoint %d is not found", prev, base)
+		}
+		base, rec = prev, r
+	}
+	for i := len(deltas) - 1; i >= 0; i-- {
+		applyDelta(rec, deltas[i])
+	}
+	return k, base, rec, nil
+}
+
+func applyDelta(state, delta map[string]interface{}) {
+	set, _ := delta["set"].(map[string]interface{})
+	for op, v := range set {
+		changed, _ := v.(map[string]interface{})
+		m, ok := state[op].(map[string]interface{})
+		if !ok {
+			m = make(map[string]interface{}, len(changed))
+			state[op] = m
+		}
+		for k, val := range changed {
+			m[k] = val
+		}
+	}
+	del, _ := delta["del"].(map[string]interface{})
+	for op, v := range del {
+		removed, _ := v.(map[string]interface{})
+		if m, ok := state[op].(map[string]interface{}); ok {
+			for k := range removed {
+				delete(m, k)
+			}
+		}
+	}
+}
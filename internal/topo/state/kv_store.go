@@ -38,12 +38,23 @@ func init() {
 // mapStore keys
 //
 //	{ "checkpoint1", "checkpoint2" ... "checkpointn" : The complete or incomplete snapshot
+//
+// To avoid rewriting the large states such as the window inputs in every checkpoint, only the changed keys of the
+// operators since the last checkpoint are saved. A full snapshot is saved every fullEvery checkpoints, and the
+// checkpoints before the last full snapshot are compacted by Clean.
 type KVStore struct {
 	db          ts2.Tskv
 	mapStore    *sync.Map // The current root store of a rule
 	checkpoints []int64
 	max         int
 	ruleId      string
+	fullEvery   int
+	// sinceFull is the count of the delta checkpoints since the last full snapshot, -1 to save a full snapshot next
+	sinceFull int
+	lastFull  int64
+	lastSaved int64
+	// digests are the digests of the saved values of each key of each operator to detect the changes
+	digests map[string]map[string]uint64
 }
 
 // Store in path ./data/checkpoint/$ruleId
@@ -51,12 +62,15 @@ type KVStore struct {
 // "checkpoints":A queue for completed checkpoint id
 // "$checkpointId":A map with key of checkpoint id and value of snapshot(gob serialized)
 // Assume each operator only has one instance
-func getKVStore(ruleId string) (*KVStore, error) {
-	db, err := ts.GetTS(ruleId)
+func getKVStore(ruleId string, fullEvery int) (*KVStore, error) {
+	db, err := ts.GetStateTS(ruleId)
 	if err != nil {
 		return nil, err
 	}
-	s := &KVStore{db: db, max: 3, mapStore: &sync.Map{}, ruleId: ruleId}
+	if fullEvery <= 0 {
+		fullEvery = DefaultFullSnapshotEvery
+	}
+	s := &KVStore{db: db, max: 3, mapStore: &sync.Map{}, ruleId: ruleId, fullEvery: fullEvery, sinceFull: -1}
 	// read data from badger db
 	if err := s.restore(); err != nil {
 		return nil, err
@@ -65,14 +79,15 @@ func getKVStore(ruleId string) (*KVStore, error) {
 }
 
 func (s *KVStore) restore() error {
-	var m map[string]interface{}
-	k, err := s.db.Last(&m)
+	k, base, m, err := loadCheckpoint(s.db)
 	if err != nil {
 		return err
 	}
 	if k > 0 {
 		s.checkpoints = []int64{k}
 		s.mapStore.Store(k, cast.MapToSyncMap(m))
+		s.lastFull = base
+		s.lastSaved = k
 	}
 	return nil
 }
@@ -107,8 +122,7 @@ func (s *KVStore) SaveCheckpoint(checkpointId int64) error {
 				s.checkpoints = s.checkpoints[1:]
 				s.mapStore.Delete(cp)
 			}
-			_, err := s.db.Set(checkpointId, cast.SyncMapToMap(m))
-			if err != nil {
+			if err := s.persist(checkpointId, cast.SyncMapToMap(m)); err != nil {
 				return fmt.Errorf("save checkpoint err: %v", err)
 			}
 		}
@@ -116,6 +130,39 @@ func (s *KVStore) SaveCheckpoint(checkpointId int64) error {
 	return nil
 }
 
+// persist saves the delta since the last saved checkpoint or a full snapshot
+func (s *KVStore) persist(checkpointId int64, state map[string]interface{}) error {
+	digests, err := digestState(state)
+	if err != nil {
+		return err
+	}
+	full := digests == nil || s.sinceFull < 0 || s.sinceFull+1 >= s.fullEvery
+	var record map[string]interface{}
+	if full {
+		record = state
+	} else {
+		record = map[string]interface{}{deltaKey: diffState(state, digests, s.digests, s.lastSaved)}
+	}
+	inserted, err := s.db.Set(checkpointId, record)
+	if err != nil {
+		// The delta chain may be broken
+		s.sinceFull = -1
+		return err
+	}
+	if !inserted {
+		return nil
+	}
+	if full {
+		s.sinceFull = 0
+		s.lastFull = checkpointId
+	} else {
+		s.sinceFull++
+	}
+	s.lastSaved = checkpointId
+	s.digests = digests
+	return nil
+}
+
 // GetOpState Only run in the initialization
 func (s *KVStore) GetOpState(opId string) (*sync.Map, error) {
 	if len(s.checkpoints) > 0 {
@@ -141,6 +188,13 @@ func (s *KVStore) GetOpState(opId string) (*sync.Map, error) {
 	return &sync.Map{}, nil
 }
 
+// Clean compacts the saved checkpoints. Only the last full snapshot and the deltas after it are kept to restore.
 func (s *KVStore) Clean() error {
+	if len(s.checkpoints) == 0 {
+		return nil
+	}
+	if s.lastFull > 0 {
+		return s.db.DeleteBefore(s.lastFull)
+	}
 	return s.db.DeleteBefore(s.checkpoints[0])
 }
@@ -23,6 +23,9 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
 	"github.com/lf-edge/ekuiper/v2/pkg/cast"
@@ -163,7 +166,7 @@ func TestLifecycle(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		store, err := getKVStore(ruleId)
+		store, err := getKVStore(ruleId, DefaultFullSnapshotEvery)
 		if err != nil {
 			t.Errorf("Get store for rule %s error: %s", ruleId, err)
 			return
@@ -226,7 +229,7 @@ func TestLifecycle(t *testing.T) {
 		}
 		// simulate restore
 		store = nil
-		store, err = getKVStore(ruleId)
+		store, err = getKVStore(ruleId, DefaultFullSnapshotEvery)
 		if err != nil {
 			t.Errorf("Restore store for rule %s error: %s", ruleId, err)
 			return
@@ -284,3 +287,85 @@ func cleanStateData() {
 		conf.Log.Error(err)
 	}
 }
+
+func TestIncrementalCheckpoint(t *testing.T) {
+	dataDir, err := conf.GetDataLoc()
+	require.NoError(t, err)
+	require.NoError(t, store.SetupDefault(dataDir))
+	ruleId := "testInc"
+	require.NoError(t, store.DropStateTS(ruleId))
+	s, err := getKVStore(ruleId, 3)
+	require.NoError(t, err)
+	states := []map[string]interface{}{
+		{"op1": map[string]interface{}{"a": 1, "b": "large"}, "op2": map[string]interface{}{"c": 1}},
+		{"op1": map[string]interface{}{"a": 2, "b": "large"}, "op2": map[string]interface{}{"c": 1}},
+		{"op1": map[string]interface{}{"a": 2, "b": "large", "d": true}, "op2": map[string]interface{}{}},
+		{"op1": map[string]interface{}{"a": 3}, "op2": map[string]interface{}{"c": 2}},
+		{"op1": map[string]interface{}{"a": 4}, "op2": map[string]interface{}{"c": 2}},
+	}
+	for i, st := range states {
+		cid := int64(i + 1)
+		for op, v := range st {
+			require.NoError(t, s.SaveState(cid, op, v.(map[string]interface{})))
+		}
+		require.NoError(t, s.SaveCheckpoint(cid))
+	}
+	var m map[string]interface{}
+	found, err := s.db.Get(1, &m)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, states[0], m)
+	// Only the changed keys are saved
+	m = nil
+	_, err = s.db.Get(2, &m)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{deltaKey: map[string]interface{}{
+		"prev": int64(1),
+		"set":  map[string]interface{}{"op1": map[string]interface{}{"a": 2}},
+		"del":  map[string]interface{}{},
+	}}, m)
+	m = nil
+	_, err = s.db.Get(3, &m)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{deltaKey: map[string]interface{}{
+		"prev": int64(2),
+		"set":  map[string]interface{}{"op1": map[string]interface{}{"d": true}},
+		"del":  map[string]interface{}{"op2": map[string]interface{}{"c": true}},
+	}}, m)
+	// Full snapshot every 3 checkpoints
+	m = nil
+	_, err = s.db.Get(4, &m)
+	require.NoError(t, err)
+	assert.Equal(t, states[3], m)
+
+	k, base, m, err := loadCheckpoint(s.db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), k)
+	assert.Equal(t, int64(4), base)
+	assert.Equal(t, states[4], m)
+
+	// Compact the checkpoints before the last full snapshot
+	require.NoError(t, s.Clean())
+	found, err = s.db.Get(3, &m)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Restore from the delta and save a full snapshot next
+	s, err = getKVStore(ruleId, 3)
+	require.NoError(t, err)
+	ns, err := s.GetOpState("op1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": 4}, cast.SyncMapToMap(ns))
+	require.NoError(t, s.SaveState(6, "op1", map[string]interface{}{"a": 5}))
+	require.NoError(t, s.SaveCheckpoint(6))
+	m = nil
+	_, err = s.db.Get(6, &m)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"op1": map[string]interface{}{"a": 5}}, m)
+
+	// Broken delta chain
+	_, err = s.db.Set(7, map[string]interface{}{deltaKey: map[string]interface{}{"prev": int64(3), "set": map[string]interface{}{}}})
+	require.NoError(t, err)
+	_, _, err = LoadCheckpoint(s.db)
+	assert.EqualError(t, err, "previous checkpoint 3 of checkpoint 7 is not found")
+}
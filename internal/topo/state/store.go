@@ -24,8 +24,16 @@ const CheckpointListKey = "checkpoints"
 
 func CreateStore(ruleId string, qos def.Qos) (api.Store, error) {
 	if qos >= def.AtLeastOnce {
-		return getKVStore(ruleId)
+		return getKVStore(ruleId, DefaultFullSnapshotEvery)
 	} else {
 		return newMemoryStore(), nil
 	}
 }
+
+// CreateRuleStore creates the store of the rule by its options
+func CreateRuleStore(ruleId string, options *def.RuleOption) (api.Store, error) {
+	if options.Qos >= def.AtLeastOnce {
+		return getKVStore(ruleId, options.FullSnapshotEvery)
+	}
+	return newMemoryStore(), nil
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
 	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
 )
 
@@ -129,7 +130,8 @@ func (s *SrcSubTopo) notifyError(poe error) {
 	// Notify error to all ref rules
 	s.refRules.Range(func(k, v interface{}) bool {
 		conf.Log.Debugf("Notify error %v to rule %s", poe, k.(string))
-		infra.DrainError(nil, poe, v.(chan<- error))
+		// The errors of the shared nodes are regarded as the errors of this source in the rules
+		infra.DrainError(nil, errorx.NewNodeError(s.name, poe), v.(chan<- error))
 		return true
 	})
 }
@@ -197,6 +199,20 @@ func (s *SrcSubTopo) Close(ctx api.StreamContext, ruleId string, runId int) {
 	_ = s.RemoveOutput(fmt.Sprintf("%s.%d", ruleId, runId))
 }
 
+// release removes the schema of the rule which never opens the subtopo, and removes the subtopo if no rule refers to it
+func (s *SrcSubTopo) release(ruleId string) {
+	if _, ok := s.refRules.Load(ruleId); ok {
+		return
+	}
+	delete(s.schemaReg, ruleId)
+	if s.refCount.Load() == 0 {
+		if ss, ok := s.source.(*SrcSubTopo); ok {
+			ss.release("$$subtopo_" + s.name)
+		}
+		RemoveSubTopo(s.name)
+	}
+}
+
 // RemoveMetrics is called when the rule is deleted
 func (s *SrcSubTopo) RemoveMetrics(ruleId string) {
 	if s.refCount.Load() == 0 {
@@ -155,14 +155,14 @@ func TestSubtopoRunError(t *testing.T) {
 	assert.Equal(t, int32(2), subTopo.refCount.Load())
 	select {
 	case err := <-errCh1:
-		assert.Equal(t, assert.AnError, err)
+		assert.ErrorIs(t, err, assert.AnError)
 		subTopo.Close(ctx1, "rule1", 1)
 	case <-time.After(1 * time.Second):
 		assert.Fail(t, "Should receive error")
 	}
 	select {
 	case err := <-errCh2:
-		assert.Equal(t, assert.AnError, err)
+		assert.ErrorIs(t, err, assert.AnError)
 		subTopo2.Close(ctx2, "rule2", 2)
 	case <-time.After(1 * time.Second):
 		assert.Fail(t, "Should receive error")
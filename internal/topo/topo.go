@@ -16,6 +16,7 @@ package topo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -35,6 +36,7 @@ import (
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/node/metric"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/state"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
 	"github.com/lf-edge/ekuiper/v2/pkg/timex"
 )
@@ -57,8 +59,12 @@ type Topo struct {
 	store       api.Store
 	coordinator *checkpoint.Coordinator
 	topo        *def.PrintableTopo
-	mu          sync.Mutex
-	hasOpened   atomic.Bool
+	// signatures of the operator configurations by the operator name, used to match the operators across rule updates
+	signatures map[string]string
+	// errorClasses are the error classes of the nodes which are not operators
+	errorClasses map[string]string
+	mu           sync.Mutex
+	hasOpened    atomic.Bool
 
 	opsWg *sync.WaitGroup
 }
@@ -122,6 +128,7 @@ func (s *Topo) Cancel() {
 
 func (s *Topo) AddSrc(src node.DataSourceNode) *Topo {
 	s.sources = append(s.sources, src)
+	s.SetErrorClass(src.GetName(), def.ErrorClassSource)
 	switch rt := src.(type) {
 	case node.MergeableTopo:
 		rt.MergeSrc(s.topo)
@@ -142,6 +149,7 @@ func (s *Topo) AddSink(inputs []node.Emitter, snk node.DataSinkNode) *Topo {
 		s.addEdge(input.(node.TopNode), snk, "sink")
 	}
 	s.sinks = append(s.sinks, snk)
+	s.SetErrorClass(snk.GetName(), def.ErrorClassSink)
 	return s
 }
 
@@ -151,6 +159,7 @@ func (s *Topo) AddSinkAlterOperator(sink *node.SinkNode, operator node.OperatorN
 	operator.AddInputCount()
 	s.addEdge(sink, operator, "op")
 	s.ops = append(s.ops, operator)
+	s.SetErrorClass(operator.GetName(), def.ErrorClassSink)
 	return s
 }
 
@@ -227,6 +236,11 @@ func (s *Topo) prepareContext() {
 		ctx := kctx.WithValue(kctx.RuleBackground(s.name), kctx.LoggerKey, contextLogger)
 		ctx = kctx.WithValue(ctx, kctx.RuleStartKey, timex.GetNowInMilli())
 		ctx = kctx.WithValue(ctx, kctx.RuleWaitGroupKey, s.opsWg)
+		if s.options != nil {
+			if limit := (kctx.StateLimit{TTL: time.Duration(s.options.StateTTL), MaxKeys: s.options.StateMaxKeys}); limit.Enabled() {
+				ctx = kctx.WithValue(ctx, kctx.StateLimitKey, limit)
+			}
+		}
 		s.ctx, s.cancel = ctx.WithCancel()
 	}
 }
@@ -256,7 +270,7 @@ func (s *Topo) Open() <-chan error {
 	log.Info("Opening stream")
 	err := infra.SafeRun(func() error {
 		var err error
-		if s.store, err = state.CreateStore(s.name, s.options.Qos); err != nil {
+		if s.store, err = state.CreateRuleStore(s.name, s.options); err != nil {
 			return fmt.Errorf("topo %s create store error %v", s.name, err)
 		}
 		if err := s.enableCheckpoint(s.ctx); err != nil {
@@ -394,6 +408,59 @@ func (s *Topo) GetMetrics() (keys []string, values []any) {
 	return
 }
 
+// InspectState summarizes the state of the operators and sinks, or only the op if it is set. A node which does not
+// take the request within the timeout, such as being blocked by the downstream, is reported as busy.
+func (s *Topo) InspectState(op string, limit int, timeout time.Duration) ([]*node.StateSummary, error) {
+	if !s.hasOpened.Load() || s.ctx == nil || s.ctx.Err() != nil {
+		return nil, fmt.Errorf("rule %s is not running", s.name)
+	}
+	nodes := make([]node.DataSinkNode, 0, len(s.ops)+len(s.sinks))
+	for _, so := range s.ops {
+		nodes = append(nodes, so)
+	}
+	nodes = append(nodes, s.sinks...)
+	type pending struct {
+		name string
+		req  *node.StateRequest
+		len  int
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var reqs []*pending
+	for _, n := range nodes {
+		if op != "" && n.GetName() != op {
+			continue
+		}
+		input, _ := n.GetInput()
+		p := &pending{name: n.GetName(), req: node.NewStateRequest(limit), len: len(input)}
+		select {
+		case input <- p.req:
+			reqs = append(reqs, p)
+		case <-ctx.Done():
+			reqs = append(reqs, &pending{name: n.GetName(), len: len(input)})
+		}
+	}
+	if op != "" && len(reqs) == 0 {
+		return nil, errorx.NewWithCode(errorx.NOT_FOUND, fmt.Sprintf("operator %s is not found in rule %s", op, s.name))
+	}
+	result := make([]*node.StateSummary, 0, len(reqs))
+	for _, p := range reqs {
+		var (
+			r  *node.StateSummary
+			ok bool
+		)
+		if p.req != nil {
+			r, ok = p.req.Result(ctx)
+		}
+		if !ok {
+			r = &node.StateSummary{Op: p.name, Entries: []*node.StateEntry{}, Message: "the node is busy, try again later"}
+		}
+		r.BufferLength = p.len
+		result = append(result, r)
+	}
+	return result, nil
+}
+
 func (s *Topo) RemoveMetrics() {
 	conf.Log.Infof("start removing %v metrics", s.name)
 	for _, sn := range s.sources {
@@ -412,6 +479,80 @@ func (s *Topo) GetTopo() *def.PrintableTopo {
 	return s.topo
 }
 
+// SetOpSignature records the configuration signature of an operator. The operators with the same signature are
+// compatible to share the state.
+func (s *Topo) SetOpSignature(name, signature string) {
+	if s.signatures == nil {
+		s.signatures = make(map[string]string)
+	}
+	s.signatures[name] = signature
+}
+
+// SetErrorClass sets the class of the errors from the node. The nodes not set are operators.
+func (s *Topo) SetErrorClass(name, class string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.errorClasses == nil {
+		s.errorClasses = make(map[string]string)
+	}
+	s.errorClasses[name] = class
+}
+
+// ErrorClass returns the class of the error by the node which produces it
+func (s *Topo) ErrorClass(err error) string {
+	var ne *errorx.NodeError
+	if !errors.As(err, &ne) {
+		return def.ErrorClassOther
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.errorClasses[ne.Node]; ok {
+		return c
+	}
+	for _, op := range s.ops {
+		if op.GetName() == ne.Node {
+			return def.ErrorClassOperator
+		}
+	}
+	return def.ErrorClassOther
+}
+
+func (s *Topo) GetOpSignatures() map[string]string {
+	return s.signatures
+}
+
+// GetEmitters returns the sources and operators in the planned order. The sinks are not included.
+func (s *Topo) GetEmitters() []node.Emitter {
+	result := make([]node.Emitter, 0, len(s.sources)+len(s.ops))
+	for _, src := range s.sources {
+		result = append(result, src)
+	}
+	for _, op := range s.ops {
+		result = append(result, op)
+	}
+	return result
+}
+
+// Release cleans up a topo which is planned but never opened, such as the sub topos created by the planning
+func (s *Topo) Release() {
+	for _, src := range s.sources {
+		if st, ok := src.(*SrcSubTopo); ok {
+			st.release(s.name)
+		}
+	}
+}
+
+// GetSharedSubTopos returns the names of the sub topos shared with other rules
+func (s *Topo) GetSharedSubTopos() []string {
+	var result []string
+	for _, src := range s.sources {
+		if _, ok := src.(node.MergeableTopo); ok {
+			result = append(result, src.GetName())
+		}
+	}
+	return result
+}
+
 func (s *Topo) ResetStreamOffset(name string, input map[string]interface{}) error {
 	for _, source := range s.sources {
 		if source.GetName() == name {
@@ -1271,6 +1271,15 @@ func TestSingleSQL(t *testing.T) {
 			},
 			DisableBufferFullDiscard: true,
 		},
+		{
+			BufferLength:  100,
+			SendError:     true,
+			ExecBatchSize: 16,
+			PlanOptimizeStrategy: &def.PlanOptimizeStrategy{
+				EnableIncrementalWindow: true,
+			},
+			DisableBufferFullDiscard: true,
+		},
 	}
 	for _, opt := range options {
 		DoRuleTest(t, tests, opt, 0)
@@ -0,0 +1,439 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trial
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+	"github.com/shirou/gopsutil/process"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/internal/topo"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const (
+	defaultBenchRate     = 1000
+	maxBenchRate         = 1000000
+	defaultBenchDuration = 10 * time.Second
+	// The interval to sample the memory usage
+	benchSampleInterval = 100 * time.Millisecond
+)
+
+// BenchDef is the definition to generate load against a rule or a canned scenario
+type BenchDef struct {
+	// RuleId is the id of the existing rule to bench. It is resolved by the server.
+	RuleId string `json:"ruleId,omitempty"`
+	// Scenario is the name of the canned scenario which runs against a temporary stream
+	Scenario string `json:"scenario,omitempty"`
+	Sql      string `json:"sql,omitempty"`
+	// Inputs are the sample payloads of each stream which are sent in loop
+	Inputs  map[string][]map[string]any `json:"inputs,omitempty"`
+	Options *def.RuleOption             `json:"options,omitempty"`
+	// Rate is the messages per second sent to each stream
+	Rate     int               `json:"rate,omitempty"`
+	Duration cast.DurationConf `json:"duration,omitempty"`
+}
+
+// BenchReport is the machine-readable result of a bench run
+type BenchReport struct {
+	RuleId   string `json:"ruleId,omitempty"`
+	Scenario string `json:"scenario,omitempty"`
+	Sql      string `json:"sql"`
+	Rate     int    `json:"rate"`
+	// Elapsed is the seconds the rule ran
+	Elapsed float64 `json:"elapsed"`
+	// Sent is the count of the messages ingested by all the sources
+	Sent int64 `json:"sent"`
+	// Received is the count of the results arrived at the sink
+	Received int64 `json:"received"`
+	Errors   int64 `json:"errors"`
+	// Throughput is the ingested messages per second
+	Throughput float64 `json:"throughput"`
+	// OutputRate is the results per second arrived at the sink
+	OutputRate float64         `json:"outputRate"`
+	Latency    *LatencyReport  `json:"latency,omitempty"`
+	Resource   *ResourceReport `json:"resource"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// LatencyReport is the latency in milliseconds from the timestamp of a result to its arrival at the sink
+type LatencyReport struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// ResourceReport is the resource usage of the whole process during the run
+type ResourceReport struct {
+	Cpus int `json:"cpus"`
+	// CpuPercent is the average cpu usage which may exceed 100 with multiple cores
+	CpuPercent float64 `json:"cpuPercent"`
+	// MemoryPeak is the peak resident memory in bytes
+	MemoryPeak uint64 `json:"memoryPeak"`
+	// HeapPeak is the peak heap memory in bytes
+	HeapPeak uint64 `json:"heapPeak"`
+}
+
+type benchScenario struct {
+	sql string
+}
+
+// The canned scenarios to bench the typical rules. The %s is replaced by the temporary stream name.
+var benchScenarios = map[string]*benchScenario{
+	"passthrough": {sql: "SELECT * FROM %s"},
+	"filter":      {sql: "SELECT deviceId, temperature * 1.8 + 32 AS fahrenheit FROM %s WHERE temperature > 25"},
+	"window":      {sql: "SELECT deviceId, avg(temperature) AS avgTemp, max(humidity) AS maxHumidity, count(*) AS cnt FROM %s GROUP BY deviceId, TumblingWindow(ss, 1)"},
+}
+
+// scenarioInputs are the sensor readings of 10 devices sent by the scenarios
+func scenarioInputs() []map[string]any {
+	result := make([]map[string]any, 0, 10)
+	for i := 0; i < 10; i++ {
+		result = append(result, map[string]any{
+			"deviceId":    fmt.Sprintf("device%d", i),
+			"temperature": 20 + float64(i),
+			"humidity":    50 + float64(i*3),
+			"status":      "running",
+		})
+	}
+	return result
+}
+
+// Bench runs the rule with the sample inputs sent in loop at the rate for the duration and reports the throughput,
+// the latency and the resource usage. The sources are mocked and the sinks are replaced by a nop sink, so the report
+// measures the processing of the rule. If the rule is nil, it runs the sql or the scenario of the definition.
+func Bench(bd *BenchDef, rule *def.Rule) (*BenchReport, error) {
+	if bd.Rate <= 0 {
+		bd.Rate = defaultBenchRate
+	}
+	if bd.Rate > maxBenchRate {
+		return nil, fmt.Errorf("rate %d exceeds the max %d", bd.Rate, maxBenchRate)
+	}
+	if bd.Duration <= 0 {
+		bd.Duration = cast.DurationConf(defaultBenchDuration)
+	}
+	if rule == nil {
+		if bd.Scenario != "" {
+			sc, ok := benchScenarios[bd.Scenario]
+			if !ok {
+				return nil, fmt.Errorf("unknown scenario %s, available scenarios are %s", bd.Scenario, strings.Join(scenarioNames(), ", "))
+			}
+			name := "bench_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+			p := processor.NewStreamProcessor()
+			if _, err := p.ExecStmt(fmt.Sprintf(`CREATE STREAM %s () WITH (TYPE="simulator", FORMAT="json")`, name)); err != nil {
+				return nil, err
+			}
+			defer func() {
+				_, _ = p.ExecStmt("DROP STREAM " + name)
+			}()
+			bd.Sql = fmt.Sprintf(sc.sql, name)
+			bd.Inputs = map[string][]map[string]any{name: scenarioInputs()}
+		}
+		if bd.Sql == "" {
+			return nil, fmt.Errorf("one of ruleId, scenario or sql is required")
+		}
+		rule = def.GetDefaultRule("", bd.Sql)
+		if bd.Options != nil {
+			rule.Options = bd.Options
+		}
+	}
+	if rule.Sql == "" {
+		return nil, fmt.Errorf("only the sql rule can be benched")
+	}
+	streams, err := benchStreams(rule)
+	if err != nil {
+		return nil, err
+	}
+	interval := time.Second / time.Duration(bd.Rate)
+	mock := make(map[string]map[string]any, len(streams))
+	for _, s := range streams {
+		inputs, ok := bd.Inputs[s]
+		if !ok || len(inputs) == 0 {
+			return nil, fmt.Errorf("inputs of stream %s are not set", s)
+		}
+		mock[s] = map[string]any{"data": inputs, "interval": interval.String(), "loop": true}
+	}
+	// Only plan the processing of the rule, the actions and side outputs are not touched
+	br := def.GetDefaultRule("$$_bench_"+uuid.New().String(), rule.Sql)
+	br.Statements = rule.Statements
+	br.Params = rule.Params
+	br.Options = rule.Options
+	br.Options.SendError = true
+	br.Actions = []map[string]any{{"nop": map[string]any{}}}
+	tp, err := planner.PlanSQLWithSourcesAndSinks(br, mock)
+	if err != nil {
+		return nil, err
+	}
+	report, err := runBench(tp, streams, time.Duration(bd.Duration))
+	if err != nil {
+		return nil, err
+	}
+	report.RuleId = bd.RuleId
+	report.Scenario = bd.Scenario
+	report.Sql = rule.Sql
+	report.Rate = bd.Rate
+	return report, nil
+}
+
+// benchStreams returns the streams read by the rule and its statements
+func benchStreams(rule *def.Rule) ([]string, error) {
+	sqls := []string{rule.Sql}
+	stmtNames := make(map[string]struct{}, len(rule.Statements))
+	for _, s := range rule.Statements {
+		sqls = append(sqls, s.Sql)
+		stmtNames[s.Name] = struct{}{}
+	}
+	var result []string
+	found := make(map[string]struct{})
+	for _, sql := range sqls {
+		stmt, err := xsql.GetStatementFromSql(sql)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range xsql.GetStreams(stmt) {
+			if _, ok := stmtNames[s]; ok {
+				continue
+			}
+			if _, ok := found[s]; ok {
+				continue
+			}
+			found[s] = struct{}{}
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// runBench runs the topo for the duration. It counts the outputs of the sources and the latency of the outputs of the
+// last operator which feeds the sink.
+func runBench(tp *topo.Topo, streams []string, duration time.Duration) (*BenchReport, error) {
+	emitters := tp.GetEmitters()
+	if len(emitters) == 0 {
+		return nil, fmt.Errorf("no operator to bench")
+	}
+	sources := make(map[string]struct{}, len(streams))
+	for _, s := range streams {
+		sources[s] = struct{}{}
+	}
+	var (
+		sent, received, errs atomic.Int64
+		latencies            []int64
+		wg                   sync.WaitGroup
+	)
+	done := make(chan struct{})
+	drain := func(ch chan any, collect func(d any)) {
+		defer wg.Done()
+		for {
+			select {
+			case d := <-ch:
+				collect(d)
+			case <-done:
+				return
+			}
+		}
+	}
+	for i, e := range emitters {
+		tn, ok := e.(node.TopNode)
+		if !ok {
+			continue
+		}
+		var collect func(d any)
+		if _, ok := sources[tn.GetName()]; ok {
+			collect = func(d any) {
+				if _, isData := benchData(d); isData {
+					sent.Add(1)
+				}
+			}
+		} else if i == len(emitters)-1 {
+			collect = func(d any) {
+				d, isData := benchData(d)
+				if !isData {
+					return
+				}
+				switch dt := d.(type) {
+				case error:
+					errs.Add(1)
+				case *xsql.RawTuple:
+					received.Add(1)
+					latencies = append(latencies, timex.GetNow().Sub(dt.Timestamp).Microseconds())
+				case api.MetaInfo:
+					received.Add(1)
+					latencies = append(latencies, timex.GetNow().Sub(dt.Created()).Microseconds())
+				default:
+					received.Add(1)
+				}
+			}
+		} else {
+			continue
+		}
+		ch := make(chan any, 1024)
+		if err := e.AddOutput(ch, "bench"); err != nil {
+			tp.Cancel()
+			close(done)
+			wg.Wait()
+			return nil, err
+		}
+		wg.Add(1)
+		go drain(ch, collect)
+	}
+
+	res := &ResourceReport{Cpus: runtime.NumCPU()}
+	proc, _ := process.NewProcess(int32(os.Getpid()))
+	cpuStart := cpuSeconds(proc)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(benchSampleInterval)
+		defer ticker.Stop()
+		for {
+			sampleMemory(proc, res)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	report := &BenchReport{}
+	start := time.Now()
+	select {
+	case err := <-tp.Open():
+		if err != nil && !errorx.IsEOF(err) {
+			report.Error = err.Error()
+		}
+	case <-time.After(duration):
+	}
+	elapsed := time.Since(start)
+	tp.Cancel()
+	close(done)
+	wg.Wait()
+
+	if cpuStart >= 0 {
+		res.CpuPercent = round((cpuSeconds(proc) - cpuStart) / elapsed.Seconds() * 100)
+	}
+	report.Resource = res
+	report.Elapsed = round(elapsed.Seconds())
+	report.Sent = sent.Load()
+	report.Received = received.Load()
+	report.Errors = errs.Load()
+	report.Throughput = round(float64(report.Sent) / elapsed.Seconds())
+	report.OutputRate = round(float64(report.Received) / elapsed.Seconds())
+	report.Latency = latencyReport(latencies)
+	return report, nil
+}
+
+// benchData unwraps the data and returns false for the control messages
+func benchData(d any) (any, bool) {
+	if b, ok := d.(*checkpoint.BufferOrEvent); ok {
+		d = b.Data
+	}
+	switch d.(type) {
+	case *xsql.WatermarkTuple, xsql.EOFTuple, *checkpoint.Barrier, checkpoint.Barrier:
+		return nil, false
+	default:
+		return d, true
+	}
+}
+
+func latencyReport(latencies []int64) *LatencyReport {
+	if len(latencies) == 0 {
+		return nil
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var sum int64
+	for _, l := range latencies {
+		sum += l
+	}
+	percentile := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(latencies)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return toMs(latencies[i])
+	}
+	return &LatencyReport{
+		Min: toMs(latencies[0]),
+		Avg: round(float64(sum) / float64(len(latencies)) / 1000),
+		P50: percentile(0.5),
+		P90: percentile(0.9),
+		P99: percentile(0.99),
+		Max: toMs(latencies[len(latencies)-1]),
+	}
+}
+
+// cpuSeconds returns the user and system cpu time of the process or -1 if not available
+func cpuSeconds(proc *process.Process) float64 {
+	if proc == nil {
+		return -1
+	}
+	t, err := proc.Times()
+	if err != nil {
+		return -1
+	}
+	return t.User + t.System
+}
+
+func sampleMemory(proc *process.Process, res *ResourceReport) {
+	if proc != nil {
+		if m, err := proc.MemoryInfo(); err == nil && m.RSS > res.MemoryPeak {
+			res.MemoryPeak = m.RSS
+		}
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.HeapAlloc > res.HeapPeak {
+		res.HeapPeak = ms.HeapAlloc
+	}
+}
+
+func scenarioNames() []string {
+	names := make([]string, 0, len(benchScenarios))
+	for k := range benchScenarios {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func toMs(us int64) float64 {
+	return round(float64(us) / 1000)
+}
+
+// round keeps 3 decimals
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trial
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/pkg/ast"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+func TestBench(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	dataDir, err := conf.GetDataLoc()
+	require.NoError(t, err)
+	require.NoError(t, store.SetupDefault(dataDir))
+	p := processor.NewStreamProcessor()
+	_, _ = p.ExecStmt("DROP STREAM demoBench")
+	_, err = p.ExecStmt(`CREATE STREAM demoBench () WITH (DATASOURCE="demoBench", TYPE="mqtt", FORMAT="json")`)
+	require.NoError(t, err)
+	defer p.ExecStmt("DROP STREAM demoBench")
+
+	closeCh := make(chan struct{})
+	defer close(closeCh)
+	go func() {
+		for {
+			select {
+			case <-closeCh:
+				return
+			default:
+				timex.Add(time.Millisecond)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	r, err := Bench(&BenchDef{
+		Sql:      "SELECT name, value * 2 AS v FROM demoBench WHERE value > 1",
+		Inputs:   map[string][]map[string]any{"demoBench": {{"name": "a", "value": 1}, {"name": "b", "value": 2}, {"name": "c", "value": "x"}}},
+		Duration: cast.DurationConf(500 * time.Millisecond),
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaultBenchRate, r.Rate)
+	assert.Greater(t, r.Sent, int64(0))
+	assert.Greater(t, r.Received, int64(0))
+	// One of the three inputs fails to filter and the other one is filtered out
+	assert.Greater(t, r.Errors, int64(0))
+	assert.Less(t, r.Received, r.Sent)
+	require.NotNil(t, r.Latency)
+	assert.LessOrEqual(t, r.Latency.P50, r.Latency.P99)
+	assert.Greater(t, r.Resource.HeapPeak, uint64(0))
+
+	// Bench an existing rule
+	rule := def.GetDefaultRule("benchRule", "SELECT * FROM demoBench")
+	r, err = Bench(&BenchDef{
+		RuleId:   "benchRule",
+		Inputs:   map[string][]map[string]any{"demoBench": {{"name": "a", "value": 1}}},
+		Rate:     100,
+		Duration: cast.DurationConf(200 * time.Millisecond),
+	}, rule)
+	require.NoError(t, err)
+	assert.Equal(t, "benchRule", r.RuleId)
+	assert.Equal(t, 100, r.Rate)
+	assert.Greater(t, r.Received, int64(0))
+
+	// Canned scenario with a temporary stream
+	r, err = Bench(&BenchDef{Scenario: "filter", Duration: cast.DurationConf(200 * time.Millisecond)}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, r.Sql, "WHERE temperature > 25")
+	assert.Greater(t, r.Received, int64(0))
+	// The temporary stream is dropped
+	streams, err := p.ShowStream(ast.TypeStream)
+	require.NoError(t, err)
+	for _, s := range streams {
+		assert.False(t, strings.HasPrefix(s, "bench_"))
+	}
+
+	_, err = Bench(&BenchDef{Scenario: "unknown"}, nil)
+	assert.EqualError(t, err, "unknown scenario unknown, available scenarios are filter, passthrough, window")
+	_, err = Bench(&BenchDef{Sql: "SELECT * FROM demoBench"}, nil)
+	assert.EqualError(t, err, "inputs of stream demoBench are not set")
+	_, err = Bench(&BenchDef{}, nil)
+	assert.EqualError(t, err, "one of ruleId, scenario or sql is required")
+}
+
+func TestLatencyReport(t *testing.T) {
+	assert.Nil(t, latencyReport(nil))
+	latencies := make([]int64, 0, 100)
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, int64(i*1000))
+	}
+	assert.Equal(t, &LatencyReport{Min: 1, Avg: 50.5, P50: 50, P90: 90, P99: 99, Max: 100}, latencyReport(latencies))
+}
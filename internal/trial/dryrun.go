@@ -0,0 +1,188 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trial
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lf-edge/ekuiper/contract/v2/api"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/checkpoint"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
+)
+
+const defaultDryRunTimeout = 5 * time.Second
+
+// DryRunDef is the definition to run a rule once against the sample inputs of each stream
+type DryRunDef struct {
+	Sql     string                      `json:"sql"`
+	Inputs  map[string][]map[string]any `json:"inputs"`
+	Options *def.RuleOption             `json:"options"`
+	// Timeout is the max duration to wait for the rule to process all the inputs
+	Timeout cast.DurationConf `json:"timeout"`
+}
+
+// DryRunResult contains the outputs of each source and operator in the planned order
+type DryRunResult struct {
+	Operators []*OperatorOutput `json:"operators"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type OperatorOutput struct {
+	Name    string `json:"name"`
+	Outputs []any  `json:"outputs"`
+
+	mu sync.Mutex
+}
+
+func (o *OperatorOutput) add(d any) {
+	if v, ok := toOutput(d); ok {
+		o.mu.Lock()
+		o.Outputs = append(o.Outputs, v)
+		o.mu.Unlock()
+	}
+}
+
+// DryRun runs the rule with the sample inputs as the stream sources and a nop sink, so that no real sources or sinks
+// are touched. It returns when all the inputs are processed or timeout.
+func DryRun(runDef string) (*DryRunResult, error) {
+	rd := &DryRunDef{Options: def.GetDefaultRule("", "").Options}
+	if err := json.Unmarshal([]byte(runDef), rd); err != nil {
+		return nil, fmt.Errorf("fail to parse dry run definition %s: %s", runDef, err)
+	}
+	if rd.Sql == "" {
+		return nil, fmt.Errorf("sql is required")
+	}
+	stmt, err := xsql.GetStatementFromSql(rd.Sql)
+	if err != nil {
+		return nil, err
+	}
+	mock := make(map[string]map[string]any, len(rd.Inputs))
+	for _, s := range xsql.GetStreams(stmt) {
+		inputs, ok := rd.Inputs[s]
+		if !ok {
+			return nil, fmt.Errorf("inputs of stream %s are not set", s)
+		}
+		mock[s] = map[string]any{"data": inputs, "interval": 1, "loop": false}
+	}
+	if rd.Timeout <= 0 {
+		rd.Timeout = cast.DurationConf(defaultDryRunTimeout)
+	}
+	rule := def.GetDefaultRule("$$_dryrun_"+uuid.New().String(), rd.Sql)
+	rule.Options = rd.Options
+	rule.Options.SendError = true
+	rule.Actions = []map[string]any{{"nop": map[string]any{}}}
+	tp, err := planner.PlanSQLWithSourcesAndSinks(rule, mock)
+	if err != nil {
+		return nil, err
+	}
+	result := &DryRunResult{}
+	runErr, err := runTopo(tp, time.Duration(rd.Timeout), func(name string) func(d any) {
+		oo := &OperatorOutput{Name: name, Outputs: make([]any, 0)}
+		result.Operators = append(result.Operators, oo)
+		return oo.add
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Error = runErr
+	return result, nil
+}
+
+// runTopo runs the topo and sends the outputs of each node to the collector created by newCollector until all the
+// inputs are processed or timeout. The collector is created in the planned order of the nodes. It returns the error
+// message of the run if the rule fails.
+func runTopo(tp *topo.Topo, timeout time.Duration, newCollector func(name string) func(d any)) (string, error) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, e := range tp.GetEmitters() {
+		tn, ok := e.(node.TopNode)
+		if !ok {
+			continue
+		}
+		collect := newCollector(tn.GetName())
+		ch := make(chan any, 1024)
+		if err := e.AddOutput(ch, "dryrun"); err != nil {
+			tp.Cancel()
+			close(done)
+			return "", err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case d := <-ch:
+					collect(d)
+				case <-done:
+					for {
+						select {
+						case d := <-ch:
+							collect(d)
+						default:
+							return
+						}
+					}
+				}
+			}
+		}()
+	}
+	var runErr string
+	select {
+	case err := <-tp.Open():
+		if err != nil && !errorx.IsEOF(err) {
+			runErr = err.Error()
+		}
+	case <-time.After(timeout):
+	}
+	tp.Cancel()
+	close(done)
+	wg.Wait()
+	return runErr, nil
+}
+
+// toOutput converts the data to the json friendly value. The control messages are ignored.
+func toOutput(d any) (any, bool) {
+	switch dt := d.(type) {
+	case *checkpoint.BufferOrEvent:
+		return toOutput(dt.Data)
+	case *xsql.WatermarkTuple, xsql.EOFTuple, *checkpoint.Barrier, checkpoint.Barrier:
+		return nil, false
+	case error:
+		return map[string]any{"error": dt.Error()}, true
+	case api.RawTuple:
+		return string(dt.Raw()), true
+	case xsql.Collection:
+		return dt.ToMaps(), true
+	case xsql.Row:
+		return dt.ToMap(), true
+	case api.MessageTupleList:
+		return dt.ToMaps(), true
+	case api.MessageTuple:
+		return dt.ToMap(), true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trial
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+func TestDryRun(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	dataDir, err := conf.GetDataLoc()
+	require.NoError(t, err)
+	require.NoError(t, store.SetupDefault(dataDir))
+	p := processor.NewStreamProcessor()
+	_, _ = p.ExecStmt("DROP STREAM demoDry")
+	_, err = p.ExecStmt(`CREATE STREAM demoDry () WITH (DATASOURCE="demoDry", TYPE="mqtt", FORMAT="json")`)
+	require.NoError(t, err)
+	defer p.ExecStmt("DROP STREAM demoDry")
+
+	closeCh := make(chan struct{})
+	defer close(closeCh)
+	go func() {
+		for {
+			select {
+			case <-closeCh:
+				return
+			default:
+				timex.Add(time.Millisecond)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	r, err := DryRun(`{"sql":"SELECT name, value * 2 AS v FROM demoDry WHERE value > 1","inputs":{"demoDry":[{"name":"a","value":1},{"name":"b","value":2},{"name":"c","value":"x"}]}}`)
+	require.NoError(t, err)
+	outputs := make(map[string][]any)
+	for _, o := range r.Operators {
+		outputs[o.Name] = o.Outputs
+	}
+	b, _ := json.Marshal(outputs)
+	assert.JSONEq(t, `{
+		"demoDry": [{"name":"a","value":1},{"name":"b","value":2},{"name":"c","value":"x"}],
+		"2_filter": [{"name":"b","value":2},{"error":"run Where error: invalid operation string(x) > int64(1)"}],
+		"3_project": [{"name":"b","v":4},{"error":"run Where error: invalid operation string(x) > int64(1)"}],
+		"nop_0_0_transform": [[{"name":"b","v":4}],{"error":"run Where error: invalid operation string(x) > int64(1)"}],
+		"nop_0_1_encode": ["[{\"name\":\"b\",\"v\":4}]",{"error":"run Where error: invalid operation string(x) > int64(1)"}]
+	}`, string(b))
+
+	_, err = DryRun(`{"sql":"SELECT * FROM demoDry","inputs":{"other":[]}}`)
+	assert.EqualError(t, err, "inputs of stream demoDry are not set")
+	_, err = DryRun(`{"sql":"SELECT * FROM"}`)
+	assert.Error(t, err)
+}
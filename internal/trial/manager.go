@@ -21,8 +21,6 @@ import (
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
 	"github.com/lf-edge/ekuiper/v2/internal/topo"
-	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
-	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 )
 
 // TrialManager Manager Initialized in the binder
@@ -72,7 +70,7 @@ func (m *Manager) StopRule(ruleId string) {
 	if r, ok := m.runs[ruleId]; ok {
 		r.topo.Cancel()
 		delete(m.runs, ruleId)
-		_ = connection.DetachConnection(context.Background(), r.def.endpoint)
+		detachEndpoints(r.def)
 	} else {
 		conf.Log.Warnf("try to stop test rule %s but it is not found", ruleId)
 	}
@@ -82,7 +80,7 @@ func (m *Manager) StartRule(ruleId string) error {
 	m.RLock()
 	defer m.RUnlock()
 	if r, ok := m.runs[ruleId]; ok {
-		trialRun(r.topo, r.def.endpoint)
+		trialRun(r.topo, r.def)
 	} else {
 		return fmt.Errorf("try to start test rule %s but it is not found", ruleId)
 	}
@@ -15,7 +15,10 @@
 package trial
 
 import (
+	"encoding/json"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -70,6 +73,9 @@ func TestTrialRule(t *testing.T) {
 
 	// Test 4 Rule without mock
 	testRealSourceTrial(t)
+
+	// Test 5 Rule with recording, tap and operator outputs
+	testSandboxTrial(t, dataDir)
 	require.Equal(t, 0, len(TrialManager.runs))
 }
 
@@ -196,3 +202,101 @@ func testRealSourceTrial(t *testing.T) {
 	closeCh <- struct{}{}
 	c3.Close()
 }
+
+func readWs(t *testing.T, path string) (*websocket.Conn, chan []byte) {
+	u := url.URL{Scheme: "ws", Host: "localhost:10091", Path: path}
+	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	require.NoError(t, err)
+	recvCh := make(chan []byte, 10)
+	go func() {
+		for {
+			_, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			recvCh <- data
+		}
+	}()
+	return c, recvCh
+}
+
+func testSandboxTrial(t *testing.T, dataDir string) {
+	_, err := TrialManager.CreateRule(`{"id":"ruleDup","sql":"select * from demo876","mockSource":{"demo876":{"data":[]}},"tap":["demo876"]}`)
+	require.EqualError(t, err, "stream demo876 is set more than once in mockSource, recordings and tap")
+	_, err = TrialManager.CreateRule(`{"id":"ruleLong","sql":"select * from demo876","duration":"1h"}`)
+	require.EqualError(t, err, "duration 1h0m0s exceeds the max duration 30m0s")
+	_, err = TrialManager.CreateRule(`{"id":"ruleNoFile","sql":"select * from demo876","recordings":{"demo876":"nonexist.json"}}`)
+	require.Error(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dataDir, "uploads"), 0o755))
+	recording := filepath.Join(dataDir, "uploads", "rec876.jsonl")
+	require.NoError(t, os.WriteFile(recording, []byte(`{"data":{"a":1},"timestamp":0}
+{"data":{"a":2},"timestamp":100}
+{"data":{"a":3},"timestamp":1000}
+`), 0o644))
+	defer os.Remove(recording)
+
+	id, err := TrialManager.CreateRule(`{"id":"ruleRec","sql":"select a from demo876 where a > 0","recordings":{"demo876":"rec876.jsonl"},"showOperators":true,"maxOutputs":2,"sinkProps":{"sendSingle":true}}`)
+	require.NoError(t, err)
+	c1, recvCh := readWs(t, "/test/ruleRec")
+	defer c1.Close()
+	c2, opsCh := readWs(t, "/test/ruleRec/operators")
+	defer c2.Close()
+	closeCh := make(chan struct{})
+	defer close(closeCh)
+	go func() {
+		for {
+			select {
+			case <-closeCh:
+				return
+			default:
+				timex.Add(100 * time.Millisecond)
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, TrialManager.StartRule(id))
+	for _, exp := range []string{`{"a":1}`, `{"a":2}`} {
+		select {
+		case data := <-recvCh:
+			require.Equal(t, exp, string(data))
+		case <-time.After(2 * time.Second):
+			require.Fail(t, "receive timeout")
+		}
+	}
+	ops := make(map[string]bool)
+	timeout := time.After(2 * time.Second)
+	for !ops["demo876"] || !ops["2_filter"] {
+		select {
+		case data := <-opsCh:
+			m := make(map[string]any)
+			require.NoError(t, json.Unmarshal(data, &m))
+			ops[m["operator"].(string)] = true
+		case <-timeout:
+			require.Fail(t, "receive operators timeout")
+		}
+	}
+	// The run stops after sending 2 results
+	select {
+	case data := <-recvCh:
+		require.Fail(t, "should not receive more results", string(data))
+	case <-time.After(1500 * time.Millisecond):
+	}
+	TrialManager.StopRule(id)
+
+	// Tap the live data of the stream
+	id, err = TrialManager.CreateRule(`{"id":"ruleTap","sql":"select * from demo878","tap":["demo878"],"sinkProps":{"sendSingle":true}}`)
+	require.NoError(t, err)
+	c3, tapCh := readWs(t, "/test/ruleTap")
+	defer c3.Close()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, TrialManager.StartRule(id))
+	select {
+	case data := <-tapCh:
+		require.Equal(t, "{\"humidity\":50,\"temperature\":22.5}", string(data))
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "receive timeout")
+	}
+	TrialManager.StopRule(id)
+}
@@ -15,28 +15,55 @@
 package trial
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/io/http/httpserver"
+	"github.com/lf-edge/ekuiper/v2/internal/io/memory/pubsub"
 	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
 	"github.com/lf-edge/ekuiper/v2/internal/topo"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/context"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/node"
 	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
 	"github.com/lf-edge/ekuiper/v2/pkg/connection"
 	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 	"github.com/lf-edge/ekuiper/v2/pkg/infra"
 )
 
+const (
+	defaultTrialDuration = 5 * time.Minute
+	maxTrialDuration     = 30 * time.Minute
+)
+
 type RunDef struct {
-	Id        string                    `json:"id"`
-	Sql       string                    `json:"sql"`
-	Mock      map[string]map[string]any `json:"mockSource"`
-	SinkProps map[string]any            `json:"sinkProps"`
+	Id   string                    `json:"id"`
+	Sql  string                    `json:"sql"`
+	Mock map[string]map[string]any `json:"mockSource"`
+	// Recordings are the files in the uploads folder with the recorded messages of the streams to replay
+	Recordings map[string]string `json:"recordings"`
+	// Tap are the streams to read their live data. The source is shared with the running rules if they share it too.
+	Tap       []string       `json:"tap"`
+	SinkProps map[string]any `json:"sinkProps"`
+	// ShowOperators sends the outputs of each operator to another WebSocket endpoint
+	ShowOperators bool `json:"showOperators"`
+	// Duration is the max duration to run. Default to 5 minutes
+	Duration cast.DurationConf `json:"duration"`
+	// MaxOutputs is the max count of the results to send. The run stops after reaching it. 0 means no limit
+	MaxOutputs int `json:"maxOutputs"`
 
-	endpoint string
+	endpoint    string
+	opsEndpoint string
+	sendTopic   string
+	opsTopic    string
 }
 
 func genTrialRuleID(def *RunDef) string {
@@ -57,6 +84,18 @@ func genTrialRule(rd *RunDef, sinkProps map[string]interface{}) *def.Rule {
 }
 
 func create(def *RunDef) (*topo.Topo, error) {
+	if def.Duration <= 0 {
+		def.Duration = cast.DurationConf(defaultTrialDuration)
+	} else if time.Duration(def.Duration) > maxTrialDuration {
+		return nil, fmt.Errorf("duration %s exceeds the max duration %s", time.Duration(def.Duration), maxTrialDuration)
+	}
+	if def.MaxOutputs < 0 {
+		return nil, fmt.Errorf("maxOutputs must not be negative")
+	}
+	mock, err := mockSources(def)
+	if err != nil {
+		return nil, err
+	}
 	endpoint := "/test/" + def.Id
 	def.endpoint = fmt.Sprintf("$$ws/%s", endpoint)
 	sinkProps := map[string]any{
@@ -64,13 +103,21 @@ func create(def *RunDef) (*topo.Topo, error) {
 		"sendError":  true,
 		"datasource": endpoint,
 	}
-	cw, err := connection.FetchConnection(context.Background(), def.endpoint, "websocket", sinkProps, nil)
+	def.sendTopic, err = fetchWebsocket(def.endpoint, sinkProps)
 	if err != nil {
 		return nil, err
 	}
-	_, err = cw.Wait(context.Background())
-	if err != nil {
-		return nil, err
+	if def.ShowOperators {
+		opsEndpoint := endpoint + "/operators"
+		def.opsEndpoint = fmt.Sprintf("$$ws/%s", opsEndpoint)
+		def.opsTopic, err = fetchWebsocket(def.opsEndpoint, map[string]any{
+			"path":       opsEndpoint,
+			"datasource": opsEndpoint,
+		})
+		if err != nil {
+			_ = connection.DetachConnection(context.Background(), def.endpoint)
+			return nil, err
+		}
 	}
 
 	for k, v := range def.SinkProps {
@@ -78,17 +125,166 @@ func create(def *RunDef) (*topo.Topo, error) {
 	}
 	trialRule := genTrialRule(def, sinkProps)
 	// Add trial run prefix for rule id to avoid duplicate rule id with real rules in runtime or other trial rule
-	tp, err := planner.PlanSQLWithSourcesAndSinks(trialRule, def.Mock)
+	tp, err := planner.PlanSQLWithSourcesAndSinks(trialRule, mock)
 	if err != nil {
+		detachEndpoints(def)
 		return nil, fmt.Errorf("fail to run rule %s: %s", def.Id, err)
 	}
 	return tp, nil
 }
 
-func trialRun(tp *topo.Topo, endpoint string) {
+// fetchWebsocket starts the WebSocket endpoint and returns the topic to send data to it
+func fetchWebsocket(endpoint string, props map[string]any) (string, error) {
+	cw, err := connection.FetchConnection(context.Background(), endpoint, "websocket", props, nil)
+	if err != nil {
+		return "", err
+	}
+	conn, err := cw.Wait(context.Background())
+	if err != nil {
+		return "", err
+	}
+	if c, ok := conn.(*httpserver.WebsocketConnection); ok {
+		return c.SendTopic, nil
+	}
+	return "", nil
+}
+
+func detachEndpoints(def *RunDef) {
+	_ = connection.DetachConnection(context.Background(), def.endpoint)
+	if def.opsEndpoint != "" {
+		_ = connection.DetachConnection(context.Background(), def.opsEndpoint)
+	}
+}
+
+// mockSources merges the mock sources, the recordings and the taps. Each stream can only be set by one of them.
+func mockSources(def *RunDef) (map[string]map[string]any, error) {
+	mock := make(map[string]map[string]any, len(def.Mock)+len(def.Recordings)+len(def.Tap))
+	for k, v := range def.Mock {
+		mock[k] = v
+	}
+	for s, file := range def.Recordings {
+		if _, ok := mock[s]; ok {
+			return nil, fmt.Errorf("stream %s is set more than once in mockSource, recordings and tap", s)
+		}
+		inputs, err := readRecording(file)
+		if err != nil {
+			return nil, err
+		}
+		mock[s], _ = replayProps(inputs)
+	}
+	for _, s := range def.Tap {
+		if _, ok := mock[s]; ok {
+			return nil, fmt.Errorf("stream %s is set more than once in mockSource, recordings and tap", s)
+		}
+		mock[s] = map[string]any{planner.MockTapKey: true}
+	}
+	return mock, nil
+}
+
+// readRecording reads the recorded messages from the file in the uploads folder. The file is a json array or json
+// lines of the messages with their timestamps in milliseconds relative to the start.
+func readRecording(name string) ([]*TimedData, error) {
+	dataDir, err := conf.GetDataLoc()
+	if err != nil {
+		return nil, err
+	}
+	if name != filepath.Base(name) {
+		return nil, fmt.Errorf("invalid recording file name %s", name)
+	}
+	content, err := os.ReadFile(filepath.Join(dataDir, "uploads", name))
+	if err != nil {
+		return nil, fmt.Errorf("fail to read recording %s: %v", name, err)
+	}
+	content = bytes.TrimSpace(content)
+	var inputs []*TimedData
+	if len(content) > 0 && content[0] == '[' {
+		err = json.Unmarshal(content, &inputs)
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(content))
+		for dec.More() {
+			in := &TimedData{}
+			if err = dec.Decode(in); err != nil {
+				break
+			}
+			inputs = append(inputs, in)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse recording %s: %v", name, err)
+	}
+	return inputs, nil
+}
+
+// watch sends the outputs of each operator to the operators endpoint if enabled and counts the results sent to the
+// WebSocket. The limit channel is closed once the max outputs are sent. It returns the function to stop watching.
+func watch(tp *topo.Topo, rd *RunDef) (<-chan struct{}, func()) {
+	limit := make(chan struct{})
+	done := make(chan struct{})
+	tapName := "trial_" + rd.Id
+	var emitters []node.Emitter
+	if rd.ShowOperators && rd.opsTopic != "" {
+		pubsub.CreatePub(rd.opsTopic)
+		for _, e := range tp.GetEmitters() {
+			tn, ok := e.(node.TopNode)
+			if !ok {
+				continue
+			}
+			name := tn.GetName()
+			ch := make(chan any, 1024)
+			if err := e.AddOutput(ch, tapName+"_ops"); err != nil {
+				continue
+			}
+			emitters = append(emitters, e)
+			go func() {
+				for {
+					select {
+					case d := <-ch:
+						if v, ok := toOutput(d); ok {
+							b, _ := json.Marshal(map[string]any{"operator": name, "data": v})
+							pubsub.ProduceAny(tp.GetContext(), rd.opsTopic, b)
+						}
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+	}
+	if rd.MaxOutputs > 0 && rd.sendTopic != "" {
+		ch := pubsub.CreateSub(rd.sendTopic, nil, tapName, 1024)
+		var count atomic.Int64
+		go func() {
+			defer pubsub.CloseSourceConsumerChannel(rd.sendTopic, tapName)
+			for {
+				select {
+				case <-ch:
+					if count.Add(1) == int64(rd.MaxOutputs) {
+						close(limit)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	return limit, func() {
+		close(done)
+		for _, e := range emitters {
+			// The shared source is kept after the run, so the taps must be removed
+			_ = e.RemoveOutput(tapName)
+		}
+		if len(emitters) > 0 {
+			pubsub.RemovePub(rd.opsTopic)
+		}
+	}
+}
+
+func trialRun(tp *topo.Topo, rd *RunDef) {
 	go func() {
-		defer connection.DetachConnection(context.Background(), endpoint)
-		timeout := time.After(5 * time.Minute)
+		defer detachEndpoints(rd)
+		limit, stop := watch(tp, rd)
+		defer stop()
+		timeout := time.After(time.Duration(rd.Duration))
 		err := infra.SafeRun(func() error {
 			select {
 			case err := <-tp.Open():
@@ -105,6 +301,11 @@ func trialRun(tp *topo.Topo, endpoint string) {
 					tp.Cancel()
 					return nil
 				}
+			case <-limit:
+				// Keep the WebSocket until timeout so that the client can receive all the results
+				tp.Cancel()
+				tp.GetContext().GetLogger().Debugf("trial run stops after sending %d results, wait for timeout", rd.MaxOutputs)
+				<-timeout
 			case <-timeout:
 				tp.GetContext().GetLogger().Debugf("trial run stops after timeout")
 				tp.Cancel()
@@ -0,0 +1,237 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trial
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/def"
+	"github.com/lf-edge/ekuiper/v2/internal/topo/planner"
+	"github.com/lf-edge/ekuiper/v2/internal/xsql"
+	"github.com/lf-edge/ekuiper/v2/pkg/cast"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+const (
+	defaultSpecTolerance = 200 * time.Millisecond
+	specSinkName         = "nop"
+)
+
+// TestSpec is the declarative test case of a rule. The inputs are replayed to the streams at their timestamps, the
+// lookup tables are mocked by the static rows and the outputs of the rule are compared with the expected ones.
+type TestSpec struct {
+	Name string `json:"name"`
+	// Rule is the id of an existing rule to test. Its sql and options are used if Sql is not set.
+	Rule    string                      `json:"rule,omitempty"`
+	Sql     string                      `json:"sql,omitempty"`
+	Options *def.RuleOption             `json:"options,omitempty"`
+	Inputs  map[string][]*TimedData     `json:"inputs"`
+	Lookups map[string][]map[string]any `json:"lookups,omitempty"`
+	// Expected are the outputs of the rule in order
+	Expected []*TimedData `json:"expected"`
+	// Tolerance is the max difference allowed between the expected and the actual timestamp of an output
+	Tolerance cast.DurationConf `json:"tolerance,omitempty"`
+	// Timeout is the max duration to run the test. Default to 5 seconds after the last timestamp.
+	Timeout cast.DurationConf `json:"timeout,omitempty"`
+}
+
+// TimedData is an input or output message with the timestamp in milliseconds relative to the start of the test
+type TimedData struct {
+	Data      map[string]any `json:"data"`
+	Timestamp *int64         `json:"timestamp,omitempty"`
+}
+
+// SpecResult is the result of a test spec. All the mismatches are listed in failures.
+type SpecResult struct {
+	Name     string       `json:"name"`
+	Passed   bool         `json:"passed"`
+	Outputs  []*TimedData `json:"outputs"`
+	Failures []string     `json:"failures,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// ParseTestSpecs parses a single spec or an array of specs
+func ParseTestSpecs(content []byte) ([]*TestSpec, error) {
+	content = bytes.TrimSpace(content)
+	var specs []*TestSpec
+	var err error
+	if len(content) > 0 && content[0] == '[' {
+		err = json.Unmarshal(content, &specs)
+	} else {
+		spec := &TestSpec{}
+		err = json.Unmarshal(content, spec)
+		specs = []*TestSpec{spec}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse test spec: %v", err)
+	}
+	for i, spec := range specs {
+		if spec.Name == "" {
+			spec.Name = fmt.Sprintf("spec%d", i)
+		}
+	}
+	return specs, nil
+}
+
+// RunTestSpec runs the rule of the spec with the mocked inputs and a nop sink, and then checks the outputs.
+// The error is returned only if the spec is invalid. The failure of the rule run is reported in the result.
+func RunTestSpec(spec *TestSpec) (*SpecResult, error) {
+	if spec.Sql == "" {
+		return nil, fmt.Errorf("spec %s: sql is required", spec.Name)
+	}
+	stmt, err := xsql.GetStatementFromSql(spec.Sql)
+	if err != nil {
+		return nil, fmt.Errorf("spec %s: %v", spec.Name, err)
+	}
+	var last int64
+	mock := make(map[string]map[string]any)
+	for _, s := range xsql.GetStreams(stmt) {
+		if rows, ok := spec.Lookups[s]; ok {
+			mock[s] = map[string]any{"data": rows}
+			continue
+		}
+		inputs, ok := spec.Inputs[s]
+		if !ok {
+			return nil, fmt.Errorf("spec %s: inputs of stream %s are not set", spec.Name, s)
+		}
+		props, ts := replayProps(inputs)
+		if ts > last {
+			last = ts
+		}
+		mock[s] = props
+	}
+	for _, e := range spec.Expected {
+		if e.Timestamp != nil && *e.Timestamp > last {
+			last = *e.Timestamp
+		}
+	}
+	tolerance := time.Duration(spec.Tolerance)
+	if tolerance <= 0 {
+		tolerance = defaultSpecTolerance
+	}
+	// keep the sources open until the last expected output is due
+	duration := time.Duration(last)*time.Millisecond + tolerance
+	for s, m := range mock {
+		if _, ok := spec.Lookups[s]; !ok {
+			m["duration"] = duration.String()
+		}
+	}
+	timeout := time.Duration(spec.Timeout)
+	if timeout <= 0 {
+		timeout = duration + defaultDryRunTimeout
+	}
+	rule := def.GetDefaultRule("$$_test_"+uuid.New().String(), spec.Sql)
+	if spec.Options != nil {
+		rule.Options = spec.Options
+	}
+	rule.Options.SendError = true
+	rule.Actions = []map[string]any{{specSinkName: map[string]any{"sendSingle": true}}}
+	tp, err := planner.PlanSQLWithSourcesAndSinks(rule, mock)
+	if err != nil {
+		return nil, fmt.Errorf("spec %s: %v", spec.Name, err)
+	}
+	result := &SpecResult{Name: spec.Name, Outputs: make([]*TimedData, 0)}
+	var mu sync.Mutex
+	start := timex.GetNow()
+	runErr, err := runTopo(tp, timeout, func(name string) func(d any) {
+		// collect the results before encoding of the sink
+		if !strings.HasPrefix(name, specSinkName+"_") || !strings.HasSuffix(name, "_transform") {
+			return func(any) {}
+		}
+		return func(d any) {
+			v, ok := toOutput(d)
+			if !ok {
+				return
+			}
+			m, ok := v.(map[string]any)
+			if !ok {
+				return
+			}
+			ts := timex.GetNow().Sub(start).Milliseconds()
+			mu.Lock()
+			result.Outputs = append(result.Outputs, &TimedData{Data: m, Timestamp: &ts})
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Error = runErr
+	result.Failures = compareOutputs(spec.Expected, result.Outputs, tolerance)
+	result.Passed = runErr == "" && len(result.Failures) == 0
+	return result, nil
+}
+
+// replayProps converts the timed data to the props of the mock source to replay them at their timestamps. It returns
+// the timestamp of the last data too.
+func replayProps(inputs []*TimedData) (map[string]any, int64) {
+	data := make([]map[string]any, len(inputs))
+	timestamps := make([]int64, len(inputs))
+	var ts int64
+	for i, in := range inputs {
+		// the data without timestamp is sent along with the previous one
+		if in.Timestamp != nil {
+			ts = *in.Timestamp
+		}
+		data[i] = in.Data
+		timestamps[i] = ts
+	}
+	return map[string]any{"data": data, "timestamps": timestamps, "interval": 1, "loop": false}, ts
+}
+
+// compareOutputs compares the outputs with the expected ones in order. The data are compared in their json form.
+func compareOutputs(expected, actual []*TimedData, tolerance time.Duration) []string {
+	var failures []string
+	if len(expected) != len(actual) {
+		failures = append(failures, fmt.Sprintf("expect %d outputs but got %d", len(expected), len(actual)))
+	}
+	for i := 0; i < len(expected) && i < len(actual); i++ {
+		e, a := expected[i], actual[i]
+		if !jsonEqual(e.Data, a.Data) {
+			failures = append(failures, fmt.Sprintf("output %d: expect %s but got %s", i, toJson(e.Data), toJson(a.Data)))
+		}
+		if e.Timestamp != nil {
+			diff := time.Duration(*a.Timestamp-*e.Timestamp) * time.Millisecond
+			if diff < -tolerance || diff > tolerance {
+				failures = append(failures, fmt.Sprintf("output %d: expect timestamp %d±%d but got %d", i, *e.Timestamp, tolerance.Milliseconds(), *a.Timestamp))
+			}
+		}
+	}
+	return failures
+}
+
+func jsonEqual(a, b any) bool {
+	var na, nb any
+	if json.Unmarshal([]byte(toJson(a)), &na) != nil || json.Unmarshal([]byte(toJson(b)), &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
+
+func toJson(v any) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bs)
+}
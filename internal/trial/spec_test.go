@@ -0,0 +1,95 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trial
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/internal/pkg/store"
+	"github.com/lf-edge/ekuiper/v2/internal/processor"
+	"github.com/lf-edge/ekuiper/v2/pkg/timex"
+)
+
+func TestRunTestSpec(t *testing.T) {
+	conf.IsTesting = true
+	conf.InitConf()
+	dataDir, err := conf.GetDataLoc()
+	require.NoError(t, err)
+	require.NoError(t, store.SetupDefault(dataDir))
+	p := processor.NewStreamProcessor()
+	_, _ = p.ExecStmt("DROP STREAM demoSpec")
+	_, _ = p.ExecStmt("DROP TABLE lookupSpec")
+	_, err = p.ExecStmt(`CREATE STREAM demoSpec () WITH (DATASOURCE="demoSpec", TYPE="mqtt", FORMAT="json")`)
+	require.NoError(t, err)
+	defer p.ExecStmt("DROP STREAM demoSpec")
+	_, err = p.ExecStmt(`CREATE TABLE lookupSpec () WITH (DATASOURCE="lookupSpec", TYPE="memory", KIND="lookup", KEY="id")`)
+	require.NoError(t, err)
+	defer p.ExecStmt("DROP TABLE lookupSpec")
+
+	closeCh := make(chan struct{})
+	defer close(closeCh)
+	go func() {
+		for {
+			select {
+			case <-closeCh:
+				return
+			default:
+				timex.Add(time.Millisecond)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	specs, err := ParseTestSpecs([]byte(`[{
+		"name": "filter",
+		"sql": "SELECT name, value * 2 AS v FROM demoSpec WHERE value > 1",
+		"inputs": {"demoSpec": [
+			{"data": {"name": "a", "value": 1}, "timestamp": 0},
+			{"data": {"name": "b", "value": 2}, "timestamp": 500},
+			{"data": {"name": "c", "value": 3}}
+		]},
+		"expected": [
+			{"data": {"name": "b", "v": 4}, "timestamp": 500},
+			{"data": {"name": "c", "v": 6}, "timestamp": 500}
+		]
+	}, {
+		"sql": "SELECT demoSpec.name, lookupSpec.size FROM demoSpec INNER JOIN lookupSpec ON demoSpec.id = lookupSpec.id",
+		"inputs": {"demoSpec": [{"data": {"name": "a", "id": 1}}, {"data": {"name": "b", "id": 2}}]},
+		"lookups": {"lookupSpec": [{"id": 1, "size": 10}, {"id": 3, "size": 30}]},
+		"expected": [{"data": {"name": "a", "size": 10}}, {"data": {"name": "b", "size": 20}}]
+	}]`))
+	require.NoError(t, err)
+	require.Len(t, specs, 2)
+
+	r, err := RunTestSpec(specs[0])
+	require.NoError(t, err)
+	assert.True(t, r.Passed, r.Failures)
+	assert.Equal(t, "filter", r.Name)
+	require.Len(t, r.Outputs, 2)
+
+	r, err = RunTestSpec(specs[1])
+	require.NoError(t, err)
+	assert.Equal(t, "spec1", r.Name)
+	assert.False(t, r.Passed)
+	assert.Equal(t, []string{"expect 2 outputs but got 1"}, r.Failures)
+
+	_, err = RunTestSpec(&TestSpec{Name: "missing", Sql: "SELECT * FROM demoSpec"})
+	assert.EqualError(t, err, "spec missing: inputs of stream demoSpec are not set")
+}
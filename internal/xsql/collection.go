@@ -592,10 +592,17 @@ func (w WindowRangeValuer) Meta(_, _ string) (interface{}, bool) {
 type WindowRange struct {
 	windowStart int64
 	windowEnd   int64
+	// early is true if the result is the partial result of the window fired before the window closes
+	early bool
 }
 
 func NewWindowRange(windowStart int64, windowEnd int64) *WindowRange {
-	return &WindowRange{windowStart, windowEnd}
+	return &WindowRange{windowStart: windowStart, windowEnd: windowEnd}
+}
+
+// NewEarlyWindowRange creates the range of the partial window result fired before the window closes
+func NewEarlyWindowRange(windowStart int64, windowEnd int64) *WindowRange {
+	return &WindowRange{windowStart: windowStart, windowEnd: windowEnd, early: true}
 }
 
 func (r *WindowRange) FuncValue(key string) (interface{}, bool) {
@@ -606,6 +613,11 @@ func (r *WindowRange) FuncValue(key string) (interface{}, bool) {
 		return r.windowEnd, true
 	case "event_time":
 		return r.windowEnd, true
+	case "window_trigger":
+		if r.early {
+			return "early", true
+		}
+		return "final", true
 	default:
 		return nil, false
 	}
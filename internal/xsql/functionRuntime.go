@@ -68,6 +68,11 @@ func (fp *funcRuntime) Get(name string, funcId int) (api.Function, api.FunctionC
 			}
 		}
 		fctx := context.NewDefaultFuncContext(fp.parentCtx, funcId)
+		if fp.parentCtx != nil && function.IsAnalyticFunc(name) {
+			if limit, ok := fp.parentCtx.Value(context.StateLimitKey).(context.StateLimit); ok {
+				fctx.LimitState(limit)
+			}
+		}
 		fp.regs[funcId] = &funcReg{
 			ins: nf,
 			ctx: fctx,
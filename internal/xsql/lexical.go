@@ -105,9 +105,14 @@ func (s *Scanner) Scan() (tok ast.Token, lit string) {
 	case '/':
 		_, _ = s.ScanWhiteSpace()
 		if r := s.read(); r == '*' {
-			if err := s.skipUntilEndComment(); err != nil {
+			c, err := s.scanUntilEndComment()
+			if err != nil {
 				return ast.ILLEGAL, ""
 			}
+			// Keep the content of hint comments like /*+ PARALLEL(project, 4) */ for the parser
+			if strings.HasPrefix(c, "+") {
+				return ast.COMMENT, c
+			}
 			return ast.COMMENT, ""
 		} else {
 			s.unread()
@@ -369,22 +374,29 @@ func (s *Scanner) skipUntilNewline() {
 	}
 }
 
-func (s *Scanner) skipUntilEndComment() error {
+// scanUntilEndComment returns the content of the block comment without the ending */
+func (s *Scanner) scanUntilEndComment() (string, error) {
+	b := &strings.Builder{}
 	for {
 		if ch1 := s.read(); ch1 == '*' {
 			// We might be at the end.
 		star:
 			ch2 := s.read()
 			if ch2 == '/' {
-				return nil
+				return b.String(), nil
 			} else if ch2 == '*' {
 				// We are back in the state machine since we see a star.
+				b.WriteRune(ch1)
 				goto star
 			} else if ch2 == eof {
-				return io.EOF
+				return "", io.EOF
 			}
+			b.WriteRune(ch1)
+			b.WriteRune(ch2)
 		} else if ch1 == eof {
-			return io.EOF
+			return "", io.EOF
+		} else {
+			b.WriteRune(ch1)
 		}
 	}
 }
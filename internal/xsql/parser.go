@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-collections/collections/stack"
 
@@ -45,6 +46,65 @@ type Parser struct {
 	fn          int    // function index number
 	clause      string
 	sourceNames []string // source names in the from/join clause
+	// lambdaParams are the parameters of the lambda expressions being parsed, the inner one is at the end
+	lambdaParams []string
+}
+
+// parseHints parses the hint comment right after SELECT like /*+ PARALLEL(project, 4) PARALLEL(decode, 2) */
+func (p *Parser) parseHints() ([]*ast.Hint, error) {
+	for {
+		tok, lit := p.scan()
+		switch {
+		case tok == ast.WS || (tok == ast.COMMENT && lit == ""):
+			continue
+		case tok == ast.COMMENT:
+			return parseHintContent(strings.TrimPrefix(lit, "+"))
+		default:
+			p.unscan()
+			return nil, nil
+		}
+	}
+}
+
+func parseHintContent(content string) ([]*ast.Hint, error) {
+	var hints []*ast.Hint
+	s := NewScanner(strings.NewReader(content))
+	next := func() (ast.Token, string) {
+		for {
+			tok, lit := s.Scan()
+			if tok != ast.WS && tok != ast.COMMA {
+				return tok, lit
+			}
+		}
+	}
+	for {
+		tok, name := next()
+		if tok == ast.EOF {
+			return hints, nil
+		}
+		if tok != ast.IDENT {
+			return nil, fmt.Errorf("found %q, expected hint name", name)
+		}
+		if tok, lit := next(); tok != ast.LPAREN {
+			return nil, fmt.Errorf("found %q, expected ( after hint %s", lit, name)
+		}
+		h := &ast.Hint{Name: strings.ToUpper(name)}
+		for {
+			tok, lit := s.Scan()
+			switch tok {
+			case ast.WS, ast.COMMA:
+				continue
+			case ast.RPAREN:
+			case ast.EOF, ast.LPAREN, ast.ILLEGAL:
+				return nil, fmt.Errorf("found %q, expected ) to close hint %s", lit, name)
+			default:
+				h.Args = append(h.Args, lit)
+				continue
+			}
+			break
+		}
+		hints = append(hints, h)
+	}
 }
 
 func (p *Parser) ParseCondition() (ast.Expr, error) {
@@ -155,6 +215,11 @@ func (p *Parser) Parse() (*ast.SelectStatement, error) {
 	} else if tok != ast.SELECT {
 		return nil, fmt.Errorf("Found %q, Expected SELECT.\n", lit)
 	}
+	if hints, err := p.parseHints(); err != nil {
+		return nil, err
+	} else {
+		selects.Hints = hints
+	}
 	p.clause = "select"
 	if fields, err := p.parseFields(); err != nil {
 		return nil, err
@@ -239,10 +304,61 @@ func (p *Parser) parseSource() (ast.Sources, error) {
 	} else {
 		sources = append(sources, &ast.Table{Name: src, Alias: alias})
 	}
-
+	// parse the table functions like FROM demo, generate_series(1, 10) AS s
+	for {
+		if tok, _ := p.scanIgnoreWhitespace(); tok != ast.COMMA {
+			p.unscan()
+			break
+		}
+		tf, err := p.parseTableFunc()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, tf)
+	}
 	return sources, nil
 }
 
+func (p *Parser) parseTableFunc() (*ast.TableFunc, error) {
+	tok, lit := p.scanIgnoreWhitespace()
+	if tok != ast.IDENT {
+		return nil, fmt.Errorf("found %q, expected table function.", lit)
+	}
+	if tok1, lit1 := p.scanIgnoreWhitespace(); tok1 != ast.LPAREN {
+		return nil, fmt.Errorf("found %q after %s, expected table function.", lit1, lit)
+	}
+	exp, err := p.parseCall(lit)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := exp.(*ast.Call)
+	if !ok || (c.FuncType != ast.FuncTypeScalar && c.FuncType != ast.FuncTypeSrf) {
+		return nil, fmt.Errorf("function %s cannot be used as a table function", lit)
+	}
+	// the table functions run before the analytic functions and aggregations
+	ast.WalkFunc(c, func(n ast.Node) bool {
+		if f, ok := n.(*ast.Call); ok && (f.FuncType == ast.FuncTypeAgg || function.IsAnalyticFunc(f.Name)) {
+			err = fmt.Errorf("function %s cannot be used in table function %s", f.Name, c.Name)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	tf := &ast.TableFunc{Call: c}
+	if tok2, _ := p.scanIgnoreWhitespace(); tok2 == ast.AS {
+		if tok3, lit3 := p.scanIgnoreWhitespace(); tok3 == ast.IDENT {
+			tf.Alias = lit3
+		} else {
+			return nil, fmt.Errorf("found %q, expected alias of table function %s.", lit3, c.Name)
+		}
+	} else {
+		p.unscan()
+	}
+	return tf, nil
+}
+
 // TODO Current func has problems when the source includes white space.
 func (p *Parser) parseSourceLiteral() (string, string, error) {
 	var sourceSeg []string
@@ -613,19 +729,24 @@ func (p *Parser) ParseExpr() (ast.Expr, error) {
 			}
 			rhs = lp
 		}
-		for node := root; ; {
-			r, ok := node.RHS.(*ast.BinaryExpr)
-			if !ok || r.OP.Precedence() >= op.Precedence() {
-				node.RHS = &ast.BinaryExpr{LHS: node.RHS, RHS: rhs, OP: op}
-				break
-			}
-			node = r
+		insertBinaryExpr(root, op, rhs)
+	}
+}
+
+// insertBinaryExpr adds the operator into the expression tree by its precedence
+func insertBinaryExpr(root *ast.BinaryExpr, op ast.Token, rhs ast.Expr) {
+	for node := root; ; {
+		r, ok := node.RHS.(*ast.BinaryExpr)
+		if !ok || r.OP.Precedence() >= op.Precedence() {
+			node.RHS = &ast.BinaryExpr{LHS: node.RHS, RHS: rhs, OP: op}
+			break
 		}
+		node = r
 	}
 }
 
 func (p *Parser) parseBetween(lhs ast.Expr, op ast.Token) (ast.Expr, error) {
-	alhs, err := p.parseUnaryExpr(false)
+	alhs, err := p.parseBetweenBound()
 	if err != nil {
 		return nil, err
 	}
@@ -633,7 +754,7 @@ func (p *Parser) parseBetween(lhs ast.Expr, op ast.Token) (ast.Expr, error) {
 	if opp != ast.AND {
 		return nil, fmt.Errorf("expect AND expression after between but found %s", opp)
 	}
-	arhs, err := p.parseUnaryExpr(false)
+	arhs, err := p.parseBetweenBound()
 	if err != nil {
 		return nil, err
 	}
@@ -647,6 +768,32 @@ func (p *Parser) parseBetween(lhs ast.Expr, op ast.Token) (ast.Expr, error) {
 	}, nil
 }
 
+// parseBetweenBound parses the bound of BETWEEN which can be an arithmetic expression like a.ts + INTERVAL '5' SECOND
+func (p *Parser) parseBetweenBound() (ast.Expr, error) {
+	root := &ast.BinaryExpr{}
+	var err error
+	root.RHS, err = p.parseUnaryExpr(false)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, _ := p.scanIgnoreWhitespace()
+		switch op {
+		case ast.ASTERISK:
+			op = ast.MUL
+		case ast.ADD, ast.SUB, ast.DIV, ast.MOD:
+		default:
+			p.unscan()
+			return root.RHS, nil
+		}
+		rhs, err := p.parseUnaryExpr(false)
+		if err != nil {
+			return nil, err
+		}
+		insertBinaryExpr(root, op, rhs)
+	}
+}
+
 func (p *Parser) parseUnaryExpr(isSubField bool) (ast.Expr, error) {
 	if tok1, _ := p.scanIgnoreWhitespace(); tok1 == ast.LPAREN {
 		expr, err := p.ParseExpr()
@@ -671,9 +818,13 @@ func (p *Parser) parseUnaryExpr(isSubField bool) (ast.Expr, error) {
 	if tok == ast.CASE {
 		return p.parseCaseExpr()
 	} else if tok == ast.IDENT {
-		if tok1, _ := p.scanIgnoreWhitespace(); tok1 == ast.LPAREN {
+		tok1, lit1 := p.scanIgnoreWhitespace()
+		if tok1 == ast.LPAREN {
 			return p.parseCall(lit)
 		}
+		if strings.EqualFold(lit, "INTERVAL") && (tok1 == ast.STRING || tok1 == ast.SINGLEQUOTE || tok1 == ast.INTEGER) {
+			return p.parseInterval(lit1)
+		}
 		p.unscan() // Back the Lparen token
 		p.unscan() // Back the ident token
 		if n, err := p.parseFieldNameSections(isSubField); err != nil {
@@ -696,6 +847,16 @@ func (p *Parser) parseUnaryExpr(isSubField bool) (ast.Expr, error) {
 				}
 				return &ast.MetaRef{StreamName: ast.DefaultStream, Name: n[0]}, nil
 			} else {
+				if !isSubField && contains(p.lambdaParams, n[0]) {
+					if len(n) == 2 {
+						return &ast.BinaryExpr{
+							LHS: &ast.LambdaParamRef{Name: n[0]},
+							OP:  ast.ARROW,
+							RHS: &ast.JsonFieldRef{Name: n[1]},
+						}, nil
+					}
+					return &ast.LambdaParamRef{Name: n[0]}, nil
+				}
 				if len(n) == 2 {
 					if len(p.sourceNames) > 0 && !contains(p.sourceNames, n[0]) {
 						return &ast.BinaryExpr{
@@ -739,6 +900,32 @@ func (p *Parser) parseUnaryExpr(isSubField bool) (ast.Expr, error) {
 	return nil, fmt.Errorf("found %q, expected expression.", lit)
 }
 
+// parseInterval parses the interval literal like INTERVAL '5' SECOND after the value
+func (p *Parser) parseInterval(val string) (ast.Expr, error) {
+	v, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("found %q, expected integer value of interval.", val)
+	}
+	tok, lit := p.scanIgnoreWhitespace()
+	if !tok.IsTimeLiteral() {
+		switch strings.ToUpper(lit) {
+		case "DAY", "DAYS":
+			tok = ast.DD
+		case "HOUR", "HOURS":
+			tok = ast.HH
+		case "MINUTE", "MINUTES":
+			tok = ast.MI
+		case "SECOND", "SECONDS":
+			tok = ast.SS
+		case "MILLISECOND", "MILLISECONDS":
+			tok = ast.MS
+		default:
+			return nil, fmt.Errorf("found %q, expected time unit of interval.", lit)
+		}
+	}
+	return &ast.IntervalLiteral{Val: v, Unit: tok}, nil
+}
+
 func (p *Parser) parseValueSetExpr() (ast.Expr, error) {
 	valsetExpr := &ast.ValueSetExpr{
 		LiteralExprs: nil,
@@ -894,6 +1081,7 @@ func (p *Parser) parseCall(n string) (ast.Expr, error) {
 	if ft == ast.FuncTypeCols && p.clause != "select" {
 		return nil, fmt.Errorf("function %s can only be used inside the select clause", n)
 	}
+	lambdaIndex, isLambdaFunc := function.LambdaArgIndex(name)
 	var args []ast.Expr
 	for {
 		if tok, _ := p.scanIgnoreWhitespace(); tok == ast.RPAREN {
@@ -901,7 +1089,13 @@ func (p *Parser) parseCall(n string) (ast.Expr, error) {
 		}
 		p.unscan()
 
-		if exp, err := p.ParseExpr(); err != nil {
+		if isLambdaFunc && len(args) == lambdaIndex && (!function.IsLambdaArgOptional(name) || p.isLambdaAhead(args)) {
+			exp, err := p.parseLambda()
+			if err != nil {
+				return nil, fmt.Errorf("function %s: %v", name, err)
+			}
+			args = append(args, exp)
+		} else if exp, err := p.ParseExpr(); err != nil {
 			return nil, err
 		} else {
 			if ft == ast.FuncTypeCols {
@@ -943,11 +1137,24 @@ func (p *Parser) parseCall(n string) (ast.Expr, error) {
 			}
 		}
 		// Add context for some aggregate func
-		if name == "deduplicate" {
+		if name == "deduplicate" || name == "top_n" {
 			args = append([]ast.Expr{&ast.Wildcard{Token: ast.ASTERISK}}, args...)
 		}
 		c := &ast.Call{Name: name, Args: args, FuncId: p.fn, FuncType: ft}
 		p.fn += 1
+		// parse filter clause of aggregate functions
+		f, err := p.parseFilter()
+		if err != nil {
+			return nil, err
+		} else if f != nil {
+			if ft != ast.FuncTypeAgg {
+				return nil, fmt.Errorf("FILTER clause is only supported by aggregate functions, but got %s", name)
+			}
+			if HasAggFuncs(f) {
+				return nil, fmt.Errorf("FILTER clause of function %s cannot contain aggregate functions", name)
+			}
+			c.Filter = f
+		}
 		e := p.parseOver(c)
 		return c, e
 	} else {
@@ -977,6 +1184,72 @@ func (p *Parser) parseCall(n string) (ast.Expr, error) {
 	}
 }
 
+// isLambdaAhead checks if the next tokens start a lambda expression for the function whose lambda argument is optional.
+// The form with the function name as the first argument has no lambda, so that `data->items` is parsed as the json path.
+func (p *Parser) isLambdaAhead(args []ast.Expr) bool {
+	if len(args) > 0 {
+		if _, ok := args[0].(*ast.StringLiteral); ok {
+			return false
+		}
+	}
+	tok, _ := p.scanIgnoreWhitespace()
+	switch tok {
+	case ast.LPAREN:
+		p.unscan()
+		return true
+	case ast.IDENT:
+		next, _ := p.scanIgnoreWhitespace()
+		p.unscan()
+		p.unscan()
+		return next == ast.ARROW
+	default:
+		p.unscan()
+		return false
+	}
+}
+
+// parseLambda parses the lambda expression like `x -> x.temp > 50` or `(acc, x) -> acc + x`.
+// The lambda parameters can be referred in the body and shadow the fields with the same name.
+func (p *Parser) parseLambda() (*ast.LambdaExpr, error) {
+	var params []string
+	tok, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case ast.IDENT:
+		params = append(params, lit)
+	case ast.LPAREN:
+		for {
+			tok, lit = p.scanIgnoreWhitespace()
+			if tok != ast.IDENT {
+				return nil, fmt.Errorf("found %q, expected lambda parameter name", lit)
+			}
+			if contains(params, lit) {
+				return nil, fmt.Errorf("duplicate lambda parameter %s", lit)
+			}
+			params = append(params, lit)
+			tok, lit = p.scanIgnoreWhitespace()
+			if tok == ast.RPAREN {
+				break
+			}
+			if tok != ast.COMMA {
+				return nil, fmt.Errorf("found %q, expected , or ) in lambda parameters", lit)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("found %q, expected lambda expression", lit)
+	}
+	if tok, lit = p.scanIgnoreWhitespace(); tok != ast.ARROW {
+		return nil, fmt.Errorf("found %q, expected -> in lambda expression", lit)
+	}
+	origin := p.lambdaParams
+	p.lambdaParams = append(append([]string{}, origin...), params...)
+	defer func() { p.lambdaParams = origin }()
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.LambdaExpr{Params: params, Body: body}, nil
+}
+
 func (p *Parser) parseCaseExpr() (*ast.CaseExpr, error) {
 	c := &ast.CaseExpr{}
 	tok, _ := p.scanIgnoreWhitespace()
@@ -1551,6 +1824,31 @@ func (p *Parser) parseStreamOptions() (*ast.Options, error) {
 						case ast.KIND:
 							val := strings.ToLower(lit3)
 							opts.KIND = val
+						case ast.SCHEMA_POLICY:
+							val := strings.ToLower(lit3)
+							switch val {
+							case ast.SchemaPolicyFailFast, ast.SchemaPolicyNullable, ast.SchemaPolicyWiden:
+								opts.SCHEMA_POLICY = val
+							default:
+								return nil, fmt.Errorf("found %q, expect FAILFAST/NULLABLE/WIDEN value in %s option.", lit3, lit1)
+							}
+						case ast.TIMESTAMP_TZ:
+							if _, err := time.LoadLocation(lit3); err != nil {
+								return nil, fmt.Errorf("found %q, invalid timezone in %s option: %v.", lit3, lit1, err)
+							}
+							opts.TIMESTAMP_TZ = lit3
+						case ast.TIMESTAMP_POLICY:
+							val := strings.ToLower(lit3)
+							switch val {
+							case ast.TimestampPolicyError, ast.TimestampPolicyProcessing, ast.TimestampPolicyDrop:
+								opts.TIMESTAMP_POLICY = val
+							default:
+								return nil, fmt.Errorf("found %q, expect ERROR/PROCESSING/DROP value in %s option.", lit3, lit1)
+							}
+						case ast.DECOMPRESSION:
+							opts.DECOMPRESSION = strings.ToLower(lit3)
+						case ast.DECRYPTION:
+							opts.DECRYPTION = strings.ToLower(lit3)
 						default:
 							f := v.Elem().FieldByName(lit1)
 							if f.IsValid() {
@@ -1606,7 +1904,7 @@ func (p *Parser) ParseOver4Window() (ast.Expr, error) {
 	return expr, nil
 }
 
-// Only support filter on window now
+// parseFilter parses the FILTER (WHERE expr) clause of windows and aggregate functions
 func (p *Parser) parseFilter() (ast.Expr, error) {
 	if tok, _ := p.scanIgnoreWhitespace(); tok != ast.FILTER {
 		p.unscan()
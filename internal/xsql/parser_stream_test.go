@@ -104,6 +104,98 @@ func TestParser_ParseCreateStream(t *testing.T) {
 				},
 			},
 		},
+		{
+			s: `CREATE STREAM demo (
+					USERID BIGINT,
+				) WITH (DATASOURCE="users", FORMAT="JSON", SCHEMA_POLICY="Widen");`,
+			stmt: &ast.StreamStmt{
+				Name: ast.StreamName("demo"),
+				StreamFields: []ast.StreamField{
+					{Name: "USERID", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+				},
+				Options: &ast.Options{
+					DATASOURCE:    "users",
+					FORMAT:        "JSON",
+					SCHEMA_POLICY: ast.SchemaPolicyWiden,
+				},
+			},
+		},
+		{
+			s: `CREATE STREAM demo (
+					USERID BIGINT,
+				) WITH (DATASOURCE="users", FORMAT="JSON", SCHEMA_POLICY="ignore");`,
+			stmt: nil,
+			err:  `found "ignore", expect FAILFAST/NULLABLE/WIDEN value in SCHEMA_POLICY option.`,
+		},
+		{
+			s: `CREATE STREAM demo (
+					header STRUCT(ts STRING),
+				) WITH (DATASOURCE="users", FORMAT="JSON", TIMESTAMP="header.ts", TIMESTAMP_FORMAT="yyyy-MM-dd HH:mm:ss", TIMESTAMP_TZ="Asia/Shanghai", TIMESTAMP_POLICY="Processing");`,
+			stmt: &ast.StreamStmt{
+				Name: ast.StreamName("demo"),
+				StreamFields: []ast.StreamField{
+					{Name: "header", FieldType: &ast.RecType{
+						StreamFields: []ast.StreamField{
+							{Name: "ts", FieldType: &ast.BasicType{Type: ast.STRINGS}},
+						},
+					}},
+				},
+				Options: &ast.Options{
+					DATASOURCE:       "users",
+					FORMAT:           "JSON",
+					TIMESTAMP:        "header.ts",
+					TIMESTAMP_FORMAT: "yyyy-MM-dd HH:mm:ss",
+					TIMESTAMP_TZ:     "Asia/Shanghai",
+					TIMESTAMP_POLICY: ast.TimestampPolicyProcessing,
+				},
+			},
+		},
+		{
+			s: `CREATE STREAM demo (
+					USERID BIGINT,
+				) WITH (DATASOURCE="users", FORMAT="JSON", DECOMPRESSION="GZIP");`,
+			stmt: &ast.StreamStmt{
+				Name: ast.StreamName("demo"),
+				StreamFields: []ast.StreamField{
+					{Name: "USERID", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+				},
+				Options: &ast.Options{
+					DATASOURCE:    "users",
+					FORMAT:        "JSON",
+					DECOMPRESSION: "gzip",
+				},
+			},
+		},
+		{
+			s: `CREATE STREAM demo (
+					USERID BIGINT,
+				) WITH (DATASOURCE="users", FORMAT="JSON", DECRYPTION="ChaCha20");`,
+			stmt: &ast.StreamStmt{
+				Name: ast.StreamName("demo"),
+				StreamFields: []ast.StreamField{
+					{Name: "USERID", FieldType: &ast.BasicType{Type: ast.BIGINT}},
+				},
+				Options: &ast.Options{
+					DATASOURCE: "users",
+					FORMAT:     "JSON",
+					DECRYPTION: "chacha20",
+				},
+			},
+		},
+		{
+			s: `CREATE STREAM demo (
+					USERID BIGINT,
+				) WITH (DATASOURCE="users", FORMAT="JSON", TIMESTAMP_POLICY="ignore");`,
+			stmt: nil,
+			err:  `found "ignore", expect ERROR/PROCESSING/DROP value in TIMESTAMP_POLICY option.`,
+		},
+		{
+			s: `CREATE STREAM demo (
+					USERID BIGINT,
+				) WITH (DATASOURCE="users", FORMAT="JSON", TIMESTAMP_TZ="Mars/Base");`,
+			stmt: nil,
+			err:  `found "Mars/Base", invalid timezone in TIMESTAMP_TZ option: unknown time zone Mars/Base.`,
+		},
 		{
 			s: `CREATE STREAM demo (
 					ADDRESSES ARRAY(STRUCT(STREET_NAME STRING, NUMBER BIGINT)),
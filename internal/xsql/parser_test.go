@@ -3654,11 +3654,40 @@ func TestParser_ParseWindowsExpr(t *testing.T) {
 				},
 			},
 		},
-		// to be supported
 		{
-			s:    `SELECT sum(f1) FILTER( where revenue > 100 ) FROM tbl GROUP BY year`,
+			s: `SELECT sum(f1) FILTER( where revenue > 100 ) FROM tbl GROUP BY year`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Expr: &ast.Call{
+							Name:     "sum",
+							FuncType: ast.FuncTypeAgg,
+							Args:     []ast.Expr{&ast.FieldRef{Name: "f1", StreamName: ast.DefaultStream}},
+							Filter: &ast.BinaryExpr{
+								LHS: &ast.FieldRef{Name: "revenue", StreamName: ast.DefaultStream},
+								OP:  ast.GT,
+								RHS: &ast.IntegerLiteral{Val: 100},
+							},
+						},
+						Name:  "sum",
+						AName: "",
+					},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "tbl"}},
+				Dimensions: ast.Dimensions{
+					ast.Dimension{Expr: &ast.FieldRef{Name: "year", StreamName: ast.DefaultStream}},
+				},
+			},
+		},
+		{
+			s:    `SELECT abs(f1) FILTER( where revenue > 100 ) FROM tbl`,
 			stmt: nil,
-			err:  "found \"FILTER\", expected FROM.",
+			err:  "FILTER clause is only supported by aggregate functions, but got abs",
+		},
+		{
+			s:    `SELECT count(*) FILTER( where avg(revenue) > 100 ) FROM tbl`,
+			stmt: nil,
+			err:  "FILTER clause of function count cannot contain aggregate functions",
 		},
 		{
 			s:    `SELECT * FROM demo GROUP BY COUNTWINDOW(3,1) FILTER where revenue > 100`,
@@ -4540,3 +4569,347 @@ func TestParser_ParseLimit(t *testing.T) {
 		require.Equal(t, tt.stmt, stmt)
 	}
 }
+
+func TestParser_ParseLambda(t *testing.T) {
+	tests := []struct {
+		s    string
+		stmt *ast.SelectStatement
+		err  string
+	}{
+		{
+			s: `SELECT array_filter(readings, x -> x.temp > threshold) AS r FROM demo`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						AName: "r",
+						Name:  "array_filter",
+						Expr: &ast.Call{
+							Name: "array_filter",
+							Args: []ast.Expr{
+								&ast.FieldRef{Name: "readings", StreamName: ast.DefaultStream},
+								&ast.LambdaExpr{
+									Params: []string{"x"},
+									Body: &ast.BinaryExpr{
+										LHS: &ast.BinaryExpr{
+											LHS: &ast.LambdaParamRef{Name: "x"},
+											OP:  ast.ARROW,
+											RHS: &ast.JsonFieldRef{Name: "temp"},
+										},
+										OP:  ast.GT,
+										RHS: &ast.FieldRef{Name: "threshold", StreamName: ast.DefaultStream},
+									},
+								},
+							},
+						},
+					},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "demo"}},
+			},
+		},
+		{
+			s: `SELECT array_reduce(a->nums, 0, (acc, x) -> acc + x) AS r FROM demo`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						AName: "r",
+						Name:  "array_reduce",
+						Expr: &ast.Call{
+							Name: "array_reduce",
+							Args: []ast.Expr{
+								&ast.BinaryExpr{
+									LHS: &ast.FieldRef{Name: "a", StreamName: ast.DefaultStream},
+									OP:  ast.ARROW,
+									RHS: &ast.JsonFieldRef{Name: "nums"},
+								},
+								&ast.IntegerLiteral{Val: 0},
+								&ast.LambdaExpr{
+									Params: []string{"acc", "x"},
+									Body: &ast.BinaryExpr{
+										LHS: &ast.LambdaParamRef{Name: "acc"},
+										OP:  ast.ADD,
+										RHS: &ast.LambdaParamRef{Name: "x"},
+									},
+								},
+							},
+						},
+					},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "demo"}},
+			},
+		},
+		{
+			s:   `SELECT array_filter(readings, x > 1) FROM demo`,
+			err: `function array_filter: found ">", expected -> in lambda expression`,
+		},
+		{
+			s:   `SELECT array_filter(readings, 1) FROM demo`,
+			err: `function array_filter: found "1", expected lambda expression`,
+		},
+		{
+			s:   `SELECT array_reduce(readings, 0, (x, x) -> x) FROM demo`,
+			err: `function array_reduce: duplicate lambda parameter x`,
+		},
+		{
+			s:   `SELECT array_filter(readings, (k, v) -> v > 1) FROM demo`,
+			err: `validate function array_filter error: the lambda expression of array_filter should have 1 parameters but got 2`,
+		},
+		{
+			s: `SELECT array_map("abs", readings) FROM demo`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Name: "array_map",
+						Expr: &ast.Call{
+							Name:     "array_map",
+							FuncType: ast.FuncTypeScalar,
+							Args: []ast.Expr{
+								&ast.StringLiteral{Val: "abs"},
+								&ast.FieldRef{StreamName: ast.DefaultStream, Name: "readings"},
+							},
+						},
+					},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "demo"}},
+			},
+		},
+		{
+			s: `SELECT array_map("upper", data->items) FROM demo`,
+			stmt: &ast.SelectStatement{
+				Fields: []ast.Field{
+					{
+						Name: "array_map",
+						Expr: &ast.Call{
+							Name:     "array_map",
+							FuncType: ast.FuncTypeScalar,
+							Args: []ast.Expr{
+								&ast.StringLiteral{Val: "upper"},
+								&ast.BinaryExpr{
+									LHS: &ast.FieldRef{Name: "data", StreamName: ast.DefaultStream},
+									OP:  ast.ARROW,
+									RHS: &ast.JsonFieldRef{Name: "items"},
+								},
+							},
+						},
+					},
+				},
+				Sources: []ast.Source{&ast.Table{Name: "demo"}},
+			},
+		},
+		{
+			s:   `SELECT array_map(readings) FROM demo`,
+			err: `validate function array_map error: Expect 2 arguments but found 1.`,
+		},
+	}
+
+	fmt.Printf("The test bucket size is %d.\n\n", len(tests))
+	for i, tt := range tests {
+		stmt, err := NewParser(strings.NewReader(tt.s)).Parse()
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+		} else if tt.err == "" && !reflect.DeepEqual(tt.stmt, stmt) {
+			t.Errorf("%d. %q\n\nstmt mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.s, tt.stmt, stmt)
+		}
+	}
+}
+
+func TestParser_ParseHints(t *testing.T) {
+	tests := []struct {
+		s     string
+		hints []*ast.Hint
+		err   string
+	}{
+		{
+			s: `SELECT /*+ PARALLEL(project, 4, deviceId) parallel(decode 2) */ a FROM demo`,
+			hints: []*ast.Hint{
+				{Name: "PARALLEL", Args: []string{"project", "4", "deviceId"}},
+				{Name: "PARALLEL", Args: []string{"decode", "2"}},
+			},
+		},
+		{
+			s: `SELECT /* normal comment */ /*+ PARALLEL(filter, 2) */ a FROM demo`,
+			hints: []*ast.Hint{
+				{Name: "PARALLEL", Args: []string{"FILTER", "2"}},
+			},
+		},
+		{
+			s: `SELECT a /*+ PARALLEL(filter, 2) */ FROM demo`,
+		},
+		{
+			s:   `SELECT /*+ PARALLEL project */ a FROM demo`,
+			err: `found "project", expected ( after hint PARALLEL`,
+		},
+		{
+			s:   `SELECT /*+ PARALLEL(project, 4 */ a FROM demo`,
+			err: `found "EOF", expected ) to close hint PARALLEL`,
+		},
+		{
+			s:   `SELECT /*+ 3(project) */ a FROM demo`,
+			err: `found "3", expected hint name`,
+		},
+	}
+	for i, tt := range tests {
+		stmt, err := NewParser(strings.NewReader(tt.s)).Parse()
+		if !reflect.DeepEqual(tt.err, testx.Errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+		} else if tt.err == "" && !reflect.DeepEqual(tt.hints, stmt.Hints) {
+			t.Errorf("%d. %q\n\nhints mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.s, tt.hints, stmt.Hints)
+		}
+	}
+}
+
+func TestParser_ParseTableFunc(t *testing.T) {
+	tests := []struct {
+		s       string
+		sources ast.Sources
+		err     string
+	}{
+		{
+			s: `SELECT s FROM demo, generate_series(1, a) AS s`,
+			sources: ast.Sources{
+				&ast.Table{Name: "demo"},
+				&ast.TableFunc{
+					Call: &ast.Call{
+						Name:     "generate_series",
+						FuncType: ast.FuncTypeSrf,
+						Args:     []ast.Expr{&ast.IntegerLiteral{Val: 1}, &ast.FieldRef{Name: "a", StreamName: ast.DefaultStream}},
+					},
+					Alias: "s",
+				},
+			},
+		},
+		{
+			s: `SELECT * FROM demo AS d, unnest(d.arr), abs(b)`,
+			sources: ast.Sources{
+				&ast.Table{Name: "demo", Alias: "d"},
+				&ast.TableFunc{
+					Call: &ast.Call{
+						Name:     "unnest",
+						FuncType: ast.FuncTypeSrf,
+						Args:     []ast.Expr{&ast.FieldRef{Name: "arr", StreamName: "d"}},
+					},
+				},
+				&ast.TableFunc{
+					Call: &ast.Call{
+						Name:     "abs",
+						FuncId:   1,
+						FuncType: ast.FuncTypeScalar,
+						Args:     []ast.Expr{&ast.FieldRef{Name: "b", StreamName: ast.DefaultStream}},
+					},
+				},
+			},
+		},
+		{
+			s:   `SELECT * FROM demo, count(a) AS c`,
+			err: "function count cannot be used as a table function",
+		},
+		{
+			s:   `SELECT * FROM demo, generate_series(1, lag(a))`,
+			err: "function lag cannot be used in table function generate_series",
+		},
+		{
+			s:   `SELECT * FROM demo, s`,
+			err: `found "EOF" after s, expected table function.`,
+		},
+		{
+			s:   `SELECT * FROM demo, generate_series(1, 2) AS 3`,
+			err: `found "3", expected alias of table function generate_series.`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			stmt, err := NewParser(strings.NewReader(tt.s)).Parse()
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.sources, stmt.Sources)
+		})
+	}
+}
+
+func TestParser_ParseInterval(t *testing.T) {
+	tests := []struct {
+		s    string
+		cond ast.Expr
+		err  string
+	}{
+		{
+			s: `SELECT * FROM demo WHERE b BETWEEN ts AND ts + INTERVAL '5' SECOND`,
+			cond: &ast.BinaryExpr{
+				OP:  ast.BETWEEN,
+				LHS: &ast.FieldRef{Name: "b", StreamName: ast.DefaultStream},
+				RHS: &ast.BetweenExpr{
+					Lower: &ast.FieldRef{Name: "ts", StreamName: ast.DefaultStream},
+					Higher: &ast.BinaryExpr{
+						OP:  ast.ADD,
+						LHS: &ast.FieldRef{Name: "ts", StreamName: ast.DefaultStream},
+						RHS: &ast.IntervalLiteral{Val: 5, Unit: ast.SS},
+					},
+				},
+			},
+		},
+		{
+			s: `SELECT * FROM demo WHERE b BETWEEN ts - INTERVAL 1 MINUTE AND ts * 2 AND c > 1`,
+			cond: &ast.BinaryExpr{
+				OP: ast.AND,
+				LHS: &ast.BinaryExpr{
+					OP:  ast.BETWEEN,
+					LHS: &ast.FieldRef{Name: "b", StreamName: ast.DefaultStream},
+					RHS: &ast.BetweenExpr{
+						Lower: &ast.BinaryExpr{
+							OP:  ast.SUB,
+							LHS: &ast.FieldRef{Name: "ts", StreamName: ast.DefaultStream},
+							RHS: &ast.IntervalLiteral{Val: 1, Unit: ast.MI},
+						},
+						Higher: &ast.BinaryExpr{
+							OP:  ast.MUL,
+							LHS: &ast.FieldRef{Name: "ts", StreamName: ast.DefaultStream},
+							RHS: &ast.IntegerLiteral{Val: 2},
+						},
+					},
+				},
+				RHS: &ast.BinaryExpr{
+					OP:  ast.GT,
+					LHS: &ast.FieldRef{Name: "c", StreamName: ast.DefaultStream},
+					RHS: &ast.IntegerLiteral{Val: 1},
+				},
+			},
+		},
+		{
+			s: `SELECT * FROM demo WHERE b > interval 2 mi`,
+			cond: &ast.BinaryExpr{
+				OP:  ast.GT,
+				LHS: &ast.FieldRef{Name: "b", StreamName: ast.DefaultStream},
+				RHS: &ast.IntervalLiteral{Val: 2, Unit: ast.MI},
+			},
+		},
+		{
+			s: `SELECT * FROM demo WHERE interval < INTERVAL "1" DAYS`,
+			cond: &ast.BinaryExpr{
+				OP:  ast.LT,
+				LHS: &ast.FieldRef{Name: "interval", StreamName: ast.DefaultStream},
+				RHS: &ast.IntervalLiteral{Val: 1, Unit: ast.DD},
+			},
+		},
+		{
+			s:   `SELECT * FROM demo WHERE b > INTERVAL 'a' SECOND`,
+			err: `found "a", expected integer value of interval.`,
+		},
+		{
+			s:   `SELECT * FROM demo WHERE b > INTERVAL 5 WEEK`,
+			err: `found "WEEK", expected time unit of interval.`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			stmt, err := NewParser(strings.NewReader(tt.s)).Parse()
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.cond, stmt.Condition)
+		})
+	}
+}
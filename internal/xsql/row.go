@@ -301,13 +301,18 @@ func (r *RawTuple) Raw() []byte {
 }
 
 func (r *RawTuple) Meta(key, table string) (any, bool) {
-	v, ok := r.Metadata[key]
-	return v, ok
+	return r.Metadata.Meta(key, table)
+}
+
+// Value always returns false because the payload is not decoded. Only the metadata can be evaluated.
+func (r *RawTuple) Value(_, _ string) (any, bool) {
+	return nil, false
 }
 
 var (
 	_ api.RawTuple        = &RawTuple{}
 	_ api.HasDynamicProps = &RawTuple{}
+	_ Valuer              = &RawTuple{}
 )
 
 // Tuple The input row, produced by the source
@@ -318,6 +323,8 @@ type Tuple struct {
 	Timestamp time.Time
 	Metadata  Metadata // immutable
 	Props     map[string]string
+	// Rawdata is the original payload kept for the raw passthrough sinks when only part of the fields are decoded
+	Rawdata []byte
 
 	AffiliateRow
 	lock      sync.Mutex             // lock for the cachedMap, because it is possible to access by multiple sinks
@@ -29,3 +29,9 @@ func (t *WatermarkTuple) IsWatermark() bool {
 }
 
 type EOFTuple int
+
+// ItemBatch is a batch of the items like rows and collections sent between the operators in the batch execution mode.
+// It saves the channel and the per item overhead. It is unpacked before sending to the sinks.
+type ItemBatch struct {
+	Items []any
+}
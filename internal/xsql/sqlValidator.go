@@ -134,6 +134,14 @@ func isSRFExists(node ast.Node) bool {
 		// skip checking Fields
 		case ast.Fields:
 			return false
+		// the table function can be a set-returning-function, but its arguments cannot
+		case *ast.TableFunc:
+			for _, arg := range f.Call.Args {
+				if isSRFExists(arg) {
+					exists = true
+				}
+			}
+			return false
 		case *ast.Call:
 			if f.FuncType == ast.FuncTypeSrf {
 				exists = true
@@ -152,6 +160,11 @@ func validateFields(stmt *ast.SelectStatement, streamNames []string) {
 	for i, join := range stmt.Joins {
 		stmt.Joins[i].Expr = validateExpr(join.Expr, streamNames)
 	}
+	for _, source := range stmt.Sources {
+		if tf, ok := source.(*ast.TableFunc); ok {
+			validateExpr(tf.Call, streamNames)
+		}
+	}
 }
 
 // validateExpr checks if the streamName of a fieldRef is existed and covert it to json filed if not exist.
@@ -187,6 +200,9 @@ func validateExpr(expr ast.Expr, streamName []string) ast.Expr {
 		if e.WhenExpr != nil {
 			e.WhenExpr = validateExpr(e.WhenExpr, streamName)
 		}
+		if e.Filter != nil {
+			e.Filter = validateExpr(e.Filter, streamName)
+		}
 		return e
 	case *ast.BinaryExpr:
 		exp := ast.BinaryExpr{}
@@ -218,6 +234,9 @@ func validateExpr(expr ast.Expr, streamName []string) ast.Expr {
 	case *ast.LikePattern:
 		e.Expr = validateExpr(e.Expr, streamName)
 		return e
+	case *ast.LambdaExpr:
+		e.Body = validateExpr(e.Body, streamName)
+		return e
 	case *ast.FieldRef:
 		sn := string(expr.(*ast.FieldRef).StreamName)
 		if sn != string(ast.DefaultStream) && !contains(streamName, sn) {
@@ -64,6 +64,8 @@ type StreamInfo struct {
 	StreamType ast.StreamType `json:"streamType"`
 	StreamKind string         `json:"streamKind"`
 	Statement  string         `json:"statement"`
+	// Labels are the key value pairs to organize the streams and tables
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 func GetDataSourceStatement(m kv.KeyValue, name string) (*StreamInfo, error) {
@@ -29,9 +29,10 @@ import (
 var (
 	// implicitValueFuncs is a set of functions that event implicitly passes the value.
 	implicitValueFuncs = map[string]bool{
-		"window_start": true,
-		"window_end":   true,
-		"event_time":   true,
+		"window_start":   true,
+		"window_end":     true,
+		"event_time":     true,
+		"window_trigger": true,
 	}
 	// ImplicitStateFuncs is a set of functions that read/update global state implicitly.
 	ImplicitStateFuncs = map[string]bool{
@@ -242,6 +243,9 @@ type ValuerEval struct {
 	// IntegerFloatDivision will set the eval system to treat
 	// a division between two integers as a floating point division.
 	IntegerFloatDivision bool
+
+	// lambdaParams are the arguments of the enclosing lambda expressions
+	lambdaParams map[string]interface{}
 }
 
 // Eval evaluates an expression and returns a value.
@@ -261,6 +265,8 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 		return v.Eval(expr.Expr)
 	case *ast.StringLiteral:
 		return expr.Val
+	case *ast.IntervalLiteral:
+		return expr.Millis()
 	case *ast.BooleanLiteral:
 		return expr.Val
 	case *ast.ColonExpr:
@@ -329,9 +335,20 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 					switch ft {
 					case ast.FuncTypeAgg:
 						args = make([]interface{}, len(expr.Args))
+						var mask []interface{}
+						if aggreValuer, ok := valuer.(AggregateCallValuer); ok && expr.Filter != nil {
+							mask = aggreValuer.GetAllTuples().AggregateEval(expr.Filter, aggreValuer.GetSingleCallValuer())
+						}
 						for i, arg := range expr.Args {
 							if aggreValuer, ok := valuer.(AggregateCallValuer); ok {
 								args[i] = aggreValuer.GetAllTuples().AggregateEval(arg, aggreValuer.GetSingleCallValuer())
+								if mask != nil {
+									filtered, err := filterByMask(args[i].([]interface{}), mask)
+									if err != nil {
+										return fmt.Errorf("call %s error: %v", expr.Name, err)
+									}
+									args[i] = filtered
+								}
 							} else {
 								args[i] = v.Eval(arg)
 								if _, ok := args[i].(error); ok {
@@ -491,6 +508,10 @@ func (v *ValuerEval) Eval(expr ast.Expr) interface{} {
 		return []interface{}{
 			v.Eval(expr.Lower), v.Eval(expr.Higher),
 		}
+	case *ast.LambdaExpr:
+		return v.evalLambda(expr)
+	case *ast.LambdaParamRef:
+		return v.lambdaParams[expr.Name]
 	case *ast.LikePattern:
 		if expr.Pattern != nil {
 			return expr.Pattern
@@ -587,6 +608,26 @@ func (v *ValuerEval) evalBinaryExpr(expr *ast.BinaryExpr) interface{} {
 	}
 }
 
+// evalLambda returns a closure which evaluates the lambda body with the arguments bound to the parameters.
+// The closure captures the current row, so the body can also refer to the fields and the outer lambda parameters.
+func (v *ValuerEval) evalLambda(expr *ast.LambdaExpr) function.Lambda {
+	return func(args ...interface{}) interface{} {
+		params := make(map[string]interface{}, len(v.lambdaParams)+len(expr.Params))
+		for k, p := range v.lambdaParams {
+			params[k] = p
+		}
+		for i, name := range expr.Params {
+			if i < len(args) {
+				params[name] = args[i]
+			} else {
+				params[name] = nil
+			}
+		}
+		ve := &ValuerEval{Valuer: v.Valuer, IntegerFloatDivision: v.IntegerFloatDivision, lambdaParams: params}
+		return ve.Eval(expr.Body)
+	}
+}
+
 func (v *ValuerEval) evalCase(expr *ast.CaseExpr) interface{} {
 	if expr.Value != nil { // compare value to all when clause
 		ev := v.Eval(expr.Value)
@@ -1208,3 +1249,19 @@ func isFloat(para interface{}) bool {
 	}
 	return false
 }
+
+// filterByMask keeps the aggregate arguments of the rows whose FILTER condition is true
+func filterByMask(values []interface{}, mask []interface{}) ([]interface{}, error) {
+	result := make([]interface{}, 0, len(values))
+	for i, v := range values {
+		switch m := mask[i].(type) {
+		case error:
+			return nil, m
+		case bool:
+			if m {
+				result = append(result, v)
+			}
+		}
+	}
+	return result, nil
+}
@@ -22,11 +22,14 @@ const (
 	LblRuleIDType = "rule"
 	LblOpIDType   = "op"
 	LblIOType     = "io"
+	LblReasonType = "reason"
 
 	LBlRuleRunning = "running"
 	LblRuleStop    = "stop"
 	LblSourceIO    = "source"
 	LblSinkIO      = "sink"
+	LblEvictTTL    = "ttl"
+	LblEvictSize   = "size"
 )
 
 var (
@@ -50,6 +53,48 @@ var (
 		Name:      "cpu_ms",
 		Help:      "gauge of rule CPU usage",
 	}, []string{LblRuleIDType})
+
+	StateEvictionCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kuiper",
+		Subsystem: "state",
+		Name:      "evictions",
+		Help:      "counter of the evicted keys of the keyed states",
+	}, []string{LblRuleIDType, LblOpIDType, LblReasonType})
+
+	RawPassthroughCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kuiper",
+		Subsystem: "sink",
+		Name:      "raw_passthrough",
+		Help:      "counter of the messages sent by the raw passthrough without decode and encode",
+	}, []string{LblRuleIDType, LblOpIDType})
+
+	MemoryUsedGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kuiper",
+		Subsystem: "memory",
+		Name:      "used_bytes",
+		Help:      "gauge of the heap memory used by the process, sampled by the memory manager",
+	})
+
+	MemoryLimitGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kuiper",
+		Subsystem: "memory",
+		Name:      "limit_bytes",
+		Help:      "gauge of the memory budget of the memory manager",
+	})
+
+	MemoryBackpressureGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kuiper",
+		Subsystem: "memory",
+		Name:      "backpressure",
+		Help:      "gauge of whether the sources are paused by the memory pressure, 1 for paused",
+	})
+
+	MemoryBackpressureCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "kuiper",
+		Subsystem: "memory",
+		Name:      "backpressure_total",
+		Help:      "counter of the times the sources are paused by the memory pressure",
+	})
 )
 
 func init() {
@@ -57,6 +102,12 @@ func init() {
 	prometheus.MustRegister(RuleStatusCountGauge)
 	prometheus.MustRegister(RuleStatusGauge)
 	prometheus.MustRegister(RuleCPUUsageGauge)
+	prometheus.MustRegister(StateEvictionCounter)
+	prometheus.MustRegister(RawPassthroughCounter)
+	prometheus.MustRegister(MemoryUsedGauge)
+	prometheus.MustRegister(MemoryLimitGauge)
+	prometheus.MustRegister(MemoryBackpressureGauge)
+	prometheus.MustRegister(MemoryBackpressureCounter)
 }
 
 func SetRuleStatusCountGauge(isRunning bool, count int) {
@@ -79,3 +130,30 @@ func RemoveRuleStatus(ruleID string) {
 func SetRuleCPUUsageGauge(ruleID string, value int) {
 	RuleCPUUsageGauge.WithLabelValues(ruleID).Set(float64(value))
 }
+
+func AddStateEvictions(ruleID, opID, reason string, count int) {
+	if count > 0 {
+		StateEvictionCounter.WithLabelValues(ruleID, opID, reason).Add(float64(count))
+	}
+}
+
+func IncRawPassthrough(ruleID, opID string) {
+	RawPassthroughCounter.WithLabelValues(ruleID, opID).Inc()
+}
+
+func SetMemoryUsed(used int64) {
+	MemoryUsedGauge.Set(float64(used))
+}
+
+func SetMemoryLimit(limit int64) {
+	MemoryLimitGauge.Set(float64(limit))
+}
+
+func SetMemoryBackpressure(paused bool) {
+	if paused {
+		MemoryBackpressureGauge.Set(1)
+		MemoryBackpressureCounter.Inc()
+	} else {
+		MemoryBackpressureGauge.Set(0)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 type Node interface {
@@ -93,6 +94,12 @@ type StringLiteral struct {
 	Val string
 }
 
+// IntervalLiteral is the time interval like INTERVAL '5' SECOND. It is evaluated to the milliseconds of the interval.
+type IntervalLiteral struct {
+	Val  int64
+	Unit Token
+}
+
 type NumberLiteral struct {
 	Val float64
 }
@@ -201,6 +208,29 @@ func (sl *StringLiteral) String() string {
 	return sl.Val
 }
 
+func (il *IntervalLiteral) expr()    {}
+func (il *IntervalLiteral) literal() {}
+func (il *IntervalLiteral) node()    {}
+func (il *IntervalLiteral) String() string {
+	return fmt.Sprintf("interval:{ %d %s }", il.Val, Tokens[il.Unit])
+}
+
+// Millis returns the milliseconds of the interval
+func (il *IntervalLiteral) Millis() int64 {
+	switch il.Unit {
+	case DD:
+		return il.Val * 24 * 3600 * 1000
+	case HH:
+		return il.Val * 3600 * 1000
+	case MI:
+		return il.Val * 60 * 1000
+	case SS:
+		return il.Val * 1000
+	default:
+		return il.Val
+	}
+}
+
 type FuncType int
 
 const (
@@ -228,6 +258,9 @@ type Call struct {
 
 	// This is used for window functions.
 	SortFields SortFields
+
+	// This is used for aggregate functions. Only the rows which match the filter are aggregated.
+	Filter Expr
 }
 
 func (c *Call) expr()    {}
@@ -249,7 +282,11 @@ func (c *Call) String() string {
 	if c.WhenExpr != nil {
 		when += ", when:{ " + c.WhenExpr.String() + " }"
 	}
-	return "Call:{ name:" + c.Name + args + when + " }"
+	filter := ""
+	if c.Filter != nil {
+		filter += ", filter:{ " + c.Filter.String() + " }"
+	}
+	return "Call:{ name:" + c.Name + args + when + filter + " }"
 }
 
 type PartitionExpr struct {
@@ -379,6 +416,30 @@ func (c *ValueSetExpr) String() string {
 	return "valueSetExpr:{ " + le + a + " }"
 }
 
+// LambdaExpr is an inline function such as `x -> x.temp > 50` or `(acc, x) -> acc + x`.
+// It can only be used as the argument of the higher order functions like array_map.
+type LambdaExpr struct {
+	Params []string
+	Body   Expr
+}
+
+func (l *LambdaExpr) expr() {}
+func (l *LambdaExpr) node() {}
+func (l *LambdaExpr) String() string {
+	return "lambda:{ params:[" + strings.Join(l.Params, ", ") + "], body:{ " + l.Body.String() + " } }"
+}
+
+// LambdaParamRef refers to a parameter of the enclosing lambda expression
+type LambdaParamRef struct {
+	Name string
+}
+
+func (l *LambdaParamRef) expr() {}
+func (l *LambdaParamRef) node() {}
+func (l *LambdaParamRef) String() string {
+	return "lambdaParam:" + l.Name
+}
+
 type BetweenExpr struct {
 	Lower  Expr
 	Higher Expr
@@ -34,6 +34,26 @@ const (
 	StreamKindScan   = "scan"
 )
 
+// The policies to handle the data which does not match the stream schema
+const (
+	// SchemaPolicyFailFast reports error for missing fields, mismatched types and any lossy numeric conversion
+	SchemaPolicyFailFast = "failfast"
+	// SchemaPolicyNullable sets the missing fields and the fields of mismatched type to null
+	SchemaPolicyNullable = "nullable"
+	// SchemaPolicyWiden keeps the wider numeric values and sets the missing fields to null
+	SchemaPolicyWiden = "widen"
+)
+
+// The policies to handle the event whose timestamp field is missing or malformed
+const (
+	// TimestampPolicyError reports error for the event
+	TimestampPolicyError = "error"
+	// TimestampPolicyProcessing uses the processing time as the event time
+	TimestampPolicyProcessing = "processing"
+	// TimestampPolicyDrop drops the event
+	TimestampPolicyDrop = "drop"
+)
+
 type StreamType int
 
 type StreamStmt struct {
@@ -240,6 +260,16 @@ type Options struct {
 	KIND string `json:"kind,omitempty"`
 	// for delimited format only
 	DELIMITER string `json:"delimiter,omitempty"`
+	// the policy to handle the data which does not match the schema
+	SCHEMA_POLICY string `json:"schemaPolicy,omitempty"`
+	// the timezone to parse the timestamp string without zone info
+	TIMESTAMP_TZ string `json:"timestampTz,omitempty"`
+	// the policy to handle the event whose timestamp field is missing or malformed
+	TIMESTAMP_POLICY string `json:"timestampPolicy,omitempty"`
+	// the method to decompress the payload before decoding, overrides the decompression of the source configuration
+	DECOMPRESSION string `json:"decompression,omitempty"`
+	// the method to decrypt the payload before decompressing, overrides the decryption of the source configuration
+	DECRYPTION string `json:"decryption,omitempty"`
 
 	RuleID       string                      `json:"-"`
 	Schema       map[string]*JsonStreamField `json:"-"`
@@ -30,10 +30,18 @@ type SelectStatement struct {
 	Dimensions Dimensions
 	Having     Expr
 	SortFields SortFields
+	Hints      []*Hint
 
 	Statement
 }
 
+// Hint is the planner hint in the comment after SELECT such as /*+ PARALLEL(project, 4) */
+type Hint struct {
+	// Name is the upper case name of the hint
+	Name string
+	Args []string
+}
+
 type Fields []Field
 
 func (f Fields) node() {}
@@ -111,6 +119,22 @@ type Table struct {
 	Source
 }
 
+// TableFunc is the table-valued function in the FROM clause such as generate_series(1, 10) AS s.
+// It is evaluated for each row of the stream and generates a row for each element of its result.
+type TableFunc struct {
+	Call  *Call
+	Alias string
+	Source
+}
+
+// ColName returns the name of the column to hold the generated values which is the alias or the function name
+func (t *TableFunc) ColName() string {
+	if t.Alias != "" {
+		return t.Alias
+	}
+	return t.Call.Name
+}
+
 type JoinType int
 
 const (
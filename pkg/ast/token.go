@@ -232,6 +232,11 @@ const (
 	SCHEMAID          = "SCHEMAID"
 	KIND              = "KIND"
 	DELIMITER         = "DELIMITER"
+	SCHEMA_POLICY     = "SCHEMA_POLICY"
+	TIMESTAMP_TZ      = "TIMESTAMP_TZ"
+	TIMESTAMP_POLICY  = "TIMESTAMP_POLICY"
+	DECOMPRESSION     = "DECOMPRESSION"
+	DECRYPTION        = "DECRYPTION"
 
 	XBIGINT   = "BIGINT"
 	XFLOAT    = "FLOAT"
@@ -257,6 +262,11 @@ var StreamTokens = map[string]struct{}{
 	SCHEMAID:          {},
 	KIND:              {},
 	DELIMITER:         {},
+	SCHEMA_POLICY:     {},
+	TIMESTAMP_TZ:      {},
+	TIMESTAMP_POLICY:  {},
+	DECOMPRESSION:     {},
+	DECRYPTION:        {},
 }
 
 var StreamDataTypes = map[string]DataType{
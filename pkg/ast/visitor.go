@@ -65,6 +65,9 @@ func Walk(v Visitor, node Node) {
 
 	// case *Table:
 
+	case *TableFunc:
+		Walk(v, n.Call)
+
 	case Joins:
 		for _, s := range n {
 			Walk(v, &s)
@@ -115,6 +118,10 @@ func Walk(v Visitor, node Node) {
 			Walk(v, n.WhenExpr)
 		}
 
+		if n.Filter != nil {
+			Walk(v, n.Filter)
+		}
+
 	case *ParenExpr:
 		Walk(v, n.Expr)
 
@@ -151,6 +158,9 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.Lower)
 		Walk(v, n.Higher)
 
+	case *LambdaExpr:
+		Walk(v, n.Body)
+
 	case *LikePattern:
 		Walk(v, n.Expr)
 
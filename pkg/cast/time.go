@@ -125,16 +125,29 @@ func InterfaceToTime(i interface{}, format string) (time.Time, error) {
 	}
 }
 
+// InterfaceToTimeInLocation converts the value to time like InterfaceToTime. The time string without zone info is parsed in the given location
+func InterfaceToTimeInLocation(i interface{}, format string, loc *time.Location) (time.Time, error) {
+	if t, ok := i.(string); ok && loc != nil {
+		return ParseTimeInLocation(t, format, loc)
+	}
+	return InterfaceToTime(i, format)
+}
+
 func TimeFromUnixMilli(t int64) time.Time {
 	return time.Unix(t/1000, (t%1000)*1e6).In(localTimeZone)
 }
 
 func ParseTime(t string, f string) (_ time.Time, err error) {
+	return ParseTimeInLocation(t, f, localTimeZone)
+}
+
+// ParseTimeInLocation parses the time string by the format. The time string without zone info is parsed in the given location
+func ParseTimeInLocation(t string, f string, loc *time.Location) (_ time.Time, err error) {
 	if f, err = convertFormat(f); err != nil {
 		return time.Time{}, err
 	}
 	c := &now.Config{
-		TimeLocation: localTimeZone,
+		TimeLocation: loc,
 		TimeFormats:  now.TimeFormats,
 	}
 	if f != "" {
@@ -209,6 +209,23 @@ func TestInterfaceToTime(t *testing.T) {
 	}
 }
 
+func TestInterfaceToTimeInLocation(t *testing.T) {
+	err := SetTimeZone("Asia/Shanghai")
+	require.NoError(t, err)
+	loc, err := time.LoadLocation("UTC")
+	require.NoError(t, err)
+	got, err := InterfaceToTimeInLocation("2022-04-13 06:22:32", "YYYY-MM-dd HH:mm:ss", loc)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1649830952000), got.UnixMilli())
+	got, err = InterfaceToTimeInLocation(int64(1649830952233), "", loc)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1649830952233), got.UnixMilli())
+	// Use the configured timezone if location is not set
+	got, err = InterfaceToTimeInLocation("2022-04-13 06:22:32", "YYYY-MM-dd HH:mm:ss", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1649802152000), got.UnixMilli())
+}
+
 func TestInterfaceToUnixMilli(t *testing.T) {
 	err := SetTimeZone("Asia/Shanghai")
 	require.NoError(t, err)
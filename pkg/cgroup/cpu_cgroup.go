@@ -0,0 +1,68 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	cGroupV2CPUMaxPath    = "/sys/fs/cgroup/cpu.max"
+	cGroupCPUQuotaPath    = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	cGroupCPUPeriodPath   = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+	cGroupV2CPUMaxUnlimit = "max"
+)
+
+// CPUQuotaCGroup returns the cpu cores limited by the cgroup. It returns 0 if there is no limit.
+func CPUQuotaCGroup() (float64, error) {
+	if v, err := os.ReadFile(cGroupV2CPUMaxPath); err == nil {
+		return parseCPUMax(strings.TrimSpace(string(v)))
+	}
+	quota, err := os.ReadFile(cGroupCPUQuotaPath)
+	if err != nil {
+		return 0, err
+	}
+	q, err := parseUint(strings.TrimSpace(string(quota)), 10, 64)
+	if err != nil || q == 0 {
+		return 0, err
+	}
+	p, err := readUint(cGroupCPUPeriodPath)
+	if err != nil || p == 0 {
+		return 0, err
+	}
+	return float64(q) / float64(p), nil
+}
+
+// parseCPUMax parses the cgroup v2 cpu.max such as "200000 100000" or "max 100000"
+func parseCPUMax(s string) (float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid cpu.max %s", s)
+	}
+	if fields[0] == cGroupV2CPUMaxUnlimit {
+		return 0, nil
+	}
+	q, err := parseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	p, err := parseUint(fields[1], 10, 64)
+	if err != nil || p == 0 {
+		return 0, fmt.Errorf("invalid cpu.max %s", s)
+	}
+	return float64(q) / float64(p), nil
+}
@@ -65,3 +65,21 @@ func (e *MockTemporaryError) Error() string {
 }
 
 func (e *MockTemporaryError) Temporary() bool { return true }
+
+// NodeError is the runtime error of a node in the rule. It keeps the name of the node to know where the error is from.
+type NodeError struct {
+	Node string
+	Err  error
+}
+
+func NewNodeError(node string, err error) error {
+	return &NodeError{Node: node, Err: err}
+}
+
+func (e *NodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
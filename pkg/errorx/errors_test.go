@@ -15,6 +15,7 @@
 package errorx
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,3 +39,12 @@ func TestErrorResult(t *testing.T) {
 	assert.Equal(t, "not found", err.Error())
 	assert.Equal(t, NOT_FOUND, err.Code())
 }
+
+func TestNodeError(t *testing.T) {
+	err := NewNodeError("op1", NewEOF())
+	assert.Equal(t, "done", err.Error())
+	assert.True(t, IsEOF(err))
+	var ne *NodeError
+	assert.True(t, errors.As(err, &ne))
+	assert.Equal(t, "op1", ne.Node)
+}
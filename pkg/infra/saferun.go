@@ -23,6 +23,7 @@ import (
 	"github.com/lf-edge/ekuiper/contract/v2/api"
 
 	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	"github.com/lf-edge/ekuiper/v2/pkg/errorx"
 )
 
 // SafeRun will catch and return the panic error together with other errors
@@ -58,6 +59,10 @@ func DrainError(ctx api.StreamContext, err error, errCh chan<- error) {
 		_, file, line, _ := runtime.Caller(1) // 1 means the caller of DrainError
 		if ctx != nil {
 			ctx.GetLogger().Errorf("runtime error from %s/l%d: %v", file, line, err)
+			// Keep the node of the error so that the rule can decide how to restart by the error class
+			if _, ok := err.(*errorx.NodeError); !ok && ctx.GetOpId() != "" {
+				err = errorx.NewNodeError(ctx.GetOpId(), err)
+			}
 		} else {
 			conf.Log.Errorf("runtime error %s/l%d: %v", file, line, err)
 		}
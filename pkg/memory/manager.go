@@ -0,0 +1,146 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+
+	"github.com/lf-edge/ekuiper/v2/internal/conf"
+	kmetrics "github.com/lf-edge/ekuiper/v2/metrics"
+)
+
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// gate is open when it is nil. Otherwise, it is a channel closed when the memory pressure is released.
+var gate atomic.Pointer[chan struct{}]
+
+// Manager watches the memory used by the whole process against the budget. All the buffered tuples, window states
+// and sink caches of all rules are in the heap, so the heap usage is the total memory of them. When the usage reaches
+// the high watermark, the sources are paused until the usage drops below the low watermark.
+// The event time windows and count windows only fire by the new data. To avoid that they hold the memory and never
+// fire while the sources are paused, a pause longer than maxPause is released for a check interval.
+type Manager struct {
+	limit    int64
+	high     int64
+	low      int64
+	maxPause time.Duration
+	pausedAt time.Time
+	// for test
+	readHeap func() int64
+	gc       func()
+	now      func() time.Time
+}
+
+// NewManager creates a manager with the limit in bytes, the high and low watermarks in the ratio of the limit and the
+// max duration to pause the sources at once
+func NewManager(limit int64, high, low float64, maxPause time.Duration) *Manager {
+	return &Manager{
+		limit:    limit,
+		high:     int64(float64(limit) * high),
+		low:      int64(float64(limit) * low),
+		maxPause: maxPause,
+		readHeap: readHeapObjects,
+		gc:       runtime.GC,
+		now:      time.Now,
+	}
+}
+
+// Start sets the limit as the soft memory limit of the go runtime unless GOMEMLIMIT is set, so that the GC runs more
+// often close to the limit. Then it checks the memory usage in the interval until the ctx is done.
+func (m *Manager) Start(ctx context.Context, interval time.Duration) {
+	if os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(m.limit)
+	}
+	kmetrics.SetMemoryLimit(m.limit)
+	conf.Log.Infof("memory manager starts with limit %d, high watermark %d and low watermark %d", m.limit, m.high, m.low)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				m.release()
+				return
+			case <-ticker.C:
+				m.check()
+			}
+		}
+	}()
+}
+
+func (m *Manager) check() {
+	used := m.readHeap()
+	// The heap objects include the garbage not collected yet, collect it before deciding
+	if used >= m.low && (used >= m.high || Paused()) {
+		m.gc()
+		used = m.readHeap()
+	}
+	kmetrics.SetMemoryUsed(used)
+	switch {
+	case !Paused() && used >= m.high:
+		ch := make(chan struct{})
+		gate.Store(&ch)
+		m.pausedAt = m.now()
+		kmetrics.SetMemoryBackpressure(true)
+		conf.Log.Warnf("memory used %d reaches the high watermark %d, pause the sources", used, m.high)
+	case Paused() && used < m.low:
+		m.release()
+		conf.Log.Infof("memory used %d drops below the low watermark %d, resume the sources", used, m.low)
+	case Paused() && m.maxPause > 0 && m.now().Sub(m.pausedAt) >= m.maxPause:
+		// Let the data in until the next check so that the watermarks progress and the windows fire
+		m.release()
+		conf.Log.Warnf("memory used %d is still above the low watermark %d after pausing %s, resume the sources until the next check", used, m.low, m.maxPause)
+	}
+}
+
+func (m *Manager) release() {
+	if ch := gate.Swap(nil); ch != nil {
+		close(*ch)
+		kmetrics.SetMemoryBackpressure(false)
+	}
+}
+
+// Paused returns whether the sources are paused by the memory pressure
+func Paused() bool {
+	return gate.Load() != nil
+}
+
+// Wait blocks until the memory pressure is released or the ctx is done. The sources call it before ingesting each
+// message, so that the consumption of the source like MQTT and Kafka is paused by not reading the next message.
+func Wait(ctx context.Context) {
+	ch := gate.Load()
+	if ch == nil {
+		return
+	}
+	select {
+	case <-*ch:
+	case <-ctx.Done():
+	}
+}
+
+func readHeapObjects() int64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return int64(sample[0].Value.Uint64())
+}
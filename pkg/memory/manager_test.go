@@ -0,0 +1,121 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagerBackpressure(t *testing.T) {
+	m := NewManager(1000, 0.9, 0.5, 0)
+	var (
+		used    int64
+		gcCount int
+		// the bytes released by each gc
+		garbage int64
+	)
+	m.readHeap = func() int64 { return used }
+	m.gc = func() {
+		gcCount++
+		used -= garbage
+	}
+	defer m.release()
+
+	tests := []struct {
+		used    int64
+		garbage int64
+		paused  bool
+		gc      int
+	}{
+		{used: 100, paused: false, gc: 0},
+		// Collected garbage below the high watermark, not paused
+		{used: 950, garbage: 100, paused: false, gc: 1},
+		{used: 950, paused: true, gc: 2},
+		// Still above the low watermark
+		{used: 600, paused: true, gc: 3},
+		{used: 600, garbage: 200, paused: false, gc: 4},
+		{used: 800, paused: false, gc: 4},
+	}
+	for i, tt := range tests {
+		used, garbage = tt.used, tt.garbage
+		m.check()
+		assert.Equal(t, tt.paused, Paused(), "case %d", i)
+		assert.Equal(t, tt.gc, gcCount, "case %d", i)
+	}
+}
+
+func TestWait(t *testing.T) {
+	m := NewManager(1000, 0.9, 0.5, 0)
+	var used int64 = 950
+	m.readHeap = func() int64 { return used }
+	m.gc = func() {}
+	// Not blocked when not paused
+	Wait(context.Background())
+	m.check()
+	assert.True(t, Paused())
+
+	done := make(chan struct{})
+	go func() {
+		Wait(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+		assert.Fail(t, "should wait under memory pressure")
+	case <-time.After(50 * time.Millisecond):
+	}
+	used = 100
+	m.check()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "should resume after the memory pressure is released")
+	}
+
+	// Cancelled ctx does not block
+	used = 950
+	m.check()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	Wait(ctx)
+	m.release()
+	assert.False(t, Paused())
+}
+
+func TestMaxPause(t *testing.T) {
+	m := NewManager(1000, 0.9, 0.5, time.Minute)
+	now := time.UnixMilli(0)
+	m.now = func() time.Time { return now }
+	m.readHeap = func() int64 { return 950 }
+	m.gc = func() {}
+	defer m.release()
+
+	m.check()
+	assert.True(t, Paused())
+	now = now.Add(30 * time.Second)
+	m.check()
+	assert.True(t, Paused())
+	// Resume for a check interval after the max pause
+	now = now.Add(30 * time.Second)
+	m.check()
+	assert.False(t, Paused())
+	m.check()
+	assert.True(t, Paused())
+	assert.Equal(t, now, m.pausedAt)
+}
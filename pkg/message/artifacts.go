@@ -21,13 +21,23 @@ import (
 )
 
 const (
-	FormatBinary     = "binary"
-	FormatJson       = "json"
-	FormatProtobuf   = "protobuf"
-	FormatDelimited  = "delimited"
-	FormatUrlEncoded = "urlencoded"
-	FormatXML        = "xml"
-	FormatCustom     = "custom"
+	FormatBinary       = "binary"
+	FormatBinStruct    = "binstruct"
+	FormatJson         = "json"
+	FormatProtobuf     = "protobuf"
+	FormatAvro         = "avro"
+	FormatDelimited    = "delimited"
+	FormatUrlEncoded   = "urlencoded"
+	FormatXML          = "xml"
+	FormatCbor         = "cbor"
+	FormatMsgpack      = "msgpack"
+	FormatBson         = "bson"
+	FormatLineProtocol = "lineprotocol"
+	FormatSparkplug    = "sparkplug"
+	FormatCan          = "can"
+	FormatFlatbuffers  = "flatbuffers"
+	FormatWasm         = "wasm"
+	FormatCustom       = "custom"
 
 	DefaultField = "self"
 	MetaKey      = "__meta"
@@ -39,6 +49,17 @@ type Converter interface {
 	Decode(ctx api.StreamContext, b []byte) (any, error)
 }
 
+// MetaDecoder decodes the bytes with the metadata of the message such as the mqtt topic
+type MetaDecoder interface {
+	DecodeWithMeta(ctx api.StreamContext, b []byte, meta map[string]any) (any, error)
+}
+
+// StreamDecoder decodes the bytes which contain many items such as NDJSON or a json array one by one.
+// Each decoded item is passed to emit without materializing the whole result. Stop decoding if emit returns error.
+type StreamDecoder interface {
+	DecodeStream(ctx api.StreamContext, b []byte, emit func(map[string]any) error) error
+}
+
 // PartialDecoder decodes a field partially
 type PartialDecoder interface {
 	DecodeField(ctx api.StreamContext, b []byte, f string) (any, error)
@@ -69,3 +90,8 @@ type Decompressor interface {
 type Encryptor interface {
 	Encrypt([]byte) ([]byte, error)
 }
+
+// Decryptor decrypts bytes
+type Decryptor interface {
+	Decrypt([]byte) ([]byte, error)
+}
@@ -50,3 +50,26 @@ type UniqueSub interface {
 type UniqueConn interface {
 	ConnId(props map[string]any) string
 }
+
+// RunOnce is the source which can read its bounded input to the end once and then send EOF.
+// Only these sources can be used in the batch rules.
+type RunOnce interface {
+	api.Bounded
+	// SetRunOnce is called before the provision
+	SetRunOnce()
+}
+
+// TwoPhaseCommit is the sink which commits its output together with the checkpoint to deliver the data exactly once.
+// The data collected between two checkpoints belong to one transaction.
+type TwoPhaseCommit interface {
+	// SetTransactional is called before the connection if the rule qos is exactly once. Then the collected data must be
+	// held in the transaction until committed.
+	SetTransactional()
+	// PreCommit is called when the sink receives the barrier of the checkpoint. It ends the transaction of the data
+	// collected since the previous checkpoint and returns it to save in the checkpoint. The returned transaction must
+	// be encodable by gob. Return nil if there is no data.
+	PreCommit(ctx api.StreamContext, checkpointId int64) (any, error)
+	// Commit writes out the transaction when its checkpoint completes. The transactions restored from the checkpoint
+	// are committed again when the rule recovers, so it must be idempotent.
+	Commit(ctx api.StreamContext, checkpointId int64, txn any) error
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pool reuses the scratch objects allocated for each message in the hot path to reduce the GC pressure.
+// Only the objects whose lifetime is within a call can be pooled. The tuples and their messages are shared by
+// the downstream nodes, the windows and the sink caches, so they are never put back.
+package pool
+
+import (
+	"bytes"
+	"sync"
+)
+
+const (
+	// The buffers and maps beyond the size are dropped so that a burst of huge messages is not retained
+	maxBufferSize = 64 * 1024
+	maxMapSize    = 1024
+)
+
+var (
+	bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	mapPool    = sync.Pool{New: func() any { return make(map[string]any) }}
+)
+
+// GetBuffer returns an empty buffer. Put it back by PutBuffer once the content is not referred.
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func PutBuffer(b *bytes.Buffer) {
+	if b == nil || b.Cap() > maxBufferSize {
+		return
+	}
+	b.Reset()
+	bufferPool.Put(b)
+}
+
+// CopyAndPutBuffer returns a copy of the buffer content and puts the buffer back
+func CopyAndPutBuffer(b *bytes.Buffer) []byte {
+	result := bytes.Clone(b.Bytes())
+	PutBuffer(b)
+	return result
+}
+
+// GetMap returns an empty map. Put it back by PutMap once the map is not referred.
+func GetMap() map[string]any {
+	return mapPool.Get().(map[string]any)
+}
+
+func PutMap(m map[string]any) {
+	if m == nil || len(m) > maxMapSize {
+		return
+	}
+	clear(m)
+	mapPool.Put(m)
+}
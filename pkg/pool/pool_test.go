@@ -0,0 +1,55 @@
+// Copyright 2024 EMQ Technologies Co., Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer(t *testing.T) {
+	b := GetBuffer()
+	assert.Equal(t, 0, b.Len())
+	b.WriteString("hello")
+	r := CopyAndPutBuffer(b)
+	assert.Equal(t, []byte("hello"), r)
+	// The copy is not affected by the reuse of the buffer
+	b = GetBuffer()
+	assert.Equal(t, 0, b.Len())
+	b.WriteString("world")
+	assert.Equal(t, []byte("hello"), r)
+	PutBuffer(b)
+	// Huge buffer is dropped without panic
+	PutBuffer(bytes.NewBuffer(make([]byte, 0, maxBufferSize+1)))
+	PutBuffer(nil)
+}
+
+func TestMap(t *testing.T) {
+	m := GetMap()
+	assert.Len(t, m, 0)
+	m["a"] = 1
+	PutMap(m)
+	m = GetMap()
+	assert.Len(t, m, 0)
+	big := make(map[string]any, maxMapSize+1)
+	for i := 0; i <= maxMapSize; i++ {
+		big[string(rune(i))] = i
+	}
+	PutMap(big)
+	assert.Len(t, big, maxMapSize+1)
+	PutMap(nil)
+}
@@ -61,6 +61,22 @@ type Function interface {
 	IsAggregate() bool
 }
 
+// AggregateFunction is an aggregate function which accumulates the rows of a group one by one
+// instead of receiving the whole column slices in Exec.
+// The accumulator is held by eKuiper and passed in each call, so it must be JSON serializable.
+// It is decoded from JSON before each call, thus a struct accumulator will be received as a map.
+type AggregateFunction interface {
+	Function
+	// CreateAccumulator returns the initial accumulator of a group
+	CreateAccumulator(ctx FunctionContext) (interface{}, error)
+	// Accumulate adds a row into the accumulator and returns the new accumulator. The args are the arguments of the row.
+	Accumulate(ctx FunctionContext, acc interface{}, args []interface{}) (interface{}, error)
+	// Merge merges two accumulators of the same group and returns the merged accumulator
+	Merge(ctx FunctionContext, acc interface{}, other interface{}) (interface{}, error)
+	// Result returns the final result of the accumulator
+	Result(ctx FunctionContext, acc interface{}) (interface{}, error)
+}
+
 type Sink interface {
 	// Should be sync function for normal case. The container will run it in go func
 	Open(ctx StreamContext) error
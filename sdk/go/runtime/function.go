@@ -87,6 +87,27 @@ func (s *funcRuntime) run() {
 		case "IsAggregate":
 			result := s.s.IsAggregate()
 			return encodeReply(true, result)
+		case "IsAccumulator":
+			_, ok := s.s.(api.AggregateFunction)
+			return encodeReply(true, ok)
+		case "CreateAccumulator", "Accumulate", "Merge", "Result":
+			af, ok := s.s.(api.AggregateFunction)
+			if !ok {
+				return encodeReply(false, "function is not an aggregate function with accumulator")
+			}
+			arg, ok := d.Arg.([]interface{})
+			if !ok {
+				return encodeReply(false, "argument is not interface array")
+			}
+			farg, fctx, err := parseFuncContextArgs(arg)
+			if err != nil {
+				return encodeReply(false, err.Error())
+			}
+			r, err := execAccumulator(af, d.Func, farg, fctx)
+			if err != nil {
+				return encodeReply(false, err.Error())
+			}
+			return encodeReply(true, r)
 		default:
 			return encodeReply(false, fmt.Sprintf("invalid func %s", d.Func))
 		}
@@ -110,6 +131,48 @@ func (s *funcRuntime) isRunning() bool {
 	return s.ctx.Err() == nil
 }
 
+// execAccumulator runs the accumulator lifecycle functions
+// Accumulate receives a batch of rows and accumulates them one by one
+func execAccumulator(af api.AggregateFunction, name string, args []interface{}, ctx api.FunctionContext) (interface{}, error) {
+	switch name {
+	case "CreateAccumulator":
+		return af.CreateAccumulator(ctx)
+	case "Accumulate":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("accumulate requires 2 arguments but got %d", len(args))
+		}
+		rows, ok := args[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("accumulate rows is not interface array")
+		}
+		acc := args[0]
+		for _, r := range rows {
+			row, ok := r.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("accumulate row is not interface array")
+			}
+			var err error
+			acc, err = af.Accumulate(ctx, acc, row)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return acc, nil
+	case "Merge":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("merge requires 2 arguments but got %d", len(args))
+		}
+		return af.Merge(ctx, args[0], args[1])
+	case "Result":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("result requires 1 argument but got %d", len(args))
+		}
+		return af.Result(ctx, args[0])
+	default:
+		return nil, fmt.Errorf("invalid func %s", name)
+	}
+}
+
 func encodeReply(state bool, arg interface{}) []byte {
 	r, _ := json.Marshal(FuncReply{
 		State:  state,
@@ -12,7 +12,7 @@
 #  See the License for the specific language governing permissions and
 #  limitations under the License.
 
-from ekuiper.function import Function
+from ekuiper.function import Function, AggregateFunction
 from ekuiper.runtime import plugin
 from ekuiper.runtime.context import Context
 from ekuiper.runtime.plugin import PluginConfig
@@ -20,7 +20,7 @@ from ekuiper.sink import Sink
 from ekuiper.source import Source
 
 __all__ = [
-    'plugin', 'PluginConfig', 'Source', 'Sink', 'Function', 'Context'
+    'plugin', 'PluginConfig', 'Source', 'Sink', 'Function', 'AggregateFunction', 'Context'
 ]
 
 name = "ekuiper"
@@ -35,3 +35,35 @@ class Function(object):
     def is_aggregate(self):
         """callback to check if function is for aggregation, return bool"""
         pass
+
+
+class AggregateFunction(Function):
+    """abstract class for eKuiper aggregate function plugin which accumulates the rows one by one.
+    The accumulator is held by eKuiper and passed in each call, so it must be JSON serializable"""
+
+    def exec(self, args: List[Any], ctx: Context) -> Any:
+        """no need to implement, the accumulator callbacks are used instead"""
+        pass
+
+    def is_aggregate(self):
+        return True
+
+    @abstractmethod
+    def create_accumulator(self, ctx: Context) -> Any:
+        """callback to create the initial accumulator of a group"""
+        pass
+
+    @abstractmethod
+    def accumulate(self, acc: Any, args: List[Any], ctx: Context) -> Any:
+        """callback to add the arguments of a row into the accumulator, return the new accumulator"""
+        pass
+
+    @abstractmethod
+    def merge(self, acc: Any, other: Any, ctx: Context) -> Any:
+        """callback to merge two accumulators of the same group, return the merged accumulator"""
+        pass
+
+    @abstractmethod
+    def result(self, acc: Any, ctx: Context) -> Any:
+        """callback to get the final result from the accumulator"""
+        pass
@@ -20,7 +20,7 @@ from . import reg
 from .connection import PairChannel
 from .contextimpl import ContextImpl
 from .symbol import SymbolRuntime
-from ..function import Function
+from ..function import Function, AggregateFunction
 
 
 class FunctionRuntime(SymbolRuntime):
@@ -61,25 +61,27 @@ class FunctionRuntime(SymbolRuntime):
                 args = c['arg']
                 if isinstance(args, list) is False or len(args) < 1:
                     return encode_reply(False, 'invalid arg')
-                fmeta = json.loads(args[-1])
-                if 'ruleId' in fmeta and 'opId' in fmeta and 'instanceId' in fmeta \
-                        and 'funcId' in fmeta:
-                    key = f"{fmeta['ruleId']}_{fmeta['opId']}_{fmeta['instanceId']}" \
-                          f"_{fmeta['funcId']}"
-                    if key in self.funcs:
-                        fctx = self.funcs[key]
-                    else:
-                        fctx = ContextImpl(fmeta)
-                        self.funcs[key] = fctx
-                else:
-                    return encode_reply(False,
-                                        f'invalid arg: {fmeta} ruleId, opId, instanceId and funcId'
-                                        f' are required')
+                fctx, err = self.get_context(args[-1])
+                if err != "":
+                    return encode_reply(False, err)
                 r = self.s.exec(args[:-1], fctx)
                 return encode_reply(True, r)
             elif name == "IsAggregate":
                 r = self.s.is_aggregate()
                 return encode_reply(True, r)
+            elif name == "IsAccumulator":
+                return encode_reply(True, isinstance(self.s, AggregateFunction))
+            elif name in ("CreateAccumulator", "Accumulate", "Merge", "Result"):
+                if not isinstance(self.s, AggregateFunction):
+                    return encode_reply(False,
+                                        'function is not an aggregate function with accumulator')
+                args = c['arg']
+                if isinstance(args, list) is False or len(args) < 1:
+                    return encode_reply(False, 'invalid arg')
+                fctx, err = self.get_context(args[-1])
+                if err != "":
+                    return encode_reply(False, err)
+                return encode_reply(True, self.exec_accumulator(name, args[:-1], fctx))
             else:
                 return encode_reply(False, "invalid func {}".format(name))
         except Exception:
@@ -89,6 +91,33 @@ class FunctionRuntime(SymbolRuntime):
                 logging.error(traceback.format_exc())
                 return encode_reply(False, traceback.format_exc())
 
+    def get_context(self, raw: str):
+        fmeta = json.loads(raw)
+        if 'ruleId' in fmeta and 'opId' in fmeta and 'instanceId' in fmeta \
+                and 'funcId' in fmeta:
+            key = f"{fmeta['ruleId']}_{fmeta['opId']}_{fmeta['instanceId']}" \
+                  f"_{fmeta['funcId']}"
+            if key in self.funcs:
+                fctx = self.funcs[key]
+            else:
+                fctx = ContextImpl(fmeta)
+                self.funcs[key] = fctx
+            return fctx, ""
+        return None, f'invalid arg: {fmeta} ruleId, opId, instanceId and funcId are required'
+
+    def exec_accumulator(self, name: str, args: list, fctx: ContextImpl):
+        if name == "CreateAccumulator":
+            return self.s.create_accumulator(fctx)
+        elif name == "Accumulate":
+            acc = args[0]
+            for row in args[1]:
+                acc = self.s.accumulate(acc, row, fctx)
+            return acc
+        elif name == "Merge":
+            return self.s.merge(args[0], args[1], fctx)
+        else:
+            return self.s.result(args[0], fctx)
+
     def stop(self):
         self.running = False
         # noinspection PyBroadException